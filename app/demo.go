@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"log/slog"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+)
+
+// newDemoRepository returns an in-memory repository with sample data,
+// everything is lost on restart
+func newDemoRepository(ctx context.Context, logger *slog.Logger) *memory.Repository {
+	repo := memory.New()
+	if err := repo.ConnectDB(ctx, "", *logger); err != nil {
+		log.Fatalf("failed to init demo repository: %v", err)
+	}
+	if err := repo.SeedDemo(ctx); err != nil {
+		log.Fatalf("failed to seed demo data: %v", err)
+	}
+
+	logger.Warn("DEMO MODE: data is kept in memory and lost on restart",
+		slog.String("login", memory.DemoAdminEmail),
+		slog.String("password", memory.DemoPassword),
+	)
+	return repo
+}
+
+// demoSecret - random JWT secret, tokens don't survive a restart anyway
+func demoSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("failed to generate jwt secret: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/services/email"
+	"github.com/TP2-Voice-Agora/backend/internal/services/onboarding"
+)
+
+// runImport handles the -import-users flag: checks the CSV file at path or,
+// with commit, imports it and prints the report. Invitations are queued in
+// the outbox and sent by the server, the import is not bound by the startup
+// timeout.
+func runImport(repo repository.Repository, path string, commit bool, logger *slog.Logger) error {
+	ctx := context.Background()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mailer := email.New(*logger, repo, nil, os.Getenv("APP_URL"))
+	report, err := onboarding.New(*logger, repo, mailer).Import(ctx, file, commit)
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		if details, ok := appErr.Details.([]models.ImportRowError); ok {
+			printRowErrors(details)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("rows: %d, valid: %d, errors: %d\n", report.Rows, len(report.Users), len(report.Errors))
+	if len(report.NewPositions) > 0 {
+		fmt.Printf("new positions: %s\n", strings.Join(report.NewPositions, ", "))
+	}
+	printRowErrors(report.Errors)
+
+	switch {
+	case len(report.Errors) > 0:
+		return fmt.Errorf("the file has %d errors, nothing is imported", len(report.Errors))
+	case !report.Committed:
+		fmt.Println("dry run, nothing is imported: run with -commit to create the users")
+	default:
+		invited := 0
+		for _, u := range report.Users {
+			if u.Invited {
+				invited++
+			}
+		}
+		fmt.Printf("imported %d users, invited %d\n", len(report.Users), invited)
+	}
+	return nil
+}
+
+func printRowErrors(errs []models.ImportRowError) {
+	for _, e := range errs {
+		switch {
+		case e.Row == 0:
+			fmt.Printf("file: %s\n", e.Message)
+		case e.Field == "":
+			fmt.Printf("row %d: %s\n", e.Row, e.Message)
+		default:
+			fmt.Printf("row %d, %s: %s\n", e.Row, e.Field, e.Message)
+		}
+	}
+}
@@ -1,21 +1,45 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"github.com/TP2-Voice-Agora/backend/internal/lib/logger/prettyslog"
-	"github.com/TP2-Voice-Agora/backend/internal/repository/postgres"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/services/analytics"
 	"github.com/TP2-Voice-Agora/backend/internal/services/auth"
+	"github.com/TP2-Voice-Agora/backend/internal/services/bot"
+	"github.com/TP2-Voice-Agora/backend/internal/services/bot/telegram"
+	"github.com/TP2-Voice-Agora/backend/internal/services/campaigns"
+	"github.com/TP2-Voice-Agora/backend/internal/services/email"
+	"github.com/TP2-Voice-Agora/backend/internal/services/export"
 	"github.com/TP2-Voice-Agora/backend/internal/services/http-server"
 	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/TP2-Voice-Agora/backend/internal/services/jobs"
+	"github.com/TP2-Voice-Agora/backend/internal/services/notifications"
+	"github.com/TP2-Voice-Agora/backend/internal/services/onboarding"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ranking"
+	"github.com/TP2-Voice-Agora/backend/internal/services/realtime"
+	"github.com/TP2-Voice-Agora/backend/internal/services/reports"
 	"github.com/TP2-Voice-Agora/backend/internal/services/users"
+	"github.com/TP2-Voice-Agora/backend/internal/services/webhooks"
 	_ "github.com/joho/godotenv"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 func main() {
+	migrateCmd := flag.String("migrate", "", "run migrations and exit: up, down or version")
+	migrateSteps := flag.Int("steps", 1, "how many migrations -migrate=down rolls back")
+	demo := flag.Bool("demo", false, "run on an in-memory database seeded with sample data")
+	importFile := flag.String("import-users", "", "check users in a CSV file and exit, see -commit")
+	importCommit := flag.Bool("commit", false, "with -import-users, create and invite the users")
+	flag.Parse()
+
 	//err := godotenv.Load()
 	//if err != nil {
 	//	log.Fatal("Error loading .env file")
@@ -26,33 +50,172 @@ func main() {
 		port = "8080"
 	}
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
+	if jwtSecret == "" && *demo {
+		jwtSecret = demoSecret()
+	}
+	if jwtSecret == "" && *migrateCmd == "" && *importFile == "" {
 		log.Fatal("JWT_SECRET is required")
 	}
 	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
+	if dbURL == "" && !*demo {
 		log.Fatal("DATABASE_URL is required")
 	}
+	if *demo && *migrateCmd != "" {
+		log.Fatal("-migrate can't be used with -demo")
+	}
+	if *demo && *importFile != "" {
+		log.Fatal("-import-users can't be used with -demo")
+	}
 
 	// Logger
 	logger := prettyslog.New()
 
+	// startup (connecting, warming caches) must not hang forever
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
 	// Repository
-	repo := &postgres.PostgresRepository{}
-	err := repo.ConnectDB(dbURL, *logger)
-	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
-	}
+	var repo repository.Repository
+	if *demo {
+		repo = newDemoRepository(ctx, logger)
+	} else {
+		db, err := openStorage(ctx, dbURL, logger)
+		if err != nil {
+			log.Fatalf("failed to connect to database: %v", err)
+		}
 
-	defer repo.CloseConnectDB()
+		defer db.CloseConnectDB()
+
+		// Schema
+		if *migrateCmd != "" {
+			if err := runMigrations(db, *migrateCmd, *migrateSteps, logger); err != nil {
+				log.Fatalf("migrations failed: %v", err)
+			}
+			return
+		}
+		if os.Getenv("AUTO_MIGRATE") == "true" {
+			if err := db.MigrateUp(context.Background()); err != nil {
+				log.Fatalf("migrations failed: %v", err)
+			}
+		}
+		if err := db.CheckSchema(ctx); err != nil {
+			log.Fatalf("refusing to start: %v", err)
+		}
+		if *importFile != "" {
+			if err := runImport(db, *importFile, *importCommit, logger); err != nil {
+				log.Fatalf("import failed: %v", err)
+			}
+			return
+		}
+		repo = db
+	}
 
 	// Services
-	ideaService := ideas.New(*logger, repo)
+	ideaService, err := ideas.New(ctx, *logger, repo)
+	if err != nil {
+		log.Fatalf("failed to start idea service: %v", err)
+	}
+	if set := os.Getenv("REACTIONS"); set != "" {
+		if err := ideaService.SetReactions(strings.Split(set, ",")); err != nil {
+			log.Fatalf("invalid REACTIONS: %v", err)
+		}
+	}
 	authService := auth.New(*logger, repo, 2*time.Hour, jwtSecret)
 	userService := users.New(*logger, repo)
+	notificationService := notifications.New(*logger, repo)
+	ideaService.SetNotifier(notificationService)
+
+	// Real-time events, replicas share them through the database
+	hub := realtime.NewHub(*logger)
+	if os.Getenv("REALTIME_PG_NOTIFY") == "true" {
+		relay, ok := repo.(realtime.Relay)
+		if !ok {
+			log.Fatal("REALTIME_PG_NOTIFY needs a PostgreSQL DATABASE_URL")
+		}
+		hub.SetRelay(relay)
+		go hub.Listen(context.Background())
+	}
+	notificationService.SetPublisher(hub)
+
+	// Webhooks, queued in the transaction of the change and sent in the background
+	webhookService := webhooks.New(*logger, repo)
+	ideaService.SetOutbox(webhookService)
+	go webhookService.Run(context.Background())
+
+	// Ranking scores, updated with every vote and comment and by the rank-ideas job
+	ranker := ranking.New(*logger, repo)
+	ideaService.SetPublisher(ideas.Publishers{hub, webhookService, ranker})
+
+	// Email, settings are served even when nothing is sent
+	var sender email.Sender
+	if smtpURL := os.Getenv("SMTP_URL"); smtpURL != "" {
+		smtp, err := email.NewSMTPSender(smtpURL, os.Getenv("SMTP_FROM"))
+		if err != nil {
+			log.Fatalf("invalid SMTP settings: %v", err)
+		}
+		sender = smtp
+	} else if *demo {
+		sender = email.NewCapture(logger)
+	}
+	var forwarders notifications.Forwarders
+	mailer := email.New(*logger, repo, sender, os.Getenv("APP_URL"))
+	if sender != nil {
+		forwarders = append(forwarders, mailer)
+		go mailer.Run(context.Background())
+	}
+
+	// Messenger bot, long-polled unless the webhook is set up
+	var platforms []bot.Platform
+	var polled []string
+	if token := os.Getenv("BOT_TELEGRAM_TOKEN"); token != "" {
+		secret := os.Getenv("BOT_TELEGRAM_WEBHOOK_SECRET")
+		platforms = append(platforms, telegram.New(*logger, token, os.Getenv("BOT_TELEGRAM_USERNAME"), secret))
+		if secret == "" {
+			polled = append(polled, telegram.Name)
+		}
+	}
+	chatBot := bot.New(*logger, repo, ideaService, os.Getenv("APP_URL"), platforms...)
+	if len(platforms) > 0 {
+		forwarders = append(forwarders, chatBot)
+		go chatBot.Run(context.Background(), polled...)
+	}
+	notificationService.SetForwarder(forwarders)
+
+	campaignService := campaigns.New(*logger, repo)
+
+	// Analytics, decision statuses are found by name unless set
+	analyticsService := analytics.New(*logger, repo)
+	if approved := os.Getenv("APPROVED_STATUSES"); approved != "" {
+		err := analyticsService.SetDecisionStatuses(mustParseIDs("APPROVED_STATUSES", approved),
+			mustParseIDs("REJECTED_STATUSES", os.Getenv("REJECTED_STATUSES")))
+		if err != nil {
+			log.Fatalf("invalid decision statuses: %v", err)
+		}
+	}
+	exportService := export.New(*logger, repo)
+	reportService := reports.New(*logger, repo)
+	// invitations go through the outbox, delivered once SMTP_URL is set
+	onboardingService := onboarding.New(*logger, repo, mailer)
+
+	// Scheduled jobs, replicas on PostgreSQL take turns through advisory locks
+	locker, ok := repo.(jobs.Locker)
+	if !ok {
+		locker = jobs.NewLocalLocker()
+	}
+	scheduler := jobs.New(*logger, repo, locker)
+	if sender != nil {
+		mustAddJob(scheduler, "email-digests", "0 * * * *", jobs.Count(mailer.SendDigests))
+	}
+	mustAddJob(scheduler, "rank-ideas", "*/10 * * * *", jobs.Count(ranker.Recalculate))
+	mustAddJob(scheduler, "purge-link-codes", "*/15 * * * *", jobs.Count(chatBot.PurgeLinkCodes))
+	mustAddJob(scheduler, "purge-stream-tickets", "*/15 * * * *", jobs.Count(authService.PurgeStreamTickets))
+	mustAddJob(scheduler, "purge-job-runs", "30 3 * * *", jobs.Count(scheduler.PurgeRuns))
+	go scheduler.Run(context.Background())
 
 	// HTTP Server
-	server := http_server.NewHTTPServer(ideaService, authService, userService, logger)
+	server := http_server.NewHTTPServer(ideaService, authService, userService, notificationService, mailer,
+		webhookService, chatBot, campaignService, scheduler, analyticsService, exportService,
+		reportService, onboardingService, hub, logger)
 	handler := server.SetupRoutes()
 
 	logger.Info("Server starting...", slog.String("port", port))
@@ -60,3 +223,25 @@ func main() {
 		log.Fatalf("server failed: %v", err)
 	}
 }
+
+func mustAddJob(scheduler *jobs.Scheduler, name, spec string, fn jobs.Func) {
+	if err := scheduler.Add(name, spec, fn); err != nil {
+		log.Fatalf("invalid job %s: %v", name, err)
+	}
+}
+
+// mustParseIDs parses a comma separated list of ids, empty for an empty value
+func mustParseIDs(name, value string) []int {
+	var ids []int
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", name, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
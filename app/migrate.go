@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// runMigrations handles the -migrate flag, migrations are not bound by the startup timeout
+func runMigrations(m repository.Migrator, cmd string, steps int, logger *slog.Logger) error {
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		if err := m.MigrateUp(ctx); err != nil {
+			return err
+		}
+	case "down":
+		if err := m.MigrateDown(ctx, steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or version", cmd)
+	}
+
+	current, latest, err := m.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("schema version", slog.Int("current", current), slog.Int("latest", latest))
+	return nil
+}
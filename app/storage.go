@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/postgres"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/sqlite"
+)
+
+// storage is a repository backed by a migratable SQL schema
+type storage interface {
+	repository.Repository
+	repository.Migrator
+}
+
+// openStorage picks the backend by the scheme of DATABASE_URL:
+// postgres://... or postgresql://... for PostgreSQL, sqlite:... for SQLite
+func openStorage(ctx context.Context, dbURL string, logger *slog.Logger) (storage, error) {
+	scheme, _, _ := strings.Cut(dbURL, ":")
+
+	var db storage
+	switch scheme {
+	case "postgres", "postgresql":
+		db = &postgres.PostgresRepository{}
+	case sqlite.Scheme:
+		db = &sqlite.SQLiteRepository{}
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_URL scheme %q, use postgres:// or sqlite:", scheme)
+	}
+
+	if err := db.ConnectDB(ctx, dbURL, *logger); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -15,439 +15,699 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/comments": {
-            "post": {
-                "description": "Вставляет коммент и возвращает его.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/analytics/contributors": {
+            "get": {
+                "description": "Пользователи, подавшие больше всего идей и оставившие больше всего комментариев за диапазон.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Вставка комментариев\\ответов"
+                    "Аналитика"
                 ],
-                "summary": "Вставка комментария(secure)",
+                "summary": "Самые активные(secure, admin)",
                 "parameters": [
                     {
-                        "description": "Comment data",
-                        "name": "comment",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.InsertCommentRequest"
-                        }
+                        "type": "string",
+                        "description": "Первый день, 2006-01-02; по умолчанию 30 дней до to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Последний день включительно, по умолчанию сегодня",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "До 100, по умолчанию 10",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Comment"
+                            "$ref": "#/definitions/models.ContributorReport"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "405": {
-                        "description": "Invalid method",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to create comment",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/ideas": {
+        "/admin/analytics/decisions": {
             "get": {
-                "description": "Возвращает все идеи списков без комментариев\\ответов.",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Идеи"
-                ],
-                "summary": "Все идеи(secure)",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Idea"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Failed to get ideas",
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                }
-            },
-            "post": {
-                "description": "Вставляет идею, и возвращает ее со всеми заполненными полями",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Идеи, поданные за диапазон, всего и по категориям: одобренные и отклоненные по текущему статусу,\nдоля одобренных среди решенных и медиана часов от подачи до первого решения. Статусы решений\nзадаются APPROVED_STATUSES и REJECTED_STATUSES, иначе определяются по названию.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Идеи"
+                    "Аналитика"
                 ],
-                "summary": "Вставка новой идеи(secure)",
+                "summary": "Решения по идеям(secure, admin)",
                 "parameters": [
                     {
-                        "description": "Idea data",
-                        "name": "idea",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.InsertIdeaRequest"
-                        }
+                        "type": "string",
+                        "description": "Первый день, 2006-01-02; по умолчанию 30 дней до to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Последний день включительно, по умолчанию сегодня",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Idea"
+                            "$ref": "#/definitions/models.DecisionReport"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to create idea",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/ideas/categories": {
+        "/admin/analytics/engagement": {
             "get": {
-                "description": "Ручка категорий идей, в теории дергается один раз при первой загрузке страницы,",
+                "description": "Сколько сотрудников каждой должности подавали идеи, комментировали и голосовали за диапазон,\nparticipation - доля проголосовавших. Сотрудники без должности учитываются только в total.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Идеи"
+                    "Аналитика"
+                ],
+                "summary": "Вовлеченность по должностям(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Первый день, 2006-01-02; по умолчанию 30 дней до to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Последний день включительно, по умолчанию сегодня",
+                        "name": "to",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Категории идей(secure)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.IdeaCategory"
-                            }
+                            "$ref": "#/definitions/models.EngagementReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/ideas/statuses": {
+        "/admin/analytics/submissions": {
             "get": {
-                "description": "Ручка статусов идей, в теории дергается один раз при первой загрузке страницы,",
+                "description": "Число идей, поданных за каждый период диапазона, включая периоды без идей. Периоды в UTC,\nнедели начинаются с понедельника. Отчеты аналитики кэшируются на 5 минут.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Идеи"
+                    "Аналитика"
+                ],
+                "summary": "Поданные идеи(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Первый день, 2006-01-02; по умолчанию 30 дней до to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Последний день включительно, по умолчанию сегодня",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "day, week или month, по умолчанию day",
+                        "name": "group",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Статусы идей(secure)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.IdeaStatus"
-                            }
+                            "$ref": "#/definitions/models.SubmissionReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/ideas/{uid}": {
-            "get": {
-                "description": "Возвращает идею по UID, уже с комментариями\\ответами",
+        "/admin/campaigns": {
+            "post": {
+                "description": "Идеи подаются в кампанию с startsAt до endsAt, голосование за них закрывается в endsAt.\ncategoryIds и positionIds ограничивают категории идей и должности авторов, пустые - любые.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Идеи"
+                    "Кампании"
                 ],
-                "summary": "Конкретная идея(secure)",
+                "summary": "Создание кампании(secure, admin)",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Idea UID",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
+                        "description": "Campaign",
+                        "name": "campaign",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CampaignRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.IdeaComment"
+                            "$ref": "#/definitions/models.Campaign"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "400": {
+                        "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to get idea by UID",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/ideas/{uid}/dislike": {
-            "post": {
-                "description": "Увеличение дизлайков",
+        "/admin/campaigns/{id}": {
+            "put": {
+                "description": "Заменяет все поля кампании, уже поданные идеи остаются в ней.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Идеи"
+                    "Кампании"
                 ],
-                "summary": "Увеличение дизлайков",
+                "summary": "Изменение кампании(secure, admin)",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Idea UID",
-                        "name": "uid",
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Campaign",
+                        "name": "campaign",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CampaignRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.Campaign"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "404": {
+                        "description": "Campaign not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет кампанию, ее идеи остаются без кампании.",
+                "tags": [
+                    "Кампании"
+                ],
+                "summary": "Удаление кампании(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
                     },
-                    "500": {
-                        "description": "Failed to increase dislikes",
+                    "404": {
+                        "description": "Campaign not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/ideas/{uid}/like": {
-            "post": {
-                "description": "Увеличение лайков",
+        "/admin/export/discussions": {
+            "get": {
+                "description": "Идеи с названиями категорий и статусов, автором, голосами, просмотрами и числом комментариев;\n/admin/export/discussions - все комментарии этих идей, строка на комментарий, ответы сразу после\nкомментария, на который отвечают. Идеи отбираются теми же параметрами, что и в GET /ideas.\nCSV в UTF-8 с BOM (Excel открывает кириллицу без перекодировки), тексты, которые таблица\nприняла бы за формулу, начинаются с апострофа. Файл передается по мере формирования.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Идеи"
+                    "Выгрузка"
                 ],
-                "summary": "Увеличение лайков",
+                "summary": "Выгрузка идей и обсуждений(secure, admin)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Idea UID",
-                        "name": "uid",
-                        "in": "path",
-                        "required": true
+                        "description": "csv или xlsx, по умолчанию csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max search results (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hot, top, controversial или rising",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "file"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "405": {
-                        "description": "Invalid method",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to increase likes",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "Аутентификация, возвращает jwt токен, который прикладывается ко всем (secure) рутам.",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/export/ideas": {
+            "get": {
+                "description": "Идеи с названиями категорий и статусов, автором, голосами, просмотрами и числом комментариев;\n/admin/export/discussions - все комментарии этих идей, строка на комментарий, ответы сразу после\nкомментария, на который отвечают. Идеи отбираются теми же параметрами, что и в GET /ideas.\nCSV в UTF-8 с BOM (Excel открывает кириллицу без перекодировки), тексты, которые таблица\nприняла бы за формулу, начинаются с апострофа. Файл передается по мере формирования.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Авторизация\\Регистрация"
+                    "Выгрузка"
                 ],
-                "summary": "Аутентификация",
+                "summary": "Выгрузка идей и обсуждений(secure, admin)",
                 "parameters": [
                     {
-                        "description": "Login data",
-                        "name": "loginRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.LoginRequest"
-                        }
+                        "type": "string",
+                        "description": "csv или xlsx, по умолчанию csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max search results (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hot, top, controversial или rising",
+                        "name": "sort",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "JWT token",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "file"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs": {
+            "get": {
+                "description": "Задачи по расписанию: выражение cron, время следующего запуска и последний запуск.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Фоновые задачи"
+                ],
+                "summary": "Фоновые задачи(secure, admin)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Job"
+                            }
                         }
                     },
-                    "500": {
-                        "description": "Failed to login",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/register": {
+        "/admin/jobs/{name}/run": {
             "post": {
-                "description": "Регистрация - будет только в админке",
-                "consumes": [
+                "description": "Запускает задачу вне расписания. Задача выполняется в фоне, ее итог - в истории запусков.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Фоновые задачи"
+                ],
+                "summary": "Запуск фоновой задачи(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Job name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.JobRun"
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Job is already running",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs/{name}/runs": {
+            "get": {
+                "description": "Последние запуски, новые сначала. Запуск, прерванный остановкой сервера, остается running.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Авторизация\\Регистрация"
+                    "Фоновые задачи"
                 ],
-                "summary": "Регистрация",
+                "summary": "История запусков задачи(secure, admin)",
                 "parameters": [
                     {
-                        "description": "Register data",
-                        "name": "registerRequest",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Job name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "До 100, по умолчанию 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.RegisterRequest"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.JobRun"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Job not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
+                }
+            }
+        },
+        "/admin/users/import": {
+            "post": {
+                "description": "Заводит пользователей по выгрузке из HR-системы. Колонки узнаются по заголовку: name/Имя,\nsurname/Фамилия, email/E-mail/Почта, position/Должность обязательны, phone/Телефон и\nhire_date/Дата приема - нет, прочие колонки пропускаются. Разделитель - запятая, точка с запятой\nили табуляция, файл в UTF-8 (с BOM или без), до 5 МиБ и 5000 строк. Телефон приводится к 10\nцифрам без +7/8, дата - 2024-09-01 или 01.09.2024. Должности сравниваются без учета регистра,\nнедостающие создаются. Без commit=true это пробный прогон: отчет о том, кем станет каждая\nстрока, и ошибках по строкам. Файл импортируется целиком: при любой ошибке не создается никто\n(422 с отчетом). Созданным пользователям уходит приглашение задать пароль, до этого войти\nони не могут. Ошибки файла целиком (нет колонки, пустой файл) - 400 с деталями.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Импорт сотрудников из CSV(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Создать пользователей, по умолчанию пробный прогон",
+                        "name": "commit",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: ok",
+                        "description": "Пробный прогон",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "201": {
+                        "description": "Пользователи созданы",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Invalid file",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to register",
+                    "413": {
+                        "description": "File too large",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Rows have errors, nothing is imported",
+                        "schema": {
+                            "$ref": "#/definitions/models.ImportReport"
                         }
                     }
                 }
             }
         },
-        "/replies": {
+        "/admin/users/{uid}/invitation": {
             "post": {
-                "security": [
+                "description": "Отправляет импортированному пользователю новое приглашение задать пароль, прежнее перестает\nдействовать. Для тех, кому письмо не дошло или у кого истек срок ссылки (14 дней).",
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Повторное приглашение(secure, admin)",
+                "parameters": [
                     {
-                        "JWTAuth": []
+                        "type": "string",
+                        "description": "User UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "The user has already set a password",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
                     }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "description": "Все вебхуки, без секретов.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
                 ],
-                "description": "Вставляет новый ответ, и возвращает его",
+                "summary": "Вебхуки(secure, admin)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Not an admin",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "События: idea.created, idea.updated (в том числе смена статуса), idea.votes, comment.added.\nТело запроса к вебхуку - JSON {id, type, createdAt, data}, data - объект как в API.\nЗаголовок X-Agora-Signature - sha256= и hex HMAC-SHA256 тела с секретом; без secret он\nгенерируется. Секрет возвращается только здесь.",
                 "consumes": [
                     "application/json"
                 ],
@@ -455,17 +715,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Вставка комментариев\\ответов"
+                    "Вебхуки"
                 ],
-                "summary": "Вставка ответа",
+                "summary": "Создание вебхука(secure, admin)",
                 "parameters": [
                     {
-                        "description": "Reply data",
-                        "name": "reply",
+                        "description": "Webhook",
+                        "name": "webhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.InsertReplyRequest"
+                            "$ref": "#/definitions/models.WebhookRequest"
                         }
                     }
                 ],
@@ -473,401 +733,3471 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Reply"
+                            "$ref": "#/definitions/models.Webhook"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "405": {
-                        "description": "Invalid method",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to create reply",
+                    "403": {
+                        "description": "Not an admin",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/pfp": {
-            "post": {
-                "description": "Загрузка новой аватарки для юзера.",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+        "/admin/webhooks/deliveries/{id}": {
+            "get": {
+                "description": "Доставка с журналом попыток: код ответа, ошибка, длительность.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пользователи"
+                    "Вебхуки"
                 ],
-                "summary": "Загрузка PFP",
+                "summary": "Доставка вебхука(secure, admin)",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "Profile picture file",
-                        "name": "profile_picture",
-                        "in": "formData",
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "url to uploaded picture",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "No file uploaded or bad request",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "405": {
-                        "description": "Invalid method",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/models.WebhookDelivery"
                         }
                     },
-                    "500": {
-                        "description": "Failed to upload file",
+                    "404": {
+                        "description": "Delivery not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/positions": {
-            "get": {
-                "description": "Ручка позиции сотрудников",
+        "/admin/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "description": "Ставит доставленное или недоставленное событие в очередь заново, с тем же id и телом.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пользователи"
+                    "Вебхуки"
+                ],
+                "summary": "Повторная доставка(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Позиции сотрудников",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.UserPosition"
-                            }
+                            "$ref": "#/definitions/models.WebhookDelivery"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "404": {
+                        "description": "Delivery not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Delivery is still pending",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/{uid}": {
-            "get": {
-                "description": "Возвращает данные пользователя по UID.",
+        "/admin/webhooks/{id}": {
+            "put": {
+                "description": "Пустой secret оставляет прежний, без active вебхук не включается и не выключается.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Пользователи"
+                    "Вебхуки"
                 ],
-                "summary": "Получение юзера по UID",
+                "summary": "Изменение вебхука(secure, admin)",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User UID",
-                        "name": "uid",
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Webhook"
                         }
                     },
-                    "405": {
-                        "description": "Invalid method",
+                    "400": {
+                        "description": "Bad request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to get user",
+                    "404": {
+                        "description": "Webhook not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     }
                 }
+            },
+            "delete": {
+                "description": "Удаляет вебхук вместе с журналом доставок.",
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Удаление вебхука(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Последние доставки, новые сначала. state=dead - недоставленные после всех попыток.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Доставки вебхука(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, delivered или dead",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "До 100, по умолчанию 50",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}/test": {
+            "post": {
+                "description": "Сразу отправляет событие ping (даже выключенному вебхуку) и возвращает доставку с журналом.\nНеудачный ping не повторяется.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вебхуки"
+                ],
+                "summary": "Тестовое событие(secure, admin)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDelivery"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/bot/accounts": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Бот"
+                ],
+                "summary": "Привязанные мессенджеры(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BotAccount"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/bot/accounts/{platform}/{id}": {
+            "delete": {
+                "description": "Бот перестаёт принимать команды из аккаунта и присылать в него уведомления.",
+                "tags": [
+                    "Бот"
+                ],
+                "summary": "Отвязка мессенджера(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Platform, e.g. telegram",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Account ID on the platform",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Account not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/bot/link-code": {
+            "post": {
+                "description": "Одноразовый код на 10 минут: пользователь отправляет боту /link КОД. В links - ссылки,\nоткрывающие бота с уже введённым кодом, по платформам. Новый код отменяет прежний.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Бот"
+                ],
+                "summary": "Код привязки мессенджера(secure)",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.BotLinkCode"
+                        }
+                    },
+                    "404": {
+                        "description": "No bot is configured",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/bot/{platform}/webhook": {
+            "post": {
+                "description": "Сюда мессенджер присылает сообщения боту, если бот работает через вебхук, а не long polling.\nTelegram подписывает запросы заголовком X-Telegram-Bot-Api-Secret-Token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Бот"
+                ],
+                "summary": "Обновления от мессенджера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Platform, e.g. telegram",
+                        "name": "platform",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "Not from the messenger",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown platform",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/campaigns": {
+            "get": {
+                "description": "Все кампании, начинающиеся позже - первыми. state - upcoming, open или closed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Кампании"
+                ],
+                "summary": "Кампании(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Campaign"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/campaigns/{id}": {
+            "get": {
+                "description": "Кампания, ее статистика (идеи, их авторы, проголосовавшие, голоса, комментарии) и первые\nместа рейтинга.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Кампании"
+                ],
+                "summary": "Страница кампании(secure)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CampaignPage"
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/campaigns/{id}/leaderboard": {
+            "get": {
+                "description": "Идеи кампании по убыванию score - лайков за вычетом дизлайков, при равенстве - по лайкам и\nзатем по дате. Идеи с равным score делят место.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Кампании"
+                ],
+                "summary": "Рейтинг идей кампании(secure)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Places (1-100, default 10)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LeaderboardEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/comments": {
+            "post": {
+                "description": "Вставляет коммент и возвращает его. С parentUID это ответ на комментарий той же идеи,\nвложенность ограничена 8 уровнями. Упоминания - как у POST /ideas.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Вставка комментария(secure)",
+                "parameters": [
+                    {
+                        "description": "Comment data",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.InsertCommentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea or parent comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create comment",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/comments/{uid}": {
+            "delete": {
+                "description": "Заменяет комментарий \"надгробием\": текст очищается, DeletedAt заполняется, ответы остаются. Доступно автору и модераторам.\nDELETE /replies/{uid} - то же самое для старых клиентов.",
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Удаление комментария(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comment UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Not the author",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Меняет текст комментария, старый текст сохраняется в истории правок. Доступно автору и модераторам.\nУпоминания разбираются заново, уведомление получают только новые упомянутые.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Редактирование комментария(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comment UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New text",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EditCommentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the author",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Comment is deleted",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/comments/{uid}/children": {
+            "get": {
+                "description": "Страница прямых ответов на комментарий, старые сначала, у каждого - его ответы на 2 уровня вглубь.\nПагинация как у /ideas/{uid}/comments.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Ответы на комментарий(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comment UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "NextCursor предыдущей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (1-100, по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommentPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/comments/{uid}/revisions": {
+            "get": {
+                "description": "Предыдущие версии текста, от старых к новым. GET /replies/{uid}/revisions - то же самое для старых клиентов.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "История правок комментария(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comment UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Revision"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Server-Sent Events. Всегда приходят события ленты идей (idea.created, idea.updated, idea.votes)\nи уведомления текущего пользователя (notification); comment.added, idea.updated и idea.votes\nидей из параметров idea - тоже. Имя SSE-события - тип, data - JSON (null, если событие было\nслишком большим для рассылки между репликами - тогда объект перечитывают).\nEventSource не умеет заголовки, поэтому вместо JWT передаётся одноразовый билет из\nPOST /events/ticket; при переподключении нужен новый билет.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "События"
+                ],
+                "summary": "Поток событий(secure)",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "UID идей, до 50",
+                        "name": "idea",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Одноразовый билет",
+                        "name": "ticket",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid, used or expired ticket",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/events/ticket": {
+            "post": {
+                "description": "Одноразовый билет на минуту для GET /events?ticket=: JWT не попадает в адрес и журналы.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "События"
+                ],
+                "summary": "Билет для потока событий(secure)",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.StreamTicket"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas": {
+            "get": {
+                "description": "Возвращает все идеи списков без комментариев\\ответов. С параметром q - полнотекстовый\nпоиск по названию и тексту: все слова запроса должны встречаться целиком, без учета регистра.\nС параметром sort - по рейтингу: hot (голоса, комментарии и просмотры с поправкой на возраст),\ntop (лайки минус дизлайки), controversial (много голосов поровну), rising (активность за сутки\nу молодых идей). Рейтинги пересчитываются при голосах и комментариях и раз в 10 минут.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Все идеи(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max search results (1-100, default 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hot, top, controversial или rising",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Idea"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get ideas",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Вставляет идею, и возвращает ее со всеми заполненными полями. Упоминания @имя.фамилия\n(без учета регистра) или @uid в тексте сохраняются, упомянутые получают уведомление; в Mentions -\nпозиции упоминаний в тексте (Start, End - в символах, End не включается) и профили упомянутых.\nС campaign идея подается в кампанию: та должна идти, категория - входить в ее категории, а\nдолжность автора - в ее должности (пустые списки разрешают любые).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Вставка новой идеи(secure)",
+                "parameters": [
+                    {
+                        "description": "Idea data",
+                        "name": "idea",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.InsertIdeaRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Idea"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Position isn't eligible for the campaign",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Campaign not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Campaign isn't open",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create idea",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/categories": {
+            "get": {
+                "description": "Ручка категорий идей, в теории дергается один раз при первой загрузке страницы,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Категории идей(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.IdeaCategory"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/statuses": {
+            "get": {
+                "description": "Ручка статусов идей, в теории дергается один раз при первой загрузке страницы,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Статусы идей(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.IdeaStatus"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/viewed": {
+            "get": {
+                "description": "Идеи, которые открывал текущий пользователь, последние сначала, с LastViewedAt и UnreadComments.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Недавно просмотренные идеи(secure)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "До 100, по умолчанию 20",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Idea"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}": {
+            "get": {
+                "description": "Возвращает идею по UID с первой страницей комментариев верхнего уровня, у каждого - ответы\nна 2 уровня вглубь (Children). Если у комментария ChildCount больше, чем загружено в Children,\nостальные ответы берутся из /comments/{uid}/children; следующая страница комментариев -\n/ideas/{uid}/comments?cursor=NextCursor. У идеи и комментариев заполнен AuthorProfile\n(имя, фамилия, аватар автора), запрашивать /users/{uid} не нужно. Reactions - счетчики\nэмодзи-реакций, Mine - реакция текущего пользователя.\nЗапрос засчитывается как просмотр (ViewCount): от одного пользователя не чаще раза в сутки, просмотры\nавтора не считаются. LastViewedAt - предыдущий визит пользователя, UnreadComments - комментарии\nдругих пользователей, появившиеся с тех пор (так же заполняются в списках идей).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Конкретная идея(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.IdeaComment"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get idea by UID",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}/comments": {
+            "get": {
+                "description": "Страница комментариев верхнего уровня, старые сначала, у каждого - ответы на 2 уровня вглубь.\nДля следующей страницы передайте NextCursor в cursor; пустой NextCursor - страниц больше нет.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Комментарии идеи(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "NextCursor предыдущей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (1-100, по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CommentPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}/dislike": {
+            "post": {
+                "description": "Увеличение дизлайков. За идеи кампании голосуют до ее окончания.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Увеличение дизлайков",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Voting in the campaign has closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to increase dislikes",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}/like": {
+            "post": {
+                "description": "Увеличение лайков. За идеи кампании голосуют до ее окончания.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Увеличение лайков",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Voting in the campaign has closed",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to increase likes",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}/reactions/{emoji}": {
+            "put": {
+                "description": "Ставит реакцию текущего пользователя, повторный вызов ничего не меняет. Эмодзи - из GET /reactions,\nв пути URL-кодированный. Возвращает счетчики реакций цели. Для комментариев -\nPUT /comments/{uid}/reactions/{emoji}, на удаленный комментарий реакцию поставить нельзя.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Реакции"
+                ],
+                "summary": "Поставить реакцию(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Emoji",
+                        "name": "emoji",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ReactionCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown reaction",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Убирает реакцию текущего пользователя, если ее нет - ничего не меняет. Возвращает счетчики\nреакций цели. Для комментариев - DELETE /comments/{uid}/reactions/{emoji}.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Реакции"
+                ],
+                "summary": "Убрать реакцию(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Emoji",
+                        "name": "emoji",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ReactionCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}/report": {
+            "get": {
+                "description": "Документ для комиссии: идея с автором, категорией, статусом и кампанией, история смены статусов,\nголоса за и против и все обсуждение, ответы с отступом под комментарием. Время - в UTC.\nОткрытие отчета не засчитывается как просмотр.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Отчет по идее в PDF(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ideas/{uid}/status": {
+            "patch": {
+                "description": "Переводит идею в другой статус, доступно только модераторам. Автор идеи получает уведомление.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Идеи"
+                ],
+                "summary": "Смена статуса идеи(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Idea UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChangeIdeaStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Idea"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not a moderator",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Idea not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invitations/accept": {
+            "post": {
+                "description": "Задает пароль импортированного пользователя по токену из ссылки в приглашении, после этого\nон входит через /login. Ссылка одноразовая.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Авторизация\\Регистрация"
+                ],
+                "summary": "Принятие приглашения",
+                "parameters": [
+                    {
+                        "description": "Token and password",
+                        "name": "acceptInvitationRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AcceptInvitationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: ok",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Invitation not found or expired",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Аутентификация, возвращает jwt токен, который прикладывается ко всем (secure) рутам.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Авторизация\\Регистрация"
+                ],
+                "summary": "Аутентификация",
+                "parameters": [
+                    {
+                        "description": "Login data",
+                        "name": "loginRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JWT token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid email or password",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to login",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications": {
+            "get": {
+                "description": "Уведомления текущего пользователя, новые сначала: comment - комментарий к вашей идее, reply - ответ\nна ваш комментарий, mention - упоминание, vote - голос за вашу идею (Detail - like/dislike), status -\nсмена статуса вашей идеи (Detail - id нового статуса). Unread - число всех непрочитанных.\nДля следующей страницы передайте NextCursor в cursor.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Уведомления(secure)",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только непрочитанные",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "NextCursor предыдущей страницы",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (1-100, по умолчанию 20)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotificationPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/email": {
+            "get": {
+                "description": "Язык писем (ru, en), письма об упоминаниях и смене статуса своих идей (immediate)\nи дайджест популярных идей (off, daily, weekly). По умолчанию ru, immediate и weekly.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Настройки писем(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailSettings"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Выключенные в настройках уведомлений типы не приходят и письмом.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Изменение настроек писем(secure)",
+                "parameters": [
+                    {
+                        "description": "Settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateEmailSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmailSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/preferences": {
+            "get": {
+                "description": "Включен ли каждый тип уведомлений в приложении и мессенджере, по умолчанию включены все. Письма настраиваются отдельно: /notifications/email.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Настройки уведомлений(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.NotificationPreference"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Включает или выключает перечисленные типы в приложении и мессенджере, остальные не меняются. Письма об упоминаниях и статусах не зависят от этих настроек, см. /notifications/email. Возвращает все настройки.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Изменение настроек уведомлений(secure)",
+                "parameters": [
+                    {
+                        "description": "Preferences",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateNotificationPreferencesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.NotificationPreference"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/read-all": {
+            "post": {
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Прочитать все уведомления(secure)",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/read": {
+            "post": {
+                "tags": [
+                    "Уведомления"
+                ],
+                "summary": "Прочитать уведомление(secure)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Notification ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Notification not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reactions": {
+            "get": {
+                "description": "Эмодзи, которыми можно реагировать на идеи и комментарии, в порядке отображения.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Реакции"
+                ],
+                "summary": "Доступные реакции(secure)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Регистрация - будет только в админке",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Авторизация\\Регистрация"
+                ],
+                "summary": "Регистрация",
+                "parameters": [
+                    {
+                        "description": "Register data",
+                        "name": "registerRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: ok",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User already exists",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to register",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/replies": {
+            "post": {
+                "security": [
+                    {
+                        "JWTAuth": []
+                    }
+                ],
+                "description": "Устаревший вариант POST /comments с parentUID: вставляет ответ на комментарий и возвращает его",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Вставка ответа",
+                "parameters": [
+                    {
+                        "description": "Reply data",
+                        "name": "reply",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.InsertReplyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Comment not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create reply",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/replies/{uid}": {
+            "patch": {
+                "description": "Устаревший вариант PATCH /comments/{uid} с полем replyText.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Вставка комментариев\\ответов"
+                ],
+                "summary": "Редактирование ответа(secure)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Reply UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New text",
+                        "name": "reply",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EditReplyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not the author",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Reply not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Reply is deleted",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/pfp": {
+            "post": {
+                "description": "Загрузка новой аватарки для юзера.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Загрузка PFP",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Profile picture file",
+                        "name": "profile_picture",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "url to uploaded picture",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "No file uploaded or bad request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to upload file",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/positions": {
+            "get": {
+                "description": "Ручка позиции сотрудников",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Позиции сотрудников",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UserPosition"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get positions",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{uid}": {
+            "get": {
+                "description": "Возвращает данные пользователя по UID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователи"
+                ],
+                "summary": "Получение юзера по UID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User UID",
+                        "name": "uid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get user",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AcceptInvitationRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8
+                },
+                "token": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
+        "models.AnalyticsGroup": {
+            "type": "string",
+            "enum": [
+                "day",
+                "week",
+                "month"
+            ],
+            "x-enum-varnames": [
+                "GroupDay",
+                "GroupWeek",
+                "GroupMonth"
+            ]
+        },
+        "models.AnalyticsRange": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuthorProfile": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "pfpURL": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BotAccount": {
+            "type": "object",
+            "properties": {
+                "externalId": {
+                    "type": "string"
+                },
+                "linkedAt": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BotLinkCode": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "links": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Campaign": {
+            "type": "object",
+            "properties": {
+                "categoryIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "endsAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "positionIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "startsAt": {
+                    "type": "string"
+                },
+                "state": {
+                    "description": "filled for API responses",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.CampaignState"
+                        }
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CampaignPage": {
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "$ref": "#/definitions/models.Campaign"
+                },
+                "leaderboard": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LeaderboardEntry"
+                    }
+                },
+                "stats": {
+                    "$ref": "#/definitions/models.CampaignStats"
+                }
+            }
+        },
+        "models.CampaignRequest": {
+            "type": "object",
+            "required": [
+                "endsAt",
+                "startsAt",
+                "title"
+            ],
+            "properties": {
+                "categoryIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 5000
+                },
+                "endsAt": {
+                    "type": "string"
+                },
+                "positionIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "startsAt": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "models.CampaignState": {
+            "type": "string",
+            "enum": [
+                "upcoming",
+                "open",
+                "closed"
+            ],
+            "x-enum-varnames": [
+                "CampaignUpcoming",
+                "CampaignOpen",
+                "CampaignClosed"
+            ]
+        },
+        "models.CampaignStats": {
+            "type": "object",
+            "properties": {
+                "authors": {
+                    "type": "integer"
+                },
+                "comments": {
+                    "type": "integer"
+                },
+                "dislikes": {
+                    "type": "integer"
+                },
+                "ideas": {
+                    "type": "integer"
+                },
+                "likes": {
+                    "type": "integer"
+                },
+                "voters": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ChangeIdeaStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Comment": {
+            "type": "object",
+            "properties": {
+                "authorID": {
+                    "type": "string"
+                },
+                "authorProfile": {
+                    "description": "filled only with the discussion",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthorProfile"
+                        }
+                    ]
+                },
+                "childCount": {
+                    "description": "direct replies, filled only with the discussion",
+                    "type": "integer"
+                },
+                "commentText": {
+                    "type": "string"
+                },
+                "commentUID": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "depth": {
+                    "type": "integer"
+                },
+                "editedAt": {
+                    "type": "string"
+                },
+                "ideaUID": {
+                    "type": "string"
+                },
+                "mentions": {
+                    "description": "filled only with the discussion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Mention"
+                    }
+                },
+                "parentUID": {
+                    "type": "string"
+                },
+                "reactions": {
+                    "description": "filled only with the discussion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReactionCount"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommentPage": {
+            "type": "object",
+            "properties": {
+                "comments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CommentThread"
+                    }
+                },
+                "nextCursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CommentThread": {
+            "type": "object",
+            "properties": {
+                "authorID": {
+                    "type": "string"
+                },
+                "authorProfile": {
+                    "description": "filled only with the discussion",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthorProfile"
+                        }
+                    ]
+                },
+                "childCount": {
+                    "description": "direct replies, filled only with the discussion",
+                    "type": "integer"
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CommentThread"
+                    }
+                },
+                "commentText": {
+                    "type": "string"
+                },
+                "commentUID": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "depth": {
+                    "type": "integer"
+                },
+                "editedAt": {
+                    "type": "string"
+                },
+                "ideaUID": {
+                    "type": "string"
+                },
+                "mentions": {
+                    "description": "filled only with the discussion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Mention"
+                    }
+                },
+                "parentUID": {
+                    "type": "string"
+                },
+                "reactions": {
+                    "description": "filled only with the discussion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReactionCount"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Contributor": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.AuthorProfile"
+                }
+            }
+        },
+        "models.ContributorReport": {
+            "type": "object",
+            "properties": {
+                "authors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Contributor"
+                    }
+                },
+                "commenters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Contributor"
+                    }
+                },
+                "range": {
+                    "$ref": "#/definitions/models.AnalyticsRange"
+                }
+            }
+        },
+        "models.DecisionReport": {
+            "type": "object",
+            "properties": {
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DecisionStats"
+                    }
+                },
+                "range": {
+                    "$ref": "#/definitions/models.AnalyticsRange"
+                },
+                "total": {
+                    "$ref": "#/definitions/models.DecisionStats"
+                }
+            }
+        },
+        "models.DecisionStats": {
+            "type": "object",
+            "properties": {
+                "approvalRate": {
+                    "type": "number"
+                },
+                "approved": {
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "categoryId": {
+                    "type": "integer"
+                },
+                "ideas": {
+                    "type": "integer"
+                },
+                "medianDecisionHours": {
+                    "type": "number"
+                },
+                "rejected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DeliveryState": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "delivered",
+                "dead"
+            ],
+            "x-enum-varnames": [
+                "DeliveryPending",
+                "DeliveryDelivered",
+                "DeliveryDead"
+            ]
+        },
+        "models.DigestFrequency": {
+            "type": "string",
+            "enum": [
+                "off",
+                "daily",
+                "weekly"
+            ],
+            "x-enum-varnames": [
+                "DigestOff",
+                "DigestDaily",
+                "DigestWeekly"
+            ]
+        },
+        "models.EditCommentRequest": {
+            "type": "object",
+            "required": [
+                "commentText"
+            ],
+            "properties": {
+                "commentText": {
+                    "type": "string",
+                    "maxLength": 5000
+                }
+            }
+        },
+        "models.EditReplyRequest": {
+            "type": "object",
+            "required": [
+                "replyText"
+            ],
+            "properties": {
+                "replyText": {
+                    "type": "string",
+                    "maxLength": 5000
+                }
+            }
+        },
+        "models.EmailSettings": {
+            "type": "object",
+            "properties": {
+                "digest": {
+                    "$ref": "#/definitions/models.DigestFrequency"
+                },
+                "immediate": {
+                    "type": "boolean"
+                },
+                "language": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EngagementReport": {
+            "type": "object",
+            "properties": {
+                "positions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PositionEngagement"
+                    }
+                },
+                "range": {
+                    "$ref": "#/definitions/models.AnalyticsRange"
+                },
+                "total": {
+                    "$ref": "#/definitions/models.PositionEngagement"
+                }
+            }
+        },
+        "models.EventType": {
+            "type": "string",
+            "enum": [
+                "idea.created",
+                "idea.updated",
+                "idea.votes",
+                "comment.added",
+                "notification",
+                "ping"
+            ],
+            "x-enum-comments": {
+                "EventCommentAdded": "Data is the Comment",
+                "EventIdeaCreated": "Data is the Idea",
+                "EventIdeaUpdated": "Data is the Idea",
+                "EventNotification": "Data is NotificationEvent",
+                "EventVotes": "Data is VoteCounts"
+            },
+            "x-enum-varnames": [
+                "EventIdeaCreated",
+                "EventIdeaUpdated",
+                "EventVotes",
+                "EventCommentAdded",
+                "EventNotification",
+                "EventPing"
+            ]
+        },
+        "models.Idea": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "authorProfile": {
+                    "description": "filled only with the discussion",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthorProfile"
+                        }
+                    ]
+                },
+                "campaignID": {
+                    "type": "integer"
+                },
+                "categoryID": {
+                    "type": "integer"
+                },
+                "creationDate": {
+                    "type": "string"
+                },
+                "dislikeCount": {
+                    "type": "integer"
+                },
+                "ideaUID": {
+                    "type": "string"
+                },
+                "lastViewedAt": {
+                    "description": "LastViewedAt - the viewer's previous visit, UnreadComments - comments\nof others posted since; filled for API responses of visited ideas",
+                    "type": "string"
+                },
+                "likeCount": {
+                    "type": "integer"
+                },
+                "mentions": {
+                    "description": "filled for API responses",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Mention"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reactions": {
+                    "description": "filled for API responses",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReactionCount"
+                    }
+                },
+                "statusID": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "unreadComments": {
+                    "type": "integer"
+                },
+                "viewCount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.IdeaCategory": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.IdeaComment": {
+            "type": "object",
+            "properties": {
+                "comments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CommentThread"
+                    }
+                },
+                "idea": {
+                    "$ref": "#/definitions/models.Idea"
+                },
+                "nextCursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.IdeaStatus": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ImportReport": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "type": "boolean"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportRowError"
+                    }
+                },
+                "newPositions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportedUser"
+                    }
+                }
+            }
+        },
+        "models.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportedUser": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "hireDate": {
+                    "type": "string"
+                },
+                "invited": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.InsertCommentRequest": {
+            "type": "object",
+            "required": [
+                "commentText",
+                "ideaUID"
+            ],
+            "properties": {
+                "commentText": {
+                    "type": "string",
+                    "maxLength": 5000
+                },
+                "ideaUID": {
+                    "type": "string"
+                },
+                "parentUID": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.InsertIdeaRequest": {
+            "type": "object",
+            "required": [
+                "category",
+                "name",
+                "status",
+                "text"
+            ],
+            "properties": {
+                "author": {
+                    "description": "ignored, taken from the token",
+                    "type": "string"
+                },
+                "campaign": {
+                    "description": "submits the idea into the campaign",
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string",
+                    "maxLength": 10000
+                }
+            }
+        },
+        "models.InsertReplyRequest": {
+            "type": "object",
+            "required": [
+                "commentUID",
+                "replyText"
+            ],
+            "properties": {
+                "commentUID": {
+                    "type": "string"
+                },
+                "replyText": {
+                    "type": "string",
+                    "maxLength": 5000
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "lastRun": {
+                    "$ref": "#/definitions/models.JobRun"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nextRunAt": {
+                    "type": "string"
+                },
+                "schedule": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobRun": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "job": {
+                    "type": "string"
+                },
+                "node": {
+                    "type": "string"
+                },
+                "scheduledAt": {
+                    "type": "string"
+                },
+                "source": {
+                    "$ref": "#/definitions/models.JobSource"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.JobStatus"
+                },
+                "triggeredBy": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobSource": {
+            "type": "string",
+            "enum": [
+                "schedule",
+                "manual"
+            ],
+            "x-enum-varnames": [
+                "JobScheduled",
+                "JobManual"
+            ]
+        },
+        "models.JobStatus": {
+            "type": "string",
+            "enum": [
+                "running",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "JobRunning",
+                "JobSucceeded",
+                "JobFailed"
+            ]
+        },
+        "models.LeaderboardEntry": {
+            "type": "object",
+            "properties": {
+                "idea": {
+                    "$ref": "#/definitions/models.Idea"
+                },
+                "place": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "integer"
+                }
             }
-        }
-    },
-    "definitions": {
-        "models.Comment": {
+        },
+        "models.LoginRequest": {
             "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
             "properties": {
-                "authorID": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 35
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 72
+                }
+            }
+        },
+        "models.Mention": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "user": {
+                    "description": "filled for API responses",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthorProfile"
+                        }
+                    ]
+                },
+                "userUID": {
                     "type": "string"
+                }
+            }
+        },
+        "models.Notification": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "filled for API responses",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthorProfile"
+                        }
+                    ]
                 },
-                "commentText": {
+                "actorUID": {
                     "type": "string"
                 },
                 "commentUID": {
                     "type": "string"
                 },
+                "createdAt": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ideaName": {
+                    "description": "filled for API responses",
+                    "type": "string"
+                },
                 "ideaUID": {
                     "type": "string"
                 },
-                "timestamp": {
+                "readAt": {
                     "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/models.NotificationType"
                 }
             }
         },
-        "models.CommentReply": {
+        "models.NotificationPage": {
             "type": "object",
             "properties": {
-                "comment": {
-                    "$ref": "#/definitions/models.Comment"
+                "nextCursor": {
+                    "type": "string"
                 },
-                "replies": {
+                "notifications": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Reply"
+                        "$ref": "#/definitions/models.Notification"
                     }
+                },
+                "unread": {
+                    "type": "integer"
                 }
             }
         },
-        "models.Idea": {
+        "models.NotificationPreference": {
             "type": "object",
+            "required": [
+                "type"
+            ],
             "properties": {
-                "author": {
-                    "type": "string"
+                "enabled": {
+                    "type": "boolean"
                 },
-                "categoryID": {
+                "type": {
+                    "enum": [
+                        "comment",
+                        "reply",
+                        "mention",
+                        "vote",
+                        "status"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.NotificationType"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.NotificationType": {
+            "type": "string",
+            "enum": [
+                "comment",
+                "reply",
+                "mention",
+                "vote",
+                "status"
+            ],
+            "x-enum-comments": {
+                "NotificationComment": "comment on the user's idea",
+                "NotificationMention": "the user is mentioned in an idea or a comment",
+                "NotificationReply": "reply to the user's comment",
+                "NotificationStatus": "status of the user's idea changed",
+                "NotificationVote": "vote for the user's idea"
+            },
+            "x-enum-varnames": [
+                "NotificationComment",
+                "NotificationReply",
+                "NotificationMention",
+                "NotificationVote",
+                "NotificationStatus"
+            ]
+        },
+        "models.PeriodCount": {
+            "type": "object",
+            "properties": {
+                "ideas": {
                     "type": "integer"
                 },
-                "creationDate": {
+                "period": {
                     "type": "string"
+                }
+            }
+        },
+        "models.PositionEngagement": {
+            "type": "object",
+            "properties": {
+                "authors": {
+                    "type": "integer"
                 },
-                "dislikeCount": {
+                "commenters": {
                     "type": "integer"
                 },
-                "ideaUID": {
-                    "type": "string"
+                "comments": {
+                    "type": "integer"
                 },
-                "likeCount": {
+                "ideas": {
                     "type": "integer"
                 },
-                "name": {
+                "members": {
+                    "type": "integer"
+                },
+                "participation": {
+                    "type": "number"
+                },
+                "position": {
                     "type": "string"
                 },
-                "statusID": {
+                "positionId": {
                     "type": "integer"
                 },
-                "text": {
-                    "type": "string"
+                "voters": {
+                    "type": "integer"
+                },
+                "votes": {
+                    "type": "integer"
                 }
             }
         },
-        "models.IdeaCategory": {
+        "models.ReactionCount": {
             "type": "object",
             "properties": {
-                "id": {
+                "count": {
                     "type": "integer"
                 },
-                "name": {
+                "emoji": {
                     "type": "string"
+                },
+                "mine": {
+                    "type": "boolean"
                 }
             }
         },
-        "models.IdeaComment": {
+        "models.RegisterRequest": {
             "type": "object",
+            "required": [
+                "email",
+                "name",
+                "password",
+                "positionID",
+                "surname"
+            ],
             "properties": {
-                "commentReplies": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.CommentReply"
-                    }
+                "email": {
+                    "type": "string",
+                    "maxLength": 35
                 },
-                "idea": {
-                    "$ref": "#/definitions/models.Idea"
+                "name": {
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "password": {
+                    "description": "bcrypt ignores bytes after 72",
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 8
+                },
+                "positionID": {
+                    "type": "integer"
+                },
+                "surname": {
+                    "type": "string",
+                    "maxLength": 20
                 }
             }
         },
-        "models.IdeaStatus": {
+        "models.Revision": {
             "type": "object",
             "properties": {
+                "editedAt": {
+                    "description": "when Text was replaced",
+                    "type": "string"
+                },
+                "editorUID": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
+                "targetUID": {
+                    "description": "comment uid",
+                    "type": "string"
+                },
+                "text": {
                     "type": "string"
                 }
             }
         },
-        "models.InsertCommentRequest": {
+        "models.StreamTicket": {
             "type": "object",
             "properties": {
-                "commentText": {
+                "expiresAt": {
                     "type": "string"
                 },
-                "ideaUID": {
+                "ticket": {
                     "type": "string"
                 }
             }
         },
-        "models.InsertIdeaRequest": {
+        "models.SubmissionReport": {
             "type": "object",
             "properties": {
-                "author": {
-                    "type": "string"
+                "group": {
+                    "$ref": "#/definitions/models.AnalyticsGroup"
                 },
-                "category": {
-                    "type": "integer"
+                "periods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PeriodCount"
+                    }
                 },
-                "name": {
-                    "type": "string"
+                "range": {
+                    "$ref": "#/definitions/models.AnalyticsRange"
                 },
-                "status": {
+                "total": {
                     "type": "integer"
-                },
-                "text": {
-                    "type": "string"
                 }
             }
         },
-        "models.InsertReplyRequest": {
+        "models.UpdateEmailSettingsRequest": {
             "type": "object",
+            "required": [
+                "digest",
+                "language"
+            ],
             "properties": {
-                "commentUID": {
-                    "type": "string"
+                "digest": {
+                    "enum": [
+                        "off",
+                        "daily",
+                        "weekly"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.DigestFrequency"
+                        }
+                    ]
                 },
-                "replyText": {
-                    "type": "string"
+                "immediate": {
+                    "type": "boolean"
+                },
+                "language": {
+                    "type": "string",
+                    "enum": [
+                        "ru",
+                        "en"
+                    ]
                 }
             }
         },
-        "models.LoginRequest": {
+        "models.UpdateNotificationPreferencesRequest": {
             "type": "object",
+            "required": [
+                "preferences"
+            ],
             "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
+                "preferences": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/models.NotificationPreference"
+                    }
                 }
             }
         },
-        "models.RegisterRequest": {
+        "models.User": {
             "type": "object",
             "properties": {
                 "email": {
                     "type": "string"
                 },
+                "hireDate": {
+                    "type": "string"
+                },
+                "isAdmin": {
+                    "type": "boolean"
+                },
+                "lastOnline": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
                 "password": {
                     "type": "string"
                 },
+                "pfpURL": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
                 "positionID": {
                     "type": "integer"
                 },
+                "reAuth": {
+                    "type": "boolean"
+                },
                 "surname": {
                     "type": "string"
+                },
+                "uid": {
+                    "type": "string"
                 }
             }
         },
-        "models.Reply": {
+        "models.UserPosition": {
             "type": "object",
             "properties": {
-                "authorID": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
                 },
-                "commentUID": {
+                "createdAt": {
                     "type": "string"
                 },
-                "replyText": {
+                "createdBy": {
                     "type": "string"
                 },
-                "replyUID": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.EventType"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
                     "type": "string"
                 },
-                "timestamp": {
+                "url": {
                     "type": "string"
                 }
             }
         },
-        "models.User": {
+        "models.WebhookAttempt": {
             "type": "object",
             "properties": {
-                "email": {
+                "attemptedAt": {
                     "type": "string"
                 },
-                "hireDate": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "error": {
                     "type": "string"
                 },
-                "isAdmin": {
-                    "type": "boolean"
+                "id": {
+                    "type": "integer"
                 },
-                "lastOnline": {
+                "responseCode": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
                     "type": "string"
                 },
-                "name": {
+                "deliveredAt": {
                     "type": "string"
                 },
-                "password": {
+                "event": {
+                    "$ref": "#/definitions/models.EventType"
+                },
+                "eventId": {
                     "type": "string"
                 },
-                "pfpURL": {
+                "id": {
+                    "type": "integer"
+                },
+                "lastError": {
                     "type": "string"
                 },
-                "phone": {
+                "log": {
+                    "description": "filled for a single delivery",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookAttempt"
+                    }
+                },
+                "nextAttemptAt": {
                     "type": "string"
                 },
-                "positionID": {
+                "payload": {
+                    "type": "string"
+                },
+                "responseCode": {
                     "type": "integer"
                 },
-                "reAuth": {
+                "state": {
+                    "$ref": "#/definitions/models.DeliveryState"
+                },
+                "webhookId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookRequest": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "active": {
                     "type": "boolean"
                 },
-                "surname": {
-                    "type": "string"
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/models.EventType"
+                    }
                 },
-                "uid": {
-                    "type": "string"
+                "secret": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 16
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 2048
                 }
             }
         },
-        "models.UserPosition": {
+        "response.ErrorBody": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "code": {
+                    "type": "string"
                 },
-                "name": {
+                "details": {},
+                "message": {
+                    "type": "string"
+                },
+                "request_id": {
                     "type": "string"
                 }
             }
+        },
+        "response.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/response.ErrorBody"
+                }
+            }
         }
     }
 }`
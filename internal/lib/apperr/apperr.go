@@ -0,0 +1,76 @@
+package apperr
+
+import (
+	"errors"
+)
+
+// Sentinel kinds of service errors. Services return *Error values that wrap
+// one of them, so callers can branch with errors.Is without caring about
+// the exact message.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrValidation   = errors.New("validation failed")
+	ErrForbidden    = errors.New("forbidden")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrTooLarge     = errors.New("payload too large")
+)
+
+// Error - typed service error, carries a client-safe message, optional details
+// and the underlying cause (never shown to the client)
+type Error struct {
+	Kind    error
+	Message string
+	Details any
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Kind, e.Cause}
+	}
+	return []error{e.Kind}
+}
+
+// WithCause attaches the underlying error, it is logged but not rendered
+func (e *Error) WithCause(err error) *Error {
+	e.Cause = err
+	return e
+}
+
+// WithDetails attaches structured details rendered to the client
+func (e *Error) WithDetails(details any) *Error {
+	e.Details = details
+	return e
+}
+
+func NotFound(msg string) *Error {
+	return &Error{Kind: ErrNotFound, Message: msg}
+}
+
+func Validation(msg string) *Error {
+	return &Error{Kind: ErrValidation, Message: msg}
+}
+
+func Forbidden(msg string) *Error {
+	return &Error{Kind: ErrForbidden, Message: msg}
+}
+
+func Conflict(msg string) *Error {
+	return &Error{Kind: ErrConflict, Message: msg}
+}
+
+func Unauthorized(msg string) *Error {
+	return &Error{Kind: ErrUnauthorized, Message: msg}
+}
+
+func TooLarge(msg string) *Error {
+	return &Error{Kind: ErrTooLarge, Message: msg}
+}
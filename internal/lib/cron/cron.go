@@ -0,0 +1,218 @@
+// Package cron parses cron expressions and finds when they fire next.
+//
+// An expression has five fields: minute (0-59), hour (0-23), day of month
+// (1-31), month (1-12) and day of week (0-6, 0 and 7 are Sunday). A field is
+// "*", a value, a range "a-b" or a comma separated list of them, each
+// optionally stepped with "/n". As in classic cron, when both days are
+// restricted a time matches either of them. Descriptors stand for common
+// expressions: @yearly (@annually), @monthly, @weekly, @daily (@midnight) and
+// @hourly; "@every 30s" fires on multiples of the duration since the zero
+// time, so every process agrees on the moments.
+package cron
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when an expression fires
+type Schedule interface {
+	// Next returns the first moment strictly after t
+	Next(t time.Time) time.Time
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses an expression or a descriptor
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("cron %q: interval under a second", spec)
+		}
+		return interval(d), nil
+	}
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields, got %d", spec, len(fields))
+	}
+	var s fieldSchedule
+	for k, b := range bounds {
+		set, err := parseField(fields[k], b)
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %s: %w", spec, b.name, err)
+		}
+		*s.field(k) = set
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domAny = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	s.dowAny = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+	return s, nil
+}
+
+// MustParse is Parse for expressions known to be valid
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+type bound struct {
+	name     string
+	min, max int
+}
+
+var bounds = []bound{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// parseField returns the field as a bit set of allowed values
+func parseField(field string, b bound) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = value(from, b); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = value(to, b); err != nil {
+					return 0, err
+				}
+			} else if stepped {
+				// "5/15" runs from 5 to the end
+				hi = b.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func value(s string, b bound) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > b.max {
+		return 0, fmt.Errorf("%q is not in %d-%d", s, b.min, b.max)
+	}
+	return v, nil
+}
+
+// fieldSchedule - parsed expression, every field is a bit set of values
+type fieldSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+func (s *fieldSchedule) field(k int) *uint64 {
+	return [...]*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}[k]
+}
+
+// searchYears bounds the search for expressions that never fire, such as
+// February 30th
+const searchYears = 5
+
+// Next moves forward field by field, from months down to minutes, in the
+// location of t. Times skipped by a DST change never fire.
+func (s fieldSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(searchYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !s.dayMatches(t):
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case s.hour&(1<<t.Hour()) == 0:
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+		case s.minute&(1<<t.Minute()) == 0:
+			// the next allowed minute of this hour, or the next hour
+			rest := s.minute >> (t.Minute() + 1) << (t.Minute() + 1)
+			if rest == 0 {
+				t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+				continue
+			}
+			t = t.Add(time.Duration(bits.TrailingZeros64(rest)-t.Minute()) * time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// forward returns next unless a repeated hour of a DST change put it before
+// t, then the minute after t
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+func (s fieldSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// interval - "@every" schedule
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(i)).Add(time.Duration(i))
+}
@@ -0,0 +1,86 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2026, 7, 1, 9, 17, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 7, 1, 9, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 7, 1, 9, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 7, 1, 9, 25, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 7, 1, 10, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2026, 7, 2, 3, 30, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 7, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 8,20 * * 1-5", time.Date(2026, 7, 1, 20, 0, 0, 0, time.UTC)},
+		{"0 9 * * 6,7", time.Date(2026, 7, 4, 9, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day matches when both are restricted
+		{"0 12 15 * 5", time.Date(2026, 7, 3, 12, 0, 0, 0, time.UTC)},
+		{"@every 10m", time.Date(2026, 7, 1, 9, 20, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2026, 7, 1, 9, 18, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+func TestNext_Never(t *testing.T) {
+	s := MustParse("0 0 30 2 *")
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestNext_Location(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	s := MustParse("0 9 * * *")
+	got := s.Next(time.Date(2026, 7, 1, 9, 0, 0, 0, moscow))
+	assert.Equal(t, time.Date(2026, 7, 2, 9, 0, 0, 0, moscow), got)
+}
+
+func TestNext_DST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	// 2:30 doesn't exist on March 29th 2026, clocks go from 2:00 to 3:00
+	s := MustParse("30 2 * * *")
+	got := s.Next(time.Date(2026, 3, 28, 12, 0, 0, 0, berlin))
+	assert.Equal(t, time.Date(2026, 3, 30, 2, 30, 0, 0, berlin), got)
+
+	// 2:xx happens twice on October 25th 2026, every run moves forward
+	s = MustParse("*/20 * * * *")
+	at := time.Date(2026, 10, 25, 1, 50, 0, 0, berlin)
+	for range 10 {
+		next := s.Next(at)
+		assert.True(t, next.After(at))
+		at = next
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@every", "@every 1ms", "@sometimes",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
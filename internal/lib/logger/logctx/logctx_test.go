@@ -0,0 +1,33 @@
+package logctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_AddsContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With(slog.String("op", "Test"))
+
+	ctx := With(context.Background(), slog.String("request_id", "req-1"))
+	ctx = With(ctx, slog.String("user_uid", "u-1"))
+	log.InfoContext(ctx, "hello")
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "Test", rec["op"])
+	assert.Equal(t, "req-1", rec["request_id"])
+	assert.Equal(t, "u-1", rec["user_uid"])
+}
+
+func TestWith_DoesNotMutateParent(t *testing.T) {
+	parent := With(context.Background(), slog.String("request_id", "req-1"))
+	_ = With(parent, slog.String("user_uid", "u-1"))
+	assert.Len(t, Attrs(parent), 1)
+}
@@ -8,6 +8,7 @@ import (
 	stdLog "log"
 	"os"
 
+	"github.com/TP2-Voice-Agora/backend/internal/lib/logger/logctx"
 	"github.com/fatih/color"
 	"log/slog"
 )
@@ -32,7 +33,7 @@ func New() *slog.Logger {
 
 	handler := opts.NewPrettyHandler(os.Stdout)
 
-	return slog.New(handler)
+	return slog.New(logctx.NewHandler(handler))
 }
 
 func (opts PrettyHandlerOptions) NewPrettyHandler(
@@ -99,7 +100,7 @@ func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &PrettyHandler{
 		Handler: h.Handler,
 		l:       h.l,
-		attrs:   attrs,
+		attrs:   append(append([]slog.Attr{}, h.attrs...), attrs...),
 	}
 }
 
@@ -0,0 +1,134 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError - single field failure reported to the client
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validator checks request structs declared with `validate` tags and
+// reports every failed field at once
+type Validator struct {
+	v        *validator.Validate
+	messages map[string]string
+}
+
+func New() *Validator {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// report fields the way the client sent them, by json name
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	return &Validator{
+		v:        v,
+		messages: map[string]string{},
+	}
+}
+
+// RegisterRef adds a tag checking that an integer id references an existing
+// record, e.g. `validate:"idea_category"`
+func (val *Validator) RegisterRef(tag string, message string, exists func(ctx context.Context, id int) bool) {
+	_ = val.v.RegisterValidationCtx(tag, func(ctx context.Context, fl validator.FieldLevel) bool {
+		return exists(ctx, int(fl.Field().Int()))
+	})
+	val.messages[tag] = message
+}
+
+// Struct validates s, returns apperr validation error with []FieldError details
+func (val *Validator) Struct(ctx context.Context, s any) error {
+	err := val.v.StructCtx(ctx, s)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	details := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		details = append(details, FieldError{
+			Field:   fe.Field(),
+			Message: val.message(fe),
+		})
+	}
+
+	return apperr.Validation("request validation failed").WithDetails(details)
+}
+
+// Var validates a single value, field is the name used in the report
+func (val *Validator) Var(field string, value any, tag string) error {
+	err := val.v.Var(value, tag)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+
+	details := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		details = append(details, FieldError{
+			Field:   field,
+			Message: val.message(fe),
+		})
+	}
+
+	return apperr.Validation("request validation failed").WithDetails(details)
+}
+
+func (val *Validator) message(fe validator.FieldError) string {
+	if msg, ok := val.messages[fe.Tag()]; ok {
+		return msg
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "uuid", "uuid4":
+		return "must be a valid uuid"
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "numeric":
+		return "must contain only digits"
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	default:
+		return "is invalid"
+	}
+}
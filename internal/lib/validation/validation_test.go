@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestValidator() *Validator {
+	v := New()
+	v.RegisterRef("user_position", "unknown position", func(_ context.Context, id int) bool { return id == 1 })
+	v.RegisterRef("idea_category", "unknown idea category", func(_ context.Context, id int) bool { return id == 1 })
+	v.RegisterRef("idea_status", "unknown idea status", func(_ context.Context, id int) bool { return id == 1 })
+	return v
+}
+
+func TestStruct_Valid(t *testing.T) {
+	v := newTestValidator()
+	err := v.Struct(context.Background(), models.RegisterRequest{
+		Email:      "ivan@agora.ru",
+		Password:   "secret123",
+		PositionID: 1,
+		Name:       "Иван",
+		Surname:    "Иванов",
+	})
+	assert.NoError(t, err)
+}
+
+func TestStruct_ReportsAllFields(t *testing.T) {
+	v := newTestValidator()
+	err := v.Struct(context.Background(), models.RegisterRequest{
+		Email:      "not-an-email",
+		Password:   "short",
+		PositionID: 42,
+		Name:       "Александр-Вячеславович",
+	})
+	require.ErrorIs(t, err, apperr.ErrValidation)
+
+	var appErr *apperr.Error
+	require.ErrorAs(t, err, &appErr)
+	details, ok := appErr.Details.([]FieldError)
+	require.True(t, ok)
+
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "email", Message: "must be a valid email"},
+		{Field: "password", Message: "must be at least 8 characters"},
+		{Field: "positionID", Message: "unknown position"},
+		{Field: "name", Message: "must be at most 20 characters"},
+		{Field: "surname", Message: "is required"},
+	}, details)
+}
+
+func TestStruct_ReferenceChecks(t *testing.T) {
+	v := newTestValidator()
+	err := v.Struct(context.Background(), models.InsertIdeaRequest{Name: "n", Text: "t", Status: 2, Category: 3})
+
+	var appErr *apperr.Error
+	require.ErrorAs(t, err, &appErr)
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "status", Message: "unknown idea status"},
+		{Field: "category", Message: "unknown idea category"},
+	}, appErr.Details)
+}
+
+func TestVar(t *testing.T) {
+	v := newTestValidator()
+	assert.NoError(t, v.Var("uid", "4f9a0c7e-3b1d-4a5e-9c1f-2d6e8b7a1c3d", "required,uuid"))
+
+	err := v.Var("uid", "42", "required,uuid")
+	var appErr *apperr.Error
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, []FieldError{{Field: "uid", Message: "must be a valid uuid"}}, appErr.Details)
+}
@@ -0,0 +1,151 @@
+// Package xlsx writes single sheet workbooks row by row. Strings are stored
+// inline rather than in a shared table, so nothing but the current row is
+// kept in memory and the workbook can be streamed as it is written.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxCellLength - characters a cell holds, longer strings are cut
+const MaxCellLength = 32767
+
+const (
+	contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// style 1 is the bold header
+	styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`
+	workbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	sheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	sheetEnd   = `</sheetData></worksheet>`
+)
+
+// Writer writes rows of the only sheet of a workbook. Close must be called
+// to complete the file.
+type Writer struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	err   error
+}
+
+// NewWriter starts a workbook with a sheet named sheetName
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(sheetName))},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// the sheet goes last, it stays open while rows are written
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &Writer{zw: zw, sheet: bufio.NewWriter(f)}
+	x.writeString(sheetStart)
+	return x, x.err
+}
+
+// WriteHeader writes a row of names in bold
+func (x *Writer) WriteHeader(names []string) error {
+	x.writeString("<row>")
+	for _, name := range names {
+		x.writeString(`<c t="inlineStr" s="1"><is><t xml:space="preserve">` + escape(name) + `</t></is></c>`)
+	}
+	x.writeString("</row>")
+	return x.err
+}
+
+// Write writes a row. Integers and floats become numbers, nil an empty cell
+// and anything else a string.
+func (x *Writer) Write(row []any) error {
+	x.writeString("<row>")
+	for _, v := range row {
+		switch v := v.(type) {
+		case nil:
+			x.writeString("<c/>")
+		case int:
+			x.writeString("<c><v>" + strconv.Itoa(v) + "</v></c>")
+		case int64:
+			x.writeString("<c><v>" + strconv.FormatInt(v, 10) + "</v></c>")
+		case float64:
+			x.writeString("<c><v>" + strconv.FormatFloat(v, 'g', -1, 64) + "</v></c>")
+		default:
+			x.writeString(`<c t="inlineStr"><is><t xml:space="preserve">` + escape(fmt.Sprint(v)) + `</t></is></c>`)
+		}
+	}
+	x.writeString("</row>")
+	return x.err
+}
+
+// Close completes the sheet and the workbook, it doesn't close the
+// underlying writer
+func (x *Writer) Close() error {
+	x.writeString(sheetEnd)
+	if x.err == nil {
+		x.err = x.sheet.Flush()
+	}
+	if err := x.zw.Close(); x.err == nil {
+		x.err = err
+	}
+	return x.err
+}
+
+// writeString keeps the first error, so that a row is checked once
+func (x *Writer) writeString(s string) {
+	if x.err != nil {
+		return
+	}
+	_, x.err = x.sheet.WriteString(s)
+}
+
+// escape cuts s to MaxCellLength and escapes it for XML, characters XML
+// can't hold are replaced
+func escape(s string) string {
+	if utf8.RuneCountInString(s) > MaxCellLength {
+		s = string([]rune(s)[:MaxCellLength])
+	}
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
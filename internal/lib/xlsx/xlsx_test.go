@@ -0,0 +1,57 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	x, err := NewWriter(&buf, "Идеи & <обсуждения>")
+	require.NoError(t, err)
+	require.NoError(t, x.WriteHeader([]string{"Название", "Голоса"}))
+	require.NoError(t, x.Write([]any{"Кофемашина\nна 3 этаж", 12}))
+	require.NoError(t, x.Write([]any{"=1+1 \x01", nil, 0.5, int64(7)}))
+	require.NoError(t, x.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		parts[f.Name] = string(body)
+		// every part is well-formed
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, f.Name)
+		}
+	}
+	assert.Len(t, parts, 6)
+	assert.Contains(t, parts["xl/workbook.xml"], `name="Идеи &amp; &lt;обсуждения&gt;"`)
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c t="inlineStr" s="1"><is><t xml:space="preserve">Название</t></is></c>`)
+	assert.Contains(t, sheet, `<t xml:space="preserve">Кофемашина&#xA;на 3 этаж</t>`)
+	assert.Contains(t, sheet, `<c><v>12</v></c>`)
+	assert.Contains(t, sheet, `<c/><c><v>0.5</v></c><c><v>7</v></c>`)
+	assert.Equal(t, 3, strings.Count(sheet, "<row>"))
+}
+
+func TestEscapeCuts(t *testing.T) {
+	long := strings.Repeat("я", MaxCellLength+10)
+	assert.Equal(t, MaxCellLength, len([]rune(escape(long))))
+}
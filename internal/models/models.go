@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // everywhere is SQLx style db tags
 
@@ -34,76 +40,937 @@ type IdeaStatus struct {
 	Name string `db:"name" json:"name"`
 }
 
+// AuthorProfile - display data of a user, joined into ideas, comments and
+// replies so that clients don't fetch /users/{uid} for every author
+type AuthorProfile struct {
+	UID     string  `db:"uid"`
+	Name    string  `db:"name"`
+	Surname string  `db:"surname"`
+	PfpURL  *string `db:"pfp_url"`
+}
+
 type Idea struct {
-	IdeaUID      string    `db:"idea_uid"`
-	Name         string    `db:"name"`
-	Text         string    `db:"text"`
-	Author       string    `db:"author"`
-	CreationDate time.Time `db:"creation_date"`
-	StatusID     int       `db:"status_id"`
-	CategoryID   int       `db:"category_id"`
-	LikeCount    int       `db:"like_count"`
-	DislikeCount int       `db:"dislike_count"`
+	IdeaUID       string          `db:"idea_uid"`
+	Name          string          `db:"name"`
+	Text          string          `db:"text"`
+	Author        string          `db:"author"`
+	CreationDate  time.Time       `db:"creation_date"`
+	StatusID      int             `db:"status_id"`
+	CategoryID    int             `db:"category_id"`
+	LikeCount     int             `db:"like_count"`
+	DislikeCount  int             `db:"dislike_count"`
+	ViewCount     int             `db:"view_count"`
+	CampaignID    *int64          `db:"campaign_id" json:",omitempty"`
+	AuthorProfile *AuthorProfile  `db:"-" json:",omitempty"` // filled only with the discussion
+	Reactions     []ReactionCount `db:"-" json:",omitempty"` // filled for API responses
+	Mentions      []Mention       `db:"-" json:",omitempty"` // filled for API responses
+	// LastViewedAt - the viewer's previous visit, UnreadComments - comments
+	// of others posted since; filled for API responses of visited ideas
+	LastViewedAt   *time.Time `db:"-" json:",omitempty"`
+	UnreadComments int        `db:"-" json:",omitempty"`
 }
 
+// VoteType - like or dislike, user votes for an idea only once
+type VoteType string
+
+const (
+	VoteLike    VoteType = "like"
+	VoteDislike VoteType = "dislike"
+)
+
+// IdeaComment - idea with the first page of its discussion, NextCursor is
+// empty when every top level comment is loaded
 type IdeaComment struct {
-	Idea           Idea
-	CommentReplies []CommentReply
+	Idea       Idea
+	Comments   []CommentThread
+	NextCursor string `json:",omitempty"`
 }
 
+// Comment is a node of a discussion tree: top level comments have no ParentUID
+// and Depth 0, replies point to the comment they answer. Path is the
+// materialized path - uids from the root down to the comment joined with "/",
+// a subtree is every comment whose path starts with Path + "/".
+//
+// Comments are never removed from a thread: deleting one clears the text and
+// sets DeletedAt (a tombstone). EditedAt is set by the last edit, previous
+// texts are kept as Revision.
 type Comment struct {
-	CommentUID  string    `db:"comment_uid"`
-	IdeaUID     string    `db:"idea_uid"`
-	AuthorID    string    `db:"author_uid"`
-	CommentText string    `db:"comment_text"`
-	Timestamp   time.Time `db:"timestamp"`
+	CommentUID    string          `db:"comment_uid"`
+	IdeaUID       string          `db:"idea_uid"`
+	ParentUID     *string         `db:"parent_uid"`
+	Depth         int             `db:"depth"`
+	Path          string          `db:"path" json:"-"`
+	AuthorID      string          `db:"author_uid"`
+	CommentText   string          `db:"comment_text"`
+	Timestamp     time.Time       `db:"timestamp"`
+	EditedAt      *time.Time      `db:"edited_at"`
+	DeletedAt     *time.Time      `db:"deleted_at"`
+	ChildCount    int             `db:"child_count"`         // direct replies, filled only with the discussion
+	AuthorProfile *AuthorProfile  `db:"-" json:",omitempty"` // filled only with the discussion
+	Reactions     []ReactionCount `db:"-" json:",omitempty"` // filled only with the discussion
+	Mentions      []Mention       `db:"-" json:",omitempty"` // filled only with the discussion
+}
+
+// CommentThread - comment with the loaded part of its subtree. Children has
+// fewer than ChildCount items when the rest is left for
+// GET /comments/{uid}/children
+type CommentThread struct {
+	Comment
+	Children []CommentThread
+}
+
+// ThreadOrder orders comments of a discussion depth first: every reply right
+// after the comment it answers, siblings in the order they come in
+func ThreadOrder(comments []Comment) []Comment {
+	children := map[string][]Comment{}
+	for _, c := range comments {
+		parent := ""
+		if c.ParentUID != nil {
+			parent = *c.ParentUID
+		}
+		children[parent] = append(children[parent], c)
+	}
+
+	ordered := make([]Comment, 0, len(comments))
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, c := range children[parent] {
+			ordered = append(ordered, c)
+			walk(c.CommentUID)
+		}
+	}
+	walk("")
+	return ordered
+}
+
+// CommentPage - page of sibling comments with their subtrees, pass NextCursor
+// as the cursor to load the next one
+type CommentPage struct {
+	Comments   []CommentThread
+	NextCursor string `json:",omitempty"`
+}
+
+// Revision - text of a comment before an edit
+type Revision struct {
+	ID        int       `db:"id"`
+	TargetUID string    `db:"target_uid"` // comment uid
+	Text      string    `db:"text"`
+	EditorUID string    `db:"editor_uid"`
+	EditedAt  time.Time `db:"edited_at"` // when Text was replaced
+}
+
+// TargetType - kind of content reactions and mentions belong to, replies are
+// comments
+type TargetType string
+
+const (
+	TargetIdea    TargetType = "idea"
+	TargetComment TargetType = "comment"
+)
+
+// Reaction - emoji left by a user on an idea or a comment, a user leaves
+// each emoji on a target at most once
+type Reaction struct {
+	TargetUID string    `db:"target_uid"`
+	UserUID   string    `db:"user_uid"`
+	Emoji     string    `db:"emoji"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ReactionCount - how many users left Emoji on the target, Mine is set when
+// the requesting user is one of them
+type ReactionCount struct {
+	TargetUID string `db:"target_uid" json:"-"`
+	Emoji     string `db:"emoji"`
+	Count     int    `db:"count"`
+	Mine      bool   `db:"mine"`
+}
+
+// FullName - name and surname a user is mentioned by as @name.surname
+type FullName struct {
+	Name    string
+	Surname string
+}
+
+// Mention - user referenced in an idea or a comment text as @name.surname or
+// @uid. Start and End (exclusive) locate the reference, @ included, in
+// characters (unicode code points) of the text.
+type Mention struct {
+	TargetUID  string         `db:"target_uid" json:"-"`
+	TargetType TargetType     `db:"target_type" json:"-"`
+	IdeaUID    string         `db:"idea_uid" json:"-"` // the idea itself or the idea of the comment
+	AuthorUID  string         `db:"author_uid" json:"-"`
+	UserUID    string         `db:"user_uid"`
+	Start      int            `db:"span_start"`
+	End        int            `db:"span_end"`
+	CreatedAt  time.Time      `db:"created_at" json:"-"`
+	User       *AuthorProfile `db:"-" json:",omitempty"` // filled for API responses
+}
+
+// NotificationType - event a user is notified about, every type can be
+// switched off in NotificationPreference
+type NotificationType string
+
+const (
+	NotificationComment NotificationType = "comment" // comment on the user's idea
+	NotificationReply   NotificationType = "reply"   // reply to the user's comment
+	NotificationMention NotificationType = "mention" // the user is mentioned in an idea or a comment
+	NotificationVote    NotificationType = "vote"    // vote for the user's idea
+	NotificationStatus  NotificationType = "status"  // status of the user's idea changed
+)
+
+// NotificationTypes lists every NotificationType
+var NotificationTypes = []NotificationType{
+	NotificationComment, NotificationReply, NotificationMention, NotificationVote, NotificationStatus,
+}
+
+// Notification - event shown to UserUID. CommentUID is set for comments,
+// replies and mentions in comments; Detail is the vote type for votes and the
+// new status id for status changes.
+type Notification struct {
+	ID         int64            `db:"id"`
+	UserUID    string           `db:"user_uid" json:"-"`
+	Type       NotificationType `db:"type"`
+	ActorUID   string           `db:"actor_uid"`
+	IdeaUID    string           `db:"idea_uid"`
+	CommentUID *string          `db:"comment_uid"`
+	Detail     string           `db:"detail"`
+	CreatedAt  time.Time        `db:"created_at"`
+	ReadAt     *time.Time       `db:"read_at"`
+	Actor      *AuthorProfile   `db:"-" json:",omitempty"` // filled for API responses
+	IdeaName   string           `db:"-" json:",omitempty"` // filled for API responses
+}
+
+// NotificationPage - newest notifications first, pass NextCursor as the
+// cursor to load older ones. Unread counts every unread notification.
+type NotificationPage struct {
+	Notifications []Notification
+	Unread        int
+	NextCursor    string `json:",omitempty"`
+}
+
+// NotificationPreference - whether the user gets notifications of Type,
+// every type is enabled until switched off
+type NotificationPreference struct {
+	UserUID string           `db:"user_uid" json:"-"`
+	Type    NotificationType `db:"type" json:"type" validate:"required,oneof=comment reply mention vote status"`
+	Enabled bool             `db:"enabled" json:"enabled"`
+}
+
+// EventType - kind of a real-time update pushed to connected clients
+type EventType string
+
+const (
+	EventIdeaCreated  EventType = "idea.created"  // Data is the Idea
+	EventIdeaUpdated  EventType = "idea.updated"  // Data is the Idea
+	EventVotes        EventType = "idea.votes"    // Data is VoteCounts
+	EventCommentAdded EventType = "comment.added" // Data is the Comment
+	EventNotification EventType = "notification"  // Data is NotificationEvent
+)
+
+// TopicIdeas - feed of every idea: created, updated and vote counts
+const TopicIdeas = "ideas"
+
+// IdeaTopic - everything about one idea: updates, vote counts and comments
+func IdeaTopic(ideaUID string) string {
+	return "idea:" + ideaUID
+}
+
+// UserTopic - notifications of one user
+func UserTopic(userUID string) string {
+	return "user:" + userUID
+}
+
+// Event - real-time update, delivered once to every subscriber of any of
+// Topics
+type Event struct {
+	Type   EventType
+	Topics []string
+	Data   any
 }
 
-type CommentReply struct {
-	Comment Comment
-	Replies []Reply
+// VoteCounts - counters of the idea after a vote
+type VoteCounts struct {
+	IdeaUID      string
+	LikeCount    int
+	DislikeCount int
 }
 
-type Reply struct {
-	ReplyUID   string    `db:"reply_uid"`
-	CommentUID string    `db:"comment_uid"`
-	AuthorID   string    `db:"author_uid"`
-	Timestamp  time.Time `db:"timestamp"`
-	ReplyText  string    `db:"reply_text"`
+// NotificationEvent - a new notification and the unread count with it
+type NotificationEvent struct {
+	Notification Notification
+	Unread       int
+}
+
+// StreamTicket - one-time pass to the event stream for clients that can't
+// send the Authorization header (EventSource). Only a hash of the ticket is
+// stored, the ticket itself is returned once when issued.
+type StreamTicket struct {
+	Ticket     string    `db:"-" json:"ticket"`
+	TicketHash string    `db:"ticket_hash" json:"-"`
+	UserUID    string    `db:"user_uid" json:"-"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expiresAt"`
+}
+
+// EmailKind - template an email is rendered from
+type EmailKind string
+
+const (
+	EmailMention    EmailKind = "mention"
+	EmailStatus     EmailKind = "status"
+	EmailDigest     EmailKind = "digest"
+	EmailInvitation EmailKind = "invitation"
+)
+
+// OutboxEmail - rendered email waiting for delivery. A failed attempt is
+// retried at NextAttemptAt until the email is sent (SentAt) or given up
+// (FailedAt), rows are kept afterwards as a delivery log.
+type OutboxEmail struct {
+	ID            int64      `db:"id"`
+	UserUID       string     `db:"user_uid"`
+	Kind          EmailKind  `db:"kind"`
+	To            string     `db:"to_addr"`
+	Subject       string     `db:"subject"`
+	TextBody      string     `db:"text_body"`
+	HTMLBody      string     `db:"html_body"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     string     `db:"last_error"`
+	CreatedAt     time.Time  `db:"created_at"`
+	SentAt        *time.Time `db:"sent_at"`
+	FailedAt      *time.Time `db:"failed_at"`
+}
+
+// DigestFrequency - how often a user gets the digest of trending ideas
+type DigestFrequency string
+
+const (
+	DigestOff    DigestFrequency = "off"
+	DigestDaily  DigestFrequency = "daily"
+	DigestWeekly DigestFrequency = "weekly"
+)
+
+// EmailSettings - what a user gets by email. Immediate covers mentions and
+// status changes of the user's ideas, switched off notification types are
+// not emailed either.
+type EmailSettings struct {
+	UserUID      string          `db:"user_uid" json:"-"`
+	Language     string          `db:"language" json:"language"`
+	Immediate    bool            `db:"immediate" json:"immediate"`
+	Digest       DigestFrequency `db:"digest" json:"digest"`
+	LastDigestAt *time.Time      `db:"last_digest_at" json:"-"`
+}
+
+// DefaultEmailSettings apply to users who haven't changed theirs
+var DefaultEmailSettings = EmailSettings{Language: "ru", Immediate: true, Digest: DigestWeekly}
+
+// DigestRecipient - user due for a digest, with the settings in effect
+type DigestRecipient struct {
+	EmailSettings
+	Email string `db:"email"`
+	Name  string `db:"name"`
+}
+
+// TrendingIdea - idea with the votes it got in the digest period
+type TrendingIdea struct {
+	Idea
+	RecentVotes int `db:"recent_votes"`
+}
+
+// IdeaSort - order of ranked ideas, highest score first
+type IdeaSort string
+
+const (
+	SortHot           IdeaSort = "hot"           // votes, comments and views, newer ideas ahead
+	SortTop           IdeaSort = "top"           // likes minus dislikes
+	SortControversial IdeaSort = "controversial" // many votes split evenly
+	SortRising        IdeaSort = "rising"        // recent votes and comments of young ideas
+)
+
+var IdeaSorts = []IdeaSort{SortHot, SortTop, SortControversial, SortRising}
+
+// IdeaActivity - what an idea's scores are computed from, Recent* count
+// since the start of the rising window
+type IdeaActivity struct {
+	IdeaUID        string    `db:"idea_uid"`
+	CreationDate   time.Time `db:"creation_date"`
+	LikeCount      int       `db:"like_count"`
+	DislikeCount   int       `db:"dislike_count"`
+	Comments       int       `db:"comments"`
+	Views          int       `db:"views"`
+	RecentVotes    int       `db:"recent_votes"`
+	RecentComments int       `db:"recent_comments"`
+}
+
+// IdeaScore - materialized scores of an idea, one per IdeaSort
+type IdeaScore struct {
+	IdeaUID       string    `db:"idea_uid"`
+	Hot           float64   `db:"hot"`
+	Top           float64   `db:"top"`
+	Controversial float64   `db:"controversial"`
+	Rising        float64   `db:"rising"`
+	ComputedAt    time.Time `db:"computed_at"`
+}
+
+// EventPing - test event sent to a webhook on request, whatever it
+// subscribes to
+const EventPing EventType = "ping"
+
+// WebhookEvents lists the event types a webhook may subscribe to
+var WebhookEvents = []EventType{EventIdeaCreated, EventIdeaUpdated, EventVotes, EventCommentAdded}
+
+// EventTypes - set of event types, stored as a comma separated list
+type EventTypes []EventType
+
+func (e EventTypes) Value() (driver.Value, error) {
+	types := make([]string, len(e))
+	for k, t := range e {
+		types[k] = string(t)
+	}
+	return strings.Join(types, ","), nil
+}
+
+func (e *EventTypes) Scan(src any) error {
+	var list string
+	switch v := src.(type) {
+	case string:
+		list = v
+	case []byte:
+		list = string(v)
+	default:
+		return fmt.Errorf("can't scan %T into EventTypes", src)
+	}
+	*e = EventTypes{}
+	for _, t := range strings.Split(list, ",") {
+		if t != "" {
+			*e = append(*e, EventType(t))
+		}
+	}
+	return nil
+}
+
+// Webhook - receiver of idea events configured by an admin. Payloads are
+// signed with Secret, which is shown only when it is set.
+type Webhook struct {
+	ID        int64      `db:"id" json:"id"`
+	URL       string     `db:"url" json:"url"`
+	Secret    string     `db:"secret" json:"secret,omitempty"`
+	Events    EventTypes `db:"events" json:"events"`
+	Active    bool       `db:"active" json:"active"`
+	CreatedBy *string    `db:"created_by" json:"createdBy"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// DeliveryState - where a webhook delivery is: pending ones are retried
+// until they are delivered or dead
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "pending"
+	DeliveryDelivered DeliveryState = "delivered"
+	DeliveryDead      DeliveryState = "dead"
+)
+
+// WebhookDelivery - event queued for a webhook. Payload is the exact signed
+// body, EventID stays the same across redeliveries. ResponseCode and
+// LastError are of the latest attempt.
+type WebhookDelivery struct {
+	ID            int64            `db:"id" json:"id"`
+	WebhookID     int64            `db:"webhook_id" json:"webhookId"`
+	EventID       string           `db:"event_id" json:"eventId"`
+	Event         EventType        `db:"event" json:"event"`
+	Payload       string           `db:"payload" json:"payload"`
+	State         DeliveryState    `db:"state" json:"state"`
+	Attempts      int              `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time        `db:"next_attempt_at" json:"nextAttemptAt"`
+	ResponseCode  *int             `db:"response_code" json:"responseCode"`
+	LastError     string           `db:"last_error" json:"lastError"`
+	CreatedAt     time.Time        `db:"created_at" json:"createdAt"`
+	DeliveredAt   *time.Time       `db:"delivered_at" json:"deliveredAt"`
+	Log           []WebhookAttempt `db:"-" json:"log,omitempty"` // filled for a single delivery
+}
+
+// WebhookAttempt - entry of the delivery log, ResponseCode is nil when the
+// request failed before a response
+type WebhookAttempt struct {
+	ID           int64     `db:"id" json:"id"`
+	DeliveryID   int64     `db:"delivery_id" json:"-"`
+	ResponseCode *int      `db:"response_code" json:"responseCode"`
+	Error        string    `db:"error" json:"error"`
+	DurationMS   int       `db:"duration_ms" json:"durationMs"`
+	AttemptedAt  time.Time `db:"attempted_at" json:"attemptedAt"`
+}
+
+// BotLinkCode - one-time code issued in the web profile, the user sends it
+// to the bot to link a messenger account. Links open the bot with the code
+// already filled in, by platform, for messengers that support it.
+type BotLinkCode struct {
+	Code      string            `db:"code" json:"code"`
+	UserUID   string            `db:"user_uid" json:"-"`
+	ExpiresAt time.Time         `db:"expires_at" json:"expiresAt"`
+	Links     map[string]string `db:"-" json:"links,omitempty"`
+}
+
+// BotAccount - messenger account linked to a user. ExternalID identifies the
+// account on Platform, ChatID is where the bot writes to the user.
+type BotAccount struct {
+	Platform   string    `db:"platform" json:"platform"`
+	ExternalID string    `db:"external_id" json:"externalId"`
+	ChatID     string    `db:"chat_id" json:"-"`
+	UserUID    string    `db:"user_uid" json:"-"`
+	Username   string    `db:"username" json:"username"`
+	LinkedAt   time.Time `db:"linked_at" json:"linkedAt"`
+}
+
+// IDs - set of dictionary ids, stored as a comma separated list
+type IDs []int
+
+func (ids IDs) Value() (driver.Value, error) {
+	list := make([]string, len(ids))
+	for k, id := range ids {
+		list[k] = strconv.Itoa(id)
+	}
+	return strings.Join(list, ","), nil
 }
 
+func (ids *IDs) Scan(src any) error {
+	var list string
+	switch v := src.(type) {
+	case string:
+		list = v
+	case []byte:
+		list = string(v)
+	default:
+		return fmt.Errorf("can't scan %T into IDs", src)
+	}
+	*ids = IDs{}
+	for _, s := range strings.Split(list, ",") {
+		if s == "" {
+			continue
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("can't scan %q into IDs: %w", list, err)
+		}
+		*ids = append(*ids, id)
+	}
+	return nil
+}
+
+// Campaign - themed call for ideas open from StartsAt until EndsAt. Ideas
+// are submitted into it while it is open, in one of CategoryIDs by authors
+// in one of PositionIDs (empty lists allow any), and voting for them closes
+// at EndsAt.
+type Campaign struct {
+	ID          int64         `db:"id" json:"id"`
+	Title       string        `db:"title" json:"title"`
+	Description string        `db:"description" json:"description"`
+	StartsAt    time.Time     `db:"starts_at" json:"startsAt"`
+	EndsAt      time.Time     `db:"ends_at" json:"endsAt"`
+	CategoryIDs IDs           `db:"category_ids" json:"categoryIds"`
+	PositionIDs IDs           `db:"position_ids" json:"positionIds"`
+	CreatedBy   *string       `db:"created_by" json:"createdBy"`
+	CreatedAt   time.Time     `db:"created_at" json:"createdAt"`
+	State       CampaignState `db:"-" json:"state"` // filled for API responses
+}
+
+// CampaignState - whether a campaign accepts ideas
+type CampaignState string
+
+const (
+	CampaignUpcoming CampaignState = "upcoming"
+	CampaignOpen     CampaignState = "open"
+	CampaignClosed   CampaignState = "closed"
+)
+
+// StateAt returns the state of the campaign at t
+func (c Campaign) StateAt(t time.Time) CampaignState {
+	switch {
+	case t.Before(c.StartsAt):
+		return CampaignUpcoming
+	case t.Before(c.EndsAt):
+		return CampaignOpen
+	}
+	return CampaignClosed
+}
+
+// CampaignStats - summary of a campaign: its ideas, their distinct authors
+// and voters, votes and comments left on them
+type CampaignStats struct {
+	Ideas    int `db:"ideas" json:"ideas"`
+	Authors  int `db:"authors" json:"authors"`
+	Voters   int `db:"voters" json:"voters"`
+	Likes    int `db:"likes" json:"likes"`
+	Dislikes int `db:"dislikes" json:"dislikes"`
+	Comments int `db:"comments" json:"comments"`
+}
+
+// LeaderboardEntry - idea of a campaign ranked by Score, likes minus
+// dislikes. Ideas with the same score share the place.
+type LeaderboardEntry struct {
+	Place int  `json:"place"`
+	Score int  `json:"score"`
+	Idea  Idea `json:"idea"`
+}
+
+// CampaignPage - campaign with its stats and the top of its leaderboard
+type CampaignPage struct {
+	Campaign    Campaign           `json:"campaign"`
+	Stats       CampaignStats      `json:"stats"`
+	Leaderboard []LeaderboardEntry `json:"leaderboard"`
+}
+
+// JobSource - what started a job run
+type JobSource string
+
+const (
+	JobScheduled JobSource = "schedule"
+	JobManual    JobSource = "manual"
+)
+
+type JobStatus string
+
+const (
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// JobRun - one run of a background job. ScheduledAt is the moment a
+// scheduled run was due, replicas record a moment once; Node is the host
+// that ran it. A run left running by a crashed process stays running.
+type JobRun struct {
+	ID          int64      `db:"id" json:"id"`
+	Job         string     `db:"job" json:"job"`
+	Source      JobSource  `db:"source" json:"source"`
+	ScheduledAt *time.Time `db:"scheduled_at" json:"scheduledAt,omitempty"`
+	TriggeredBy *string    `db:"triggered_by" json:"triggeredBy,omitempty"`
+	Node        string     `db:"node" json:"node"`
+	Status      JobStatus  `db:"status" json:"status"`
+	Error       string     `db:"error" json:"error,omitempty"`
+	StartedAt   time.Time  `db:"started_at" json:"startedAt"`
+	FinishedAt  *time.Time `db:"finished_at" json:"finishedAt,omitempty"`
+}
+
+// Job - background job registered with the scheduler
+type Job struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	NextRunAt time.Time `json:"nextRunAt"`
+	LastRun   *JobRun   `json:"lastRun,omitempty"`
+}
+
+// BrowseHistory - the last visit of an idea by a user, CountedAt is the
+// last visit counted in the idea's ViewCount
 type BrowseHistory struct {
-	VisitorID string `db:"visitor_uid"`
-	IdeaID    string `db:"idea_uid"`
+	VisitorID string    `db:"visitor_uid"`
+	IdeaID    string    `db:"idea_uid"`
+	ViewedAt  time.Time `db:"viewed_at"`
+	CountedAt time.Time `db:"counted_at"`
+}
+
+// IdeaVisit - the user's last visit of an idea and comments of others
+// posted since
+type IdeaVisit struct {
+	IdeaUID        string    `db:"idea_uid"`
+	ViewedAt       time.Time `db:"viewed_at"`
+	UnreadComments int       `db:"unread_comments"`
+}
+
+// IdeaStatusChange - a move of an idea to another status, ChangedBy is nil
+// once the moderator's account is gone
+type IdeaStatusChange struct {
+	ID        int64     `db:"id" json:"id"`
+	IdeaUID   string    `db:"idea_uid" json:"ideaUID"`
+	StatusID  int       `db:"status_id" json:"statusID"`
+	ChangedBy *string   `db:"changed_by" json:"changedBy,omitempty"`
+	ChangedAt time.Time `db:"changed_at" json:"changedAt"`
+}
+
+// AnalyticsGroup - length of the periods submitted ideas are counted by,
+// periods are in UTC and weeks start on Monday
+type AnalyticsGroup string
+
+const (
+	GroupDay   AnalyticsGroup = "day"
+	GroupWeek  AnalyticsGroup = "week"
+	GroupMonth AnalyticsGroup = "month"
+)
+
+// Start returns the start of the period t falls within
+func (g AnalyticsGroup) Start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch g {
+	case GroupWeek:
+		// Sunday is the last day of the week
+		return time.Date(y, m, d-(int(t.UTC().Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case GroupMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Next returns the start of the period after the one starting at start
+func (g AnalyticsGroup) Next(start time.Time) time.Time {
+	switch g {
+	case GroupWeek:
+		return start.AddDate(0, 0, 7)
+	case GroupMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// AnalyticsRange - days analytics are computed over, both inclusive. Zero
+// values are replaced by the service's defaults.
+type AnalyticsRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// PeriodCount - ideas submitted within the period starting at Period
+type PeriodCount struct {
+	Period time.Time `db:"period" json:"period"`
+	Ideas  int       `db:"ideas" json:"ideas"`
+}
+
+// SubmissionReport - ideas submitted within Range per period, periods
+// without ideas included
+type SubmissionReport struct {
+	Range   AnalyticsRange `json:"range"`
+	Group   AnalyticsGroup `json:"group"`
+	Total   int            `json:"total"`
+	Periods []PeriodCount  `json:"periods"`
+}
+
+// CategoryOutcomes - ideas of a category by their current status
+type CategoryOutcomes struct {
+	CategoryID int `db:"category_id"`
+	Ideas      int `db:"ideas"`
+	Approved   int `db:"approved"`
+	Rejected   int `db:"rejected"`
+}
+
+// IdeaDecision - the first move of an idea to a decision status
+type IdeaDecision struct {
+	IdeaUID      string    `db:"idea_uid"`
+	CategoryID   int       `db:"category_id"`
+	CreationDate time.Time `db:"creation_date"`
+	DecidedAt    time.Time `db:"decided_at"`
+}
+
+// DecisionStats - how ideas were decided on. ApprovalRate is the share of
+// approved among decided ideas, MedianDecisionHours is measured from
+// submission to the first decision; both are nil until there is one.
+type DecisionStats struct {
+	CategoryID          int      `json:"categoryId,omitempty"`
+	Category            string   `json:"category,omitempty"`
+	Ideas               int      `json:"ideas"`
+	Approved            int      `json:"approved"`
+	Rejected            int      `json:"rejected"`
+	ApprovalRate        *float64 `json:"approvalRate"`
+	MedianDecisionHours *float64 `json:"medianDecisionHours"`
+}
+
+// DecisionReport - decisions on ideas submitted within Range, in total and
+// per category
+type DecisionReport struct {
+	Range      AnalyticsRange  `json:"range"`
+	Total      DecisionStats   `json:"total"`
+	Categories []DecisionStats `json:"categories"`
+}
+
+// UserCount - how many ideas or comments the user has posted
+type UserCount struct {
+	UserUID string `db:"user_uid"`
+	Count   int    `db:"count"`
+}
+
+type Contributor struct {
+	User  AuthorProfile `json:"user"`
+	Count int           `json:"count"`
+}
+
+// ContributorReport - users who posted the most ideas and comments within
+// Range, most first
+type ContributorReport struct {
+	Range      AnalyticsRange `json:"range"`
+	Authors    []Contributor  `json:"authors"`
+	Commenters []Contributor  `json:"commenters"`
+}
+
+// PositionEngagement - what members of a position did within a range:
+// Authors, Commenters and Voters are members who posted an idea, a comment
+// or voted, Participation is the share of members who voted
+type PositionEngagement struct {
+	PositionID    int      `db:"position_id" json:"positionId,omitempty"`
+	Position      string   `db:"-" json:"position,omitempty"`
+	Members       int      `db:"members" json:"members"`
+	Authors       int      `db:"authors" json:"authors"`
+	Commenters    int      `db:"commenters" json:"commenters"`
+	Voters        int      `db:"voters" json:"voters"`
+	Ideas         int      `db:"ideas" json:"ideas"`
+	Comments      int      `db:"comments" json:"comments"`
+	Votes         int      `db:"votes" json:"votes"`
+	Participation *float64 `db:"-" json:"participation"`
+}
+
+// EngagementReport - engagement within Range in total and per position,
+// users without a position count in Total only
+type EngagementReport struct {
+	Range     AnalyticsRange       `json:"range"`
+	Total     PositionEngagement   `json:"total"`
+	Positions []PositionEngagement `json:"positions"`
+}
+
+// ExportFormat - file format of exported ideas and discussions
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportXLSX ExportFormat = "xlsx"
+)
+
+// IdeaFilter - what the ideas list is narrowed and ordered by: a search
+// Query returns up to Limit matches, otherwise every idea ordered by Sort or
+// listed as is when Sort is empty
+type IdeaFilter struct {
+	Query string
+	Limit int
+	Sort  IdeaSort
+}
+
+// Invitation - a link sent to an imported user to set a password. Only a
+// hash of the token is stored, a user has one invitation at a time.
+type Invitation struct {
+	TokenHash string    `db:"token_hash"`
+	UserUID   string    `db:"user_uid"`
+	CreatedAt time.Time `db:"created_at"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+// ImportRowError - why a row of an imported file is rejected. Row is the
+// line in the file, the header being 1, and 0 for the file as a whole;
+// Field is the column, empty when the whole row is wrong.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportedUser - a valid row of an imported file, UID is set once the user
+// is created and Invited once the invitation is queued
+type ImportedUser struct {
+	Row      int        `json:"row"`
+	UID      string     `json:"uid,omitempty"`
+	Name     string     `json:"name"`
+	Surname  string     `json:"surname"`
+	Email    string     `json:"email"`
+	Phone    string     `json:"phone,omitempty"`
+	Position string     `json:"position"`
+	HireDate *time.Time `json:"hireDate,omitempty"`
+	Invited  bool       `json:"invited"`
 }
 
+// ImportReport - outcome of a users import. A file is imported as a whole:
+// with any Errors nothing is created, a dry run only checks the rows.
+// NewPositions are positions missing in the dictionary, created on commit.
+type ImportReport struct {
+	DryRun       bool             `json:"dryRun"`
+	Committed    bool             `json:"committed"`
+	Rows         int              `json:"rows"`
+	Users        []ImportedUser   `json:"users"`
+	NewPositions []string         `json:"newPositions"`
+	Errors       []ImportRowError `json:"errors"`
+}
+
+// request structs are validated with `validate` tags (see lib/validation),
+// max lengths follow the columns in sql schema
+
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email,max=35"`
+	Password string `json:"password" validate:"required,max=72"`
 }
 
 type RegisterRequest struct {
-	Email      string `json:"email"`
-	Password   string `json:"password"`
-	PositionID int    `json:"positionID"`
-	Name       string `json:"name"`
-	Surname    string `json:"surname"`
+	Email      string `json:"email" validate:"required,email,max=35"`
+	Password   string `json:"password" validate:"required,min=8,max=72"` // bcrypt ignores bytes after 72
+	PositionID int    `json:"positionID" validate:"required,user_position"`
+	Name       string `json:"name" validate:"required,max=20"`
+	Surname    string `json:"surname" validate:"required,max=20"`
+}
+
+// AcceptInvitationRequest sets the password of an imported user, Token comes
+// from the invitation link
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required,max=64"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
 }
 
 type InsertIdeaRequest struct {
-	Name     string `json:"name"`
-	Text     string `json:"text"`
-	Author   string `json:"author"`
-	Status   int    `json:"status"`
-	Category int    `json:"category"`
+	Name     string `json:"name" validate:"required,max=200"`
+	Text     string `json:"text" validate:"required,max=10000"`
+	Author   string `json:"author"` // ignored, taken from the token
+	Status   int    `json:"status" validate:"required,idea_status"`
+	Category int    `json:"category" validate:"required,idea_category"`
+	Campaign *int64 `json:"campaign"` // submits the idea into the campaign
 }
 
+// InsertCommentRequest - ParentUID is set to reply to a comment of the same idea
 type InsertCommentRequest struct {
-	IdeaUID     string `json:"ideaUID"`
-	CommentText string `json:"commentText"`
+	IdeaUID     string `json:"ideaUID" validate:"required,uuid"`
+	ParentUID   string `json:"parentUID" validate:"omitempty,uuid"`
+	CommentText string `json:"commentText" validate:"required,max=5000"`
 }
 
+// InsertReplyRequest and EditReplyRequest are kept for POST /replies and
+// PATCH /replies/{uid}, replies are comments with a parent now
 type InsertReplyRequest struct {
-	CommentUID string `json:"commentUID"`
-	ReplyText  string `json:"replyText"`
+	CommentUID string `json:"commentUID" validate:"required,uuid"`
+	ReplyText  string `json:"replyText" validate:"required,max=5000"`
+}
+
+type ChangeIdeaStatusRequest struct {
+	Status int `json:"status" validate:"required,idea_status"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreference `json:"preferences" validate:"required,min=1,dive"`
+}
+
+type UpdateEmailSettingsRequest struct {
+	Language  string          `json:"language" validate:"required,oneof=ru en"`
+	Immediate bool            `json:"immediate"`
+	Digest    DigestFrequency `json:"digest" validate:"required,oneof=off daily weekly"`
+}
+
+// WebhookRequest creates or updates a webhook. An empty Secret is generated
+// on creation and kept on update, a nil Active means true on creation and
+// no change on update.
+type WebhookRequest struct {
+	URL    string      `json:"url" validate:"required,http_url,max=2048"`
+	Secret string      `json:"secret" validate:"omitempty,min=16,max=128"`
+	Events []EventType `json:"events" validate:"required,min=1,dive,oneof=idea.created idea.updated idea.votes comment.added"`
+	Active *bool       `json:"active"`
+}
+
+// CampaignRequest creates or updates a campaign, empty CategoryIDs and
+// PositionIDs allow any
+type CampaignRequest struct {
+	Title       string    `json:"title" validate:"required,max=200"`
+	Description string    `json:"description" validate:"max=5000"`
+	StartsAt    time.Time `json:"startsAt" validate:"required"`
+	EndsAt      time.Time `json:"endsAt" validate:"required,gtfield=StartsAt"`
+	CategoryIDs []int     `json:"categoryIds" validate:"dive,idea_category"`
+	PositionIDs []int     `json:"positionIds" validate:"dive,user_position"`
+}
+
+type EditCommentRequest struct {
+	CommentText string `json:"commentText" validate:"required,max=5000"`
+}
+
+type EditReplyRequest struct {
+	ReplyText string `json:"replyText" validate:"required,max=5000"`
 }
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// within reports whether t falls within [from, to)
+func within(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+func (r *Repository) CountIdeasByPeriod(ctx context.Context, from, to time.Time, group models.AnalyticsGroup) ([]models.PeriodCount, error) {
+	defer r.rlock()()
+
+	byPeriod := map[time.Time]int{}
+	for _, idea := range r.st.ideas {
+		if within(idea.CreationDate, from, to) {
+			byPeriod[group.Start(idea.CreationDate)]++
+		}
+	}
+	var counts []models.PeriodCount
+	for _, period := range slices.SortedFunc(maps.Keys(byPeriod), time.Time.Compare) {
+		counts = append(counts, models.PeriodCount{Period: period, Ideas: byPeriod[period]})
+	}
+	return counts, nil
+}
+
+func (r *Repository) SelectCategoryOutcomes(ctx context.Context, from, to time.Time, approved, rejected []int) ([]models.CategoryOutcomes, error) {
+	defer r.rlock()()
+
+	byCategory := map[int]models.CategoryOutcomes{}
+	for _, idea := range r.st.ideas {
+		if !within(idea.CreationDate, from, to) {
+			continue
+		}
+		o := byCategory[idea.CategoryID]
+		o.CategoryID = idea.CategoryID
+		o.Ideas++
+		if slices.Contains(approved, idea.StatusID) {
+			o.Approved++
+		}
+		if slices.Contains(rejected, idea.StatusID) {
+			o.Rejected++
+		}
+		byCategory[idea.CategoryID] = o
+	}
+	return slices.SortedFunc(maps.Values(byCategory), func(a, b models.CategoryOutcomes) int {
+		return cmp.Compare(a.CategoryID, b.CategoryID)
+	}), nil
+}
+
+func (r *Repository) SelectIdeaDecisions(ctx context.Context, from, to time.Time, statusIDs []int) ([]models.IdeaDecision, error) {
+	defer r.rlock()()
+
+	byIdea := map[string]models.IdeaDecision{}
+	for _, c := range r.st.statusChanges {
+		idea := r.st.ideas[c.IdeaUID]
+		if !slices.Contains(statusIDs, c.StatusID) || !within(idea.CreationDate, from, to) {
+			continue
+		}
+		// changes are appended in order, ties go to the earlier one
+		if d, ok := byIdea[c.IdeaUID]; ok && !c.ChangedAt.Before(d.DecidedAt) {
+			continue
+		}
+		byIdea[c.IdeaUID] = models.IdeaDecision{
+			IdeaUID:      idea.IdeaUID,
+			CategoryID:   idea.CategoryID,
+			CreationDate: idea.CreationDate,
+			DecidedAt:    c.ChangedAt,
+		}
+	}
+	return slices.SortedFunc(maps.Values(byIdea), func(a, b models.IdeaDecision) int {
+		return cmp.Compare(a.IdeaUID, b.IdeaUID)
+	}), nil
+}
+
+func (r *Repository) SelectTopAuthors(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error) {
+	defer r.rlock()()
+
+	byUser := map[string]int{}
+	for _, idea := range r.st.ideas {
+		if within(idea.CreationDate, from, to) {
+			byUser[idea.Author]++
+		}
+	}
+	return topUsers(byUser, limit), nil
+}
+
+func (r *Repository) SelectTopCommenters(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error) {
+	defer r.rlock()()
+
+	byUser := map[string]int{}
+	for _, c := range r.st.comments {
+		if c.DeletedAt == nil && within(c.Timestamp, from, to) {
+			byUser[c.AuthorID]++
+		}
+	}
+	return topUsers(byUser, limit), nil
+}
+
+func topUsers(byUser map[string]int, limit int) []models.UserCount {
+	var counts []models.UserCount
+	for uid, n := range byUser {
+		counts = append(counts, models.UserCount{UserUID: uid, Count: n})
+	}
+	slices.SortFunc(counts, func(a, b models.UserCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.UserUID, b.UserUID))
+	})
+	return counts[:min(limit, len(counts))]
+}
+
+func (r *Repository) SelectPositionEngagement(ctx context.Context, from, to time.Time) ([]models.PositionEngagement, error) {
+	defer r.rlock()()
+
+	ideas, comments, votes := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, idea := range r.st.ideas {
+		if within(idea.CreationDate, from, to) {
+			ideas[idea.Author]++
+		}
+	}
+	for _, c := range r.st.comments {
+		if c.DeletedAt == nil && within(c.Timestamp, from, to) {
+			comments[c.AuthorID]++
+		}
+	}
+	for k, votedAt := range r.st.votes {
+		if within(votedAt, from, to) {
+			votes[k.userUID]++
+		}
+	}
+
+	byPosition := map[int]models.PositionEngagement{}
+	for uid, u := range r.st.users {
+		e := byPosition[u.PositionID]
+		e.PositionID = u.PositionID
+		e.Members++
+		if n := ideas[uid]; n > 0 {
+			e.Authors++
+			e.Ideas += n
+		}
+		if n := comments[uid]; n > 0 {
+			e.Commenters++
+			e.Comments += n
+		}
+		if n := votes[uid]; n > 0 {
+			e.Voters++
+			e.Votes += n
+		}
+		byPosition[u.PositionID] = e
+	}
+	return slices.SortedFunc(maps.Values(byPosition), func(a, b models.PositionEngagement) int {
+		return cmp.Compare(a.PositionID, b.PositionID)
+	}), nil
+}
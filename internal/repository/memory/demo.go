@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DemoPassword - password of every user created by SeedDemo
+const DemoPassword = "demo12345"
+
+// DemoAdminEmail - the demo user with admin rights
+const DemoAdminEmail = "admin@agora.demo"
+
+// SeedDemo fills an empty repository with sample users, ideas and discussions
+func (r *Repository) SeedDemo(ctx context.Context) error {
+	r.SeedDictionaries(
+		[]models.UserPosition{
+			{ID: 1, Name: "Разработчик"},
+			{ID: 2, Name: "Аналитик"},
+			{ID: 3, Name: "Руководитель"},
+		},
+		[]models.IdeaCategory{
+			{ID: 1, Name: "Офис"},
+			{ID: 2, Name: "Процессы"},
+			{ID: 3, Name: "Продукт"},
+		},
+		[]models.IdeaStatus{
+			{ID: 1, Name: "Новая"},
+			{ID: 2, Name: "На оценке"},
+			{ID: 3, Name: "Одобрена"},
+			{ID: 4, Name: "Отклонена"},
+		},
+	)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(DemoPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	hired := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	people := []struct {
+		email, name, surname string
+		position             int
+		admin                bool
+	}{
+		{DemoAdminEmail, "Анна", "Смирнова", 3, true},
+		{"ivan@agora.demo", "Иван", "Петров", 1, false},
+		{"olga@agora.demo", "Ольга", "Кузнецова", 2, false},
+	}
+	users := make([]models.User, len(people))
+	for i, p := range people {
+		users[i] = models.User{
+			UID:        uuid.NewString(),
+			Name:       p.name,
+			Surname:    p.surname,
+			PositionID: p.position,
+			Email:      p.email,
+			Password:   string(hash),
+			Phone:      fmt.Sprintf("999000000%d", i),
+			HireDate:   &hired,
+			IsAdmin:    p.admin,
+		}
+		if err := r.InsertUser(ctx, users[i]); err != nil {
+			return err
+		}
+	}
+	admin, ivan, olga := users[0], users[1], users[2]
+
+	ideas := []struct {
+		author           models.User
+		name, text       string
+		status, category int
+	}{
+		{ivan, "Переговорки по QR-коду", "Бронировать переговорные через QR-код на двери вместо общей таблицы.", 1, 1},
+		{olga, "Еженедельные демо", "Каждую пятницу команды показывают, что сделали за неделю.", 2, 2},
+		{ivan, "Тёмная тема", "Добавить тёмную тему во внутренний портал.", 3, 3},
+		{admin, "Датчики движения для света", "Выключать свет в пустых кабинетах, чтобы сократить расходы на электричество.", 1, 1},
+	}
+	uids := make([]string, len(ideas))
+	for i, d := range ideas {
+		uids[i] = uuid.NewString()
+		if err := r.InsertIdea(ctx, models.Idea{
+			IdeaUID:    uids[i],
+			Name:       d.name,
+			Text:       d.text,
+			Author:     d.author.UID,
+			StatusID:   d.status,
+			CategoryID: d.category,
+		}); err != nil {
+			return err
+		}
+	}
+
+	comment := models.Comment{
+		CommentUID:  uuid.NewString(),
+		IdeaUID:     uids[0],
+		AuthorID:    olga.UID,
+		CommentText: "Отличная идея, постоянно путаемся с бронированием.",
+	}
+	if err := r.InsertIdeaComment(ctx, comment); err != nil {
+		return err
+	}
+	reply := models.Comment{
+		CommentUID:  uuid.NewString(),
+		IdeaUID:     uids[0],
+		ParentUID:   &comment.CommentUID,
+		AuthorID:    ivan.UID,
+		CommentText: "Спасибо! Могу сделать прототип за спринт.",
+	}
+	if err := r.InsertIdeaComment(ctx, reply); err != nil {
+		return err
+	}
+	if err := r.InsertIdeaComment(ctx, models.Comment{
+		CommentUID:  uuid.NewString(),
+		IdeaUID:     uids[0],
+		ParentUID:   &reply.CommentUID,
+		AuthorID:    olga.UID,
+		CommentText: "Будет здорово, готова протестировать.",
+	}); err != nil {
+		return err
+	}
+	if err := r.InsertIdeaComment(ctx, models.Comment{
+		CommentUID:  uuid.NewString(),
+		IdeaUID:     uids[1],
+		AuthorID:    admin.UID,
+		CommentText: "Давайте попробуем в следующем месяце.",
+	}); err != nil {
+		return err
+	}
+
+	votes := []struct {
+		idea  int
+		voter models.User
+		like  bool
+	}{
+		{0, olga, true}, {0, admin, true}, {1, ivan, true}, {1, admin, false}, {2, olga, true},
+	}
+	for _, v := range votes {
+		if _, err := r.CheckVote(ctx, uids[v.idea], v.voter.UID); err != nil {
+			return err
+		}
+		if v.like {
+			err = r.IncrementLikeCount(ctx, uids[v.idea])
+		} else {
+			err = r.IncrementDislikeCount(ctx, uids[v.idea])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
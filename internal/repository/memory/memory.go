@@ -0,0 +1,1928 @@
+// Package memory implements repository.Repository on top of in-process maps.
+// It mirrors PostgresRepository semantics (ordering, foreign keys, not-found
+// errors, counters) and is used by tests and the -demo mode.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+type voteKey struct {
+	ideaUID string
+	userUID string
+}
+
+type reactionKey struct {
+	targetUID string
+	userUID   string
+	emoji     string
+}
+
+type preferenceKey struct {
+	userUID string
+	typ     models.NotificationType
+}
+
+type state struct {
+	positions  []models.UserPosition
+	categories []models.IdeaCategory
+	statuses   []models.IdeaStatus
+
+	users    map[string]models.User
+	ideas    map[string]models.Idea
+	comments map[string]models.Comment
+	votes    map[voteKey]time.Time
+
+	reactions map[reactionKey]time.Time
+	mentions  []models.Mention
+
+	notifications      []models.Notification
+	lastNotificationID int64
+	preferences        map[preferenceKey]bool
+
+	outbox        []models.OutboxEmail
+	lastOutboxID  int64
+	emailSettings map[string]models.EmailSettings
+
+	webhooks       []models.Webhook
+	lastWebhookID  int64
+	deliveries     []models.WebhookDelivery
+	lastDeliveryID int64
+	attempts       []models.WebhookAttempt
+	lastAttemptID  int64
+
+	linkCodes   map[string]models.BotLinkCode
+	botAccounts []models.BotAccount
+
+	// invitations by token hash
+	invitations map[string]models.Invitation
+	// streamTickets by ticket hash
+	streamTickets map[string]models.StreamTicket
+
+	campaigns      []models.Campaign
+	lastCampaignID int64
+
+	jobRuns      []models.JobRun
+	lastJobRunID int64
+
+	scores map[string]models.IdeaScore
+	// visits - browse history keyed by idea and visitor
+	visits map[voteKey]models.BrowseHistory
+
+	statusChanges      []models.IdeaStatusChange
+	lastStatusChangeID int64
+
+	revisions      []models.Revision
+	lastRevisionID int
+}
+
+func newState() *state {
+	return &state{
+		users:    map[string]models.User{},
+		ideas:    map[string]models.Idea{},
+		comments: map[string]models.Comment{},
+		votes:    map[voteKey]time.Time{},
+
+		reactions: map[reactionKey]time.Time{},
+
+		preferences: map[preferenceKey]bool{},
+
+		emailSettings: map[string]models.EmailSettings{},
+
+		linkCodes: map[string]models.BotLinkCode{},
+
+		invitations:   map[string]models.Invitation{},
+		streamTickets: map[string]models.StreamTicket{},
+
+		scores: map[string]models.IdeaScore{},
+		visits: map[voteKey]models.BrowseHistory{},
+	}
+}
+
+// clone copies everything WithTx may need to restore
+func (s *state) clone() *state {
+	return &state{
+		positions:  slices.Clone(s.positions),
+		categories: slices.Clone(s.categories),
+		statuses:   slices.Clone(s.statuses),
+		users:      cloneMap(s.users),
+		ideas:      cloneMap(s.ideas),
+		comments:   cloneMap(s.comments),
+		votes:      cloneMap(s.votes),
+
+		reactions: cloneMap(s.reactions),
+		mentions:  slices.Clone(s.mentions),
+
+		notifications:      slices.Clone(s.notifications),
+		lastNotificationID: s.lastNotificationID,
+		preferences:        cloneMap(s.preferences),
+
+		outbox:        slices.Clone(s.outbox),
+		lastOutboxID:  s.lastOutboxID,
+		emailSettings: cloneMap(s.emailSettings),
+
+		webhooks:       slices.Clone(s.webhooks),
+		lastWebhookID:  s.lastWebhookID,
+		deliveries:     slices.Clone(s.deliveries),
+		lastDeliveryID: s.lastDeliveryID,
+		attempts:       slices.Clone(s.attempts),
+		lastAttemptID:  s.lastAttemptID,
+
+		linkCodes:   cloneMap(s.linkCodes),
+		botAccounts: slices.Clone(s.botAccounts),
+
+		invitations:   cloneMap(s.invitations),
+		streamTickets: cloneMap(s.streamTickets),
+
+		campaigns:      slices.Clone(s.campaigns),
+		lastCampaignID: s.lastCampaignID,
+
+		jobRuns:      slices.Clone(s.jobRuns),
+		lastJobRunID: s.lastJobRunID,
+
+		scores: cloneMap(s.scores),
+		visits: cloneMap(s.visits),
+
+		statusChanges:      slices.Clone(s.statusChanges),
+		lastStatusChangeID: s.lastStatusChangeID,
+
+		revisions:      slices.Clone(s.revisions),
+		lastRevisionID: s.lastRevisionID,
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Repository - implements Repository interface in memory, safe for concurrent use
+type Repository struct {
+	mu   *sync.RWMutex
+	st   *state
+	inTx bool // the lock is already held by WithTx
+	log  slog.Logger
+}
+
+func New() *Repository {
+	return &Repository{
+		mu:  &sync.RWMutex{},
+		st:  newState(),
+		log: *slog.Default(),
+	}
+}
+
+func (r *Repository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+func (r *Repository) rlock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.RLock()
+	return r.mu.RUnlock
+}
+
+// SeedDictionaries loads positions, categories and statuses, which have no
+// insert methods in Repository (in PostgreSQL they are filled by hand)
+func (r *Repository) SeedDictionaries(positions []models.UserPosition, categories []models.IdeaCategory, statuses []models.IdeaStatus) {
+	defer r.lock()()
+	r.st.positions = append(r.st.positions, positions...)
+	r.st.categories = append(r.st.categories, categories...)
+	r.st.statuses = append(r.st.statuses, statuses...)
+}
+
+func (r *Repository) ConnectDB(ctx context.Context, sourceURL string, log slog.Logger) error {
+	r.log = log
+	r.log.DebugContext(ctx, "using in-memory repository")
+	return nil
+}
+
+func (r *Repository) CloseConnectDB() error {
+	return nil
+}
+
+// WithTx holds the write lock for the whole fn and restores the snapshot on error
+func (r *Repository) WithTx(ctx context.Context, fn func(repo repository.Repository) error) (err error) {
+	if r.inTx {
+		return fn(r)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := r.st.clone()
+	defer func() {
+		if p := recover(); p != nil {
+			*r.st = *snapshot
+			panic(p)
+		}
+		if err != nil {
+			*r.st = *snapshot
+		}
+	}()
+
+	return fn(&Repository{mu: r.mu, st: r.st, inTx: true, log: r.log})
+}
+
+func (r *Repository) InsertUser(ctx context.Context, user models.User) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[user.UID]; ok {
+		return repository.ErrConflict
+	}
+	if !hasID(r.st.positions, user.PositionID, func(p models.UserPosition) int { return p.ID }) {
+		return repository.ErrInvalidReference
+	}
+	r.st.users[user.UID] = user
+	return nil
+}
+
+func (r *Repository) SelectUserByEmail(ctx context.Context, email string) (models.User, error) {
+	defer r.rlock()()
+
+	for _, u := range r.st.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r *Repository) UserEmailTaken(ctx context.Context, email string) (bool, error) {
+	defer r.rlock()()
+
+	email = strings.ToLower(email)
+	for _, u := range r.st.users {
+		if strings.ToLower(u.Email) == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *Repository) SelectUserByUID(ctx context.Context, uid string) (models.User, error) {
+	defer r.rlock()()
+
+	u, ok := r.st.users[uid]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *Repository) SelectPositions(ctx context.Context) ([]models.UserPosition, error) {
+	defer r.rlock()()
+	return sortedByID(r.st.positions, func(p models.UserPosition) int { return p.ID }), nil
+}
+
+func (r *Repository) UpdateUserPassword(ctx context.Context, uid string, hash string) error {
+	defer r.lock()()
+
+	u, ok := r.st.users[uid]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	u.Password = hash
+	r.st.users[uid] = u
+	return nil
+}
+
+func (r *Repository) InsertPosition(ctx context.Context, name string) (models.UserPosition, error) {
+	defer r.lock()()
+
+	id := 0
+	for _, p := range r.st.positions {
+		if p.Name == name {
+			return models.UserPosition{}, repository.ErrConflict
+		}
+		id = max(id, p.ID)
+	}
+	position := models.UserPosition{ID: id + 1, Name: name}
+	r.st.positions = append(r.st.positions, position)
+	return position, nil
+}
+
+func (r *Repository) InsertInvitation(ctx context.Context, inv models.Invitation) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[inv.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if _, ok := r.st.invitations[inv.TokenHash]; ok {
+		return repository.ErrConflict
+	}
+	maps.DeleteFunc(r.st.invitations, func(_ string, i models.Invitation) bool { return i.UserUID == inv.UserUID })
+	r.st.invitations[inv.TokenHash] = inv
+	return nil
+}
+
+func (r *Repository) ConsumeInvitation(ctx context.Context, tokenHash string) (models.Invitation, error) {
+	defer r.lock()()
+
+	inv, ok := r.st.invitations[tokenHash]
+	if !ok {
+		return models.Invitation{}, repository.ErrNotFound
+	}
+	delete(r.st.invitations, tokenHash)
+	return inv, nil
+}
+
+func (r *Repository) InsertStreamTicket(ctx context.Context, ticket models.StreamTicket) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[ticket.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if _, ok := r.st.streamTickets[ticket.TicketHash]; ok {
+		return repository.ErrConflict
+	}
+	r.st.streamTickets[ticket.TicketHash] = ticket
+	return nil
+}
+
+func (r *Repository) ConsumeStreamTicket(ctx context.Context, ticketHash string) (models.StreamTicket, error) {
+	defer r.lock()()
+
+	t, ok := r.st.streamTickets[ticketHash]
+	if !ok {
+		return models.StreamTicket{}, repository.ErrNotFound
+	}
+	delete(r.st.streamTickets, ticketHash)
+	return t, nil
+}
+
+func (r *Repository) DeleteExpiredStreamTickets(ctx context.Context, now time.Time) (int, error) {
+	defer r.lock()()
+
+	n := len(r.st.streamTickets)
+	maps.DeleteFunc(r.st.streamTickets, func(_ string, t models.StreamTicket) bool { return !t.ExpiresAt.After(now) })
+	return n - len(r.st.streamTickets), nil
+}
+
+func (r *Repository) UpdateUserPfpURL(ctx context.Context, uid string, url string) error {
+	defer r.lock()()
+
+	u, ok := r.st.users[uid]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	u.PfpURL = &url
+	r.st.users[uid] = u
+	return nil
+}
+
+func (r *Repository) InsertIdea(ctx context.Context, idea models.Idea) error {
+	defer r.lock()()
+
+	if _, ok := r.st.ideas[idea.IdeaUID]; ok {
+		return repository.ErrConflict
+	}
+	if _, ok := r.st.users[idea.Author]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if !hasID(r.st.statuses, idea.StatusID, func(s models.IdeaStatus) int { return s.ID }) ||
+		!hasID(r.st.categories, idea.CategoryID, func(c models.IdeaCategory) int { return c.ID }) {
+		return repository.ErrInvalidReference
+	}
+	if idea.CampaignID != nil {
+		if _, err := r.campaign(*idea.CampaignID); err != nil {
+			return repository.ErrInvalidReference
+		}
+	}
+
+	// counters and creation date are DB defaults
+	idea.CreationDate = time.Now()
+	idea.LikeCount = 0
+	idea.DislikeCount = 0
+	r.st.ideas[idea.IdeaUID] = idea
+	return nil
+}
+
+func (r *Repository) SelectIdeas(ctx context.Context) ([]models.Idea, error) {
+	defer r.rlock()()
+
+	var ideas []models.Idea
+	for _, idea := range r.st.ideas {
+		ideas = append(ideas, idea)
+	}
+	slices.SortFunc(ideas, func(a, b models.Idea) int {
+		return compareTime(a.CreationDate, b.CreationDate, a.IdeaUID, b.IdeaUID)
+	})
+	return ideas, nil
+}
+
+func (r *Repository) SelectIdeaByUID(ctx context.Context, uid string) (models.Idea, error) {
+	defer r.rlock()()
+
+	idea, ok := r.st.ideas[uid]
+	if !ok {
+		return models.Idea{}, repository.ErrNotFound
+	}
+	return idea, nil
+}
+
+func (r *Repository) SelectUserIdeas(ctx context.Context, uid string, limit int) ([]models.Idea, error) {
+	defer r.rlock()()
+
+	var ideas []models.Idea
+	for _, idea := range r.st.ideas {
+		if idea.Author == uid {
+			ideas = append(ideas, idea)
+		}
+	}
+	slices.SortFunc(ideas, func(a, b models.Idea) int {
+		return -compareTime(a.CreationDate, b.CreationDate, a.IdeaUID, b.IdeaUID)
+	})
+	if limit > 0 && len(ideas) > limit {
+		ideas = ideas[:limit]
+	}
+	return ideas, nil
+}
+
+func (r *Repository) SearchIdeas(ctx context.Context, query string, limit int) ([]models.Idea, error) {
+	terms := repository.SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	defer r.rlock()()
+
+	type hit struct {
+		idea  models.Idea
+		score int
+	}
+	var hits []hit
+	for _, idea := range r.st.ideas {
+		words := map[string]int{}
+		for _, w := range repository.SearchTerms(idea.Name + " " + idea.Text) {
+			words[w]++
+		}
+
+		score := 0
+		for _, term := range terms {
+			if words[term] == 0 {
+				score = 0
+				break
+			}
+			score += words[term]
+		}
+		if score > 0 {
+			hits = append(hits, hit{idea: idea, score: score})
+		}
+	}
+
+	// the more occurrences the better, newer ideas win ties
+	slices.SortFunc(hits, func(a, b hit) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		return -compareTime(a.idea.CreationDate, b.idea.CreationDate, a.idea.IdeaUID, b.idea.IdeaUID)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	var ideas []models.Idea
+	for _, h := range hits {
+		ideas = append(ideas, h.idea)
+	}
+	return ideas, nil
+}
+
+func (r *Repository) SelectIdeaWithAuthor(ctx context.Context, uid string) (models.Idea, error) {
+	defer r.rlock()()
+
+	idea, ok := r.st.ideas[uid]
+	if !ok {
+		return models.Idea{}, repository.ErrNotFound
+	}
+	author := r.st.users[idea.Author]
+	return repository.IdeaRow{
+		Idea:          idea,
+		AuthorName:    author.Name,
+		AuthorSurname: author.Surname,
+		AuthorPfpURL:  author.PfpURL,
+	}.WithProfile(), nil
+}
+
+func (r *Repository) UpdateIdeaStatus(ctx context.Context, uid string, statusID int) error {
+	defer r.lock()()
+
+	idea, ok := r.st.ideas[uid]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if !hasID(r.st.statuses, statusID, func(s models.IdeaStatus) int { return s.ID }) {
+		return repository.ErrInvalidReference
+	}
+	idea.StatusID = statusID
+	r.st.ideas[uid] = idea
+	return nil
+}
+
+func (r *Repository) InsertIdeaStatusChange(ctx context.Context, change models.IdeaStatusChange) error {
+	defer r.lock()()
+
+	if _, ok := r.st.ideas[change.IdeaUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if !hasID(r.st.statuses, change.StatusID, func(s models.IdeaStatus) int { return s.ID }) {
+		return repository.ErrInvalidReference
+	}
+	if change.ChangedBy != nil {
+		if _, ok := r.st.users[*change.ChangedBy]; !ok {
+			return repository.ErrInvalidReference
+		}
+	}
+	r.st.lastStatusChangeID++
+	change.ID = r.st.lastStatusChangeID
+	r.st.statusChanges = append(r.st.statusChanges, change)
+	return nil
+}
+
+func (r *Repository) SelectIdeaStatusChanges(ctx context.Context, ideaUID string) ([]models.IdeaStatusChange, error) {
+	defer r.rlock()()
+
+	var changes []models.IdeaStatusChange
+	for _, c := range r.st.statusChanges {
+		if c.IdeaUID == ideaUID {
+			changes = append(changes, c)
+		}
+	}
+	slices.SortStableFunc(changes, func(a, b models.IdeaStatusChange) int {
+		return a.ChangedAt.Compare(b.ChangedAt)
+	})
+	return changes, nil
+}
+
+func (r *Repository) InsertIdeaComment(ctx context.Context, comment models.Comment) error {
+	defer r.lock()()
+
+	if _, ok := r.st.comments[comment.CommentUID]; ok {
+		return repository.ErrConflict
+	}
+	if _, ok := r.st.ideas[comment.IdeaUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if _, ok := r.st.users[comment.AuthorID]; !ok {
+		return repository.ErrInvalidReference
+	}
+
+	comment.Depth = 0
+	comment.Path = repository.ChildPath("", comment.CommentUID)
+	if comment.ParentUID != nil {
+		parent, ok := r.st.comments[*comment.ParentUID]
+		if !ok {
+			return repository.ErrInvalidReference
+		}
+		comment.Depth = parent.Depth + 1
+		comment.Path = repository.ChildPath(parent.Path, comment.CommentUID)
+	}
+
+	comment.Timestamp = time.Now()
+	comment.ChildCount = 0
+	comment.AuthorProfile = nil
+	r.st.comments[comment.CommentUID] = comment
+	return nil
+}
+
+func (r *Repository) SelectIdeaComments(ctx context.Context, ideaUID string) ([]models.Comment, error) {
+	defer r.rlock()()
+
+	var comments []models.Comment
+	for _, c := range r.st.comments {
+		if c.IdeaUID == ideaUID {
+			comments = append(comments, c)
+		}
+	}
+	sortComments(comments)
+	return comments, nil
+}
+
+func (r *Repository) SelectCommentByUID(ctx context.Context, uid string) (models.Comment, error) {
+	defer r.rlock()()
+
+	c, ok := r.st.comments[uid]
+	if !ok {
+		return models.Comment{}, repository.ErrNotFound
+	}
+	return c, nil
+}
+
+func (r *Repository) SelectCommentChildren(ctx context.Context, ideaUID, parentUID, afterUID string, limit int) ([]models.Comment, error) {
+	defer r.rlock()()
+
+	var after models.Comment
+	if afterUID != "" {
+		var ok bool
+		if after, ok = r.st.comments[afterUID]; !ok {
+			return nil, nil
+		}
+	}
+
+	var comments []models.Comment
+	for _, c := range r.st.comments {
+		if parentUID == "" && (c.IdeaUID != ideaUID || c.ParentUID != nil) {
+			continue
+		}
+		if parentUID != "" && (c.ParentUID == nil || *c.ParentUID != parentUID) {
+			continue
+		}
+		if afterUID != "" && compareTime(c.Timestamp, after.Timestamp, c.CommentUID, after.CommentUID) <= 0 {
+			continue
+		}
+		comments = append(comments, r.withThreadColumns(c))
+	}
+	sortComments(comments)
+	if limit > 0 && len(comments) > limit {
+		comments = comments[:limit]
+	}
+	return comments, nil
+}
+
+func (r *Repository) SelectCommentSubtrees(ctx context.Context, rootUIDs []string, maxDepth int) ([]models.Comment, error) {
+	defer r.rlock()()
+
+	var roots []models.Comment
+	for _, uid := range rootUIDs {
+		if root, ok := r.st.comments[uid]; ok {
+			roots = append(roots, root)
+		}
+	}
+
+	var comments []models.Comment
+	for _, c := range r.st.comments {
+		if c.Depth > maxDepth {
+			continue
+		}
+		for _, root := range roots {
+			if c.IdeaUID == root.IdeaUID && repository.InSubtree(c.Path, root.Path) {
+				comments = append(comments, r.withThreadColumns(c))
+				break
+			}
+		}
+	}
+	sortComments(comments)
+	return comments, nil
+}
+
+// withThreadColumns fills what the SQL backends join to comments of a thread
+func (r *Repository) withThreadColumns(c models.Comment) models.Comment {
+	for _, child := range r.st.comments {
+		if child.ParentUID != nil && *child.ParentUID == c.CommentUID {
+			c.ChildCount++
+		}
+	}
+	author := r.st.users[c.AuthorID]
+	return repository.CommentRow{
+		Comment:       c,
+		AuthorName:    author.Name,
+		AuthorSurname: author.Surname,
+		AuthorPfpURL:  author.PfpURL,
+	}.WithProfile()
+}
+
+func sortComments(comments []models.Comment) {
+	slices.SortFunc(comments, func(a, b models.Comment) int {
+		return compareTime(a.Timestamp, b.Timestamp, a.CommentUID, b.CommentUID)
+	})
+}
+
+func (r *Repository) UpdateCommentText(ctx context.Context, uid string, text string) error {
+	defer r.lock()()
+
+	c, ok := r.st.comments[uid]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	c.CommentText = text
+	c.EditedAt = &now
+	r.st.comments[uid] = c
+	return nil
+}
+
+func (r *Repository) TombstoneComment(ctx context.Context, uid string) error {
+	defer r.lock()()
+
+	c, ok := r.st.comments[uid]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	c.CommentText = ""
+	c.DeletedAt = &now
+	r.st.comments[uid] = c
+	return nil
+}
+
+func (r *Repository) InsertRevision(ctx context.Context, revision models.Revision) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[revision.EditorUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	r.st.lastRevisionID++
+	revision.ID = r.st.lastRevisionID
+	revision.EditedAt = time.Now()
+	r.st.revisions = append(r.st.revisions, revision)
+	return nil
+}
+
+func (r *Repository) SelectRevisions(ctx context.Context, targetUID string) ([]models.Revision, error) {
+	defer r.rlock()()
+
+	// appended in id order, which is edited_at order
+	var revisions []models.Revision
+	for _, rev := range r.st.revisions {
+		if rev.TargetUID == targetUID {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions, nil
+}
+
+func (r *Repository) DeleteRevisions(ctx context.Context, targetUID string) error {
+	defer r.lock()()
+
+	r.st.revisions = slices.DeleteFunc(r.st.revisions, func(rev models.Revision) bool {
+		return rev.TargetUID == targetUID
+	})
+	return nil
+}
+
+func (r *Repository) InsertReaction(ctx context.Context, reaction models.Reaction) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[reaction.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	key := reactionKey{targetUID: reaction.TargetUID, userUID: reaction.UserUID, emoji: reaction.Emoji}
+	if _, ok := r.st.reactions[key]; ok {
+		return repository.ErrConflict
+	}
+	r.st.reactions[key] = time.Now()
+	return nil
+}
+
+func (r *Repository) DeleteReaction(ctx context.Context, targetUID, userUID, emoji string) error {
+	defer r.lock()()
+
+	key := reactionKey{targetUID: targetUID, userUID: userUID, emoji: emoji}
+	if _, ok := r.st.reactions[key]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.st.reactions, key)
+	return nil
+}
+
+func (r *Repository) SelectReactionCounts(ctx context.Context, targetUIDs []string, viewerUID string) ([]models.ReactionCount, error) {
+	defer r.rlock()()
+
+	type group struct{ targetUID, emoji string }
+	counts := map[group]*models.ReactionCount{}
+	for key := range r.st.reactions {
+		if !slices.Contains(targetUIDs, key.targetUID) {
+			continue
+		}
+		g := group{key.targetUID, key.emoji}
+		if counts[g] == nil {
+			counts[g] = &models.ReactionCount{TargetUID: key.targetUID, Emoji: key.emoji}
+		}
+		counts[g].Count++
+		if key.userUID == viewerUID {
+			counts[g].Mine = true
+		}
+	}
+
+	var res []models.ReactionCount
+	for _, c := range counts {
+		res = append(res, *c)
+	}
+	slices.SortFunc(res, func(a, b models.ReactionCount) int {
+		if c := strings.Compare(a.TargetUID, b.TargetUID); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Emoji, b.Emoji)
+	})
+	return res, nil
+}
+
+func (r *Repository) InsertMentions(ctx context.Context, mentions []models.Mention) error {
+	defer r.lock()()
+
+	for _, m := range mentions {
+		_, authorOK := r.st.users[m.AuthorUID]
+		_, userOK := r.st.users[m.UserUID]
+		if !authorOK || !userOK {
+			return repository.ErrInvalidReference
+		}
+		for _, old := range r.st.mentions {
+			if old.TargetUID == m.TargetUID && old.Start == m.Start {
+				return repository.ErrConflict
+			}
+		}
+	}
+	now := time.Now()
+	for _, m := range mentions {
+		m.CreatedAt = now
+		m.User = nil
+		r.st.mentions = append(r.st.mentions, m)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteMentions(ctx context.Context, targetUID string) error {
+	defer r.lock()()
+
+	r.st.mentions = slices.DeleteFunc(r.st.mentions, func(m models.Mention) bool {
+		return m.TargetUID == targetUID
+	})
+	return nil
+}
+
+func (r *Repository) SelectMentions(ctx context.Context, targetUIDs []string) ([]models.Mention, error) {
+	defer r.rlock()()
+
+	var mentions []models.Mention
+	for _, m := range r.st.mentions {
+		if !slices.Contains(targetUIDs, m.TargetUID) {
+			continue
+		}
+		user := r.st.users[m.UserUID]
+		mentions = append(mentions, repository.MentionRow{
+			Mention:     m,
+			UserName:    user.Name,
+			UserSurname: user.Surname,
+			UserPfpURL:  user.PfpURL,
+		}.WithProfile())
+	}
+	slices.SortFunc(mentions, func(a, b models.Mention) int {
+		if c := strings.Compare(a.TargetUID, b.TargetUID); c != 0 {
+			return c
+		}
+		return a.Start - b.Start
+	})
+	return mentions, nil
+}
+
+func (r *Repository) SelectProfiles(ctx context.Context, uids []string) ([]models.AuthorProfile, error) {
+	defer r.rlock()()
+
+	var profiles []models.AuthorProfile
+	for k, uid := range uids {
+		if u, ok := r.st.users[uid]; ok && !slices.Contains(uids[:k], uid) {
+			profiles = append(profiles, profile(u))
+		}
+	}
+	return profiles, nil
+}
+
+func (r *Repository) SelectProfilesByName(ctx context.Context, names []models.FullName) ([]models.AuthorProfile, error) {
+	defer r.rlock()()
+
+	var profiles []models.AuthorProfile
+	for _, u := range r.st.users {
+		for _, n := range names {
+			if strings.EqualFold(u.Name, n.Name) && strings.EqualFold(u.Surname, n.Surname) {
+				profiles = append(profiles, profile(u))
+				break
+			}
+		}
+	}
+	return profiles, nil
+}
+
+func profile(u models.User) models.AuthorProfile {
+	return models.AuthorProfile{UID: u.UID, Name: u.Name, Surname: u.Surname, PfpURL: u.PfpURL}
+}
+
+func (r *Repository) InsertNotification(ctx context.Context, n models.Notification) (models.Notification, error) {
+	defer r.lock()()
+
+	_, userOK := r.st.users[n.UserUID]
+	_, actorOK := r.st.users[n.ActorUID]
+	_, ideaOK := r.st.ideas[n.IdeaUID]
+	if !userOK || !actorOK || !ideaOK {
+		return models.Notification{}, repository.ErrInvalidReference
+	}
+	r.st.lastNotificationID++
+	n.ID = r.st.lastNotificationID
+	n.CreatedAt = time.Now()
+	n.ReadAt = nil
+	n.Actor = nil
+	n.IdeaName = ""
+	r.st.notifications = append(r.st.notifications, n)
+	return n, nil
+}
+
+func (r *Repository) SelectNotifications(ctx context.Context, userUID string, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, error) {
+	defer r.rlock()()
+
+	// appended in id order, walk backwards for newest first
+	var res []models.Notification
+	for k := len(r.st.notifications) - 1; k >= 0 && len(res) < limit; k-- {
+		n := r.st.notifications[k]
+		if n.UserUID != userUID || (unreadOnly && n.ReadAt != nil) || (beforeID > 0 && n.ID >= beforeID) {
+			continue
+		}
+		actor := r.st.users[n.ActorUID]
+		res = append(res, repository.NotificationRow{
+			Notification: n,
+			ActorName:    actor.Name,
+			ActorSurname: actor.Surname,
+			ActorPfpURL:  actor.PfpURL,
+			IdeaTitle:    r.st.ideas[n.IdeaUID].Name,
+		}.WithDetails())
+	}
+	return res, nil
+}
+
+func (r *Repository) CountUnreadNotifications(ctx context.Context, userUID string) (int, error) {
+	defer r.rlock()()
+
+	count := 0
+	for _, n := range r.st.notifications {
+		if n.UserUID == userUID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *Repository) MarkNotificationRead(ctx context.Context, userUID string, id int64) error {
+	defer r.lock()()
+
+	for k, n := range r.st.notifications {
+		if n.ID != id || n.UserUID != userUID {
+			continue
+		}
+		if n.ReadAt == nil {
+			now := time.Now()
+			r.st.notifications[k].ReadAt = &now
+		}
+		return nil
+	}
+	return repository.ErrNotFound
+}
+
+func (r *Repository) MarkAllNotificationsRead(ctx context.Context, userUID string) error {
+	defer r.lock()()
+
+	now := time.Now()
+	for k, n := range r.st.notifications {
+		if n.UserUID == userUID && n.ReadAt == nil {
+			r.st.notifications[k].ReadAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *Repository) SelectNotificationPreferences(ctx context.Context, userUID string) ([]models.NotificationPreference, error) {
+	defer r.rlock()()
+
+	var preferences []models.NotificationPreference
+	for key, enabled := range r.st.preferences {
+		if key.userUID == userUID {
+			preferences = append(preferences, models.NotificationPreference{UserUID: userUID, Type: key.typ, Enabled: enabled})
+		}
+	}
+	slices.SortFunc(preferences, func(a, b models.NotificationPreference) int {
+		return strings.Compare(string(a.Type), string(b.Type))
+	})
+	return preferences, nil
+}
+
+func (r *Repository) UpsertNotificationPreference(ctx context.Context, preference models.NotificationPreference) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[preference.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	r.st.preferences[preferenceKey{userUID: preference.UserUID, typ: preference.Type}] = preference.Enabled
+	return nil
+}
+
+func (r *Repository) InsertOutboxEmail(ctx context.Context, e models.OutboxEmail) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[e.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	r.st.lastOutboxID++
+	e.ID = r.st.lastOutboxID
+	e.Attempts = 0
+	e.LastError = ""
+	e.CreatedAt = time.Now()
+	e.SentAt, e.FailedAt = nil, nil
+	r.st.outbox = append(r.st.outbox, e)
+	return nil
+}
+
+func (r *Repository) ClaimOutboxEmails(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEmail, error) {
+	defer r.lock()()
+
+	var due []int
+	for k, e := range r.st.outbox {
+		if e.SentAt == nil && e.FailedAt == nil && !e.NextAttemptAt.After(now) {
+			due = append(due, k)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b int) int {
+		return r.st.outbox[a].NextAttemptAt.Compare(r.st.outbox[b].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	slices.Sort(due)
+
+	claimed := make([]models.OutboxEmail, 0, len(due))
+	for _, k := range due {
+		r.st.outbox[k].Attempts++
+		r.st.outbox[k].NextAttemptAt = leaseUntil
+		claimed = append(claimed, r.st.outbox[k])
+	}
+	return claimed, nil
+}
+
+func (r *Repository) outboxEmail(id int64) (*models.OutboxEmail, error) {
+	for k := range r.st.outbox {
+		if r.st.outbox[k].ID == id {
+			return &r.st.outbox[k], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *Repository) MarkOutboxEmailSent(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	e, err := r.outboxEmail(id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	e.SentAt = &now
+	e.LastError = ""
+	return nil
+}
+
+func (r *Repository) FailOutboxEmail(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	defer r.lock()()
+
+	e, err := r.outboxEmail(id)
+	if err != nil {
+		return err
+	}
+	e.LastError = lastError
+	if retryAt != nil {
+		e.NextAttemptAt = *retryAt
+	} else {
+		now := time.Now()
+		e.FailedAt = &now
+	}
+	return nil
+}
+
+func (r *Repository) SelectOutboxEmails(ctx context.Context, userUID string, limit int) ([]models.OutboxEmail, error) {
+	defer r.rlock()()
+
+	var res []models.OutboxEmail
+	for k := len(r.st.outbox) - 1; k >= 0 && len(res) < limit; k-- {
+		if userUID == "" || r.st.outbox[k].UserUID == userUID {
+			res = append(res, r.st.outbox[k])
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository) SelectEmailSettings(ctx context.Context, userUID string) (models.EmailSettings, error) {
+	defer r.rlock()()
+
+	settings, ok := r.st.emailSettings[userUID]
+	if !ok {
+		return models.EmailSettings{}, repository.ErrNotFound
+	}
+	return settings, nil
+}
+
+func (r *Repository) UpsertEmailSettings(ctx context.Context, settings models.EmailSettings) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[settings.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	settings.LastDigestAt = r.st.emailSettings[settings.UserUID].LastDigestAt
+	r.st.emailSettings[settings.UserUID] = settings
+	return nil
+}
+
+func (r *Repository) SelectDigestRecipients(ctx context.Context, frequency models.DigestFrequency, sentBefore time.Time, limit int) ([]models.DigestRecipient, error) {
+	defer r.rlock()()
+
+	var res []models.DigestRecipient
+	for _, u := range r.st.users {
+		settings, ok := r.st.emailSettings[u.UID]
+		if !ok {
+			settings = models.DefaultEmailSettings
+			settings.UserUID = u.UID
+		}
+		if settings.Digest != frequency || settings.LastDigestAt != nil && !settings.LastDigestAt.Before(sentBefore) {
+			continue
+		}
+		res = append(res, models.DigestRecipient{EmailSettings: settings, Email: u.Email, Name: u.Name})
+	}
+	slices.SortFunc(res, func(a, b models.DigestRecipient) int { return strings.Compare(a.UserUID, b.UserUID) })
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *Repository) MarkDigestSent(ctx context.Context, userUID string, at time.Time) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[userUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	settings, ok := r.st.emailSettings[userUID]
+	if !ok {
+		settings = models.DefaultEmailSettings
+		settings.UserUID = userUID
+	}
+	settings.LastDigestAt = &at
+	r.st.emailSettings[userUID] = settings
+	return nil
+}
+
+func (r *Repository) SelectTrendingIdeas(ctx context.Context, since time.Time, limit int) ([]models.TrendingIdea, error) {
+	defer r.rlock()()
+
+	recent := map[string]int{}
+	for key, votedAt := range r.st.votes {
+		if !votedAt.Before(since) {
+			recent[key.ideaUID]++
+		}
+	}
+	var res []models.TrendingIdea
+	for uid, n := range recent {
+		if idea, ok := r.st.ideas[uid]; ok {
+			res = append(res, models.TrendingIdea{Idea: idea, RecentVotes: n})
+		}
+	}
+	slices.SortFunc(res, func(a, b models.TrendingIdea) int {
+		if a.RecentVotes != b.RecentVotes {
+			return b.RecentVotes - a.RecentVotes
+		}
+		if c := b.CreationDate.Compare(a.CreationDate); c != 0 {
+			return c
+		}
+		return strings.Compare(a.IdeaUID, b.IdeaUID)
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *Repository) InsertWebhook(ctx context.Context, w models.Webhook) (models.Webhook, error) {
+	defer r.lock()()
+
+	if w.CreatedBy != nil {
+		if _, ok := r.st.users[*w.CreatedBy]; !ok {
+			return models.Webhook{}, repository.ErrInvalidReference
+		}
+	}
+	r.st.lastWebhookID++
+	w.ID = r.st.lastWebhookID
+	w.CreatedAt = time.Now()
+	// stored webhooks are replaced, never changed in place
+	w.Events = slices.Clone(w.Events)
+	r.st.webhooks = append(r.st.webhooks, w)
+	return w, nil
+}
+
+func (r *Repository) webhook(id int64) (*models.Webhook, error) {
+	for k := range r.st.webhooks {
+		if r.st.webhooks[k].ID == id {
+			return &r.st.webhooks[k], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *Repository) UpdateWebhook(ctx context.Context, w models.Webhook) error {
+	defer r.lock()()
+
+	stored, err := r.webhook(w.ID)
+	if err != nil {
+		return err
+	}
+	stored.URL = w.URL
+	stored.Secret = w.Secret
+	stored.Events = slices.Clone(w.Events)
+	stored.Active = w.Active
+	return nil
+}
+
+func (r *Repository) DeleteWebhook(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	if _, err := r.webhook(id); err != nil {
+		return err
+	}
+	r.st.webhooks = slices.DeleteFunc(r.st.webhooks, func(w models.Webhook) bool { return w.ID == id })
+	deleted := map[int64]bool{}
+	r.st.deliveries = slices.DeleteFunc(r.st.deliveries, func(d models.WebhookDelivery) bool {
+		deleted[d.ID] = d.WebhookID == id
+		return deleted[d.ID]
+	})
+	r.st.attempts = slices.DeleteFunc(r.st.attempts, func(a models.WebhookAttempt) bool { return deleted[a.DeliveryID] })
+	return nil
+}
+
+func (r *Repository) SelectWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	defer r.rlock()()
+
+	w, err := r.webhook(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	res := *w
+	res.Events = slices.Clone(w.Events)
+	return res, nil
+}
+
+func (r *Repository) SelectWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	defer r.rlock()()
+
+	res := make([]models.Webhook, 0, len(r.st.webhooks))
+	for _, w := range r.st.webhooks {
+		w.Events = slices.Clone(w.Events)
+		res = append(res, w)
+	}
+	return res, nil
+}
+
+func (r *Repository) InsertWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	defer r.lock()()
+
+	if _, err := r.webhook(d.WebhookID); err != nil {
+		return models.WebhookDelivery{}, repository.ErrInvalidReference
+	}
+	r.st.lastDeliveryID++
+	d.ID = r.st.lastDeliveryID
+	d.CreatedAt = time.Now()
+	d.ResponseCode, d.LastError, d.DeliveredAt, d.Log = nil, "", nil, nil
+	r.st.deliveries = append(r.st.deliveries, d)
+	return d, nil
+}
+
+func (r *Repository) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	defer r.lock()()
+
+	var due []int
+	for k, d := range r.st.deliveries {
+		if d.State == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, k)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b int) int {
+		return r.st.deliveries[a].NextAttemptAt.Compare(r.st.deliveries[b].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	slices.Sort(due)
+
+	claimed := make([]models.WebhookDelivery, 0, len(due))
+	for _, k := range due {
+		r.st.deliveries[k].Attempts++
+		r.st.deliveries[k].NextAttemptAt = leaseUntil
+		claimed = append(claimed, r.st.deliveries[k])
+	}
+	return claimed, nil
+}
+
+func (r *Repository) delivery(id int64) (*models.WebhookDelivery, error) {
+	for k := range r.st.deliveries {
+		if r.st.deliveries[k].ID == id {
+			return &r.st.deliveries[k], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *Repository) RecordWebhookAttempt(ctx context.Context, a models.WebhookAttempt, state models.DeliveryState, retryAt time.Time) error {
+	defer r.lock()()
+
+	d, err := r.delivery(a.DeliveryID)
+	if err != nil {
+		return err
+	}
+	r.st.lastAttemptID++
+	a.ID = r.st.lastAttemptID
+	a.AttemptedAt = time.Now()
+	r.st.attempts = append(r.st.attempts, a)
+
+	d.State = state
+	d.ResponseCode = a.ResponseCode
+	d.LastError = a.Error
+	switch state {
+	case models.DeliveryPending:
+		d.NextAttemptAt = retryAt
+	case models.DeliveryDelivered:
+		d.DeliveredAt = &a.AttemptedAt
+	}
+	return nil
+}
+
+func (r *Repository) RequeueWebhookDelivery(ctx context.Context, id int64, at time.Time) error {
+	defer r.lock()()
+
+	d, err := r.delivery(id)
+	if err != nil {
+		return err
+	}
+	d.State = models.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = at
+	return nil
+}
+
+func (r *Repository) SelectWebhookDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	defer r.rlock()()
+
+	d, err := r.delivery(id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return *d, nil
+}
+
+func (r *Repository) SelectWebhookDeliveries(ctx context.Context, webhookID int64, state models.DeliveryState, limit int) ([]models.WebhookDelivery, error) {
+	defer r.rlock()()
+
+	var res []models.WebhookDelivery
+	for k := len(r.st.deliveries) - 1; k >= 0 && len(res) < limit; k-- {
+		d := r.st.deliveries[k]
+		if d.WebhookID == webhookID && (state == "" || d.State == state) {
+			res = append(res, d)
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository) SelectWebhookAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookAttempt, error) {
+	defer r.rlock()()
+
+	var res []models.WebhookAttempt
+	for _, a := range r.st.attempts {
+		if a.DeliveryID == deliveryID {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository) InsertBotLinkCode(ctx context.Context, code models.BotLinkCode) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[code.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	if _, ok := r.st.linkCodes[code.Code]; ok {
+		return repository.ErrConflict
+	}
+	maps.DeleteFunc(r.st.linkCodes, func(_ string, c models.BotLinkCode) bool { return c.UserUID == code.UserUID })
+	r.st.linkCodes[code.Code] = code
+	return nil
+}
+
+func (r *Repository) ConsumeBotLinkCode(ctx context.Context, code string) (models.BotLinkCode, error) {
+	defer r.lock()()
+
+	c, ok := r.st.linkCodes[code]
+	if !ok {
+		return models.BotLinkCode{}, repository.ErrNotFound
+	}
+	delete(r.st.linkCodes, code)
+	return c, nil
+}
+
+func (r *Repository) DeleteExpiredBotLinkCodes(ctx context.Context, now time.Time) (int, error) {
+	defer r.lock()()
+
+	n := len(r.st.linkCodes)
+	maps.DeleteFunc(r.st.linkCodes, func(_ string, c models.BotLinkCode) bool { return !c.ExpiresAt.After(now) })
+	return n - len(r.st.linkCodes), nil
+}
+
+func (r *Repository) LinkBotAccount(ctx context.Context, account models.BotAccount) error {
+	defer r.lock()()
+
+	if _, ok := r.st.users[account.UserUID]; !ok {
+		return repository.ErrInvalidReference
+	}
+	r.st.botAccounts = slices.DeleteFunc(r.st.botAccounts, func(a models.BotAccount) bool {
+		return a.Platform == account.Platform && (a.ExternalID == account.ExternalID || a.UserUID == account.UserUID)
+	})
+	account.LinkedAt = time.Now()
+	r.st.botAccounts = append(r.st.botAccounts, account)
+	return nil
+}
+
+func (r *Repository) SelectBotAccount(ctx context.Context, platform, externalID string) (models.BotAccount, error) {
+	defer r.rlock()()
+
+	for _, a := range r.st.botAccounts {
+		if a.Platform == platform && a.ExternalID == externalID {
+			return a, nil
+		}
+	}
+	return models.BotAccount{}, repository.ErrNotFound
+}
+
+func (r *Repository) SelectBotAccounts(ctx context.Context, userUID string) ([]models.BotAccount, error) {
+	defer r.rlock()()
+
+	var res []models.BotAccount
+	for _, a := range r.st.botAccounts {
+		if a.UserUID == userUID {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository) DeleteBotAccount(ctx context.Context, platform, externalID string) error {
+	defer r.lock()()
+
+	n := len(r.st.botAccounts)
+	r.st.botAccounts = slices.DeleteFunc(r.st.botAccounts, func(a models.BotAccount) bool {
+		return a.Platform == platform && a.ExternalID == externalID
+	})
+	if len(r.st.botAccounts) == n {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) InsertCampaign(ctx context.Context, c models.Campaign) (models.Campaign, error) {
+	defer r.lock()()
+
+	if c.CreatedBy != nil {
+		if _, ok := r.st.users[*c.CreatedBy]; !ok {
+			return models.Campaign{}, repository.ErrInvalidReference
+		}
+	}
+	r.st.lastCampaignID++
+	c.ID = r.st.lastCampaignID
+	c.CreatedAt = time.Now()
+	// stored campaigns are replaced, never changed in place
+	c.CategoryIDs = slices.Clone(c.CategoryIDs)
+	c.PositionIDs = slices.Clone(c.PositionIDs)
+	r.st.campaigns = append(r.st.campaigns, c)
+	return c, nil
+}
+
+func (r *Repository) campaign(id int64) (*models.Campaign, error) {
+	for k := range r.st.campaigns {
+		if r.st.campaigns[k].ID == id {
+			return &r.st.campaigns[k], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *Repository) UpdateCampaign(ctx context.Context, c models.Campaign) error {
+	defer r.lock()()
+
+	stored, err := r.campaign(c.ID)
+	if err != nil {
+		return err
+	}
+	stored.Title = c.Title
+	stored.Description = c.Description
+	stored.StartsAt = c.StartsAt
+	stored.EndsAt = c.EndsAt
+	stored.CategoryIDs = slices.Clone(c.CategoryIDs)
+	stored.PositionIDs = slices.Clone(c.PositionIDs)
+	return nil
+}
+
+func (r *Repository) DeleteCampaign(ctx context.Context, id int64) error {
+	defer r.lock()()
+
+	if _, err := r.campaign(id); err != nil {
+		return err
+	}
+	r.st.campaigns = slices.DeleteFunc(r.st.campaigns, func(c models.Campaign) bool { return c.ID == id })
+	for uid, idea := range r.st.ideas {
+		if idea.CampaignID != nil && *idea.CampaignID == id {
+			idea.CampaignID = nil
+			r.st.ideas[uid] = idea
+		}
+	}
+	return nil
+}
+
+func (r *Repository) SelectCampaign(ctx context.Context, id int64) (models.Campaign, error) {
+	defer r.rlock()()
+
+	c, err := r.campaign(id)
+	if err != nil {
+		return models.Campaign{}, err
+	}
+	res := *c
+	res.CategoryIDs = slices.Clone(c.CategoryIDs)
+	res.PositionIDs = slices.Clone(c.PositionIDs)
+	return res, nil
+}
+
+func (r *Repository) SelectCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	defer r.rlock()()
+
+	res := make([]models.Campaign, 0, len(r.st.campaigns))
+	for _, c := range r.st.campaigns {
+		c.CategoryIDs = slices.Clone(c.CategoryIDs)
+		c.PositionIDs = slices.Clone(c.PositionIDs)
+		res = append(res, c)
+	}
+	slices.SortFunc(res, func(a, b models.Campaign) int {
+		if c := b.StartsAt.Compare(a.StartsAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return res, nil
+}
+
+// campaignIdeas returns ideas of the campaign in no particular order
+func (r *Repository) campaignIdeas(campaignID int64) []models.Idea {
+	var ideas []models.Idea
+	for _, idea := range r.st.ideas {
+		if idea.CampaignID != nil && *idea.CampaignID == campaignID {
+			ideas = append(ideas, idea)
+		}
+	}
+	return ideas
+}
+
+func (r *Repository) SelectCampaignLeaderboard(ctx context.Context, campaignID int64, limit int) ([]models.Idea, error) {
+	defer r.rlock()()
+
+	ideas := r.campaignIdeas(campaignID)
+	slices.SortFunc(ideas, func(a, b models.Idea) int {
+		if c := cmp.Compare(b.LikeCount-b.DislikeCount, a.LikeCount-a.DislikeCount); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.LikeCount, a.LikeCount); c != 0 {
+			return c
+		}
+		return compareTime(a.CreationDate, b.CreationDate, a.IdeaUID, b.IdeaUID)
+	})
+	return ideas[:min(len(ideas), limit)], nil
+}
+
+func (r *Repository) SelectCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error) {
+	defer r.rlock()()
+
+	var stats models.CampaignStats
+	authors, voters := map[string]bool{}, map[string]bool{}
+	for _, idea := range r.campaignIdeas(campaignID) {
+		stats.Ideas++
+		stats.Likes += idea.LikeCount
+		stats.Dislikes += idea.DislikeCount
+		authors[idea.Author] = true
+		for k := range r.st.votes {
+			if k.ideaUID == idea.IdeaUID {
+				voters[k.userUID] = true
+			}
+		}
+		for _, c := range r.st.comments {
+			if c.IdeaUID == idea.IdeaUID && c.DeletedAt == nil {
+				stats.Comments++
+			}
+		}
+	}
+	stats.Authors, stats.Voters = len(authors), len(voters)
+	return stats, nil
+}
+
+func (r *Repository) InsertJobRun(ctx context.Context, run models.JobRun) (models.JobRun, error) {
+	defer r.lock()()
+
+	if run.TriggeredBy != nil {
+		if _, ok := r.st.users[*run.TriggeredBy]; !ok {
+			return models.JobRun{}, repository.ErrInvalidReference
+		}
+	}
+	if run.ScheduledAt != nil && slices.ContainsFunc(r.st.jobRuns, func(j models.JobRun) bool {
+		return j.Job == run.Job && j.ScheduledAt != nil && j.ScheduledAt.Equal(*run.ScheduledAt)
+	}) {
+		return models.JobRun{}, repository.ErrConflict
+	}
+	r.st.lastJobRunID++
+	run.ID = r.st.lastJobRunID
+	run.Error, run.FinishedAt = "", nil
+	r.st.jobRuns = append(r.st.jobRuns, run)
+	return run, nil
+}
+
+func (r *Repository) FinishJobRun(ctx context.Context, id int64, status models.JobStatus, runErr string, finishedAt time.Time) error {
+	defer r.lock()()
+
+	for k := range r.st.jobRuns {
+		if r.st.jobRuns[k].ID == id {
+			r.st.jobRuns[k].Status = status
+			r.st.jobRuns[k].Error = runErr
+			r.st.jobRuns[k].FinishedAt = &finishedAt
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *Repository) SelectJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	defer r.rlock()()
+
+	var runs []models.JobRun
+	for _, run := range slices.Backward(r.st.jobRuns) {
+		if len(runs) == limit {
+			break
+		}
+		if job == "" || run.Job == job {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func (r *Repository) DeleteJobRuns(ctx context.Context, before time.Time) (int, error) {
+	defer r.lock()()
+
+	n := len(r.st.jobRuns)
+	r.st.jobRuns = slices.DeleteFunc(r.st.jobRuns, func(run models.JobRun) bool {
+		return run.FinishedAt != nil && run.StartedAt.Before(before)
+	})
+	return n - len(r.st.jobRuns), nil
+}
+
+func (r *Repository) SelectIdeaActivity(ctx context.Context, since time.Time, uids ...string) ([]models.IdeaActivity, error) {
+	defer r.rlock()()
+
+	var res []models.IdeaActivity
+	for _, idea := range r.st.ideas {
+		if len(uids) > 0 && !slices.Contains(uids, idea.IdeaUID) {
+			continue
+		}
+		a := models.IdeaActivity{
+			IdeaUID:      idea.IdeaUID,
+			CreationDate: idea.CreationDate,
+			LikeCount:    idea.LikeCount,
+			DislikeCount: idea.DislikeCount,
+			Views:        idea.ViewCount,
+		}
+		for k, at := range r.st.votes {
+			if k.ideaUID == idea.IdeaUID && !at.Before(since) {
+				a.RecentVotes++
+			}
+		}
+		for _, c := range r.st.comments {
+			if c.IdeaUID != idea.IdeaUID || c.DeletedAt != nil {
+				continue
+			}
+			a.Comments++
+			if !c.Timestamp.Before(since) {
+				a.RecentComments++
+			}
+		}
+		res = append(res, a)
+	}
+	slices.SortFunc(res, func(a, b models.IdeaActivity) int { return strings.Compare(a.IdeaUID, b.IdeaUID) })
+	return res, nil
+}
+
+func (r *Repository) UpsertIdeaScores(ctx context.Context, scores []models.IdeaScore) error {
+	defer r.lock()()
+
+	for _, score := range scores {
+		if _, ok := r.st.ideas[score.IdeaUID]; !ok {
+			return repository.ErrInvalidReference
+		}
+	}
+	for _, score := range scores {
+		r.st.scores[score.IdeaUID] = score
+	}
+	return nil
+}
+
+func (r *Repository) SelectRankedIdeas(ctx context.Context, sort models.IdeaSort) ([]models.Idea, error) {
+	if !slices.Contains(models.IdeaSorts, sort) {
+		return nil, fmt.Errorf("unknown idea sort %q", sort)
+	}
+	defer r.rlock()()
+
+	value := func(s models.IdeaScore) float64 {
+		switch sort {
+		case models.SortHot:
+			return s.Hot
+		case models.SortTop:
+			return s.Top
+		case models.SortControversial:
+			return s.Controversial
+		default:
+			return s.Rising
+		}
+	}
+	ideas := slices.Collect(maps.Values(r.st.ideas))
+	slices.SortFunc(ideas, func(a, b models.Idea) int {
+		sa, okA := r.st.scores[a.IdeaUID]
+		sb, okB := r.st.scores[b.IdeaUID]
+		if okA != okB {
+			if okA {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(value(sb), value(sa)); okA && c != 0 {
+			return c
+		}
+		return compareTime(b.CreationDate, a.CreationDate, a.IdeaUID, b.IdeaUID)
+	})
+	return ideas, nil
+}
+
+func (r *Repository) SelectIdeaCategories(ctx context.Context) ([]models.IdeaCategory, error) {
+	defer r.rlock()()
+	return sortedByID(r.st.categories, func(c models.IdeaCategory) int { return c.ID }), nil
+}
+
+func (r *Repository) SelectIdeaStatuses(ctx context.Context) ([]models.IdeaStatus, error) {
+	defer r.rlock()()
+	return sortedByID(r.st.statuses, func(s models.IdeaStatus) int { return s.ID }), nil
+}
+
+func (r *Repository) CheckVote(ctx context.Context, ideaUID string, userUID string) (bool, error) {
+	defer r.lock()()
+
+	key := voteKey{ideaUID: ideaUID, userUID: userUID}
+	if _, ok := r.st.votes[key]; ok {
+		return false, nil
+	}
+	if _, ok := r.st.ideas[ideaUID]; !ok {
+		return false, repository.ErrInvalidReference
+	}
+	if _, ok := r.st.users[userUID]; !ok {
+		return false, repository.ErrInvalidReference
+	}
+
+	r.st.votes[key] = time.Now()
+	return true, nil
+}
+
+func (r *Repository) UpsertBrowseHistory(ctx context.Context, visit models.BrowseHistory, countedAfter time.Time) (bool, error) {
+	defer r.lock()()
+
+	if _, ok := r.st.users[visit.VisitorID]; !ok {
+		return false, repository.ErrInvalidReference
+	}
+	if _, ok := r.st.ideas[visit.IdeaID]; !ok {
+		return false, repository.ErrInvalidReference
+	}
+	key := voteKey{ideaUID: visit.IdeaID, userUID: visit.VisitorID}
+	prev, ok := r.st.visits[key]
+	counted := !ok || !prev.CountedAt.After(countedAfter)
+	visit.CountedAt = visit.ViewedAt
+	if !counted {
+		visit.CountedAt = prev.CountedAt
+	}
+	r.st.visits[key] = visit
+	return counted, nil
+}
+
+func (r *Repository) IncrementViewCount(ctx context.Context, ideaUID string) error {
+	defer r.lock()()
+
+	idea, ok := r.st.ideas[ideaUID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	idea.ViewCount++
+	r.st.ideas[ideaUID] = idea
+	return nil
+}
+
+func (r *Repository) SelectIdeaVisits(ctx context.Context, visitorUID string, ideaUIDs []string) ([]models.IdeaVisit, error) {
+	defer r.rlock()()
+
+	var visits []models.IdeaVisit
+	for _, uid := range ideaUIDs {
+		visit, ok := r.st.visits[voteKey{ideaUID: uid, userUID: visitorUID}]
+		if !ok {
+			continue
+		}
+		v := models.IdeaVisit{IdeaUID: uid, ViewedAt: visit.ViewedAt}
+		for _, c := range r.st.comments {
+			if c.IdeaUID == uid && c.Timestamp.After(visit.ViewedAt) && c.DeletedAt == nil && c.AuthorID != visitorUID {
+				v.UnreadComments++
+			}
+		}
+		visits = append(visits, v)
+	}
+	return visits, nil
+}
+
+func (r *Repository) SelectViewedIdeas(ctx context.Context, visitorUID string, limit int) ([]models.Idea, error) {
+	defer r.rlock()()
+
+	var visits []models.BrowseHistory
+	for k, visit := range r.st.visits {
+		if k.userUID == visitorUID {
+			visits = append(visits, visit)
+		}
+	}
+	slices.SortFunc(visits, func(a, b models.BrowseHistory) int {
+		return compareTime(b.ViewedAt, a.ViewedAt, a.IdeaID, b.IdeaID)
+	})
+	visits = visits[:min(limit, len(visits))]
+	ideas := make([]models.Idea, 0, len(visits))
+	for _, visit := range visits {
+		ideas = append(ideas, r.st.ideas[visit.IdeaID])
+	}
+	return ideas, nil
+}
+
+func (r *Repository) IncrementLikeCount(ctx context.Context, ideaUID string) error {
+	defer r.lock()()
+
+	idea, ok := r.st.ideas[ideaUID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	idea.LikeCount++
+	r.st.ideas[ideaUID] = idea
+	return nil
+}
+
+func (r *Repository) IncrementDislikeCount(ctx context.Context, ideaUID string) error {
+	defer r.lock()()
+
+	idea, ok := r.st.ideas[ideaUID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	idea.DislikeCount++
+	r.st.ideas[ideaUID] = idea
+	return nil
+}
+
+func hasID[T any](items []T, id int, idOf func(T) int) bool {
+	for _, item := range items {
+		if idOf(item) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedByID[T any](items []T, idOf func(T) int) []T {
+	if len(items) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(items)
+	slices.SortFunc(sorted, func(a, b T) int { return idOf(a) - idOf(b) })
+	return sorted
+}
+
+// compareTime orders by time, then by uid as a tie breaker
+func compareTime(a, b time.Time, uidA, uidB string) int {
+	if c := a.Compare(b); c != 0 {
+		return c
+	}
+	return strings.Compare(uidA, uidB)
+}
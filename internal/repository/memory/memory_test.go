@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) repository.Repository {
+	repo := New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	return repo
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, newRepo)
+}
+
+func TestConcurrentVotes(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	u := repotest.User(t, repo, "author@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "popular")
+
+	const voters = 20
+	var wg sync.WaitGroup
+	for i := 0; i < voters; i++ {
+		voter := repotest.User(t, repo, fmt.Sprintf("voter%d@example.com", i))
+		// every voter tries twice, only the first vote counts
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = repo.WithTx(ctx, func(tx repository.Repository) error {
+					ok, err := tx.CheckVote(ctx, idea.IdeaUID, voter.UID)
+					if err != nil || !ok {
+						return err
+					}
+					return tx.IncrementLikeCount(ctx, idea.IdeaUID)
+				})
+			}()
+		}
+	}
+	wg.Wait()
+
+	got, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, voters, got.LikeCount)
+}
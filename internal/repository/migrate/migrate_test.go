@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_SortsAndPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_votes.up.sql":   {Data: []byte("CREATE TABLE votes();")},
+		"0002_add_votes.down.sql": {Data: []byte("DROP TABLE votes;")},
+		"0001_init.up.sql":        {Data: []byte("CREATE TABLE users();")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, Migration{Version: 1, Name: "init", Up: "CREATE TABLE users();"}, migrations[0])
+	assert.Equal(t, Migration{
+		Version: 2,
+		Name:    "add_votes",
+		Up:      "CREATE TABLE votes();",
+		Down:    "DROP TABLE votes;",
+	}, migrations[1])
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load(fstest.MapFS{"init.sql": {Data: []byte("")}})
+	assert.ErrorContains(t, err, "name must look like")
+
+	_, err = Load(fstest.MapFS{"0001_init.down.sql": {Data: []byte("DROP TABLE users;")}})
+	assert.ErrorContains(t, err, "has no up file")
+
+	_, err = Load(fstest.MapFS{
+		"0001_init.up.sql":  {Data: []byte("CREATE TABLE users();")},
+		"0001_other.up.sql": {Data: []byte("CREATE TABLE ideas();")},
+	})
+	assert.ErrorContains(t, err, "different names")
+}
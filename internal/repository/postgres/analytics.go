@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// within matches rows whose column falls within [from, to)
+func within(column string, from, to time.Time) sq.And {
+	return sq.And{sq.GtOrEq{column: from}, sq.Lt{column: to}}
+}
+
+func (pg *PostgresRepository) CountIdeasByPeriod(ctx context.Context, from, to time.Time, group models.AnalyticsGroup) ([]models.PeriodCount, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Select("count(*) AS ideas").
+		Column(sq.Expr("date_trunc(?, creation_date AT TIME ZONE 'UTC') AS period", string(group))).
+		From("ideas").
+		Where(within("creation_date", from, to)).
+		GroupBy("period").
+		OrderBy("period").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var counts []models.PeriodCount
+	for rows.Next() {
+		var c models.PeriodCount
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		c.Period = c.Period.UTC()
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+func (pg *PostgresRepository) SelectCategoryOutcomes(ctx context.Context, from, to time.Time, approved, rejected []int) ([]models.CategoryOutcomes, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	isApproved, approvedArgs, err := sq.Eq{"status_id": approved}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	isRejected, rejectedArgs, err := sq.Eq{"status_id": rejected}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	q, args, err := psql.Select("COALESCE(category_id, 0) AS category_id", "count(*) AS ideas").
+		Column(sq.Expr("count(*) FILTER (WHERE "+isApproved+") AS approved", approvedArgs...)).
+		Column(sq.Expr("count(*) FILTER (WHERE "+isRejected+") AS rejected", rejectedArgs...)).
+		From("ideas").
+		Where(within("creation_date", from, to)).
+		GroupBy("1").
+		OrderBy("1").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var outcomes []models.CategoryOutcomes
+	for rows.Next() {
+		var o models.CategoryOutcomes
+		if err := rows.StructScan(&o); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
+
+func (pg *PostgresRepository) SelectIdeaDecisions(ctx context.Context, from, to time.Time, statusIDs []int) ([]models.IdeaDecision, error) {
+	if len(statusIDs) == 0 {
+		return nil, nil
+	}
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	decided, decidedArgs, err := sq.Eq{"c.status_id": statusIDs}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	q, args, err := psql.Select("i.idea_uid", "COALESCE(i.category_id, 0) AS category_id", "i.creation_date",
+		"d.changed_at AS decided_at").
+		From("ideas i").
+		Join(`idea_status_changes d ON d.id = (SELECT c.id FROM idea_status_changes c
+			WHERE c.idea_uid = i.idea_uid AND `+decided+` ORDER BY c.changed_at, c.id LIMIT 1)`, decidedArgs...).
+		Where(within("i.creation_date", from, to)).
+		OrderBy("i.idea_uid").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var decisions []models.IdeaDecision
+	for rows.Next() {
+		var d models.IdeaDecision
+		if err := rows.StructScan(&d); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
+
+func (pg *PostgresRepository) SelectTopAuthors(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Select("author AS user_uid", "count(*) AS count").
+		From("ideas").
+		Where(within("creation_date", from, to)).
+		GroupBy("author").
+		OrderBy("count DESC", "user_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return pg.selectUserCounts(ctx, q, args)
+}
+
+func (pg *PostgresRepository) SelectTopCommenters(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Select("author_uid AS user_uid", "count(*) AS count").
+		From("comments").
+		Where(within("timestamp", from, to)).
+		Where("deleted_at IS NULL").
+		GroupBy("author_uid").
+		OrderBy("count DESC", "user_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return pg.selectUserCounts(ctx, q, args)
+}
+
+func (pg *PostgresRepository) selectUserCounts(ctx context.Context, q string, args []interface{}) ([]models.UserCount, error) {
+	rows, err := pg.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var counts []models.UserCount
+	for rows.Next() {
+		var c models.UserCount
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// SelectPositionEngagement joins every user with what they did, each
+// subquery gives a user at most one row
+func (pg *PostgresRepository) SelectPositionEngagement(ctx context.Context, from, to time.Time) ([]models.PositionEngagement, error) {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	q, args, err := psql.Select(
+		"COALESCE(u.position_id, 0) AS position_id",
+		"count(*) AS members",
+		"count(i.author) AS authors",
+		"COALESCE(sum(i.n), 0)::bigint AS ideas",
+		"count(c.author_uid) AS commenters",
+		"COALESCE(sum(c.n), 0)::bigint AS comments",
+		"count(v.user_uid) AS voters",
+		"COALESCE(sum(v.n), 0)::bigint AS votes",
+	).
+		From("users u").
+		LeftJoin(`(SELECT author, count(*) AS n FROM ideas
+			WHERE creation_date >= ? AND creation_date < ? GROUP BY author) i ON i.author = u.uid`, from, to).
+		LeftJoin(`(SELECT author_uid, count(*) AS n FROM comments
+			WHERE deleted_at IS NULL AND timestamp >= ? AND timestamp < ? GROUP BY author_uid) c ON c.author_uid = u.uid`, from, to).
+		LeftJoin(`(SELECT user_uid, count(*) AS n FROM vote_history
+			WHERE voted_at >= ? AND voted_at < ? GROUP BY user_uid) v ON v.user_uid = u.uid`, from, to).
+		GroupBy("1").
+		OrderBy("1").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pg.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var engagement []models.PositionEngagement
+	for rows.Next() {
+		var e models.PositionEngagement
+		if err := rows.StructScan(&e); err != nil {
+			return nil, err
+		}
+		engagement = append(engagement, e)
+	}
+	return engagement, rows.Err()
+}
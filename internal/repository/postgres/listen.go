@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Notify sends payload to every session listening on channel, inside a
+// transaction it is delivered on commit
+func (pg *PostgresRepository) Notify(ctx context.Context, channel, payload string) error {
+	_, err := pg.ext().ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return mapErr(err)
+}
+
+// Listen takes a connection out of the pool, LISTENs on channel and calls
+// handle for every notification until ctx is done or the connection fails.
+// The connection is closed afterwards instead of going back to the pool.
+func (pg *PostgresRepository) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	conn, err := pg.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		err := listen(ctx, c.Conn(), channel, handle)
+		// still subscribed, never reuse it
+		return errors.Join(err, driver.ErrBadConn)
+	})
+}
+
+func listen(ctx context.Context, c *pgx.Conn, channel string, handle func(payload string)) error {
+	if _, err := c.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		n, err := c.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handle(n.Payload)
+	}
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+)
+
+// unlockTimeout bounds releasing a lock, the connection is closed anyway
+const unlockTimeout = 5 * time.Second
+
+// TryLock takes the session-level advisory lock named name on a connection
+// of its own, unless another session holds it. The lock is held until
+// unlock is called or the connection drops, so a crashed process never
+// keeps it.
+func (pg *PostgresRepository) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := pg.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := lockKey(name)
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, mapErr(err)
+	}
+
+	unlock := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			pg.log.Error("failed to release advisory lock " + name + ": " + err.Error())
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// lockKey maps a lock name to the bigint key of pg advisory locks
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("agora:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// advisoryLockKey - arbitrary constant shared by every replica
+const advisoryLockKey = 7_402_318_551
+
+// advisoryLocker holds a session level advisory lock on the migration connection,
+// replicas starting together wait for each other instead of racing
+type advisoryLocker struct{}
+
+func (advisoryLocker) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey)
+	return err
+}
+
+func (advisoryLocker) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+	return err
+}
+
+func (pg *PostgresRepository) migrator() (*migrate.Migrator, error) {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(pg.db, sub, advisoryLocker{}, pg.log)
+}
+
+// MigrateUp applies pending migrations
+func (pg *PostgresRepository) MigrateUp(ctx context.Context) error {
+	m, err := pg.migrator()
+	if err != nil {
+		return err
+	}
+	return m.Up(ctx)
+}
+
+// MigrateDown rolls back the last steps migrations
+func (pg *PostgresRepository) MigrateDown(ctx context.Context, steps int) error {
+	m, err := pg.migrator()
+	if err != nil {
+		return err
+	}
+	return m.Down(ctx, steps)
+}
+
+// SchemaVersion returns applied and expected schema versions
+func (pg *PostgresRepository) SchemaVersion(ctx context.Context) (int, int, error) {
+	m, err := pg.migrator()
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err := m.Version(ctx)
+	return current, m.Latest(), err
+}
+
+// CheckSchema fails with migrate.ErrSchemaMismatch if the database is not
+// exactly at the version this build expects
+func (pg *PostgresRepository) CheckSchema(ctx context.Context) error {
+	m, err := pg.migrator()
+	if err != nil {
+		return err
+	}
+	return m.Check(ctx)
+}
@@ -0,0 +1,82 @@
+-- baseline schema, IF NOT EXISTS lets databases created from the old sql/init.sql adopt migrations
+
+CREATE TABLE IF NOT EXISTS user_positions(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(30) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users(
+    uid UUID PRIMARY KEY,
+    password TEXT NOT NULL,
+    name VARCHAR(20),
+    surname VARCHAR(20),
+    position_id INT,
+    email VARCHAR(35),
+    phone VARCHAR(10), -- без +7/8
+    hire_date TIMESTAMP,
+    last_online TIMESTAMP,
+    pfp_url TEXT,
+    is_admin BOOL NOT NULL DEFAULT false, --TODO change to diff service(admin panel)
+    re_auth BOOL NOT NULL DEFAULT false,  -- forces user to log in again
+    FOREIGN KEY (position_id) REFERENCES user_positions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS idea_categories(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(30) UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS idea_statuses(
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(10) UNIQUE NOT NULL --initiated, rejected, approved
+);
+
+CREATE TABLE IF NOT EXISTS ideas(
+    idea_uid UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    text TEXT NOT NULL,
+    author UUID NOT NULL,
+    creation_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    status_id INT,
+    category_id INT,
+    like_count INT NOT NULL DEFAULT 0,
+    dislike_count INT NOT NULL DEFAULT 0,
+    FOREIGN KEY (author) REFERENCES users(uid),
+    FOREIGN KEY (status_id) REFERENCES idea_statuses(id),
+    FOREIGN KEY (category_id) REFERENCES idea_categories(id)
+);
+
+CREATE TABLE IF NOT EXISTS comments(
+    comment_uid UUID PRIMARY KEY,
+    idea_uid UUID NOT NULL,
+    author_uid UUID NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    comment_text TEXT NOT NULL,
+    --TODO create reactions for comments
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+CREATE TABLE IF NOT EXISTS replies(
+    reply_uid UUID PRIMARY KEY,
+    comment_uid UUID NOT NULL,
+    author_uid UUID NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    reply_text TEXT NOT NULL,
+    FOREIGN KEY (comment_uid) REFERENCES comments(comment_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+CREATE TABLE IF NOT EXISTS vote_history(
+    idea_uid UUID NOT NULL,
+    user_uid UUID NOT NULL,
+    voted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (idea_uid, user_uid),
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS browse_history(
+    visitor_uid UUID NOT NULL,
+    idea_uid UUID NOT NULL
+);
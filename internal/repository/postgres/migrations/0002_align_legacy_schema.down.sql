@@ -0,0 +1,5 @@
+-- column renames are not reverted, the old names were never used by the code
+DROP INDEX IF EXISTS replies_comment_idx;
+DROP INDEX IF EXISTS comments_idea_idx;
+DROP INDEX IF EXISTS ideas_author_idx;
+DROP INDEX IF EXISTS users_email_idx;
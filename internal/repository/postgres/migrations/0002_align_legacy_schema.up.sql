@@ -0,0 +1,52 @@
+-- databases created from the old sql/init.sql drifted from what the code
+-- queries, bring them in line; on fresh databases every step is a no-op
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'comments'
+                 AND column_name = 'author_id') THEN
+        ALTER TABLE comments RENAME COLUMN author_id TO author_uid;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'replies'
+                 AND column_name = 'comment_id') THEN
+        ALTER TABLE replies RENAME COLUMN comment_id TO comment_uid;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'replies'
+                 AND column_name = 'author_id') THEN
+        ALTER TABLE replies RENAME COLUMN author_id TO author_uid;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'browse_history'
+                 AND column_name = 'visitor_id') THEN
+        ALTER TABLE browse_history RENAME COLUMN visitor_id TO visitor_uid;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'browse_history'
+                 AND column_name = 'idea_id') THEN
+        ALTER TABLE browse_history RENAME COLUMN idea_id TO idea_uid;
+    END IF;
+END $$;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS re_auth BOOL NOT NULL DEFAULT false;
+
+UPDATE ideas SET like_count = 0 WHERE like_count IS NULL;
+UPDATE ideas SET dislike_count = 0 WHERE dislike_count IS NULL;
+ALTER TABLE ideas ALTER COLUMN like_count SET NOT NULL;
+ALTER TABLE ideas ALTER COLUMN dislike_count SET NOT NULL;
+
+UPDATE users SET is_admin = false WHERE is_admin IS NULL;
+ALTER TABLE users ALTER COLUMN is_admin SET NOT NULL;
+
+-- lookups by email on every login
+CREATE INDEX IF NOT EXISTS users_email_idx ON users (email);
+CREATE INDEX IF NOT EXISTS ideas_author_idx ON ideas (author, creation_date DESC);
+CREATE INDEX IF NOT EXISTS comments_idea_idx ON comments (idea_uid);
+CREATE INDEX IF NOT EXISTS replies_comment_idx ON replies (comment_uid);
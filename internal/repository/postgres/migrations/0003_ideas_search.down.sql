@@ -0,0 +1 @@
+DROP INDEX IF EXISTS ideas_search_idx;
@@ -0,0 +1,2 @@
+-- full-text search over ideas, see PostgresRepository.SearchIdeas
+CREATE INDEX IF NOT EXISTS ideas_search_idx ON ideas USING GIN (to_tsvector('simple', name || ' ' || text));
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS comment_revisions;
+
+ALTER TABLE replies DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE replies DROP COLUMN IF EXISTS edited_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS edited_at;
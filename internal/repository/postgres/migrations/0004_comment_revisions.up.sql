@@ -0,0 +1,18 @@
+-- editing and deleting comments and replies
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE replies ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE replies ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- previous texts; target_uid is a comment or a reply, so there is no FK
+CREATE TABLE IF NOT EXISTS comment_revisions(
+    id BIGSERIAL PRIMARY KEY,
+    target_uid UUID NOT NULL,
+    text TEXT NOT NULL,
+    editor_uid UUID NOT NULL,
+    edited_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    FOREIGN KEY (editor_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS comment_revisions_target_idx ON comment_revisions(target_uid);
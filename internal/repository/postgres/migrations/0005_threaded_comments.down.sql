@@ -0,0 +1,30 @@
+-- only direct answers to top level comments fit the old layout,
+-- deeper comments are lost
+
+CREATE TABLE replies(
+    reply_uid UUID PRIMARY KEY,
+    comment_uid UUID NOT NULL,
+    author_uid UUID NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    reply_text TEXT NOT NULL,
+    edited_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (comment_uid) REFERENCES comments(comment_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+CREATE INDEX replies_comment_idx ON replies (comment_uid);
+
+INSERT INTO replies (reply_uid, comment_uid, author_uid, timestamp, reply_text, edited_at, deleted_at)
+SELECT comment_uid, parent_uid, author_uid, timestamp, comment_text, edited_at, deleted_at
+FROM comments
+WHERE depth = 1;
+
+DELETE FROM comments WHERE parent_uid IS NOT NULL;
+
+DROP INDEX IF EXISTS comments_roots_idx;
+DROP INDEX IF EXISTS comments_parent_idx;
+
+ALTER TABLE comments DROP COLUMN path;
+ALTER TABLE comments DROP COLUMN depth;
+ALTER TABLE comments DROP COLUMN parent_uid;
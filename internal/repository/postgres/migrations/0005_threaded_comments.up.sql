@@ -0,0 +1,23 @@
+-- replies become comments with a parent: one table for threads of any depth.
+-- path is the materialized path of uids from the root ("root/child/uid"),
+-- a subtree is selected by its prefix
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS parent_uid UUID REFERENCES comments(comment_uid) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS depth INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS path TEXT;
+
+UPDATE comments SET path = comment_uid::text WHERE path IS NULL;
+
+-- reply uids are kept, so their revisions stay attached
+INSERT INTO comments (comment_uid, idea_uid, author_uid, timestamp, comment_text, edited_at, deleted_at, parent_uid, depth, path)
+SELECT r.reply_uid, c.idea_uid, r.author_uid, r.timestamp, r.reply_text, r.edited_at, r.deleted_at,
+    c.comment_uid, 1, c.path || '/' || r.reply_uid::text
+FROM replies r
+JOIN comments c ON c.comment_uid = r.comment_uid;
+
+ALTER TABLE comments ALTER COLUMN path SET NOT NULL;
+
+DROP TABLE replies;
+
+CREATE INDEX IF NOT EXISTS comments_parent_idx ON comments (parent_uid, timestamp, comment_uid);
+CREATE INDEX IF NOT EXISTS comments_roots_idx ON comments (idea_uid, timestamp, comment_uid) WHERE parent_uid IS NULL;
@@ -0,0 +1,10 @@
+-- emoji reactions on ideas and comments; target_uid is an idea or a comment,
+-- so there is no FK, like in comment_revisions
+CREATE TABLE IF NOT EXISTS reactions(
+    target_uid UUID NOT NULL,
+    user_uid UUID NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (target_uid, user_uid, emoji),
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- @name.surname and @uid references in idea and comment texts; target_uid is
+-- an idea or a comment, so there is no FK, like in reactions
+CREATE TABLE IF NOT EXISTS mentions(
+    target_uid UUID NOT NULL,
+    target_type VARCHAR(16) NOT NULL,
+    idea_uid UUID NOT NULL,
+    author_uid UUID NOT NULL,
+    user_uid UUID NOT NULL,
+    span_start INTEGER NOT NULL,
+    span_end INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (target_uid, span_start),
+    FOREIGN KEY (author_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS mentions_user_idx ON mentions(user_uid);
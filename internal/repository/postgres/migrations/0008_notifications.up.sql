@@ -0,0 +1,27 @@
+-- in-app notifications; comment_uid has no FK, comments are never removed
+CREATE TABLE IF NOT EXISTS notifications(
+    id BIGSERIAL PRIMARY KEY,
+    user_uid UUID NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    actor_uid UUID NOT NULL,
+    idea_uid UUID NOT NULL,
+    comment_uid UUID,
+    detail VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    read_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (actor_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications(user_uid, id DESC);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications(user_uid) WHERE read_at IS NULL;
+
+-- only switched off (or back on) types are stored, the rest are enabled
+CREATE TABLE IF NOT EXISTS notification_preferences(
+    user_uid UUID NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_uid, type),
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS vote_history_voted_at_idx;
+DROP TABLE IF EXISTS email_settings;
+DROP TABLE IF EXISTS email_outbox;
@@ -0,0 +1,33 @@
+-- rendered emails waiting for delivery, sent and given up ones stay as a log
+CREATE TABLE IF NOT EXISTS email_outbox(
+    id BIGSERIAL PRIMARY KEY,
+    user_uid UUID NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    to_addr VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    sent_at TIMESTAMP WITH TIME ZONE,
+    failed_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox(next_attempt_at)
+    WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS email_outbox_user_idx ON email_outbox(user_uid, id DESC);
+
+-- users without a row get the defaults of models.DefaultEmailSettings
+CREATE TABLE IF NOT EXISTS email_settings(
+    user_uid UUID PRIMARY KEY,
+    language VARCHAR(2) NOT NULL,
+    immediate BOOLEAN NOT NULL,
+    digest VARCHAR(8) NOT NULL,
+    last_digest_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS vote_history_voted_at_idx ON vote_history(voted_at);
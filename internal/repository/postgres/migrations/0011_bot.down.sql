@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bot_accounts;
+DROP TABLE IF EXISTS bot_link_codes;
@@ -0,0 +1,20 @@
+-- one-time codes linking a messenger account, a user has one at a time
+CREATE TABLE IF NOT EXISTS bot_link_codes(
+    code VARCHAR(16) PRIMARY KEY,
+    user_uid UUID NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+-- messenger accounts linked to users, one per user and platform
+CREATE TABLE IF NOT EXISTS bot_accounts(
+    platform VARCHAR(32) NOT NULL,
+    external_id VARCHAR(64) NOT NULL,
+    chat_id VARCHAR(64) NOT NULL,
+    user_uid UUID NOT NULL,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    linked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (platform, external_id),
+    UNIQUE (user_uid, platform),
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS ideas_campaign_idx;
+ALTER TABLE ideas DROP COLUMN IF EXISTS campaign_id;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,20 @@
+-- themed calls for ideas, category_ids and position_ids are comma separated
+-- lists of ids ideas and their authors must be in, empty allows any
+CREATE TABLE IF NOT EXISTS campaigns(
+    id BIGSERIAL PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    category_ids TEXT NOT NULL DEFAULT '',
+    position_ids TEXT NOT NULL DEFAULT '',
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CHECK (starts_at < ends_at),
+    FOREIGN KEY (created_by) REFERENCES users(uid) ON DELETE SET NULL
+);
+
+-- ideas outlive a deleted campaign
+ALTER TABLE ideas ADD COLUMN IF NOT EXISTS campaign_id BIGINT REFERENCES campaigns(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS ideas_campaign_idx ON ideas (campaign_id) WHERE campaign_id IS NOT NULL;
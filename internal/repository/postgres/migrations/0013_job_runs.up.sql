@@ -0,0 +1,18 @@
+-- history of background jobs, a scheduled moment is run once whichever
+-- replica gets to it
+CREATE TABLE IF NOT EXISTS job_runs(
+    id BIGSERIAL PRIMARY KEY,
+    job VARCHAR(64) NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    scheduled_at TIMESTAMP WITH TIME ZONE,
+    triggered_by UUID,
+    node VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'running',
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    finished_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (job, scheduled_at),
+    FOREIGN KEY (triggered_by) REFERENCES users(uid) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS job_runs_job_idx ON job_runs (job, id);
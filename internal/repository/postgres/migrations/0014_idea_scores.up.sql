@@ -0,0 +1,11 @@
+-- ranking scores of ideas, recomputed on votes and comments and
+-- periodically as ideas age
+CREATE TABLE IF NOT EXISTS idea_scores(
+    idea_uid UUID PRIMARY KEY,
+    hot DOUBLE PRECISION NOT NULL DEFAULT 0,
+    top DOUBLE PRECISION NOT NULL DEFAULT 0,
+    controversial DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rising DOUBLE PRECISION NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE
+);
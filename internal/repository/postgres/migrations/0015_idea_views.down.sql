@@ -0,0 +1,13 @@
+ALTER TABLE ideas DROP COLUMN IF EXISTS view_count;
+
+ALTER TABLE browse_history RENAME TO browse_history_visits;
+
+CREATE TABLE browse_history(
+    visitor_uid UUID NOT NULL,
+    idea_uid UUID NOT NULL
+);
+
+INSERT INTO browse_history (visitor_uid, idea_uid)
+SELECT visitor_uid, idea_uid FROM browse_history_visits;
+
+DROP TABLE browse_history_visits;
@@ -0,0 +1,29 @@
+-- browse_history keeps one row per visitor and idea: viewed_at is the last
+-- visit (for unread comments and recently viewed ideas), counted_at the last
+-- visit counted in ideas.view_count. Legacy rows have no time, they count as
+-- visited at the upgrade.
+ALTER TABLE browse_history RENAME TO browse_history_legacy;
+
+CREATE TABLE browse_history(
+    visitor_uid UUID NOT NULL,
+    idea_uid UUID NOT NULL,
+    viewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    counted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (visitor_uid, idea_uid),
+    FOREIGN KEY (visitor_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE
+);
+
+INSERT INTO browse_history (visitor_uid, idea_uid)
+SELECT DISTINCT h.visitor_uid, h.idea_uid
+FROM browse_history_legacy h
+JOIN users u ON u.uid = h.visitor_uid
+JOIN ideas i ON i.idea_uid = h.idea_uid;
+
+DROP TABLE browse_history_legacy;
+
+CREATE INDEX IF NOT EXISTS browse_history_recent_idx ON browse_history (visitor_uid, viewed_at);
+
+ALTER TABLE ideas ADD COLUMN IF NOT EXISTS view_count INT NOT NULL DEFAULT 0;
+
+UPDATE ideas i SET view_count = (SELECT count(*) FROM browse_history h WHERE h.idea_uid = i.idea_uid);
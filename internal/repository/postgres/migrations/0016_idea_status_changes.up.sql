@@ -0,0 +1,18 @@
+-- moves of ideas between statuses, analytics measure time to decision with
+-- them; ideas moved before this migration have no history
+CREATE TABLE IF NOT EXISTS idea_status_changes(
+    id BIGSERIAL PRIMARY KEY,
+    idea_uid UUID NOT NULL,
+    status_id INT NOT NULL,
+    changed_by UUID,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (status_id) REFERENCES idea_statuses(id),
+    FOREIGN KEY (changed_by) REFERENCES users(uid) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idea_status_changes_idea_idx ON idea_status_changes (idea_uid, changed_at);
+
+-- analytics count ideas and comments within date ranges
+CREATE INDEX IF NOT EXISTS ideas_creation_date_idx ON ideas (creation_date);
+CREATE INDEX IF NOT EXISTS comments_timestamp_idx ON comments (timestamp) WHERE deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- invitations of imported users to set a password, a user has one at a time;
+-- only a hash of the token is kept, the token itself is in the email
+CREATE TABLE IF NOT EXISTS user_invitations(
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_uid UUID NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+-- one-time passes to the event stream, only a hash of the ticket is kept
+CREATE TABLE IF NOT EXISTS stream_tickets(
+    ticket_hash VARCHAR(64) PRIMARY KEY,
+    user_uid UUID NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	maxTxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// WithTx runs fn inside a SERIALIZABLE transaction. The repository passed to fn
+// executes every statement in that transaction; it is committed when fn
+// returns nil and rolled back otherwise. Serialization failures and deadlocks
+// restart fn from scratch, so fn must not have side effects outside the repo.
+// Nested calls join the outer transaction.
+func (pg *PostgresRepository) WithTx(ctx context.Context, fn func(repo repository.Repository) error) error {
+	if pg.tx != nil {
+		return fn(pg)
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = pg.runTx(ctx, fn)
+		if !isRetryable(err) {
+			return err
+		}
+
+		pg.log.WarnContext(ctx, "retrying transaction",
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+
+		// jitter so that conflicting transactions don't collide again
+		delay := txRetryDelay*time.Duration(attempt) + time.Duration(rand.Int64N(int64(txRetryDelay)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxTxAttempts, err)
+}
+
+func (pg *PostgresRepository) runTx(ctx context.Context, fn func(repo repository.Repository) error) (err error) {
+	tx, err := pg.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	txRepo := &PostgresRepository{db: pg.db, tx: tx, log: pg.log}
+	if err := fn(txRepo); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			pg.log.ErrorContext(ctx, "failed to rollback transaction", slog.String("error", rbErr.Error()))
+		}
+		return err
+	}
+
+	return mapErr(tx.Commit())
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
@@ -1,37 +1,312 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
 	"log/slog"
+	"time"
+)
+
+// Errors returned by every Repository implementation, drivers' own errors
+// (sql.ErrNoRows, pg error codes) never leak out of the repository.
+var (
+	ErrNotFound         = errors.New("record not found")
+	ErrConflict         = errors.New("record already exists")
+	ErrInvalidReference = errors.New("referenced record does not exist")
 )
 
 // Repository - interface to work with DB
 type Repository interface {
-	ConnectDB(sourceURL string, log slog.Logger) error
+	ConnectDB(ctx context.Context, sourceURL string, log slog.Logger) error
 	CloseConnectDB() error
 
-	InsertUser(models.User) error
-	SelectUserByEmail(string) (models.User, error)
-	SelectUserByUID(uid string) (models.User, error)
+	// WithTx runs fn atomically, every call on the repo passed to fn is part of
+	// one transaction. Returning an error from fn rolls everything back.
+	WithTx(ctx context.Context, fn func(repo Repository) error) error
+
+	InsertUser(ctx context.Context, user models.User) error
+	SelectUserByEmail(ctx context.Context, email string) (models.User, error)
+	// UserEmailTaken reports whether some user has the email, ignoring case
+	UserEmailTaken(ctx context.Context, email string) (bool, error)
+	SelectUserByUID(ctx context.Context, uid string) (models.User, error)
+	// UpdateUserPassword sets the password hash, ErrNotFound for a missing
+	// user
+	UpdateUserPassword(ctx context.Context, uid string, hash string) error
+
+	SelectPositions(ctx context.Context) ([]models.UserPosition, error)
+	// InsertPosition adds a position and returns it with ID set, ErrConflict
+	// when the name is taken
+	InsertPosition(ctx context.Context, name string) (models.UserPosition, error)
+
+	// InsertInvitation saves the invitation, replacing the one issued to the
+	// user before. ErrInvalidReference for a missing user.
+	InsertInvitation(ctx context.Context, invitation models.Invitation) error
+	// ConsumeInvitation deletes the invitation and returns it, expired or not
+	ConsumeInvitation(ctx context.Context, tokenHash string) (models.Invitation, error)
+
+	// InsertStreamTicket saves the ticket, a user may hold several.
+	// ErrInvalidReference for a missing user.
+	InsertStreamTicket(ctx context.Context, ticket models.StreamTicket) error
+	// ConsumeStreamTicket deletes the ticket and returns it, expired or not
+	ConsumeStreamTicket(ctx context.Context, ticketHash string) (models.StreamTicket, error)
+	// DeleteExpiredStreamTickets deletes tickets expired by now and returns
+	// how many
+	DeleteExpiredStreamTickets(ctx context.Context, now time.Time) (int, error)
+
+	InsertIdea(ctx context.Context, idea models.Idea) error
+	SelectIdeas(ctx context.Context) ([]models.Idea, error)
+	SelectIdeaByUID(ctx context.Context, uid string) (models.Idea, error)
+	SelectUserIdeas(ctx context.Context, uid string, limit int) ([]models.Idea, error)
+	// SearchIdeas returns ideas whose name or text contain every word of query
+	// (see SearchTerms), most relevant first
+	SearchIdeas(ctx context.Context, query string, limit int) ([]models.Idea, error)
+	// SelectIdeaWithAuthor returns the idea with its author's profile
+	SelectIdeaWithAuthor(ctx context.Context, uid string) (models.Idea, error)
+	// UpdateIdeaStatus returns ErrNotFound for a missing idea and
+	// ErrInvalidReference for an unknown status
+	UpdateIdeaStatus(ctx context.Context, uid string, statusID int) error
+
+	// InsertIdeaComment adds a top level comment, or a reply when ParentUID is
+	// set. Depth and Path are derived from the parent, a missing parent is
+	// ErrInvalidReference.
+	InsertIdeaComment(ctx context.Context, comment models.Comment) error
+	// SelectIdeaComments returns every comment of the idea at any depth
+	SelectIdeaComments(ctx context.Context, ideaUID string) ([]models.Comment, error)
+	SelectCommentByUID(ctx context.Context, uid string) (models.Comment, error)
+	// SelectCommentChildren returns up to limit direct replies to parentUID, or
+	// top level comments of the idea when parentUID is empty, ordered by
+	// timestamp and uid. With afterUID set the page starts right after that
+	// comment (keyset pagination), an unknown afterUID gives an empty page.
+	// Comments come with ChildCount and authors' profiles.
+	SelectCommentChildren(ctx context.Context, ideaUID, parentUID, afterUID string, limit int) ([]models.Comment, error)
+	// SelectCommentSubtrees returns descendants of rootUIDs down to maxDepth,
+	// ordered by timestamp and uid, with ChildCount and authors' profiles
+	SelectCommentSubtrees(ctx context.Context, rootUIDs []string, maxDepth int) ([]models.Comment, error)
+
+	// UpdateCommentText replaces the text and sets edited_at, the old text
+	// should be saved with InsertRevision first
+	UpdateCommentText(ctx context.Context, uid string, text string) error
+	// TombstoneComment clears the text and sets deleted_at
+	TombstoneComment(ctx context.Context, uid string) error
+
+	InsertRevision(ctx context.Context, revision models.Revision) error
+	// SelectRevisions returns previous texts of a comment, oldest first
+	SelectRevisions(ctx context.Context, targetUID string) ([]models.Revision, error)
+	DeleteRevisions(ctx context.Context, targetUID string) error
+
+	// InsertReaction fails with ErrConflict when the user has already left
+	// this emoji on the target
+	InsertReaction(ctx context.Context, reaction models.Reaction) error
+	// DeleteReaction returns ErrNotFound when there is no such reaction
+	DeleteReaction(ctx context.Context, targetUID, userUID, emoji string) error
+	// SelectReactionCounts counts reactions on the targets per emoji, Mine
+	// marks emojis left by viewerUID. Rows of a target are adjacent, emojis
+	// sort differently under database collations so callers order them.
+	SelectReactionCounts(ctx context.Context, targetUIDs []string, viewerUID string) ([]models.ReactionCount, error)
+
+	// InsertMentions saves mentions of one text, an empty slice is a no-op
+	InsertMentions(ctx context.Context, mentions []models.Mention) error
+	// DeleteMentions drops every mention of the target, none is not an error
+	DeleteMentions(ctx context.Context, targetUID string) error
+	// SelectMentions returns mentions of the targets with profiles of the
+	// mentioned users, ordered by target and Start
+	SelectMentions(ctx context.Context, targetUIDs []string) ([]models.Mention, error)
+	// SelectProfiles returns profiles of existing users among uids
+	SelectProfiles(ctx context.Context, uids []string) ([]models.AuthorProfile, error)
+	// SelectProfilesByName returns profiles of users whose name and surname
+	// match one of names ignoring case, a name may match several users
+	SelectProfilesByName(ctx context.Context, names []models.FullName) ([]models.AuthorProfile, error)
+
+	// InsertNotification saves a new unread notification and returns it with
+	// ID and CreatedAt assigned by the storage
+	InsertNotification(ctx context.Context, notification models.Notification) (models.Notification, error)
+	// SelectNotifications returns up to limit notifications of the user, newest
+	// (highest ID) first, with Actor and IdeaName filled. beforeID > 0 starts
+	// the page below that ID, unreadOnly skips read ones.
+	SelectNotifications(ctx context.Context, userUID string, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userUID string) (int, error)
+	// MarkNotificationRead returns ErrNotFound unless the user has such a
+	// notification, marking a read one again keeps its ReadAt
+	MarkNotificationRead(ctx context.Context, userUID string, id int64) error
+	MarkAllNotificationsRead(ctx context.Context, userUID string) error
+	// SelectNotificationPreferences returns preferences the user has set,
+	// types without a row are enabled
+	SelectNotificationPreferences(ctx context.Context, userUID string) ([]models.NotificationPreference, error)
+	UpsertNotificationPreference(ctx context.Context, preference models.NotificationPreference) error
 
-	SelectPositions() ([]models.UserPosition, error)
+	// InsertOutboxEmail queues a rendered email, due at NextAttemptAt
+	InsertOutboxEmail(ctx context.Context, email models.OutboxEmail) error
+	// ClaimOutboxEmails returns up to limit unsent emails due at now, oldest
+	// first, with Attempts incremented and NextAttemptAt moved to leaseUntil:
+	// other workers skip them while they are being sent
+	ClaimOutboxEmails(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEmail, error)
+	MarkOutboxEmailSent(ctx context.Context, id int64) error
+	// FailOutboxEmail records the error and retries at retryAt, a nil retryAt
+	// gives the email up
+	FailOutboxEmail(ctx context.Context, id int64, lastError string, retryAt *time.Time) error
+	// SelectOutboxEmails returns emails to userUID, or every email when it is
+	// empty, newest first
+	SelectOutboxEmails(ctx context.Context, userUID string, limit int) ([]models.OutboxEmail, error)
 
-	InsertIdea(models.Idea) error
-	SelectIdeas() ([]models.Idea, error)
-	SelectIdeaByUID(uid string) (models.Idea, error)
-	SelectUserIdeas(string, int) ([]models.Idea, error)
-	InsertIdeaComment(models.Comment) error
-	InsertCommentReply(models.Reply) error
-	SelectIdeaComments(string) ([]models.Comment, error)
-	SelectCommentReplies(string) ([]models.Reply, error)
+	// SelectEmailSettings returns ErrNotFound for users with default settings
+	SelectEmailSettings(ctx context.Context, userUID string) (models.EmailSettings, error)
+	// UpsertEmailSettings saves everything but LastDigestAt
+	UpsertEmailSettings(ctx context.Context, settings models.EmailSettings) error
+	// SelectDigestRecipients returns up to limit users whose digest is
+	// frequency (models.DefaultEmailSettings for users without settings) and
+	// who haven't got one since sentBefore
+	SelectDigestRecipients(ctx context.Context, frequency models.DigestFrequency, sentBefore time.Time, limit int) ([]models.DigestRecipient, error)
+	// MarkDigestSent sets LastDigestAt, creating default settings if needed
+	MarkDigestSent(ctx context.Context, userUID string, at time.Time) error
+	// SelectTrendingIdeas returns up to limit ideas voted for since the given
+	// time, most votes first, ties broken by newer ideas
+	SelectTrendingIdeas(ctx context.Context, since time.Time, limit int) ([]models.TrendingIdea, error)
 
-	SelectIdeaCategories() ([]models.IdeaCategory, error)
-	SelectIdeaStatuses() ([]models.IdeaStatus, error)
+	// InsertWebhook returns the webhook with ID and CreatedAt set
+	InsertWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	// UpdateWebhook saves URL, Secret, Events and Active
+	UpdateWebhook(ctx context.Context, webhook models.Webhook) error
+	// DeleteWebhook deletes the webhook with its deliveries
+	DeleteWebhook(ctx context.Context, id int64) error
+	SelectWebhook(ctx context.Context, id int64) (models.Webhook, error)
+	// SelectWebhooks returns every webhook, oldest first
+	SelectWebhooks(ctx context.Context) ([]models.Webhook, error)
 
-	CheckVote(ideaUID string, userUID string) (bool, error)
+	// InsertWebhookDelivery queues a delivery in State, due at NextAttemptAt,
+	// and returns it with ID and CreatedAt set
+	InsertWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at
+	// now, oldest first, with Attempts incremented and NextAttemptAt moved to
+	// leaseUntil: other workers skip them while they are being sent
+	ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	// RecordWebhookAttempt adds the attempt to the delivery log and moves the
+	// delivery to state, a pending one is retried at retryAt
+	RecordWebhookAttempt(ctx context.Context, attempt models.WebhookAttempt, state models.DeliveryState, retryAt time.Time) error
+	// RequeueWebhookDelivery makes the delivery pending again, due at the
+	// given time with no attempts
+	RequeueWebhookDelivery(ctx context.Context, id int64, at time.Time) error
+	SelectWebhookDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error)
+	// SelectWebhookDeliveries returns deliveries of the webhook in state, or
+	// in any state when it is empty, newest first
+	SelectWebhookDeliveries(ctx context.Context, webhookID int64, state models.DeliveryState, limit int) ([]models.WebhookDelivery, error)
+	// SelectWebhookAttempts returns the delivery log, oldest first
+	SelectWebhookAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookAttempt, error)
 
-	UpdateUserPfpURL(uid string, url string) error
+	// InsertBotLinkCode saves the code, replacing codes issued to the user
+	// before
+	InsertBotLinkCode(ctx context.Context, code models.BotLinkCode) error
+	// ConsumeBotLinkCode deletes the code and returns it, expired or not
+	ConsumeBotLinkCode(ctx context.Context, code string) (models.BotLinkCode, error)
+	// DeleteExpiredBotLinkCodes deletes codes expired by now and returns how
+	// many
+	DeleteExpiredBotLinkCodes(ctx context.Context, now time.Time) (int, error)
+	// LinkBotAccount saves the account, replacing the one with the same
+	// ExternalID and the user's other account on the platform
+	LinkBotAccount(ctx context.Context, account models.BotAccount) error
+	SelectBotAccount(ctx context.Context, platform, externalID string) (models.BotAccount, error)
+	// SelectBotAccounts returns the user's accounts, oldest first
+	SelectBotAccounts(ctx context.Context, userUID string) ([]models.BotAccount, error)
+	DeleteBotAccount(ctx context.Context, platform, externalID string) error
+
+	// InsertCampaign returns the campaign with ID and CreatedAt set
+	InsertCampaign(ctx context.Context, campaign models.Campaign) (models.Campaign, error)
+	// UpdateCampaign saves everything but CreatedBy and CreatedAt
+	UpdateCampaign(ctx context.Context, campaign models.Campaign) error
+	// DeleteCampaign deletes the campaign, its ideas stay without one
+	DeleteCampaign(ctx context.Context, id int64) error
+	SelectCampaign(ctx context.Context, id int64) (models.Campaign, error)
+	// SelectCampaigns returns every campaign, latest to start first
+	SelectCampaigns(ctx context.Context) ([]models.Campaign, error)
+	// SelectCampaignLeaderboard returns up to limit ideas of the campaign,
+	// most likes net of dislikes first, ties broken by more likes and then
+	// by older ideas
+	SelectCampaignLeaderboard(ctx context.Context, campaignID int64, limit int) ([]models.Idea, error)
+	// SelectCampaignStats counts ideas of the campaign, their authors,
+	// voters, votes and comments that aren't deleted
+	SelectCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error)
+
+	// InsertJobRun returns the run with ID set, ErrConflict when a run of the
+	// job at the same ScheduledAt is already recorded
+	InsertJobRun(ctx context.Context, run models.JobRun) (models.JobRun, error)
+	// FinishJobRun records the outcome of a run
+	FinishJobRun(ctx context.Context, id int64, status models.JobStatus, runErr string, finishedAt time.Time) error
+	// SelectJobRuns returns up to limit runs of the job, of every job when it
+	// is empty, newest first
+	SelectJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error)
+	// DeleteJobRuns deletes finished runs started before the given time and
+	// returns how many
+	DeleteJobRuns(ctx context.Context, before time.Time) (int, error)
+
+	// SelectIdeaActivity returns what scores of the given ideas, of every
+	// idea when none are given, are computed from. Recent votes and comments
+	// are counted since the given time, deleted comments aren't counted.
+	SelectIdeaActivity(ctx context.Context, since time.Time, uids ...string) ([]models.IdeaActivity, error)
+	// UpsertIdeaScores saves the scores, replacing earlier ones
+	UpsertIdeaScores(ctx context.Context, scores []models.IdeaScore) error
+	// SelectRankedIdeas returns every idea by the score of sort, highest
+	// first; ideas not scored yet go last, newer first
+	SelectRankedIdeas(ctx context.Context, sort models.IdeaSort) ([]models.Idea, error)
+
+	// UpsertBrowseHistory records a visit at ViewedAt. The visit is counted,
+	// CountedAt set to ViewedAt and counted returned true, unless the visitor
+	// has been counted since countedAfter. ErrInvalidReference for an unknown
+	// idea or visitor.
+	UpsertBrowseHistory(ctx context.Context, visit models.BrowseHistory, countedAfter time.Time) (counted bool, err error)
+	IncrementViewCount(ctx context.Context, ideaUID string) error
+	// SelectIdeaVisits returns the visitor's last visits of those ideas that
+	// were visited, with comments of others posted since that aren't deleted
+	SelectIdeaVisits(ctx context.Context, visitorUID string, ideaUIDs []string) ([]models.IdeaVisit, error)
+	// SelectViewedIdeas returns up to limit ideas the visitor has visited,
+	// last visited first
+	SelectViewedIdeas(ctx context.Context, visitorUID string, limit int) ([]models.Idea, error)
+
+	// InsertIdeaStatusChange records a move of an idea, ErrInvalidReference
+	// for an unknown idea, status or user
+	InsertIdeaStatusChange(ctx context.Context, change models.IdeaStatusChange) error
+	// SelectIdeaStatusChanges returns the moves of an idea, earliest first
+	SelectIdeaStatusChanges(ctx context.Context, ideaUID string) ([]models.IdeaStatusChange, error)
+
+	// Analytics below count ideas created, comments posted and votes cast
+	// within [from, to), deleted comments aren't counted.
+
+	// CountIdeasByPeriod returns periods of group with at least one idea,
+	// oldest first
+	CountIdeasByPeriod(ctx context.Context, from, to time.Time, group models.AnalyticsGroup) ([]models.PeriodCount, error)
+	// SelectCategoryOutcomes counts ideas per category, approved and rejected
+	// by their current status, ordered by category
+	SelectCategoryOutcomes(ctx context.Context, from, to time.Time, approved, rejected []int) ([]models.CategoryOutcomes, error)
+	// SelectIdeaDecisions returns ideas moved to one of statusIDs with the
+	// time of the first such move, ordered by idea
+	SelectIdeaDecisions(ctx context.Context, from, to time.Time, statusIDs []int) ([]models.IdeaDecision, error)
+	// SelectTopAuthors returns up to limit users by ideas, most first, ties
+	// broken by uid
+	SelectTopAuthors(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error)
+	// SelectTopCommenters returns up to limit users by comments, most first,
+	// ties broken by uid
+	SelectTopCommenters(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error)
+	// SelectPositionEngagement returns engagement of users grouped by
+	// position, PositionID 0 for users without one, ordered by position.
+	// Positions without users are left out.
+	SelectPositionEngagement(ctx context.Context, from, to time.Time) ([]models.PositionEngagement, error)
+
+	SelectIdeaCategories(ctx context.Context) ([]models.IdeaCategory, error)
+	SelectIdeaStatuses(ctx context.Context) ([]models.IdeaStatus, error)
+
+	CheckVote(ctx context.Context, ideaUID string, userUID string) (bool, error)
+
+	UpdateUserPfpURL(ctx context.Context, uid string, url string) error
+
+	IncrementDislikeCount(ctx context.Context, ideaUID string) error
+	IncrementLikeCount(ctx context.Context, ideaUID string) error
+}
 
-	IncrementDislikeCount(ideaUID string) error
-	IncrementLikeCount(ideaUID string) error
+// Migrator is implemented by repositories backed by a versioned SQL schema
+type Migrator interface {
+	MigrateUp(ctx context.Context) error
+	MigrateDown(ctx context.Context, steps int) error
+	// SchemaVersion returns applied and expected by this build versions
+	SchemaVersion(ctx context.Context) (current int, latest int, err error)
+	// CheckSchema fails unless the schema is exactly at the expected version
+	CheckSchema(ctx context.Context) error
 }
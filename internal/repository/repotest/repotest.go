@@ -0,0 +1,1903 @@
+// Package repotest is a conformance suite every repository.Repository
+// implementation must pass, so that the in-memory backend used in tests keeps
+// behaving like the SQL ones.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Dictionaries every Factory must load before returning the repository,
+// Repository has no methods to insert them.
+var (
+	Positions = []models.UserPosition{
+		{ID: 1, Name: "Developer"},
+		{ID: 2, Name: "Manager"},
+	}
+	Categories = []models.IdeaCategory{
+		{ID: 1, Name: "Office"},
+		{ID: 2, Name: "Process"},
+	}
+	Statuses = []models.IdeaStatus{
+		{ID: 1, Name: "New"},
+		{ID: 2, Name: "Approved"},
+	}
+)
+
+// Factory returns an empty repository with the dictionaries above loaded.
+// It is called once per test case.
+type Factory func(t *testing.T) repository.Repository
+
+// Run runs the whole suite against repositories made by newRepo
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.Repository)
+	}{
+		{"Dictionaries", testDictionaries},
+		{"Users", testUsers},
+		{"UserPfp", testUserPfp},
+		{"Positions", testPositions},
+		{"Invitations", testInvitations},
+		{"StreamTickets", testStreamTickets},
+		{"Ideas", testIdeas},
+		{"IdeaReferences", testIdeaReferences},
+		{"IdeaStatus", testIdeaStatus},
+		{"UserIdeas", testUserIdeas},
+		{"SearchIdeas", testSearchIdeas},
+		{"Comments", testComments},
+		{"IdeaWithAuthor", testIdeaWithAuthor},
+		{"CommentChildren", testCommentChildren},
+		{"CommentSubtrees", testCommentSubtrees},
+		{"EditComments", testEditComments},
+		{"Revisions", testRevisions},
+		{"Reactions", testReactions},
+		{"Mentions", testMentions},
+		{"ProfilesByName", testProfilesByName},
+		{"Notifications", testNotifications},
+		{"NotificationPreferences", testNotificationPreferences},
+		{"Outbox", testOutbox},
+		{"EmailSettings", testEmailSettings},
+		{"TrendingIdeas", testTrendingIdeas},
+		{"IdeaScores", testIdeaScores},
+		{"BrowseHistory", testBrowseHistory},
+		{"Analytics", testAnalytics},
+		{"Webhooks", testWebhooks},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"BotAccounts", testBotAccounts},
+		{"Campaigns", testCampaigns},
+		{"CampaignIdeas", testCampaignIdeas},
+		{"JobRuns", testJobRuns},
+		{"Votes", testVotes},
+		{"Counters", testCounters},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// User inserts a user with the given email and returns it
+func User(t *testing.T, repo repository.Repository, email string) models.User {
+	t.Helper()
+	u := models.User{
+		UID:        uuid.NewString(),
+		Name:       "Ivan",
+		Surname:    "Petrov",
+		PositionID: Positions[0].ID,
+		Email:      email,
+		Password:   "hash",
+		Phone:      "9990001122",
+	}
+	require.NoError(t, repo.InsertUser(context.Background(), u))
+	return u
+}
+
+// Idea inserts an idea by author and returns it as stored
+func Idea(t *testing.T, repo repository.Repository, author string, name string) models.Idea {
+	t.Helper()
+	ctx := context.Background()
+	idea := models.Idea{
+		IdeaUID:    uuid.NewString(),
+		Name:       name,
+		Text:       "text of " + name,
+		Author:     author,
+		StatusID:   Statuses[0].ID,
+		CategoryID: Categories[0].ID,
+	}
+	require.NoError(t, repo.InsertIdea(ctx, idea))
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	return stored
+}
+
+// pause keeps default timestamps of consecutive inserts apart
+func pause() {
+	time.Sleep(2 * time.Millisecond)
+}
+
+func testDictionaries(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	positions, err := repo.SelectPositions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Positions, positions)
+
+	categories, err := repo.SelectIdeaCategories(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Categories, categories)
+
+	statuses, err := repo.SelectIdeaStatuses(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Statuses, statuses)
+}
+
+func testUsers(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "ivan@example.com")
+
+	got, err := repo.SelectUserByUID(ctx, u.UID)
+	require.NoError(t, err)
+	assert.Equal(t, u.Email, got.Email)
+	assert.Equal(t, u.Password, got.Password)
+	assert.Equal(t, u.PositionID, got.PositionID)
+	assert.False(t, got.IsAdmin)
+
+	got, err = repo.SelectUserByEmail(ctx, u.Email)
+	require.NoError(t, err)
+	assert.Equal(t, u.UID, got.UID)
+
+	_, err = repo.SelectUserByUID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.SelectUserByEmail(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	taken, err := repo.UserEmailTaken(ctx, "Ivan@Example.COM")
+	require.NoError(t, err)
+	assert.True(t, taken, "emails are compared ignoring case")
+	taken, err = repo.UserEmailTaken(ctx, "nobody@example.com")
+	require.NoError(t, err)
+	assert.False(t, taken)
+
+	err = repo.InsertUser(ctx, u)
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	bad := u
+	bad.UID = uuid.NewString()
+	bad.Email = "other@example.com"
+	bad.PositionID = 999
+	err = repo.InsertUser(ctx, bad)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+}
+
+func testUserPfp(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "pfp@example.com")
+
+	require.NoError(t, repo.UpdateUserPfpURL(ctx, u.UID, "/uploads/a.png"))
+	got, err := repo.SelectUserByUID(ctx, u.UID)
+	require.NoError(t, err)
+	require.NotNil(t, got.PfpURL)
+	assert.Equal(t, "/uploads/a.png", *got.PfpURL)
+
+	err = repo.UpdateUserPfpURL(ctx, uuid.NewString(), "/uploads/b.png")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testPositions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	position, err := repo.InsertPosition(ctx, "Designer")
+	require.NoError(t, err)
+	assert.Equal(t, "Designer", position.Name)
+	assert.Greater(t, position.ID, Positions[len(Positions)-1].ID)
+	_, err = repo.InsertPosition(ctx, Positions[0].Name)
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	positions, err := repo.SelectPositions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, append(slices.Clone(Positions), position), positions)
+
+	u := User(t, repo, "designer@example.com")
+	require.NoError(t, repo.UpdateUserPassword(ctx, u.UID, "new hash"))
+	got, err := repo.SelectUserByUID(ctx, u.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "new hash", got.Password)
+	assert.ErrorIs(t, repo.UpdateUserPassword(ctx, uuid.NewString(), "hash"), repository.ErrNotFound)
+}
+
+func testInvitations(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	created := time.Now().UTC().Truncate(time.Millisecond)
+	expires := created.Add(14 * 24 * time.Hour)
+	invite := func(hash, uid string) models.Invitation {
+		return models.Invitation{TokenHash: hash, UserUID: uid, CreatedAt: created, ExpiresAt: expires}
+	}
+
+	require.NoError(t, repo.InsertInvitation(ctx, invite("hash-a1", alice.UID)))
+	require.NoError(t, repo.InsertInvitation(ctx, invite("hash-a2", alice.UID)))
+	require.NoError(t, repo.InsertInvitation(ctx, invite("hash-b", bob.UID)))
+	assert.ErrorIs(t, repo.InsertInvitation(ctx, invite("hash-b", alice.UID)), repository.ErrConflict)
+	assert.ErrorIs(t, repo.InsertInvitation(ctx, invite("hash-x", uuid.NewString())), repository.ErrInvalidReference)
+
+	_, err := repo.ConsumeInvitation(ctx, "hash-a1")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "replaced by the newer invitation")
+	inv, err := repo.ConsumeInvitation(ctx, "hash-a2")
+	require.NoError(t, err)
+	assert.Equal(t, alice.UID, inv.UserUID)
+	assert.True(t, created.Equal(inv.CreatedAt))
+	assert.True(t, expires.Equal(inv.ExpiresAt))
+	_, err = repo.ConsumeInvitation(ctx, "hash-a2")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "invitations are one-time")
+
+	inv, err = repo.ConsumeInvitation(ctx, "hash-b")
+	require.NoError(t, err)
+	assert.Equal(t, bob.UID, inv.UserUID)
+}
+
+func testStreamTickets(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	expires := time.Now().UTC().Add(time.Minute).Truncate(time.Millisecond)
+	ticket := func(hash, uid string, expires time.Time) models.StreamTicket {
+		return models.StreamTicket{TicketHash: hash, UserUID: uid, ExpiresAt: expires}
+	}
+
+	require.NoError(t, repo.InsertStreamTicket(ctx, ticket("hash-1", alice.UID, expires)))
+	require.NoError(t, repo.InsertStreamTicket(ctx, ticket("hash-2", alice.UID, expires)), "one per open tab")
+	assert.ErrorIs(t, repo.InsertStreamTicket(ctx, ticket("hash-1", alice.UID, expires)), repository.ErrConflict)
+	assert.ErrorIs(t, repo.InsertStreamTicket(ctx, ticket("hash-x", uuid.NewString(), expires)),
+		repository.ErrInvalidReference)
+
+	got, err := repo.ConsumeStreamTicket(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, alice.UID, got.UserUID)
+	assert.True(t, expires.Equal(got.ExpiresAt))
+	_, err = repo.ConsumeStreamTicket(ctx, "hash-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "tickets are one-time")
+
+	require.NoError(t, repo.InsertStreamTicket(ctx, ticket("hash-3", alice.UID, expires.Add(time.Hour))))
+	purged, err := repo.DeleteExpiredStreamTickets(ctx, expires)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = repo.ConsumeStreamTicket(ctx, "hash-2")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.ConsumeStreamTicket(ctx, "hash-3")
+	assert.NoError(t, err)
+}
+
+func testIdeas(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+
+	ideas, err := repo.SelectIdeas(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ideas)
+
+	u := User(t, repo, "author@example.com")
+	first := Idea(t, repo, u.UID, "first")
+	pause()
+	second := Idea(t, repo, u.UID, "second")
+
+	assert.Equal(t, "first", first.Name)
+	assert.Equal(t, u.UID, first.Author)
+	assert.Zero(t, first.LikeCount)
+	assert.Zero(t, first.DislikeCount)
+	assert.WithinDuration(t, time.Now(), first.CreationDate, time.Minute)
+
+	// oldest first
+	ideas, err = repo.SelectIdeas(ctx)
+	require.NoError(t, err)
+	require.Len(t, ideas, 2)
+	assert.Equal(t, first.IdeaUID, ideas[0].IdeaUID)
+	assert.Equal(t, second.IdeaUID, ideas[1].IdeaUID)
+
+	_, err = repo.SelectIdeaByUID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	err = repo.InsertIdea(ctx, first)
+	assert.ErrorIs(t, err, repository.ErrConflict)
+}
+
+func testIdeaReferences(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "refs@example.com")
+
+	base := models.Idea{
+		Name:       "idea",
+		Text:       "text",
+		Author:     u.UID,
+		StatusID:   Statuses[0].ID,
+		CategoryID: Categories[0].ID,
+	}
+	cases := map[string]func(idea *models.Idea){
+		"author":   func(idea *models.Idea) { idea.Author = uuid.NewString() },
+		"status":   func(idea *models.Idea) { idea.StatusID = 999 },
+		"category": func(idea *models.Idea) { idea.CategoryID = 999 },
+	}
+	for name, mutate := range cases {
+		idea := base
+		idea.IdeaUID = uuid.NewString()
+		mutate(&idea)
+		err := repo.InsertIdea(ctx, idea)
+		assert.ErrorIs(t, err, repository.ErrInvalidReference, name)
+	}
+
+	ideas, err := repo.SelectIdeas(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ideas)
+}
+
+func testUserIdeas(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "many@example.com")
+	other := User(t, repo, "other@example.com")
+
+	var uids []string
+	for _, name := range []string{"a", "b", "c"} {
+		uids = append(uids, Idea(t, repo, u.UID, name).IdeaUID)
+		pause()
+	}
+	Idea(t, repo, other.UID, "foreign")
+
+	// newest first, limited
+	ideas, err := repo.SelectUserIdeas(ctx, u.UID, 2)
+	require.NoError(t, err)
+	require.Len(t, ideas, 2)
+	assert.Equal(t, uids[2], ideas[0].IdeaUID)
+	assert.Equal(t, uids[1], ideas[1].IdeaUID)
+
+	ideas, err = repo.SelectUserIdeas(ctx, u.UID, 10)
+	require.NoError(t, err)
+	assert.Len(t, ideas, 3)
+
+	ideas, err = repo.SelectUserIdeas(ctx, uuid.NewString(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, ideas)
+}
+
+func testSearchIdeas(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "search@example.com")
+
+	insert := func(name, text string) string {
+		idea := models.Idea{
+			IdeaUID:    uuid.NewString(),
+			Name:       name,
+			Text:       text,
+			Author:     u.UID,
+			StatusID:   Statuses[0].ID,
+			CategoryID: Categories[0].ID,
+		}
+		require.NoError(t, repo.InsertIdea(ctx, idea))
+		return idea.IdeaUID
+	}
+	lights := insert("Датчики движения", "Выключать свет в пустых кабинетах")
+	coffee := insert("Кофемашина", "Новая кофемашина на третьем этаже, свет там тоже нужен")
+	insert("Coffee break", "Shorter meetings, longer coffee breaks")
+
+	search := func(query string, limit int) []string {
+		t.Helper()
+		ideas, err := repo.SearchIdeas(ctx, query, limit)
+		require.NoError(t, err)
+		var uids []string
+		for _, idea := range ideas {
+			uids = append(uids, idea.IdeaUID)
+		}
+		return uids
+	}
+
+	// whole words, case-insensitive, name and text
+	assert.Equal(t, []string{lights}, search("ДАТЧИКИ", 10))
+	assert.Equal(t, []string{coffee}, search("кофемашина", 10))
+	assert.ElementsMatch(t, []string{lights, coffee}, search("свет", 10))
+	assert.Empty(t, search("датчик", 10))
+
+	// every word must match, punctuation is ignored
+	assert.Equal(t, []string{lights}, search("свет, кабинетах!", 10))
+	assert.Empty(t, search("свет coffee", 10))
+
+	assert.Len(t, search("свет", 1), 1)
+	assert.Empty(t, search("  ?! ", 10))
+	assert.Empty(t, search(`" OR name:*`, 10))
+}
+
+// Comment inserts a comment, a reply when parent is not empty
+func Comment(t *testing.T, repo repository.Repository, ideaUID, parent, author, text string) models.Comment {
+	t.Helper()
+	c := models.Comment{CommentUID: uuid.NewString(), IdeaUID: ideaUID, AuthorID: author, CommentText: text}
+	if parent != "" {
+		c.ParentUID = &parent
+	}
+	require.NoError(t, repo.InsertIdeaComment(context.Background(), c))
+	pause()
+	return c
+}
+
+func testComments(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "talker@example.com")
+	idea := Idea(t, repo, u.UID, "discussed")
+
+	first := Comment(t, repo, idea.IdeaUID, "", u.UID, "first")
+	second := Comment(t, repo, idea.IdeaUID, "", u.UID, "second")
+	reply := Comment(t, repo, idea.IdeaUID, first.CommentUID, u.UID, "reply")
+	deep := Comment(t, repo, idea.IdeaUID, reply.CommentUID, u.UID, "deep")
+
+	comments, err := repo.SelectIdeaComments(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	require.Len(t, comments, 4)
+	assert.Equal(t, first.CommentUID, comments[0].CommentUID)
+	assert.Equal(t, "first", comments[0].CommentText)
+	assert.Equal(t, u.UID, comments[0].AuthorID)
+	assert.False(t, comments[0].Timestamp.IsZero())
+	assert.Equal(t, second.CommentUID, comments[1].CommentUID)
+
+	got, err := repo.SelectCommentByUID(ctx, first.CommentUID)
+	require.NoError(t, err)
+	assert.Nil(t, got.ParentUID)
+	assert.Equal(t, 0, got.Depth)
+	assert.Equal(t, first.CommentUID, got.Path)
+
+	got, err = repo.SelectCommentByUID(ctx, deep.CommentUID)
+	require.NoError(t, err)
+	require.NotNil(t, got.ParentUID)
+	assert.Equal(t, reply.CommentUID, *got.ParentUID)
+	assert.Equal(t, 2, got.Depth)
+	assert.Equal(t, first.CommentUID+"/"+reply.CommentUID+"/"+deep.CommentUID, got.Path)
+
+	comments, err = repo.SelectIdeaComments(ctx, uuid.NewString())
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+
+	orphan := models.Comment{CommentUID: uuid.NewString(), IdeaUID: uuid.NewString(), AuthorID: u.UID, CommentText: "x"}
+	assert.ErrorIs(t, repo.InsertIdeaComment(ctx, orphan), repository.ErrInvalidReference)
+
+	missing := uuid.NewString()
+	orphanReply := models.Comment{CommentUID: uuid.NewString(), IdeaUID: idea.IdeaUID, ParentUID: &missing, AuthorID: u.UID, CommentText: "x"}
+	assert.ErrorIs(t, repo.InsertIdeaComment(ctx, orphanReply), repository.ErrInvalidReference)
+
+	duplicate := first
+	duplicate.ParentUID = &second.CommentUID
+	assert.ErrorIs(t, repo.InsertIdeaComment(ctx, duplicate), repository.ErrConflict)
+}
+
+func testIdeaWithAuthor(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := User(t, repo, "author@example.com")
+	require.NoError(t, repo.UpdateUserPfpURL(ctx, author.UID, "/uploads/author.png"))
+	idea := Idea(t, repo, author.UID, "shown")
+
+	got, err := repo.SelectIdeaWithAuthor(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, idea.IdeaUID, got.IdeaUID)
+	assert.Equal(t, "shown", got.Name)
+	require.NotNil(t, got.AuthorProfile)
+	assert.Equal(t, author.UID, got.AuthorProfile.UID)
+	assert.Equal(t, author.Name, got.AuthorProfile.Name)
+	assert.Equal(t, author.Surname, got.AuthorProfile.Surname)
+	require.NotNil(t, got.AuthorProfile.PfpURL)
+	assert.Equal(t, "/uploads/author.png", *got.AuthorProfile.PfpURL)
+
+	_, err = repo.SelectIdeaWithAuthor(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testCommentChildren(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := User(t, repo, "author@example.com")
+	critic := User(t, repo, "critic@example.com")
+	require.NoError(t, repo.UpdateUserPfpURL(ctx, critic.UID, "/uploads/critic.png"))
+	idea := Idea(t, repo, author.UID, "thread")
+
+	roots, err := repo.SelectCommentChildren(ctx, idea.IdeaUID, "", "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, roots)
+
+	first := Comment(t, repo, idea.IdeaUID, "", critic.UID, "first")
+	answer1 := Comment(t, repo, idea.IdeaUID, first.CommentUID, author.UID, "answer 1")
+	second := Comment(t, repo, idea.IdeaUID, "", author.UID, "second")
+	answer2 := Comment(t, repo, idea.IdeaUID, first.CommentUID, critic.UID, "answer 2")
+	Comment(t, repo, idea.IdeaUID, answer1.CommentUID, critic.UID, "nested")
+	third := Comment(t, repo, idea.IdeaUID, "", author.UID, "third")
+
+	// another idea's discussion must not leak in
+	other := Idea(t, repo, author.UID, "other")
+	Comment(t, repo, other.IdeaUID, "", author.UID, "elsewhere")
+
+	uids := func(comments []models.Comment) []string {
+		var res []string
+		for _, c := range comments {
+			res = append(res, c.CommentUID)
+		}
+		return res
+	}
+
+	roots, err = repo.SelectCommentChildren(ctx, idea.IdeaUID, "", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.CommentUID, second.CommentUID, third.CommentUID}, uids(roots))
+
+	c := roots[0]
+	assert.Equal(t, "first", c.CommentText)
+	assert.Equal(t, 2, c.ChildCount)
+	require.NotNil(t, c.AuthorProfile)
+	assert.Equal(t, critic.UID, c.AuthorProfile.UID)
+	assert.Equal(t, critic.Name, c.AuthorProfile.Name)
+	require.NotNil(t, c.AuthorProfile.PfpURL)
+	assert.Equal(t, "/uploads/critic.png", *c.AuthorProfile.PfpURL)
+	assert.Equal(t, 0, roots[1].ChildCount)
+
+	// keyset pages
+	page, err := repo.SelectCommentChildren(ctx, idea.IdeaUID, "", "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{first.CommentUID, second.CommentUID}, uids(page))
+	page, err = repo.SelectCommentChildren(ctx, idea.IdeaUID, "", second.CommentUID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{third.CommentUID}, uids(page))
+	page, err = repo.SelectCommentChildren(ctx, idea.IdeaUID, "", third.CommentUID, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	page, err = repo.SelectCommentChildren(ctx, idea.IdeaUID, "", uuid.NewString(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+
+	children, err := repo.SelectCommentChildren(ctx, idea.IdeaUID, first.CommentUID, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{answer1.CommentUID, answer2.CommentUID}, uids(children))
+	assert.Equal(t, 1, children[0].ChildCount)
+	assert.Equal(t, author.UID, children[0].AuthorProfile.UID)
+
+	children, err = repo.SelectCommentChildren(ctx, idea.IdeaUID, first.CommentUID, answer1.CommentUID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{answer2.CommentUID}, uids(children))
+}
+
+func testCommentSubtrees(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "author@example.com")
+	idea := Idea(t, repo, u.UID, "tree")
+
+	// a
+	// ├─ a1
+	// │  └─ a11
+	// │     └─ a111
+	// └─ a2
+	// b
+	// └─ b1
+	a := Comment(t, repo, idea.IdeaUID, "", u.UID, "a")
+	b := Comment(t, repo, idea.IdeaUID, "", u.UID, "b")
+	a1 := Comment(t, repo, idea.IdeaUID, a.CommentUID, u.UID, "a1")
+	b1 := Comment(t, repo, idea.IdeaUID, b.CommentUID, u.UID, "b1")
+	a11 := Comment(t, repo, idea.IdeaUID, a1.CommentUID, u.UID, "a11")
+	a2 := Comment(t, repo, idea.IdeaUID, a.CommentUID, u.UID, "a2")
+	a111 := Comment(t, repo, idea.IdeaUID, a11.CommentUID, u.UID, "a111")
+
+	texts := func(comments []models.Comment) []string {
+		var res []string
+		for _, c := range comments {
+			res = append(res, c.CommentText)
+		}
+		return res
+	}
+
+	sub, err := repo.SelectCommentSubtrees(ctx, []string{a.CommentUID}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1", "a11", "a2", "a111"}, texts(sub))
+	assert.Equal(t, 1, sub[0].ChildCount)
+	require.NotNil(t, sub[0].AuthorProfile)
+	assert.Equal(t, u.UID, sub[0].AuthorProfile.UID)
+
+	sub, err = repo.SelectCommentSubtrees(ctx, []string{a.CommentUID, b.CommentUID}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1", "b1", "a2"}, texts(sub))
+
+	sub, err = repo.SelectCommentSubtrees(ctx, []string{a11.CommentUID, b1.CommentUID}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a111"}, texts(sub))
+
+	sub, err = repo.SelectCommentSubtrees(ctx, []string{a2.CommentUID, a111.CommentUID, uuid.NewString()}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, sub)
+
+	sub, err = repo.SelectCommentSubtrees(ctx, nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, sub)
+}
+
+func testEditComments(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "editor@example.com")
+	idea := Idea(t, repo, u.UID, "edited")
+
+	comment := Comment(t, repo, idea.IdeaUID, "", u.UID, "draft")
+	reply := Comment(t, repo, idea.IdeaUID, comment.CommentUID, u.UID, "draft reply")
+
+	got, err := repo.SelectCommentByUID(ctx, comment.CommentUID)
+	require.NoError(t, err)
+	assert.Equal(t, "draft", got.CommentText)
+	assert.Nil(t, got.EditedAt)
+	assert.Nil(t, got.DeletedAt)
+
+	require.NoError(t, repo.UpdateCommentText(ctx, comment.CommentUID, "final"))
+	got, err = repo.SelectCommentByUID(ctx, comment.CommentUID)
+	require.NoError(t, err)
+	assert.Equal(t, "final", got.CommentText)
+	require.NotNil(t, got.EditedAt)
+	assert.False(t, got.EditedAt.Before(got.Timestamp))
+
+	require.NoError(t, repo.TombstoneComment(ctx, comment.CommentUID))
+
+	// tombstones stay in the thread with their replies
+	roots, err := repo.SelectCommentChildren(ctx, idea.IdeaUID, "", "", 10)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Empty(t, roots[0].CommentText)
+	assert.NotNil(t, roots[0].EditedAt)
+	assert.NotNil(t, roots[0].DeletedAt)
+	assert.Equal(t, 1, roots[0].ChildCount)
+
+	sub, err := repo.SelectCommentSubtrees(ctx, []string{comment.CommentUID}, 10)
+	require.NoError(t, err)
+	require.Len(t, sub, 1)
+	assert.Equal(t, reply.CommentUID, sub[0].CommentUID)
+	assert.Equal(t, "draft reply", sub[0].CommentText)
+
+	missing := uuid.NewString()
+	_, err = repo.SelectCommentByUID(ctx, missing)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdateCommentText(ctx, missing, "x"), repository.ErrNotFound)
+	assert.ErrorIs(t, repo.TombstoneComment(ctx, missing), repository.ErrNotFound)
+}
+
+func testRevisions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "reviser@example.com")
+	target, other := uuid.NewString(), uuid.NewString()
+
+	for _, text := range []string{"v1", "v2"} {
+		require.NoError(t, repo.InsertRevision(ctx, models.Revision{TargetUID: target, Text: text, EditorUID: u.UID}))
+		pause()
+	}
+	require.NoError(t, repo.InsertRevision(ctx, models.Revision{TargetUID: other, Text: "x", EditorUID: u.UID}))
+
+	revisions, err := repo.SelectRevisions(ctx, target)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "v1", revisions[0].Text)
+	assert.Equal(t, "v2", revisions[1].Text)
+	assert.Equal(t, u.UID, revisions[0].EditorUID)
+	assert.Equal(t, target, revisions[0].TargetUID)
+	assert.NotZero(t, revisions[0].ID)
+	assert.WithinDuration(t, time.Now(), revisions[0].EditedAt, time.Minute)
+
+	err = repo.InsertRevision(ctx, models.Revision{TargetUID: target, Text: "x", EditorUID: uuid.NewString()})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	require.NoError(t, repo.DeleteRevisions(ctx, target))
+	revisions, err = repo.SelectRevisions(ctx, target)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	revisions, err = repo.SelectRevisions(ctx, other)
+	require.NoError(t, err)
+	assert.Len(t, revisions, 1)
+}
+
+func testReactions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	idea := Idea(t, repo, alice.UID, "liked")
+	comment := Comment(t, repo, idea.IdeaUID, "", bob.UID, "nice")
+
+	react := func(target, user, emoji string) error {
+		return repo.InsertReaction(ctx, models.Reaction{TargetUID: target, UserUID: user, Emoji: emoji})
+	}
+	require.NoError(t, react(idea.IdeaUID, alice.UID, "👍"))
+	require.NoError(t, react(idea.IdeaUID, bob.UID, "👍"))
+	require.NoError(t, react(idea.IdeaUID, bob.UID, "🎉"))
+	require.NoError(t, react(comment.CommentUID, alice.UID, "❤️"))
+
+	assert.ErrorIs(t, react(idea.IdeaUID, bob.UID, "👍"), repository.ErrConflict)
+	assert.ErrorIs(t, react(idea.IdeaUID, uuid.NewString(), "👍"), repository.ErrInvalidReference)
+
+	counts, err := repo.SelectReactionCounts(ctx, []string{idea.IdeaUID}, alice.UID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.ReactionCount{
+		{TargetUID: idea.IdeaUID, Emoji: "👍", Count: 2, Mine: true},
+		{TargetUID: idea.IdeaUID, Emoji: "🎉", Count: 1, Mine: false},
+	}, counts)
+
+	counts, err = repo.SelectReactionCounts(ctx, []string{comment.CommentUID, idea.IdeaUID, uuid.NewString()}, bob.UID)
+	require.NoError(t, err)
+	require.Len(t, counts, 3)
+	// rows of one target are adjacent
+	targets := []string{counts[0].TargetUID, counts[1].TargetUID, counts[2].TargetUID}
+	assert.Contains(t, [][]string{
+		{comment.CommentUID, idea.IdeaUID, idea.IdeaUID},
+		{idea.IdeaUID, idea.IdeaUID, comment.CommentUID},
+	}, targets)
+	for _, c := range counts {
+		assert.Equal(t, c.TargetUID == idea.IdeaUID, c.Mine)
+	}
+
+	counts, err = repo.SelectReactionCounts(ctx, []string{idea.IdeaUID}, "")
+	require.NoError(t, err)
+	for _, c := range counts {
+		assert.False(t, c.Mine)
+	}
+
+	require.NoError(t, repo.DeleteReaction(ctx, idea.IdeaUID, bob.UID, "👍"))
+	assert.ErrorIs(t, repo.DeleteReaction(ctx, idea.IdeaUID, bob.UID, "👍"), repository.ErrNotFound)
+
+	counts, err = repo.SelectReactionCounts(ctx, []string{idea.IdeaUID}, bob.UID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []models.ReactionCount{
+		{TargetUID: idea.IdeaUID, Emoji: "👍", Count: 1, Mine: false},
+		{TargetUID: idea.IdeaUID, Emoji: "🎉", Count: 1, Mine: true},
+	}, counts)
+
+	counts, err = repo.SelectReactionCounts(ctx, nil, bob.UID)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func testMentions(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	idea := Idea(t, repo, alice.UID, "mentioning")
+	comment := Comment(t, repo, idea.IdeaUID, "", bob.UID, "thanks @alice")
+
+	mention := func(target string, typ models.TargetType, author, user string, start int) models.Mention {
+		return models.Mention{TargetUID: target, TargetType: typ, IdeaUID: idea.IdeaUID,
+			AuthorUID: author, UserUID: user, Start: start, End: start + 5}
+	}
+	require.NoError(t, repo.InsertMentions(ctx, []models.Mention{
+		mention(idea.IdeaUID, models.TargetIdea, alice.UID, bob.UID, 10),
+		mention(idea.IdeaUID, models.TargetIdea, alice.UID, bob.UID, 0),
+	}))
+	require.NoError(t, repo.InsertMentions(ctx, []models.Mention{
+		mention(comment.CommentUID, models.TargetComment, bob.UID, alice.UID, 7),
+	}))
+	require.NoError(t, repo.InsertMentions(ctx, nil))
+
+	err := repo.InsertMentions(ctx, []models.Mention{mention(idea.IdeaUID, models.TargetIdea, alice.UID, bob.UID, 0)})
+	assert.ErrorIs(t, err, repository.ErrConflict)
+	err = repo.InsertMentions(ctx, []models.Mention{mention(idea.IdeaUID, models.TargetIdea, alice.UID, uuid.NewString(), 20)})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	mentions, err := repo.SelectMentions(ctx, []string{idea.IdeaUID})
+	require.NoError(t, err)
+	require.Len(t, mentions, 2)
+	assert.Equal(t, 0, mentions[0].Start)
+	assert.Equal(t, 10, mentions[1].Start)
+	assert.Equal(t, 15, mentions[1].End)
+	assert.Equal(t, models.TargetIdea, mentions[0].TargetType)
+	assert.Equal(t, idea.IdeaUID, mentions[0].IdeaUID)
+	assert.Equal(t, alice.UID, mentions[0].AuthorUID)
+	assert.Equal(t, bob.UID, mentions[0].UserUID)
+	require.NotNil(t, mentions[0].User)
+	assert.Equal(t, bob.UID, mentions[0].User.UID)
+	assert.Equal(t, bob.Surname, mentions[0].User.Surname)
+	assert.False(t, mentions[0].CreatedAt.IsZero())
+
+	mentions, err = repo.SelectMentions(ctx, []string{idea.IdeaUID, comment.CommentUID})
+	require.NoError(t, err)
+	assert.Len(t, mentions, 3)
+
+	require.NoError(t, repo.DeleteMentions(ctx, idea.IdeaUID))
+	require.NoError(t, repo.DeleteMentions(ctx, idea.IdeaUID))
+	mentions, err = repo.SelectMentions(ctx, []string{idea.IdeaUID, comment.CommentUID})
+	require.NoError(t, err)
+	require.Len(t, mentions, 1)
+	assert.Equal(t, comment.CommentUID, mentions[0].TargetUID)
+
+	mentions, err = repo.SelectMentions(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, mentions)
+}
+
+func testProfilesByName(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	insert := func(email, name, surname string) models.User {
+		u := models.User{UID: uuid.NewString(), Name: name, Surname: surname,
+			PositionID: Positions[0].ID, Email: email, Password: "hash", Phone: "9990001122"}
+		require.NoError(t, repo.InsertUser(ctx, u))
+		return u
+	}
+	anna := insert("anna@example.com", "Анна", "Иванова")
+	namesake := insert("anna2@example.com", "Анна", "Иванова")
+	john := insert("john@example.com", "John", "Smith")
+
+	profiles, err := repo.SelectProfilesByName(ctx, []models.FullName{
+		{Name: "анна", Surname: "ИВАНОВА"},
+		{Name: "JOHN", Surname: "smith"},
+		{Name: "Nobody", Surname: "Known"},
+	})
+	require.NoError(t, err)
+	var uids []string
+	for _, p := range profiles {
+		uids = append(uids, p.UID)
+	}
+	assert.ElementsMatch(t, []string{anna.UID, namesake.UID, john.UID}, uids)
+
+	profiles, err = repo.SelectProfilesByName(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, profiles)
+
+	profiles, err = repo.SelectProfiles(ctx, []string{john.UID, uuid.NewString(), john.UID})
+	require.NoError(t, err)
+	assert.Equal(t, []models.AuthorProfile{{UID: john.UID, Name: "John", Surname: "Smith"}}, profiles)
+}
+
+func testIdeaStatus(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "author@example.com")
+	idea := Idea(t, repo, u.UID, "moderated")
+
+	require.NoError(t, repo.UpdateIdeaStatus(ctx, idea.IdeaUID, Statuses[1].ID))
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, Statuses[1].ID, stored.StatusID)
+
+	assert.ErrorIs(t, repo.UpdateIdeaStatus(ctx, uuid.NewString(), Statuses[1].ID), repository.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdateIdeaStatus(ctx, idea.IdeaUID, 999), repository.ErrInvalidReference)
+}
+
+func testNotifications(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	idea := Idea(t, repo, alice.UID, "noticed")
+	comment := Comment(t, repo, idea.IdeaUID, "", bob.UID, "hi")
+
+	notify := func(user string, typ models.NotificationType, commentUID *string, detail string) models.Notification {
+		t.Helper()
+		n, err := repo.InsertNotification(ctx, models.Notification{
+			UserUID: user, Type: typ, ActorUID: bob.UID, IdeaUID: idea.IdeaUID, CommentUID: commentUID, Detail: detail,
+		})
+		require.NoError(t, err)
+		return n
+	}
+	first := notify(alice.UID, models.NotificationComment, &comment.CommentUID, "")
+	notify(alice.UID, models.NotificationVote, nil, string(models.VoteLike))
+	last := notify(alice.UID, models.NotificationStatus, nil, "2")
+	notify(bob.UID, models.NotificationMention, &comment.CommentUID, "")
+	assert.Greater(t, last.ID, first.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+	assert.Equal(t, alice.UID, first.UserUID)
+
+	_, err := repo.InsertNotification(ctx, models.Notification{
+		UserUID: uuid.NewString(), Type: models.NotificationVote, ActorUID: bob.UID, IdeaUID: idea.IdeaUID,
+	})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	page, err := repo.SelectNotifications(ctx, alice.UID, false, 0, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, last.ID, page[0].ID)
+	assert.Equal(t, models.NotificationStatus, page[0].Type)
+	assert.Equal(t, "2", page[0].Detail)
+	assert.Nil(t, page[0].CommentUID)
+	assert.Equal(t, models.NotificationVote, page[1].Type)
+	assert.Greater(t, page[0].ID, page[1].ID)
+	assert.False(t, page[0].CreatedAt.IsZero())
+	assert.Nil(t, page[0].ReadAt)
+	require.NotNil(t, page[0].Actor)
+	assert.Equal(t, bob.UID, page[0].Actor.UID)
+	assert.Equal(t, bob.Name, page[0].Actor.Name)
+	assert.Equal(t, idea.Name, page[0].IdeaName)
+
+	older, err := repo.SelectNotifications(ctx, alice.UID, false, page[1].ID, 10)
+	require.NoError(t, err)
+	require.Len(t, older, 1)
+	assert.Equal(t, models.NotificationComment, older[0].Type)
+	require.NotNil(t, older[0].CommentUID)
+	assert.Equal(t, comment.CommentUID, *older[0].CommentUID)
+
+	unread, err := repo.CountUnreadNotifications(ctx, alice.UID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, unread)
+
+	require.NoError(t, repo.MarkNotificationRead(ctx, alice.UID, page[1].ID))
+	require.NoError(t, repo.MarkNotificationRead(ctx, alice.UID, page[1].ID))
+	assert.ErrorIs(t, repo.MarkNotificationRead(ctx, bob.UID, page[1].ID), repository.ErrNotFound)
+	assert.ErrorIs(t, repo.MarkNotificationRead(ctx, alice.UID, 1<<40), repository.ErrNotFound)
+
+	onlyUnread, err := repo.SelectNotifications(ctx, alice.UID, true, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, onlyUnread, 2)
+	assert.Equal(t, page[0].ID, onlyUnread[0].ID)
+	assert.Equal(t, older[0].ID, onlyUnread[1].ID)
+
+	require.NoError(t, repo.MarkAllNotificationsRead(ctx, alice.UID))
+	unread, err = repo.CountUnreadNotifications(ctx, alice.UID)
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+	all, err := repo.SelectNotifications(ctx, alice.UID, false, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	for _, n := range all {
+		assert.NotNil(t, n.ReadAt)
+	}
+
+	unread, err = repo.CountUnreadNotifications(ctx, bob.UID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, unread, "other users' notifications are untouched")
+}
+
+func testNotificationPreferences(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "prefs@example.com")
+
+	prefs, err := repo.SelectNotificationPreferences(ctx, u.UID)
+	require.NoError(t, err)
+	assert.Empty(t, prefs)
+
+	set := func(typ models.NotificationType, enabled bool) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNotificationPreference(ctx,
+			models.NotificationPreference{UserUID: u.UID, Type: typ, Enabled: enabled}))
+	}
+	set(models.NotificationVote, false)
+	set(models.NotificationReply, false)
+	set(models.NotificationReply, true)
+
+	prefs, err = repo.SelectNotificationPreferences(ctx, u.UID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.NotificationPreference{
+		{UserUID: u.UID, Type: models.NotificationReply, Enabled: true},
+		{UserUID: u.UID, Type: models.NotificationVote, Enabled: false},
+	}, prefs)
+
+	err = repo.UpsertNotificationPreference(ctx,
+		models.NotificationPreference{UserUID: uuid.NewString(), Type: models.NotificationVote})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+}
+
+func testOutbox(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	queue := func(u models.User, subject string, due time.Time) {
+		t.Helper()
+		require.NoError(t, repo.InsertOutboxEmail(ctx, models.OutboxEmail{
+			UserUID: u.UID, Kind: models.EmailMention, To: u.Email,
+			Subject: subject, TextBody: "text", HTMLBody: "<p>html</p>", NextAttemptAt: due,
+		}))
+	}
+	queue(alice, "first", now.Add(-time.Minute))
+	queue(bob, "second", now.Add(-time.Hour))
+	queue(alice, "later", now.Add(time.Hour))
+
+	err := repo.InsertOutboxEmail(ctx, models.OutboxEmail{UserUID: uuid.NewString(), Kind: models.EmailMention,
+		To: "x@example.com", NextAttemptAt: now})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	lease := now.Add(5 * time.Minute)
+	claimed, err := repo.ClaimOutboxEmails(ctx, now, lease, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "first", claimed[0].Subject, "ordered by id")
+	assert.Equal(t, "second", claimed[1].Subject)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.True(t, claimed[0].NextAttemptAt.Equal(lease))
+	assert.Equal(t, alice.Email, claimed[0].To)
+	assert.Equal(t, "<p>html</p>", claimed[0].HTMLBody)
+
+	again, err := repo.ClaimOutboxEmails(ctx, now, lease, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again, "leased emails are skipped")
+
+	require.NoError(t, repo.MarkOutboxEmailSent(ctx, claimed[0].ID))
+	retry := now.Add(time.Minute)
+	require.NoError(t, repo.FailOutboxEmail(ctx, claimed[1].ID, "421 try later", &retry))
+	assert.ErrorIs(t, repo.MarkOutboxEmailSent(ctx, claimed[1].ID+100), repository.ErrNotFound)
+
+	claimed, err = repo.ClaimOutboxEmails(ctx, now.Add(2*time.Minute), lease.Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "second", claimed[0].Subject)
+	assert.Equal(t, 2, claimed[0].Attempts)
+	assert.Equal(t, "421 try later", claimed[0].LastError)
+	require.NoError(t, repo.FailOutboxEmail(ctx, claimed[0].ID, "550 no such user", nil))
+
+	claimed, err = repo.ClaimOutboxEmails(ctx, now.Add(2*time.Hour), now.Add(3*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "given up and sent emails stay put")
+	assert.Equal(t, "later", claimed[0].Subject)
+
+	log, err := repo.SelectOutboxEmails(ctx, bob.UID, 10)
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.NotNil(t, log[0].FailedAt)
+	assert.Nil(t, log[0].SentAt)
+	assert.Equal(t, "550 no such user", log[0].LastError)
+
+	log, err = repo.SelectOutboxEmails(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, log, 3)
+	assert.Equal(t, "later", log[0].Subject, "newest first")
+	assert.NotNil(t, log[2].SentAt)
+	assert.False(t, log[2].CreatedAt.IsZero())
+}
+
+func testEmailSettings(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	carol := User(t, repo, "carol@example.com")
+
+	_, err := repo.SelectEmailSettings(ctx, alice.UID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	require.NoError(t, repo.UpsertEmailSettings(ctx, models.EmailSettings{
+		UserUID: bob.UID, Language: "en", Immediate: false, Digest: models.DigestDaily,
+	}))
+	require.NoError(t, repo.UpsertEmailSettings(ctx, models.EmailSettings{
+		UserUID: carol.UID, Language: "ru", Immediate: true, Digest: models.DigestOff,
+	}))
+	err = repo.UpsertEmailSettings(ctx, models.EmailSettings{UserUID: uuid.NewString(), Language: "ru", Digest: models.DigestOff})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	settings, err := repo.SelectEmailSettings(ctx, bob.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "en", settings.Language)
+	assert.False(t, settings.Immediate)
+	assert.Nil(t, settings.LastDigestAt)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	weekly, err := repo.SelectDigestRecipients(ctx, models.DigestWeekly, now, 10)
+	require.NoError(t, err)
+	require.Len(t, weekly, 1, "defaults apply to users without settings")
+	assert.Equal(t, alice.UID, weekly[0].UserUID)
+	assert.Equal(t, alice.Email, weekly[0].Email)
+	assert.Equal(t, alice.Name, weekly[0].Name)
+	assert.Equal(t, models.DefaultEmailSettings.Language, weekly[0].Language)
+	assert.True(t, weekly[0].Immediate)
+
+	daily, err := repo.SelectDigestRecipients(ctx, models.DigestDaily, now, 10)
+	require.NoError(t, err)
+	require.Len(t, daily, 1)
+	assert.Equal(t, "en", daily[0].Language)
+
+	require.NoError(t, repo.MarkDigestSent(ctx, alice.UID, now))
+	require.NoError(t, repo.MarkDigestSent(ctx, bob.UID, now))
+	weekly, err = repo.SelectDigestRecipients(ctx, models.DigestWeekly, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, weekly)
+	weekly, err = repo.SelectDigestRecipients(ctx, models.DigestWeekly, now.Add(time.Second), 10)
+	require.NoError(t, err)
+	assert.Len(t, weekly, 1)
+
+	settings, err = repo.SelectEmailSettings(ctx, bob.UID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DigestDaily, settings.Digest, "marking keeps the settings")
+	require.NotNil(t, settings.LastDigestAt)
+	assert.True(t, settings.LastDigestAt.Equal(now))
+
+	// changing the settings keeps the last digest time
+	require.NoError(t, repo.UpsertEmailSettings(ctx, models.EmailSettings{
+		UserUID: bob.UID, Language: "ru", Immediate: true, Digest: models.DigestDaily,
+	}))
+	settings, err = repo.SelectEmailSettings(ctx, bob.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "ru", settings.Language)
+	assert.NotNil(t, settings.LastDigestAt)
+}
+
+func testTrendingIdeas(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := User(t, repo, "author@example.com")
+	Idea(t, repo, author.UID, "quiet")
+	popular := Idea(t, repo, author.UID, "popular")
+	some := Idea(t, repo, author.UID, "some")
+	for k := 0; k < 3; k++ {
+		u := User(t, repo, fmt.Sprintf("voter%d@example.com", k))
+		_, err := repo.CheckVote(ctx, popular.IdeaUID, u.UID)
+		require.NoError(t, err)
+		if k == 0 {
+			_, err = repo.CheckVote(ctx, some.IdeaUID, u.UID)
+			require.NoError(t, err)
+		}
+	}
+
+	ideas, err := repo.SelectTrendingIdeas(ctx, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, ideas, 2, "ideas without votes aren't trending")
+	assert.Equal(t, popular.IdeaUID, ideas[0].IdeaUID)
+	assert.Equal(t, 3, ideas[0].RecentVotes)
+	assert.Equal(t, "popular", ideas[0].Name)
+	assert.Equal(t, some.IdeaUID, ideas[1].IdeaUID)
+
+	ideas, err = repo.SelectTrendingIdeas(ctx, time.Now().Add(-time.Hour), 1)
+	require.NoError(t, err)
+	assert.Len(t, ideas, 1)
+
+	ideas, err = repo.SelectTrendingIdeas(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, ideas)
+}
+
+func testIdeaScores(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := User(t, repo, "author@example.com")
+	old := Idea(t, repo, author.UID, "old")
+	pause()
+	voted := Idea(t, repo, author.UID, "voted")
+	pause()
+	unscored := Idea(t, repo, author.UID, "unscored")
+	voter := User(t, repo, "voter@example.com")
+	_, err := repo.CheckVote(ctx, voted.IdeaUID, voter.UID)
+	require.NoError(t, err)
+	require.NoError(t, repo.IncrementLikeCount(ctx, voted.IdeaUID))
+	Comment(t, repo, voted.IdeaUID, "", author.UID, "first")
+	gone := Comment(t, repo, voted.IdeaUID, "", author.UID, "second")
+	require.NoError(t, repo.TombstoneComment(ctx, gone.CommentUID))
+
+	activity, err := repo.SelectIdeaActivity(ctx, time.Now().Add(-time.Hour), voted.IdeaUID)
+	require.NoError(t, err)
+	require.Len(t, activity, 1)
+	assert.Equal(t, voted.IdeaUID, activity[0].IdeaUID)
+	assert.WithinDuration(t, voted.CreationDate, activity[0].CreationDate, time.Millisecond)
+	assert.Equal(t, 1, activity[0].LikeCount)
+	assert.Equal(t, 1, activity[0].Comments, "deleted comments aren't counted")
+	assert.Equal(t, 1, activity[0].RecentVotes)
+	assert.Equal(t, 1, activity[0].RecentComments)
+
+	activity, err = repo.SelectIdeaActivity(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, activity, 3, "every idea without uids")
+	for _, a := range activity {
+		assert.Zero(t, a.RecentVotes)
+		assert.Zero(t, a.RecentComments)
+	}
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, repo.UpsertIdeaScores(ctx, []models.IdeaScore{
+		{IdeaUID: old.IdeaUID, Hot: 1, Top: 5, Controversial: 0.2, Rising: 0, ComputedAt: now},
+		{IdeaUID: voted.IdeaUID, Hot: 2, Top: 1, Controversial: 0.1, Rising: 3, ComputedAt: now},
+	}))
+	// upserted again with other scores
+	require.NoError(t, repo.UpsertIdeaScores(ctx, []models.IdeaScore{
+		{IdeaUID: voted.IdeaUID, Hot: 2, Top: 1, Controversial: 0.3, Rising: 3, ComputedAt: now},
+	}))
+	assert.ErrorIs(t, repo.UpsertIdeaScores(ctx, []models.IdeaScore{{IdeaUID: uuid.NewString(), ComputedAt: now}}),
+		repository.ErrInvalidReference)
+
+	order := func(sort models.IdeaSort) []string {
+		ideas, err := repo.SelectRankedIdeas(ctx, sort)
+		require.NoError(t, err)
+		var names []string
+		for _, idea := range ideas {
+			names = append(names, idea.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"voted", "old", "unscored"}, order(models.SortHot))
+	assert.Equal(t, []string{"old", "voted", "unscored"}, order(models.SortTop))
+	assert.Equal(t, []string{"voted", "old", "unscored"}, order(models.SortControversial))
+	assert.Equal(t, []string{"voted", "old", "unscored"}, order(models.SortRising))
+	// equal scores go newer first
+	require.NoError(t, repo.UpsertIdeaScores(ctx, []models.IdeaScore{{IdeaUID: unscored.IdeaUID, ComputedAt: now}}))
+	assert.Equal(t, []string{"voted", "unscored", "old"}, order(models.SortRising))
+
+	_, err = repo.SelectRankedIdeas(ctx, "random")
+	assert.Error(t, err)
+}
+
+func testBrowseHistory(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	author := User(t, repo, "author@example.com")
+	reader := User(t, repo, "reader@example.com")
+	first := Idea(t, repo, author.UID, "first")
+	second := Idea(t, repo, author.UID, "second")
+	unvisited := Idea(t, repo, author.UID, "unvisited")
+
+	start := time.Now().Truncate(time.Millisecond)
+	visit := func(idea models.Idea, at time.Time) bool {
+		t.Helper()
+		counted, err := repo.UpsertBrowseHistory(ctx,
+			models.BrowseHistory{VisitorID: reader.UID, IdeaID: idea.IdeaUID, ViewedAt: at}, at.Add(-time.Hour))
+		require.NoError(t, err)
+		return counted
+	}
+	assert.True(t, visit(first, start.Add(-time.Minute)), "the first visit counts")
+	assert.False(t, visit(first, start), "counted within the hour")
+	assert.True(t, visit(second, start.Add(-2*time.Minute)))
+	assert.True(t, visit(first, start.Add(time.Hour)), "the hour is over")
+
+	_, err := repo.UpsertBrowseHistory(ctx,
+		models.BrowseHistory{VisitorID: reader.UID, IdeaID: uuid.NewString(), ViewedAt: start}, start)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+	_, err = repo.UpsertBrowseHistory(ctx,
+		models.BrowseHistory{VisitorID: uuid.NewString(), IdeaID: first.IdeaUID, ViewedAt: start}, start)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	require.NoError(t, repo.IncrementViewCount(ctx, first.IdeaUID))
+	require.NoError(t, repo.IncrementViewCount(ctx, first.IdeaUID))
+	assert.ErrorIs(t, repo.IncrementViewCount(ctx, uuid.NewString()), repository.ErrNotFound)
+	stored, err := repo.SelectIdeaByUID(ctx, first.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.ViewCount)
+
+	viewed, err := repo.SelectViewedIdeas(ctx, reader.UID, 10)
+	require.NoError(t, err)
+	require.Len(t, viewed, 2)
+	assert.Equal(t, "first", viewed[0].Name, "last visited first")
+	assert.Equal(t, 2, viewed[0].ViewCount)
+	assert.Equal(t, "second", viewed[1].Name)
+	viewed, err = repo.SelectViewedIdeas(ctx, reader.UID, 1)
+	require.NoError(t, err)
+	assert.Len(t, viewed, 1)
+	viewed, err = repo.SelectViewedIdeas(ctx, author.UID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, viewed)
+
+	// comments since the visit, but not the reader's own or deleted ones
+	Comment(t, repo, second.IdeaUID, "", author.UID, "new")
+	gone := Comment(t, repo, second.IdeaUID, "", author.UID, "gone")
+	require.NoError(t, repo.TombstoneComment(ctx, gone.CommentUID))
+	Comment(t, repo, second.IdeaUID, "", reader.UID, "mine")
+	Comment(t, repo, first.IdeaUID, "", author.UID, "before the last visit")
+
+	visits, err := repo.SelectIdeaVisits(ctx, reader.UID, []string{first.IdeaUID, second.IdeaUID, unvisited.IdeaUID})
+	require.NoError(t, err)
+	require.Len(t, visits, 2, "ideas never visited are left out")
+	slices.SortFunc(visits, func(a, b models.IdeaVisit) int { return a.ViewedAt.Compare(b.ViewedAt) })
+	assert.Equal(t, second.IdeaUID, visits[0].IdeaUID)
+	assert.True(t, start.Add(-2*time.Minute).Equal(visits[0].ViewedAt))
+	assert.Equal(t, 1, visits[0].UnreadComments)
+	assert.Equal(t, first.IdeaUID, visits[1].IdeaUID)
+	assert.Zero(t, visits[1].UnreadComments)
+
+	visits, err = repo.SelectIdeaVisits(ctx, reader.UID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, visits)
+}
+
+func testAnalytics(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	from := time.Now().Add(-time.Hour)
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	manager := models.User{UID: uuid.NewString(), Name: "Carol", Surname: "Sidorova", PositionID: Positions[1].ID,
+		Email: "carol@example.com", Password: "hash", Phone: "9990001133"}
+	require.NoError(t, repo.InsertUser(ctx, manager))
+	idle := models.User{UID: uuid.NewString(), Name: "Dave", Surname: "Orlov", PositionID: Positions[1].ID,
+		Email: "dave@example.com", Password: "hash", Phone: "9990001144"}
+	require.NoError(t, repo.InsertUser(ctx, idle))
+
+	approved := Idea(t, repo, alice.UID, "approved")
+	Idea(t, repo, alice.UID, "pending")
+	process := models.Idea{IdeaUID: uuid.NewString(), Name: "process", Text: "text", Author: bob.UID,
+		StatusID: Statuses[0].ID, CategoryID: Categories[1].ID}
+	require.NoError(t, repo.InsertIdea(ctx, process))
+	require.NoError(t, repo.UpdateIdeaStatus(ctx, approved.IdeaUID, Statuses[1].ID))
+
+	decided := approved.CreationDate.Add(48 * time.Hour).Truncate(time.Millisecond)
+	for _, change := range []models.IdeaStatusChange{
+		{IdeaUID: approved.IdeaUID, StatusID: Statuses[1].ID, ChangedBy: &alice.UID, ChangedAt: decided.Add(time.Hour)},
+		{IdeaUID: approved.IdeaUID, StatusID: Statuses[1].ID, ChangedAt: decided},
+		{IdeaUID: process.IdeaUID, StatusID: Statuses[0].ID, ChangedAt: decided},
+	} {
+		require.NoError(t, repo.InsertIdeaStatusChange(ctx, change))
+	}
+	assert.ErrorIs(t, repo.InsertIdeaStatusChange(ctx, models.IdeaStatusChange{IdeaUID: uuid.NewString(),
+		StatusID: Statuses[1].ID, ChangedAt: decided}), repository.ErrInvalidReference)
+	assert.ErrorIs(t, repo.InsertIdeaStatusChange(ctx, models.IdeaStatusChange{IdeaUID: approved.IdeaUID,
+		StatusID: 999, ChangedAt: decided}), repository.ErrInvalidReference)
+
+	history, err := repo.SelectIdeaStatusChanges(ctx, approved.IdeaUID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.True(t, history[0].ChangedAt.Equal(decided), "earliest first")
+	assert.Nil(t, history[0].ChangedBy)
+	require.NotNil(t, history[1].ChangedBy)
+	assert.Equal(t, alice.UID, *history[1].ChangedBy)
+	assert.Equal(t, Statuses[1].ID, history[1].StatusID)
+
+	Comment(t, repo, approved.IdeaUID, "", manager.UID, "first")
+	Comment(t, repo, process.IdeaUID, "", manager.UID, "second")
+	Comment(t, repo, approved.IdeaUID, "", bob.UID, "third")
+	gone := Comment(t, repo, approved.IdeaUID, "", bob.UID, "gone")
+	require.NoError(t, repo.TombstoneComment(ctx, gone.CommentUID))
+	for _, voter := range []string{bob.UID, manager.UID} {
+		_, err := repo.CheckVote(ctx, approved.IdeaUID, voter)
+		require.NoError(t, err)
+	}
+	to := time.Now().Add(time.Hour)
+
+	for _, group := range []models.AnalyticsGroup{models.GroupDay, models.GroupWeek, models.GroupMonth} {
+		counts, err := repo.CountIdeasByPeriod(ctx, from, to, group)
+		require.NoError(t, err)
+		require.NotEmpty(t, counts, group)
+		total := 0
+		for _, c := range counts {
+			total += c.Ideas
+			assert.True(t, group.Start(c.Period).Equal(c.Period), "%s starts at %s", group, c.Period)
+		}
+		assert.Equal(t, 3, total, group)
+		assert.True(t, group.Start(approved.CreationDate).Equal(counts[0].Period), group)
+	}
+
+	outcomes, err := repo.SelectCategoryOutcomes(ctx, from, to, []int{Statuses[1].ID}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []models.CategoryOutcomes{
+		{CategoryID: Categories[0].ID, Ideas: 2, Approved: 1},
+		{CategoryID: Categories[1].ID, Ideas: 1},
+	}, outcomes)
+
+	decisions, err := repo.SelectIdeaDecisions(ctx, from, to, []int{Statuses[1].ID})
+	require.NoError(t, err)
+	require.Len(t, decisions, 1, "the first of the moves counts")
+	assert.Equal(t, approved.IdeaUID, decisions[0].IdeaUID)
+	assert.Equal(t, Categories[0].ID, decisions[0].CategoryID)
+	assert.True(t, approved.CreationDate.Equal(decisions[0].CreationDate))
+	assert.True(t, decided.Equal(decisions[0].DecidedAt), decisions[0].DecidedAt)
+	decisions, err = repo.SelectIdeaDecisions(ctx, from, to, nil)
+	require.NoError(t, err)
+	assert.Empty(t, decisions)
+
+	authors, err := repo.SelectTopAuthors(ctx, from, to, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserCount{{UserUID: alice.UID, Count: 2}, {UserUID: bob.UID, Count: 1}}, authors)
+	authors, err = repo.SelectTopAuthors(ctx, from, to, 1)
+	require.NoError(t, err)
+	assert.Len(t, authors, 1)
+	commenters, err := repo.SelectTopCommenters(ctx, from, to, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserCount{{UserUID: manager.UID, Count: 2}, {UserUID: bob.UID, Count: 1}}, commenters,
+		"deleted comments don't count")
+
+	engagement, err := repo.SelectPositionEngagement(ctx, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, []models.PositionEngagement{
+		{PositionID: Positions[0].ID, Members: 2, Authors: 2, Commenters: 1, Voters: 1, Ideas: 3, Comments: 1, Votes: 1},
+		{PositionID: Positions[1].ID, Members: 2, Commenters: 1, Voters: 1, Comments: 2, Votes: 1},
+	}, engagement)
+
+	// nothing happened an hour ago
+	outcomes, err = repo.SelectCategoryOutcomes(ctx, from.Add(-time.Hour), from, []int{Statuses[1].ID}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, outcomes)
+	counts, err := repo.CountIdeasByPeriod(ctx, from.Add(-time.Hour), from, models.GroupDay)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+	engagement, err = repo.SelectPositionEngagement(ctx, from.Add(-time.Hour), from)
+	require.NoError(t, err)
+	require.Len(t, engagement, 2)
+	assert.Equal(t, models.PositionEngagement{PositionID: Positions[1].ID, Members: 2}, engagement[1])
+}
+
+func testBotAccounts(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	alice := User(t, repo, "alice@example.com")
+	bob := User(t, repo, "bob@example.com")
+	expires := time.Now().UTC().Add(10 * time.Minute).Truncate(time.Millisecond)
+
+	require.NoError(t, repo.InsertBotLinkCode(ctx, models.BotLinkCode{Code: "AAAA1111", UserUID: alice.UID, ExpiresAt: expires}))
+	require.NoError(t, repo.InsertBotLinkCode(ctx, models.BotLinkCode{Code: "BBBB2222", UserUID: alice.UID, ExpiresAt: expires}))
+	assert.ErrorIs(t, repo.InsertBotLinkCode(ctx, models.BotLinkCode{Code: "BBBB2222", UserUID: bob.UID, ExpiresAt: expires}),
+		repository.ErrConflict)
+	assert.ErrorIs(t, repo.InsertBotLinkCode(ctx, models.BotLinkCode{Code: "CCCC3333", UserUID: uuid.NewString(), ExpiresAt: expires}),
+		repository.ErrInvalidReference)
+
+	_, err := repo.ConsumeBotLinkCode(ctx, "AAAA1111")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "replaced by the newer code")
+	code, err := repo.ConsumeBotLinkCode(ctx, "BBBB2222")
+	require.NoError(t, err)
+	assert.Equal(t, alice.UID, code.UserUID)
+	assert.True(t, expires.Equal(code.ExpiresAt))
+	_, err = repo.ConsumeBotLinkCode(ctx, "BBBB2222")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "codes are one-time")
+
+	require.NoError(t, repo.InsertBotLinkCode(ctx, models.BotLinkCode{Code: "DDDD4444", UserUID: alice.UID, ExpiresAt: expires}))
+	require.NoError(t, repo.InsertBotLinkCode(ctx, models.BotLinkCode{Code: "EEEE5555", UserUID: bob.UID, ExpiresAt: expires.Add(time.Hour)}))
+	purged, err := repo.DeleteExpiredBotLinkCodes(ctx, expires)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = repo.ConsumeBotLinkCode(ctx, "DDDD4444")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.ConsumeBotLinkCode(ctx, "EEEE5555")
+	assert.NoError(t, err)
+
+	link := func(a models.BotAccount) {
+		t.Helper()
+		require.NoError(t, repo.WithTx(ctx, func(repo repository.Repository) error { return repo.LinkBotAccount(ctx, a) }))
+	}
+	link(models.BotAccount{Platform: "telegram", ExternalID: "100", ChatID: "100", UserUID: alice.UID, Username: "alice"})
+	link(models.BotAccount{Platform: "slack", ExternalID: "U1", ChatID: "D1", UserUID: alice.UID})
+	// the same messenger account linked to bob moves to him
+	link(models.BotAccount{Platform: "telegram", ExternalID: "100", ChatID: "100", UserUID: bob.UID})
+	// alice links another telegram account
+	link(models.BotAccount{Platform: "telegram", ExternalID: "200", ChatID: "200", UserUID: alice.UID})
+
+	account, err := repo.SelectBotAccount(ctx, "telegram", "100")
+	require.NoError(t, err)
+	assert.Equal(t, bob.UID, account.UserUID)
+	assert.False(t, account.LinkedAt.IsZero())
+	accounts, err := repo.SelectBotAccounts(ctx, alice.UID)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.ElementsMatch(t, []string{"U1", "200"}, []string{accounts[0].ExternalID, accounts[1].ExternalID})
+
+	// bob relinks his account from another chat, his old one is replaced
+	link(models.BotAccount{Platform: "telegram", ExternalID: "300", ChatID: "300", UserUID: bob.UID})
+	_, err = repo.SelectBotAccount(ctx, "telegram", "100")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	require.NoError(t, repo.DeleteBotAccount(ctx, "telegram", "200"))
+	assert.ErrorIs(t, repo.DeleteBotAccount(ctx, "telegram", "200"), repository.ErrNotFound)
+	accounts, err = repo.SelectBotAccounts(ctx, alice.UID)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "slack", accounts[0].Platform)
+}
+
+func testCampaigns(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	admin := User(t, repo, "admin@example.com")
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	first, err := repo.InsertCampaign(ctx, models.Campaign{
+		Title: "Reduce office energy costs", Description: "by Q3",
+		StartsAt: now, EndsAt: now.Add(30 * 24 * time.Hour),
+		CategoryIDs: models.IDs{Categories[0].ID}, PositionIDs: models.IDs{Positions[0].ID, Positions[1].ID},
+		CreatedBy: &admin.UID,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, first.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+	second, err := repo.InsertCampaign(ctx, models.Campaign{
+		Title: "Faster onboarding", StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(48 * time.Hour),
+	})
+	require.NoError(t, err)
+	stranger := uuid.NewString()
+	_, err = repo.InsertCampaign(ctx, models.Campaign{Title: "x", StartsAt: now, EndsAt: now.Add(time.Hour), CreatedBy: &stranger})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	stored, err := repo.SelectCampaign(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Reduce office energy costs", stored.Title)
+	assert.Equal(t, "by Q3", stored.Description)
+	assert.True(t, now.Equal(stored.StartsAt))
+	assert.True(t, now.Add(30*24*time.Hour).Equal(stored.EndsAt))
+	assert.Equal(t, models.IDs{1}, stored.CategoryIDs)
+	assert.Equal(t, models.IDs{1, 2}, stored.PositionIDs)
+	require.NotNil(t, stored.CreatedBy)
+	assert.Equal(t, admin.UID, *stored.CreatedBy)
+
+	stored.Title = "Reduce energy costs"
+	stored.EndsAt = now.Add(60 * 24 * time.Hour)
+	stored.CategoryIDs = nil
+	require.NoError(t, repo.UpdateCampaign(ctx, stored))
+	assert.ErrorIs(t, repo.UpdateCampaign(ctx, models.Campaign{ID: second.ID + 100}), repository.ErrNotFound)
+
+	campaigns, err := repo.SelectCampaigns(ctx)
+	require.NoError(t, err)
+	require.Len(t, campaigns, 2)
+	assert.Equal(t, second.ID, campaigns[0].ID, "latest to start first")
+	assert.Empty(t, campaigns[0].CategoryIDs)
+	assert.Nil(t, campaigns[0].CreatedBy)
+	assert.Equal(t, "Reduce energy costs", campaigns[1].Title)
+	assert.True(t, now.Add(60*24*time.Hour).Equal(campaigns[1].EndsAt))
+	assert.Empty(t, campaigns[1].CategoryIDs)
+
+	require.NoError(t, repo.DeleteCampaign(ctx, second.ID))
+	assert.ErrorIs(t, repo.DeleteCampaign(ctx, second.ID), repository.ErrNotFound)
+	_, err = repo.SelectCampaign(ctx, second.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testCampaignIdeas(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	campaign, err := repo.InsertCampaign(ctx, models.Campaign{Title: "Energy", StartsAt: now, EndsAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	users := []models.User{
+		User(t, repo, "a@example.com"), User(t, repo, "b@example.com"), User(t, repo, "c@example.com"),
+	}
+
+	submit := func(author, name string, campaignID int64) models.Idea {
+		t.Helper()
+		idea := models.Idea{
+			IdeaUID: uuid.NewString(), Name: name, Text: "text of " + name, Author: author,
+			StatusID: Statuses[0].ID, CategoryID: Categories[0].ID, CampaignID: &campaignID,
+		}
+		require.NoError(t, repo.InsertIdea(ctx, idea))
+		stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.CampaignID)
+		assert.Equal(t, campaignID, *stored.CampaignID)
+		return stored
+	}
+	vote := func(idea models.Idea, u models.User, like bool) {
+		t.Helper()
+		ok, err := repo.CheckVote(ctx, idea.IdeaUID, u.UID)
+		require.NoError(t, err)
+		require.True(t, ok)
+		if like {
+			require.NoError(t, repo.IncrementLikeCount(ctx, idea.IdeaUID))
+		} else {
+			require.NoError(t, repo.IncrementDislikeCount(ctx, idea.IdeaUID))
+		}
+	}
+
+	solar := submit(users[0].UID, "solar panels", campaign.ID)
+	lights := submit(users[0].UID, "motion lights", campaign.ID)
+	heating := submit(users[1].UID, "smart heating", campaign.ID)
+	unrelated := Idea(t, repo, users[2].UID, "unrelated")
+	assert.Nil(t, unrelated.CampaignID)
+	missing := campaign.ID + 100
+	err = repo.InsertIdea(ctx, models.Idea{
+		IdeaUID: uuid.NewString(), Name: "lost", Text: "lost", Author: users[0].UID,
+		StatusID: Statuses[0].ID, CategoryID: Categories[0].ID, CampaignID: &missing,
+	})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	// heating: +2, lights: +1 -1, solar: +1; unrelated votes don't count
+	vote(heating, users[1], true)
+	vote(heating, users[2], true)
+	vote(lights, users[1], true)
+	vote(lights, users[2], false)
+	vote(solar, users[1], true)
+	vote(unrelated, users[0], true)
+	Comment(t, repo, solar.IdeaUID, "", users[2].UID, "yes")
+	deleted := Comment(t, repo, solar.IdeaUID, "", users[2].UID, "no")
+	require.NoError(t, repo.TombstoneComment(ctx, deleted.CommentUID))
+	Comment(t, repo, unrelated.IdeaUID, "", users[2].UID, "unrelated")
+
+	leaderboard := func(limit int) []string {
+		t.Helper()
+		ideas, err := repo.SelectCampaignLeaderboard(ctx, campaign.ID, limit)
+		require.NoError(t, err)
+		var uids []string
+		for _, idea := range ideas {
+			uids = append(uids, idea.IdeaUID)
+		}
+		return uids
+	}
+	assert.Equal(t, []string{heating.IdeaUID, solar.IdeaUID, lights.IdeaUID}, leaderboard(10),
+		"net likes first, then more likes")
+	assert.Equal(t, []string{heating.IdeaUID}, leaderboard(1))
+
+	stats, err := repo.SelectCampaignStats(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStats{Ideas: 3, Authors: 2, Voters: 2, Likes: 4, Dislikes: 1, Comments: 1}, stats)
+
+	empty, err := repo.SelectCampaignStats(ctx, missing)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStats{}, empty)
+
+	// ideas outlive the campaign
+	require.NoError(t, repo.DeleteCampaign(ctx, campaign.ID))
+	stored, err := repo.SelectIdeaByUID(ctx, solar.IdeaUID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.CampaignID)
+}
+
+func testJobRuns(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	admin := User(t, repo, "admin@example.com")
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	slot := now.Truncate(time.Hour)
+
+	scheduled, err := repo.InsertJobRun(ctx, models.JobRun{
+		Job: "digests", Source: models.JobScheduled, ScheduledAt: &slot, Node: "a", Status: models.JobRunning, StartedAt: now,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, scheduled.ID)
+	_, err = repo.InsertJobRun(ctx, models.JobRun{
+		Job: "digests", Source: models.JobScheduled, ScheduledAt: &slot, Node: "b", Status: models.JobRunning, StartedAt: now,
+	})
+	assert.ErrorIs(t, err, repository.ErrConflict, "another replica has run this moment")
+
+	manual := func(job string, startedAt time.Time) models.JobRun {
+		t.Helper()
+		run, err := repo.InsertJobRun(ctx, models.JobRun{
+			Job: job, Source: models.JobManual, TriggeredBy: &admin.UID, Node: "a", Status: models.JobRunning, StartedAt: startedAt,
+		})
+		require.NoError(t, err)
+		return run
+	}
+	first := manual("digests", now)
+	second := manual("digests", now)
+	other := manual("purge", now.Add(-48*time.Hour))
+	stranger := uuid.NewString()
+	_, err = repo.InsertJobRun(ctx, models.JobRun{Job: "digests", Source: models.JobManual, TriggeredBy: &stranger, StartedAt: now})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	finished := now.Add(time.Second)
+	require.NoError(t, repo.FinishJobRun(ctx, scheduled.ID, models.JobSucceeded, "", finished))
+	require.NoError(t, repo.FinishJobRun(ctx, first.ID, models.JobFailed, "smtp is down", finished))
+	require.NoError(t, repo.FinishJobRun(ctx, other.ID, models.JobSucceeded, "", finished))
+	assert.ErrorIs(t, repo.FinishJobRun(ctx, other.ID+100, models.JobSucceeded, "", finished), repository.ErrNotFound)
+
+	runs, err := repo.SelectJobRuns(ctx, "digests", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, []int64{second.ID, first.ID, scheduled.ID}, []int64{runs[0].ID, runs[1].ID, runs[2].ID})
+	assert.Equal(t, models.JobRunning, runs[0].Status)
+	assert.Nil(t, runs[0].FinishedAt)
+	assert.Nil(t, runs[0].ScheduledAt)
+	require.NotNil(t, runs[0].TriggeredBy)
+	assert.Equal(t, admin.UID, *runs[0].TriggeredBy)
+	assert.Equal(t, models.JobManual, runs[0].Source)
+	assert.Equal(t, models.JobFailed, runs[1].Status)
+	assert.Equal(t, "smtp is down", runs[1].Error)
+	require.NotNil(t, runs[1].FinishedAt)
+	assert.True(t, finished.Equal(*runs[1].FinishedAt))
+	require.NotNil(t, runs[2].ScheduledAt)
+	assert.True(t, slot.Equal(*runs[2].ScheduledAt))
+	assert.True(t, now.Equal(runs[2].StartedAt))
+	assert.Equal(t, "a", runs[2].Node)
+
+	all, err := repo.SelectJobRuns(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, other.ID, all[0].ID)
+
+	// running ones are kept however old
+	purged, err := repo.DeleteJobRuns(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	runs, err = repo.SelectJobRuns(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, second.ID, runs[0].ID)
+}
+
+func testVotes(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "voter@example.com")
+	idea := Idea(t, repo, u.UID, "voted")
+
+	ok, err := repo.CheckVote(ctx, idea.IdeaUID, u.UID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.CheckVote(ctx, idea.IdeaUID, u.UID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = repo.CheckVote(ctx, uuid.NewString(), u.UID)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+}
+
+func testCounters(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "counter@example.com")
+	idea := Idea(t, repo, u.UID, "counted")
+
+	require.NoError(t, repo.IncrementLikeCount(ctx, idea.IdeaUID))
+	require.NoError(t, repo.IncrementLikeCount(ctx, idea.IdeaUID))
+	require.NoError(t, repo.IncrementDislikeCount(ctx, idea.IdeaUID))
+
+	got, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.LikeCount)
+	assert.Equal(t, 1, got.DislikeCount)
+
+	assert.ErrorIs(t, repo.IncrementLikeCount(ctx, uuid.NewString()), repository.ErrNotFound)
+	assert.ErrorIs(t, repo.IncrementDislikeCount(ctx, uuid.NewString()), repository.ErrNotFound)
+}
+
+func testTxCommit(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "tx@example.com")
+	idea := Idea(t, repo, u.UID, "tx")
+
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		if _, err := tx.CheckVote(ctx, idea.IdeaUID, u.UID); err != nil {
+			return err
+		}
+		// nested WithTx joins the outer transaction
+		return tx.WithTx(ctx, func(tx repository.Repository) error {
+			return tx.IncrementLikeCount(ctx, idea.IdeaUID)
+		})
+	})
+	require.NoError(t, err)
+
+	got, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.LikeCount)
+}
+
+func testTxRollback(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	u := User(t, repo, "rollback@example.com")
+	idea := Idea(t, repo, u.UID, "rollback")
+	errAbort := errors.New("abort")
+
+	err := repo.WithTx(ctx, func(tx repository.Repository) error {
+		if _, err := tx.CheckVote(ctx, idea.IdeaUID, u.UID); err != nil {
+			return err
+		}
+		if err := tx.IncrementLikeCount(ctx, idea.IdeaUID); err != nil {
+			return err
+		}
+		User(t, tx, "ghost@example.com")
+		return errAbort
+	})
+	require.ErrorIs(t, err, errAbort)
+
+	got, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Zero(t, got.LikeCount)
+
+	_, err = repo.SelectUserByEmail(ctx, "ghost@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	// the vote was rolled back as well
+	ok, err := repo.CheckVote(ctx, idea.IdeaUID, u.UID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func testWebhooks(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	admin := User(t, repo, "admin@example.com")
+
+	first, err := repo.InsertWebhook(ctx, models.Webhook{
+		URL: "https://pm.example.com/hook", Secret: "0123456789abcdef",
+		Events: models.EventTypes{models.EventIdeaCreated, models.EventIdeaUpdated}, Active: true, CreatedBy: &admin.UID,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, first.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+	second, err := repo.InsertWebhook(ctx, models.Webhook{
+		URL: "https://chat.example.com/hook", Secret: "fedcba9876543210", Events: models.EventTypes{models.EventVotes},
+	})
+	require.NoError(t, err)
+	stranger := uuid.NewString()
+	_, err = repo.InsertWebhook(ctx, models.Webhook{URL: "https://x.example.com", Events: models.EventTypes{models.EventVotes}, CreatedBy: &stranger})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	stored, err := repo.SelectWebhook(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EventTypes{models.EventIdeaCreated, models.EventIdeaUpdated}, stored.Events)
+	assert.True(t, stored.Active)
+	require.NotNil(t, stored.CreatedBy)
+	assert.Equal(t, admin.UID, *stored.CreatedBy)
+	assert.Equal(t, "0123456789abcdef", stored.Secret)
+
+	stored.URL = "https://pm.example.com/v2/hook"
+	stored.Events = models.EventTypes{models.EventCommentAdded}
+	stored.Active = false
+	require.NoError(t, repo.UpdateWebhook(ctx, stored))
+	assert.ErrorIs(t, repo.UpdateWebhook(ctx, models.Webhook{ID: second.ID + 100}), repository.ErrNotFound)
+
+	webhooks, err := repo.SelectWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	assert.Equal(t, first.ID, webhooks[0].ID)
+	assert.Equal(t, "https://pm.example.com/v2/hook", webhooks[0].URL)
+	assert.Equal(t, models.EventTypes{models.EventCommentAdded}, webhooks[0].Events)
+	assert.False(t, webhooks[0].Active)
+	assert.Nil(t, webhooks[1].CreatedBy)
+
+	require.NoError(t, repo.DeleteWebhook(ctx, second.ID))
+	assert.ErrorIs(t, repo.DeleteWebhook(ctx, second.ID), repository.ErrNotFound)
+	_, err = repo.SelectWebhook(ctx, second.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testWebhookDeliveries(t *testing.T, repo repository.Repository) {
+	ctx := context.Background()
+	hook, err := repo.InsertWebhook(ctx, models.Webhook{
+		URL: "https://pm.example.com/hook", Secret: "0123456789abcdef", Events: models.EventTypes{models.EventIdeaCreated}, Active: true,
+	})
+	require.NoError(t, err)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	queue := func(payload string, due time.Time) models.WebhookDelivery {
+		t.Helper()
+		d, err := repo.InsertWebhookDelivery(ctx, models.WebhookDelivery{
+			WebhookID: hook.ID, EventID: uuid.NewString(), Event: models.EventIdeaCreated,
+			Payload: payload, State: models.DeliveryPending, NextAttemptAt: due,
+		})
+		require.NoError(t, err)
+		return d
+	}
+	first := queue(`{"n":1}`, now.Add(-time.Minute))
+	queue(`{"n":2}`, now.Add(-time.Hour))
+	queue(`{"n":3}`, now.Add(time.Hour))
+	assert.NotZero(t, first.ID)
+	_, err = repo.InsertWebhookDelivery(ctx, models.WebhookDelivery{WebhookID: hook.ID + 100, EventID: uuid.NewString(),
+		Event: models.EventPing, Payload: "{}", State: models.DeliveryPending, NextAttemptAt: now})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	lease := now.Add(time.Minute)
+	claimed, err := repo.ClaimWebhookDeliveries(ctx, now, lease, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, `{"n":1}`, claimed[0].Payload, "ordered by id")
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.True(t, claimed[0].NextAttemptAt.Equal(lease))
+	again, err := repo.ClaimWebhookDeliveries(ctx, now, lease, 10)
+	require.NoError(t, err)
+	assert.Empty(t, again, "leased deliveries are skipped")
+
+	ok, failed := 200, 503
+	require.NoError(t, repo.RecordWebhookAttempt(ctx, models.WebhookAttempt{
+		DeliveryID: claimed[0].ID, ResponseCode: &ok, DurationMS: 12,
+	}, models.DeliveryDelivered, time.Time{}))
+	require.NoError(t, repo.RecordWebhookAttempt(ctx, models.WebhookAttempt{
+		DeliveryID: claimed[1].ID, ResponseCode: &failed, Error: "unexpected status 503", DurationMS: 40,
+	}, models.DeliveryPending, now.Add(30*time.Second)))
+	assert.ErrorIs(t, repo.RecordWebhookAttempt(ctx, models.WebhookAttempt{DeliveryID: claimed[1].ID + 100},
+		models.DeliveryDead, time.Time{}), repository.ErrNotFound)
+
+	claimed, err = repo.ClaimWebhookDeliveries(ctx, now.Add(time.Minute), lease.Add(time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, `{"n":2}`, claimed[0].Payload)
+	assert.Equal(t, 2, claimed[0].Attempts)
+	require.NotNil(t, claimed[0].ResponseCode)
+	assert.Equal(t, 503, *claimed[0].ResponseCode)
+	require.NoError(t, repo.RecordWebhookAttempt(ctx, models.WebhookAttempt{
+		DeliveryID: claimed[0].ID, Error: "connection refused", DurationMS: 1,
+	}, models.DeliveryDead, time.Time{}))
+	dead := claimed[0].ID
+
+	stored, err := repo.SelectWebhookDelivery(ctx, dead)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDead, stored.State)
+	assert.Nil(t, stored.ResponseCode)
+	assert.Equal(t, "connection refused", stored.LastError)
+	delivered, err := repo.SelectWebhookDelivery(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDelivered, delivered.State)
+	assert.NotNil(t, delivered.DeliveredAt)
+	_, err = repo.SelectWebhookDelivery(ctx, dead+100)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	log, err := repo.SelectWebhookAttempts(ctx, dead)
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	require.NotNil(t, log[0].ResponseCode)
+	assert.Equal(t, 503, *log[0].ResponseCode)
+	assert.Equal(t, 40, log[0].DurationMS)
+	assert.Nil(t, log[1].ResponseCode)
+	assert.False(t, log[1].AttemptedAt.IsZero())
+
+	deadOnes, err := repo.SelectWebhookDeliveries(ctx, hook.ID, models.DeliveryDead, 10)
+	require.NoError(t, err)
+	require.Len(t, deadOnes, 1)
+	assert.Equal(t, dead, deadOnes[0].ID)
+	all, err := repo.SelectWebhookDeliveries(ctx, hook.ID, "", 2)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, `{"n":3}`, all[0].Payload, "newest first")
+
+	require.NoError(t, repo.RequeueWebhookDelivery(ctx, dead, now))
+	assert.ErrorIs(t, repo.RequeueWebhookDelivery(ctx, dead+100, now), repository.ErrNotFound)
+	claimed, err = repo.ClaimWebhookDeliveries(ctx, now, lease, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, dead, claimed[0].ID)
+	assert.Equal(t, 1, claimed[0].Attempts, "attempts start over")
+
+	require.NoError(t, repo.DeleteWebhook(ctx, hook.ID))
+	log, err = repo.SelectWebhookAttempts(ctx, dead)
+	require.NoError(t, err)
+	assert.Empty(t, log, "deleted with the webhook")
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchTerms splits a full-text query into lower-cased words. Every backend
+// matches ideas containing all the terms as whole words in name or text, so
+// they must tokenize the query the same way.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
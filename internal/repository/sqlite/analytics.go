@@ -0,0 +1,245 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// within matches rows whose column falls within [from, to)
+func within(column string, from, to time.Time) sq.And {
+	return sq.And{sq.GtOrEq{column: timeArg(from)}, sq.Lt{column: timeArg(to)}}
+}
+
+// periodStart truncates creation_date to the start of its period, SQLite
+// date functions read the stored UTC text
+func periodStart(group models.AnalyticsGroup) string {
+	switch group {
+	case models.GroupWeek:
+		// the next Sunday unless it is one, then back to Monday
+		return "date(creation_date, 'weekday 0', '-6 days')"
+	case models.GroupMonth:
+		return "strftime('%Y-%m-01', creation_date)"
+	default:
+		return "date(creation_date)"
+	}
+}
+
+func (sl *SQLiteRepository) CountIdeasByPeriod(ctx context.Context, from, to time.Time, group models.AnalyticsGroup) ([]models.PeriodCount, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select(periodStart(group)+" AS period", "count(*) AS ideas").
+		From("ideas").
+		Where(within("creation_date", from, to)).
+		GroupBy("period").
+		OrderBy("period").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var counts []models.PeriodCount
+	for rows.Next() {
+		// an expression has no declared type, the date comes as text
+		var row struct {
+			Period string `db:"period"`
+			Ideas  int    `db:"ideas"`
+		}
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		period, err := time.Parse(time.DateOnly, row.Period)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, models.PeriodCount{Period: period, Ideas: row.Ideas})
+	}
+	return counts, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectCategoryOutcomes(ctx context.Context, from, to time.Time, approved, rejected []int) ([]models.CategoryOutcomes, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	isApproved, approvedArgs, err := sq.Eq{"status_id": approved}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	isRejected, rejectedArgs, err := sq.Eq{"status_id": rejected}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	q, args, err := qb.Select("COALESCE(category_id, 0) AS category_id", "count(*) AS ideas").
+		Column(sq.Expr("count(*) FILTER (WHERE "+isApproved+") AS approved", approvedArgs...)).
+		Column(sq.Expr("count(*) FILTER (WHERE "+isRejected+") AS rejected", rejectedArgs...)).
+		From("ideas").
+		Where(within("creation_date", from, to)).
+		GroupBy("1").
+		OrderBy("1").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var outcomes []models.CategoryOutcomes
+	for rows.Next() {
+		var o models.CategoryOutcomes
+		if err := rows.StructScan(&o); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectIdeaDecisions(ctx context.Context, from, to time.Time, statusIDs []int) ([]models.IdeaDecision, error) {
+	if len(statusIDs) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	decided, decidedArgs, err := sq.Eq{"c.status_id": statusIDs}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	q, args, err := qb.Select("i.idea_uid", "COALESCE(i.category_id, 0) AS category_id", "i.creation_date",
+		"d.changed_at AS decided_at").
+		From("ideas i").
+		Join(`idea_status_changes d ON d.id = (SELECT c.id FROM idea_status_changes c
+			WHERE c.idea_uid = i.idea_uid AND `+decided+` ORDER BY c.changed_at, c.id LIMIT 1)`, decidedArgs...).
+		Where(within("i.creation_date", from, to)).
+		OrderBy("i.idea_uid").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var decisions []models.IdeaDecision
+	for rows.Next() {
+		var d models.IdeaDecision
+		if err := rows.StructScan(&d); err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectTopAuthors(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("author AS user_uid", "count(*) AS count").
+		From("ideas").
+		Where(within("creation_date", from, to)).
+		GroupBy("author").
+		OrderBy("count DESC", "user_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return sl.selectUserCounts(ctx, q, args)
+}
+
+func (sl *SQLiteRepository) SelectTopCommenters(ctx context.Context, from, to time.Time, limit int) ([]models.UserCount, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("author_uid AS user_uid", "count(*) AS count").
+		From("comments").
+		Where(within("timestamp", from, to)).
+		Where("deleted_at IS NULL").
+		GroupBy("author_uid").
+		OrderBy("count DESC", "user_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return sl.selectUserCounts(ctx, q, args)
+}
+
+func (sl *SQLiteRepository) selectUserCounts(ctx context.Context, q string, args []interface{}) ([]models.UserCount, error) {
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var counts []models.UserCount
+	for rows.Next() {
+		var c models.UserCount
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// SelectPositionEngagement joins every user with what they did, each
+// subquery gives a user at most one row
+func (sl *SQLiteRepository) SelectPositionEngagement(ctx context.Context, from, to time.Time) ([]models.PositionEngagement, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select(
+		"COALESCE(u.position_id, 0) AS position_id",
+		"count(*) AS members",
+		"count(i.author) AS authors",
+		"COALESCE(sum(i.n), 0) AS ideas",
+		"count(c.author_uid) AS commenters",
+		"COALESCE(sum(c.n), 0) AS comments",
+		"count(v.user_uid) AS voters",
+		"COALESCE(sum(v.n), 0) AS votes",
+	).
+		From("users u").
+		LeftJoin(`(SELECT author, count(*) AS n FROM ideas
+			WHERE creation_date >= ? AND creation_date < ? GROUP BY author) i ON i.author = u.uid`,
+			timeArg(from), timeArg(to)).
+		LeftJoin(`(SELECT author_uid, count(*) AS n FROM comments
+			WHERE deleted_at IS NULL AND timestamp >= ? AND timestamp < ? GROUP BY author_uid) c ON c.author_uid = u.uid`,
+			timeArg(from), timeArg(to)).
+		LeftJoin(`(SELECT user_uid, count(*) AS n FROM vote_history
+			WHERE voted_at >= ? AND voted_at < ? GROUP BY user_uid) v ON v.user_uid = u.uid`,
+			timeArg(from), timeArg(to)).
+		GroupBy("1").
+		OrderBy("1").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var engagement []models.PositionEngagement
+	for rows.Next() {
+		var e models.PositionEngagement
+		if err := rows.StructScan(&e); err != nil {
+			return nil, err
+		}
+		engagement = append(engagement, e)
+	}
+	return engagement, rows.Err()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// fileLocker does nothing: SQLite has no advisory locks and is meant for a
+// single instance. Each migration still runs in its own transaction, so a
+// concurrent runner fails and rolls back instead of half-applying it.
+type fileLocker struct{}
+
+func (fileLocker) Lock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+func (fileLocker) Unlock(ctx context.Context, conn *sql.Conn) error {
+	return nil
+}
+
+func (sl *SQLiteRepository) migrator() (*migrate.Migrator, error) {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sl.db, sub, fileLocker{}, sl.log)
+}
+
+// MigrateUp applies pending migrations
+func (sl *SQLiteRepository) MigrateUp(ctx context.Context) error {
+	m, err := sl.migrator()
+	if err != nil {
+		return err
+	}
+	return m.Up(ctx)
+}
+
+// MigrateDown rolls back the last steps migrations
+func (sl *SQLiteRepository) MigrateDown(ctx context.Context, steps int) error {
+	m, err := sl.migrator()
+	if err != nil {
+		return err
+	}
+	return m.Down(ctx, steps)
+}
+
+// SchemaVersion returns applied and expected schema versions
+func (sl *SQLiteRepository) SchemaVersion(ctx context.Context) (int, int, error) {
+	m, err := sl.migrator()
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err := m.Version(ctx)
+	return current, m.Latest(), err
+}
+
+// CheckSchema fails with migrate.ErrSchemaMismatch if the database is not
+// exactly at the version this build expects
+func (sl *SQLiteRepository) CheckSchema(ctx context.Context) error {
+	m, err := sl.migrator()
+	if err != nil {
+		return err
+	}
+	return m.Check(ctx)
+}
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS ideas_fts;
+DROP TABLE IF EXISTS browse_history;
+DROP TABLE IF EXISTS vote_history;
+DROP TABLE IF EXISTS replies;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS ideas;
+DROP TABLE IF EXISTS idea_statuses;
+DROP TABLE IF EXISTS idea_categories;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS user_positions;
@@ -0,0 +1,110 @@
+-- same tables as the PostgreSQL schema; uuids are TEXT, timestamps are
+-- ISO 8601 TEXT with milliseconds so that they sort correctly
+
+CREATE TABLE user_positions(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(30) UNIQUE NOT NULL
+);
+
+CREATE TABLE users(
+    uid TEXT PRIMARY KEY,
+    password TEXT NOT NULL,
+    name VARCHAR(20),
+    surname VARCHAR(20),
+    position_id INTEGER,
+    email VARCHAR(35),
+    phone VARCHAR(10), -- без +7/8
+    hire_date TIMESTAMP,
+    last_online TIMESTAMP,
+    pfp_url TEXT,
+    is_admin BOOLEAN NOT NULL DEFAULT 0,
+    re_auth BOOLEAN NOT NULL DEFAULT 0,
+    FOREIGN KEY (position_id) REFERENCES user_positions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX users_email_idx ON users(email);
+
+CREATE TABLE idea_categories(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(30) UNIQUE NOT NULL
+);
+
+CREATE TABLE idea_statuses(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(10) UNIQUE NOT NULL
+);
+
+CREATE TABLE ideas(
+    idea_uid TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    text TEXT NOT NULL,
+    author TEXT NOT NULL,
+    creation_date DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    status_id INTEGER,
+    category_id INTEGER,
+    like_count INTEGER NOT NULL DEFAULT 0,
+    dislike_count INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (author) REFERENCES users(uid),
+    FOREIGN KEY (status_id) REFERENCES idea_statuses(id),
+    FOREIGN KEY (category_id) REFERENCES idea_categories(id)
+);
+
+CREATE INDEX ideas_author_idx ON ideas(author);
+
+CREATE TABLE comments(
+    comment_uid TEXT PRIMARY KEY,
+    idea_uid TEXT NOT NULL,
+    author_uid TEXT NOT NULL,
+    timestamp DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    comment_text TEXT NOT NULL,
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+CREATE INDEX comments_idea_idx ON comments(idea_uid);
+
+CREATE TABLE replies(
+    reply_uid TEXT PRIMARY KEY,
+    comment_uid TEXT NOT NULL,
+    author_uid TEXT NOT NULL,
+    timestamp DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    reply_text TEXT NOT NULL,
+    FOREIGN KEY (comment_uid) REFERENCES comments(comment_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+CREATE INDEX replies_comment_idx ON replies(comment_uid);
+
+CREATE TABLE vote_history(
+    idea_uid TEXT NOT NULL,
+    user_uid TEXT NOT NULL,
+    voted_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (idea_uid, user_uid),
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE TABLE browse_history(
+    visitor_uid TEXT NOT NULL,
+    idea_uid TEXT NOT NULL
+);
+
+-- full-text search over ideas, kept in sync by triggers. It stores its own
+-- copy of the text: ideas has no INTEGER PRIMARY KEY, and implicit rowids may
+-- change on VACUUM. Diacritics are kept so that "ё" and "е" differ, as in PostgreSQL
+CREATE VIRTUAL TABLE ideas_fts USING fts5(
+    idea_uid UNINDEXED, name, text,
+    tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER ideas_fts_insert AFTER INSERT ON ideas BEGIN
+    INSERT INTO ideas_fts(idea_uid, name, text) VALUES (new.idea_uid, new.name, new.text);
+END;
+
+CREATE TRIGGER ideas_fts_delete AFTER DELETE ON ideas BEGIN
+    DELETE FROM ideas_fts WHERE idea_uid = old.idea_uid;
+END;
+
+CREATE TRIGGER ideas_fts_update AFTER UPDATE OF name, text ON ideas BEGIN
+    UPDATE ideas_fts SET name = new.name, text = new.text WHERE idea_uid = old.idea_uid;
+END;
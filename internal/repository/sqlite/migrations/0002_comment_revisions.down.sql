@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS comment_revisions;
+
+ALTER TABLE replies DROP COLUMN deleted_at;
+ALTER TABLE replies DROP COLUMN edited_at;
+ALTER TABLE comments DROP COLUMN deleted_at;
+ALTER TABLE comments DROP COLUMN edited_at;
@@ -0,0 +1,18 @@
+-- editing and deleting comments and replies
+
+ALTER TABLE comments ADD COLUMN edited_at DATETIME;
+ALTER TABLE comments ADD COLUMN deleted_at DATETIME;
+ALTER TABLE replies ADD COLUMN edited_at DATETIME;
+ALTER TABLE replies ADD COLUMN deleted_at DATETIME;
+
+-- previous texts; target_uid is a comment or a reply, so there is no FK
+CREATE TABLE comment_revisions(
+    id INTEGER PRIMARY KEY,
+    target_uid TEXT NOT NULL,
+    text TEXT NOT NULL,
+    editor_uid TEXT NOT NULL,
+    edited_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    FOREIGN KEY (editor_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX comment_revisions_target_idx ON comment_revisions(target_uid);
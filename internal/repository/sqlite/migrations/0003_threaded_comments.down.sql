@@ -0,0 +1,48 @@
+-- only direct answers to top level comments fit the old layout,
+-- deeper comments are lost. SQLite can't drop a column with a foreign key,
+-- so comments is rebuilt; replies are filled after the rebuild, dropping the
+-- old table would cascade into them otherwise
+
+CREATE TABLE comments_old(
+    comment_uid TEXT PRIMARY KEY,
+    idea_uid TEXT NOT NULL,
+    author_uid TEXT NOT NULL,
+    timestamp DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    comment_text TEXT NOT NULL,
+    edited_at DATETIME,
+    deleted_at DATETIME,
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+INSERT INTO comments_old (comment_uid, idea_uid, author_uid, timestamp, comment_text, edited_at, deleted_at)
+SELECT comment_uid, idea_uid, author_uid, timestamp, comment_text, edited_at, deleted_at
+FROM comments
+WHERE parent_uid IS NULL;
+
+CREATE TEMP TABLE replies_old AS
+SELECT comment_uid AS reply_uid, parent_uid AS comment_uid, author_uid, timestamp,
+    comment_text AS reply_text, edited_at, deleted_at
+FROM comments
+WHERE depth = 1;
+
+DROP TABLE comments;
+ALTER TABLE comments_old RENAME TO comments;
+CREATE INDEX comments_idea_idx ON comments(idea_uid);
+
+CREATE TABLE replies(
+    reply_uid TEXT PRIMARY KEY,
+    comment_uid TEXT NOT NULL,
+    author_uid TEXT NOT NULL,
+    timestamp DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    reply_text TEXT NOT NULL,
+    edited_at DATETIME,
+    deleted_at DATETIME,
+    FOREIGN KEY (comment_uid) REFERENCES comments(comment_uid) ON DELETE CASCADE,
+    FOREIGN KEY (author_uid) REFERENCES users(uid)
+);
+
+CREATE INDEX replies_comment_idx ON replies(comment_uid);
+
+INSERT INTO replies SELECT * FROM temp.replies_old;
+DROP TABLE temp.replies_old;
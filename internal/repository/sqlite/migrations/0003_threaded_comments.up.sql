@@ -0,0 +1,21 @@
+-- replies become comments with a parent: one table for threads of any depth.
+-- path is the materialized path of uids from the root ("root/child/uid"),
+-- a subtree is selected by its prefix
+
+ALTER TABLE comments ADD COLUMN parent_uid TEXT REFERENCES comments(comment_uid) ON DELETE CASCADE;
+ALTER TABLE comments ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN path TEXT NOT NULL DEFAULT '';
+
+UPDATE comments SET path = comment_uid;
+
+-- reply uids are kept, so their revisions stay attached
+INSERT INTO comments (comment_uid, idea_uid, author_uid, timestamp, comment_text, edited_at, deleted_at, parent_uid, depth, path)
+SELECT r.reply_uid, c.idea_uid, r.author_uid, r.timestamp, r.reply_text, r.edited_at, r.deleted_at,
+    c.comment_uid, 1, c.path || '/' || r.reply_uid
+FROM replies r
+JOIN comments c ON c.comment_uid = r.comment_uid;
+
+DROP TABLE replies;
+
+CREATE INDEX comments_parent_idx ON comments(parent_uid, timestamp, comment_uid);
+CREATE INDEX comments_roots_idx ON comments(idea_uid, timestamp, comment_uid) WHERE parent_uid IS NULL;
@@ -0,0 +1,10 @@
+-- emoji reactions on ideas and comments; target_uid is an idea or a comment,
+-- so there is no FK, like in comment_revisions
+CREATE TABLE reactions(
+    target_uid TEXT NOT NULL,
+    user_uid TEXT NOT NULL,
+    emoji TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (target_uid, user_uid, emoji),
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- @name.surname and @uid references in idea and comment texts; target_uid is
+-- an idea or a comment, so there is no FK, like in reactions
+CREATE TABLE mentions(
+    target_uid TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    idea_uid TEXT NOT NULL,
+    author_uid TEXT NOT NULL,
+    user_uid TEXT NOT NULL,
+    span_start INTEGER NOT NULL,
+    span_end INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (target_uid, span_start),
+    FOREIGN KEY (author_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX mentions_user_idx ON mentions(user_uid);
@@ -0,0 +1,27 @@
+-- in-app notifications; comment_uid has no FK, comments are never removed
+CREATE TABLE notifications(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_uid TEXT NOT NULL,
+    type TEXT NOT NULL,
+    actor_uid TEXT NOT NULL,
+    idea_uid TEXT NOT NULL,
+    comment_uid TEXT,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    read_at DATETIME,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (actor_uid) REFERENCES users(uid) ON DELETE CASCADE,
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_idx ON notifications(user_uid, id DESC);
+CREATE INDEX notifications_unread_idx ON notifications(user_uid) WHERE read_at IS NULL;
+
+-- only switched off (or back on) types are stored, the rest are enabled
+CREATE TABLE notification_preferences(
+    user_uid TEXT NOT NULL,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_uid, type),
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
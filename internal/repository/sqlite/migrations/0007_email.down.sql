@@ -0,0 +1,3 @@
+DROP INDEX vote_history_voted_at_idx;
+DROP TABLE email_settings;
+DROP TABLE email_outbox;
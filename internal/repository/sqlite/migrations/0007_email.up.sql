@@ -0,0 +1,33 @@
+-- rendered emails waiting for delivery, sent and given up ones stay as a log
+CREATE TABLE email_outbox(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_uid TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    to_addr TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    sent_at DATETIME,
+    failed_at DATETIME,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX email_outbox_due_idx ON email_outbox(next_attempt_at)
+    WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX email_outbox_user_idx ON email_outbox(user_uid, id DESC);
+
+-- users without a row get the defaults of models.DefaultEmailSettings
+CREATE TABLE email_settings(
+    user_uid TEXT PRIMARY KEY,
+    language TEXT NOT NULL,
+    immediate BOOLEAN NOT NULL,
+    digest TEXT NOT NULL,
+    last_digest_at DATETIME,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+CREATE INDEX vote_history_voted_at_idx ON vote_history(voted_at);
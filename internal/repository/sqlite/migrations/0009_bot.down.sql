@@ -0,0 +1,2 @@
+DROP TABLE bot_accounts;
+DROP TABLE bot_link_codes;
@@ -0,0 +1,20 @@
+-- one-time codes linking a messenger account, a user has one at a time
+CREATE TABLE bot_link_codes(
+    code TEXT PRIMARY KEY,
+    user_uid TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
+
+-- messenger accounts linked to users, one per user and platform
+CREATE TABLE bot_accounts(
+    platform TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    chat_id TEXT NOT NULL,
+    user_uid TEXT NOT NULL,
+    username TEXT NOT NULL DEFAULT '',
+    linked_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    PRIMARY KEY (platform, external_id),
+    UNIQUE (user_uid, platform),
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
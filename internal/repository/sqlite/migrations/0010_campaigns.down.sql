@@ -0,0 +1,5 @@
+DROP TRIGGER campaigns_delete;
+DROP TRIGGER ideas_campaign_insert;
+DROP INDEX ideas_campaign_idx;
+ALTER TABLE ideas DROP COLUMN campaign_id;
+DROP TABLE campaigns;
@@ -0,0 +1,31 @@
+-- themed calls for ideas, category_ids and position_ids are comma separated
+-- lists of ids ideas and their authors must be in, empty allows any
+CREATE TABLE campaigns(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NOT NULL,
+    category_ids TEXT NOT NULL DEFAULT '',
+    position_ids TEXT NOT NULL DEFAULT '',
+    created_by TEXT,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    CHECK (starts_at < ends_at),
+    FOREIGN KEY (created_by) REFERENCES users(uid) ON DELETE SET NULL
+);
+
+-- SQLite can't drop a column with a foreign key, rebuilding ideas would take
+-- its search index and every table referencing it along. The reference is
+-- kept by triggers instead: ideas outlive a deleted campaign.
+ALTER TABLE ideas ADD COLUMN campaign_id INTEGER;
+
+CREATE INDEX ideas_campaign_idx ON ideas(campaign_id);
+
+CREATE TRIGGER ideas_campaign_insert BEFORE INSERT ON ideas
+WHEN NEW.campaign_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM campaigns WHERE id = NEW.campaign_id) BEGIN
+    SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed');
+END;
+
+CREATE TRIGGER campaigns_delete AFTER DELETE ON campaigns BEGIN
+    UPDATE ideas SET campaign_id = NULL WHERE campaign_id = OLD.id;
+END;
@@ -0,0 +1,18 @@
+-- history of background jobs, a scheduled moment is run once whichever
+-- process gets to it
+CREATE TABLE job_runs(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job TEXT NOT NULL,
+    source TEXT NOT NULL,
+    scheduled_at DATETIME,
+    triggered_by TEXT,
+    node TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'running',
+    error TEXT NOT NULL DEFAULT '',
+    started_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    finished_at DATETIME,
+    UNIQUE (job, scheduled_at),
+    FOREIGN KEY (triggered_by) REFERENCES users(uid) ON DELETE SET NULL
+);
+
+CREATE INDEX job_runs_job_idx ON job_runs(job, id);
@@ -0,0 +1,11 @@
+-- ranking scores of ideas, recomputed on votes and comments and
+-- periodically as ideas age
+CREATE TABLE idea_scores(
+    idea_uid TEXT PRIMARY KEY,
+    hot REAL NOT NULL DEFAULT 0,
+    top REAL NOT NULL DEFAULT 0,
+    controversial REAL NOT NULL DEFAULT 0,
+    rising REAL NOT NULL DEFAULT 0,
+    computed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS comments_timestamp_idx;
+DROP INDEX IF EXISTS ideas_creation_date_idx;
+DROP TABLE IF EXISTS idea_status_changes;
@@ -0,0 +1,18 @@
+-- moves of ideas between statuses, analytics measure time to decision with
+-- them; ideas moved before this migration have no history
+CREATE TABLE idea_status_changes(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    idea_uid TEXT NOT NULL,
+    status_id INTEGER NOT NULL,
+    changed_by TEXT,
+    changed_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    FOREIGN KEY (idea_uid) REFERENCES ideas(idea_uid) ON DELETE CASCADE,
+    FOREIGN KEY (status_id) REFERENCES idea_statuses(id),
+    FOREIGN KEY (changed_by) REFERENCES users(uid) ON DELETE SET NULL
+);
+
+CREATE INDEX idea_status_changes_idea_idx ON idea_status_changes(idea_uid, changed_at);
+
+-- analytics count ideas and comments within date ranges
+CREATE INDEX ideas_creation_date_idx ON ideas(creation_date);
+CREATE INDEX comments_timestamp_idx ON comments(timestamp) WHERE deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- invitations of imported users to set a password, a user has one at a time;
+-- only a hash of the token is kept, the token itself is in the email
+CREATE TABLE user_invitations(
+    token_hash TEXT PRIMARY KEY,
+    user_uid TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
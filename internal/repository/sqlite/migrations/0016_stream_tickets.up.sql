@@ -0,0 +1,7 @@
+-- one-time passes to the event stream, only a hash of the ticket is kept
+CREATE TABLE stream_tickets(
+    ticket_hash TEXT PRIMARY KEY,
+    user_uid TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (user_uid) REFERENCES users(uid) ON DELETE CASCADE
+);
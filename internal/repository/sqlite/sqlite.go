@@ -0,0 +1,2235 @@
+package sqlite
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/jmoiron/sqlx"
+	"log/slog"
+	"modernc.org/sqlite"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	// sqlx doesn't know the modernc driver name, named queries need ? placeholders
+	sqlx.BindDriver("sqlite", sqlx.QUESTION)
+
+	// lower() of sqlite only folds ascii, unicode_lower matches names in any script
+	err := sqlite.RegisterDeterministicScalarFunction("unicode_lower", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch v := args[0].(type) {
+			case string:
+				return strings.ToLower(v), nil
+			case []byte:
+				return strings.ToLower(string(v)), nil
+			default:
+				return v, nil
+			}
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// SQLiteRepository - implements Repository interface for SQLite, for small
+// single-node deployments
+type SQLiteRepository struct {
+	db  *sqlx.DB
+	tx  *sqlx.Tx // set for repositories handed out by WithTx
+	log slog.Logger
+}
+
+// queryer is satisfied by both *sqlx.DB and *sqlx.Tx
+type queryer interface {
+	sqlx.ExtContext
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// ext returns the transaction when inside WithTx, the pool otherwise
+func (sl *SQLiteRepository) ext() queryer {
+	if sl.tx != nil {
+		return sl.tx
+	}
+	return sl.db
+}
+
+// use ConnectDB before query, and CloseConnectDB when a query is finished.
+// sourceURL is sqlite:///abs/path.db, sqlite://rel/path.db or sqlite::memory:
+func (sl *SQLiteRepository) ConnectDB(ctx context.Context, sourceURL string, log slog.Logger) error {
+	dsn, inMemory, err := ParseURL(sourceURL)
+	if err != nil {
+		return err
+	}
+
+	sl.db, err = sqlx.ConnectContext(ctx, "sqlite", dsn)
+	sl.log = log
+	sl.log.Debug("connecting to database")
+
+	if err != nil {
+		log.Error("failed to connect to database" + err.Error())
+		return err
+	}
+	if inMemory {
+		// every connection would get its own empty database
+		sl.db.SetMaxOpenConns(1)
+	}
+	return nil
+}
+
+func (sl *SQLiteRepository) CloseConnectDB() error {
+	err := sl.db.Close()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sl *SQLiteRepository) InsertUser(ctx context.Context, user models.User) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("users").
+		Columns(
+			"uid", "password", "name", "surname", "position_id", "email",
+			"phone", "hire_date", "last_online", "pfp_url", "is_admin",
+		).Values(
+		user.UID, user.Password, user.Name, user.Surname, user.PositionID, user.Email, user.Phone, user.HireDate, user.LastOnline, user.PfpURL, user.IsAdmin,
+	).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+
+	return mapErr(err)
+}
+
+// SelectUserByEmail selects user by email from table users, returns User struct
+func (sl *SQLiteRepository) SelectUserByEmail(ctx context.Context, email string) (models.User, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("users").Where(sq.Eq{"email": email}).ToSql()
+	if err != nil {
+		return models.User{}, err
+	}
+	var user models.User
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&user)
+
+	return user, mapErr(err)
+}
+
+// UserEmailTaken uses unicode_lower like SelectProfilesByName
+func (sl *SQLiteRepository) UserEmailTaken(ctx context.Context, email string) (bool, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("1").From("users").
+		Where(sq.Expr("unicode_lower(email) = ?", strings.ToLower(email))).
+		Prefix("SELECT EXISTS (").Suffix(")").ToSql()
+	if err != nil {
+		return false, err
+	}
+	var taken bool
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&taken)
+	return taken, mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectUserByUID(ctx context.Context, uid string) (models.User, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("users").Where(sq.Eq{"uid": uid}).ToSql()
+	if err != nil {
+		return models.User{}, err
+	}
+	var user models.User
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&user)
+
+	return user, mapErr(err)
+}
+
+func (sl *SQLiteRepository) UpdateUserPassword(ctx context.Context, uid string, hash string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("users").Set("password", hash).Where(sq.Eq{"uid": uid}).ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+// SelectPositions selects all job positions into a slice, then returns it
+func (sl *SQLiteRepository) SelectPositions(ctx context.Context) ([]models.UserPosition, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("user_positions").OrderBy("id").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var positions []models.UserPosition
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var position models.UserPosition
+		err = rows.StructScan(&position)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
+
+func (sl *SQLiteRepository) InsertPosition(ctx context.Context, name string) (models.UserPosition, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("user_positions").Columns("name").Values(name).Suffix("RETURNING *").ToSql()
+	if err != nil {
+		return models.UserPosition{}, err
+	}
+
+	var position models.UserPosition
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&position)
+	return position, mapErr(err)
+}
+
+func (sl *SQLiteRepository) InsertInvitation(ctx context.Context, inv models.Invitation) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("user_invitations").
+		Columns("token_hash", "user_uid", "created_at", "expires_at").
+		Values(inv.TokenHash, inv.UserUID, timeArg(inv.CreatedAt), timeArg(inv.ExpiresAt)).
+		Suffix("ON CONFLICT (user_uid) DO UPDATE SET token_hash = excluded.token_hash, " +
+			"created_at = excluded.created_at, expires_at = excluded.expires_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) ConsumeInvitation(ctx context.Context, tokenHash string) (models.Invitation, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("user_invitations").Where(sq.Eq{"token_hash": tokenHash}).Suffix("RETURNING *").ToSql()
+	if err != nil {
+		return models.Invitation{}, err
+	}
+
+	var inv models.Invitation
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&inv)
+	return inv, mapErr(err)
+}
+
+func (sl *SQLiteRepository) InsertStreamTicket(ctx context.Context, t models.StreamTicket) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("stream_tickets").
+		Columns("ticket_hash", "user_uid", "expires_at").
+		Values(t.TicketHash, t.UserUID, timeArg(t.ExpiresAt)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) ConsumeStreamTicket(ctx context.Context, ticketHash string) (models.StreamTicket, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("stream_tickets").Where(sq.Eq{"ticket_hash": ticketHash}).Suffix("RETURNING *").ToSql()
+	if err != nil {
+		return models.StreamTicket{}, err
+	}
+
+	var t models.StreamTicket
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&t)
+	return t, mapErr(err)
+}
+
+func (sl *SQLiteRepository) DeleteExpiredStreamTickets(ctx context.Context, now time.Time) (int, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("stream_tickets").Where(sq.LtOrEq{"expires_at": timeArg(now)}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	return affected(sl.ext().ExecContext(ctx, q, args...))
+}
+
+// Probably final
+func (sl *SQLiteRepository) InsertIdea(ctx context.Context, idea models.Idea) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, _, err := qb.Insert("ideas").
+		Columns(
+			"idea_uid", "name", "text", "author", "status_id",
+			"category_id", "campaign_id",
+		).
+		Values(
+			sq.Expr(":idea_uid"), sq.Expr(":name"), sq.Expr(":text"), sq.Expr(":author"), sq.Expr(":status_id"),
+			sq.Expr(":category_id"), sq.Expr(":campaign_id"),
+		).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = sl.ext().NamedExecContext(ctx, q, idea)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectIdeas(ctx context.Context) ([]models.Idea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+	q, args, err := qb.Select("*").From("ideas").OrderBy("creation_date", "idea_uid").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var ideas []models.Idea
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idea models.Idea
+		err = rows.StructScan(&idea)
+		if err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+
+	return ideas, nil
+}
+
+func (sl *SQLiteRepository) SelectUserIdeas(ctx context.Context, uid string, limit int) ([]models.Idea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.
+		Select("*").
+		From("ideas").
+		Where(sq.Eq{"author": uid}).
+		OrderBy("creation_date DESC", "idea_uid DESC")
+
+	if limit > 0 {
+		builder = builder.Limit(uint64(limit))
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var ideas []models.Idea
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idea models.Idea
+		if err := rows.StructScan(&idea); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+
+	return ideas, nil
+}
+
+// SearchIdeas queries the ideas_fts FTS5 index, ranked by bm25
+func (sl *SQLiteRepository) SearchIdeas(ctx context.Context, query string, limit int) ([]models.Idea, error) {
+	terms := repository.SearchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	// terms are letters and digits only, quoting keeps FTS5 operators
+	// (AND, NEAR, column filters) out of user input
+	match := `"` + strings.Join(terms, `" "`) + `"`
+
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.
+		Select("ideas.*").
+		From("ideas_fts").
+		Join("ideas ON ideas.idea_uid = ideas_fts.idea_uid").
+		Where("ideas_fts MATCH ?", match).
+		OrderBy("bm25(ideas_fts)", "ideas.creation_date DESC", "ideas.idea_uid DESC")
+
+	if limit > 0 {
+		builder = builder.Limit(uint64(limit))
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var ideas []models.Idea
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var idea models.Idea
+		if err := rows.StructScan(&idea); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+
+	return ideas, nil
+}
+
+func (sl *SQLiteRepository) SelectIdeaByUID(ctx context.Context, uid string) (models.Idea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+	q, args, err := qb.Select("*").From("ideas").Where(sq.Eq{"idea_uid": uid}).ToSql()
+	if err != nil {
+		return models.Idea{}, err
+	}
+	var idea models.Idea
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&idea)
+	if err != nil {
+		return models.Idea{}, mapErr(err)
+	}
+	return idea, nil
+}
+
+func (sl *SQLiteRepository) UpdateIdeaStatus(ctx context.Context, uid string, statusID int) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("ideas").
+		Set("status_id", statusID).
+		Where(sq.Eq{"idea_uid": uid}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) InsertIdeaStatusChange(ctx context.Context, change models.IdeaStatusChange) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("idea_status_changes").
+		Columns("idea_uid", "status_id", "changed_by", "changed_at").
+		Values(change.IdeaUID, change.StatusID, change.ChangedBy, timeArg(change.ChangedAt)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectIdeaStatusChanges(ctx context.Context, ideaUID string) ([]models.IdeaStatusChange, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("idea_status_changes").
+		Where(sq.Eq{"idea_uid": ideaUID}).
+		OrderBy("changed_at", "id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var changes []models.IdeaStatusChange
+	for rows.Next() {
+		var c models.IdeaStatusChange
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// authorColumns are joined as u to show profiles with ideas and comments,
+// see repository.IdeaRow and repository.CommentRow
+const authorColumns = `
+	COALESCE(u.name, '') AS author_name,
+	COALESCE(u.surname, '') AS author_surname,
+	u.pfp_url AS author_pfp_url`
+
+// childCountColumn counts direct replies of comment c
+const childCountColumn = `(SELECT count(*) FROM comments ch WHERE ch.parent_uid = c.comment_uid) AS child_count`
+
+// SelectIdeaWithAuthor returns the idea joined with its author's profile
+func (sl *SQLiteRepository) SelectIdeaWithAuthor(ctx context.Context, uid string) (models.Idea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("i.*", authorColumns).
+		From("ideas i").
+		LeftJoin("users u ON u.uid = i.author").
+		Where(sq.Eq{"i.idea_uid": uid}).
+		ToSql()
+	if err != nil {
+		return models.Idea{}, err
+	}
+
+	var row repository.IdeaRow
+	if err := sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&row); err != nil {
+		return models.Idea{}, mapErr(err)
+	}
+	return row.WithProfile(), nil
+}
+
+// InsertIdeaComment takes depth and path of a reply from its parent in the
+// same statement, so nothing is inserted when the parent is missing
+func (sl *SQLiteRepository) InsertIdeaComment(ctx context.Context, comment models.Comment) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	if comment.ParentUID == nil {
+		q, args, err := qb.Insert("comments").
+			Columns("comment_uid", "idea_uid", "author_uid", "comment_text", "depth", "path").
+			Values(comment.CommentUID, comment.IdeaUID, comment.AuthorID, comment.CommentText,
+				0, repository.ChildPath("", comment.CommentUID)).
+			ToSql()
+		if err != nil {
+			return err
+		}
+		_, err = sl.ext().ExecContext(ctx, q, args...)
+		return mapErr(err)
+	}
+
+	parent := sq.Select().
+		Column("?", comment.CommentUID).
+		Column("?", comment.IdeaUID).
+		Column("?", comment.AuthorID).
+		Column("?", comment.CommentText).
+		Column("p.comment_uid").
+		Column("p.depth + 1").
+		Column("p.path || ? || ?", repository.PathSeparator, comment.CommentUID).
+		From("comments p").
+		Where(sq.Eq{"p.comment_uid": *comment.ParentUID})
+
+	q, args, err := qb.Insert("comments").
+		Columns("comment_uid", "idea_uid", "author_uid", "comment_text", "parent_uid", "depth", "path").
+		Select(parent).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := sl.ext().ExecContext(ctx, q, args...)
+	if err != nil {
+		return mapErr(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrInvalidReference
+	}
+	return nil
+}
+
+func (sl *SQLiteRepository) SelectIdeaComments(ctx context.Context, uid string) ([]models.Comment, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("comments").Where(sq.Eq{"idea_uid": uid}).OrderBy("timestamp", "comment_uid").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var comments []models.Comment
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment models.Comment
+		err = rows.StructScan(&comment)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+func (sl *SQLiteRepository) SelectCommentByUID(ctx context.Context, uid string) (models.Comment, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("comments").Where(sq.Eq{"comment_uid": uid}).ToSql()
+	if err != nil {
+		return models.Comment{}, err
+	}
+	var comment models.Comment
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&comment)
+
+	return comment, mapErr(err)
+}
+
+// SelectCommentChildren pages siblings by (timestamp, comment_uid) of the
+// last comment of the previous page
+func (sl *SQLiteRepository) SelectCommentChildren(ctx context.Context, ideaUID, parentUID, afterUID string, limit int) ([]models.Comment, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.Select("c.*", childCountColumn, authorColumns).
+		From("comments c").
+		LeftJoin("users u ON u.uid = c.author_uid").
+		OrderBy("c.timestamp", "c.comment_uid")
+
+	if parentUID == "" {
+		builder = builder.Where(sq.Eq{"c.idea_uid": ideaUID, "c.parent_uid": nil})
+	} else {
+		builder = builder.Where(sq.Eq{"c.parent_uid": parentUID})
+	}
+	if afterUID != "" {
+		builder = builder.Where("(c.timestamp, c.comment_uid) > (SELECT a.timestamp, a.comment_uid FROM comments a WHERE a.comment_uid = ?)", afterUID)
+	}
+	if limit > 0 {
+		builder = builder.Limit(uint64(limit))
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return sl.selectCommentRows(ctx, q, args)
+}
+
+// SelectCommentSubtrees finds descendants by the materialized path prefix,
+// comparing within the idea keeps the scan on comments_idea_idx
+func (sl *SQLiteRepository) SelectCommentSubtrees(ctx context.Context, rootUIDs []string, maxDepth int) ([]models.Comment, error) {
+	if len(rootUIDs) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("c.*", childCountColumn, authorColumns).
+		From("comments r").
+		Join("comments c ON c.idea_uid = r.idea_uid AND c.path LIKE r.path || '/%'").
+		LeftJoin("users u ON u.uid = c.author_uid").
+		Where(sq.Eq{"r.comment_uid": rootUIDs}).
+		Where(sq.LtOrEq{"c.depth": maxDepth}).
+		OrderBy("c.timestamp", "c.comment_uid").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return sl.selectCommentRows(ctx, q, args)
+}
+
+func (sl *SQLiteRepository) selectCommentRows(ctx context.Context, q string, args []interface{}) ([]models.Comment, error) {
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var row repository.CommentRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		comments = append(comments, row.WithProfile())
+	}
+	return comments, rows.Err()
+}
+
+func (sl *SQLiteRepository) UpdateCommentText(ctx context.Context, uid string, text string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("comments").
+		Set("comment_text", text).
+		Set("edited_at", sq.Expr(nowExpr)).
+		Where(sq.Eq{"comment_uid": uid}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) TombstoneComment(ctx context.Context, uid string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("comments").
+		Set("comment_text", "").
+		Set("deleted_at", sq.Expr(nowExpr)).
+		Where(sq.Eq{"comment_uid": uid}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) InsertRevision(ctx context.Context, revision models.Revision) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("comment_revisions").
+		Columns("target_uid", "text", "editor_uid").
+		Values(revision.TargetUID, revision.Text, revision.EditorUID).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectRevisions(ctx context.Context, targetUID string) ([]models.Revision, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("comment_revisions").
+		Where(sq.Eq{"target_uid": targetUID}).
+		OrderBy("edited_at", "id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []models.Revision
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision models.Revision
+		if err := rows.StructScan(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (sl *SQLiteRepository) DeleteRevisions(ctx context.Context, targetUID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("comment_revisions").Where(sq.Eq{"target_uid": targetUID}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) InsertReaction(ctx context.Context, reaction models.Reaction) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("reactions").
+		Columns("target_uid", "user_uid", "emoji").
+		Values(reaction.TargetUID, reaction.UserUID, reaction.Emoji).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) DeleteReaction(ctx context.Context, targetUID, userUID, emoji string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("reactions").
+		Where(sq.Eq{"target_uid": targetUID, "user_uid": userUID, "emoji": emoji}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectReactionCounts(ctx context.Context, targetUIDs []string, viewerUID string) ([]models.ReactionCount, error) {
+	if len(targetUIDs) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("target_uid", "emoji", "count(*) AS count").
+		Column("MAX(CASE WHEN CAST(user_uid AS TEXT) = ? THEN 1 ELSE 0 END) = 1 AS mine", viewerUID).
+		From("reactions").
+		Where(sq.Eq{"target_uid": targetUIDs}).
+		GroupBy("target_uid", "emoji").
+		OrderBy("target_uid", "emoji").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []models.ReactionCount
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count models.ReactionCount
+		if err := rows.StructScan(&count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+func (sl *SQLiteRepository) InsertMentions(ctx context.Context, mentions []models.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	insert := qb.Insert("mentions").
+		Columns("target_uid", "target_type", "idea_uid", "author_uid", "user_uid", "span_start", "span_end")
+	for _, m := range mentions {
+		insert = insert.Values(m.TargetUID, m.TargetType, m.IdeaUID, m.AuthorUID, m.UserUID, m.Start, m.End)
+	}
+	q, args, err := insert.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) DeleteMentions(ctx context.Context, targetUID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("mentions").Where(sq.Eq{"target_uid": targetUID}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+// mentionedColumns are joined as u to show profiles of mentioned users, see
+// repository.MentionRow
+const mentionedColumns = `
+	u.name AS user_name,
+	u.surname AS user_surname,
+	u.pfp_url AS user_pfp_url`
+
+func (sl *SQLiteRepository) SelectMentions(ctx context.Context, targetUIDs []string) ([]models.Mention, error) {
+	if len(targetUIDs) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("m.*", mentionedColumns).
+		From("mentions m").
+		Join("users u ON u.uid = m.user_uid").
+		Where(sq.Eq{"m.target_uid": targetUIDs}).
+		OrderBy("m.target_uid", "m.span_start").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentions []models.Mention
+	for rows.Next() {
+		var row repository.MentionRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, row.WithProfile())
+	}
+	return mentions, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectProfiles(ctx context.Context, uids []string) ([]models.AuthorProfile, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("uid", "name", "surname", "pfp_url").
+		From("users").
+		Where(sq.Eq{"uid": uids}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return sl.selectProfiles(ctx, q, args)
+}
+
+// SelectProfilesByName uses unicode_lower: the builtin lower() of
+// sqlite only knows ascii letters, and names are mostly cyrillic
+func (sl *SQLiteRepository) SelectProfilesByName(ctx context.Context, names []models.FullName) ([]models.AuthorProfile, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	match := sq.Or{}
+	for _, n := range names {
+		match = append(match, sq.Expr("unicode_lower(name) = ? AND unicode_lower(surname) = ?",
+			strings.ToLower(n.Name), strings.ToLower(n.Surname)))
+	}
+	q, args, err := qb.Select("uid", "name", "surname", "pfp_url").
+		From("users").
+		Where(match).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return sl.selectProfiles(ctx, q, args)
+}
+
+func (sl *SQLiteRepository) selectProfiles(ctx context.Context, q string, args []interface{}) ([]models.AuthorProfile, error) {
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []models.AuthorProfile
+	for rows.Next() {
+		var p models.AuthorProfile
+		if err := rows.StructScan(&p); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+func (sl *SQLiteRepository) InsertNotification(ctx context.Context, n models.Notification) (models.Notification, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("notifications").
+		Columns("user_uid", "type", "actor_uid", "idea_uid", "comment_uid", "detail").
+		Values(n.UserUID, n.Type, n.ActorUID, n.IdeaUID, n.CommentUID, n.Detail).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return models.Notification{}, err
+	}
+
+	n.ReadAt, n.Actor, n.IdeaName = nil, nil, ""
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return models.Notification{}, mapErr(err)
+	}
+	return n, nil
+}
+
+// actorColumns are joined as u and i to show who caused a notification and
+// where, see repository.NotificationRow
+const actorColumns = `
+	COALESCE(u.name, '') AS actor_name,
+	COALESCE(u.surname, '') AS actor_surname,
+	u.pfp_url AS actor_pfp_url,
+	COALESCE(i.name, '') AS idea_name`
+
+func (sl *SQLiteRepository) SelectNotifications(ctx context.Context, userUID string, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.Select("n.*", actorColumns).
+		From("notifications n").
+		LeftJoin("users u ON u.uid = n.actor_uid").
+		LeftJoin("ideas i ON i.idea_uid = n.idea_uid").
+		Where(sq.Eq{"n.user_uid": userUID})
+	if unreadOnly {
+		builder = builder.Where(sq.Eq{"n.read_at": nil})
+	}
+	if beforeID > 0 {
+		builder = builder.Where(sq.Lt{"n.id": beforeID})
+	}
+	q, args, err := builder.OrderBy("n.id DESC").Limit(uint64(limit)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var row repository.NotificationRow
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, row.WithDetails())
+	}
+	return notifications, rows.Err()
+}
+
+func (sl *SQLiteRepository) CountUnreadNotifications(ctx context.Context, userUID string) (int, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("count(*)").
+		From("notifications").
+		Where(sq.Eq{"user_uid": userUID, "read_at": nil}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&count)
+	return count, mapErr(err)
+}
+
+func (sl *SQLiteRepository) MarkNotificationRead(ctx context.Context, userUID string, id int64) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("notifications").
+		Set("read_at", sq.Expr("COALESCE(read_at, "+nowExpr+")")).
+		Where(sq.Eq{"id": id, "user_uid": userUID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) MarkAllNotificationsRead(ctx context.Context, userUID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("notifications").
+		Set("read_at", sq.Expr(nowExpr)).
+		Where(sq.Eq{"user_uid": userUID, "read_at": nil}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectNotificationPreferences(ctx context.Context, userUID string) ([]models.NotificationPreference, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").
+		From("notification_preferences").
+		Where(sq.Eq{"user_uid": userUID}).
+		OrderBy("type").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var preferences []models.NotificationPreference
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err := rows.StructScan(&p); err != nil {
+			return nil, err
+		}
+		preferences = append(preferences, p)
+	}
+	return preferences, rows.Err()
+}
+
+func (sl *SQLiteRepository) UpsertNotificationPreference(ctx context.Context, preference models.NotificationPreference) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("notification_preferences").
+		Columns("user_uid", "type", "enabled").
+		Values(preference.UserUID, preference.Type, preference.Enabled).
+		Suffix("ON CONFLICT (user_uid, type) DO UPDATE SET enabled = excluded.enabled").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) InsertOutboxEmail(ctx context.Context, e models.OutboxEmail) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("email_outbox").
+		Columns("user_uid", "kind", "to_addr", "subject", "text_body", "html_body", "next_attempt_at").
+		Values(e.UserUID, e.Kind, e.To, e.Subject, e.TextBody, e.HTMLBody, timeArg(e.NextAttemptAt)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) ClaimOutboxEmails(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEmail, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	due := qb.Select("id").
+		From("email_outbox").
+		Where(sq.Eq{"sent_at": nil, "failed_at": nil}).
+		Where(sq.LtOrEq{"next_attempt_at": timeArg(now)}).
+		OrderBy("next_attempt_at", "id").
+		Limit(uint64(limit))
+	q, args, err := qb.Update("email_outbox").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("next_attempt_at", timeArg(leaseUntil)).
+		Where(due.Prefix("id IN (").Suffix(")")).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var emails []models.OutboxEmail
+	for rows.Next() {
+		var e models.OutboxEmail
+		if err := rows.StructScan(&e); err != nil {
+			return nil, err
+		}
+		emails = append(emails, e)
+	}
+	// RETURNING keeps no order
+	slices.SortFunc(emails, func(a, b models.OutboxEmail) int { return cmp.Compare(a.ID, b.ID) })
+	return emails, rows.Err()
+}
+
+func (sl *SQLiteRepository) MarkOutboxEmailSent(ctx context.Context, id int64) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("email_outbox").
+		Set("sent_at", sq.Expr(nowExpr)).
+		Set("last_error", "").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) FailOutboxEmail(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	update := qb.Update("email_outbox").
+		Set("last_error", lastError).
+		Where(sq.Eq{"id": id})
+	if retryAt != nil {
+		update = update.Set("next_attempt_at", timeArg(*retryAt))
+	} else {
+		update = update.Set("failed_at", sq.Expr(nowExpr))
+	}
+	q, args, err := update.ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectOutboxEmails(ctx context.Context, userUID string, limit int) ([]models.OutboxEmail, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.Select("*").From("email_outbox")
+	if userUID != "" {
+		builder = builder.Where(sq.Eq{"user_uid": userUID})
+	}
+	q, args, err := builder.OrderBy("id DESC").Limit(uint64(limit)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var emails []models.OutboxEmail
+	for rows.Next() {
+		var row models.OutboxEmail
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		emails = append(emails, row)
+	}
+	return emails, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectEmailSettings(ctx context.Context, userUID string) (models.EmailSettings, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").
+		From("email_settings").
+		Where(sq.Eq{"user_uid": userUID}).
+		ToSql()
+	if err != nil {
+		return models.EmailSettings{}, err
+	}
+
+	var settings models.EmailSettings
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&settings)
+	return settings, mapErr(err)
+}
+
+func (sl *SQLiteRepository) UpsertEmailSettings(ctx context.Context, s models.EmailSettings) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("email_settings").
+		Columns("user_uid", "language", "immediate", "digest").
+		Values(s.UserUID, s.Language, s.Immediate, s.Digest).
+		Suffix(`ON CONFLICT (user_uid) DO UPDATE SET language = excluded.language,
+			immediate = excluded.immediate, digest = excluded.digest`).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectDigestRecipients(ctx context.Context, frequency models.DigestFrequency, sentBefore time.Time, limit int) ([]models.DigestRecipient, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+	d := models.DefaultEmailSettings
+
+	q, args, err := qb.Select("u.uid AS user_uid", "u.email", "u.name", "s.last_digest_at").
+		Column("COALESCE(s.language, ?) AS language", d.Language).
+		Column("COALESCE(s.immediate, ?) AS immediate", d.Immediate).
+		Column("COALESCE(s.digest, ?) AS digest", d.Digest).
+		From("users u").
+		LeftJoin("email_settings s ON s.user_uid = u.uid").
+		Where("COALESCE(s.digest, ?) = ?", d.Digest, frequency).
+		Where(sq.Or{sq.Eq{"s.last_digest_at": nil}, sq.Lt{"s.last_digest_at": timeArg(sentBefore)}}).
+		OrderBy("u.uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var recipients []models.DigestRecipient
+	for rows.Next() {
+		var row models.DigestRecipient
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, row)
+	}
+	return recipients, rows.Err()
+}
+
+func (sl *SQLiteRepository) MarkDigestSent(ctx context.Context, userUID string, at time.Time) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+	d := models.DefaultEmailSettings
+
+	q, args, err := qb.Insert("email_settings").
+		Columns("user_uid", "language", "immediate", "digest", "last_digest_at").
+		Values(userUID, d.Language, d.Immediate, d.Digest, timeArg(at)).
+		Suffix("ON CONFLICT (user_uid) DO UPDATE SET last_digest_at = excluded.last_digest_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectTrendingIdeas(ctx context.Context, since time.Time, limit int) ([]models.TrendingIdea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("i.*", "count(*) AS recent_votes").
+		From("ideas i").
+		Join("vote_history v ON v.idea_uid = i.idea_uid").
+		Where(sq.GtOrEq{"v.voted_at": timeArg(since)}).
+		GroupBy("i.idea_uid").
+		OrderBy("recent_votes DESC", "i.creation_date DESC", "i.idea_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var ideas []models.TrendingIdea
+	for rows.Next() {
+		var row models.TrendingIdea
+		if err := rows.StructScan(&row); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, row)
+	}
+	return ideas, rows.Err()
+}
+
+func (sl *SQLiteRepository) InsertWebhook(ctx context.Context, w models.Webhook) (models.Webhook, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("webhooks").
+		Columns("url", "secret", "events", "active", "created_by").
+		Values(w.URL, w.Secret, w.Events, w.Active, w.CreatedBy).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&w.ID, &w.CreatedAt)
+	return w, mapErr(err)
+}
+
+func (sl *SQLiteRepository) UpdateWebhook(ctx context.Context, w models.Webhook) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("webhooks").
+		Set("url", w.URL).
+		Set("secret", w.Secret).
+		Set("events", w.Events).
+		Set("active", w.Active).
+		Where(sq.Eq{"id": w.ID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) DeleteWebhook(ctx context.Context, id int64) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("webhooks").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectWebhook(ctx context.Context, id int64) (models.Webhook, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("webhooks").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	var w models.Webhook
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&w)
+	return w, mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("webhooks").OrderBy("id").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		var w models.Webhook
+		if err := rows.StructScan(&w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (sl *SQLiteRepository) InsertWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("webhook_deliveries").
+		Columns("webhook_id", "event_id", "event", "payload", "state", "attempts", "next_attempt_at").
+		Values(d.WebhookID, d.EventID, d.Event, d.Payload, d.State, d.Attempts, timeArg(d.NextAttemptAt)).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&d.ID, &d.CreatedAt)
+	return d, mapErr(err)
+}
+
+func (sl *SQLiteRepository) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	due := qb.Select("id").
+		From("webhook_deliveries").
+		Where(sq.Eq{"state": models.DeliveryPending}).
+		Where(sq.LtOrEq{"next_attempt_at": timeArg(now)}).
+		OrderBy("next_attempt_at", "id").
+		Limit(uint64(limit))
+	q, args, err := qb.Update("webhook_deliveries").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("next_attempt_at", timeArg(leaseUntil)).
+		Where(due.Prefix("id IN (").Suffix(")")).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.StructScan(&d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	// RETURNING keeps no order
+	slices.SortFunc(deliveries, func(a, b models.WebhookDelivery) int { return cmp.Compare(a.ID, b.ID) })
+	return deliveries, rows.Err()
+}
+
+func (sl *SQLiteRepository) RecordWebhookAttempt(ctx context.Context, a models.WebhookAttempt, state models.DeliveryState, retryAt time.Time) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	update := qb.Update("webhook_deliveries").
+		Set("state", state).
+		Set("response_code", a.ResponseCode).
+		Set("last_error", a.Error).
+		Where(sq.Eq{"id": a.DeliveryID})
+	switch state {
+	case models.DeliveryPending:
+		update = update.Set("next_attempt_at", timeArg(retryAt))
+	case models.DeliveryDelivered:
+		update = update.Set("delivered_at", sq.Expr(nowExpr))
+	}
+	q, args, err := update.ToSql()
+	if err != nil {
+		return err
+	}
+	if err := mustAffect(sl.ext().ExecContext(ctx, q, args...)); err != nil {
+		return err
+	}
+
+	q, args, err = qb.Insert("webhook_attempts").
+		Columns("delivery_id", "response_code", "error", "duration_ms").
+		Values(a.DeliveryID, a.ResponseCode, a.Error, a.DurationMS).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) RequeueWebhookDelivery(ctx context.Context, id int64, at time.Time) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("webhook_deliveries").
+		Set("state", models.DeliveryPending).
+		Set("attempts", 0).
+		Set("next_attempt_at", timeArg(at)).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectWebhookDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("webhook_deliveries").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	var d models.WebhookDelivery
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&d)
+	return d, mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectWebhookDeliveries(ctx context.Context, webhookID int64, state models.DeliveryState, limit int) ([]models.WebhookDelivery, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.Select("*").From("webhook_deliveries").Where(sq.Eq{"webhook_id": webhookID})
+	if state != "" {
+		builder = builder.Where(sq.Eq{"state": state})
+	}
+	q, args, err := builder.OrderBy("id DESC").Limit(uint64(limit)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.StructScan(&d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectWebhookAttempts(ctx context.Context, deliveryID int64) ([]models.WebhookAttempt, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").
+		From("webhook_attempts").
+		Where(sq.Eq{"delivery_id": deliveryID}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var attempts []models.WebhookAttempt
+	for rows.Next() {
+		var a models.WebhookAttempt
+		if err := rows.StructScan(&a); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+func (sl *SQLiteRepository) InsertBotLinkCode(ctx context.Context, c models.BotLinkCode) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("bot_link_codes").
+		Columns("code", "user_uid", "expires_at").
+		Values(c.Code, c.UserUID, timeArg(c.ExpiresAt)).
+		Suffix("ON CONFLICT (user_uid) DO UPDATE SET code = excluded.code, expires_at = excluded.expires_at").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) ConsumeBotLinkCode(ctx context.Context, code string) (models.BotLinkCode, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("bot_link_codes").Where(sq.Eq{"code": code}).Suffix("RETURNING *").ToSql()
+	if err != nil {
+		return models.BotLinkCode{}, err
+	}
+
+	var c models.BotLinkCode
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&c)
+	return c, mapErr(err)
+}
+
+func (sl *SQLiteRepository) DeleteExpiredBotLinkCodes(ctx context.Context, now time.Time) (int, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("bot_link_codes").Where(sq.LtOrEq{"expires_at": timeArg(now)}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	return affected(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) LinkBotAccount(ctx context.Context, a models.BotAccount) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("bot_accounts").
+		Where(sq.Eq{"platform": a.Platform}).
+		Where(sq.Or{sq.Eq{"external_id": a.ExternalID}, sq.Eq{"user_uid": a.UserUID}}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := sl.ext().ExecContext(ctx, q, args...); err != nil {
+		return mapErr(err)
+	}
+
+	q, args, err = qb.Insert("bot_accounts").
+		Columns("platform", "external_id", "chat_id", "user_uid", "username").
+		Values(a.Platform, a.ExternalID, a.ChatID, a.UserUID, a.Username).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, q, args...)
+	return mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectBotAccount(ctx context.Context, platform, externalID string) (models.BotAccount, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("bot_accounts").
+		Where(sq.Eq{"platform": platform, "external_id": externalID}).
+		ToSql()
+	if err != nil {
+		return models.BotAccount{}, err
+	}
+
+	var a models.BotAccount
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&a)
+	return a, mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectBotAccounts(ctx context.Context, userUID string) ([]models.BotAccount, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("bot_accounts").
+		Where(sq.Eq{"user_uid": userUID}).
+		OrderBy("linked_at", "platform").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var accounts []models.BotAccount
+	for rows.Next() {
+		var a models.BotAccount
+		if err := rows.StructScan(&a); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func (sl *SQLiteRepository) DeleteBotAccount(ctx context.Context, platform, externalID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("bot_accounts").
+		Where(sq.Eq{"platform": platform, "external_id": externalID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) InsertCampaign(ctx context.Context, c models.Campaign) (models.Campaign, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("campaigns").
+		Columns("title", "description", "starts_at", "ends_at", "category_ids", "position_ids", "created_by").
+		Values(c.Title, c.Description, timeArg(c.StartsAt), timeArg(c.EndsAt), c.CategoryIDs, c.PositionIDs, c.CreatedBy).
+		Suffix("RETURNING id, created_at").
+		ToSql()
+	if err != nil {
+		return models.Campaign{}, err
+	}
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&c.ID, &c.CreatedAt)
+	return c, mapErr(err)
+}
+
+func (sl *SQLiteRepository) UpdateCampaign(ctx context.Context, c models.Campaign) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("campaigns").
+		Set("title", c.Title).
+		Set("description", c.Description).
+		Set("starts_at", timeArg(c.StartsAt)).
+		Set("ends_at", timeArg(c.EndsAt)).
+		Set("category_ids", c.CategoryIDs).
+		Set("position_ids", c.PositionIDs).
+		Where(sq.Eq{"id": c.ID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) DeleteCampaign(ctx context.Context, id int64) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("campaigns").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectCampaign(ctx context.Context, id int64) (models.Campaign, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("campaigns").Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return models.Campaign{}, err
+	}
+
+	var c models.Campaign
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&c)
+	return c, mapErr(err)
+}
+
+func (sl *SQLiteRepository) SelectCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("campaigns").OrderBy("starts_at DESC", "id DESC").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var campaigns []models.Campaign
+	for rows.Next() {
+		var c models.Campaign
+		if err := rows.StructScan(&c); err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectCampaignLeaderboard(ctx context.Context, campaignID int64, limit int) ([]models.Idea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("ideas").
+		Where(sq.Eq{"campaign_id": campaignID}).
+		OrderBy("like_count - dislike_count DESC", "like_count DESC", "creation_date", "idea_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var ideas []models.Idea
+	for rows.Next() {
+		var idea models.Idea
+		if err := rows.StructScan(&idea); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	return ideas, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select(
+		"count(*) AS ideas",
+		"count(DISTINCT i.author) AS authors",
+		"coalesce(sum(i.like_count), 0) AS likes",
+		"coalesce(sum(i.dislike_count), 0) AS dislikes",
+	).
+		Column(sq.Expr(`(SELECT count(DISTINCT v.user_uid) FROM vote_history v
+			JOIN ideas vi ON vi.idea_uid = v.idea_uid WHERE vi.campaign_id = ?) AS voters`, campaignID)).
+		Column(sq.Expr(`(SELECT count(*) FROM comments c
+			JOIN ideas ci ON ci.idea_uid = c.idea_uid WHERE ci.campaign_id = ? AND c.deleted_at IS NULL) AS comments`, campaignID)).
+		From("ideas i").
+		Where(sq.Eq{"i.campaign_id": campaignID}).
+		ToSql()
+	if err != nil {
+		return models.CampaignStats{}, err
+	}
+
+	var stats models.CampaignStats
+	err = sl.ext().QueryRowxContext(ctx, q, args...).StructScan(&stats)
+	return stats, mapErr(err)
+}
+
+func (sl *SQLiteRepository) InsertJobRun(ctx context.Context, run models.JobRun) (models.JobRun, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	var scheduledAt any
+	if run.ScheduledAt != nil {
+		scheduledAt = timeArg(*run.ScheduledAt)
+	}
+	q, args, err := qb.Insert("job_runs").
+		Columns("job", "source", "scheduled_at", "triggered_by", "node", "status", "started_at").
+		Values(run.Job, run.Source, scheduledAt, run.TriggeredBy, run.Node, run.Status, timeArg(run.StartedAt)).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return models.JobRun{}, err
+	}
+
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&run.ID)
+	return run, mapErr(err)
+}
+
+func (sl *SQLiteRepository) FinishJobRun(ctx context.Context, id int64, status models.JobStatus, runErr string, finishedAt time.Time) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("job_runs").
+		Set("status", status).
+		Set("error", runErr).
+		Set("finished_at", timeArg(finishedAt)).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectJobRuns(ctx context.Context, job string, limit int) ([]models.JobRun, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	query := qb.Select("*").From("job_runs").OrderBy("id DESC").Limit(uint64(limit))
+	if job != "" {
+		query = query.Where(sq.Eq{"job": job})
+	}
+	q, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var runs []models.JobRun
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.StructScan(&run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (sl *SQLiteRepository) DeleteJobRuns(ctx context.Context, before time.Time) (int, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Delete("job_runs").
+		Where(sq.Lt{"started_at": timeArg(before)}).
+		Where(sq.NotEq{"finished_at": nil}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	return affected(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectIdeaCategories(ctx context.Context) ([]models.IdeaCategory, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("idea_categories").OrderBy("id").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var ideaCategories []models.IdeaCategory
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ideaCategory models.IdeaCategory
+		err = rows.StructScan(&ideaCategory)
+		if err != nil {
+			return nil, err
+		}
+		ideaCategories = append(ideaCategories, ideaCategory)
+	}
+
+	return ideaCategories, nil
+}
+
+func (sl *SQLiteRepository) SelectIdeaStatuses(ctx context.Context) ([]models.IdeaStatus, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("*").From("idea_statuses").OrderBy("id").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var ideaStatuses []models.IdeaStatus
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ideaStatus models.IdeaStatus
+		err = rows.StructScan(&ideaStatus)
+		if err != nil {
+			return nil, err
+		}
+		ideaStatuses = append(ideaStatuses, ideaStatus)
+	}
+
+	return ideaStatuses, nil
+}
+
+func (sl *SQLiteRepository) UpdateUserPfpURL(ctx context.Context, uid string, url string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("users").
+		Set("pfp_url", url).
+		Where(sq.Eq{"uid": uid}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) IncrementLikeCount(ctx context.Context, ideaUID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("ideas").
+		Set("like_count", sq.Expr("like_count + 1")).
+		Where(sq.Eq{"idea_uid": ideaUID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) IncrementDislikeCount(ctx context.Context, ideaUID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("ideas").
+		Set("dislike_count", sq.Expr("dislike_count + 1")).
+		Where(sq.Eq{"idea_uid": ideaUID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) CheckVote(ctx context.Context, ideaUID string, userUID string) (bool, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	var exists bool
+	checkQuery, args, err := qb.Select("exists (SELECT 1 FROM vote_history WHERE idea_uid = ? AND user_uid = ?)").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	err = sl.ext().QueryRowxContext(ctx, checkQuery, ideaUID, userUID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	insertQuery, args, err := qb.Insert("vote_history").
+		Columns("idea_uid", "user_uid").
+		Values(ideaUID, userUID).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = sl.ext().ExecContext(ctx, insertQuery, args...)
+	if err != nil {
+		return false, mapErr(err)
+	}
+
+	return true, nil
+}
+
+// nowExpr - current time as stored by column defaults, ISO 8601 with
+// milliseconds so that it sorts as text
+const nowExpr = "strftime('%Y-%m-%dT%H:%M:%fZ', 'now')"
+
+// timeArg formats t like nowExpr does, timestamps are compared as text
+func timeArg(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func (sl *SQLiteRepository) SelectIdeaActivity(ctx context.Context, since time.Time, uids ...string) ([]models.IdeaActivity, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	builder := qb.Select("i.idea_uid", "i.creation_date", "i.like_count", "i.dislike_count", "i.view_count AS views").
+		Column(`(SELECT count(*) FROM comments c
+			WHERE c.idea_uid = i.idea_uid AND c.deleted_at IS NULL) AS comments`).
+		Column(sq.Expr(`(SELECT count(*) FROM vote_history v
+			WHERE v.idea_uid = i.idea_uid AND v.voted_at >= ?) AS recent_votes`, timeArg(since))).
+		Column(sq.Expr(`(SELECT count(*) FROM comments c
+			WHERE c.idea_uid = i.idea_uid AND c.deleted_at IS NULL AND c.timestamp >= ?) AS recent_comments`, timeArg(since))).
+		From("ideas i").
+		OrderBy("i.idea_uid")
+	if len(uids) > 0 {
+		builder = builder.Where(sq.Eq{"i.idea_uid": uids})
+	}
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var activity []models.IdeaActivity
+	for rows.Next() {
+		var a models.IdeaActivity
+		if err := rows.StructScan(&a); err != nil {
+			return nil, err
+		}
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
+
+// scoreBatch - scores saved by a single statement
+const scoreBatch = 500
+
+func (sl *SQLiteRepository) UpsertIdeaScores(ctx context.Context, scores []models.IdeaScore) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	for chunk := range slices.Chunk(scores, scoreBatch) {
+		builder := qb.Insert("idea_scores").
+			Columns("idea_uid", "hot", "top", "controversial", "rising", "computed_at").
+			Suffix(`ON CONFLICT (idea_uid) DO UPDATE SET hot = excluded.hot, top = excluded.top,
+				controversial = excluded.controversial, rising = excluded.rising, computed_at = excluded.computed_at`)
+		for _, s := range chunk {
+			builder = builder.Values(s.IdeaUID, s.Hot, s.Top, s.Controversial, s.Rising, timeArg(s.ComputedAt))
+		}
+		q, args, err := builder.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := sl.ext().ExecContext(ctx, q, args...); err != nil {
+			return mapErr(err)
+		}
+	}
+	return nil
+}
+
+func (sl *SQLiteRepository) SelectRankedIdeas(ctx context.Context, sort models.IdeaSort) ([]models.Idea, error) {
+	if !slices.Contains(models.IdeaSorts, sort) {
+		return nil, fmt.Errorf("unknown idea sort %q", sort)
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("i.*").
+		From("ideas i").
+		LeftJoin("idea_scores s ON s.idea_uid = i.idea_uid").
+		OrderBy("s."+string(sort)+" DESC NULLS LAST", "i.creation_date DESC", "i.idea_uid").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var ideas []models.Idea
+	for rows.Next() {
+		var idea models.Idea
+		if err := rows.StructScan(&idea); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	return ideas, rows.Err()
+}
+
+func (sl *SQLiteRepository) UpsertBrowseHistory(ctx context.Context, visit models.BrowseHistory, countedAfter time.Time) (bool, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Insert("browse_history").
+		Columns("visitor_uid", "idea_uid", "viewed_at", "counted_at").
+		Values(visit.VisitorID, visit.IdeaID, timeArg(visit.ViewedAt), timeArg(visit.ViewedAt)).
+		Suffix(`ON CONFLICT (visitor_uid, idea_uid) DO UPDATE SET viewed_at = excluded.viewed_at,
+			counted_at = CASE WHEN browse_history.counted_at <= ? THEN excluded.counted_at ELSE browse_history.counted_at END
+			RETURNING counted_at = viewed_at`, timeArg(countedAfter)).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var counted bool
+	err = sl.ext().QueryRowxContext(ctx, q, args...).Scan(&counted)
+	return counted, mapErr(err)
+}
+
+func (sl *SQLiteRepository) IncrementViewCount(ctx context.Context, ideaUID string) error {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Update("ideas").
+		Set("view_count", sq.Expr("view_count + 1")).
+		Where(sq.Eq{"idea_uid": ideaUID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	return mustAffect(sl.ext().ExecContext(ctx, q, args...))
+}
+
+func (sl *SQLiteRepository) SelectIdeaVisits(ctx context.Context, visitorUID string, ideaUIDs []string) ([]models.IdeaVisit, error) {
+	if len(ideaUIDs) == 0 {
+		return nil, nil
+	}
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("b.idea_uid", "b.viewed_at").
+		Column(`(SELECT count(*) FROM comments c WHERE c.idea_uid = b.idea_uid AND c.timestamp > b.viewed_at
+			AND c.deleted_at IS NULL AND c.author_uid <> b.visitor_uid) AS unread_comments`).
+		From("browse_history b").
+		Where(sq.Eq{"b.visitor_uid": visitorUID, "b.idea_uid": ideaUIDs}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var visits []models.IdeaVisit
+	for rows.Next() {
+		var v models.IdeaVisit
+		if err := rows.StructScan(&v); err != nil {
+			return nil, err
+		}
+		visits = append(visits, v)
+	}
+	return visits, rows.Err()
+}
+
+func (sl *SQLiteRepository) SelectViewedIdeas(ctx context.Context, visitorUID string, limit int) ([]models.Idea, error) {
+	qb := sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	q, args, err := qb.Select("i.*").
+		From("browse_history b").
+		Join("ideas i ON i.idea_uid = b.idea_uid").
+		Where(sq.Eq{"b.visitor_uid": visitorUID}).
+		OrderBy("b.viewed_at DESC", "i.idea_uid").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sl.ext().QueryxContext(ctx, q, args...)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer rows.Close()
+
+	var ideas []models.Idea
+	for rows.Next() {
+		var idea models.Idea
+		if err := rows.StructScan(&idea); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	return ideas, rows.Err()
+}
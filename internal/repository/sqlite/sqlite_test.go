@@ -0,0 +1,174 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepo(t *testing.T) repository.Repository {
+	ctx := context.Background()
+	repo := &SQLiteRepository{}
+	url := Scheme + "://" + filepath.Join(t.TempDir(), "agora.db")
+	require.NoError(t, repo.ConnectDB(ctx, url, *slog.Default()))
+	t.Cleanup(func() { _ = repo.CloseConnectDB() })
+	require.NoError(t, repo.MigrateUp(ctx))
+	require.NoError(t, repo.CheckSchema(ctx))
+
+	for _, p := range repotest.Positions {
+		_, err := repo.db.ExecContext(ctx, `INSERT INTO user_positions (id, name) VALUES (?, ?)`, p.ID, p.Name)
+		require.NoError(t, err)
+	}
+	for _, c := range repotest.Categories {
+		_, err := repo.db.ExecContext(ctx, `INSERT INTO idea_categories (id, name) VALUES (?, ?)`, c.ID, c.Name)
+		require.NoError(t, err)
+	}
+	for _, s := range repotest.Statuses {
+		_, err := repo.db.ExecContext(ctx, `INSERT INTO idea_statuses (id, name) VALUES (?, ?)`, s.ID, s.Name)
+		require.NoError(t, err)
+	}
+	return repo
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, newRepo)
+}
+
+func TestMigrateDown(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t).(*SQLiteRepository)
+
+	_, latest, err := repo.SchemaVersion(ctx)
+	require.NoError(t, err)
+
+	// every down migration must undo its up
+	require.NoError(t, repo.MigrateDown(ctx, latest))
+	current, _, err := repo.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, current)
+
+	require.NoError(t, repo.MigrateUp(ctx))
+	require.NoError(t, repo.CheckSchema(ctx))
+}
+
+// threadedCommentsVersion - migration that moved replies into comments
+const threadedCommentsVersion = 3
+
+func TestMigrateRepliesIntoComments(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t).(*SQLiteRepository)
+	u := repotest.User(t, repo, "author@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "discussed")
+
+	_, latest, err := repo.SchemaVersion(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.MigrateDown(ctx, latest-threadedCommentsVersion+1))
+
+	comment, reply := uuid.NewString(), uuid.NewString()
+	_, err = repo.db.ExecContext(ctx, `INSERT INTO comments (comment_uid, idea_uid, author_uid, comment_text) VALUES (?, ?, ?, 'comment')`,
+		comment, idea.IdeaUID, u.UID)
+	require.NoError(t, err)
+	_, err = repo.db.ExecContext(ctx, `INSERT INTO replies (reply_uid, comment_uid, author_uid, reply_text, edited_at) VALUES (?, ?, ?, 'reply', '2025-01-02T03:04:05.000Z')`,
+		reply, comment, u.UID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.MigrateUp(ctx))
+
+	got, err := repo.SelectCommentByUID(ctx, reply)
+	require.NoError(t, err)
+	assert.Equal(t, "reply", got.CommentText)
+	assert.Equal(t, idea.IdeaUID, got.IdeaUID)
+	require.NotNil(t, got.ParentUID)
+	assert.Equal(t, comment, *got.ParentUID)
+	assert.Equal(t, 1, got.Depth)
+	assert.Equal(t, comment+"/"+reply, got.Path)
+	assert.NotNil(t, got.EditedAt)
+
+	root, err := repo.SelectCommentByUID(ctx, comment)
+	require.NoError(t, err)
+	assert.Nil(t, root.ParentUID)
+	assert.Equal(t, comment, root.Path)
+
+	// replies of migrated comments nest further
+	repotest.Comment(t, repo, idea.IdeaUID, reply, u.UID, "deeper")
+	sub, err := repo.SelectCommentSubtrees(ctx, []string{comment}, 10)
+	require.NoError(t, err)
+	require.Len(t, sub, 2)
+
+	// going back keeps the first level of replies only
+	require.NoError(t, repo.MigrateDown(ctx, latest-threadedCommentsVersion+1))
+	var replies []string
+	require.NoError(t, sqlx.SelectContext(ctx, repo.db, &replies, `SELECT reply_uid FROM replies`))
+	assert.Equal(t, []string{reply}, replies)
+}
+
+func TestConcurrentVotes(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	u := repotest.User(t, repo, "author@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "popular")
+
+	const voters = 20
+	var wg sync.WaitGroup
+	for i := 0; i < voters; i++ {
+		voter := repotest.User(t, repo, fmt.Sprintf("voter%d@example.com", i))
+		// every voter tries twice, only the first vote counts
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = repo.WithTx(ctx, func(tx repository.Repository) error {
+					ok, err := tx.CheckVote(ctx, idea.IdeaUID, voter.UID)
+					if err != nil || !ok {
+						return err
+					}
+					return tx.IncrementLikeCount(ctx, idea.IdeaUID)
+				})
+			}()
+		}
+	}
+	wg.Wait()
+
+	got, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, voters, got.LikeCount)
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		path     string
+		inMemory bool
+		wantErr  bool
+	}{
+		{url: "sqlite:///var/lib/agora/agora.db", path: "/var/lib/agora/agora.db"},
+		{url: "sqlite://data/agora.db", path: "data/agora.db"},
+		{url: "sqlite:agora.db?_pragma=cache_size(2000)", path: "agora.db"},
+		{url: "sqlite::memory:", path: ":memory:", inMemory: true},
+		{url: "sqlite://", wantErr: true},
+		{url: "postgres://localhost/agora", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			dsn, inMemory, err := ParseURL(tt.url)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.inMemory, inMemory)
+			assert.Regexp(t, `^`+regexp.QuoteMeta(tt.path)+`\?_pragma=foreign_keys\(1\)`, dsn)
+		})
+	}
+}
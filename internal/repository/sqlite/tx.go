@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"log/slog"
+	"math/rand/v2"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+)
+
+const (
+	maxTxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+)
+
+// WithTx runs fn inside a transaction. SQLite has a single writer, and
+// transactions take the write lock up front (see pragmas), so they are
+// serializable. If the lock can't be taken within busy_timeout fn is retried,
+// it must not have side effects outside the repo. Nested calls join the
+// outer transaction.
+func (sl *SQLiteRepository) WithTx(ctx context.Context, fn func(repo repository.Repository) error) error {
+	if sl.tx != nil {
+		return fn(sl)
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = sl.runTx(ctx, fn)
+		if !isRetryable(err) {
+			return err
+		}
+
+		sl.log.WarnContext(ctx, "retrying transaction",
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+
+		delay := txRetryDelay*time.Duration(attempt) + time.Duration(rand.Int64N(int64(txRetryDelay)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return fmt.Errorf("transaction failed after %d attempts: %w", maxTxAttempts, err)
+}
+
+func (sl *SQLiteRepository) runTx(ctx context.Context, fn func(repo repository.Repository) error) (err error) {
+	tx, err := sl.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	txRepo := &SQLiteRepository{db: sl.db, tx: tx, log: sl.log}
+	if err := fn(txRepo); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			sl.log.ErrorContext(ctx, "failed to rollback transaction", slog.String("error", rbErr.Error()))
+		}
+		return err
+	}
+
+	return mapErr(tx.Commit())
+}
+
+func isRetryable(err error) bool {
+	var liteErr *sqlite.Error
+	if !errors.As(err, &liteErr) {
+		return false
+	}
+	return liteErr.Code()&0xff == sqlite3.SQLITE_BUSY
+}
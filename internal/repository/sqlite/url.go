@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Scheme of DATABASE_URL selecting this backend
+const Scheme = "sqlite"
+
+// connection pragmas: enforce foreign keys like PostgreSQL does, wait for
+// locks instead of failing, and take the write lock when a transaction
+// begins so that concurrent WithTx calls never deadlock on lock upgrade
+var pragmas = []string{
+	"_pragma=foreign_keys(1)",
+	"_pragma=busy_timeout(5000)",
+	"_txlock=immediate",
+}
+
+// ParseURL converts sqlite:///abs/path.db, sqlite://rel/path.db or
+// sqlite::memory: into a modernc.org/sqlite DSN. Query parameters are passed
+// to the driver as is.
+func ParseURL(sourceURL string) (dsn string, inMemory bool, err error) {
+	rest, ok := strings.CutPrefix(sourceURL, Scheme+":")
+	if !ok {
+		return "", false, fmt.Errorf("sqlite: url must start with %s:, got %q", Scheme, sourceURL)
+	}
+	rest = strings.TrimPrefix(rest, "//")
+
+	path, rawQuery, _ := strings.Cut(rest, "?")
+	if path == "" {
+		return "", false, fmt.Errorf("sqlite: database path is empty in %q", sourceURL)
+	}
+	if _, err := url.ParseQuery(rawQuery); err != nil {
+		return "", false, fmt.Errorf("sqlite: bad url parameters: %w", err)
+	}
+
+	inMemory = path == ":memory:"
+	params := pragmas
+	if !inMemory {
+		// readers don't block the writer
+		params = append(params, "_pragma=journal_mode(WAL)")
+	}
+	if rawQuery != "" {
+		params = append(params, rawQuery)
+	}
+
+	return path + "?" + strings.Join(params, "&"), inMemory, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// SQL backends join authors' profile columns to ideas and comments shown in a
+// discussion (and mentioned users' ones to mentions), so a page of the thread
+// costs a fixed number of queries.
+
+// IdeaRow - idea row with the author's profile columns
+type IdeaRow struct {
+	models.Idea
+	AuthorName    string  `db:"author_name"`
+	AuthorSurname string  `db:"author_surname"`
+	AuthorPfpURL  *string `db:"author_pfp_url"`
+}
+
+// WithProfile returns the idea with AuthorProfile filled
+func (row IdeaRow) WithProfile() models.Idea {
+	idea := row.Idea
+	idea.AuthorProfile = &models.AuthorProfile{
+		UID:     idea.Author,
+		Name:    row.AuthorName,
+		Surname: row.AuthorSurname,
+		PfpURL:  row.AuthorPfpURL,
+	}
+	return idea
+}
+
+// CommentRow - comment row with the author's profile columns
+type CommentRow struct {
+	models.Comment
+	AuthorName    string  `db:"author_name"`
+	AuthorSurname string  `db:"author_surname"`
+	AuthorPfpURL  *string `db:"author_pfp_url"`
+}
+
+// WithProfile returns the comment with AuthorProfile filled
+func (row CommentRow) WithProfile() models.Comment {
+	comment := row.Comment
+	comment.AuthorProfile = &models.AuthorProfile{
+		UID:     comment.AuthorID,
+		Name:    row.AuthorName,
+		Surname: row.AuthorSurname,
+		PfpURL:  row.AuthorPfpURL,
+	}
+	return comment
+}
+
+// MentionRow - mention row with the mentioned user's profile columns
+type MentionRow struct {
+	models.Mention
+	UserName    string  `db:"user_name"`
+	UserSurname string  `db:"user_surname"`
+	UserPfpURL  *string `db:"user_pfp_url"`
+}
+
+// WithProfile returns the mention with User filled
+func (row MentionRow) WithProfile() models.Mention {
+	mention := row.Mention
+	mention.User = &models.AuthorProfile{
+		UID:     mention.UserUID,
+		Name:    row.UserName,
+		Surname: row.UserSurname,
+		PfpURL:  row.UserPfpURL,
+	}
+	return mention
+}
+
+// NotificationRow - notification row with the actor's profile and the idea
+// name columns
+type NotificationRow struct {
+	models.Notification
+	ActorName    string  `db:"actor_name"`
+	ActorSurname string  `db:"actor_surname"`
+	ActorPfpURL  *string `db:"actor_pfp_url"`
+	IdeaTitle    string  `db:"idea_name"`
+}
+
+// WithDetails returns the notification with Actor and IdeaName filled
+func (row NotificationRow) WithDetails() models.Notification {
+	n := row.Notification
+	n.Actor = &models.AuthorProfile{
+		UID:     n.ActorUID,
+		Name:    row.ActorName,
+		Surname: row.ActorSurname,
+		PfpURL:  row.ActorPfpURL,
+	}
+	n.IdeaName = row.IdeaTitle
+	return n
+}
+
+// PathSeparator joins uids in models.Comment.Path
+const PathSeparator = "/"
+
+// ChildPath returns the path of a comment under the parent with parentPath,
+// empty parentPath means a top level comment
+func ChildPath(parentPath, uid string) string {
+	if parentPath == "" {
+		return uid
+	}
+	return parentPath + PathSeparator + uid
+}
+
+// InSubtree reports whether path lies strictly under rootPath
+func InSubtree(path, rootPath string) bool {
+	return strings.HasPrefix(path, rootPath+PathSeparator)
+}
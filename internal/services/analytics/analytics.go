@@ -0,0 +1,379 @@
+// Package analytics computes reports for the management dashboard: ideas
+// submitted per period, decisions on them, the most active users and
+// engagement per position. Reports are cached for CacheTTL, so numbers of
+// the current day may lag behind by as much.
+package analytics
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+const (
+	// CacheTTL - how long a report is served before it is computed again
+	CacheTTL = 5 * time.Minute
+	// DefaultDays - days up to today reported when the range has no start
+	DefaultDays = 30
+	// MaxDays - the longest range reported
+	MaxDays = 5 * 366
+	// DefaultContributors - users in each top when no limit is given
+	DefaultContributors = 10
+)
+
+// approvedNames and rejectedNames start names of statuses taken for
+// decisions unless SetDecisionStatuses is called
+var (
+	approvedNames = []string{"approv", "одобр"}
+	rejectedNames = []string{"reject", "отклон"}
+)
+
+type Analytics struct {
+	log  slog.Logger
+	repo repository.Repository
+	now  func() time.Time
+
+	// approved and rejected are set before serving, nil finds them by name
+	approved, rejected []int
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	report  any
+	expires time.Time
+}
+
+func New(log slog.Logger, repo repository.Repository) *Analytics {
+	return &Analytics{
+		log:   log,
+		repo:  repo,
+		now:   time.Now,
+		cache: map[string]cacheEntry{},
+	}
+}
+
+// SetDecisionStatuses sets statuses that mean an idea was approved or
+// rejected. By default they are found by name, see DecisionStatuses.
+func (a *Analytics) SetDecisionStatuses(approved, rejected []int) error {
+	if len(approved) == 0 {
+		return fmt.Errorf("no approved statuses")
+	}
+	for _, id := range rejected {
+		if slices.Contains(approved, id) {
+			return fmt.Errorf("status %d is both approved and rejected", id)
+		}
+	}
+	a.approved, a.rejected = slices.Clone(approved), slices.Clone(rejected)
+	return nil
+}
+
+// DecisionStatuses returns statuses whose names start with "approv" or
+// "reject", in English or Russian, ignoring case
+func DecisionStatuses(statuses []models.IdeaStatus) (approved, rejected []int) {
+	named := func(s models.IdeaStatus, prefixes []string) bool {
+		name := strings.ToLower(strings.TrimSpace(s.Name))
+		return slices.ContainsFunc(prefixes, func(p string) bool { return strings.HasPrefix(name, p) })
+	}
+	for _, s := range statuses {
+		switch {
+		case named(s, approvedNames):
+			approved = append(approved, s.ID)
+		case named(s, rejectedNames):
+			rejected = append(rejected, s.ID)
+		}
+	}
+	return approved, rejected
+}
+
+func (a *Analytics) decisionStatuses(ctx context.Context) (approved, rejected []int, err error) {
+	if a.approved != nil {
+		return a.approved, a.rejected, nil
+	}
+	statuses, err := a.repo.SelectIdeaStatuses(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	approved, rejected = DecisionStatuses(statuses)
+	return approved, rejected, nil
+}
+
+// resolve fills in the defaults of the range and returns it with its
+// bounds as [from, to)
+func (a *Analytics) resolve(r models.AnalyticsRange) (models.AnalyticsRange, time.Time, time.Time, error) {
+	if r.To.IsZero() {
+		r.To = a.now()
+	}
+	r.To = models.GroupDay.Start(r.To)
+	if r.From.IsZero() {
+		r.From = r.To.AddDate(0, 0, 1-DefaultDays)
+	}
+	r.From = models.GroupDay.Start(r.From)
+
+	if r.From.After(r.To) {
+		return r, time.Time{}, time.Time{}, apperr.Validation("from must not be after to")
+	}
+	to := r.To.AddDate(0, 0, 1)
+	if to.Sub(r.From) > MaxDays*24*time.Hour {
+		return r, time.Time{}, time.Time{}, apperr.Validation(fmt.Sprintf("range must not be longer than %d days", MaxDays))
+	}
+	return r, r.From, to, nil
+}
+
+// cached returns the report stored under key until it expires, computing
+// and storing it otherwise. Failures aren't cached.
+func cached[T any](ctx context.Context, a *Analytics, key string, compute func() (T, error)) (T, error) {
+	now := a.now()
+	a.mu.Lock()
+	entry, ok := a.cache[key]
+	a.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.report.(T), nil
+	}
+
+	report, err := compute()
+	if err != nil {
+		a.log.ErrorContext(ctx, "failed to compute report: "+err.Error(), slog.String("report", key))
+		return report, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k, e := range a.cache {
+		if !now.Before(e.expires) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[key] = cacheEntry{report: report, expires: now.Add(CacheTTL)}
+	return report, nil
+}
+
+func rangeKey(report string, r models.AnalyticsRange, rest ...any) string {
+	return fmt.Sprintf("%s %s %s %v", report, r.From.Format(time.DateOnly), r.To.Format(time.DateOnly), rest)
+}
+
+// GetSubmissions counts ideas submitted within the range per period of
+// group, every period the range touches is listed
+func (a *Analytics) GetSubmissions(ctx context.Context, r models.AnalyticsRange, group models.AnalyticsGroup) (models.SubmissionReport, error) {
+	if group == "" {
+		group = models.GroupDay
+	}
+	if !slices.Contains([]models.AnalyticsGroup{models.GroupDay, models.GroupWeek, models.GroupMonth}, group) {
+		return models.SubmissionReport{}, apperr.Validation("unknown group")
+	}
+	r, from, to, err := a.resolve(r)
+	if err != nil {
+		return models.SubmissionReport{}, err
+	}
+
+	return cached(ctx, a, rangeKey("submissions", r, group), func() (models.SubmissionReport, error) {
+		counts, err := a.repo.CountIdeasByPeriod(ctx, from, to, group)
+		if err != nil {
+			return models.SubmissionReport{}, err
+		}
+		report := models.SubmissionReport{Range: r, Group: group, Periods: []models.PeriodCount{}}
+		for period := group.Start(from); period.Before(to); period = group.Next(period) {
+			c := models.PeriodCount{Period: period}
+			if len(counts) > 0 && counts[0].Period.Equal(period) {
+				c.Ideas, counts = counts[0].Ideas, counts[1:]
+			}
+			report.Total += c.Ideas
+			report.Periods = append(report.Periods, c)
+		}
+		return report, nil
+	})
+}
+
+// GetDecisions reports how ideas submitted within the range were decided
+// on, in total and per category. Categories without ideas are listed too.
+func (a *Analytics) GetDecisions(ctx context.Context, r models.AnalyticsRange) (models.DecisionReport, error) {
+	r, from, to, err := a.resolve(r)
+	if err != nil {
+		return models.DecisionReport{}, err
+	}
+
+	return cached(ctx, a, rangeKey("decisions", r), func() (models.DecisionReport, error) {
+		approved, rejected, err := a.decisionStatuses(ctx)
+		if err != nil {
+			return models.DecisionReport{}, err
+		}
+		outcomes, err := a.repo.SelectCategoryOutcomes(ctx, from, to, approved, rejected)
+		if err != nil {
+			return models.DecisionReport{}, err
+		}
+		decisions, err := a.repo.SelectIdeaDecisions(ctx, from, to, slices.Concat(approved, rejected))
+		if err != nil {
+			return models.DecisionReport{}, err
+		}
+		categories, err := a.repo.SelectIdeaCategories(ctx)
+		if err != nil {
+			return models.DecisionReport{}, err
+		}
+
+		byCategory := map[int]*models.DecisionStats{}
+		report := models.DecisionReport{Range: r, Categories: []models.DecisionStats{}}
+		for _, c := range categories {
+			byCategory[c.ID] = &models.DecisionStats{CategoryID: c.ID, Category: c.Name}
+		}
+		for _, o := range outcomes {
+			stats, ok := byCategory[o.CategoryID]
+			if !ok {
+				// ideas without a category
+				stats = &models.DecisionStats{CategoryID: o.CategoryID}
+				byCategory[o.CategoryID] = stats
+			}
+			stats.Ideas, stats.Approved, stats.Rejected = o.Ideas, o.Approved, o.Rejected
+			report.Total.Ideas += o.Ideas
+			report.Total.Approved += o.Approved
+			report.Total.Rejected += o.Rejected
+		}
+
+		hours := map[int][]float64{}
+		var total []float64
+		for _, d := range decisions {
+			h := d.DecidedAt.Sub(d.CreationDate).Hours()
+			hours[d.CategoryID] = append(hours[d.CategoryID], h)
+			total = append(total, h)
+		}
+		report.Total.ApprovalRate = rate(report.Total.Approved, report.Total.Approved+report.Total.Rejected)
+		report.Total.MedianDecisionHours = median(total)
+		for id, stats := range byCategory {
+			stats.ApprovalRate = rate(stats.Approved, stats.Approved+stats.Rejected)
+			stats.MedianDecisionHours = median(hours[id])
+			report.Categories = append(report.Categories, *stats)
+		}
+		slices.SortFunc(report.Categories, func(a, b models.DecisionStats) int {
+			return cmp.Compare(a.CategoryID, b.CategoryID)
+		})
+		return report, nil
+	})
+}
+
+// GetContributors returns up to limit users who posted the most ideas and
+// the most comments within the range, DefaultContributors when limit isn't
+// positive
+func (a *Analytics) GetContributors(ctx context.Context, r models.AnalyticsRange, limit int) (models.ContributorReport, error) {
+	if limit <= 0 {
+		limit = DefaultContributors
+	}
+	r, from, to, err := a.resolve(r)
+	if err != nil {
+		return models.ContributorReport{}, err
+	}
+
+	return cached(ctx, a, rangeKey("contributors", r, limit), func() (models.ContributorReport, error) {
+		authors, err := a.repo.SelectTopAuthors(ctx, from, to, limit)
+		if err != nil {
+			return models.ContributorReport{}, err
+		}
+		commenters, err := a.repo.SelectTopCommenters(ctx, from, to, limit)
+		if err != nil {
+			return models.ContributorReport{}, err
+		}
+
+		var uids []string
+		for _, c := range slices.Concat(authors, commenters) {
+			uids = append(uids, c.UserUID)
+		}
+		profiles, err := a.repo.SelectProfiles(ctx, uids)
+		if err != nil {
+			return models.ContributorReport{}, err
+		}
+		byUID := make(map[string]models.AuthorProfile, len(profiles))
+		for _, p := range profiles {
+			byUID[p.UID] = p
+		}
+		contributors := func(counts []models.UserCount) []models.Contributor {
+			list := make([]models.Contributor, 0, len(counts))
+			for _, c := range counts {
+				profile, ok := byUID[c.UserUID]
+				if !ok {
+					profile = models.AuthorProfile{UID: c.UserUID}
+				}
+				list = append(list, models.Contributor{User: profile, Count: c.Count})
+			}
+			return list
+		}
+		return models.ContributorReport{
+			Range:      r,
+			Authors:    contributors(authors),
+			Commenters: contributors(commenters),
+		}, nil
+	})
+}
+
+// GetEngagement reports what users did within the range in total and per
+// position, positions without members are listed too
+func (a *Analytics) GetEngagement(ctx context.Context, r models.AnalyticsRange) (models.EngagementReport, error) {
+	r, from, to, err := a.resolve(r)
+	if err != nil {
+		return models.EngagementReport{}, err
+	}
+
+	return cached(ctx, a, rangeKey("engagement", r), func() (models.EngagementReport, error) {
+		rows, err := a.repo.SelectPositionEngagement(ctx, from, to)
+		if err != nil {
+			return models.EngagementReport{}, err
+		}
+		positions, err := a.repo.SelectPositions(ctx)
+		if err != nil {
+			return models.EngagementReport{}, err
+		}
+
+		byPosition := map[int]models.PositionEngagement{}
+		for _, e := range rows {
+			byPosition[e.PositionID] = e
+		}
+		report := models.EngagementReport{Range: r, Positions: make([]models.PositionEngagement, 0, len(positions))}
+		for _, p := range positions {
+			e := byPosition[p.ID]
+			e.PositionID, e.Position = p.ID, p.Name
+			e.Participation = rate(e.Voters, e.Members)
+			report.Positions = append(report.Positions, e)
+		}
+		for _, e := range rows {
+			t := &report.Total
+			t.Members += e.Members
+			t.Authors += e.Authors
+			t.Commenters += e.Commenters
+			t.Voters += e.Voters
+			t.Ideas += e.Ideas
+			t.Comments += e.Comments
+			t.Votes += e.Votes
+		}
+		report.Total.Participation = rate(report.Total.Voters, report.Total.Members)
+		return report, nil
+	})
+}
+
+// rate returns n/of, nil when of is zero
+func rate(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	r := float64(n) / float64(of)
+	return &r
+}
+
+// median returns the middle value, nil for no values
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := slices.Sorted(slices.Values(values))
+	m := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		m = (sorted[len(sorted)/2-1] + m) / 2
+	}
+	return &m
+}
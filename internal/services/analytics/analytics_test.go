@@ -0,0 +1,159 @@
+package analytics
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var statuses = []models.IdeaStatus{
+	{ID: 1, Name: "New"},
+	{ID: 2, Name: "Approved"},
+	{ID: 3, Name: "Rejected"},
+}
+
+func TestDecisionStatuses(t *testing.T) {
+	approved, rejected := DecisionStatuses(statuses)
+	assert.Equal(t, []int{2}, approved)
+	assert.Equal(t, []int{3}, rejected)
+
+	approved, rejected = DecisionStatuses([]models.IdeaStatus{
+		{ID: 1, Name: "Новая"}, {ID: 3, Name: "Одобрена"}, {ID: 4, Name: "Отклонена"}, {ID: 5, Name: " APPROVED "},
+	})
+	assert.Equal(t, []int{3, 5}, approved)
+	assert.Equal(t, []int{4}, rejected)
+
+	a := New(*slog.Default(), memory.New())
+	assert.Error(t, a.SetDecisionStatuses(nil, []int{3}))
+	assert.Error(t, a.SetDecisionStatuses([]int{2, 3}, []int{3}))
+	assert.NoError(t, a.SetDecisionStatuses([]int{2}, nil))
+}
+
+func TestRange(t *testing.T) {
+	a := New(*slog.Default(), memory.New())
+	a.now = func() time.Time { return time.Date(2026, 7, 1, 23, 30, 0, 0, time.FixedZone("MSK", 3*3600)) }
+
+	r, from, to, err := a.resolve(models.AnalyticsRange{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), r.To, "days are in UTC")
+	assert.Equal(t, time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC), r.From)
+	assert.Equal(t, r.From, from)
+	assert.Equal(t, time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC), to, "to is inclusive")
+
+	day := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	_, from, to, err = a.resolve(models.AnalyticsRange{From: day, To: day})
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, to.Sub(from))
+
+	_, _, _, err = a.resolve(models.AnalyticsRange{From: day, To: day.AddDate(0, 0, -1)})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, _, _, err = a.resolve(models.AnalyticsRange{From: day.AddDate(-6, 0, 0), To: day})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = a.GetSubmissions(context.Background(), models.AnalyticsRange{}, "year")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+func TestReports(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, statuses)
+	a := New(*slog.Default(), repo)
+	i, err := ideas.New(ctx, *slog.Default(), repo)
+	require.NoError(t, err)
+
+	moderator := models.User{UID: uuid.NewString(), Name: "Anna", Surname: "Smirnova",
+		PositionID: repotest.Positions[1].ID, Email: "admin@example.com", Password: "hash", IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, moderator))
+	author := repotest.User(t, repo, "author@example.com")
+	approved := repotest.Idea(t, repo, author.UID, "approved")
+	rejected := repotest.Idea(t, repo, author.UID, "rejected")
+	process := models.Idea{IdeaUID: uuid.NewString(), Name: "process", Text: "text", Author: moderator.UID,
+		StatusID: statuses[0].ID, CategoryID: repotest.Categories[1].ID}
+	require.NoError(t, repo.InsertIdea(ctx, process))
+
+	// the moderator's move is recorded with the change
+	_, err = i.ChangeIdeaStatus(ctx, approved.IdeaUID, moderator.UID, 2)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateIdeaStatus(ctx, rejected.IdeaUID, 3))
+	require.NoError(t, repo.InsertIdeaStatusChange(ctx, models.IdeaStatusChange{
+		IdeaUID: rejected.IdeaUID, StatusID: 3, ChangedAt: rejected.CreationDate.Add(10 * time.Hour)}))
+
+	_, err = i.InsertComment(ctx, process.IdeaUID, "", author.UID, "nice")
+	require.NoError(t, err)
+	_, err = i.Vote(ctx, process.IdeaUID, author.UID, models.VoteLike)
+	require.NoError(t, err)
+
+	today := models.AnalyticsRange{From: approved.CreationDate, To: approved.CreationDate}
+
+	decisions, err := a.GetDecisions(ctx, today)
+	require.NoError(t, err)
+	assert.Equal(t, 3, decisions.Total.Ideas)
+	assert.Equal(t, 1, decisions.Total.Approved)
+	assert.Equal(t, 1, decisions.Total.Rejected)
+	require.NotNil(t, decisions.Total.ApprovalRate)
+	assert.Equal(t, 0.5, *decisions.Total.ApprovalRate)
+	require.NotNil(t, decisions.Total.MedianDecisionHours)
+	assert.InDelta(t, 5, *decisions.Total.MedianDecisionHours, 0.01, "halfway between right away and 10 hours")
+	require.Len(t, decisions.Categories, 2)
+	assert.Equal(t, "Office", decisions.Categories[0].Category)
+	assert.Equal(t, 2, decisions.Categories[0].Ideas)
+	assert.Equal(t, "Process", decisions.Categories[1].Category)
+	assert.Nil(t, decisions.Categories[1].ApprovalRate, "nothing decided")
+	assert.Nil(t, decisions.Categories[1].MedianDecisionHours)
+
+	submissions, err := a.GetSubmissions(ctx, models.AnalyticsRange{To: approved.CreationDate}, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.GroupDay, submissions.Group)
+	require.Len(t, submissions.Periods, DefaultDays)
+	assert.Equal(t, 3, submissions.Total)
+	assert.Equal(t, 3, submissions.Periods[DefaultDays-1].Ideas)
+	assert.Zero(t, submissions.Periods[0].Ideas)
+	weeks, err := a.GetSubmissions(ctx, today, models.GroupWeek)
+	require.NoError(t, err)
+	require.Len(t, weeks.Periods, 1)
+	assert.Equal(t, time.Monday, weeks.Periods[0].Period.Weekday())
+	assert.Equal(t, 3, weeks.Periods[0].Ideas)
+
+	contributors, err := a.GetContributors(ctx, today, 0)
+	require.NoError(t, err)
+	require.Len(t, contributors.Authors, 2)
+	assert.Equal(t, models.Contributor{User: models.AuthorProfile{UID: author.UID, Name: "Ivan", Surname: "Petrov"}, Count: 2},
+		contributors.Authors[0])
+	assert.Equal(t, moderator.UID, contributors.Authors[1].User.UID)
+	require.Len(t, contributors.Commenters, 1)
+	assert.Equal(t, 1, contributors.Commenters[0].Count)
+
+	engagement, err := a.GetEngagement(ctx, today)
+	require.NoError(t, err)
+	assert.Equal(t, 2, engagement.Total.Members)
+	assert.Equal(t, 1, engagement.Total.Voters)
+	require.NotNil(t, engagement.Total.Participation)
+	assert.Equal(t, 0.5, *engagement.Total.Participation)
+	require.Len(t, engagement.Positions, 2)
+	assert.Equal(t, "Developer", engagement.Positions[0].Position)
+	assert.Equal(t, 1, engagement.Positions[0].Comments)
+	assert.Equal(t, 1.0, *engagement.Positions[0].Participation)
+	assert.Equal(t, 1, engagement.Positions[1].Ideas)
+	assert.Equal(t, 0.0, *engagement.Positions[1].Participation)
+
+	// reports are served from the cache until it expires
+	repotest.Idea(t, repo, author.UID, "late")
+	cachedReport, err := a.GetDecisions(ctx, today)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cachedReport.Total.Ideas)
+	now := time.Now().Add(CacheTTL)
+	a.now = func() time.Time { return now }
+	fresh, err := a.GetDecisions(ctx, today)
+	require.NoError(t, err)
+	assert.Equal(t, 4, fresh.Total.Ideas)
+}
@@ -1,7 +1,13 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
 	"github.com/TP2-Voice-Agora/backend/internal/lib/jwt"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
 	"github.com/TP2-Voice-Agora/backend/internal/repository"
@@ -11,11 +17,15 @@ import (
 	"time"
 )
 
+// StreamTicketTTL - how long a ticket to the event stream waits to be used
+const StreamTicketTTL = time.Minute
+
 type Auth struct {
 	log       slog.Logger
 	repo      repository.Repository
 	tokenTTL  time.Duration
 	jwtSecret string
+	now       func() time.Time
 }
 
 func New(log slog.Logger, repo repository.Repository, tokenTTL time.Duration, jwtSecret string) *Auth {
@@ -24,21 +34,22 @@ func New(log slog.Logger, repo repository.Repository, tokenTTL time.Duration, jw
 		repo:      repo,
 		tokenTTL:  tokenTTL,
 		jwtSecret: jwtSecret,
+		now:       time.Now,
 	}
 }
 
-func (a *Auth) Register(u models.User) error {
+func (a *Auth) Register(ctx context.Context, u models.User) error {
 	op := "AuthRegister"
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("uid", u.UID),
 	)
 
-	log.Info("attempting to register user")
+	log.InfoContext(ctx, "attempting to register user")
 
 	hashPass, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Error("failed to generate password hash")
+		log.ErrorContext(ctx, "failed to generate password hash")
 		return err
 	}
 
@@ -48,38 +59,60 @@ func (a *Auth) Register(u models.User) error {
 
 	u.UID = uid
 
-	err = a.repo.InsertUser(u)
+	// email is not unique in schema, so check and insert atomically
+	err = a.repo.WithTx(ctx, func(repo repository.Repository) error {
+		_, err := repo.SelectUserByEmail(ctx, u.Email)
+		if err == nil {
+			return repository.ErrConflict
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		return repo.InsertUser(ctx, u)
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		log.DebugContext(ctx, "user already exists")
+		return apperr.Conflict("user with this email already exists")
+	}
+	if errors.Is(err, repository.ErrInvalidReference) {
+		log.DebugContext(ctx, "unknown position")
+		return apperr.Validation("unknown position")
+	}
 	if err != nil {
-		log.Error("failed to insert user")
+		log.ErrorContext(ctx, "failed to insert user")
 		return err
 	}
-	log.Info("successfully registered user")
+	log.InfoContext(ctx, "successfully registered user")
 
 	return nil
 }
 
-func (a *Auth) Login(email string, password string) (string, string, error) {
+func (a *Auth) Login(ctx context.Context, email string, password string) (string, string, error) {
 	op := "AuthLogin"
 	log := a.log.With(
 		slog.String("op", op),
 		slog.String("user", email),
 	)
 
-	log.Info("attempting to login user")
+	log.InfoContext(ctx, "attempting to login user")
 
-	user, err := a.repo.SelectUserByEmail(email)
+	user, err := a.repo.SelectUserByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.DebugContext(ctx, "user not found")
+		return "", "", apperr.Unauthorized("invalid email or password")
+	}
 	if err != nil {
-		log.Error("error selecting user" + err.Error())
-		return "", "", errors.New("user not found")
+		log.ErrorContext(ctx, "error selecting user"+err.Error())
+		return "", "", err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		a.log.Error("incorrect password")
-		return "", "", errors.New("incorrect password")
+		a.log.ErrorContext(ctx, "incorrect password")
+		return "", "", apperr.Unauthorized("invalid email or password")
 	}
 	//TODO: make app provider
 
-	log.Info("user logged in")
+	log.InfoContext(ctx, "user logged in")
 
 	token := jwt.NewToken(user, a.tokenTTL, a.jwtSecret)
 
@@ -89,3 +122,59 @@ func (a *Auth) Login(email string, password string) (string, string, error) {
 func (a *Auth) GetJWT() string {
 	return a.jwtSecret
 }
+
+// IssueStreamTicket returns a one-time ticket to the event stream for the
+// user, so that the long-lived JWT never goes into a URL
+func (a *Auth) IssueStreamTicket(ctx context.Context, userUID string) (models.StreamTicket, error) {
+	log := a.log.With(slog.String("op", "AuthIssueStreamTicket"), slog.String("uid", userUID))
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return models.StreamTicket{}, err
+	}
+	ticket := models.StreamTicket{
+		Ticket:    base64.RawURLEncoding.EncodeToString(b),
+		UserUID:   userUID,
+		ExpiresAt: a.now().Add(StreamTicketTTL),
+	}
+	ticket.TicketHash = hashTicket(ticket.Ticket)
+
+	if err := a.repo.InsertStreamTicket(ctx, ticket); err != nil {
+		log.ErrorContext(ctx, "failed to insert stream ticket: "+err.Error())
+		return models.StreamTicket{}, err
+	}
+	return ticket, nil
+}
+
+// RedeemStreamTicket spends the ticket and returns the uid of the user it
+// was issued to
+func (a *Auth) RedeemStreamTicket(ctx context.Context, ticket string) (string, error) {
+	t, err := a.repo.ConsumeStreamTicket(ctx, hashTicket(ticket))
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", apperr.Unauthorized("invalid or used ticket")
+	}
+	if err != nil {
+		a.log.ErrorContext(ctx, "failed to consume stream ticket: "+err.Error(), slog.String("op", "AuthRedeemStreamTicket"))
+		return "", err
+	}
+	if !a.now().Before(t.ExpiresAt) {
+		return "", apperr.Unauthorized("ticket has expired")
+	}
+	return t.UserUID, nil
+}
+
+// PurgeStreamTickets deletes expired stream tickets and returns how many
+// were deleted
+func (a *Auth) PurgeStreamTickets(ctx context.Context) (int, error) {
+	n, err := a.repo.DeleteExpiredStreamTickets(ctx, a.now())
+	if err != nil {
+		a.log.ErrorContext(ctx, "failed to purge stream tickets: "+err.Error(), slog.String("op", "AuthPurgeStreamTickets"))
+		return 0, err
+	}
+	return n, nil
+}
+
+func hashTicket(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
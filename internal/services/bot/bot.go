@@ -0,0 +1,262 @@
+// Package bot lets users work with ideas from a messenger: submit them with
+// a command, vote with inline buttons and get notified of comments on their
+// ideas, replies and status changes. Messengers plug in as a Platform, the
+// bot itself only speaks in updates and messages, and every action goes
+// through the ideas service like the web client does.
+//
+// A messenger account acts for the user it is linked to. The user issues a
+// one-time code in the web profile and sends it to the bot with /link.
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/services/interfaces"
+)
+
+const (
+	// LinkCodeTTL - time a link code is accepted for
+	LinkCodeTTL = 10 * time.Minute
+	// SendTimeout bounds a single call to the messenger
+	SendTimeout = 10 * time.Second
+	// LatestIdeas - ideas shown by /ideas
+	LatestIdeas = 5
+)
+
+// linkCodeAlphabet leaves out characters easy to confuse when retyping a
+// code: 0 and O, 1 and I
+const linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const linkCodeLength = 8
+
+// Platform - messenger the bot talks through. Updates come either from Poll
+// or from the messenger calling the webhook, decoded by Parse.
+type Platform interface {
+	// Name identifies the platform in linked accounts and webhook URLs
+	Name() string
+	// Poll fetches updates and hands them to handle one by one until ctx is
+	// done
+	Poll(ctx context.Context, handle func(context.Context, Update)) error
+	// Parse decodes an update posted to the webhook, it returns
+	// apperr.ErrUnauthorized for requests not coming from the messenger
+	Parse(r *http.Request) (Update, error)
+	Send(ctx context.Context, chatID string, msg Message) error
+	// Answer acknowledges a button press, text is shown as a short popup
+	Answer(ctx context.Context, callbackID, text string) error
+	// Link returns a link opening the bot with code sent to it, "" if the
+	// messenger has none
+	Link(code string) string
+}
+
+// Update - message sent to the bot or a button pressed. CallbackID is set
+// for buttons, Data is the data of the pressed button then.
+type Update struct {
+	ChatID     string
+	UserID     string
+	Username   string
+	Text       string
+	CallbackID string
+	Data       string
+}
+
+// Message - text sent by the bot with rows of buttons under it
+type Message struct {
+	Text    string
+	Buttons [][]Button
+}
+
+// Button comes back as an Update with its Data when pressed, or opens URL
+// when one is set
+type Button struct {
+	Text string
+	Data string
+	URL  string
+}
+
+type Bot struct {
+	log       slog.Logger
+	repo      repository.Repository
+	ideas     interfaces.IdeaService
+	platforms map[string]Platform
+	baseURL   string
+	now       func() time.Time
+	// sending tracks notifications being forwarded in the background
+	sending sync.WaitGroup
+}
+
+// New returns a bot serving users of the given platforms, links in messages
+// point to the frontend at baseURL
+func New(log slog.Logger, repo repository.Repository, ideas interfaces.IdeaService, baseURL string, platforms ...Platform) *Bot {
+	b := &Bot{
+		log:       log,
+		repo:      repo,
+		ideas:     ideas,
+		platforms: make(map[string]Platform, len(platforms)),
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		now:       time.Now,
+	}
+	for _, p := range platforms {
+		b.platforms[p.Name()] = p
+	}
+	return b
+}
+
+func (b *Bot) ideaURL(uid string) string {
+	return b.baseURL + "/ideas/" + uid
+}
+
+// Run long-polls every platform until ctx is done, platforms receiving
+// updates through the webhook are left out
+func (b *Bot) Run(ctx context.Context, platforms ...string) {
+	var wg sync.WaitGroup
+	for _, name := range platforms {
+		p, ok := b.platforms[name]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handle := func(ctx context.Context, update Update) { b.handle(ctx, p, update) }
+			if err := p.Poll(ctx, handle); err != nil && ctx.Err() == nil {
+				b.log.ErrorContext(ctx, "stopped polling "+name+": "+err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Webhook handles an update the platform posted to the webhook
+func (b *Bot) Webhook(ctx context.Context, platform string, r *http.Request) error {
+	p, ok := b.platforms[platform]
+	if !ok {
+		return apperr.NotFound("unknown platform " + platform)
+	}
+	update, err := p.Parse(r)
+	if err != nil {
+		return err
+	}
+	b.handle(ctx, p, update)
+	return nil
+}
+
+// CreateLinkCode issues a one-time code linking a messenger account to the
+// user, codes issued before stop working
+func (b *Bot) CreateLinkCode(ctx context.Context, userUID string) (models.BotLinkCode, error) {
+	op := "BotCreateLinkCode"
+	log := b.log.With(slog.String("op", op), slog.String("userUID", userUID))
+
+	if len(b.platforms) == 0 {
+		return models.BotLinkCode{}, apperr.NotFound("no messenger bot is configured")
+	}
+	code := models.BotLinkCode{UserUID: userUID, ExpiresAt: b.now().Add(LinkCodeTTL)}
+	var err error
+	// a collision with a code of someone else is unlikely, once more is enough
+	for range 2 {
+		code.Code = newLinkCode()
+		err = b.repo.InsertBotLinkCode(ctx, code)
+		if !errors.Is(err, repository.ErrConflict) {
+			break
+		}
+	}
+	if errors.Is(err, repository.ErrInvalidReference) {
+		return models.BotLinkCode{}, apperr.NotFound("user not found")
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to save link code: "+err.Error())
+		return models.BotLinkCode{}, err
+	}
+
+	code.Links = make(map[string]string)
+	for name, p := range b.platforms {
+		if link := p.Link(code.Code); link != "" {
+			code.Links[name] = link
+		}
+	}
+	log.InfoContext(ctx, "issued link code")
+	return code, nil
+}
+
+func newLinkCode() string {
+	code := make([]byte, linkCodeLength)
+	_, _ = rand.Read(code)
+	for i, c := range code {
+		code[i] = linkCodeAlphabet[int(c)%len(linkCodeAlphabet)]
+	}
+	return string(code)
+}
+
+// PurgeLinkCodes deletes expired link codes and returns how many were
+// deleted
+func (b *Bot) PurgeLinkCodes(ctx context.Context) (int, error) {
+	n, err := b.repo.DeleteExpiredBotLinkCodes(ctx, b.now())
+	if err != nil {
+		b.log.ErrorContext(ctx, "failed to purge link codes: "+err.Error(), slog.String("op", "BotPurgeLinkCodes"))
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetAccounts returns messenger accounts linked to the user
+func (b *Bot) GetAccounts(ctx context.Context, userUID string) ([]models.BotAccount, error) {
+	accounts, err := b.repo.SelectBotAccounts(ctx, userUID)
+	if err != nil {
+		b.log.ErrorContext(ctx, "failed to fetch bot accounts: "+err.Error(), slog.String("op", "BotGetAccounts"))
+		return nil, err
+	}
+	if accounts == nil {
+		accounts = []models.BotAccount{}
+	}
+	return accounts, nil
+}
+
+// Unlink unlinks the user's messenger account
+func (b *Bot) Unlink(ctx context.Context, userUID, platform, externalID string) error {
+	op := "BotUnlink"
+	log := b.log.With(slog.String("op", op), slog.String("userUID", userUID))
+
+	account, err := b.repo.SelectBotAccount(ctx, platform, externalID)
+	if err == nil && account.UserUID != userUID {
+		err = repository.ErrNotFound
+	}
+	if err == nil {
+		err = b.repo.DeleteBotAccount(ctx, platform, externalID)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("account not found")
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to unlink account: "+err.Error())
+		return err
+	}
+	log.InfoContext(ctx, "unlinked account", slog.String("platform", platform))
+	return nil
+}
+
+// send delivers msg, a failure is only logged: there is nobody to report it to
+func (b *Bot) send(ctx context.Context, p Platform, chatID string, msg Message) {
+	ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+	defer cancel()
+	if err := p.Send(ctx, chatID, msg); err != nil {
+		b.log.ErrorContext(ctx, "failed to send message: "+err.Error(),
+			slog.String("platform", p.Name()), slog.String("chatID", chatID))
+	}
+}
+
+func (b *Bot) answer(ctx context.Context, p Platform, callbackID, text string) {
+	ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+	defer cancel()
+	if err := p.Answer(ctx, callbackID, text); err != nil {
+		b.log.ErrorContext(ctx, "failed to answer button: "+err.Error(), slog.String("platform", p.Name()))
+	}
+}
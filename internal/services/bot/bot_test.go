@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/TP2-Voice-Agora/backend/internal/services/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fake - messenger recording what the bot sends
+type fake struct {
+	mu      sync.Mutex
+	sent    map[string][]Message
+	answers []string
+}
+
+func newFake() *fake {
+	return &fake{sent: map[string][]Message{}}
+}
+
+func (f *fake) Name() string { return "fake" }
+
+func (f *fake) Poll(ctx context.Context, handle func(context.Context, Update)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (f *fake) Parse(r *http.Request) (Update, error) {
+	return Update{}, apperr.Unauthorized("fake")
+}
+
+func (f *fake) Send(ctx context.Context, chatID string, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent[chatID] = append(f.sent[chatID], msg)
+	return nil
+}
+
+func (f *fake) Answer(ctx context.Context, callbackID, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.answers = append(f.answers, text)
+	return nil
+}
+
+func (f *fake) Link(code string) string { return "https://fake.example.com/bot?start=" + code }
+
+// last returns the last message sent to the chat
+func (f *fake) last(t *testing.T, chatID string) Message {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	require.NotEmpty(t, f.sent[chatID])
+	return f.sent[chatID][len(f.sent[chatID])-1]
+}
+
+// setup wires the bot into the notification center, like main does
+func setup(t *testing.T) (*Bot, *fake, *ideas.Ideas, *memory.Repository) {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	i, err := ideas.New(context.Background(), *slog.Default(), repo)
+	require.NoError(t, err)
+	f := newFake()
+	b := New(*slog.Default(), repo, i, "https://agora.example.com/", f)
+	n := notifications.New(*slog.Default(), repo)
+	n.SetForwarder(b)
+	i.SetNotifier(n)
+	return b, f, i, repo
+}
+
+// say sends text to the bot from the chat of the same id
+func say(b *Bot, f *fake, chatID, text string) {
+	b.handle(context.Background(), f, Update{ChatID: chatID, UserID: chatID, Text: text})
+}
+
+// linked links the chat to the user
+func linked(t *testing.T, b *Bot, f *fake, chatID string, user models.User) {
+	t.Helper()
+	code, err := b.CreateLinkCode(context.Background(), user.UID)
+	require.NoError(t, err)
+	say(b, f, chatID, "/link "+code.Code)
+	require.Contains(t, f.last(t, chatID).Text, "аккаунт привязан")
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text, command, args string
+	}{
+		{"/idea Title\nText", "/idea", "Title\nText"},
+		{"  /IDEA@agora_bot   Title ", "/idea", "Title"},
+		{"/idea\nTitle\nText", "/idea", "Title\nText"},
+		{"/ideas", "/ideas", ""},
+		{"hello", "", "hello"},
+	}
+	for _, tt := range tests {
+		command, args := parseCommand(tt.text)
+		assert.Equal(t, tt.command, command, tt.text)
+		assert.Equal(t, tt.args, args, tt.text)
+	}
+}
+
+func TestLinking(t *testing.T) {
+	ctx := context.Background()
+	b, f, _, repo := setup(t)
+	alice := repotest.User(t, repo, "alice@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	say(b, f, "1", "/ideas")
+	assert.Equal(t, textNotLinked, f.last(t, "1").Text)
+
+	code, err := b.CreateLinkCode(ctx, alice.UID)
+	require.NoError(t, err)
+	assert.Len(t, code.Code, linkCodeLength)
+	assert.Equal(t, "https://fake.example.com/bot?start="+code.Code, code.Links["fake"])
+	assert.Equal(t, now.Add(LinkCodeTTL), code.ExpiresAt)
+
+	say(b, f, "1", "/link nope")
+	assert.Equal(t, textBadCode, f.last(t, "1").Text)
+	// deep links send /start with the code, case doesn't matter
+	say(b, f, "1", "/start "+strings.ToLower(code.Code))
+	assert.True(t, strings.HasPrefix(f.last(t, "1").Text, "Готово, Ivan, аккаунт привязан."))
+	say(b, f, "2", "/link "+code.Code)
+	assert.Equal(t, textBadCode, f.last(t, "2").Text, "codes are one-time")
+
+	code, err = b.CreateLinkCode(ctx, bob.UID)
+	require.NoError(t, err)
+	now = now.Add(LinkCodeTTL)
+	say(b, f, "2", "/link "+code.Code)
+	assert.Equal(t, textBadCode, f.last(t, "2").Text, "expired")
+	n, err := b.PurgeLinkCodes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	accounts, err := b.GetAccounts(ctx, alice.UID)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, models.BotAccount{Platform: "fake", ExternalID: "1", ChatID: "1", UserUID: alice.UID,
+		LinkedAt: accounts[0].LinkedAt}, accounts[0])
+	accounts, err = b.GetAccounts(ctx, bob.UID)
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	assert.ErrorIs(t, b.Unlink(ctx, bob.UID, "fake", "1"), apperr.ErrNotFound, "not bob's")
+	require.NoError(t, b.Unlink(ctx, alice.UID, "fake", "1"))
+	say(b, f, "1", "/ideas")
+	assert.Equal(t, textNotLinked, f.last(t, "1").Text)
+
+	linked(t, b, f, "1", alice)
+	say(b, f, "1", "/unlink")
+	assert.Equal(t, textUnlinked, f.last(t, "1").Text)
+	accounts, err = b.GetAccounts(ctx, alice.UID)
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+
+	_, err = New(*slog.Default(), repo, nil, "").CreateLinkCode(ctx, alice.UID)
+	assert.ErrorIs(t, err, apperr.ErrNotFound, "no platforms")
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+	b, f, i, repo := setup(t)
+	alice := repotest.User(t, repo, "alice@example.com")
+	linked(t, b, f, "1", alice)
+
+	say(b, f, "1", "/idea")
+	assert.Contains(t, f.last(t, "1").Text, "#2 — Process")
+	say(b, f, "1", "/idea #7 Title")
+	assert.True(t, strings.HasPrefix(f.last(t, "1").Text, "Нет категории #7."))
+
+	say(b, f, "1", "/idea #2 Coffee machine\nOn the third floor,\nplease")
+	msg := f.last(t, "1")
+	assert.Equal(t, "Идея «Coffee machine» опубликована.", msg.Text)
+	say(b, f, "1", "/idea Standup at 10")
+
+	all, err := i.GetAllIdeas(ctx, alice.UID)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	byName := map[string]models.Idea{}
+	for _, idea := range all {
+		byName[idea.Name] = idea
+	}
+	coffee := byName["Coffee machine"]
+	assert.Equal(t, "On the third floor,\nplease", coffee.Text)
+	assert.Equal(t, alice.UID, coffee.Author)
+	assert.Equal(t, 2, coffee.CategoryID)
+	assert.Equal(t, repotest.Statuses[0].ID, coffee.StatusID)
+	assert.Equal(t, [][]Button{{{Text: "Открыть на сайте", URL: "https://agora.example.com/ideas/" + coffee.IdeaUID}}}, msg.Buttons)
+	standup := byName["Standup at 10"]
+	assert.Equal(t, "Standup at 10", standup.Text, "the name doubles as the text")
+	assert.Equal(t, repotest.Categories[0].ID, standup.CategoryID)
+}
+
+func TestVoteButtons(t *testing.T) {
+	ctx := context.Background()
+	b, f, i, repo := setup(t)
+	alice := repotest.User(t, repo, "alice@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	linked(t, b, f, "2", bob)
+
+	say(b, f, "2", "/ideas")
+	assert.Equal(t, textNoIdeas, f.last(t, "2").Text)
+
+	for _, name := range []string{"first", "second", "third", "fourth", "fifth", "sixth"} {
+		_, err := i.InsertIdea(ctx, name, "text of "+name, alice.UID, 1, 1)
+		require.NoError(t, err)
+	}
+	f.sent["2"] = nil
+	say(b, f, "2", "/ideas")
+	require.Len(t, f.sent["2"], LatestIdeas)
+	newest := f.sent["2"][0]
+	assert.Contains(t, newest.Text, "«sixth»")
+	assert.Contains(t, newest.Text, "👍 0  👎 0")
+	require.Len(t, newest.Buttons, 2)
+	like, dislike := newest.Buttons[0][0], newest.Buttons[0][1]
+
+	press := func(data string) string {
+		b.handle(ctx, f, Update{ChatID: "2", UserID: "2", CallbackID: "cb", Data: data})
+		return f.answers[len(f.answers)-1]
+	}
+	assert.Equal(t, "Голос учтён.", press(like.Data))
+	assert.Equal(t, "Вы уже голосовали за эту идею.", press(dislike.Data))
+	assert.Equal(t, "Идея не найдена.", press(dataLike+"00000000-0000-0000-0000-000000000000"))
+	b.handle(ctx, f, Update{ChatID: "3", UserID: "3", CallbackID: "cb", Data: like.Data})
+	assert.Equal(t, textNotLinked, f.answers[len(f.answers)-1])
+
+	ideaUID := strings.TrimPrefix(like.Data, dataLike)
+	idea, err := i.GetIdeaByUID(ctx, ideaUID, bob.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "sixth", idea.Idea.Name)
+	assert.Equal(t, 1, idea.Idea.LikeCount)
+	assert.Zero(t, idea.Idea.DislikeCount)
+}
+
+func TestNotified(t *testing.T) {
+	ctx := context.Background()
+	b, f, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	admin := models.User{UID: "8f0f5b8e-2b1a-4f0e-9c39-5d2f3f6b7a10", Name: "Mod", Surname: "Erator",
+		PositionID: 1, Email: "mod@example.com", Password: "hash", IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, admin))
+	linked(t, b, f, "1", author)
+	linked(t, b, f, "2", bob)
+	f.sent = map[string][]Message{}
+
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+	_, err := i.Vote(ctx, idea.IdeaUID, bob.UID, models.VoteLike)
+	require.NoError(t, err)
+	comment, err := i.InsertComment(ctx, idea.IdeaUID, "", bob.UID, "great idea")
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, comment.CommentUID, admin.UID, "agreed")
+	require.NoError(t, err)
+	_, err = i.ChangeIdeaStatus(ctx, idea.IdeaUID, admin.UID, 2)
+	require.NoError(t, err)
+	b.sending.Wait()
+
+	texts := func(chatID string) []string {
+		var res []string
+		for _, msg := range f.sent[chatID] {
+			res = append(res, msg.Text)
+		}
+		return res
+	}
+	// sent in the background, in no particular order
+	assert.ElementsMatch(t, []string{
+		"Ivan Petrov прокомментировал(а) вашу идею «idea»:\n\ngreat idea",
+		"Mod Erator прокомментировал(а) вашу идею «idea»:\n\nagreed",
+		"Mod Erator изменил(а) статус вашей идеи «idea»: Approved",
+	}, texts("1"), "no votes")
+	assert.Equal(t, []string{"Mod Erator ответил(а) на ваш комментарий к идее «idea»:\n\nagreed"}, texts("2"))
+	assert.Equal(t, "https://agora.example.com/ideas/"+idea.IdeaUID, f.sent["2"][0].Buttons[0][0].URL)
+
+	// messages follow the preferences of the app
+	_, err = notifications.New(*slog.Default(), repo).SetPreferences(ctx, bob.UID,
+		[]models.NotificationPreference{{Type: models.NotificationReply, Enabled: false}})
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, comment.CommentUID, admin.UID, "muted")
+	require.NoError(t, err)
+	b.sending.Wait()
+	assert.Len(t, f.sent["2"], 1)
+}
@@ -0,0 +1,298 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// Commands the bot understands, /start with a code is how messengers pass
+// the code from a link
+const (
+	CommandStart  = "/start"
+	CommandLink   = "/link"
+	CommandHelp   = "/help"
+	CommandIdea   = "/idea"
+	CommandIdeas  = "/ideas"
+	CommandUnlink = "/unlink"
+)
+
+// Data of the vote buttons, followed by the idea uid
+const (
+	dataLike    = "like:"
+	dataDislike = "dislike:"
+)
+
+// excerptLength - characters of an idea or a comment quoted in a message
+const excerptLength = 300
+
+const (
+	textHelp = "Я помогаю предлагать идеи и голосовать за них.\n\n" +
+		"/idea Заголовок — предложить идею, текст пишите со следующей строки. " +
+		"Категорию можно указать номером: /idea #2 Заголовок\n" +
+		"/ideas — новые идеи с кнопками для голосования\n" +
+		"/unlink — отвязать этот аккаунт\n\n" +
+		"Ещё я присылаю комментарии к вашим идеям, ответы на ваши комментарии и смену статуса ваших идей."
+	textNotLinked = "Сначала привяжите аккаунт: получите код в профиле на сайте и отправьте мне /link КОД."
+	textBadCode   = "Код не подошёл: он неверный или устарел. Получите новый в профиле на сайте."
+	textFailed    = "Что-то пошло не так, попробуйте позже."
+	textNoIdeas   = "Идей пока нет, предложите первую: /idea Заголовок"
+	textUnlinked  = "Аккаунт отвязан, уведомления больше не придут."
+)
+
+func (b *Bot) handle(ctx context.Context, p Platform, u Update) {
+	log := b.log.With(slog.String("op", "BotHandle"),
+		slog.String("platform", p.Name()),
+		slog.String("externalID", u.UserID),
+	)
+
+	if u.ChatID == "" {
+		return // an update of a kind the bot doesn't read
+	}
+	if u.CallbackID != "" {
+		b.answer(ctx, p, u.CallbackID, b.press(ctx, log, p, u))
+		return
+	}
+
+	command, args := parseCommand(u.Text)
+	var replies []Message
+	switch command {
+	case CommandStart, CommandLink:
+		if args == "" {
+			replies = text(textHelp)
+			break
+		}
+		replies = b.link(ctx, log, p, u, args)
+	case CommandIdea, CommandIdeas, CommandUnlink:
+		account, err := b.repo.SelectBotAccount(ctx, p.Name(), u.UserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			replies = text(textNotLinked)
+			break
+		}
+		if err != nil {
+			log.ErrorContext(ctx, "failed to fetch account: "+err.Error())
+			replies = text(textFailed)
+			break
+		}
+		switch command {
+		case CommandIdea:
+			replies = b.submit(ctx, log, account, args)
+		case CommandIdeas:
+			replies = b.latest(ctx, log, account)
+		case CommandUnlink:
+			replies = b.unlink(ctx, log, account)
+		}
+	default:
+		replies = text(textHelp)
+	}
+
+	for _, msg := range replies {
+		b.send(ctx, p, u.ChatID, msg)
+	}
+}
+
+func text(s string) []Message {
+	return []Message{{Text: s}}
+}
+
+// parseCommand splits "/command@bot arguments" into the command and the
+// trimmed arguments, a text that isn't a command has no command
+func parseCommand(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "/") {
+		return "", s
+	}
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end < 0 {
+		end = len(s)
+	}
+	command, args := s[:end], s[end:]
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(args)
+}
+
+// link links the messenger account with the code, the code is spent only
+// when linking succeeds
+func (b *Bot) link(ctx context.Context, log *slog.Logger, p Platform, u Update, code string) []Message {
+	var userUID string
+	err := b.repo.WithTx(ctx, func(repo repository.Repository) error {
+		c, err := repo.ConsumeBotLinkCode(ctx, strings.ToUpper(code))
+		if err != nil {
+			return err
+		}
+		if !b.now().Before(c.ExpiresAt) {
+			return repository.ErrNotFound
+		}
+		userUID = c.UserUID
+		return repo.LinkBotAccount(ctx, models.BotAccount{
+			Platform:   p.Name(),
+			ExternalID: u.UserID,
+			ChatID:     u.ChatID,
+			UserUID:    c.UserUID,
+			Username:   u.Username,
+		})
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return text(textBadCode)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to link account: "+err.Error())
+		return text(textFailed)
+	}
+	log.InfoContext(ctx, "linked account", slog.String("userUID", userUID))
+
+	greeting := "Готово, аккаунт привязан."
+	if user, err := b.repo.SelectUserByUID(ctx, userUID); err == nil {
+		greeting = "Готово, " + user.Name + ", аккаунт привязан."
+	}
+	return text(greeting + "\n\n" + textHelp)
+}
+
+func (b *Bot) unlink(ctx context.Context, log *slog.Logger, account models.BotAccount) []Message {
+	if err := b.repo.DeleteBotAccount(ctx, account.Platform, account.ExternalID); err != nil &&
+		!errors.Is(err, repository.ErrNotFound) {
+		log.ErrorContext(ctx, "failed to unlink account: "+err.Error())
+		return text(textFailed)
+	}
+	log.InfoContext(ctx, "unlinked account", slog.String("userUID", account.UserUID))
+	return text(textUnlinked)
+}
+
+// submit publishes an idea: "[#category] name" on the first line, the text
+// on the following ones, the name doubles as the text when there are none.
+// New ideas get the first status, and the first category unless one is
+// given.
+func (b *Bot) submit(ctx context.Context, log *slog.Logger, account models.BotAccount, args string) []Message {
+	categories := b.ideas.GetIdeaCategories()
+	statuses := b.ideas.GetIdeaStatuses()
+	if len(categories) == 0 || len(statuses) == 0 {
+		log.ErrorContext(ctx, "no idea categories or statuses")
+		return text(textFailed)
+	}
+	if args == "" {
+		return text("Напишите заголовок идеи после команды, а текст — со следующей строки:\n" +
+			"/idea #2 Заголовок\nТекст идеи\n\n" + listCategories(categories))
+	}
+
+	category := categories[0].ID
+	if rest, ok := strings.CutPrefix(args, "#"); ok {
+		number, name := rest, ""
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			number, name = rest[:i], rest[i:]
+		}
+		id, err := strconv.Atoi(number)
+		if err != nil || !slices.ContainsFunc(categories, func(c models.IdeaCategory) bool { return c.ID == id }) {
+			return text("Нет категории #" + number + ".\n\n" + listCategories(categories))
+		}
+		category, args = id, strings.TrimSpace(name)
+	}
+	name, body, _ := strings.Cut(args, "\n")
+	name, body = strings.TrimSpace(name), strings.TrimSpace(body)
+	if body == "" {
+		body = name
+	}
+
+	idea, err := b.ideas.InsertIdea(ctx, name, body, account.UserUID, statuses[0].ID, category)
+	var appErr *apperr.Error
+	if errors.Is(err, apperr.ErrValidation) && errors.As(err, &appErr) {
+		return text("Идея не сохранена: " + appErr.Message)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to submit idea: "+err.Error())
+		return text(textFailed)
+	}
+	log.InfoContext(ctx, "submitted idea", slog.String("ideaUID", idea.IdeaUID))
+	return []Message{{
+		Text:    "Идея «" + idea.Name + "» опубликована.",
+		Buttons: [][]Button{{b.openButton(idea.IdeaUID)}},
+	}}
+}
+
+func listCategories(categories []models.IdeaCategory) string {
+	var sb strings.Builder
+	sb.WriteString("Категории:")
+	for _, c := range categories {
+		fmt.Fprintf(&sb, "\n#%d — %s", c.ID, c.Name)
+	}
+	return sb.String()
+}
+
+// latest shows the newest ideas, one message each with vote buttons
+func (b *Bot) latest(ctx context.Context, log *slog.Logger, account models.BotAccount) []Message {
+	ideas, err := b.ideas.GetAllIdeas(ctx, account.UserUID)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch ideas: "+err.Error())
+		return text(textFailed)
+	}
+	if len(ideas) == 0 {
+		return text(textNoIdeas)
+	}
+	slices.SortFunc(ideas, func(a, b models.Idea) int { return b.CreationDate.Compare(a.CreationDate) })
+
+	replies := make([]Message, 0, LatestIdeas)
+	for _, idea := range ideas[:min(len(ideas), LatestIdeas)] {
+		replies = append(replies, Message{
+			Text: fmt.Sprintf("«%s»\n\n%s\n\n👍 %d  👎 %d", idea.Name, excerpt(idea.Text), idea.LikeCount, idea.DislikeCount),
+			Buttons: [][]Button{
+				{{Text: "👍 Нравится", Data: dataLike + idea.IdeaUID}, {Text: "👎 Не нравится", Data: dataDislike + idea.IdeaUID}},
+				{b.openButton(idea.IdeaUID)},
+			},
+		})
+	}
+	return replies
+}
+
+func (b *Bot) openButton(ideaUID string) Button {
+	return Button{Text: "Открыть на сайте", URL: b.ideaURL(ideaUID)}
+}
+
+// press votes with a button and returns the text to answer it with
+func (b *Bot) press(ctx context.Context, log *slog.Logger, p Platform, u Update) string {
+	vote, ideaUID := models.VoteLike, ""
+	if uid, ok := strings.CutPrefix(u.Data, dataLike); ok {
+		ideaUID = uid
+	} else if uid, ok := strings.CutPrefix(u.Data, dataDislike); ok {
+		vote, ideaUID = models.VoteDislike, uid
+	} else {
+		return ""
+	}
+
+	account, err := b.repo.SelectBotAccount(ctx, p.Name(), u.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return textNotLinked
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch account: "+err.Error())
+		return textFailed
+	}
+
+	counted, err := b.ideas.Vote(ctx, ideaUID, account.UserUID, vote)
+	switch {
+	case errors.Is(err, apperr.ErrNotFound):
+		return "Идея не найдена."
+	case err != nil:
+		log.ErrorContext(ctx, "failed to vote: "+err.Error())
+		return textFailed
+	case !counted:
+		return "Вы уже голосовали за эту идею."
+	}
+	return "Голос учтён."
+}
+
+// excerpt cuts s to excerptLength characters
+func excerpt(s string) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) <= excerptLength {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:excerptLength])) + "…"
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/services/notifications"
+)
+
+// Notified forwards a comment on the user's idea, a reply to the user's
+// comment or a status change of the user's idea to every messenger account
+// the user has linked, unless the user has switched the type off in the app.
+// Messages are sent in the background so a slow messenger never holds up
+// the request, a failed one is only logged.
+func (b *Bot) Notified(ctx context.Context, n models.Notification) error {
+	switch n.Type {
+	case models.NotificationComment, models.NotificationReply, models.NotificationStatus:
+	default:
+		return nil
+	}
+
+	enabled, err := notifications.Enabled(ctx, b.repo, n.UserUID, n.Type)
+	if err != nil || !enabled {
+		return err
+	}
+	accounts, err := b.repo.SelectBotAccounts(ctx, n.UserUID)
+	if err != nil || len(accounts) == 0 {
+		return err
+	}
+	msg, err := b.notification(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	for _, a := range accounts {
+		p, ok := b.platforms[a.Platform]
+		if !ok {
+			continue
+		}
+		b.sending.Add(1)
+		go func() {
+			defer b.sending.Done()
+			b.send(ctx, p, a.ChatID, msg)
+		}()
+	}
+	return nil
+}
+
+func (b *Bot) notification(ctx context.Context, n models.Notification) (Message, error) {
+	idea, err := b.repo.SelectIdeaByUID(ctx, n.IdeaUID)
+	if err != nil {
+		return Message{}, err
+	}
+	actor, err := b.actor(ctx, n.ActorUID)
+	if err != nil {
+		return Message{}, err
+	}
+
+	var text string
+	switch n.Type {
+	case models.NotificationStatus:
+		text = actor + " изменил(а) статус вашей идеи «" + idea.Name + "»: " + b.status(n.Detail)
+	case models.NotificationComment, models.NotificationReply:
+		text = actor + " прокомментировал(а) вашу идею «" + idea.Name + "»"
+		if n.Type == models.NotificationReply {
+			text = actor + " ответил(а) на ваш комментарий к идее «" + idea.Name + "»"
+		}
+		if n.CommentUID != nil {
+			comment, err := b.repo.SelectCommentByUID(ctx, *n.CommentUID)
+			if err != nil {
+				return Message{}, err
+			}
+			text += ":\n\n" + excerpt(comment.CommentText)
+		}
+	}
+	return Message{Text: text, Buttons: [][]Button{{b.openButton(idea.IdeaUID)}}}, nil
+}
+
+// actor returns the display name of the user who caused a notification
+func (b *Bot) actor(ctx context.Context, uid string) (string, error) {
+	profiles, err := b.repo.SelectProfiles(ctx, []string{uid})
+	if err != nil || len(profiles) == 0 {
+		return "", err
+	}
+	return strings.TrimSpace(profiles[0].Name + " " + profiles[0].Surname), nil
+}
+
+func (b *Bot) status(id string) string {
+	for _, s := range b.ideas.GetIdeaStatuses() {
+		if strconv.Itoa(s.ID) == id {
+			return s.Name
+		}
+	}
+	return id
+}
@@ -0,0 +1,243 @@
+// Package telegram connects the bot to Telegram through the Bot API, with
+// updates either long-polled with getUpdates or posted to the webhook.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/services/bot"
+)
+
+// Name of the platform in linked accounts and the webhook URL
+const Name = "telegram"
+
+const (
+	// PollTimeout - time getUpdates waits for an update before returning
+	// empty
+	PollTimeout = 30 * time.Second
+	// RetryDelay - wait after a failed getUpdates
+	RetryDelay = 5 * time.Second
+)
+
+// HeaderSecret carries the secret token given to setWebhook in every update
+// Telegram posts
+const HeaderSecret = "X-Telegram-Bot-Api-Secret-Token"
+
+const maxUpdateSize = 1 << 20
+
+type Client struct {
+	log      slog.Logger
+	token    string
+	username string
+	secret   string
+	apiURL   string
+	http     *http.Client
+	offset   int64
+}
+
+// New returns a client of the bot with token. Username builds t.me links,
+// secret is the token the webhook was set with, without one the webhook
+// accepts nothing and updates have to be polled.
+func New(log slog.Logger, token, username, secret string) *Client {
+	return &Client{
+		log:      log,
+		token:    token,
+		username: username,
+		secret:   secret,
+		apiURL:   "https://api.telegram.org",
+		http:     &http.Client{Timeout: PollTimeout + 10*time.Second},
+	}
+}
+
+func (c *Client) Name() string {
+	return Name
+}
+
+// Link opens a chat with the bot, pressing Start sends it "/start code"
+func (c *Client) Link(code string) string {
+	if c.username == "" {
+		return ""
+	}
+	return "https://t.me/" + c.username + "?start=" + code
+}
+
+// update - the part of a Telegram Update the bot reads
+type update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *message       `json:"message"`
+	CallbackQuery *callbackQuery `json:"callback_query"`
+}
+
+type message struct {
+	From *user  `json:"from"`
+	Chat chat   `json:"chat"`
+	Text string `json:"text"`
+}
+
+type callbackQuery struct {
+	ID      string   `json:"id"`
+	From    user     `json:"from"`
+	Message *message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+type user struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type chat struct {
+	ID int64 `json:"id"`
+}
+
+// convert returns the update as the bot sees it, updates of other kinds
+// come back without ChatID
+func (u update) convert() bot.Update {
+	switch {
+	case u.Message != nil && u.Message.From != nil:
+		return bot.Update{
+			ChatID:   strconv.FormatInt(u.Message.Chat.ID, 10),
+			UserID:   strconv.FormatInt(u.Message.From.ID, 10),
+			Username: u.Message.From.Username,
+			Text:     u.Message.Text,
+		}
+	case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+		q := u.CallbackQuery
+		return bot.Update{
+			ChatID:     strconv.FormatInt(q.Message.Chat.ID, 10),
+			UserID:     strconv.FormatInt(q.From.ID, 10),
+			Username:   q.From.Username,
+			CallbackID: q.ID,
+			Data:       q.Data,
+		}
+	}
+	return bot.Update{}
+}
+
+// Poll calls getUpdates in a loop, a failed call is retried after
+// RetryDelay
+func (c *Client) Poll(ctx context.Context, handle func(context.Context, bot.Update)) error {
+	for {
+		var updates []update
+		err := c.call(ctx, "getUpdates", map[string]any{
+			"offset":          c.offset,
+			"timeout":         int(PollTimeout.Seconds()),
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.log.ErrorContext(ctx, "failed to get updates: "+err.Error())
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(RetryDelay):
+			}
+			continue
+		}
+		for _, u := range updates {
+			c.offset = u.UpdateID + 1
+			handle(ctx, u.convert())
+		}
+	}
+}
+
+// Parse checks the secret token and decodes the update
+func (c *Client) Parse(r *http.Request) (bot.Update, error) {
+	got := r.Header.Get(HeaderSecret)
+	if c.secret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(c.secret)) != 1 {
+		return bot.Update{}, apperr.Unauthorized("invalid secret token")
+	}
+	var u update
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateSize)).Decode(&u); err != nil {
+		return bot.Update{}, apperr.Validation("invalid update").WithCause(err)
+	}
+	return u.convert(), nil
+}
+
+type inlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+func (c *Client) Send(ctx context.Context, chatID string, msg bot.Message) error {
+	params := map[string]any{
+		"chat_id":              chatID,
+		"text":                 msg.Text,
+		"link_preview_options": map[string]bool{"is_disabled": true},
+	}
+	if len(msg.Buttons) > 0 {
+		keyboard := make([][]inlineButton, 0, len(msg.Buttons))
+		for _, row := range msg.Buttons {
+			buttons := make([]inlineButton, 0, len(row))
+			for _, b := range row {
+				buttons = append(buttons, inlineButton{Text: b.Text, CallbackData: b.Data, URL: b.URL})
+			}
+			keyboard = append(keyboard, buttons)
+		}
+		params["reply_markup"] = map[string]any{"inline_keyboard": keyboard}
+	}
+	return c.call(ctx, "sendMessage", params, nil)
+}
+
+func (c *Client) Answer(ctx context.Context, callbackID, text string) error {
+	return c.call(ctx, "answerCallbackQuery", map[string]any{
+		"callback_query_id": callbackID,
+		"text":              text,
+	}, nil)
+}
+
+// call invokes a Bot API method and decodes its result into result unless
+// it is nil
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// the URL holds the token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("telegram %s: %s", method, resp.Status)
+	}
+	if !reply.OK {
+		return fmt.Errorf("telegram %s: %s", method, reply.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
@@ -0,0 +1,155 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/services/bot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const updates = `[
+	{"update_id": 10, "message": {"message_id": 1, "from": {"id": 42, "username": "alice"}, "chat": {"id": 42}, "text": "/ideas"}},
+	{"update_id": 11, "callback_query": {"id": "cb1", "from": {"id": 42}, "message": {"chat": {"id": 42}}, "data": "like:uid"}},
+	{"update_id": 12, "edited_message": {"message_id": 1, "chat": {"id": 42}, "text": "/idea"}}
+]`
+
+// api - Bot API answering the first getUpdates with updates and recording
+// calls of other methods
+type api struct {
+	*httptest.Server
+	mu      sync.Mutex
+	offsets []float64
+	calls   map[string][]map[string]any
+}
+
+func newAPI(t *testing.T) *api {
+	a := &api{calls: map[string][]map[string]any{}}
+	a.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, ok := strings.CutPrefix(r.URL.Path, "/bottoken/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var params map[string]any
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &params))
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		switch method {
+		case "getUpdates":
+			a.offsets = append(a.offsets, params["offset"].(float64))
+			result := "[]"
+			if len(a.offsets) == 1 {
+				result = updates
+			}
+			_, _ = io.WriteString(w, `{"ok": true, "result": `+result+`}`)
+		case "sendMessage":
+			if params["chat_id"] == "blocked" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(w, `{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`)
+				return
+			}
+			fallthrough
+		default:
+			a.calls[method] = append(a.calls[method], params)
+			_, _ = io.WriteString(w, `{"ok": true, "result": true}`)
+		}
+	}))
+	t.Cleanup(a.Close)
+	return a
+}
+
+func newClient(a *api, secret string) *Client {
+	c := New(*slog.Default(), "token", "agora_bot", secret)
+	c.apiURL = a.URL
+	return c
+}
+
+func TestPoll(t *testing.T) {
+	a := newAPI(t)
+	c := newClient(a, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []bot.Update
+	err := c.Poll(ctx, func(ctx context.Context, u bot.Update) {
+		got = append(got, u)
+		if len(got) == 3 {
+			cancel()
+		}
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []bot.Update{
+		{ChatID: "42", UserID: "42", Username: "alice", Text: "/ideas"},
+		{ChatID: "42", UserID: "42", CallbackID: "cb1", Data: "like:uid"},
+		{}, // edited messages aren't read
+	}, got)
+	assert.Equal(t, []float64{0}, a.offsets)
+	assert.Equal(t, int64(13), c.offset, "the next call confirms the updates")
+}
+
+func TestSend(t *testing.T) {
+	ctx := context.Background()
+	a := newAPI(t)
+	c := newClient(a, "")
+
+	require.NoError(t, c.Send(ctx, "42", bot.Message{
+		Text: "«idea»",
+		Buttons: [][]bot.Button{
+			{{Text: "👍", Data: "like:uid"}, {Text: "👎", Data: "dislike:uid"}},
+			{{Text: "Открыть", URL: "https://agora.example.com/ideas/uid"}},
+		},
+	}))
+	require.NoError(t, c.Send(ctx, "42", bot.Message{Text: "plain"}))
+	require.NoError(t, c.Answer(ctx, "cb1", "Голос учтён."))
+	err := c.Send(ctx, "blocked", bot.Message{Text: "plain"})
+	assert.EqualError(t, err, "telegram sendMessage: Forbidden: bot was blocked by the user")
+
+	sent := a.calls["sendMessage"]
+	require.Len(t, sent, 2)
+	keyboard, _ := json.Marshal(sent[0]["reply_markup"])
+	assert.JSONEq(t, `{"inline_keyboard": [
+		[{"text": "👍", "callback_data": "like:uid"}, {"text": "👎", "callback_data": "dislike:uid"}],
+		[{"text": "Открыть", "url": "https://agora.example.com/ideas/uid"}]
+	]}`, string(keyboard))
+	assert.Equal(t, "42", sent[0]["chat_id"])
+	assert.NotContains(t, sent[1], "reply_markup")
+	assert.Equal(t, []map[string]any{{"callback_query_id": "cb1", "text": "Голос учтён."}}, a.calls["answerCallbackQuery"])
+
+	assert.Equal(t, "https://t.me/agora_bot?start=CODE", c.Link("CODE"))
+	assert.Empty(t, New(*slog.Default(), "token", "", "").Link("CODE"))
+}
+
+func TestParse(t *testing.T) {
+	body := `{"update_id": 1, "message": {"from": {"id": 7}, "chat": {"id": -100}, "text": "/ideas"}}`
+	request := func(secret string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/bot/telegram/webhook", strings.NewReader(body))
+		if secret != "" {
+			r.Header.Set(HeaderSecret, secret)
+		}
+		return r
+	}
+
+	c := New(*slog.Default(), "token", "agora_bot", "webhook-secret")
+	u, err := c.Parse(request("webhook-secret"))
+	require.NoError(t, err)
+	assert.Equal(t, bot.Update{ChatID: "-100", UserID: "7", Text: "/ideas"}, u)
+
+	_, err = c.Parse(request("wrong"))
+	assert.ErrorIs(t, err, apperr.ErrUnauthorized)
+	_, err = c.Parse(request(""))
+	assert.ErrorIs(t, err, apperr.ErrUnauthorized)
+	_, err = New(*slog.Default(), "token", "agora_bot", "").Parse(request(""))
+	assert.ErrorIs(t, err, apperr.ErrUnauthorized, "polling, the webhook is off")
+}
@@ -0,0 +1,182 @@
+// Package campaigns manages themed calls for ideas. Admins set a campaign's
+// dates, target categories and eligible positions; submitting and voting
+// within those rules is enforced by the ideas service, this one shows
+// campaign pages with their stats and leaderboards.
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+const (
+	// PageLeaderboard - places shown on the campaign page
+	PageLeaderboard = 10
+	// MaxLeaderboard - places returned by GetLeaderboard at most
+	MaxLeaderboard = 100
+)
+
+type Campaigns struct {
+	log  slog.Logger
+	repo repository.Repository
+	now  func() time.Time
+}
+
+func New(log slog.Logger, repo repository.Repository) *Campaigns {
+	return &Campaigns{
+		log:  log,
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+// GetCampaigns returns every campaign, latest to start first
+func (c *Campaigns) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	campaigns, err := c.repo.SelectCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := c.now()
+	for k := range campaigns {
+		campaigns[k].State = campaigns[k].StateAt(now)
+	}
+	if campaigns == nil {
+		campaigns = []models.Campaign{}
+	}
+	return campaigns, nil
+}
+
+// GetCampaign returns the campaign page: the campaign, its stats and the top
+// PageLeaderboard places
+func (c *Campaigns) GetCampaign(ctx context.Context, id int64) (models.CampaignPage, error) {
+	campaign, err := c.campaign(ctx, id)
+	if err != nil {
+		return models.CampaignPage{}, err
+	}
+	stats, err := c.repo.SelectCampaignStats(ctx, id)
+	if err != nil {
+		return models.CampaignPage{}, err
+	}
+	leaderboard, err := c.leaderboard(ctx, id, PageLeaderboard)
+	if err != nil {
+		return models.CampaignPage{}, err
+	}
+	return models.CampaignPage{Campaign: campaign, Stats: stats, Leaderboard: leaderboard}, nil
+}
+
+// GetLeaderboard returns up to limit places of the campaign, PageLeaderboard
+// when limit isn't positive
+func (c *Campaigns) GetLeaderboard(ctx context.Context, id int64, limit int) ([]models.LeaderboardEntry, error) {
+	if _, err := c.campaign(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = PageLeaderboard
+	}
+	return c.leaderboard(ctx, id, min(limit, MaxLeaderboard))
+}
+
+// leaderboard ranks ideas by score, ideas with equal scores share a place
+// and the next one skips as many places ("1, 2, 2, 4")
+func (c *Campaigns) leaderboard(ctx context.Context, id int64, limit int) ([]models.LeaderboardEntry, error) {
+	ideas, err := c.repo.SelectCampaignLeaderboard(ctx, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]models.LeaderboardEntry, 0, len(ideas))
+	for k, idea := range ideas {
+		entry := models.LeaderboardEntry{Place: k + 1, Score: idea.LikeCount - idea.DislikeCount, Idea: idea}
+		if k > 0 && entries[k-1].Score == entry.Score {
+			entry.Place = entries[k-1].Place
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// CreateCampaign saves a campaign of actorUID
+func (c *Campaigns) CreateCampaign(ctx context.Context, actorUID string, req models.CampaignRequest) (models.Campaign, error) {
+	op := "CampaignsCreate"
+	log := c.log.With(slog.String("op", op), slog.String("actorUID", actorUID))
+
+	campaign := models.Campaign{CreatedBy: &actorUID}
+	if err := apply(&campaign, req); err != nil {
+		return models.Campaign{}, err
+	}
+	campaign, err := c.repo.InsertCampaign(ctx, campaign)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create campaign: "+err.Error())
+		return models.Campaign{}, err
+	}
+	log.InfoContext(ctx, "successfully created campaign", slog.Int64("campaignID", campaign.ID))
+	campaign.State = campaign.StateAt(c.now())
+	return campaign, nil
+}
+
+// UpdateCampaign changes the campaign, ideas already submitted stay in it
+// whatever the new rules are
+func (c *Campaigns) UpdateCampaign(ctx context.Context, id int64, req models.CampaignRequest) (models.Campaign, error) {
+	op := "CampaignsUpdate"
+	log := c.log.With(slog.String("op", op), slog.Int64("campaignID", id))
+
+	campaign, err := c.campaign(ctx, id)
+	if err != nil {
+		return models.Campaign{}, err
+	}
+	if err := apply(&campaign, req); err != nil {
+		return models.Campaign{}, err
+	}
+	if err := c.repo.UpdateCampaign(ctx, campaign); err != nil {
+		log.ErrorContext(ctx, "failed to update campaign: "+err.Error())
+		return models.Campaign{}, err
+	}
+	log.InfoContext(ctx, "successfully updated campaign")
+	campaign.State = campaign.StateAt(c.now())
+	return campaign, nil
+}
+
+// DeleteCampaign deletes the campaign, its ideas stay without one
+func (c *Campaigns) DeleteCampaign(ctx context.Context, id int64) error {
+	err := c.repo.DeleteCampaign(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("campaign not found")
+	}
+	return err
+}
+
+func (c *Campaigns) campaign(ctx context.Context, id int64) (models.Campaign, error) {
+	campaign, err := c.repo.SelectCampaign(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Campaign{}, apperr.NotFound("campaign not found")
+	}
+	campaign.State = campaign.StateAt(c.now())
+	return campaign, err
+}
+
+// apply copies the request into the campaign, lists are kept sorted and
+// without repeats
+func apply(campaign *models.Campaign, req models.CampaignRequest) error {
+	if !req.StartsAt.Before(req.EndsAt) {
+		return apperr.Validation("the campaign has to end after it starts")
+	}
+	campaign.Title = req.Title
+	campaign.Description = req.Description
+	campaign.StartsAt = req.StartsAt
+	campaign.EndsAt = req.EndsAt
+	campaign.CategoryIDs = ids(req.CategoryIDs)
+	campaign.PositionIDs = ids(req.PositionIDs)
+	return nil
+}
+
+func ids(list []int) models.IDs {
+	res := append(models.IDs{}, list...)
+	slices.Sort(res)
+	return slices.Compact(res)
+}
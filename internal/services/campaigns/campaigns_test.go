@@ -0,0 +1,125 @@
+package campaigns
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+func setup(t *testing.T) (*Campaigns, *memory.Repository, models.User) {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	c := New(*slog.Default(), repo)
+	c.now = func() time.Time { return now }
+	return c, repo, repotest.User(t, repo, "admin@example.com")
+}
+
+func request(title string, starts, ends time.Time) models.CampaignRequest {
+	return models.CampaignRequest{Title: title, StartsAt: starts, EndsAt: ends}
+}
+
+func TestCampaigns(t *testing.T) {
+	ctx := context.Background()
+	c, _, admin := setup(t)
+
+	req := request("Energy", now.Add(-time.Hour), now.Add(time.Hour))
+	req.CategoryIDs = []int{2, 1, 2}
+	created, err := c.CreateCampaign(ctx, admin.UID, req)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignOpen, created.State)
+	assert.Equal(t, models.IDs{1, 2}, created.CategoryIDs)
+	require.NotNil(t, created.CreatedBy)
+	assert.Equal(t, admin.UID, *created.CreatedBy)
+
+	_, err = c.CreateCampaign(ctx, admin.UID, request("Backwards", now, now))
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = c.CreateCampaign(ctx, admin.UID, request("Onboarding", now.Add(time.Hour), now.Add(2*time.Hour)))
+	require.NoError(t, err)
+
+	updated, err := c.UpdateCampaign(ctx, created.ID, request("Energy costs", now.Add(-2*time.Hour), now.Add(-time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignClosed, updated.State)
+	assert.Empty(t, updated.CategoryIDs)
+	_, err = c.UpdateCampaign(ctx, created.ID+100, req)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+
+	campaigns, err := c.GetCampaigns(ctx)
+	require.NoError(t, err)
+	require.Len(t, campaigns, 2)
+	assert.Equal(t, "Onboarding", campaigns[0].Title)
+	assert.Equal(t, models.CampaignUpcoming, campaigns[0].State)
+	assert.Equal(t, "Energy costs", campaigns[1].Title)
+	assert.Equal(t, models.CampaignClosed, campaigns[1].State)
+
+	require.NoError(t, c.DeleteCampaign(ctx, created.ID))
+	assert.ErrorIs(t, c.DeleteCampaign(ctx, created.ID), apperr.ErrNotFound)
+	_, err = c.GetCampaign(ctx, created.ID)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestGetCampaign(t *testing.T) {
+	ctx := context.Background()
+	c, repo, admin := setup(t)
+	campaign, err := c.CreateCampaign(ctx, admin.UID, request("Energy", now.Add(-time.Hour), now.Add(time.Hour)))
+	require.NoError(t, err)
+
+	submit := func(likes, dislikes int) string {
+		t.Helper()
+		idea := models.Idea{
+			IdeaUID: uuid.NewString(), Name: "idea", Text: "text", Author: admin.UID,
+			StatusID: 1, CategoryID: 1, CampaignID: &campaign.ID,
+		}
+		require.NoError(t, repo.InsertIdea(ctx, idea))
+		for v := 0; v < likes+dislikes; v++ {
+			voter := repotest.User(t, repo, uuid.NewString()+"@example.com")
+			_, err := repo.CheckVote(ctx, idea.IdeaUID, voter.UID)
+			require.NoError(t, err)
+			if v < likes {
+				require.NoError(t, repo.IncrementLikeCount(ctx, idea.IdeaUID))
+			} else {
+				require.NoError(t, repo.IncrementDislikeCount(ctx, idea.IdeaUID))
+			}
+		}
+		return idea.IdeaUID
+	}
+	// scores 1, 1 (with more likes), 2 and 0
+	uids := []string{submit(1, 0), submit(2, 1), submit(2, 0), submit(0, 0)}
+
+	page, err := c.GetCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign.ID, page.Campaign.ID)
+	assert.Equal(t, models.CampaignOpen, page.Campaign.State)
+	assert.Equal(t, 4, page.Stats.Ideas)
+	assert.Equal(t, 1, page.Stats.Authors)
+	assert.Equal(t, 6, page.Stats.Voters)
+	assert.Equal(t, 5, page.Stats.Likes)
+	assert.Equal(t, 1, page.Stats.Dislikes)
+
+	type place struct {
+		Place, Score int
+		UID          string
+	}
+	var got []place
+	for _, e := range page.Leaderboard {
+		got = append(got, place{e.Place, e.Score, e.Idea.IdeaUID})
+	}
+	assert.Equal(t, []place{{1, 2, uids[2]}, {2, 1, uids[1]}, {2, 1, uids[0]}, {4, 0, uids[3]}}, got)
+
+	top, err := c.GetLeaderboard(ctx, campaign.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, uids[2], top[0].Idea.IdeaUID)
+	_, err = c.GetLeaderboard(ctx, campaign.ID+100, 1)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
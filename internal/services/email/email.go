@@ -0,0 +1,383 @@
+// Package email sends notifications by email: mentions and status changes
+// right away, trending ideas as a daily or weekly digest, and invitations of
+// imported users. Emails are rendered into a persistent outbox first and
+// delivered from it with retries, so a slow or failing SMTP server never
+// holds up the request that caused them.
+package email
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// Delivery settings
+const (
+	// Batch - emails claimed from the outbox at once
+	Batch = 20
+	// Lease - time a claimed email is hidden from other workers, it is
+	// retried afterwards if the worker died while sending it
+	Lease = 5 * time.Minute
+	// SendTimeout bounds delivery of a single email
+	SendTimeout = 30 * time.Second
+	// DigestSize - ideas in a digest
+	DigestSize = 10
+	// DispatchInterval - how often Run sends due emails
+	DispatchInterval = 30 * time.Second
+)
+
+// MaxAttempts - attempts to deliver an email before it is given up
+const MaxAttempts = 5
+
+// retryDelays - waits after the 1st, 2nd... failed attempt
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// digestPeriods - votes counted in a digest, they also space digests out.
+// Digests are queued by the hour so a user is due a little early rather
+// than an hour late.
+var digestPeriods = []struct {
+	frequency models.DigestFrequency
+	period    time.Duration
+}{
+	{models.DigestDaily, 24 * time.Hour},
+	{models.DigestWeekly, 7 * 24 * time.Hour},
+}
+
+const digestSlack = time.Hour
+
+type Mailer struct {
+	log     slog.Logger
+	repo    repository.Repository
+	sender  Sender
+	baseURL string
+	now     func() time.Time
+}
+
+// New returns a mailer delivering through sender, links in emails point to
+// the frontend at baseURL. Without a sender only settings are served, neither
+// Notified nor Run may be used then.
+func New(log slog.Logger, repo repository.Repository, sender Sender, baseURL string) *Mailer {
+	return &Mailer{
+		log:     log,
+		repo:    repo,
+		sender:  sender,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		now:     time.Now,
+	}
+}
+
+func (m *Mailer) ideaURL(uid string) string {
+	return m.baseURL + "/ideas/" + uid
+}
+
+func (m *Mailer) settingsURL() string {
+	return m.baseURL + "/settings/notifications"
+}
+
+func (m *Mailer) inviteURL(token string) string {
+	return m.baseURL + "/invite?token=" + url.QueryEscape(token)
+}
+
+// Invite queues an invitation of an imported user to set a password by the
+// link with token. It is queued whatever the user's settings, an account
+// can't be used without it.
+func (m *Mailer) Invite(ctx context.Context, user models.User, token string, expiresAt time.Time) error {
+	settings, err := m.settings(ctx, user.UID)
+	if err != nil {
+		return err
+	}
+	msg, err := render(models.EmailInvitation, settings.Language, invitationData{
+		common:    common{Lang: language(settings.Language), Name: user.Name},
+		Email:     user.Email,
+		InviteURL: m.inviteURL(token),
+		ExpiresAt: expiresAt.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return m.repo.InsertOutboxEmail(ctx, m.outboxEmail(user.UID, user.Email, models.EmailInvitation, msg))
+}
+
+// Notified queues an email about a new mention or status change unless the
+// user has switched immediate emails off, other notifications are ignored
+func (m *Mailer) Notified(ctx context.Context, n models.Notification) error {
+	var kind models.EmailKind
+	switch n.Type {
+	case models.NotificationMention:
+		kind = models.EmailMention
+	case models.NotificationStatus:
+		kind = models.EmailStatus
+	default:
+		return nil
+	}
+
+	settings, err := m.settings(ctx, n.UserUID)
+	if err != nil || !settings.Immediate {
+		return err
+	}
+	user, err := m.repo.SelectUserByUID(ctx, n.UserUID)
+	if err != nil {
+		return err
+	}
+	idea, err := m.repo.SelectIdeaByUID(ctx, n.IdeaUID)
+	if err != nil {
+		return err
+	}
+	actor, err := m.actor(ctx, n.ActorUID)
+	if err != nil {
+		return err
+	}
+
+	base := common{Lang: language(settings.Language), Name: user.Name, SettingsURL: m.settingsURL()}
+	var data any
+	if kind == models.EmailMention {
+		data = mentionData{
+			common:    base,
+			Actor:     actor,
+			IdeaName:  idea.Name,
+			IdeaURL:   m.ideaURL(idea.IdeaUID),
+			InComment: n.CommentUID != nil,
+		}
+	} else {
+		status, err := m.status(ctx, n.Detail)
+		if err != nil {
+			return err
+		}
+		data = statusData{
+			common:   base,
+			Actor:    actor,
+			IdeaName: idea.Name,
+			IdeaURL:  m.ideaURL(idea.IdeaUID),
+			Status:   status,
+		}
+	}
+
+	msg, err := render(kind, settings.Language, data)
+	if err != nil {
+		return err
+	}
+	return m.repo.InsertOutboxEmail(ctx, m.outboxEmail(user.UID, user.Email, kind, msg))
+}
+
+func (m *Mailer) outboxEmail(userUID, to string, kind models.EmailKind, msg Message) models.OutboxEmail {
+	return models.OutboxEmail{
+		UserUID:       userUID,
+		Kind:          kind,
+		To:            to,
+		Subject:       msg.Subject,
+		TextBody:      msg.Text,
+		HTMLBody:      msg.HTML,
+		NextAttemptAt: m.now(),
+	}
+}
+
+// settings returns the user's settings or the defaults
+func (m *Mailer) settings(ctx context.Context, userUID string) (models.EmailSettings, error) {
+	settings, err := m.repo.SelectEmailSettings(ctx, userUID)
+	if errors.Is(err, repository.ErrNotFound) {
+		settings = models.DefaultEmailSettings
+		settings.UserUID = userUID
+		return settings, nil
+	}
+	return settings, err
+}
+
+// actor returns the display name of the user who caused a notification
+func (m *Mailer) actor(ctx context.Context, uid string) (string, error) {
+	profiles, err := m.repo.SelectProfiles(ctx, []string{uid})
+	if err != nil || len(profiles) == 0 {
+		return "", err
+	}
+	return strings.TrimSpace(profiles[0].Name + " " + profiles[0].Surname), nil
+}
+
+func (m *Mailer) status(ctx context.Context, id string) (string, error) {
+	statuses, err := m.repo.SelectIdeaStatuses(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range statuses {
+		if strconv.Itoa(s.ID) == id {
+			return s.Name, nil
+		}
+	}
+	return id, nil
+}
+
+// SendPending delivers due emails from the outbox and returns how many were
+// sent. A failed email is retried after retryDelays, a permanent rejection
+// or MaxAttempts failures give it up.
+func (m *Mailer) SendPending(ctx context.Context) (int, error) {
+	op := "EmailSendPending"
+	log := m.log.With(slog.String("op", op))
+
+	sent := 0
+	for {
+		now := m.now()
+		emails, err := m.repo.ClaimOutboxEmails(ctx, now, now.Add(Lease), Batch)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to claim emails: "+err.Error())
+			return sent, err
+		}
+		for _, e := range emails {
+			if m.deliver(ctx, log, e) {
+				sent++
+			}
+		}
+		if len(emails) < Batch {
+			return sent, nil
+		}
+	}
+}
+
+func (m *Mailer) deliver(ctx context.Context, log *slog.Logger, e models.OutboxEmail) bool {
+	log = log.With(slog.Int64("emailID", e.ID))
+
+	sendCtx, cancel := context.WithTimeout(ctx, SendTimeout)
+	err := m.sender.Send(sendCtx, Message{To: e.To, Subject: e.Subject, Text: e.TextBody, HTML: e.HTMLBody})
+	cancel()
+	if err == nil {
+		if err := m.repo.MarkOutboxEmailSent(ctx, e.ID); err != nil {
+			// the lease runs out and the email goes again, better than never
+			log.ErrorContext(ctx, "failed to mark email sent: "+err.Error())
+		}
+		return true
+	}
+
+	var retryAt *time.Time
+	if e.Attempts < MaxAttempts && !permanent(err) {
+		at := m.now().Add(retryDelays[min(e.Attempts, len(retryDelays))-1])
+		retryAt = &at
+		log.WarnContext(ctx, "failed to send email, will retry: "+err.Error())
+	} else {
+		log.ErrorContext(ctx, "failed to send email, giving up: "+err.Error())
+	}
+	if err := m.repo.FailOutboxEmail(ctx, e.ID, err.Error(), retryAt); err != nil {
+		log.ErrorContext(ctx, "failed to record email failure: "+err.Error())
+	}
+	return false
+}
+
+// SendDigests queues digests for every user due one and returns how many
+// were queued. Nobody gets a digest for a period without votes.
+func (m *Mailer) SendDigests(ctx context.Context) (int, error) {
+	op := "EmailSendDigests"
+	log := m.log.With(slog.String("op", op))
+
+	queued := 0
+	for _, p := range digestPeriods {
+		now := m.now()
+		trending, err := m.repo.SelectTrendingIdeas(ctx, now.Add(-p.period), DigestSize)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to fetch trending ideas: "+err.Error())
+			return queued, err
+		}
+		if len(trending) == 0 {
+			continue
+		}
+		ideas := make([]digestIdea, 0, len(trending))
+		for _, t := range trending {
+			ideas = append(ideas, digestIdea{
+				Name:        t.Name,
+				URL:         m.ideaURL(t.IdeaUID),
+				Likes:       t.LikeCount,
+				Dislikes:    t.DislikeCount,
+				RecentVotes: t.RecentVotes,
+			})
+		}
+
+		// every queued digest moves its recipient out of the next page
+		for {
+			recipients, err := m.repo.SelectDigestRecipients(ctx, p.frequency, now.Add(-p.period+digestSlack), Batch)
+			if err != nil {
+				log.ErrorContext(ctx, "failed to fetch digest recipients: "+err.Error())
+				return queued, err
+			}
+			for _, r := range recipients {
+				if err := m.queueDigest(ctx, r, p.frequency, ideas, now); err != nil {
+					log.ErrorContext(ctx, "failed to queue digest: "+err.Error(), slog.String("userUID", r.UserUID))
+					return queued, err
+				}
+				queued++
+			}
+			if len(recipients) < Batch {
+				break
+			}
+		}
+	}
+	if queued > 0 {
+		log.InfoContext(ctx, "queued digests", slog.Int("count", queued))
+	}
+	return queued, nil
+}
+
+func (m *Mailer) queueDigest(ctx context.Context, r models.DigestRecipient, frequency models.DigestFrequency, ideas []digestIdea, now time.Time) error {
+	msg, err := render(models.EmailDigest, r.Language, digestData{
+		common: common{Lang: language(r.Language), Name: r.Name, SettingsURL: m.settingsURL()},
+		Weekly: frequency == models.DigestWeekly,
+		Ideas:  ideas,
+	})
+	if err != nil {
+		return err
+	}
+	return m.repo.WithTx(ctx, func(repo repository.Repository) error {
+		if err := repo.InsertOutboxEmail(ctx, m.outboxEmail(r.UserUID, r.Email, models.EmailDigest, msg)); err != nil {
+			return err
+		}
+		return repo.MarkDigestSent(ctx, r.UserUID, now)
+	})
+}
+
+// Run delivers the outbox every DispatchInterval until ctx is done.
+// Digests are queued by the email-digests job.
+func (m *Mailer) Run(ctx context.Context) {
+	dispatch := time.NewTicker(DispatchInterval)
+	defer dispatch.Stop()
+
+	// errors are logged by SendPending
+	_, _ = m.SendPending(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dispatch.C:
+			_, _ = m.SendPending(ctx)
+		}
+	}
+}
+
+// GetSettings returns the user's email settings, the defaults if the user
+// hasn't changed them
+func (m *Mailer) GetSettings(ctx context.Context, userUID string) (models.EmailSettings, error) {
+	return m.settings(ctx, userUID)
+}
+
+// SetSettings saves the user's email settings
+func (m *Mailer) SetSettings(ctx context.Context, settings models.EmailSettings) (models.EmailSettings, error) {
+	op := "EmailSetSettings"
+	log := m.log.With(slog.String("op", op), slog.String("userUID", settings.UserUID))
+
+	switch settings.Digest {
+	case models.DigestOff, models.DigestDaily, models.DigestWeekly:
+	default:
+		return models.EmailSettings{}, apperr.Validation("unknown digest frequency " + string(settings.Digest))
+	}
+	if language(settings.Language) != settings.Language {
+		return models.EmailSettings{}, apperr.Validation("unsupported language " + settings.Language)
+	}
+
+	if err := m.repo.UpsertEmailSettings(ctx, settings); err != nil {
+		log.ErrorContext(ctx, "failed to save email settings: "+err.Error())
+		return models.EmailSettings{}, err
+	}
+	log.InfoContext(ctx, "successfully saved email settings")
+	return m.settings(ctx, settings.UserUID)
+}
@@ -0,0 +1,317 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/TP2-Voice-Agora/backend/internal/services/notifications"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setup wires the mailer into the notification center, like main does
+func setup(t *testing.T) (*Mailer, *Capture, *ideas.Ideas, *memory.Repository) {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	capture := NewCapture(nil)
+	m := New(*slog.Default(), repo, capture, "https://agora.example.com/")
+	n := notifications.New(*slog.Default(), repo)
+	n.SetForwarder(m)
+	i, err := ideas.New(context.Background(), *slog.Default(), repo)
+	require.NoError(t, err)
+	i.SetNotifier(n)
+	return m, capture, i, repo
+}
+
+func TestCompose(t *testing.T) {
+	raw, err := compose(mail.Address{Name: "Агора", Address: "noreply@agora.example.com"}, Message{
+		To:      "user@example.com",
+		Subject: "Новый статус идеи",
+		Text:    "Здравствуйте!",
+		HTML:    "<p>Здравствуйте!</p>",
+	}, time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Новый статус идеи", subject)
+	from, err := msg.Header.AddressList("From")
+	require.NoError(t, err)
+	assert.Equal(t, "Агора", from[0].Name)
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@agora.example.com>"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part) // quoted-printable is decoded by the reader
+		require.NoError(t, err)
+		bodies = append(bodies, part.Header.Get("Content-Type")+" "+string(body))
+	}
+	assert.Equal(t, []string{
+		"text/plain; charset=utf-8 Здравствуйте!",
+		"text/html; charset=utf-8 <p>Здравствуйте!</p>",
+	}, bodies)
+}
+
+func TestRender(t *testing.T) {
+	base := common{Lang: "en", Name: "Ann", SettingsURL: "https://agora.example.com/settings"}
+	msg, err := render(models.EmailStatus, "en", statusData{
+		common: base, Actor: "Bob", IdeaName: "<b>idea</b>", IdeaURL: "https://agora.example.com/ideas/1", Status: "Done",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `Your idea "<b>idea</b>" is now Done`, msg.Subject)
+	assert.Contains(t, msg.Text, `"<b>idea</b>"`)
+	assert.Contains(t, msg.HTML, "&lt;b&gt;idea&lt;/b&gt;", "escaped in HTML")
+	assert.Contains(t, msg.HTML, `href="https://agora.example.com/settings"`)
+
+	msg, err = render(models.EmailMention, "de", mentionData{common: base, Actor: "Bob", IdeaName: "idea"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(msg.Subject, "Bob упоминает вас"), "falls back to Russian")
+
+	msg, err = render(models.EmailDigest, "ru", digestData{common: base, Weekly: true, Ideas: []digestIdea{
+		{Name: "first", URL: "https://agora.example.com/ideas/1", Likes: 3, RecentVotes: 4},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Популярные идеи за неделю", msg.Subject)
+	assert.Contains(t, msg.Text, "- first (голосов: 4, 👍 3 / 👎 0)")
+}
+
+func TestNotified(t *testing.T) {
+	ctx := context.Background()
+	m, capture, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	admin := models.User{UID: "8f0f5b8e-2b1a-4f0e-9c39-5d2f3f6b7a10", Name: "Mod", Surname: "Erator",
+		PositionID: 1, Email: "mod@example.com", Password: "hash", IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, admin))
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+	// emails have settings of their own, switching statuses off in the app
+	// leaves them be
+	_, err := notifications.New(*slog.Default(), repo).SetPreferences(ctx, author.UID,
+		[]models.NotificationPreference{{Type: models.NotificationStatus, Enabled: false}})
+	require.NoError(t, err)
+
+	_, err = i.Vote(ctx, idea.IdeaUID, bob.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", bob.UID, "comment")
+	require.NoError(t, err)
+	_, err = i.ChangeIdeaStatus(ctx, idea.IdeaUID, admin.UID, 2)
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", admin.UID, "@"+bob.UID+" look")
+	require.NoError(t, err)
+
+	// bob reads English and doesn't want immediate emails after all
+	_, err = m.SetSettings(ctx, models.EmailSettings{UserUID: bob.UID, Language: "en", Immediate: false, Digest: models.DigestOff})
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", admin.UID, "@"+bob.UID+" again")
+	require.NoError(t, err)
+
+	outbox, err := repo.SelectOutboxEmails(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, outbox, 2, "votes and comments aren't emailed")
+	assert.Equal(t, models.EmailMention, outbox[0].Kind)
+	assert.Equal(t, bob.Email, outbox[0].To)
+	assert.Equal(t, models.EmailStatus, outbox[1].Kind)
+	assert.Equal(t, author.Email, outbox[1].To)
+	assert.Contains(t, outbox[1].TextBody, "Mod Erator")
+	assert.Contains(t, outbox[1].TextBody, "https://agora.example.com/ideas/"+idea.IdeaUID)
+	assert.Empty(t, capture.Messages(), "sent only from the outbox")
+
+	sent, err := m.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	messages := capture.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, author.Email, messages[0].To)
+	assert.Equal(t, "Новый статус идеи «idea»: "+repotest.Statuses[1].Name, messages[0].Subject)
+
+	outbox, err = repo.SelectOutboxEmails(ctx, author.UID, 10)
+	require.NoError(t, err)
+	require.Len(t, outbox, 1)
+	assert.NotNil(t, outbox[0].SentAt)
+	assert.Equal(t, 1, outbox[0].Attempts)
+
+	sent, err = m.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+}
+
+func TestInvite(t *testing.T) {
+	ctx := context.Background()
+	m, capture, _, repo := setup(t)
+	u := repotest.User(t, repo, "new@example.com")
+	expires := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, m.Invite(ctx, u, "to+ken", expires))
+	sent, err := m.SendPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	msg := capture.Messages()[0]
+	assert.Equal(t, u.Email, msg.To)
+	assert.Equal(t, "Приглашение в Voice Agora", msg.Subject)
+	assert.Contains(t, msg.Text, "https://agora.example.com/invite?token=to%2Bken")
+	assert.Contains(t, msg.Text, "до 15.03.2026")
+	assert.Contains(t, msg.HTML, `href="https://agora.example.com/invite?token=to%2Bken"`)
+	assert.NotContains(t, msg.HTML, "/settings/notifications", "nothing to set up yet")
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	m, capture, _, repo := setup(t)
+	user := repotest.User(t, repo, "user@example.com")
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	queue := func() {
+		require.NoError(t, repo.InsertOutboxEmail(ctx, m.outboxEmail(user.UID, user.Email, models.EmailDigest,
+			Message{Subject: "subject", Text: "text", HTML: "html"})))
+	}
+	last := func() models.OutboxEmail {
+		outbox, err := repo.SelectOutboxEmails(ctx, user.UID, 1)
+		require.NoError(t, err)
+		require.Len(t, outbox, 1)
+		return outbox[0]
+	}
+
+	queue()
+	capture.Fail(errors.New("connection refused"))
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		sent, err := m.SendPending(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		e := last()
+		assert.Equal(t, attempt, e.Attempts)
+		assert.Equal(t, "connection refused", e.LastError)
+		if attempt < MaxAttempts {
+			require.Nil(t, e.FailedAt)
+			assert.WithinDuration(t, now.Add(retryDelays[attempt-1]), e.NextAttemptAt, time.Second)
+
+			sent, err = m.SendPending(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, sent)
+			assert.Equal(t, attempt, last().Attempts, "not due yet")
+			now = e.NextAttemptAt
+		}
+	}
+	assert.NotNil(t, last().FailedAt, "given up")
+
+	// rejected recipients aren't retried
+	queue()
+	capture.Fail(&textproto.Error{Code: 550, Msg: "no such user"})
+	_, err := m.SendPending(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, last().FailedAt)
+	assert.Equal(t, 1, last().Attempts)
+
+	queue()
+	capture.Fail(nil)
+	sent, err := m.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestDigests(t *testing.T) {
+	ctx := context.Background()
+	m, capture, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	daily := repotest.User(t, repo, "daily@example.com")
+	off := repotest.User(t, repo, "off@example.com")
+	_, err := m.SetSettings(ctx, models.EmailSettings{UserUID: daily.UID, Language: "en", Digest: models.DigestDaily})
+	require.NoError(t, err)
+	_, err = m.SetSettings(ctx, models.EmailSettings{UserUID: off.UID, Language: "ru", Digest: models.DigestOff})
+	require.NoError(t, err)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	queued, err := m.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, queued, "nothing to tell about")
+
+	popular := repotest.Idea(t, repo, author.UID, "popular")
+	repotest.Idea(t, repo, author.UID, "quiet")
+	for _, u := range []models.User{daily, off} {
+		_, err = i.Vote(ctx, popular.IdeaUID, u.UID, models.VoteLike)
+		require.NoError(t, err)
+	}
+
+	queued, err = m.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued, "daily and the author with default weekly")
+	_, err = m.SendPending(ctx)
+	require.NoError(t, err)
+	messages := capture.Messages()
+	require.Len(t, messages, 2)
+	recipients := map[string]Message{}
+	for _, msg := range messages {
+		recipients[msg.To] = msg
+	}
+	require.Contains(t, recipients, daily.Email)
+	assert.Equal(t, "Trending ideas of the day", recipients[daily.Email].Subject)
+	assert.Contains(t, recipients[daily.Email].Text, "popular (2 votes")
+	assert.NotContains(t, recipients[daily.Email].Text, "quiet")
+	require.Contains(t, recipients[author.Email].Subject, "неделю")
+
+	queued, err = m.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, queued, "already sent")
+
+	// the votes are out of the period two days later
+	start := now
+	now = start.Add(48 * time.Hour)
+	queued, err = m.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, queued)
+
+	// a day later only the daily digest is due
+	now = start.Add(23*time.Hour + time.Minute)
+	queued, err = m.SendDigests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+	outbox, err := repo.SelectOutboxEmails(ctx, "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, daily.Email, outbox[0].To)
+}
+
+func TestSettings(t *testing.T) {
+	ctx := context.Background()
+	m, _, _, repo := setup(t)
+	user := repotest.User(t, repo, "user@example.com")
+
+	settings, err := m.GetSettings(ctx, user.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "ru", settings.Language)
+	assert.True(t, settings.Immediate)
+	assert.Equal(t, models.DigestWeekly, settings.Digest)
+
+	settings, err = m.SetSettings(ctx, models.EmailSettings{UserUID: user.UID, Language: "en", Digest: models.DigestOff})
+	require.NoError(t, err)
+	assert.Equal(t, models.EmailSettings{UserUID: user.UID, Language: "en", Digest: models.DigestOff}, settings)
+
+	_, err = m.SetSettings(ctx, models.EmailSettings{UserUID: user.UID, Language: "de", Digest: models.DigestOff})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = m.SetSettings(ctx, models.EmailSettings{UserUID: user.UID, Language: "en", Digest: "hourly"})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
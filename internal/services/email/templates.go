@@ -0,0 +1,124 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// Languages emails are written in, the first one is used for anything else
+var Languages = []string{"ru", "en"}
+
+//go:embed templates
+var templateFS embed.FS
+
+// Every kind has templates/<kind>.<lang>.txt, which also defines "subject",
+// and templates/<kind>.<lang>.html with "content" and "footer" for
+// templates/layout.html
+var templates = mustParseTemplates()
+
+type templateSet struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+func mustParseTemplates() map[string]templateSet {
+	sets := make(map[string]templateSet)
+	for _, kind := range []models.EmailKind{models.EmailMention, models.EmailStatus, models.EmailDigest, models.EmailInvitation} {
+		for _, lang := range Languages {
+			name := string(kind) + "." + lang
+			text := texttemplate.Must(texttemplate.New(name+".txt").
+				Option("missingkey=error").ParseFS(templateFS, "templates/"+name+".txt"))
+			html := htmltemplate.Must(htmltemplate.New("layout.html").
+				Option("missingkey=error").ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+			sets[name] = templateSet{text: text, html: html}
+		}
+	}
+	return sets
+}
+
+// language returns lang if there are templates in it
+func language(lang string) string {
+	if slices.Contains(Languages, lang) {
+		return lang
+	}
+	return Languages[0]
+}
+
+// render executes the templates of kind in lang, data must have a Lang field
+// for the layout
+func render(kind models.EmailKind, lang string, data any) (Message, error) {
+	set, ok := templates[string(kind)+"."+language(lang)]
+	if !ok {
+		return Message{}, fmt.Errorf("no templates for %s", kind)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := set.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := set.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// common - fields of every template
+type common struct {
+	Lang        string
+	Name        string
+	SettingsURL string
+}
+
+type mentionData struct {
+	common
+	Actor     string
+	IdeaName  string
+	IdeaURL   string
+	InComment bool
+}
+
+type statusData struct {
+	common
+	Actor    string
+	IdeaName string
+	IdeaURL  string
+	Status   string
+}
+
+type digestData struct {
+	common
+	Weekly bool
+	Ideas  []digestIdea
+}
+
+type digestIdea struct {
+	Name        string
+	URL         string
+	Likes       int
+	Dislikes    int
+	RecentVotes int
+}
+
+// invitationData - SettingsURL is left empty, the invitee has nothing to set
+// up before the first sign in
+type invitationData struct {
+	common
+	Email     string
+	InviteURL string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,313 @@
+// Package export writes ideas and their discussions as CSV or XLSX for
+// reviewers preparing committee meetings. Ideas are selected by the same
+// filters as the ideas list, rows are written as they are built and
+// discussions are read idea by idea, so an export streams whatever its size.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/xlsx"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// TimeLayout - dates and times of exported rows, in UTC
+const TimeLayout = time.DateTime
+
+// batch - ideas whose counts and authors are read with one query
+const batch = 500
+
+var (
+	ideaColumns = []string{"UID", "Название", "Текст", "Категория", "Статус", "Автор", "Создана",
+		"Лайки", "Дизлайки", "Просмотры", "Комментарии", "Кампания"}
+	commentColumns = []string{"UID идеи", "Идея", "UID комментария", "Ответ на", "Уровень", "Автор", "Текст",
+		"Создан", "Изменен", "Удален"}
+)
+
+type Export struct {
+	log  slog.Logger
+	repo repository.Repository
+	now  func() time.Time
+}
+
+func New(log slog.Logger, repo repository.Repository) *Export {
+	return &Export{log: log, repo: repo, now: time.Now}
+}
+
+// ExportIdeas writes ideas matching filter with category, status and author
+// names, votes, views and comments. Nothing is written when the filter or
+// the format is invalid.
+func (e *Export) ExportIdeas(ctx context.Context, filter models.IdeaFilter, format models.ExportFormat, w io.Writer) error {
+	log := e.log.With(slog.String("op", "ExportIdeas"), slog.String("format", string(format)))
+
+	ideas, err := e.selectIdeas(ctx, filter, format)
+	if err != nil {
+		return err
+	}
+	names, err := e.dictionaries(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch dictionaries: "+err.Error())
+		return err
+	}
+
+	sh, err := newSheet(w, format, "Идеи")
+	if err != nil {
+		return err
+	}
+	if err := sh.WriteHeader(ideaColumns); err != nil {
+		return err
+	}
+	profiles := map[string]models.AuthorProfile{}
+	for chunk := range slices.Chunk(ideas, batch) {
+		uids := make([]string, len(chunk))
+		for n, idea := range chunk {
+			uids[n] = idea.IdeaUID
+		}
+		activity, err := e.repo.SelectIdeaActivity(ctx, e.now(), uids...)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to fetch comment counts: "+err.Error())
+			return err
+		}
+		comments := make(map[string]int, len(activity))
+		for _, a := range activity {
+			comments[a.IdeaUID] = a.Comments
+		}
+		if err := loadProfiles(ctx, e.repo, profiles, chunk, func(idea models.Idea) string { return idea.Author }); err != nil {
+			log.ErrorContext(ctx, "failed to fetch authors: "+err.Error())
+			return err
+		}
+
+		for _, idea := range chunk {
+			var campaign any
+			if idea.CampaignID != nil {
+				campaign = *idea.CampaignID
+			}
+			err := sh.Write([]any{idea.IdeaUID, idea.Name, idea.Text, names.categories[idea.CategoryID],
+				names.statuses[idea.StatusID], fullName(profiles, idea.Author), formatTime(&idea.CreationDate),
+				idea.LikeCount, idea.DislikeCount, idea.ViewCount, comments[idea.IdeaUID], campaign})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := sh.Close(); err != nil {
+		return err
+	}
+
+	log.InfoContext(ctx, "exported ideas", slog.Int("ideas", len(ideas)))
+	return nil
+}
+
+// ExportDiscussions writes comments of ideas matching filter, a row per
+// comment. Threads go depth first, replies right after the comment they
+// answer, ideas in the order of the list. Deleted comments stay as rows
+// without text, like in the discussion.
+func (e *Export) ExportDiscussions(ctx context.Context, filter models.IdeaFilter, format models.ExportFormat, w io.Writer) error {
+	log := e.log.With(slog.String("op", "ExportDiscussions"), slog.String("format", string(format)))
+
+	ideas, err := e.selectIdeas(ctx, filter, format)
+	if err != nil {
+		return err
+	}
+
+	sh, err := newSheet(w, format, "Обсуждения")
+	if err != nil {
+		return err
+	}
+	if err := sh.WriteHeader(commentColumns); err != nil {
+		return err
+	}
+	profiles := map[string]models.AuthorProfile{}
+	total := 0
+	for _, idea := range ideas {
+		// the client may be gone, there is no one to write the rest for
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		comments, err := e.repo.SelectIdeaComments(ctx, idea.IdeaUID)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to fetch comments: "+err.Error())
+			return err
+		}
+		if err := loadProfiles(ctx, e.repo, profiles, comments, func(c models.Comment) string { return c.AuthorID }); err != nil {
+			log.ErrorContext(ctx, "failed to fetch authors: "+err.Error())
+			return err
+		}
+
+		for _, c := range models.ThreadOrder(comments) {
+			var parent any
+			if c.ParentUID != nil {
+				parent = *c.ParentUID
+			}
+			err := sh.Write([]any{idea.IdeaUID, idea.Name, c.CommentUID, parent, c.Depth,
+				fullName(profiles, c.AuthorID), c.CommentText, formatTime(&c.Timestamp), formatTime(c.EditedAt),
+				formatTime(c.DeletedAt)})
+			if err != nil {
+				return err
+			}
+		}
+		total += len(comments)
+	}
+	if err := sh.Close(); err != nil {
+		return err
+	}
+
+	log.InfoContext(ctx, "exported discussions", slog.Int("ideas", len(ideas)), slog.Int("comments", total))
+	return nil
+}
+
+// selectIdeas checks the format and returns ideas of the list narrowed by
+// filter
+func (e *Export) selectIdeas(ctx context.Context, filter models.IdeaFilter, format models.ExportFormat) ([]models.Idea, error) {
+	if format != models.ExportCSV && format != models.ExportXLSX {
+		return nil, apperr.Validation("unknown export format " + string(format))
+	}
+
+	var ideas []models.Idea
+	var err error
+	switch {
+	case filter.Query != "":
+		if len(repository.SearchTerms(filter.Query)) == 0 {
+			return nil, apperr.Validation("search query must contain at least one word")
+		}
+		ideas, err = e.repo.SearchIdeas(ctx, filter.Query, filter.Limit)
+	case filter.Sort != "":
+		if !slices.Contains(models.IdeaSorts, filter.Sort) {
+			return nil, apperr.Validation("unknown sort " + string(filter.Sort))
+		}
+		ideas, err = e.repo.SelectRankedIdeas(ctx, filter.Sort)
+	default:
+		ideas, err = e.repo.SelectIdeas(ctx)
+	}
+	if err != nil {
+		e.log.ErrorContext(ctx, "failed to fetch ideas to export: "+err.Error())
+		return nil, err
+	}
+	return ideas, nil
+}
+
+type dictionaries struct {
+	categories map[int]string
+	statuses   map[int]string
+}
+
+func (e *Export) dictionaries(ctx context.Context) (dictionaries, error) {
+	d := dictionaries{categories: map[int]string{}, statuses: map[int]string{}}
+	categories, err := e.repo.SelectIdeaCategories(ctx)
+	if err != nil {
+		return d, err
+	}
+	for _, c := range categories {
+		d.categories[c.ID] = c.Name
+	}
+	statuses, err := e.repo.SelectIdeaStatuses(ctx)
+	if err != nil {
+		return d, err
+	}
+	for _, s := range statuses {
+		d.statuses[s.ID] = s.Name
+	}
+	return d, nil
+}
+
+// loadProfiles adds profiles of the authors of items missing in profiles
+func loadProfiles[T any](ctx context.Context, repo repository.Repository, profiles map[string]models.AuthorProfile,
+	items []T, author func(T) string) error {
+	var missing []string
+	for _, item := range items {
+		uid := author(item)
+		if _, ok := profiles[uid]; !ok && !slices.Contains(missing, uid) {
+			missing = append(missing, uid)
+		}
+	}
+	for chunk := range slices.Chunk(missing, batch) {
+		found, err := repo.SelectProfiles(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		for _, p := range found {
+			profiles[p.UID] = p
+		}
+	}
+	return nil
+}
+
+// fullName - name and surname of the author, empty for removed users
+func fullName(profiles map[string]models.AuthorProfile, uid string) string {
+	p, ok := profiles[uid]
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(p.Name + " " + p.Surname)
+}
+
+func formatTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(TimeLayout)
+}
+
+// sheet - rows of an export in either format
+type sheet interface {
+	WriteHeader(names []string) error
+	Write(row []any) error
+	Close() error
+}
+
+func newSheet(w io.Writer, format models.ExportFormat, name string) (sheet, error) {
+	if format == models.ExportXLSX {
+		return xlsx.NewWriter(w, name)
+	}
+	// the byte order mark makes Excel read the file as UTF-8, not in the
+	// locale's code page
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	return &csvSheet{w: csv.NewWriter(w)}, nil
+}
+
+type csvSheet struct {
+	w *csv.Writer
+}
+
+func (s *csvSheet) WriteHeader(names []string) error {
+	return s.w.Write(names)
+}
+
+func (s *csvSheet) Write(row []any) error {
+	record := make([]string, len(row))
+	for n, v := range row {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			record[n] = defuseFormula(v)
+		default:
+			record[n] = fmt.Sprint(v)
+		}
+	}
+	return s.w.Write(record)
+}
+
+func (s *csvSheet) Close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// defuseFormula prefixes user texts spreadsheets would run as formulas with
+// a quote, so that they are shown as typed
+func defuseFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readCSV parses an export, checking that it starts with the byte order mark
+func readCSV(t *testing.T, buf *bytes.Buffer) [][]string {
+	t.Helper()
+	body, ok := strings.CutPrefix(buf.String(), "\ufeff")
+	require.True(t, ok, "no byte order mark")
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+func TestExportIdeas(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	e := New(*slog.Default(), repo)
+
+	author := repotest.User(t, repo, "author@example.com")
+	coffee := repotest.Idea(t, repo, author.UID, "Кофемашина на третий этаж")
+	formula := repotest.Idea(t, repo, author.UID, "=HYPERLINK(\"http://evil\")")
+	repotest.Comment(t, repo, coffee.IdeaUID, "", author.UID, "поддерживаю")
+	require.NoError(t, repo.IncrementLikeCount(ctx, coffee.IdeaUID))
+
+	var buf bytes.Buffer
+	require.NoError(t, e.ExportIdeas(ctx, models.IdeaFilter{}, models.ExportCSV, &buf))
+	records := readCSV(t, &buf)
+	require.Len(t, records, 3)
+	assert.Equal(t, ideaColumns, records[0])
+
+	rows := map[string][]string{records[1][0]: records[1], records[2][0]: records[2]}
+	row := rows[coffee.IdeaUID]
+	assert.Equal(t, "Кофемашина на третий этаж", row[1])
+	assert.Equal(t, "Office", row[3])
+	assert.Equal(t, "New", row[4])
+	assert.Equal(t, "Ivan Petrov", row[5])
+	assert.Equal(t, coffee.CreationDate.UTC().Format(TimeLayout), row[6])
+	assert.Equal(t, []string{"1", "0", "0", "1", ""}, row[7:])
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\")", rows[formula.IdeaUID][1], "formulas are shown as typed")
+
+	// the filter of the list narrows the export
+	buf.Reset()
+	require.NoError(t, e.ExportIdeas(ctx, models.IdeaFilter{Query: "кофемашина", Limit: 20}, models.ExportCSV, &buf))
+	records = readCSV(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, coffee.IdeaUID, records[1][0])
+
+	buf.Reset()
+	require.NoError(t, e.ExportIdeas(ctx, models.IdeaFilter{}, models.ExportXLSX, &buf))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var sheet string
+	for _, f := range zr.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			body, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			sheet = string(body)
+		}
+	}
+	assert.Contains(t, sheet, "Кофемашина на третий этаж")
+	assert.Contains(t, sheet, "=HYPERLINK", "cells of a workbook are never formulas")
+	assert.Equal(t, 3, strings.Count(sheet, "<row>"))
+}
+
+func TestExportInvalid(t *testing.T) {
+	ctx := context.Background()
+	e := New(*slog.Default(), memory.New())
+
+	var buf bytes.Buffer
+	err := e.ExportIdeas(ctx, models.IdeaFilter{}, "pdf", &buf)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	err = e.ExportDiscussions(ctx, models.IdeaFilter{Sort: "new"}, models.ExportCSV, &buf)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	err = e.ExportIdeas(ctx, models.IdeaFilter{Query: "?!"}, models.ExportXLSX, &buf)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	assert.Zero(t, buf.Len(), "nothing is written before the ideas are found")
+}
+
+func TestExportDiscussions(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	e := New(*slog.Default(), repo)
+
+	author := repotest.User(t, repo, "author@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "Идея")
+	repotest.Idea(t, repo, author.UID, "silent")
+	first := repotest.Comment(t, repo, idea.IdeaUID, "", author.UID, "первый")
+	second := repotest.Comment(t, repo, idea.IdeaUID, "", author.UID, "второй")
+	reply := repotest.Comment(t, repo, idea.IdeaUID, first.CommentUID, author.UID, "ответ")
+	require.NoError(t, repo.TombstoneComment(ctx, second.CommentUID))
+
+	var buf bytes.Buffer
+	require.NoError(t, e.ExportDiscussions(ctx, models.IdeaFilter{}, models.ExportCSV, &buf))
+	records := readCSV(t, &buf)
+	require.Len(t, records, 4, "ideas without comments have no rows")
+	assert.Equal(t, commentColumns, records[0])
+
+	// the reply follows the comment it answers
+	assert.Equal(t, []string{first.CommentUID, reply.CommentUID, second.CommentUID},
+		[]string{records[1][2], records[2][2], records[3][2]})
+	assert.Equal(t, []string{idea.IdeaUID, "Идея"}, records[2][:2])
+	assert.Equal(t, first.CommentUID, records[2][3])
+	assert.Equal(t, "1", records[2][4])
+	assert.Equal(t, "Ivan Petrov", records[2][5])
+	assert.Equal(t, "ответ", records[2][6])
+	assert.Empty(t, records[1][3])
+	assert.Empty(t, records[1][9])
+	assert.Empty(t, records[3][6], "deleted text isn't exported")
+	assert.NotEmpty(t, records[3][9])
+}
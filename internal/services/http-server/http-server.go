@@ -1,15 +1,26 @@
 package http_server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/validation"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
 	"github.com/TP2-Voice-Agora/backend/internal/services/http-server/mware"
+	"github.com/TP2-Voice-Agora/backend/internal/services/http-server/response"
 	i "github.com/TP2-Voice-Agora/backend/internal/services/interfaces"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"io"
 	"log"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -17,23 +28,110 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
+const (
+	requestTimeout = 15 * time.Second
+	bulkTimeout    = 10 * time.Minute // exports stream every idea, imports check every row
+
+	maxBodySize   = 1 << 20 // 1 MiB for JSON bodies
+	maxPFPSize    = 5 << 20 // 5 MiB for profile pictures
+	maxImportFile = 5 << 20 // 5 MiB CSV of users, as onboarding.MaxFileSize
+	// the file and the boundaries and part headers of the multipart form around it
+	maxImportSize = maxImportFile + 64<<10
+
+	defaultSearchLimit = 20
+	defaultCommentPage = 20
+
+	defaultNotificationPage = 20
+	defaultDeliveryPage     = 50
+	defaultLeaderboard      = 10
+	defaultJobRunPage       = 20
+	defaultViewedPage       = 20
+	defaultContributors     = 10
+
+	maxEventIdeas  = 50               // idea topics one event stream may follow
+	eventHeartbeat = 25 * time.Second // keeps proxies from closing idle streams
+	eventRetry     = 3 * time.Second  // EventSource reconnection delay
+)
+
 // HTTPServer encapsulates the server dependencies and routes.
 // for push
 type HTTPServer struct {
-	ideaService i.IdeaService
-	authService i.AuthService
-	userService i.UserService
-	log         *slog.Logger
+	ideaService         i.IdeaService
+	authService         i.AuthService
+	userService         i.UserService
+	notificationService i.NotificationService
+	emailService        i.EmailService
+	webhookService      i.WebhookService
+	botService          i.BotService
+	campaignService     i.CampaignService
+	jobService          i.JobService
+	analyticsService    i.AnalyticsService
+	exportService       i.ExportService
+	reportService       i.ReportService
+	onboardingService   i.OnboardingService
+	eventHub            i.EventHub
+	log                 *slog.Logger
+	validate            *validation.Validator
 }
 
 // NewHTTPServer creates and configures a new HTTPServer instance.
-func NewHTTPServer(ideaService i.IdeaService, authService i.AuthService, userService i.UserService, log *slog.Logger) *HTTPServer {
-	return &HTTPServer{
-		ideaService: ideaService,
-		authService: authService,
-		userService: userService,
-		log:         log,
+func NewHTTPServer(ideaService i.IdeaService, authService i.AuthService, userService i.UserService,
+	notificationService i.NotificationService, emailService i.EmailService,
+	webhookService i.WebhookService, botService i.BotService, campaignService i.CampaignService,
+	jobService i.JobService, analyticsService i.AnalyticsService, exportService i.ExportService,
+	reportService i.ReportService, onboardingService i.OnboardingService, eventHub i.EventHub,
+	log *slog.Logger) *HTTPServer {
+	s := &HTTPServer{
+		ideaService:         ideaService,
+		authService:         authService,
+		userService:         userService,
+		notificationService: notificationService,
+		emailService:        emailService,
+		webhookService:      webhookService,
+		botService:          botService,
+		campaignService:     campaignService,
+		jobService:          jobService,
+		analyticsService:    analyticsService,
+		exportService:       exportService,
+		reportService:       reportService,
+		onboardingService:   onboardingService,
+		eventHub:            eventHub,
+		log:                 log,
+		validate:            validation.New(),
 	}
+
+	// categories and statuses are cached by idea service, positions are read on demand
+	s.validate.RegisterRef("idea_category", "unknown idea category", func(ctx context.Context, id int) bool {
+		for _, c := range s.ideaService.GetIdeaCategories() {
+			if c.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+	s.validate.RegisterRef("idea_status", "unknown idea status", func(ctx context.Context, id int) bool {
+		for _, st := range s.ideaService.GetIdeaStatuses() {
+			if st.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+	s.validate.RegisterRef("user_position", "unknown position", func(ctx context.Context, id int) bool {
+		positions, err := s.userService.GetPositions(ctx)
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to fetch positions for validation", slog.String("error", err.Error()))
+			return false
+		}
+		for _, p := range positions {
+			if p.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+
+	return s
 }
 
 // SetupRoutes builds and returns an http.Handler with all routes and
@@ -44,69 +142,256 @@ func (s *HTTPServer) SetupRoutes() http.Handler {
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "https://voice.ffokildam.ru"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Requested-With"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	r.Group(func(r chi.Router) {
-		r.Use(middleware.Logger)
-		r.Use(middleware.Recoverer)
-		r.Use(middleware.RequestID)
-		r.Use(middleware.RealIP)
-
-		r.Use(func(next http.Handler) http.Handler {
-			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				log.Printf("Real IP: %s", r.RemoteAddr)
-				next.ServeHTTP(w, r)
-			})
+	// request id goes first, so that every error envelope (auth included) carries it
+	r.Use(middleware.RequestID)
+	r.Use(mware.LogContext)
+	r.Use(middleware.RealIP)
+	// credentials in the query never reach the access log
+	r.Use(mware.HideSecrets)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.Printf("Real IP: %s", r.RemoteAddr)
+			next.ServeHTTP(w, r)
 		})
+	})
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		s.error(w, r, apperr.NotFound("route not found"))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, http.StatusMethodNotAllowed, response.ErrorResponse{Error: response.ErrorBody{
+			Code:      "method_not_allowed",
+			Message:   "invalid method",
+			RequestID: middleware.GetReqID(r.Context()),
+		}})
+	})
 
+	// cancels request context (and so every query) when the deadline is hit,
+	// event streams are long-lived and stay out of it, exports and imports
+	// get longer
+	timeout := middleware.Timeout(requestTimeout)
+
+	r.Group(func(r chi.Router) {
+		r.Use(timeout)
 		r.Post("/login", s.handleLogin)
 		r.Post("/register", s.handleRegister)
+		r.Post("/invitations/accept", s.handleAcceptInvitation)
 		r.Handle("/uploads/*", http.StripPrefix("/uploads/", http.FileServer(http.Dir("uploads"))))
 		r.Get("/swagger/*", httpSwagger.WrapHandler)
+		// messengers authenticate updates themselves, see bot.Platform
+		r.Post("/bot/{platform}/webhook", s.handleBotWebhook)
 	})
 
 	r.Group(func(r chi.Router) {
+		r.Use(timeout)
 		r.Use(mware.AuthMiddleware(s.authService.GetJWT(), s.log, s.userService))
 
-		r.Use(middleware.Logger)
-		r.Use(middleware.Recoverer)
-		r.Use(middleware.RequestID)
-		r.Use(middleware.RealIP)
-
-		r.Use(func(next http.Handler) http.Handler {
-			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				log.Printf("Real IP: %s", r.RemoteAddr)
-				next.ServeHTTP(w, r)
-			})
-		})
-
 		r.Get("/ideas/categories", s.handleGetIdeaCategories)
 		r.Get("/ideas/statuses", s.handleGetIdeaStatuses)
 
 		r.Get("/ideas", s.handleGetAllIdeas)
+		r.Get("/ideas/viewed", s.handleGetViewedIdeas)
 		r.Get("/ideas/{uid}", s.handleGetIdeaByUID)
+		r.Get("/ideas/{uid}/comments", s.handleGetIdeaComments)
+		r.Get("/ideas/{uid}/report", s.handleGetIdeaReport)
 		r.Post("/ideas", s.handleInsertIdea)
+		r.Patch("/ideas/{uid}/status", s.handleChangeIdeaStatus)
 
 		r.Post("/ideas/{uid}/like", s.handleIncreaseLikes)
 		r.Post("/ideas/{uid}/dislike", s.handleIncreaseDislikes)
 
+		r.Get("/reactions", s.handleGetReactionSet)
+		r.Put("/ideas/{uid}/reactions/{emoji}", s.handleReact(models.TargetIdea))
+		r.Delete("/ideas/{uid}/reactions/{emoji}", s.handleUnreact(models.TargetIdea))
+		r.Put("/comments/{uid}/reactions/{emoji}", s.handleReact(models.TargetComment))
+		r.Delete("/comments/{uid}/reactions/{emoji}", s.handleUnreact(models.TargetComment))
+
 		r.Post("/comments", s.handleInsertComment)
+		r.Patch("/comments/{uid}", s.handleEditComment)
+		r.Delete("/comments/{uid}", s.handleDeleteComment)
+		r.Get("/comments/{uid}/revisions", s.handleGetCommentRevisions)
+		r.Get("/comments/{uid}/children", s.handleGetCommentChildren)
+		// replies are comments with a parent, /replies is kept for old clients
 		r.Post("/replies", s.handleInsertReply)
+		r.Patch("/replies/{uid}", s.handleEditReply)
+		r.Delete("/replies/{uid}", s.handleDeleteComment)
+		r.Get("/replies/{uid}/revisions", s.handleGetCommentRevisions)
+
+		r.Get("/notifications", s.handleGetNotifications)
+		r.Post("/notifications/read-all", s.handleMarkAllNotificationsRead)
+		r.Post("/notifications/{id}/read", s.handleMarkNotificationRead)
+		r.Get("/notifications/preferences", s.handleGetNotificationPreferences)
+		r.Put("/notifications/preferences", s.handleSetNotificationPreferences)
+		r.Get("/notifications/email", s.handleGetEmailSettings)
+		r.Put("/notifications/email", s.handleSetEmailSettings)
+
+		r.Post("/events/ticket", s.handleCreateStreamTicket)
 
 		r.Get("/users/{uid}", s.handleGetUser)
 		r.Post("/users/pfp", s.handleUploadUserPFP)
 
 		r.Get("/users/positions", s.handleGetUserPositions)
+
+		r.Get("/campaigns", s.handleGetCampaigns)
+		r.Get("/campaigns/{id}", s.handleGetCampaign)
+		r.Get("/campaigns/{id}/leaderboard", s.handleGetCampaignLeaderboard)
+
+		r.Post("/bot/link-code", s.handleCreateBotLinkCode)
+		r.Get("/bot/accounts", s.handleGetBotAccounts)
+		r.Delete("/bot/accounts/{platform}/{id}", s.handleUnlinkBotAccount)
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(mware.AdminOnly(s.log))
+
+			r.Get("/webhooks", s.handleGetWebhooks)
+			r.Post("/webhooks", s.handleCreateWebhook)
+			r.Put("/webhooks/{id}", s.handleUpdateWebhook)
+			r.Delete("/webhooks/{id}", s.handleDeleteWebhook)
+			r.Post("/webhooks/{id}/test", s.handleTestWebhook)
+			r.Get("/webhooks/{id}/deliveries", s.handleGetWebhookDeliveries)
+			r.Get("/webhooks/deliveries/{id}", s.handleGetWebhookDelivery)
+			r.Post("/webhooks/deliveries/{id}/redeliver", s.handleRedeliverWebhook)
+
+			r.Post("/campaigns", s.handleCreateCampaign)
+			r.Put("/campaigns/{id}", s.handleUpdateCampaign)
+			r.Delete("/campaigns/{id}", s.handleDeleteCampaign)
+
+			r.Get("/jobs", s.handleGetJobs)
+			r.Post("/jobs/{name}/run", s.handleRunJob)
+			r.Get("/jobs/{name}/runs", s.handleGetJobRuns)
+
+			r.Get("/analytics/submissions", s.handleGetSubmissions)
+			r.Get("/analytics/decisions", s.handleGetDecisions)
+			r.Get("/analytics/contributors", s.handleGetContributors)
+			r.Get("/analytics/engagement", s.handleGetEngagement)
+
+			r.Post("/users/{uid}/invitation", s.handleInviteUser)
+		})
+	})
+
+	r.Group(func(r chi.Router) {
+		// EventSource can't send headers, a one-time ticket comes in the query
+		r.Use(mware.StreamTicket(s.log, s.authService, s.userService))
+
+		r.Get("/events", s.handleEvents)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(bulkTimeout))
+		r.Use(mware.AuthMiddleware(s.authService.GetJWT(), s.log, s.userService))
+		r.Use(mware.AdminOnly(s.log))
+
+		r.Get("/admin/export/ideas", s.handleExport("ideas", s.exportService.ExportIdeas))
+		r.Get("/admin/export/discussions", s.handleExport("discussions", s.exportService.ExportDiscussions))
+		r.Post("/admin/users/import", s.handleImportUsers)
 	})
 
 	return r
 }
 
+// error renders err as the uniform JSON error envelope
+func (s *HTTPServer) error(w http.ResponseWriter, r *http.Request, err error) {
+	response.Error(w, r, s.log, err)
+}
+
+// decode reads a single JSON object from the request body into v and
+// validates it. Unknown fields and oversized bodies are rejected.
+func (s *HTTPServer) decode(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apperr.TooLarge("request body too large").WithCause(err)
+		}
+		return apperr.Validation("invalid request body: " + err.Error()).WithCause(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return apperr.Validation("request body must contain a single JSON object")
+	}
+
+	return s.validate.Struct(r.Context(), v)
+}
+
+// pathUID returns a uuid path parameter or a validation error
+func (s *HTTPServer) pathUID(r *http.Request, name string) (string, error) {
+	uid := chi.URLParam(r, name)
+	if err := s.validate.Var(name, uid, "required,uuid"); err != nil {
+		return "", err
+	}
+	return uid, nil
+}
+
+// pathID reads a positive integer id from the path
+func (s *HTTPServer) pathID(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, apperr.Validation("request validation failed").
+			WithDetails([]validation.FieldError{{Field: name, Message: "must be a positive integer"}})
+	}
+	return id, nil
+}
+
+// queryInt reads an optional integer query parameter checked against the
+// validator tag, def is returned when the parameter is absent
+func (s *HTTPServer) queryInt(r *http.Request, name string, def int, tag string) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, apperr.Validation("request validation failed").
+			WithDetails([]validation.FieldError{{Field: name, Message: "must be an integer"}})
+	}
+	if err := s.validate.Var(name, v, tag); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// queryRange reads the optional from and to dates of analytics
+func (s *HTTPServer) queryRange(r *http.Request) (models.AnalyticsRange, error) {
+	var rng models.AnalyticsRange
+	for name, v := range map[string]*time.Time{"from": &rng.From, "to": &rng.To} {
+		raw := r.URL.Query().Get(name)
+		if raw == "" {
+			continue
+		}
+		day, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return rng, apperr.Validation("request validation failed").
+				WithDetails([]validation.FieldError{{Field: name, Message: "must be a date as 2006-01-02"}})
+		}
+		*v = day
+	}
+	return rng, nil
+}
+
+// pageParams reads cursor and limit of paginated comment lists
+func (s *HTTPServer) pageParams(r *http.Request) (string, int, error) {
+	cursor := r.URL.Query().Get("cursor")
+	if err := s.validate.Var("cursor", cursor, "omitempty,uuid"); err != nil {
+		return "", 0, err
+	}
+	limit, err := s.queryInt(r, "limit", defaultCommentPage, "min=1,max=100")
+	if err != nil {
+		return "", 0, err
+	}
+	return cursor, limit, nil
+}
+
 // handleLogin
 // @Summary      Аутентификация
 // @Description  Аутентификация, возвращает jwt токен, который прикладывается ко всем (secure) рутам.
@@ -115,27 +400,21 @@ func (s *HTTPServer) SetupRoutes() http.Handler {
 // @Produce      json
 // @Param        loginRequest  body  models.LoginRequest true  "Login data"
 // @Success      200  {string}  string  "JWT token"
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to login"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      401  {object}  response.ErrorResponse  "Invalid email or password"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to login"
 // @Router       /login [post]
 func (s *HTTPServer) handleLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var body models.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		s.log.Error("failed to decode request body", slog.String("error", err.Error()))
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
 		return
 	}
 
-	jwtToken, userUID, err := s.authService.Login(body.Email, body.Password)
+	jwtToken, userUID, err := s.authService.Login(r.Context(), body.Email, body.Password)
 	if err != nil {
-		s.log.Error("failed to log in user", slog.String("email", body.Email), slog.String("error", err.Error()))
-		http.Error(w, "Failed to login", http.StatusInternalServerError)
+		s.log.ErrorContext(r.Context(), "failed to log in user", slog.String("email", body.Email), slog.String("error", err.Error()))
+		s.error(w, r, err)
 		return
 	}
 	var respBody struct {
@@ -144,11 +423,7 @@ func (s *HTTPServer) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 	respBody.Token = jwtToken
 	respBody.Uid = userUID
-	resp, _ := json.Marshal(respBody)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(resp)
-
+	response.JSON(w, http.StatusOK, respBody)
 }
 
 // handleRegister
@@ -159,23 +434,18 @@ func (s *HTTPServer) handleLogin(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        registerRequest  body  models.RegisterRequest true "Register data"
 // @Success      200  {object}  map[string]string  "message: ok"
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to register"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      409  {object}  response.ErrorResponse  "User already exists"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to register"
 // @Router       /register [post]
 func (s *HTTPServer) handleRegister(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var body models.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
 		return
 	}
 
-	err := s.authService.Register(models.User{
+	err := s.authService.Register(r.Context(), models.User{
 		Email:      body.Email,
 		Password:   body.Password,
 		PositionID: body.PositionID,
@@ -183,15 +453,12 @@ func (s *HTTPServer) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Surname:    body.Surname,
 	})
 	if err != nil {
-		s.log.Error("failed to register user", slog.String("email", body.Email), slog.String("error", err.Error()))
-		http.Error(w, "Failed to register", http.StatusInternalServerError)
+		s.log.ErrorContext(r.Context(), "failed to register user", slog.String("email", body.Email), slog.String("error", err.Error()))
+		s.error(w, r, err)
 		return
 	}
 
-	resp, _ := json.Marshal(map[string]string{"message": "ok"})
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusOK, map[string]string{"message": "ok"})
 }
 
 // handleGetIdeaCategories
@@ -201,18 +468,10 @@ func (s *HTTPServer) handleRegister(w http.ResponseWriter, r *http.Request) {
 // @Tags         Идеи
 // @Produce      json
 // @Success      200  {array}   models.IdeaCategory
-// @Failure      405  {string}  string  "Invalid method"
 // @Router       /ideas/categories [get]
 func (s *HTTPServer) handleGetIdeaCategories(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	categories := s.ideaService.GetIdeaCategories()
-	resp, _ := json.Marshal(categories)
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusOK, categories)
 }
 
 // handleGetIdeaStatuses
@@ -222,18 +481,10 @@ func (s *HTTPServer) handleGetIdeaCategories(w http.ResponseWriter, r *http.Requ
 // @Tags         Идеи
 // @Produce      json
 // @Success      200  {array}   models.IdeaStatus
-// @Failure      405  {string}  string  "Invalid method"
 // @Router       /ideas/statuses [get]
 func (s *HTTPServer) handleGetIdeaStatuses(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	statuses := s.ideaService.GetIdeaStatuses()
-	resp, _ := json.Marshal(statuses)
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusOK, statuses)
 }
 
 // handleGetIdeaStatuses
@@ -242,311 +493,1770 @@ func (s *HTTPServer) handleGetIdeaStatuses(w http.ResponseWriter, r *http.Reques
 // @Tags         Пользователи
 // @Produce      json
 // @Success      200  {array}   models.UserPosition
-// @Failure      405  {string}  string  "Invalid method"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to get positions"
 // @Router       /users/positions [get]
 func (s *HTTPServer) handleGetUserPositions(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
-	positions, err := s.userService.GetPositions()
+	positions, err := s.userService.GetPositions(r.Context())
 	if err != nil {
-		s.log.Error("failed to get positions", slog.String("error", err.Error()))
+		s.log.ErrorContext(r.Context(), "failed to get positions", slog.String("error", err.Error()))
+		s.error(w, r, err)
+		return
 	}
-	resp, _ := json.Marshal(positions)
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusOK, positions)
 }
 
 // handleGetAllIdeas
 // @Summary      Все идеи(secure)
-// @Description  Возвращает все идеи списков без комментариев\ответов.
+// @Description  Возвращает все идеи списков без комментариев\ответов. С параметром q - полнотекстовый
+// @Description  поиск по названию и тексту: все слова запроса должны встречаться целиком, без учета регистра.
+// @Description  С параметром sort - по рейтингу: hot (голоса, комментарии и просмотры с поправкой на возраст),
+// @Description  top (лайки минус дизлайки), controversial (много голосов поровну), rising (активность за сутки
+// @Description  у молодых идей). Рейтинги пересчитываются при голосах и комментариях и раз в 10 минут.
 // @Tags         Идеи
 // @Produce      json
+// @Param        q      query     string  false  "Search query"
+// @Param        limit  query     int     false  "Max search results (1-100, default 20)"
+// @Param        sort   query     string  false  "hot, top, controversial или rising"
 // @Success      200  {array}   models.Idea
-// @Failure      500  {string}  string  "Failed to get ideas"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to get ideas"
 // @Router       /ideas [get]
 func (s *HTTPServer) handleGetAllIdeas(w http.ResponseWriter, r *http.Request) {
+	filter, err := s.ideaFilter(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
 
-	ideas, err := s.ideaService.GetAllIdeas()
+	viewerUID := r.Context().Value(mware.ContextUserUID).(string)
+	var ideas []models.Idea
+	switch {
+	case filter.Query != "":
+		ideas, err = s.ideaService.SearchIdeas(r.Context(), filter.Query, filter.Limit, viewerUID)
+	case filter.Sort != "":
+		ideas, err = s.ideaService.GetRankedIdeas(r.Context(), filter.Sort, viewerUID)
+	default:
+		ideas, err = s.ideaService.GetAllIdeas(r.Context(), viewerUID)
+	}
 	if err != nil {
-		http.Error(w, "Failed to get ideas", http.StatusInternalServerError)
+		s.error(w, r, err)
 		return
 	}
 
-	resp, _ := json.Marshal(ideas)
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusOK, ideas)
+}
+
+// ideaFilter reads the parameters of GET /ideas, the exports select ideas
+// with the same ones
+func (s *HTTPServer) ideaFilter(r *http.Request) (models.IdeaFilter, error) {
+	f := models.IdeaFilter{Query: r.URL.Query().Get("q"), Sort: models.IdeaSort(r.URL.Query().Get("sort"))}
+	if err := s.validate.Var("q", f.Query, "max=200"); err != nil {
+		return f, err
+	}
+	if err := s.validate.Var("sort", string(f.Sort), "omitempty,oneof=hot top controversial rising"); err != nil {
+		return f, err
+	}
+	limit, err := s.queryInt(r, "limit", defaultSearchLimit, "min=1,max=100")
+	if err != nil {
+		return f, err
+	}
+	f.Limit = limit
+	return f, nil
+}
+
+// handleGetViewedIdeas
+// @Summary      Недавно просмотренные идеи(secure)
+// @Description  Идеи, которые открывал текущий пользователь, последние сначала, с LastViewedAt и UnreadComments.
+// @Tags         Идеи
+// @Produce      json
+// @Param        limit  query  int  false  "До 100, по умолчанию 20"
+// @Success      200  {array}   models.Idea
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Router       /ideas/viewed [get]
+func (s *HTTPServer) handleGetViewedIdeas(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.queryInt(r, "limit", defaultViewedPage, "min=1,max=100")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	viewerUID := r.Context().Value(mware.ContextUserUID).(string)
+	ideas, err := s.ideaService.GetViewedIdeas(r.Context(), viewerUID, limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, ideas)
 }
 
 // handleGetIdeaByUID
 // @Summary      Конкретная идея(secure)
-// @Description  Возвращает идею по UID, уже с комментариями\ответами
+// @Description  Возвращает идею по UID с первой страницей комментариев верхнего уровня, у каждого - ответы
+// @Description  на 2 уровня вглубь (Children). Если у комментария ChildCount больше, чем загружено в Children,
+// @Description  остальные ответы берутся из /comments/{uid}/children; следующая страница комментариев -
+// @Description  /ideas/{uid}/comments?cursor=NextCursor. У идеи и комментариев заполнен AuthorProfile
+// @Description  (имя, фамилия, аватар автора), запрашивать /users/{uid} не нужно. Reactions - счетчики
+// @Description  эмодзи-реакций, Mine - реакция текущего пользователя.
+// @Description  Запрос засчитывается как просмотр (ViewCount): от одного пользователя не чаще раза в сутки, просмотры
+// @Description  автора не считаются. LastViewedAt - предыдущий визит пользователя, UnreadComments - комментарии
+// @Description  других пользователей, появившиеся с тех пор (так же заполняются в списках идей).
 // @Tags         Идеи
 // @Produce      json
 // @Param        uid   path      string  true  "Idea UID"
 // @Success      200   {object}  models.IdeaComment
-// @Failure      405   {string}  string  "Invalid method"
-// @Failure      500   {string}  string  "Failed to get idea by UID"
+// @Failure      404   {object}  response.ErrorResponse  "Idea not found"
+// @Failure      500   {object}  response.ErrorResponse  "Failed to get idea by UID"
 // @Router       /ideas/{uid} [get]
 func (s *HTTPServer) handleGetIdeaByUID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
 		return
 	}
 
-	uid := chi.URLParam(r, "uid")
-
-	ideaComment, err := s.ideaService.GetIdeaByUID(uid)
+	viewerUID := r.Context().Value(mware.ContextUserUID).(string)
+	ideaComment, err := s.ideaService.GetIdeaByUID(r.Context(), uid, viewerUID)
 	if err != nil {
-		http.Error(w, "Failed to get idea by UID", http.StatusInternalServerError)
+		s.error(w, r, err)
 		return
 	}
 
-	resp, _ := json.Marshal(ideaComment)
-	w.Header().Set("Content-Type", "application/json")
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusOK, ideaComment)
 }
 
 // handleInsertIdea
 // @Summary      Вставка новой идеи(secure)
-// @Description  Вставляет идею, и возвращает ее со всеми заполненными полями
+// @Description  Вставляет идею, и возвращает ее со всеми заполненными полями. Упоминания @имя.фамилия
+// @Description  (без учета регистра) или @uid в тексте сохраняются, упомянутые получают уведомление; в Mentions -
+// @Description  позиции упоминаний в тексте (Start, End - в символах, End не включается) и профили упомянутых.
+// @Description  С campaign идея подается в кампанию: та должна идти, категория - входить в ее категории, а
+// @Description  должность автора - в ее должности (пустые списки разрешают любые).
 // @Tags         Идеи
 // @Accept       json
 // @Produce      json
 // @Param        idea  body  models.InsertIdeaRequest true  "Idea data"
 // @Success      201  {object}  models.Idea
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      500  {string}  string  "Failed to create idea"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Position isn't eligible for the campaign"
+// @Failure      404  {object}  response.ErrorResponse  "Campaign not found"
+// @Failure      409  {object}  response.ErrorResponse  "Campaign isn't open"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to create idea"
 // @Router       /ideas [post]
 func (s *HTTPServer) handleInsertIdea(w http.ResponseWriter, r *http.Request) {
 	var body models.InsertIdeaRequest
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		s.log.Error("failed to decode request body", slog.String("error", err.Error()))
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := s.decode(w, r, &body); err != nil {
+		s.log.ErrorContext(r.Context(), "failed to decode request body", slog.String("error", err.Error()))
+		s.error(w, r, err)
 		return
 	}
 	body.Author = r.Context().Value(mware.ContextUserUID).(string)
 
-	newIdea, err := s.ideaService.InsertIdea(
-		body.Name, body.Text, body.Author, body.Status, body.Category,
-	)
+	var newIdea models.Idea
+	var err error
+	if body.Campaign != nil {
+		newIdea, err = s.ideaService.InsertCampaignIdea(
+			r.Context(), *body.Campaign, body.Name, body.Text, body.Author, body.Status, body.Category,
+		)
+	} else {
+		newIdea, err = s.ideaService.InsertIdea(
+			r.Context(), body.Name, body.Text, body.Author, body.Status, body.Category,
+		)
+	}
 	if err != nil {
-		http.Error(w, "Failed to create idea", http.StatusInternalServerError)
+		s.error(w, r, err)
 		return
 	}
 
-	resp, _ := json.Marshal(newIdea)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusCreated, newIdea)
+}
+
+// handleChangeIdeaStatus
+// @Summary      Смена статуса идеи(secure)
+// @Description  Переводит идею в другой статус, доступно только модераторам. Автор идеи получает уведомление.
+// @Tags         Идеи
+// @Accept       json
+// @Produce      json
+// @Param        uid     path  string                          true  "Idea UID"
+// @Param        status  body  models.ChangeIdeaStatusRequest  true  "New status"
+// @Success      200  {object}  models.Idea
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not a moderator"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Router       /ideas/{uid}/status [patch]
+func (s *HTTPServer) handleChangeIdeaStatus(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	var body models.ChangeIdeaStatusRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	actorUID := r.Context().Value(mware.ContextUserUID).(string)
+	idea, err := s.ideaService.ChangeIdeaStatus(r.Context(), uid, actorUID, body.Status)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, idea)
+}
+
+// handleGetIdeaComments
+// @Summary      Комментарии идеи(secure)
+// @Description  Страница комментариев верхнего уровня, старые сначала, у каждого - ответы на 2 уровня вглубь.
+// @Description  Для следующей страницы передайте NextCursor в cursor; пустой NextCursor - страниц больше нет.
+// @Tags         Вставка комментариев\ответов
+// @Produce      json
+// @Param        uid     path   string  true   "Idea UID"
+// @Param        cursor  query  string  false  "NextCursor предыдущей страницы"
+// @Param        limit   query  int     false  "Размер страницы (1-100, по умолчанию 20)"
+// @Success      200  {object}  models.CommentPage
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Router       /ideas/{uid}/comments [get]
+func (s *HTTPServer) handleGetIdeaComments(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	cursor, limit, err := s.pageParams(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	viewerUID := r.Context().Value(mware.ContextUserUID).(string)
+	page, err := s.ideaService.GetIdeaComments(r.Context(), uid, viewerUID, cursor, limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, page)
+}
+
+// handleGetIdeaReport
+// @Summary      Отчет по идее в PDF(secure)
+// @Description  Документ для комиссии: идея с автором, категорией, статусом и кампанией, история смены статусов,
+// @Description  голоса за и против и все обсуждение, ответы с отступом под комментарием. Время - в UTC.
+// @Description  Открытие отчета не засчитывается как просмотр.
+// @Tags         Идеи
+// @Produce      application/pdf
+// @Param        uid  path  string  true  "Idea UID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Router       /ideas/{uid}/report [get]
+func (s *HTTPServer) handleGetIdeaReport(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	pdf, err := s.reportService.IdeaReport(r.Context(), uid)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": "idea-" + uid + ".pdf"}))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(pdf); err != nil {
+		s.log.ErrorContext(r.Context(), "failed to send report", slog.String("error", err.Error()))
+	}
+}
+
+// handleGetCommentChildren
+// @Summary      Ответы на комментарий(secure)
+// @Description  Страница прямых ответов на комментарий, старые сначала, у каждого - его ответы на 2 уровня вглубь.
+// @Description  Пагинация как у /ideas/{uid}/comments.
+// @Tags         Вставка комментариев\ответов
+// @Produce      json
+// @Param        uid     path   string  true   "Comment UID"
+// @Param        cursor  query  string  false  "NextCursor предыдущей страницы"
+// @Param        limit   query  int     false  "Размер страницы (1-100, по умолчанию 20)"
+// @Success      200  {object}  models.CommentPage
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Comment not found"
+// @Router       /comments/{uid}/children [get]
+func (s *HTTPServer) handleGetCommentChildren(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	cursor, limit, err := s.pageParams(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	viewerUID := r.Context().Value(mware.ContextUserUID).(string)
+	page, err := s.ideaService.GetCommentChildren(r.Context(), uid, viewerUID, cursor, limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, page)
 }
 
 // handleInsertComment
 // @Summary      Вставка комментария(secure)
-// @Description  Вставляет коммент и возвращает его.
+// @Description  Вставляет коммент и возвращает его. С parentUID это ответ на комментарий той же идеи,
+// @Description  вложенность ограничена 8 уровнями. Упоминания - как у POST /ideas.
 // @Tags         Вставка комментариев\ответов
 // @Accept       json
 // @Produce      json
 // @Param        comment body models.InsertCommentRequest true "Comment data"
 // @Success      201  {object}  models.Comment
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to create comment"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Idea or parent comment not found"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to create comment"
 // @Router       /comments [post]
 func (s *HTTPServer) handleInsertComment(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var body models.InsertCommentRequest
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
 		return
 	}
 
 	authorUID := r.Context().Value(mware.ContextUserUID).(string)
 
-	newComment, err := s.ideaService.InsertComment(body.IdeaUID, authorUID, body.CommentText)
+	newComment, err := s.ideaService.InsertComment(r.Context(), body.IdeaUID, body.ParentUID, authorUID, body.CommentText)
 	if err != nil {
-		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		s.error(w, r, err)
 		return
 	}
-	resp, _ := json.Marshal(newComment)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write(resp)
-
+	response.JSON(w, http.StatusCreated, newComment)
 }
 
 // handleInsertReply
 // @Summary      Вставка ответа
-// @Description  Вставляет новый ответ, и возвращает его
+// @Description  Устаревший вариант POST /comments с parentUID: вставляет ответ на комментарий и возвращает его
 // @Tags         Вставка комментариев\ответов
 // @Accept       json
 // @Produce      json
 // @Param        reply  body  models.InsertReplyRequest true "Reply data"
 // @Security     JWTAuth
-// @Success      201  {object}  models.Reply
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to create reply"
+// @Success      201  {object}  models.Comment
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Comment not found"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to create reply"
 // @Router       /replies [post]
 func (s *HTTPServer) handleInsertReply(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-		return
-	}
-
 	var body models.InsertReplyRequest
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
 		return
 	}
 
 	authorID := r.Context().Value(mware.ContextUserUID).(string)
-	newReply, err := s.ideaService.InsertReply(body.CommentUID, authorID, body.ReplyText)
+	newReply, err := s.ideaService.InsertReply(r.Context(), body.CommentUID, authorID, body.ReplyText)
 	if err != nil {
-		http.Error(w, "Failed to create reply", http.StatusInternalServerError)
+		s.error(w, r, err)
+		return
 	}
 
-	resp, _ := json.Marshal(newReply)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write(resp)
+	response.JSON(w, http.StatusCreated, newReply)
 }
 
-// handleGetUser
-// @Summary      Получение юзера по UID
-// @Description  Возвращает данные пользователя по UID.
-// @Tags         Пользователи
+// handleEditComment
+// @Summary      Редактирование комментария(secure)
+// @Description  Меняет текст комментария, старый текст сохраняется в истории правок. Доступно автору и модераторам.
+// @Description  Упоминания разбираются заново, уведомление получают только новые упомянутые.
+// @Tags         Вставка комментариев\ответов
+// @Accept       json
 // @Produce      json
-// @Param        uid   path      string  true  "User UID"
-// @Success      200   {object}  models.User
-// @Failure      405   {string}  string  "Invalid method"
-// @Failure      500   {string}  string  "Failed to get user"
-// @Router       /users/{uid} [get]
-func (s *HTTPServer) handleGetUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+// @Param        uid      path  string                     true  "Comment UID"
+// @Param        comment  body  models.EditCommentRequest  true  "New text"
+// @Success      200  {object}  models.Comment
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not the author"
+// @Failure      404  {object}  response.ErrorResponse  "Comment not found"
+// @Failure      409  {object}  response.ErrorResponse  "Comment is deleted"
+// @Router       /comments/{uid} [patch]
+func (s *HTTPServer) handleEditComment(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
 		return
 	}
-	uid := chi.URLParam(r, "uid")
-
-	user, err := s.userService.GetUserByUID(uid)
-	if err != nil {
-		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+	var body models.EditCommentRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
 	}
 
-	resp, _ := json.Marshal(user)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(resp)
-}
-
-// handleUploadUserPFP
-// @Summary      Загрузка PFP
-// @Description  Загрузка новой аватарки для юзера.
-// @Tags         Пользователи
-// @Accept       multipart/form-data
-// @Produce      json
-// @Param        profile_picture  formData  file  true  "Profile picture file"
-// @Success      200  {object}  map[string]string  "url to uploaded picture"
-// @Failure      400  {string}  string  "No file uploaded or bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to upload file"
-// @Router       /users/pfp [post]
-func (s *HTTPServer) handleUploadUserPFP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	comment, err := s.ideaService.EditComment(r.Context(), uid, userUID, body.CommentText)
+	if err != nil {
+		s.error(w, r, err)
 		return
 	}
+	response.JSON(w, http.StatusOK, comment)
+}
 
-	file, header, err := r.FormFile("profile_picture")
+// handleDeleteComment
+// @Summary      Удаление комментария(secure)
+// @Description  Заменяет комментарий "надгробием": текст очищается, DeletedAt заполняется, ответы остаются. Доступно автору и модераторам.
+// @Description  DELETE /replies/{uid} - то же самое для старых клиентов.
+// @Tags         Вставка комментариев\ответов
+// @Param        uid  path  string  true  "Comment UID"
+// @Success      204
+// @Failure      403  {object}  response.ErrorResponse  "Not the author"
+// @Failure      404  {object}  response.ErrorResponse  "Comment not found"
+// @Router       /comments/{uid} [delete]
+func (s *HTTPServer) handleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
 	if err != nil {
-		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		s.error(w, r, err)
+		return
 	}
-	defer file.Close()
 
 	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	if err := s.ideaService.DeleteComment(r.Context(), uid, userUID); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	url, err := s.userService.UploadPFP(file, header, userUID)
+// handleGetCommentRevisions
+// @Summary      История правок комментария(secure)
+// @Description  Предыдущие версии текста, от старых к новым. GET /replies/{uid}/revisions - то же самое для старых клиентов.
+// @Tags         Вставка комментариев\ответов
+// @Produce      json
+// @Param        uid  path  string  true  "Comment UID"
+// @Success      200  {array}   models.Revision
+// @Failure      404  {object}  response.ErrorResponse  "Comment not found"
+// @Router       /comments/{uid}/revisions [get]
+func (s *HTTPServer) handleGetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
 	if err != nil {
-		s.log.Error("failed to upload user profile", slog.String("error", err.Error()))
-		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
+		s.error(w, r, err)
+		return
 	}
 
-	resp, _ := json.Marshal(map[string]string{"url": url})
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(resp)
+	revisions, err := s.ideaService.GetCommentRevisions(r.Context(), uid)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, revisions)
 }
 
-// handleIncreaseLikes
-// @Summary      Увеличение лайков
-// @Description  Увеличение лайков
-// @Tags         Идеи
+// handleEditReply
+// @Summary      Редактирование ответа(secure)
+// @Description  Устаревший вариант PATCH /comments/{uid} с полем replyText.
+// @Tags         Вставка комментариев\ответов
+// @Accept       json
 // @Produce      json
-// @Param        uid   path      string  true  "Idea UID"
-// @Success      200  {string}  string  "ok"
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to increase likes"
-// @Router       /ideas/{uid}/like [post]
-func (s *HTTPServer) handleIncreaseLikes(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
+// @Param        uid    path  string                   true  "Reply UID"
+// @Param        reply  body  models.EditReplyRequest  true  "New text"
+// @Success      200  {object}  models.Comment
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not the author"
+// @Failure      404  {object}  response.ErrorResponse  "Reply not found"
+// @Failure      409  {object}  response.ErrorResponse  "Reply is deleted"
+// @Router       /replies/{uid} [patch]
+func (s *HTTPServer) handleEditReply(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	var body models.EditReplyRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
 	}
 
 	userUID := r.Context().Value(mware.ContextUserUID).(string)
-
-	ok, err := s.ideaService.CheckVote(chi.URLParam(r, "uid"), userUID)
+	reply, err := s.ideaService.EditComment(r.Context(), uid, userUID, body.ReplyText)
 	if err != nil {
-		s.log.Error("failed to check liked user", slog.String("error", err.Error()))
+		s.error(w, r, err)
+		return
 	}
-	if ok {
-		err = s.ideaService.IncrementLikes(chi.URLParam(r, "uid"))
+	response.JSON(w, http.StatusOK, reply)
+}
+
+// handleGetReactionSet
+// @Summary      Доступные реакции(secure)
+// @Description  Эмодзи, которыми можно реагировать на идеи и комментарии, в порядке отображения.
+// @Tags         Реакции
+// @Produce      json
+// @Success      200  {array}  string
+// @Router       /reactions [get]
+func (s *HTTPServer) handleGetReactionSet(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, s.ideaService.GetReactionSet())
+}
+
+// reactionParams reads target uid and url-escaped emoji of reaction routes
+func (s *HTTPServer) reactionParams(r *http.Request) (string, string, error) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		return "", "", err
 	}
+	emoji, err := url.PathUnescape(chi.URLParam(r, "emoji"))
 	if err != nil {
-		s.log.Error("failed to increase likes", slog.String("error", err.Error()))
-		http.Error(w, "Failed to increase likes", http.StatusInternalServerError)
+		return "", "", apperr.Validation("invalid emoji").WithCause(err)
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("ok"))
+	if err := s.validate.Var("emoji", emoji, "required,max=32"); err != nil {
+		return "", "", err
+	}
+	return uid, emoji, nil
 }
 
-// handleIncreaseDislikes
-// @Summary      Увеличение дизлайков
-// @Description  Увеличение дизлайков
-// @Tags         Идеи
+// handleReact
+// @Summary      Поставить реакцию(secure)
+// @Description  Ставит реакцию текущего пользователя, повторный вызов ничего не меняет. Эмодзи - из GET /reactions,
+// @Description  в пути URL-кодированный. Возвращает счетчики реакций цели. Для комментариев -
+// @Description  PUT /comments/{uid}/reactions/{emoji}, на удаленный комментарий реакцию поставить нельзя.
+// @Tags         Реакции
+// @Produce      json
+// @Param        uid    path  string  true  "Idea UID"
+// @Param        emoji  path  string  true  "Emoji"
+// @Success      200  {array}   models.ReactionCount
+// @Failure      400  {object}  response.ErrorResponse  "Unknown reaction"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Router       /ideas/{uid}/reactions/{emoji} [put]
+func (s *HTTPServer) handleReact(target models.TargetType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, emoji, err := s.reactionParams(r)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+
+		userUID := r.Context().Value(mware.ContextUserUID).(string)
+		counts, err := s.ideaService.React(r.Context(), target, uid, userUID, emoji)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+		response.JSON(w, http.StatusOK, counts)
+	}
+}
+
+// handleUnreact
+// @Summary      Убрать реакцию(secure)
+// @Description  Убирает реакцию текущего пользователя, если ее нет - ничего не меняет. Возвращает счетчики
+// @Description  реакций цели. Для комментариев - DELETE /comments/{uid}/reactions/{emoji}.
+// @Tags         Реакции
+// @Produce      json
+// @Param        uid    path  string  true  "Idea UID"
+// @Param        emoji  path  string  true  "Emoji"
+// @Success      200  {array}   models.ReactionCount
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Router       /ideas/{uid}/reactions/{emoji} [delete]
+func (s *HTTPServer) handleUnreact(target models.TargetType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, emoji, err := s.reactionParams(r)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+
+		userUID := r.Context().Value(mware.ContextUserUID).(string)
+		counts, err := s.ideaService.Unreact(r.Context(), target, uid, userUID, emoji)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+		response.JSON(w, http.StatusOK, counts)
+	}
+}
+
+// handleGetNotifications
+// @Summary      Уведомления(secure)
+// @Description  Уведомления текущего пользователя, новые сначала: comment - комментарий к вашей идее, reply - ответ
+// @Description  на ваш комментарий, mention - упоминание, vote - голос за вашу идею (Detail - like/dislike), status -
+// @Description  смена статуса вашей идеи (Detail - id нового статуса). Unread - число всех непрочитанных.
+// @Description  Для следующей страницы передайте NextCursor в cursor.
+// @Tags         Уведомления
+// @Produce      json
+// @Param        unread  query  bool    false  "Только непрочитанные"
+// @Param        cursor  query  string  false  "NextCursor предыдущей страницы"
+// @Param        limit   query  int     false  "Размер страницы (1-100, по умолчанию 20)"
+// @Success      200  {object}  models.NotificationPage
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Router       /notifications [get]
+func (s *HTTPServer) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
+	cursor := r.URL.Query().Get("cursor")
+	if err := s.validate.Var("cursor", cursor, "omitempty,number"); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	limit, err := s.queryInt(r, "limit", defaultNotificationPage, "min=1,max=100")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	unreadOnly := false
+	if raw := r.URL.Query().Get("unread"); raw != "" {
+		if unreadOnly, err = strconv.ParseBool(raw); err != nil {
+			s.error(w, r, apperr.Validation("request validation failed").
+				WithDetails([]validation.FieldError{{Field: "unread", Message: "must be a boolean"}}))
+			return
+		}
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	page, err := s.notificationService.GetNotifications(r.Context(), userUID, unreadOnly, cursor, limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, page)
+}
+
+// handleMarkNotificationRead
+// @Summary      Прочитать уведомление(secure)
+// @Tags         Уведомления
+// @Param        id  path  int  true  "Notification ID"
+// @Success      204
+// @Failure      404  {object}  response.ErrorResponse  "Notification not found"
+// @Router       /notifications/{id}/read [post]
+func (s *HTTPServer) handleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	if err := s.notificationService.MarkRead(r.Context(), userUID, id); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMarkAllNotificationsRead
+// @Summary      Прочитать все уведомления(secure)
+// @Tags         Уведомления
+// @Success      204
+// @Router       /notifications/read-all [post]
+func (s *HTTPServer) handleMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	if err := s.notificationService.MarkAllRead(r.Context(), userUID); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetNotificationPreferences
+// @Summary      Настройки уведомлений(secure)
+// @Description  Включен ли каждый тип уведомлений в приложении и мессенджере, по умолчанию включены все. Письма настраиваются отдельно: /notifications/email.
+// @Tags         Уведомления
+// @Produce      json
+// @Success      200  {array}  models.NotificationPreference
+// @Router       /notifications/preferences [get]
+func (s *HTTPServer) handleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	preferences, err := s.notificationService.GetPreferences(r.Context(), userUID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, preferences)
+}
+
+// handleSetNotificationPreferences
+// @Summary      Изменение настроек уведомлений(secure)
+// @Description  Включает или выключает перечисленные типы в приложении и мессенджере, остальные не меняются. Письма об упоминаниях и статусах не зависят от этих настроек, см. /notifications/email. Возвращает все настройки.
+// @Tags         Уведомления
+// @Accept       json
+// @Produce      json
+// @Param        preferences  body  models.UpdateNotificationPreferencesRequest  true  "Preferences"
+// @Success      200  {array}   models.NotificationPreference
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Router       /notifications/preferences [put]
+func (s *HTTPServer) handleSetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var body models.UpdateNotificationPreferencesRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	preferences, err := s.notificationService.SetPreferences(r.Context(), userUID, body.Preferences)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, preferences)
+}
+
+// handleGetEmailSettings
+// @Summary      Настройки писем(secure)
+// @Description  Язык писем (ru, en), письма об упоминаниях и смене статуса своих идей (immediate)
+// @Description  и дайджест популярных идей (off, daily, weekly). По умолчанию ru, immediate и weekly.
+// @Tags         Уведомления
+// @Produce      json
+// @Success      200  {object}  models.EmailSettings
+// @Router       /notifications/email [get]
+func (s *HTTPServer) handleGetEmailSettings(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	settings, err := s.emailService.GetSettings(r.Context(), userUID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, settings)
+}
+
+// handleSetEmailSettings
+// @Summary      Изменение настроек писем(secure)
+// @Description  Выключенные в настройках уведомлений типы не приходят и письмом.
+// @Tags         Уведомления
+// @Accept       json
+// @Produce      json
+// @Param        settings  body  models.UpdateEmailSettingsRequest  true  "Settings"
+// @Success      200  {object}  models.EmailSettings
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Router       /notifications/email [put]
+func (s *HTTPServer) handleSetEmailSettings(w http.ResponseWriter, r *http.Request) {
+	var body models.UpdateEmailSettingsRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	settings, err := s.emailService.SetSettings(r.Context(), models.EmailSettings{
+		UserUID:   userUID,
+		Language:  body.Language,
+		Immediate: body.Immediate,
+		Digest:    body.Digest,
+	})
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, settings)
+}
+
+// handleGetWebhooks
+// @Summary      Вебхуки(secure, admin)
+// @Description  Все вебхуки, без секретов.
+// @Tags         Вебхуки
+// @Produce      json
+// @Success      200  {array}   models.Webhook
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/webhooks [get]
+func (s *HTTPServer) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.webhookService.GetWebhooks(r.Context())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, webhooks)
+}
+
+// handleCreateWebhook
+// @Summary      Создание вебхука(secure, admin)
+// @Description  События: idea.created, idea.updated (в том числе смена статуса), idea.votes, comment.added.
+// @Description  Тело запроса к вебхуку - JSON {id, type, createdAt, data}, data - объект как в API.
+// @Description  Заголовок X-Agora-Signature - sha256= и hex HMAC-SHA256 тела с секретом; без secret он
+// @Description  генерируется. Секрет возвращается только здесь.
+// @Tags         Вебхуки
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body  models.WebhookRequest  true  "Webhook"
+// @Success      201  {object}  models.Webhook
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/webhooks [post]
+func (s *HTTPServer) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var body models.WebhookRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	webhook, err := s.webhookService.CreateWebhook(r.Context(), userUID, body)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, webhook)
+}
+
+// handleUpdateWebhook
+// @Summary      Изменение вебхука(secure, admin)
+// @Description  Пустой secret оставляет прежний, без active вебхук не включается и не выключается.
+// @Tags         Вебхуки
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int                    true  "Webhook ID"
+// @Param        webhook  body  models.WebhookRequest  true  "Webhook"
+// @Success      200  {object}  models.Webhook
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Webhook not found"
+// @Router       /admin/webhooks/{id} [put]
+func (s *HTTPServer) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	var body models.WebhookRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	webhook, err := s.webhookService.UpdateWebhook(r.Context(), id, body)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, webhook)
+}
+
+// handleDeleteWebhook
+// @Summary      Удаление вебхука(secure, admin)
+// @Description  Удаляет вебхук вместе с журналом доставок.
+// @Tags         Вебхуки
+// @Param        id  path  int  true  "Webhook ID"
+// @Success      204
+// @Failure      404  {object}  response.ErrorResponse  "Webhook not found"
+// @Router       /admin/webhooks/{id} [delete]
+func (s *HTTPServer) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	if err := s.webhookService.DeleteWebhook(r.Context(), id); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTestWebhook
+// @Summary      Тестовое событие(secure, admin)
+// @Description  Сразу отправляет событие ping (даже выключенному вебхуку) и возвращает доставку с журналом.
+// @Description  Неудачный ping не повторяется.
+// @Tags         Вебхуки
+// @Produce      json
+// @Param        id  path  int  true  "Webhook ID"
+// @Success      200  {object}  models.WebhookDelivery
+// @Failure      404  {object}  response.ErrorResponse  "Webhook not found"
+// @Router       /admin/webhooks/{id}/test [post]
+func (s *HTTPServer) handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	delivery, err := s.webhookService.TestWebhook(r.Context(), id)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, delivery)
+}
+
+// handleGetWebhookDeliveries
+// @Summary      Доставки вебхука(secure, admin)
+// @Description  Последние доставки, новые сначала. state=dead - недоставленные после всех попыток.
+// @Tags         Вебхуки
+// @Produce      json
+// @Param        id     path   int     true   "Webhook ID"
+// @Param        state  query  string  false  "pending, delivered или dead"
+// @Param        limit  query  int     false  "До 100, по умолчанию 50"
+// @Success      200  {array}   models.WebhookDelivery
+// @Failure      404  {object}  response.ErrorResponse  "Webhook not found"
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func (s *HTTPServer) handleGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	state := r.URL.Query().Get("state")
+	if err := s.validate.Var("state", state, "omitempty,oneof=pending delivered dead"); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	limit, err := s.queryInt(r, "limit", defaultDeliveryPage, "min=1,max=100")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	deliveries, err := s.webhookService.GetDeliveries(r.Context(), id, models.DeliveryState(state), limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, deliveries)
+}
+
+// handleGetWebhookDelivery
+// @Summary      Доставка вебхука(secure, admin)
+// @Description  Доставка с журналом попыток: код ответа, ошибка, длительность.
+// @Tags         Вебхуки
+// @Produce      json
+// @Param        id  path  int  true  "Delivery ID"
+// @Success      200  {object}  models.WebhookDelivery
+// @Failure      404  {object}  response.ErrorResponse  "Delivery not found"
+// @Router       /admin/webhooks/deliveries/{id} [get]
+func (s *HTTPServer) handleGetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	delivery, err := s.webhookService.GetDelivery(r.Context(), id)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, delivery)
+}
+
+// handleRedeliverWebhook
+// @Summary      Повторная доставка(secure, admin)
+// @Description  Ставит доставленное или недоставленное событие в очередь заново, с тем же id и телом.
+// @Tags         Вебхуки
+// @Produce      json
+// @Param        id  path  int  true  "Delivery ID"
+// @Success      200  {object}  models.WebhookDelivery
+// @Failure      404  {object}  response.ErrorResponse  "Delivery not found"
+// @Failure      409  {object}  response.ErrorResponse  "Delivery is still pending"
+// @Router       /admin/webhooks/deliveries/{id}/redeliver [post]
+func (s *HTTPServer) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	delivery, err := s.webhookService.Redeliver(r.Context(), id)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, delivery)
+}
+
+// handleCreateStreamTicket
+// @Summary      Билет для потока событий(secure)
+// @Description  Одноразовый билет на минуту для GET /events?ticket=: JWT не попадает в адрес и журналы.
+// @Tags         События
+// @Produce      json
+// @Success      201  {object}  models.StreamTicket
+// @Router       /events/ticket [post]
+func (s *HTTPServer) handleCreateStreamTicket(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	ticket, err := s.authService.IssueStreamTicket(r.Context(), userUID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, ticket)
+}
+
+// handleEvents
+// @Summary      Поток событий(secure)
+// @Description  Server-Sent Events. Всегда приходят события ленты идей (idea.created, idea.updated, idea.votes)
+// @Description  и уведомления текущего пользователя (notification); comment.added, idea.updated и idea.votes
+// @Description  идей из параметров idea - тоже. Имя SSE-события - тип, data - JSON (null, если событие было
+// @Description  слишком большим для рассылки между репликами - тогда объект перечитывают).
+// @Description  EventSource не умеет заголовки, поэтому вместо JWT передаётся одноразовый билет из
+// @Description  POST /events/ticket; при переподключении нужен новый билет.
+// @Tags         События
+// @Produce      text/event-stream
+// @Param        idea    query  []string  false  "UID идей, до 50"  collectionFormat(multi)
+// @Param        ticket  query  string    true   "Одноразовый билет"
+// @Success      200  {string}  string  "event stream"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      401  {object}  response.ErrorResponse  "Invalid, used or expired ticket"
+// @Router       /events [get]
+func (s *HTTPServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ideas := r.URL.Query()["idea"]
+	if err := s.validate.Var("idea", ideas, fmt.Sprintf("max=%d,dive,uuid", maxEventIdeas)); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	topics := []string{models.TopicIdeas, models.UserTopic(userUID)}
+	for _, uid := range ideas {
+		topics = append(topics, models.IdeaTopic(uid))
+	}
+
+	sub := s.eventHub.Subscribe(topics...)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// the stream outlives any write deadline of the server
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "retry: %d\n\n", eventRetry.Milliseconds())
+	if err := rc.Flush(); err != nil {
+		s.log.ErrorContext(r.Context(), "event stream can't be flushed", slog.String("error", err.Error()))
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": ping\n\n")
+		case msg, ok := <-sub.C:
+			if !ok {
+				// dropped for falling behind, the client reconnects
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, msg.Data)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleGetUser
+// @Summary      Получение юзера по UID
+// @Description  Возвращает данные пользователя по UID.
+// @Tags         Пользователи
+// @Produce      json
+// @Param        uid   path      string  true  "User UID"
+// @Success      200   {object}  models.User
+// @Failure      404   {object}  response.ErrorResponse  "User not found"
+// @Failure      500   {object}  response.ErrorResponse  "Failed to get user"
+// @Router       /users/{uid} [get]
+func (s *HTTPServer) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	user, err := s.userService.GetUserByUID(r.Context(), uid)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, user)
+}
+
+// handleUploadUserPFP
+// @Summary      Загрузка PFP
+// @Description  Загрузка новой аватарки для юзера.
+// @Tags         Пользователи
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        profile_picture  formData  file  true  "Profile picture file"
+// @Success      200  {object}  map[string]string  "url to uploaded picture"
+// @Failure      400  {object}  response.ErrorResponse  "No file uploaded or bad request"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to upload file"
+// @Router       /users/pfp [post]
+func (s *HTTPServer) handleUploadUserPFP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPFPSize)
+	file, header, err := r.FormFile("profile_picture")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.error(w, r, apperr.TooLarge("profile picture must be at most 5 MiB").WithCause(err))
+		return
+	}
+	if err != nil {
+		s.error(w, r, apperr.Validation("no file uploaded").WithCause(err))
+		return
+	}
+	defer file.Close()
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+
+	url, err := s.userService.UploadPFP(r.Context(), file, header, userUID)
+	if err != nil {
+		s.log.ErrorContext(r.Context(), "failed to upload user profile", slog.String("error", err.Error()))
+		s.error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"url": url})
+}
+
+// handleIncreaseLikes
+// @Summary      Увеличение лайков
+// @Description  Увеличение лайков. За идеи кампании голосуют до ее окончания.
+// @Tags         Идеи
 // @Produce      json
 // @Param        uid   path      string  true  "Idea UID"
 // @Success      200  {string}  string  "ok"
-// @Failure      400  {string}  string  "Bad request"
-// @Failure      405  {string}  string  "Invalid method"
-// @Failure      500  {string}  string  "Failed to increase dislikes"
-// @Router       /ideas/{uid}/dislike [post]
-func (s *HTTPServer) handleIncreaseDislikes(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid method", http.StatusMethodNotAllowed)
-	}
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Failure      409  {object}  response.ErrorResponse  "Voting in the campaign has closed"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to increase likes"
+// @Router       /ideas/{uid}/like [post]
+func (s *HTTPServer) handleIncreaseLikes(w http.ResponseWriter, r *http.Request) {
 	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	ideaUID, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
 
-	ok, err := s.ideaService.CheckVote(chi.URLParam(r, "uid"), userUID)
+	_, err = s.ideaService.Vote(r.Context(), ideaUID, userUID, models.VoteLike)
 	if err != nil {
-		s.log.Error("failed to check liked user", slog.String("error", err.Error()))
+		s.log.ErrorContext(r.Context(), "failed to increase likes", slog.String("error", err.Error()))
+		s.error(w, r, err)
+		return
 	}
-	if ok {
-		err = s.ideaService.IncrementDislikes(chi.URLParam(r, "uid"))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// handleIncreaseDislikes
+// @Summary      Увеличение дизлайков
+// @Description  Увеличение дизлайков. За идеи кампании голосуют до ее окончания.
+// @Tags         Идеи
+// @Produce      json
+// @Param        uid   path      string  true  "Idea UID"
+// @Success      200  {string}  string  "ok"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Idea not found"
+// @Failure      409  {object}  response.ErrorResponse  "Voting in the campaign has closed"
+// @Failure      500  {object}  response.ErrorResponse  "Failed to increase dislikes"
+// @Router       /ideas/{uid}/dislike [post]
+func (s *HTTPServer) handleIncreaseDislikes(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	ideaUID, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
 	}
+
+	_, err = s.ideaService.Vote(r.Context(), ideaUID, userUID, models.VoteDislike)
 	if err != nil {
-		s.log.Error("failed to increase dislikes", slog.String("error", err.Error()))
-		http.Error(w, "Failed to increase dislikes", http.StatusInternalServerError)
+		s.log.ErrorContext(r.Context(), "failed to increase dislikes", slog.String("error", err.Error()))
+		s.error(w, r, err)
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
+
+// handleCreateBotLinkCode
+// @Summary      Код привязки мессенджера(secure)
+// @Description  Одноразовый код на 10 минут: пользователь отправляет боту /link КОД. В links - ссылки,
+// @Description  открывающие бота с уже введённым кодом, по платформам. Новый код отменяет прежний.
+// @Tags         Бот
+// @Produce      json
+// @Success      201  {object}  models.BotLinkCode
+// @Failure      404  {object}  response.ErrorResponse  "No bot is configured"
+// @Router       /bot/link-code [post]
+func (s *HTTPServer) handleCreateBotLinkCode(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	code, err := s.botService.CreateLinkCode(r.Context(), userUID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, code)
+}
+
+// handleGetBotAccounts
+// @Summary      Привязанные мессенджеры(secure)
+// @Tags         Бот
+// @Produce      json
+// @Success      200  {array}  models.BotAccount
+// @Router       /bot/accounts [get]
+func (s *HTTPServer) handleGetBotAccounts(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	accounts, err := s.botService.GetAccounts(r.Context(), userUID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, accounts)
+}
+
+// handleUnlinkBotAccount
+// @Summary      Отвязка мессенджера(secure)
+// @Description  Бот перестаёт принимать команды из аккаунта и присылать в него уведомления.
+// @Tags         Бот
+// @Param        platform  path  string  true  "Platform, e.g. telegram"
+// @Param        id        path  string  true  "Account ID on the platform"
+// @Success      204
+// @Failure      404  {object}  response.ErrorResponse  "Account not found"
+// @Router       /bot/accounts/{platform}/{id} [delete]
+func (s *HTTPServer) handleUnlinkBotAccount(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	err := s.botService.Unlink(r.Context(), userUID, chi.URLParam(r, "platform"), chi.URLParam(r, "id"))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleBotWebhook
+// @Summary      Обновления от мессенджера
+// @Description  Сюда мессенджер присылает сообщения боту, если бот работает через вебхук, а не long polling.
+// @Description  Telegram подписывает запросы заголовком X-Telegram-Bot-Api-Secret-Token.
+// @Tags         Бот
+// @Accept       json
+// @Param        platform  path  string  true  "Platform, e.g. telegram"
+// @Success      200
+// @Failure      401  {object}  response.ErrorResponse  "Not from the messenger"
+// @Failure      404  {object}  response.ErrorResponse  "Unknown platform"
+// @Router       /bot/{platform}/webhook [post]
+func (s *HTTPServer) handleBotWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := s.botService.Webhook(r.Context(), chi.URLParam(r, "platform"), r); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetCampaigns
+// @Summary      Кампании(secure)
+// @Description  Все кампании, начинающиеся позже - первыми. state - upcoming, open или closed.
+// @Tags         Кампании
+// @Produce      json
+// @Success      200  {array}   models.Campaign
+// @Router       /campaigns [get]
+func (s *HTTPServer) handleGetCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := s.campaignService.GetCampaigns(r.Context())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, campaigns)
+}
+
+// handleGetCampaign
+// @Summary      Страница кампании(secure)
+// @Description  Кампания, ее статистика (идеи, их авторы, проголосовавшие, голоса, комментарии) и первые
+// @Description  места рейтинга.
+// @Tags         Кампании
+// @Produce      json
+// @Param        id  path  int  true  "Campaign ID"
+// @Success      200  {object}  models.CampaignPage
+// @Failure      404  {object}  response.ErrorResponse  "Campaign not found"
+// @Router       /campaigns/{id} [get]
+func (s *HTTPServer) handleGetCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	page, err := s.campaignService.GetCampaign(r.Context(), id)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, page)
+}
+
+// handleGetCampaignLeaderboard
+// @Summary      Рейтинг идей кампании(secure)
+// @Description  Идеи кампании по убыванию score - лайков за вычетом дизлайков, при равенстве - по лайкам и
+// @Description  затем по дате. Идеи с равным score делят место.
+// @Tags         Кампании
+// @Produce      json
+// @Param        id     path   int  true   "Campaign ID"
+// @Param        limit  query  int  false  "Places (1-100, default 10)"
+// @Success      200  {array}   models.LeaderboardEntry
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Campaign not found"
+// @Router       /campaigns/{id}/leaderboard [get]
+func (s *HTTPServer) handleGetCampaignLeaderboard(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	limit, err := s.queryInt(r, "limit", defaultLeaderboard, "min=1,max=100")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	entries, err := s.campaignService.GetLeaderboard(r.Context(), id, limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, entries)
+}
+
+// handleCreateCampaign
+// @Summary      Создание кампании(secure, admin)
+// @Description  Идеи подаются в кампанию с startsAt до endsAt, голосование за них закрывается в endsAt.
+// @Description  categoryIds и positionIds ограничивают категории идей и должности авторов, пустые - любые.
+// @Tags         Кампании
+// @Accept       json
+// @Produce      json
+// @Param        campaign  body  models.CampaignRequest  true  "Campaign"
+// @Success      201  {object}  models.Campaign
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/campaigns [post]
+func (s *HTTPServer) handleCreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var body models.CampaignRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	campaign, err := s.campaignService.CreateCampaign(r.Context(), userUID, body)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, campaign)
+}
+
+// handleUpdateCampaign
+// @Summary      Изменение кампании(secure, admin)
+// @Description  Заменяет все поля кампании, уже поданные идеи остаются в ней.
+// @Tags         Кампании
+// @Accept       json
+// @Produce      json
+// @Param        id        path  int                     true  "Campaign ID"
+// @Param        campaign  body  models.CampaignRequest  true  "Campaign"
+// @Success      200  {object}  models.Campaign
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Campaign not found"
+// @Router       /admin/campaigns/{id} [put]
+func (s *HTTPServer) handleUpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	var body models.CampaignRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	campaign, err := s.campaignService.UpdateCampaign(r.Context(), id, body)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, campaign)
+}
+
+// handleDeleteCampaign
+// @Summary      Удаление кампании(secure, admin)
+// @Description  Удаляет кампанию, ее идеи остаются без кампании.
+// @Tags         Кампании
+// @Param        id  path  int  true  "Campaign ID"
+// @Success      204
+// @Failure      404  {object}  response.ErrorResponse  "Campaign not found"
+// @Router       /admin/campaigns/{id} [delete]
+func (s *HTTPServer) handleDeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathID(r, "id")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	if err := s.campaignService.DeleteCampaign(r.Context(), id); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetJobs
+// @Summary      Фоновые задачи(secure, admin)
+// @Description  Задачи по расписанию: выражение cron, время следующего запуска и последний запуск.
+// @Tags         Фоновые задачи
+// @Produce      json
+// @Success      200  {array}   models.Job
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/jobs [get]
+func (s *HTTPServer) handleGetJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := s.jobService.GetJobs(r.Context())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, jobs)
+}
+
+// handleRunJob
+// @Summary      Запуск фоновой задачи(secure, admin)
+// @Description  Запускает задачу вне расписания. Задача выполняется в фоне, ее итог - в истории запусков.
+// @Tags         Фоновые задачи
+// @Produce      json
+// @Param        name  path  string  true  "Job name"
+// @Success      202  {object}  models.JobRun
+// @Failure      404  {object}  response.ErrorResponse  "Job not found"
+// @Failure      409  {object}  response.ErrorResponse  "Job is already running"
+// @Router       /admin/jobs/{name}/run [post]
+func (s *HTTPServer) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	userUID := r.Context().Value(mware.ContextUserUID).(string)
+	run, err := s.jobService.Trigger(r.Context(), chi.URLParam(r, "name"), userUID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusAccepted, run)
+}
+
+// handleGetJobRuns
+// @Summary      История запусков задачи(secure, admin)
+// @Description  Последние запуски, новые сначала. Запуск, прерванный остановкой сервера, остается running.
+// @Tags         Фоновые задачи
+// @Produce      json
+// @Param        name   path   string  true   "Job name"
+// @Param        limit  query  int     false  "До 100, по умолчанию 20"
+// @Success      200  {array}   models.JobRun
+// @Failure      404  {object}  response.ErrorResponse  "Job not found"
+// @Router       /admin/jobs/{name}/runs [get]
+func (s *HTTPServer) handleGetJobRuns(w http.ResponseWriter, r *http.Request) {
+	limit, err := s.queryInt(r, "limit", defaultJobRunPage, "min=1,max=100")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	runs, err := s.jobService.GetRuns(r.Context(), chi.URLParam(r, "name"), limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, runs)
+}
+
+// handleGetSubmissions
+// @Summary      Поданные идеи(secure, admin)
+// @Description  Число идей, поданных за каждый период диапазона, включая периоды без идей. Периоды в UTC,
+// @Description  недели начинаются с понедельника. Отчеты аналитики кэшируются на 5 минут.
+// @Tags         Аналитика
+// @Produce      json
+// @Param        from   query  string  false  "Первый день, 2006-01-02; по умолчанию 30 дней до to"
+// @Param        to     query  string  false  "Последний день включительно, по умолчанию сегодня"
+// @Param        group  query  string  false  "day, week или month, по умолчанию day"
+// @Success      200  {object}  models.SubmissionReport
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/analytics/submissions [get]
+func (s *HTTPServer) handleGetSubmissions(w http.ResponseWriter, r *http.Request) {
+	rng, err := s.queryRange(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	group := r.URL.Query().Get("group")
+	if err := s.validate.Var("group", group, "omitempty,oneof=day week month"); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	report, err := s.analyticsService.GetSubmissions(r.Context(), rng, models.AnalyticsGroup(group))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
+
+// handleGetDecisions
+// @Summary      Решения по идеям(secure, admin)
+// @Description  Идеи, поданные за диапазон, всего и по категориям: одобренные и отклоненные по текущему статусу,
+// @Description  доля одобренных среди решенных и медиана часов от подачи до первого решения. Статусы решений
+// @Description  задаются APPROVED_STATUSES и REJECTED_STATUSES, иначе определяются по названию.
+// @Tags         Аналитика
+// @Produce      json
+// @Param        from  query  string  false  "Первый день, 2006-01-02; по умолчанию 30 дней до to"
+// @Param        to    query  string  false  "Последний день включительно, по умолчанию сегодня"
+// @Success      200  {object}  models.DecisionReport
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/analytics/decisions [get]
+func (s *HTTPServer) handleGetDecisions(w http.ResponseWriter, r *http.Request) {
+	rng, err := s.queryRange(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	report, err := s.analyticsService.GetDecisions(r.Context(), rng)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
+
+// handleGetContributors
+// @Summary      Самые активные(secure, admin)
+// @Description  Пользователи, подавшие больше всего идей и оставившие больше всего комментариев за диапазон.
+// @Tags         Аналитика
+// @Produce      json
+// @Param        from   query  string  false  "Первый день, 2006-01-02; по умолчанию 30 дней до to"
+// @Param        to     query  string  false  "Последний день включительно, по умолчанию сегодня"
+// @Param        limit  query  int     false  "До 100, по умолчанию 10"
+// @Success      200  {object}  models.ContributorReport
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/analytics/contributors [get]
+func (s *HTTPServer) handleGetContributors(w http.ResponseWriter, r *http.Request) {
+	rng, err := s.queryRange(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	limit, err := s.queryInt(r, "limit", defaultContributors, "min=1,max=100")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	report, err := s.analyticsService.GetContributors(r.Context(), rng, limit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
+
+// handleGetEngagement
+// @Summary      Вовлеченность по должностям(secure, admin)
+// @Description  Сколько сотрудников каждой должности подавали идеи, комментировали и голосовали за диапазон,
+// @Description  participation - доля проголосовавших. Сотрудники без должности учитываются только в total.
+// @Tags         Аналитика
+// @Produce      json
+// @Param        from  query  string  false  "Первый день, 2006-01-02; по умолчанию 30 дней до to"
+// @Param        to    query  string  false  "Последний день включительно, по умолчанию сегодня"
+// @Success      200  {object}  models.EngagementReport
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/analytics/engagement [get]
+func (s *HTTPServer) handleGetEngagement(w http.ResponseWriter, r *http.Request) {
+	rng, err := s.queryRange(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	report, err := s.analyticsService.GetEngagement(r.Context(), rng)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
+
+// exportTypes - content types of export formats
+var exportTypes = map[models.ExportFormat]string{
+	models.ExportCSV:  "text/csv; charset=utf-8",
+	models.ExportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// attachment sends the download headers with the first write, so that
+// errors found before it are still rendered as JSON
+type attachment struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (a *attachment) Write(p []byte) (int, error) {
+	if !a.started {
+		a.started = true
+		a.w.Header().Set("Content-Type", a.contentType)
+		a.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.filename}))
+		a.w.WriteHeader(http.StatusOK)
+	}
+	return a.w.Write(p)
+}
+
+// handleExport
+// @Summary      Выгрузка идей и обсуждений(secure, admin)
+// @Description  Идеи с названиями категорий и статусов, автором, голосами, просмотрами и числом комментариев;
+// @Description  /admin/export/discussions - все комментарии этих идей, строка на комментарий, ответы сразу после
+// @Description  комментария, на который отвечают. Идеи отбираются теми же параметрами, что и в GET /ideas.
+// @Description  CSV в UTF-8 с BOM (Excel открывает кириллицу без перекодировки), тексты, которые таблица
+// @Description  приняла бы за формулу, начинаются с апострофа. Файл передается по мере формирования.
+// @Tags         Выгрузка
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format  query  string  false  "csv или xlsx, по умолчанию csv"
+// @Param        q       query  string  false  "Search query"
+// @Param        limit   query  int     false  "Max search results (1-100, default 20)"
+// @Param        sort    query  string  false  "hot, top, controversial или rising"
+// @Success      200  {file}    file
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Router       /admin/export/ideas [get]
+// @Router       /admin/export/discussions [get]
+func (s *HTTPServer) handleExport(name string,
+	export func(context.Context, models.IdeaFilter, models.ExportFormat, io.Writer) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if err := s.validate.Var("format", format, "omitempty,oneof=csv xlsx"); err != nil {
+			s.error(w, r, err)
+			return
+		}
+		if format == "" {
+			format = string(models.ExportCSV)
+		}
+		filter, err := s.ideaFilter(r)
+		if err != nil {
+			s.error(w, r, err)
+			return
+		}
+
+		out := &attachment{
+			w:           w,
+			contentType: exportTypes[models.ExportFormat(format)],
+			filename:    fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format(time.DateOnly), format),
+		}
+		err = export(r.Context(), filter, models.ExportFormat(format), out)
+		if err != nil && !out.started {
+			s.error(w, r, err)
+			return
+		}
+		if err != nil {
+			// the file is cut short, the client sees a broken download
+			s.log.ErrorContext(r.Context(), "export failed", slog.String("export", name), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// handleImportUsers
+// @Summary      Импорт сотрудников из CSV(secure, admin)
+// @Description  Заводит пользователей по выгрузке из HR-системы. Колонки узнаются по заголовку: name/Имя,
+// @Description  surname/Фамилия, email/E-mail/Почта, position/Должность обязательны, phone/Телефон и
+// @Description  hire_date/Дата приема - нет, прочие колонки пропускаются. Разделитель - запятая, точка с запятой
+// @Description  или табуляция, файл в UTF-8 (с BOM или без), до 5 МиБ и 5000 строк. Телефон приводится к 10
+// @Description  цифрам без +7/8, дата - 2024-09-01 или 01.09.2024. Должности сравниваются без учета регистра,
+// @Description  недостающие создаются. Без commit=true это пробный прогон: отчет о том, кем станет каждая
+// @Description  строка, и ошибках по строкам. Файл импортируется целиком: при любой ошибке не создается никто
+// @Description  (422 с отчетом). Созданным пользователям уходит приглашение задать пароль, до этого войти
+// @Description  они не могут. Ошибки файла целиком (нет колонки, пустой файл) - 400 с деталями.
+// @Tags         Пользователи
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file    formData  file  true   "CSV file"
+// @Param        commit  query     bool  false  "Создать пользователей, по умолчанию пробный прогон"
+// @Success      200  {object}  models.ImportReport  "Пробный прогон"
+// @Success      201  {object}  models.ImportReport  "Пользователи созданы"
+// @Failure      400  {object}  response.ErrorResponse  "Invalid file"
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Failure      413  {object}  response.ErrorResponse  "File too large"
+// @Failure      422  {object}  models.ImportReport  "Rows have errors, nothing is imported"
+// @Router       /admin/users/import [post]
+func (s *HTTPServer) handleImportUsers(w http.ResponseWriter, r *http.Request) {
+	commit := false
+	if raw := r.URL.Query().Get("commit"); raw != "" {
+		var err error
+		if commit, err = strconv.ParseBool(raw); err != nil {
+			s.error(w, r, apperr.Validation("request validation failed").
+				WithDetails([]validation.FieldError{{Field: "commit", Message: "must be a boolean"}}))
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, _, err := r.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.error(w, r, apperr.TooLarge(fmt.Sprintf("the file must be at most %d MiB", maxImportFile>>20)).WithCause(err))
+		return
+	}
+	if err != nil {
+		s.error(w, r, apperr.Validation("no file uploaded").WithCause(err))
+		return
+	}
+	defer file.Close()
+
+	report, err := s.onboardingService.Import(r.Context(), file, commit)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	switch {
+	case report.Committed:
+		status = http.StatusCreated
+	case commit:
+		status = http.StatusUnprocessableEntity
+	}
+	response.JSON(w, status, report)
+}
+
+// handleInviteUser
+// @Summary      Повторное приглашение(secure, admin)
+// @Description  Отправляет импортированному пользователю новое приглашение задать пароль, прежнее перестает
+// @Description  действовать. Для тех, кому письмо не дошло или у кого истек срок ссылки (14 дней).
+// @Tags         Пользователи
+// @Param        uid  path  string  true  "User UID"
+// @Success      204
+// @Failure      403  {object}  response.ErrorResponse  "Not an admin"
+// @Failure      404  {object}  response.ErrorResponse  "User not found"
+// @Failure      409  {object}  response.ErrorResponse  "The user has already set a password"
+// @Router       /admin/users/{uid}/invitation [post]
+func (s *HTTPServer) handleInviteUser(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.pathUID(r, "uid")
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	if err := s.onboardingService.Invite(r.Context(), uid); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAcceptInvitation
+// @Summary      Принятие приглашения
+// @Description  Задает пароль импортированного пользователя по токену из ссылки в приглашении, после этого
+// @Description  он входит через /login. Ссылка одноразовая.
+// @Tags         Авторизация\Регистрация
+// @Accept       json
+// @Produce      json
+// @Param        acceptInvitationRequest  body  models.AcceptInvitationRequest  true  "Token and password"
+// @Success      200  {object}  map[string]string  "message: ok"
+// @Failure      400  {object}  response.ErrorResponse  "Bad request"
+// @Failure      404  {object}  response.ErrorResponse  "Invitation not found or expired"
+// @Router       /invitations/accept [post]
+func (s *HTTPServer) handleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var body models.AcceptInvitationRequest
+	if err := s.decode(w, r, &body); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	if err := s.onboardingService.AcceptInvitation(r.Context(), body.Token, body.Password); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, map[string]string{"message": "ok"})
+}
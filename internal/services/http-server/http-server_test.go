@@ -0,0 +1,208 @@
+package http_server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/jwt"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/analytics"
+	"github.com/TP2-Voice-Agora/backend/internal/services/auth"
+	"github.com/TP2-Voice-Agora/backend/internal/services/bot"
+	"github.com/TP2-Voice-Agora/backend/internal/services/campaigns"
+	"github.com/TP2-Voice-Agora/backend/internal/services/email"
+	"github.com/TP2-Voice-Agora/backend/internal/services/export"
+	"github.com/TP2-Voice-Agora/backend/internal/services/http-server/response"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/TP2-Voice-Agora/backend/internal/services/jobs"
+	"github.com/TP2-Voice-Agora/backend/internal/services/notifications"
+	"github.com/TP2-Voice-Agora/backend/internal/services/onboarding"
+	"github.com/TP2-Voice-Agora/backend/internal/services/realtime"
+	"github.com/TP2-Voice-Agora/backend/internal/services/reports"
+	"github.com/TP2-Voice-Agora/backend/internal/services/users"
+	"github.com/TP2-Voice-Agora/backend/internal/services/webhooks"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jwtSecret = "test-secret"
+
+// accessLog - what middleware.Logger writes, safe for the handlers
+// finishing in the background
+type accessLog struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *accessLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *accessLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+// setup serves the routes over the in-memory repository, requests are
+// logged to the returned access log
+func setup(t *testing.T) (*httptest.Server, *memory.Repository, *accessLog) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+
+	accesses := &accessLog{}
+	defaultLogger := middleware.DefaultLogger
+	middleware.DefaultLogger = middleware.RequestLogger(&middleware.DefaultLogFormatter{
+		Logger: log.New(accesses, "", 0), NoColor: true})
+	t.Cleanup(func() { middleware.DefaultLogger = defaultLogger })
+
+	ideaService, err := ideas.New(ctx, *logger, repo)
+	require.NoError(t, err)
+	mailer := email.New(*logger, repo, nil, "")
+	server := NewHTTPServer(ideaService, auth.New(*logger, repo, time.Hour, jwtSecret), users.New(*logger, repo),
+		notifications.New(*logger, repo), mailer, webhooks.New(*logger, repo),
+		bot.New(*logger, repo, ideaService, ""), campaigns.New(*logger, repo),
+		jobs.New(*logger, repo, jobs.NewLocalLocker()), analytics.New(*logger, repo), export.New(*logger, repo),
+		reports.New(*logger, repo), onboarding.New(*logger, repo, mailer), realtime.NewHub(*logger), logger)
+
+	ts := httptest.NewServer(server.SetupRoutes())
+	t.Cleanup(ts.Close)
+	return ts, repo, accesses
+}
+
+func request(t *testing.T, method, url, token string, body io.Reader) *http.Request {
+	req, err := http.NewRequest(method, url, body)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestEventsTicket(t *testing.T) {
+	ts, repo, accesses := setup(t)
+	user := repotest.User(t, repo, "alice@example.com")
+	token := jwt.NewToken(user, time.Hour, jwtSecret)
+
+	resp, err := http.DefaultClient.Do(request(t, http.MethodPost, ts.URL+"/events/ticket", token, nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var ticket models.StreamTicket
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ticket))
+	require.NotEmpty(t, ticket.Ticket)
+
+	idea := repotest.Idea(t, repo, user.UID, "idea")
+	ctx, cancel := context.WithCancel(context.Background())
+	req := request(t, http.MethodGet, ts.URL+"/events?idea="+idea.IdeaUID+"&ticket="+ticket.Ticket, "", nil)
+	stream, err := http.DefaultClient.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+	line, err := bufio.NewReader(stream.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "retry: 3000\n", line)
+	cancel()
+	stream.Body.Close()
+
+	// tickets are one-time, JWTs are not taken in the query any more
+	for _, query := range []string{"ticket=" + ticket.Ticket, "token=" + token, ""} {
+		resp, err := http.DefaultClient.Get(ts.URL + "/events?" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, query)
+	}
+
+	assert.Eventually(t, func() bool { return strings.Count(accesses.String(), `"GET `) == 4 },
+		time.Second, 10*time.Millisecond, "the stream is logged when it ends")
+	logged := accesses.String()
+	assert.Contains(t, logged, "GET "+ts.URL+"/events?idea="+idea.IdeaUID+" HTTP/1.1")
+	assert.NotContains(t, logged, ticket.Ticket)
+	assert.NotContains(t, logged, token)
+	assert.NotContains(t, logged, "ticket=")
+	assert.NotContains(t, logged, "token=")
+}
+
+// csvUpload - a multipart form with a CSV file of the given size
+func csvUpload(t *testing.T, size int) (io.Reader, string) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	file, err := form.CreateFormFile("file", "users.csv")
+	require.NoError(t, err)
+	_, err = file.Write([]byte("email,name,surname,position\n" + strings.Repeat("x", size-28)))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	return body, form.FormDataContentType()
+}
+
+func TestImportUsersTooLarge(t *testing.T) {
+	ts, repo, _ := setup(t)
+	admin := models.User{UID: uuid.NewString(), Email: "admin@example.com", PositionID: 1, IsAdmin: true}
+	require.NoError(t, repo.InsertUser(context.Background(), admin))
+	token := jwt.NewToken(admin, time.Hour, jwtSecret)
+
+	for name, size := range map[string]int{
+		"over the file limit": maxImportFile + 1,
+		"over the body limit": maxImportSize,
+	} {
+		body, contentType := csvUpload(t, size)
+		req := request(t, http.MethodPost, ts.URL+"/admin/users/import", token, body)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err, name)
+		var envelope response.ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&envelope), name)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, name)
+		assert.Contains(t, envelope.Error.Message, "5 MiB", name)
+	}
+}
+
+func TestIdeaFilter(t *testing.T) {
+	ts, repo, _ := setup(t)
+	admin := models.User{UID: uuid.NewString(), Email: "admin@example.com", PositionID: 1, IsAdmin: true}
+	require.NoError(t, repo.InsertUser(context.Background(), admin))
+	token := jwt.NewToken(admin, time.Hour, jwtSecret)
+	repotest.Idea(t, repo, admin.UID, "apple pie")
+	repotest.Idea(t, repo, admin.UID, "banana bread")
+
+	get := func(url string) *http.Response {
+		resp, err := http.DefaultClient.Do(request(t, http.MethodGet, ts.URL+url, token, nil))
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	for query, found := range map[string]int{"": 2, "?q=": 2, "?q=apple": 1, "?q=apple&sort=top": 1, "?sort=top": 2} {
+		resp := get("/ideas" + query)
+		require.Equal(t, http.StatusOK, resp.StatusCode, query)
+		var ideas []models.Idea
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ideas), query)
+		assert.Len(t, ideas, found, query)
+	}
+
+	// the list and the exports reject the same parameters
+	for _, query := range []string{"?sort=new", "?q=apple&limit=0", "?q=" + strings.Repeat("x", 201)} {
+		for _, path := range []string{"/ideas", "/admin/export/ideas", "/admin/export/discussions"} {
+			assert.Equal(t, http.StatusBadRequest, get(path+query).StatusCode, path+query)
+		}
+	}
+}
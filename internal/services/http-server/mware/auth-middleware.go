@@ -2,7 +2,12 @@ package mware
 
 import (
 	"context"
+	"errors"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
 	"github.com/TP2-Voice-Agora/backend/internal/lib/jwt"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/logger/logctx"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/services/http-server/response"
 	i "github.com/TP2-Voice-Agora/backend/internal/services/interfaces"
 	"log/slog"
 	"net/http"
@@ -14,6 +19,7 @@ type contextKey string
 const (
 	ContextUserUID   contextKey = "userUID"
 	ContextUserEmail contextKey = "userEmail"
+	ContextUserAdmin contextKey = "userAdmin"
 )
 
 func AuthMiddleware(jwtSecret string, log *slog.Logger, s i.UserService) func(http.Handler) http.Handler {
@@ -21,41 +27,101 @@ func AuthMiddleware(jwtSecret string, log *slog.Logger, s i.UserService) func(ht
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if !strings.HasPrefix(authHeader, "Bearer ") {
-				log.Error("No authorization header found" + authHeader)
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				log.ErrorContext(r.Context(), "No authorization header found"+authHeader)
+				response.Error(w, r, log, apperr.Unauthorized("authorization header is required"))
 				return
 			}
 			token := strings.TrimPrefix(authHeader, "Bearer ")
 			if token == "" {
-				http.Error(w, "No token provided", http.StatusUnauthorized)
+				response.Error(w, r, log, apperr.Unauthorized("no token provided"))
 				return
 			}
 
 			uid, email, err := jwt.ParseToken(token, jwtSecret)
 			if err != nil {
 
-				log.Error("Failed to parse token", slog.String("error", err.Error()), slog.String("token", token))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				log.ErrorContext(r.Context(), "Failed to parse token", slog.String("error", err.Error()), slog.String("token", token))
+				response.Error(w, r, log, apperr.Unauthorized("invalid token").WithCause(err))
 				return
 			}
 
-			u, err := s.GetUserByUID(uid)
+			u, err := currentUser(r, log, s, uid)
 			if err != nil {
-				log.Error("Failed to get user by uid", slog.String("error", err.Error()))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				response.Error(w, r, log, err)
+				return
 			}
 
-			if u.ReAuth == true {
-				log.Warn("User re-auth, token will be reset", slog.String("uid", uid), slog.String("email", email))
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			next.ServeHTTP(w, withUser(r, uid, email, u.IsAdmin))
+		})
+	}
+}
+
+// StreamTicket authenticates the event stream by a one-time ticket in the
+// query (see AuthService.IssueStreamTicket), as EventSource can't send the
+// Authorization header. Must go after HideSecrets.
+func StreamTicket(log *slog.Logger, a i.AuthService, s i.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ticket := QuerySecret(r, "ticket")
+			if ticket == "" {
+				response.Error(w, r, log, apperr.Unauthorized("ticket is required, get one with POST /events/ticket"))
+				return
+			}
+			uid, err := a.RedeemStreamTicket(r.Context(), ticket)
+			if err != nil {
+				response.Error(w, r, log, err)
 				return
 			}
 
-			// Кладём uid и email в context
-			ctx := context.WithValue(r.Context(), ContextUserUID, uid)
-			ctx = context.WithValue(ctx, ContextUserEmail, email)
+			u, err := currentUser(r, log, s, uid)
+			if err != nil {
+				response.Error(w, r, log, err)
+				return
+			}
 
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, withUser(r, uid, u.Email, u.IsAdmin))
+		})
+	}
+}
+
+// currentUser loads the user a credential was issued to, users who have to
+// log in again are rejected
+func currentUser(r *http.Request, log *slog.Logger, s i.UserService, uid string) (models.User, error) {
+	u, err := s.GetUserByUID(r.Context(), uid)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return models.User{}, apperr.Unauthorized("invalid token").WithCause(err)
+	}
+	if err != nil {
+		log.ErrorContext(r.Context(), "Failed to get user by uid", slog.String("error", err.Error()))
+		return models.User{}, err
+	}
+
+	if u.ReAuth == true {
+		log.WarnContext(r.Context(), "User re-auth, token will be reset", slog.String("uid", uid), slog.String("email", u.Email))
+		return models.User{}, apperr.Unauthorized("token has been revoked, please log in again")
+	}
+	return u, nil
+}
+
+// withUser puts the user into the request context
+func withUser(r *http.Request, uid, email string, admin bool) *http.Request {
+	// Кладём uid и email в context
+	ctx := context.WithValue(r.Context(), ContextUserUID, uid)
+	ctx = context.WithValue(ctx, ContextUserEmail, email)
+	ctx = context.WithValue(ctx, ContextUserAdmin, admin)
+	ctx = logctx.With(ctx, slog.String("user_uid", uid))
+	return r.WithContext(ctx)
+}
+
+// AdminOnly lets only admins through, must go after AuthMiddleware
+func AdminOnly(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if admin, _ := r.Context().Value(ContextUserAdmin).(bool); !admin {
+				response.Error(w, r, log, apperr.Forbidden("only an admin can do this"))
+				return
+			}
+			next.ServeHTTP(w, r)
 		})
 	}
 }
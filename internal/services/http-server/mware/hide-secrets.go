@@ -0,0 +1,40 @@
+package mware
+
+import (
+	"context"
+	"net/http"
+)
+
+// secretParams - query parameters carrying credentials: stream tickets and
+// JWTs old clients still send to /events
+var secretParams = []string{"ticket", "token"}
+
+type secretsKey struct{}
+
+// HideSecrets takes credentials out of the query before anything logs the
+// request URI, they are read with QuerySecret. Must go before
+// middleware.Logger.
+func HideSecrets(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		secrets := map[string]string{}
+		for _, name := range secretParams {
+			if query.Has(name) {
+				secrets[name] = query.Get(name)
+				query.Del(name)
+			}
+		}
+		if len(secrets) > 0 {
+			r = r.Clone(context.WithValue(r.Context(), secretsKey{}, secrets))
+			r.URL.RawQuery = query.Encode()
+			r.RequestURI = r.URL.RequestURI()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// QuerySecret returns the credential HideSecrets took out of the query
+func QuerySecret(r *http.Request, name string) string {
+	secrets, _ := r.Context().Value(secretsKey{}).(map[string]string)
+	return secrets[name]
+}
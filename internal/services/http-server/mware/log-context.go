@@ -0,0 +1,16 @@
+package mware
+
+import (
+	"github.com/TP2-Voice-Agora/backend/internal/lib/logger/logctx"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+)
+
+// LogContext puts request id into the logging context, must go after middleware.RequestID
+func LogContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := logctx.With(r.Context(), slog.String("request_id", middleware.GetReqID(r.Context())))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,83 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ErrorBody - body of every error response
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ErrorResponse - {"error": {...}} envelope
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// JSON writes v with the given status code
+func JSON(w http.ResponseWriter, status int, v any) {
+	resp, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(resp)
+}
+
+// Error renders err as an error envelope. Typed service errors are mapped
+// to their status codes, everything else is logged and hidden behind 500.
+func Error(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	status, code := classify(err)
+	body := ErrorBody{
+		Code:      code,
+		Message:   "internal server error",
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) && status != http.StatusInternalServerError {
+		body.Message = appErr.Message
+		body.Details = appErr.Details
+	}
+
+	if status == http.StatusInternalServerError {
+		log.ErrorContext(r.Context(), "request failed",
+			slog.String("path", r.URL.Path),
+			slog.String("error", err.Error()),
+		)
+	} else {
+		log.DebugContext(r.Context(), "request rejected",
+			slog.String("path", r.URL.Path),
+			slog.String("code", code),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	JSON(w, status, ErrorResponse{Error: body})
+}
+
+func classify(err error) (int, string) {
+	switch {
+	case errors.Is(err, apperr.ErrValidation):
+		return http.StatusBadRequest, "validation_failed"
+	case errors.Is(err, apperr.ErrUnauthorized):
+		return http.StatusUnauthorized, "unauthorized"
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden, "forbidden"
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict, "conflict"
+	case errors.Is(err, apperr.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge, "payload_too_large"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
@@ -0,0 +1,57 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_StatusMapping(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+		msg    string
+	}{
+		{apperr.Validation("bad name"), http.StatusBadRequest, "validation_failed", "bad name"},
+		{apperr.Unauthorized("no token"), http.StatusUnauthorized, "unauthorized", "no token"},
+		{apperr.Forbidden("not yours"), http.StatusForbidden, "forbidden", "not yours"},
+		{apperr.NotFound("idea not found"), http.StatusNotFound, "not_found", "idea not found"},
+		{apperr.Conflict("exists"), http.StatusConflict, "conflict", "exists"},
+		{apperr.TooLarge("too big"), http.StatusRequestEntityTooLarge, "payload_too_large", "too big"},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError, "internal", "internal server error"},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ideas", nil)
+		Error(rec, req, slog.Default(), c.err)
+
+		var body ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, c.status, rec.Code)
+		assert.Equal(t, c.code, body.Error.Code)
+		assert.Equal(t, c.msg, body.Error.Message)
+	}
+}
+
+func TestError_DetailsAndCause(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ideas", nil)
+	err := apperr.Validation("invalid request body").
+		WithDetails(map[string]string{"name": "required"}).
+		WithCause(errors.New("unexpected EOF"))
+	Error(rec, req, slog.Default(), err)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t,
+		`{"error":{"code":"validation_failed","message":"invalid request body","details":{"name":"required"}}}`,
+		rec.Body.String(),
+	)
+}
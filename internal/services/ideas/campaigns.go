@@ -0,0 +1,71 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// InsertCampaignIdea publishes the idea into the campaign. The campaign has
+// to be open, the category one of its categories and the author's position
+// one of its positions.
+func (i *Ideas) InsertCampaignIdea(ctx context.Context, campaignID int64, name, text, author string, status, category int) (models.Idea, error) {
+	return i.insertIdea(ctx, &campaignID, name, text, author, status, category)
+}
+
+// checkSubmission tells whether the author may submit an idea of the
+// category into the campaign now
+func (i *Ideas) checkSubmission(ctx context.Context, repo repository.Repository, campaignID int64, author string, category int) error {
+	campaign, err := repo.SelectCampaign(ctx, campaignID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("campaign not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch campaign.StateAt(i.now()) {
+	case models.CampaignUpcoming:
+		return apperr.Conflict("the campaign hasn't started yet")
+	case models.CampaignClosed:
+		return apperr.Conflict("the campaign has closed")
+	}
+	if len(campaign.CategoryIDs) > 0 && !slices.Contains(campaign.CategoryIDs, category) {
+		return apperr.Validation("the campaign doesn't accept ideas of this category").
+			WithDetails(map[string]models.IDs{"categoryIds": campaign.CategoryIDs})
+	}
+	if len(campaign.PositionIDs) > 0 {
+		user, err := repo.SelectUserByUID(ctx, author)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.Validation("unknown idea author")
+		}
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(campaign.PositionIDs, user.PositionID) {
+			return apperr.Forbidden("your position isn't eligible for the campaign")
+		}
+	}
+	return nil
+}
+
+// checkVoting fails with a conflict once the campaign of the idea has ended,
+// ideas outside campaigns are voted for at any time
+func (i *Ideas) checkVoting(ctx context.Context, repo repository.Repository, ideaUID string) error {
+	idea, err := repo.SelectIdeaByUID(ctx, ideaUID)
+	if err != nil || idea.CampaignID == nil {
+		return err
+	}
+	campaign, err := repo.SelectCampaign(ctx, *idea.CampaignID)
+	if err != nil {
+		return err
+	}
+	if !i.now().Before(campaign.EndsAt) {
+		return apperr.Conflict("voting in the campaign has closed")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package ideas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var campaignStart = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+// setupCampaign returns the service at campaignStart and an open campaign
+// for Office ideas of developers, running for a week
+func setupCampaign(t *testing.T) (*Ideas, *memory.Repository, models.Campaign) {
+	ideas, repo := setupIdeas(t)
+	ideas.now = func() time.Time { return campaignStart }
+	campaign, err := repo.InsertCampaign(context.Background(), models.Campaign{
+		Title:       "Reduce office energy costs",
+		StartsAt:    campaignStart.Add(-time.Hour),
+		EndsAt:      campaignStart.Add(7 * 24 * time.Hour),
+		CategoryIDs: models.IDs{repotest.Categories[0].ID},
+		PositionIDs: models.IDs{repotest.Positions[0].ID},
+	})
+	require.NoError(t, err)
+	return ideas, repo, campaign
+}
+
+func TestInsertCampaignIdea(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo, campaign := setupCampaign(t)
+	u := repotest.User(t, repo, "a@example.com")
+
+	idea, err := ideas.InsertCampaignIdea(ctx, campaign.ID, "solar panels", "on the roof", u.UID, 1, 1)
+	require.NoError(t, err)
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.CampaignID)
+	assert.Equal(t, campaign.ID, *stored.CampaignID)
+
+	plain, err := ideas.InsertIdea(ctx, "plain", "text", u.UID, 1, 2)
+	require.NoError(t, err)
+	assert.Nil(t, plain.CampaignID)
+}
+
+func TestInsertCampaignIdea_Rules(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo, campaign := setupCampaign(t)
+	developer := repotest.User(t, repo, "dev@example.com")
+	manager := models.User{UID: "00000000-0000-0000-0000-00000000000a", Name: "Anna", Surname: "Ivanova",
+		PositionID: repotest.Positions[1].ID, Email: "pm@example.com", Password: "hash"}
+	require.NoError(t, repo.InsertUser(ctx, manager))
+
+	_, err := ideas.InsertCampaignIdea(ctx, campaign.ID+100, "name", "text", developer.UID, 1, 1)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = ideas.InsertCampaignIdea(ctx, campaign.ID, "name", "text", developer.UID, 1, 2)
+	assert.ErrorIs(t, err, apperr.ErrValidation, "category isn't targeted")
+	_, err = ideas.InsertCampaignIdea(ctx, campaign.ID, "name", "text", manager.UID, 1, 1)
+	assert.ErrorIs(t, err, apperr.ErrForbidden, "position isn't eligible")
+
+	ideas.now = func() time.Time { return campaign.StartsAt.Add(-time.Minute) }
+	_, err = ideas.InsertCampaignIdea(ctx, campaign.ID, "name", "text", developer.UID, 1, 1)
+	assert.ErrorIs(t, err, apperr.ErrConflict, "not started")
+	ideas.now = func() time.Time { return campaign.EndsAt }
+	_, err = ideas.InsertCampaignIdea(ctx, campaign.ID, "name", "text", developer.UID, 1, 1)
+	assert.ErrorIs(t, err, apperr.ErrConflict, "closed")
+
+	all, err := repo.SelectIdeas(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestVote_CampaignDeadline(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo, campaign := setupCampaign(t)
+	author := repotest.User(t, repo, "a@example.com")
+	voter := repotest.User(t, repo, "b@example.com")
+	late := repotest.User(t, repo, "c@example.com")
+	idea, err := ideas.InsertCampaignIdea(ctx, campaign.ID, "solar panels", "on the roof", author.UID, 1, 1)
+	require.NoError(t, err)
+	plain := repotest.Idea(t, repo, author.UID, "plain")
+
+	ideas.now = func() time.Time { return campaign.EndsAt.Add(-time.Second) }
+	ok, err := ideas.Vote(ctx, idea.IdeaUID, voter.UID, models.VoteLike)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ideas.now = func() time.Time { return campaign.EndsAt }
+	_, err = ideas.Vote(ctx, idea.IdeaUID, late.UID, models.VoteLike)
+	assert.ErrorIs(t, err, apperr.ErrConflict)
+	ok, err = ideas.Vote(ctx, plain.IdeaUID, late.UID, models.VoteLike)
+	require.NoError(t, err)
+	assert.True(t, ok, "ideas outside campaigns are voted for at any time")
+
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.LikeCount)
+}
@@ -1,11 +1,27 @@
 package ideas
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
 	"github.com/TP2-Voice-Agora/backend/internal/repository"
 	"github.com/google/uuid"
 	"log/slog"
+	"slices"
+	"time"
+)
+
+// Discussion limits
+const (
+	// MaxCommentDepth - deepest allowed reply, top level comments have depth 0
+	MaxCommentDepth = 8
+	// ThreadDepth - levels of replies loaded with every comment of a page,
+	// deeper ones are fetched with GetCommentChildren
+	ThreadDepth = 2
+	// DefaultCommentPage - comments per page when no limit is given
+	DefaultCommentPage = 20
 )
 
 type Ideas struct {
@@ -13,28 +29,41 @@ type Ideas struct {
 	repo            repository.Repository
 	ideasCategories []models.IdeaCategory
 	ideasStatuses   []models.IdeaStatus
+	reactions       []string // allowed emojis, see SetReactions
+	notifier        Notifier
+	publisher       Publisher
+	outbox          Outbox
+	now             func() time.Time
 }
 
-func New(log slog.Logger, repo repository.Repository) *Ideas {
+// New loads categories and statuses into the cache, ctx bounds the initial
+// queries. Reactions start with DefaultReactions.
+func New(ctx context.Context, log slog.Logger, repo repository.Repository) (*Ideas, error) {
 	i := &Ideas{
-		log:  log,
-		repo: repo,
+		log:       log,
+		repo:      repo,
+		reactions: slices.Clone(DefaultReactions),
+		notifier:  nopNotifier{},
+		publisher: nopPublisher{},
+		outbox:    nopOutbox{},
+		now:       time.Now,
 	}
 
 	var err error
 
-	i.ideasCategories, err = i.repo.SelectIdeaCategories()
+	i.ideasCategories, err = i.repo.SelectIdeaCategories(ctx)
 	if err != nil {
-		log.Error("failed to fetch ideas categories" + err.Error())
-		return nil
+		log.ErrorContext(ctx, "failed to fetch ideas categories: "+err.Error())
+		return nil, fmt.Errorf("failed to fetch ideas categories: %w", err)
 	}
 
-	i.ideasStatuses, err = i.repo.SelectIdeaStatuses()
+	i.ideasStatuses, err = i.repo.SelectIdeaStatuses(ctx)
 	if err != nil {
-		log.Error("failed to fetch ideas statuses" + err.Error())
+		log.ErrorContext(ctx, "failed to fetch ideas statuses: "+err.Error())
+		return nil, fmt.Errorf("failed to fetch ideas statuses: %w", err)
 	}
 
-	return i
+	return i, nil
 }
 
 func (i *Ideas) GetIdeaCategories() []models.IdeaCategory {
@@ -45,23 +74,53 @@ func (i *Ideas) GetIdeaStatuses() []models.IdeaStatus {
 	return i.ideasStatuses
 }
 
-func (i *Ideas) GetAllIdeas() ([]models.Idea, error) {
+// GetAllIdeas returns every idea with reactions and mentions, Mine marks
+// viewerUID's reactions
+func (i *Ideas) GetAllIdeas(ctx context.Context, viewerUID string) ([]models.Idea, error) {
 	op := "IdeasGetAll"
 	log := i.log.With(slog.String("op", op))
-	log.Debug("fetching all ideas")
+	log.DebugContext(ctx, "fetching all ideas")
 
-	ideas, err := i.repo.SelectIdeas()
+	ideas, err := i.repo.SelectIdeas(ctx)
 	if err != nil {
-		log.Error("failed to fetch ideas" + err.Error())
+		log.ErrorContext(ctx, "failed to fetch ideas"+err.Error())
 		return []models.Idea{}, err
 	}
+	if err := i.decorateIdeas(ctx, ideas, viewerUID); err != nil {
+		log.ErrorContext(ctx, "failed to fetch reactions and mentions"+err.Error())
+		return []models.Idea{}, err
+	}
+
+	log.InfoContext(ctx, "successfully fetched ideas")
+
+	return ideas, nil
+}
 
-	log.Info("successfully fetched ideas")
+// GetRankedIdeas returns every idea by the score of sort (see package
+// ranking), highest first
+func (i *Ideas) GetRankedIdeas(ctx context.Context, sort models.IdeaSort, viewerUID string) ([]models.Idea, error) {
+	op := "IdeasGetRanked"
+	log := i.log.With(slog.String("op", op), slog.String("sort", string(sort)))
 
+	if !slices.Contains(models.IdeaSorts, sort) {
+		return []models.Idea{}, apperr.Validation("unknown sort " + string(sort))
+	}
+	ideas, err := i.repo.SelectRankedIdeas(ctx, sort)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch ranked ideas: "+err.Error())
+		return []models.Idea{}, err
+	}
+	if ideas == nil {
+		ideas = []models.Idea{}
+	}
+	if err := i.decorateIdeas(ctx, ideas, viewerUID); err != nil {
+		log.ErrorContext(ctx, "failed to fetch reactions and mentions: "+err.Error())
+		return []models.Idea{}, err
+	}
 	return ideas, nil
 }
 
-func (i *Ideas) GetIdeaByUID(uid string) (models.IdeaComment, error) {
+func (i *Ideas) GetIdeaByUID(ctx context.Context, uid, viewerUID string) (models.IdeaComment, error) {
 	op := "IdeaGetByUID"
 	log := i.log.With(
 		slog.String("op", op),
@@ -69,127 +128,270 @@ func (i *Ideas) GetIdeaByUID(uid string) (models.IdeaComment, error) {
 	)
 
 	if uid == "" {
-		log.Error("uid is empty")
-		return models.IdeaComment{}, nil
+		log.ErrorContext(ctx, "uid is empty")
+		return models.IdeaComment{}, apperr.Validation("idea uid is required")
 	}
 
-	log.Debug("fetching idea by uid")
+	log.DebugContext(ctx, "fetching idea with the first page of comments")
 
-	idea, err := i.repo.SelectIdeaByUID(uid)
+	idea, err := i.repo.SelectIdeaWithAuthor(ctx, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.DebugContext(ctx, "idea not found")
+		return models.IdeaComment{}, apperr.NotFound("idea not found")
+	}
 	if err != nil {
-		log.Error("failed to fetch idea by uid" + err.Error())
+		log.ErrorContext(ctx, "failed to fetch idea by uid"+err.Error())
 		return models.IdeaComment{}, err
 	}
-	log.Info("successfully fetched idea")
-	log.Info("fetching comments for idea")
 
-	comments, err := i.repo.SelectIdeaComments(idea.IdeaUID)
-	if err != nil {
-		log.Error("failed to fetch comments for idea" + err.Error())
+	ideas := []models.Idea{idea}
+	if err := i.decorateIdeas(ctx, ideas, viewerUID); err != nil {
+		log.ErrorContext(ctx, "failed to fetch reactions and mentions"+err.Error())
 		return models.IdeaComment{}, err
 	}
-
-	commentsReplies := make([]models.CommentReply, len(comments))
-
-	// putting together comments and replies
-	for j, comment := range comments {
-		log.Info("fetching replies for comment")
-
-		replies, err := i.repo.SelectCommentReplies(comment.CommentUID)
-		if err != nil {
-			log.Error("failed to fetch replies for comment" + comment.CommentUID + err.Error())
-		}
-		commentsReplies[j] = models.CommentReply{
-			Comment: comment,
-			Replies: replies,
-		}
+	idea = ideas[0]
+	// after the previous visit is read for the unread comments
+	if i.recordView(ctx, log, idea, viewerUID) {
+		idea.ViewCount++
 	}
 
-	ideaComment := models.IdeaComment{
-		Idea:           idea,
-		CommentReplies: commentsReplies,
+	page, err := i.commentPage(ctx, uid, "", viewerUID, "", DefaultCommentPage)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch idea comments"+err.Error())
+		return models.IdeaComment{}, err
 	}
 
-	return ideaComment, nil
+	log.InfoContext(ctx, "successfully fetched idea", slog.Int("comments", len(page.Comments)))
+
+	return models.IdeaComment{Idea: idea, Comments: page.Comments, NextCursor: page.NextCursor}, nil
 }
 
-func (i *Ideas) GetAuthorIdeas(uid string, limit int) ([]models.Idea, error) {
+func (i *Ideas) GetAuthorIdeas(ctx context.Context, uid string, limit int) ([]models.Idea, error) {
 	op := "IdeasGetAuthorIdeas"
 	log := i.log.With(
 		slog.String("op", op),
 		slog.String("uid", uid),
 		slog.Int("limit", limit),
 	)
-	log.Debug("fetching all author ideas")
+	log.DebugContext(ctx, "fetching all author ideas")
 
-	ideas, err := i.repo.SelectUserIdeas(uid, limit)
+	ideas, err := i.repo.SelectUserIdeas(ctx, uid, limit)
 
 	if err != nil {
-		log.Error("failed to fetch author ideas" + err.Error())
+		log.ErrorContext(ctx, "failed to fetch author ideas"+err.Error())
 		return nil, err
 	}
 
-	log.Info("successfully fetched author ideas")
+	log.InfoContext(ctx, "successfully fetched author ideas")
 
 	return ideas, nil
 }
 
-func (i *Ideas) GetIdeaComments(uid string) ([]models.Comment, error) {
-	op := "IdeasGetIdeaComments"
+// SearchIdeas runs a full-text search over idea names and texts
+func (i *Ideas) SearchIdeas(ctx context.Context, query string, limit int, viewerUID string) ([]models.Idea, error) {
+	op := "IdeasSearch"
 	log := i.log.With(
 		slog.String("op", op),
-		slog.String("uid", uid),
+		slog.String("query", query),
+		slog.Int("limit", limit),
 	)
-	log.Debug("fetching all idea comments")
+	log.DebugContext(ctx, "searching ideas")
+
+	if len(repository.SearchTerms(query)) == 0 {
+		log.DebugContext(ctx, "query has no words")
+		return nil, apperr.Validation("search query must contain at least one word")
+	}
 
-	ideas, err := i.repo.SelectIdeaComments(uid)
+	ideas, err := i.repo.SearchIdeas(ctx, query, limit)
 	if err != nil {
-		log.Error("failed to fetch idea comments" + err.Error())
+		log.ErrorContext(ctx, "failed to search ideas"+err.Error())
+		return nil, err
+	}
+	if err := i.decorateIdeas(ctx, ideas, viewerUID); err != nil {
+		log.ErrorContext(ctx, "failed to fetch reactions and mentions"+err.Error())
 		return nil, err
 	}
 
-	log.Info("successfully fetched idea comments")
+	log.InfoContext(ctx, "successfully searched ideas")
 
 	return ideas, nil
 }
 
-func (i *Ideas) GetCommentReplies(uid string) ([]models.Reply, error) {
-	op := "IdeasGetCommentReplies"
+// GetIdeaComments returns a page of top level comments, each with
+// ThreadDepth levels of replies
+func (i *Ideas) GetIdeaComments(ctx context.Context, ideaUID, viewerUID, cursor string, limit int) (models.CommentPage, error) {
+	op := "IdeasGetIdeaComments"
+	log := i.log.With(
+		slog.String("op", op),
+		slog.String("uid", ideaUID),
+		slog.String("cursor", cursor),
+	)
+	log.DebugContext(ctx, "fetching idea comments")
+
+	if _, err := i.repo.SelectIdeaByUID(ctx, ideaUID); errors.Is(err, repository.ErrNotFound) {
+		return models.CommentPage{}, apperr.NotFound("idea not found")
+	} else if err != nil {
+		log.ErrorContext(ctx, "failed to fetch idea"+err.Error())
+		return models.CommentPage{}, err
+	}
+
+	page, err := i.commentPage(ctx, ideaUID, "", viewerUID, cursor, limit)
+	if err != nil {
+		log.DebugContext(ctx, "failed to fetch idea comments: "+err.Error())
+		return models.CommentPage{}, err
+	}
+
+	log.InfoContext(ctx, "successfully fetched idea comments")
+
+	return page, nil
+}
+
+// GetCommentChildren returns a page of direct replies to the comment, each
+// with ThreadDepth levels of their own replies
+func (i *Ideas) GetCommentChildren(ctx context.Context, uid, viewerUID, cursor string, limit int) (models.CommentPage, error) {
+	op := "IdeasGetCommentChildren"
 	log := i.log.With(
 		slog.String("op", op),
 		slog.String("uid", uid),
+		slog.String("cursor", cursor),
 	)
-	log.Debug("fetching all idea replies")
+	log.DebugContext(ctx, "fetching comment children")
 
-	replies, err := i.repo.SelectCommentReplies(uid)
+	comment, err := i.repo.SelectCommentByUID(ctx, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.CommentPage{}, apperr.NotFound("comment not found")
+	}
 	if err != nil {
-		log.Error("failed to fetch idea replies" + err.Error())
-		return nil, err
+		log.ErrorContext(ctx, "failed to fetch comment"+err.Error())
+		return models.CommentPage{}, err
+	}
+
+	page, err := i.commentPage(ctx, comment.IdeaUID, uid, viewerUID, cursor, limit)
+	if err != nil {
+		log.DebugContext(ctx, "failed to fetch comment children: "+err.Error())
+		return models.CommentPage{}, err
+	}
+
+	log.InfoContext(ctx, "successfully fetched comment children")
+
+	return page, nil
+}
+
+// commentPage loads limit siblings after cursor (uid of the last comment of
+// the previous page), their subtrees ThreadDepth levels deep, reactions and
+// mentions: four queries whatever the size of the discussion
+func (i *Ideas) commentPage(ctx context.Context, ideaUID, parentUID, viewerUID, cursor string, limit int) (models.CommentPage, error) {
+	if cursor != "" {
+		if _, err := uuid.Parse(cursor); err != nil {
+			return models.CommentPage{}, apperr.Validation("invalid cursor")
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultCommentPage
+	}
+
+	// one extra row tells whether there is a next page
+	siblings, err := i.repo.SelectCommentChildren(ctx, ideaUID, parentUID, cursor, limit+1)
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+	page := models.CommentPage{Comments: []models.CommentThread{}}
+	if len(siblings) > limit {
+		siblings = siblings[:limit]
+		page.NextCursor = siblings[limit-1].CommentUID
+	}
+	if len(siblings) == 0 {
+		return page, nil
+	}
+
+	uids := make([]string, 0, len(siblings))
+	for _, c := range siblings {
+		uids = append(uids, c.CommentUID)
+	}
+	descendants, err := i.repo.SelectCommentSubtrees(ctx, uids, siblings[0].Depth+ThreadDepth)
+	if err != nil {
+		return models.CommentPage{}, err
+	}
+
+	// one query per kind for the whole page, the slice shares siblings and descendants
+	all := append(siblings, descendants...)
+	if err := i.decorateComments(ctx, all, viewerUID); err != nil {
+		return models.CommentPage{}, err
 	}
+	siblings, descendants = all[:len(siblings)], all[len(siblings):]
+
+	page.Comments = buildThreads(siblings, descendants)
+	return page, nil
+}
 
-	log.Info("successfully fetched comment replies")
+// decorateIdeas fills reactions, mentions and the last visits of the ideas
+// shown to viewerUID
+func (i *Ideas) decorateIdeas(ctx context.Context, ideas []models.Idea, viewerUID string) error {
+	if err := i.withIdeaReactions(ctx, ideas, viewerUID); err != nil {
+		return err
+	}
+	if err := i.withIdeaVisits(ctx, ideas, viewerUID); err != nil {
+		return err
+	}
+	return i.withIdeaMentions(ctx, i.repo, ideas)
+}
 
-	return replies, nil
+// decorateComments fills reactions and mentions of the comments shown to viewerUID
+func (i *Ideas) decorateComments(ctx context.Context, comments []models.Comment, viewerUID string) error {
+	if err := i.withCommentReactions(ctx, comments, viewerUID); err != nil {
+		return err
+	}
+	return i.withCommentMentions(ctx, i.repo, comments)
 }
 
-func (i *Ideas) InsertIdea(name, text, author string, status, category int) (models.Idea, error) {
+// buildThreads hangs descendants (ordered by time) under their parents
+func buildThreads(roots, descendants []models.Comment) []models.CommentThread {
+	children := map[string][]models.Comment{}
+	for _, c := range descendants {
+		if c.ParentUID != nil {
+			children[*c.ParentUID] = append(children[*c.ParentUID], c)
+		}
+	}
+
+	var build func(c models.Comment) models.CommentThread
+	build = func(c models.Comment) models.CommentThread {
+		thread := models.CommentThread{Comment: c, Children: []models.CommentThread{}}
+		for _, child := range children[c.CommentUID] {
+			thread.Children = append(thread.Children, build(child))
+		}
+		return thread
+	}
+
+	threads := make([]models.CommentThread, 0, len(roots))
+	for _, c := range roots {
+		threads = append(threads, build(c))
+	}
+	return threads
+}
+
+func (i *Ideas) InsertIdea(ctx context.Context, name, text, author string, status, category int) (models.Idea, error) {
+	return i.insertIdea(ctx, nil, name, text, author, status, category)
+}
+
+// insertIdea publishes the idea, into the campaign when campaignID is set
+func (i *Ideas) insertIdea(ctx context.Context, campaignID *int64, name, text, author string, status, category int) (models.Idea, error) {
 	op := "IdeasInsertIdea"
 	log := i.log.With(slog.String("op", op),
 		slog.String("name", name),
 	)
-	log.Debug("inserting idea")
+	log.DebugContext(ctx, "inserting idea")
 
 	if name == "" {
-		log.Error("idea name is null")
-		return models.Idea{}, nil
+		log.ErrorContext(ctx, "idea name is null")
+		return models.Idea{}, apperr.Validation("idea name is required")
 	}
 	if text == "" {
-		log.Error("idea text is null")
-		return models.Idea{}, nil
+		log.ErrorContext(ctx, "idea text is null")
+		return models.Idea{}, apperr.Validation("idea text is required")
 	}
 	if author == "" {
-		log.Error("idea author is null")
-		return models.Idea{}, nil
+		log.ErrorContext(ctx, "idea author is null")
+		return models.Idea{}, apperr.Validation("idea author is required")
 	}
 
 	ideaUID := uuid.New().String()
@@ -202,160 +404,465 @@ func (i *Ideas) InsertIdea(name, text, author string, status, category int) (mod
 		Author:     author,
 		StatusID:   status,
 		CategoryID: category,
+		CampaignID: campaignID,
 	}
 
-	err := i.repo.InsertIdea(idea)
+	mentions, err := i.resolveMentions(ctx, text)
 	if err != nil {
-		log.Error("failed to insert idea" + err.Error())
+		log.ErrorContext(ctx, "failed to resolve mentions"+err.Error())
 		return models.Idea{}, err
 	}
 
-	log.Info("successfully inserted idea, UUID:" + idea.IdeaUID)
+	var notify []models.Mention
+	var event models.Event
+	err = i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		if campaignID != nil {
+			if err := i.checkSubmission(ctx, repo, *campaignID, author, category); err != nil {
+				return err
+			}
+		}
+		if err := repo.InsertIdea(ctx, idea); err != nil {
+			return err
+		}
+		notify, err = saveMentions(ctx, repo, mentions, models.TargetIdea, ideaUID, ideaUID, author, nil)
+		if err != nil {
+			return err
+		}
+		if event, err = i.ideaEvent(ctx, repo, models.EventIdeaCreated, ideaUID); err != nil {
+			return err
+		}
+		return i.outbox.Queue(ctx, repo, event)
+	})
+	if errors.Is(err, repository.ErrInvalidReference) {
+		log.DebugContext(ctx, "idea references unknown status or category")
+		return models.Idea{}, apperr.Validation("unknown idea status or category")
+	}
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		log.DebugContext(ctx, "idea rejected by the campaign: "+err.Error())
+		return models.Idea{}, err
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to insert idea"+err.Error())
+		return models.Idea{}, err
+	}
+	idea.Mentions = mentions
+
+	log.InfoContext(ctx, "successfully inserted idea, UUID:"+idea.IdeaUID)
+	i.notifyMentioned(ctx, notify)
+	i.publish(ctx, event)
 	return idea, nil
 }
 
-func (i *Ideas) InsertComment(ideaUID, authorUID, commentText string) (models.Comment, error) {
+// InsertComment adds a top level comment to the idea, or a reply to
+// parentUID when it is set. Replies deeper than MaxCommentDepth are rejected.
+func (i *Ideas) InsertComment(ctx context.Context, ideaUID, parentUID, authorUID, commentText string) (models.Comment, error) {
 	op := "IdeasInsertComment"
 	log := i.log.With(slog.String("op", op),
 		slog.String("ideaUID", ideaUID),
+		slog.String("parentUID", parentUID),
 	)
-	log.Debug("inserting comment")
+	log.DebugContext(ctx, "inserting comment")
 
-	if ideaUID == "" {
-		log.Error("ideaUID is null")
-		return models.Comment{}, nil
+	if ideaUID == "" && parentUID == "" {
+		log.ErrorContext(ctx, "ideaUID is null")
+		return models.Comment{}, apperr.Validation("idea uid is required")
 	}
 	if authorUID == "" {
-		log.Error("authorUID is null")
-		return models.Comment{}, nil
+		log.ErrorContext(ctx, "authorUID is null")
+		return models.Comment{}, apperr.Validation("comment author is required")
 	}
 	if commentText == "" {
-		log.Error("commentText is null")
-		return models.Comment{}, nil
+		log.ErrorContext(ctx, "commentText is null")
+		return models.Comment{}, apperr.Validation("comment text is required")
 	}
 
-	commentUID := uuid.New().String()
-
 	//timestamp - in PSQL
 	comment := models.Comment{
-		CommentUID:  commentUID,
+		CommentUID:  uuid.New().String(),
 		IdeaUID:     ideaUID,
 		AuthorID:    authorUID,
 		CommentText: commentText,
 	}
 
-	err := i.repo.InsertIdeaComment(comment)
+	if parentUID != "" {
+		parent, err := i.repo.SelectCommentByUID(ctx, parentUID)
+		if errors.Is(err, repository.ErrNotFound) {
+			log.DebugContext(ctx, "parent comment not found")
+			return models.Comment{}, apperr.NotFound("comment not found")
+		}
+		if err != nil {
+			log.ErrorContext(ctx, "failed to fetch parent comment"+err.Error())
+			return models.Comment{}, err
+		}
+		if ideaUID != "" && parent.IdeaUID != ideaUID {
+			return models.Comment{}, apperr.Validation("parent comment belongs to another idea")
+		}
+		if parent.Depth+1 > MaxCommentDepth {
+			return models.Comment{}, apperr.Validation(fmt.Sprintf("replies can't be nested deeper than %d levels", MaxCommentDepth))
+		}
+
+		comment.IdeaUID = parent.IdeaUID
+		comment.ParentUID = &parent.CommentUID
+		comment.Depth = parent.Depth + 1
+		comment.Path = repository.ChildPath(parent.Path, comment.CommentUID)
+	} else {
+		comment.Path = repository.ChildPath("", comment.CommentUID)
+	}
+
+	mentions, err := i.resolveMentions(ctx, commentText)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to resolve mentions"+err.Error())
+		return models.Comment{}, err
+	}
+
+	var notify []models.Mention
+	var event models.Event
+	err = i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		if err := repo.InsertIdeaComment(ctx, comment); err != nil {
+			return err
+		}
+		notify, err = saveMentions(ctx, repo, mentions, models.TargetComment, comment.CommentUID, comment.IdeaUID, authorUID, nil)
+		if err != nil {
+			return err
+		}
+		if event, err = i.commentEvent(ctx, repo, comment.CommentUID); err != nil {
+			return err
+		}
+		return i.outbox.Queue(ctx, repo, event)
+	})
+	if errors.Is(err, repository.ErrInvalidReference) {
+		if parentUID != "" {
+			log.DebugContext(ctx, "reply references unknown comment")
+			return models.Comment{}, apperr.NotFound("comment not found")
+		}
+		log.DebugContext(ctx, "comment references unknown idea")
+		return models.Comment{}, apperr.NotFound("idea not found")
+	}
 	if err != nil {
-		log.Error("failed to insert comment" + err.Error())
+		log.ErrorContext(ctx, "failed to insert comment"+err.Error())
 		return models.Comment{}, err
 	}
 
-	log.Info("successfully inserted comment, UUID:" + comment.CommentUID)
+	comment.Mentions = mentions
 
+	log.InfoContext(ctx, "successfully inserted comment, UUID:"+comment.CommentUID)
+	i.notifyMentioned(ctx, notify)
+	i.notifyFailed(ctx, "comment", i.notifier.Commented(ctx, comment))
+	i.publish(ctx, event)
 	return comment, nil
 }
 
-func (i *Ideas) InsertReply(commentUID, authorID, replyText string) (models.Reply, error) {
-	op := "IdeasInsertReply"
-	log := i.log.With(slog.String("op", op),
-		slog.String("commentUID", commentUID),
-	)
-	log.Debug("inserting reply")
-
+// InsertReply answers the comment, it is InsertComment with a parent
+func (i *Ideas) InsertReply(ctx context.Context, commentUID, authorID, replyText string) (models.Comment, error) {
 	if commentUID == "" {
-		log.Error("commentUID is null")
-		return models.Reply{}, nil
+		return models.Comment{}, apperr.Validation("comment uid is required")
 	}
-	if authorID == "" {
-		log.Error("AuthorID is null")
-		return models.Reply{}, nil
+	return i.InsertComment(ctx, "", commentUID, authorID, replyText)
+}
+
+// canModify checks that actor may edit or delete content written by author:
+// authors manage their own comments, admins moderate everything
+func canModify(ctx context.Context, repo repository.Repository, actorUID, authorUID string) error {
+	if actorUID == authorUID {
+		return nil
+	}
+	actor, err := repo.SelectUserByUID(ctx, actorUID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.Forbidden("only the author or a moderator can change this")
+	}
+	if err != nil {
+		return err
 	}
-	if replyText == "" {
-		log.Error("ReplyText is null")
-		return models.Reply{}, nil
+	if !actor.IsAdmin {
+		return apperr.Forbidden("only the author or a moderator can change this")
 	}
+	return nil
+}
 
-	replyUID := uuid.New().String()
+// EditComment replaces the comment text, the previous one is saved as a
+// revision. Mentions are parsed again, only users who weren't mentioned
+// before are notified.
+func (i *Ideas) EditComment(ctx context.Context, uid, editorUID, text string) (models.Comment, error) {
+	op := "IdeasEditComment"
+	log := i.log.With(slog.String("op", op),
+		slog.String("commentUID", uid),
+		slog.String("editorUID", editorUID),
+	)
+	log.DebugContext(ctx, "editing comment")
 
-	reply := models.Reply{
-		ReplyUID:   replyUID,
-		CommentUID: commentUID,
-		AuthorID:   authorID,
-		ReplyText:  replyText,
+	if text == "" {
+		log.ErrorContext(ctx, "commentText is null")
+		return models.Comment{}, apperr.Validation("comment text is required")
 	}
 
-	err := i.repo.InsertCommentReply(reply)
+	mentions, err := i.resolveMentions(ctx, text)
 	if err != nil {
-		log.Error("failed to insert reply" + err.Error())
-		return models.Reply{}, err
+		log.ErrorContext(ctx, "failed to resolve mentions"+err.Error())
+		return models.Comment{}, err
 	}
 
-	log.Info("successfully inserted reply, UUID:" + reply.ReplyUID)
+	var comment models.Comment
+	var notify []models.Mention
+	err = i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		var err error
+		comment, err = repo.SelectCommentByUID(ctx, uid)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.NotFound("comment not found")
+		}
+		if err != nil {
+			return err
+		}
+		if err := canModify(ctx, repo, editorUID, comment.AuthorID); err != nil {
+			return err
+		}
+		if comment.DeletedAt != nil {
+			return apperr.Conflict("comment is deleted")
+		}
+		if comment.CommentText == text {
+			return nil
+		}
+
+		err = repo.InsertRevision(ctx, models.Revision{TargetUID: uid, Text: comment.CommentText, EditorUID: editorUID})
+		if err != nil {
+			return err
+		}
+		if err := repo.UpdateCommentText(ctx, uid, text); err != nil {
+			return err
+		}
 
-	return reply, nil
+		previous, err := repo.SelectMentions(ctx, []string{uid})
+		if err != nil {
+			return err
+		}
+		if err := repo.DeleteMentions(ctx, uid); err != nil {
+			return err
+		}
+		notify, err = saveMentions(ctx, repo, mentions, models.TargetComment, uid, comment.IdeaUID, comment.AuthorID, previous)
+		if err != nil {
+			return err
+		}
+
+		comment, err = repo.SelectCommentByUID(ctx, uid)
+		return err
+	})
+	if err != nil {
+		log.DebugContext(ctx, "failed to edit comment: "+err.Error())
+		return models.Comment{}, err
+	}
+
+	log.InfoContext(ctx, "successfully edited comment")
+	i.notifyMentioned(ctx, notify)
+
+	comments := []models.Comment{comment}
+	if err := i.withCommentMentions(ctx, i.repo, comments); err != nil {
+		log.ErrorContext(ctx, "failed to fetch mentions"+err.Error())
+		return models.Comment{}, err
+	}
+	return comments[0], nil
 }
 
-func (i *Ideas) IncrementLikes(ideaUID string) error {
-	op := "IdeasIncrementLikes"
+// DeleteComment turns the comment into a tombstone and drops its revisions
+// and mentions, replies stay in place. Deleting a deleted comment is a no-op.
+func (i *Ideas) DeleteComment(ctx context.Context, uid, userUID string) error {
+	op := "IdeasDeleteComment"
 	log := i.log.With(slog.String("op", op),
-		slog.String("ideaUID", ideaUID),
+		slog.String("commentUID", uid),
+		slog.String("userUID", userUID),
 	)
-	log.Debug("incrementing likes")
+	log.DebugContext(ctx, "deleting comment")
 
-	if ideaUID == "" {
-		log.Error("ideaUID is null")
-		return errors.New("ideaUID is null")
-	}
+	err := i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		comment, err := repo.SelectCommentByUID(ctx, uid)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.NotFound("comment not found")
+		}
+		if err != nil {
+			return err
+		}
+		if err := canModify(ctx, repo, userUID, comment.AuthorID); err != nil {
+			return err
+		}
+		if comment.DeletedAt != nil {
+			return nil
+		}
 
-	err := i.repo.IncrementLikeCount(ideaUID)
+		if err := repo.TombstoneComment(ctx, uid); err != nil {
+			return err
+		}
+		if err := repo.DeleteMentions(ctx, uid); err != nil {
+			return err
+		}
+		return repo.DeleteRevisions(ctx, uid)
+	})
 	if err != nil {
-		log.Error("failed to increment likes" + err.Error())
+		log.DebugContext(ctx, "failed to delete comment: "+err.Error())
 		return err
 	}
-	log.Info("successfully inserted likes")
+
+	log.InfoContext(ctx, "successfully deleted comment")
 	return nil
 }
 
-func (i *Ideas) IncrementDislikes(ideaUID string) error {
-	op := "IdeasIncrementDislikes"
-	log := i.log.With(slog.String("op", op),
-		slog.String("ideaUID", ideaUID),
-	)
-	log.Debug("incrementing dislikes")
+// GetCommentRevisions returns previous texts of the comment, oldest first
+func (i *Ideas) GetCommentRevisions(ctx context.Context, uid string) ([]models.Revision, error) {
+	op := "IdeasGetCommentRevisions"
+	log := i.log.With(slog.String("op", op), slog.String("commentUID", uid))
+	log.DebugContext(ctx, "fetching comment revisions")
 
-	if ideaUID == "" {
-		log.Error("ideaUID is null")
-		return errors.New("ideaUID is null")
+	if _, err := i.repo.SelectCommentByUID(ctx, uid); errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.NotFound("comment not found")
+	} else if err != nil {
+		log.ErrorContext(ctx, "failed to fetch comment"+err.Error())
+		return nil, err
 	}
 
-	err := i.repo.IncrementDislikeCount(ideaUID)
+	revisions, err := i.repo.SelectRevisions(ctx, uid)
 	if err != nil {
-		log.Error("failed to increment dislikes" + err.Error())
-		return err
+		log.ErrorContext(ctx, "failed to fetch comment revisions"+err.Error())
+		return nil, err
 	}
-	log.Info("successfully inserted dislikes")
-	return nil
+	return revisions, nil
 }
 
-func (i *Ideas) CheckVote(ideaUID string, userUID string) (bool, error) {
-	op := "IdeasCheckVote"
+// Vote records the user's vote and bumps the matching counter in one
+// transaction. Returns false when the user has already voted for the idea.
+func (i *Ideas) Vote(ctx context.Context, ideaUID, userUID string, vote models.VoteType) (bool, error) {
+	op := "IdeasVote"
 	log := i.log.With(slog.String("op", op),
 		slog.String("ideaUID", ideaUID),
 		slog.String("userUID", userUID),
+		slog.String("vote", string(vote)),
 	)
-	log.Debug("checking likes")
+	log.DebugContext(ctx, "voting for idea")
 
 	if ideaUID == "" {
-		log.Error("ideaUID is null")
+		log.ErrorContext(ctx, "ideaUID is null")
+		return false, apperr.Validation("idea uid is required")
 	}
 	if userUID == "" {
-		log.Error("userUID is null")
+		log.ErrorContext(ctx, "userUID is null")
+		return false, apperr.Validation("user uid is required")
+	}
+	if vote != models.VoteLike && vote != models.VoteDislike {
+		log.ErrorContext(ctx, "unknown vote type")
+		return false, apperr.Validation("unknown vote type")
+	}
+
+	var counted bool
+	var event models.Event
+	err := i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		if err := i.checkVoting(ctx, repo, ideaUID); err != nil {
+			return err
+		}
+		ok, err := repo.CheckVote(ctx, ideaUID, userUID)
+		if err != nil || !ok {
+			return err
+		}
+
+		if vote == models.VoteLike {
+			err = repo.IncrementLikeCount(ctx, ideaUID)
+		} else {
+			err = repo.IncrementDislikeCount(ctx, ideaUID)
+		}
+		if err != nil {
+			return err
+		}
+		if event, err = i.votesEvent(ctx, repo, ideaUID); err != nil {
+			return err
+		}
+		err = i.outbox.Queue(ctx, repo, event)
+		counted = err == nil
+		return err
+	})
+	if errors.Is(err, repository.ErrInvalidReference) || errors.Is(err, repository.ErrNotFound) {
+		return false, apperr.NotFound("idea not found")
 	}
+	if errors.Is(err, apperr.ErrConflict) {
+		log.DebugContext(ctx, "voting has closed")
+		return false, err
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to vote"+err.Error())
+		return false, err
+	}
+
+	if !counted {
+		log.InfoContext(ctx, "user has already voted")
+		return false, nil
+	}
+	log.InfoContext(ctx, "successfully voted")
+	i.notifyFailed(ctx, "vote", i.notifier.Voted(ctx, ideaUID, userUID, vote))
+	i.publish(ctx, event)
+	return true, nil
+}
+
+// ChangeIdeaStatus moves the idea to another status, only moderators may do
+// it. Setting the current status again changes nothing and notifies no one.
+func (i *Ideas) ChangeIdeaStatus(ctx context.Context, ideaUID, actorUID string, statusID int) (models.Idea, error) {
+	op := "IdeasChangeStatus"
+	log := i.log.With(slog.String("op", op),
+		slog.String("ideaUID", ideaUID),
+		slog.String("actorUID", actorUID),
+		slog.Int("status", statusID),
+	)
+	log.DebugContext(ctx, "changing idea status")
+
+	var idea models.Idea
+	var event models.Event
+	changed := false
+	err := i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		actor, err := repo.SelectUserByUID(ctx, actorUID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if err != nil || !actor.IsAdmin {
+			return apperr.Forbidden("only a moderator can change idea status")
+		}
 
-	ok, err := i.repo.CheckVote(ideaUID, userUID)
+		idea, err = repo.SelectIdeaByUID(ctx, ideaUID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.NotFound("idea not found")
+		}
+		if err != nil || idea.StatusID == statusID {
+			return err
+		}
+
+		err = repo.UpdateIdeaStatus(ctx, ideaUID, statusID)
+		if errors.Is(err, repository.ErrInvalidReference) {
+			return apperr.Validation("unknown idea status")
+		}
+		if err != nil {
+			return err
+		}
+		err = repo.InsertIdeaStatusChange(ctx, models.IdeaStatusChange{
+			IdeaUID:   ideaUID,
+			StatusID:  statusID,
+			ChangedBy: &actorUID,
+			ChangedAt: i.now(),
+		})
+		if err != nil {
+			return err
+		}
+		if event, err = i.ideaEvent(ctx, repo, models.EventIdeaUpdated, ideaUID); err != nil {
+			return err
+		}
+		if err := i.outbox.Queue(ctx, repo, event); err != nil {
+			return err
+		}
+		idea.StatusID = statusID
+		changed = true
+		return nil
+	})
 	if err != nil {
-		log.Error("failed to check vote" + err.Error())
+		log.DebugContext(ctx, "failed to change idea status: "+err.Error())
+		return models.Idea{}, err
 	}
-	log.Info("successfully checked votes")
-	return ok, nil
+
+	if changed {
+		log.InfoContext(ctx, "successfully changed idea status")
+		i.notifyFailed(ctx, "status change", i.notifier.StatusChanged(ctx, idea, actorUID))
+		i.publish(ctx, event)
+	}
+	return idea, nil
 }
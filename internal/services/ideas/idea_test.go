@@ -1,207 +1,539 @@
 package ideas
 
 import (
+	"context"
 	"errors"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"log/slog"
 	"testing"
+	"time"
 )
 
-// MockRepository — мок реализации Repository
-type MockRepository struct {
-	mock.Mock
-}
-
-func (m *MockRepository) ConnectDB(sourceURL string, log slog.Logger) error { return nil }
-func (m *MockRepository) CloseConnectDB() error                             { return nil }
-func (m *MockRepository) InsertUser(u models.User) error                    { return nil }
-func (m *MockRepository) SelectUserByEmail(string) (models.User, error)     { return models.User{}, nil }
-func (m *MockRepository) SelectPositions() ([]models.UserPosition, error)   { return nil, nil }
-func (m *MockRepository) SelectUserByUID(uid string) (models.User, error)   { return models.User{}, nil }
-func (m *MockRepository) UpdateUserPfpURL(uid string, url string) error     { return nil }
-func (m *MockRepository) IncrementDislikeCount(ideaUID string) error        { return nil }
-func (m *MockRepository) IncrementLikeCount(ideaUID string) error           { return nil }
-func (m *MockRepository) CheckVote(ideaUID string, userUID string) (bool, error) {
-	return false, nil
-}
-func (m *MockRepository) InsertIdea(idea models.Idea) error {
-	args := m.Called(idea)
-	return args.Error(0)
-}
-func (m *MockRepository) SelectIdeas() ([]models.Idea, error) {
-	args := m.Called()
-	return args.Get(0).([]models.Idea), args.Error(1)
-}
-func (m *MockRepository) SelectIdeaByUID(uid string) (models.Idea, error) {
-	args := m.Called(uid)
-	return args.Get(0).(models.Idea), args.Error(1)
-}
-func (m *MockRepository) SelectUserIdeas(uid string, limit int) ([]models.Idea, error) {
-	args := m.Called(uid, limit)
-	return args.Get(0).([]models.Idea), args.Error(1)
-}
-func (m *MockRepository) InsertIdeaComment(comment models.Comment) error {
-	args := m.Called(comment)
-	return args.Error(0)
-}
-func (m *MockRepository) InsertCommentReply(reply models.Reply) error {
-	args := m.Called(reply)
-	return args.Error(0)
-}
-func (m *MockRepository) SelectIdeaComments(uid string) ([]models.Comment, error) {
-	args := m.Called(uid)
-	return args.Get(0).([]models.Comment), args.Error(1)
-}
-func (m *MockRepository) SelectCommentReplies(uid string) ([]models.Reply, error) {
-	args := m.Called(uid)
-	return args.Get(0).([]models.Reply), args.Error(1)
-}
-func (m *MockRepository) SelectIdeaCategories() ([]models.IdeaCategory, error) {
-	args := m.Called()
-	return args.Get(0).([]models.IdeaCategory), args.Error(1)
-}
-func (m *MockRepository) SelectIdeaStatuses() ([]models.IdeaStatus, error) {
-	args := m.Called()
-	return args.Get(0).([]models.IdeaStatus), args.Error(1)
-}
-
-func setupIdeasWithMocks(t *testing.T) (*Ideas, *MockRepository) {
-	repo := new(MockRepository)
-	cats := []models.IdeaCategory{{ID: 1, Name: "cat"}}
-	stats := []models.IdeaStatus{{ID: 1, Name: "status"}}
-	repo.On("SelectIdeaCategories").Return(cats, nil)
-	repo.On("SelectIdeaStatuses").Return(stats, nil)
-	ideas := New(*slog.Default(), repo)
-	assert.NotNil(t, ideas)
+// failingRepo - in-memory repository whose SelectIdeas always fails
+type failingRepo struct {
+	*memory.Repository
+}
+
+func (failingRepo) SelectIdeas(ctx context.Context) ([]models.Idea, error) {
+	return nil, errors.New("err")
+}
+
+// noDictionaries - in-memory repository whose categories can't be loaded
+type noDictionaries struct {
+	*memory.Repository
+}
+
+func (noDictionaries) SelectIdeaCategories(ctx context.Context) ([]models.IdeaCategory, error) {
+	return nil, errors.New("connection reset")
+}
+
+func newRepo() *memory.Repository {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	return repo
+}
+
+func setupIdeas(t *testing.T) (*Ideas, *memory.Repository) {
+	repo := newRepo()
+	ideas, err := New(context.Background(), *slog.Default(), repo)
+	require.NoError(t, err)
 	return ideas, repo
 }
 
+func TestNew_DictionariesError(t *testing.T) {
+	ideas, err := New(context.Background(), *slog.Default(), noDictionaries{newRepo()})
+	assert.Error(t, err)
+	assert.Nil(t, ideas)
+}
+
 func TestGetIdeaCategories(t *testing.T) {
-	ideas, _ := setupIdeasWithMocks(t)
+	ideas, _ := setupIdeas(t)
 	cats := ideas.GetIdeaCategories()
-	assert.Equal(t, 1, len(cats))
+	assert.Equal(t, repotest.Categories, cats)
 }
 
 func TestGetIdeaStatuses(t *testing.T) {
-	ideas, _ := setupIdeasWithMocks(t)
+	ideas, _ := setupIdeas(t)
 	stats := ideas.GetIdeaStatuses()
-	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, repotest.Statuses, stats)
 }
 
 func TestGetAllIdeas_Success(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	expected := []models.Idea{{IdeaUID: uuid.New().String(), Name: "name"}}
-	repo.On("SelectIdeas").Return(expected, nil)
-	got, err := ideas.GetAllIdeas()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	expected := repotest.Idea(t, repo, u.UID, "name")
+	got, err := ideas.GetAllIdeas(context.Background(), "")
 	assert.NoError(t, err)
-	assert.Equal(t, expected, got)
+	assert.Equal(t, []models.Idea{expected}, got)
 }
 
 func TestGetAllIdeas_RepoError(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	repo.On("SelectIdeas").Return([]models.Idea{}, errors.New("err"))
-	got, err := ideas.GetAllIdeas()
+	ideas, err := New(context.Background(), *slog.Default(), failingRepo{newRepo()})
+	require.NoError(t, err)
+	got, err := ideas.GetAllIdeas(context.Background(), "")
 	assert.Error(t, err)
 	assert.Equal(t, []models.Idea{}, got) // исправлено: ожидаем пустой срез, а не nil
 }
 
 func TestGetIdeaByUID_EmptyUID(t *testing.T) {
-	ideas, _ := setupIdeasWithMocks(t)
-	result, err := ideas.GetIdeaByUID("")
-	assert.NoError(t, err)
+	ideas, _ := setupIdeas(t)
+	result, err := ideas.GetIdeaByUID(context.Background(), "", "")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
 	assert.Equal(t, models.IdeaComment{}, result)
 }
 
-func TestGetIdeaByUID_Success(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	idea := models.Idea{IdeaUID: "id1"}
-	comments := []models.Comment{{CommentUID: "c1", IdeaUID: "id1"}}
-	replies := []models.Reply{{ReplyUID: "r1", CommentUID: "c1"}}
+func TestGetIdeaByUID_NotFound(t *testing.T) {
+	ideas, _ := setupIdeas(t)
+	_, err := ideas.GetIdeaByUID(context.Background(), uuid.NewString(), "")
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
 
-	repo.On("SelectIdeaByUID", "id1").Return(idea, nil)
-	repo.On("SelectIdeaComments", "id1").Return(comments, nil)
-	repo.On("SelectCommentReplies", "c1").Return(replies, nil)
+func TestGetIdeaByUID_Success(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "comment")
+	require.NoError(t, err)
+	reply, err := ideas.InsertReply(ctx, comment.CommentUID, u.UID, "reply")
+	require.NoError(t, err)
 
-	ic, err := ideas.GetIdeaByUID("id1")
+	ic, err := ideas.GetIdeaByUID(ctx, idea.IdeaUID, "")
 	assert.NoError(t, err)
-	assert.Equal(t, idea, ic.Idea)
-	assert.Len(t, ic.CommentReplies, 1)
-	assert.Equal(t, comments[0], ic.CommentReplies[0].Comment)
-	assert.Equal(t, replies, ic.CommentReplies[0].Replies)
+	assert.Equal(t, idea.IdeaUID, ic.Idea.IdeaUID)
+	require.NotNil(t, ic.Idea.AuthorProfile)
+	assert.Equal(t, u.Name, ic.Idea.AuthorProfile.Name)
+	assert.Empty(t, ic.NextCursor)
+	require.Len(t, ic.Comments, 1)
+	assert.Equal(t, comment.CommentUID, ic.Comments[0].CommentUID)
+	assert.Equal(t, u.Surname, ic.Comments[0].AuthorProfile.Surname)
+	assert.Equal(t, 1, ic.Comments[0].ChildCount)
+	require.Len(t, ic.Comments[0].Children, 1)
+	assert.Equal(t, reply.CommentUID, ic.Comments[0].Children[0].CommentUID)
+	assert.Empty(t, ic.Comments[0].Children[0].Children)
 }
 
 func TestGetAuthorIdeas(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	authors := []models.Idea{{IdeaUID: "i1"}, {IdeaUID: "i2"}}
-	repo.On("SelectUserIdeas", "u1", 2).Return(authors, nil)
-	result, err := ideas.GetAuthorIdeas("u1", 2)
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	for _, name := range []string{"i1", "i2", "i3"} {
+		repotest.Idea(t, repo, u.UID, name)
+	}
+	result, err := ideas.GetAuthorIdeas(context.Background(), u.UID, 2)
 	assert.NoError(t, err)
-	assert.Equal(t, authors, result)
+	assert.Len(t, result, 2)
 }
 
-func TestGetIdeaComments(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	comms := []models.Comment{{CommentUID: "c"}}
-	repo.On("SelectIdeaComments", "i1").Return(comms, nil)
-	result, err := ideas.GetIdeaComments("i1")
+func TestSearchIdeas(t *testing.T) {
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "Солнечные панели")
+	repotest.Idea(t, repo, u.UID, "Велопарковка")
+
+	result, err := ideas.SearchIdeas(context.Background(), "панели", 10, "")
 	assert.NoError(t, err)
-	assert.Equal(t, comms, result)
+	require.Len(t, result, 1)
+	assert.Equal(t, idea.IdeaUID, result[0].IdeaUID)
 }
 
-func TestGetCommentReplies(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	repls := []models.Reply{{ReplyUID: "r"}}
-	repo.On("SelectCommentReplies", "c1").Return(repls, nil)
-	result, err := ideas.GetCommentReplies("c1")
-	assert.NoError(t, err)
-	assert.Equal(t, repls, result)
+func TestSearchIdeas_EmptyQuery(t *testing.T) {
+	ideas, _ := setupIdeas(t)
+	_, err := ideas.SearchIdeas(context.Background(), " ,. ", 10, "")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+// chain inserts replies one under another below parent, returns them top down
+func chain(t *testing.T, ideas *Ideas, parent models.Comment, author string, n int) []models.Comment {
+	var res []models.Comment
+	for k := 0; k < n; k++ {
+		c, err := ideas.InsertComment(context.Background(), parent.IdeaUID, parent.CommentUID, author, "reply")
+		require.NoError(t, err)
+		res = append(res, c)
+		parent = c
+	}
+	return res
+}
+
+func TestGetIdeaComments(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+
+	var roots []models.Comment
+	for k := 0; k < 3; k++ {
+		c, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "c")
+		require.NoError(t, err)
+		roots = append(roots, c)
+		time.Sleep(2 * time.Millisecond)
+	}
+	deep := chain(t, ideas, roots[0], u.UID, ThreadDepth+1)
+
+	page, err := ideas.GetIdeaComments(ctx, idea.IdeaUID, "", "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Comments, 2)
+	assert.Equal(t, roots[0].CommentUID, page.Comments[0].CommentUID)
+	assert.Equal(t, roots[1].CommentUID, page.Comments[1].CommentUID)
+	assert.Equal(t, roots[1].CommentUID, page.NextCursor)
+
+	// ThreadDepth levels are loaded, the last one reports more children
+	node := page.Comments[0]
+	for _, want := range deep[:ThreadDepth] {
+		require.Len(t, node.Children, 1)
+		node = node.Children[0]
+		assert.Equal(t, want.CommentUID, node.CommentUID)
+	}
+	assert.Equal(t, 1, node.ChildCount)
+	assert.Empty(t, node.Children)
+
+	page, err = ideas.GetIdeaComments(ctx, idea.IdeaUID, "", page.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Comments, 1)
+	assert.Equal(t, roots[2].CommentUID, page.Comments[0].CommentUID)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = ideas.GetIdeaComments(ctx, idea.IdeaUID, "", "not-a-uid", 2)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = ideas.GetIdeaComments(ctx, uuid.NewString(), "", "", 2)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestGetCommentChildren(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	root, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "c")
+	require.NoError(t, err)
+	deep := chain(t, ideas, root, u.UID, ThreadDepth+2)
+
+	// continue below the last loaded level
+	last := deep[ThreadDepth-1]
+	page, err := ideas.GetCommentChildren(ctx, last.CommentUID, "", "", 10)
+	require.NoError(t, err)
+	require.Len(t, page.Comments, 1)
+	assert.Equal(t, deep[ThreadDepth].CommentUID, page.Comments[0].CommentUID)
+	require.Len(t, page.Comments[0].Children, 1)
+	assert.Equal(t, deep[ThreadDepth+1].CommentUID, page.Comments[0].Children[0].CommentUID)
+
+	page, err = ideas.GetCommentChildren(ctx, deep[len(deep)-1].CommentUID, "", "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, page.Comments)
+	assert.NotNil(t, page.Comments)
+
+	_, err = ideas.GetCommentChildren(ctx, uuid.NewString(), "", "", 10)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
 }
 
 func TestInsertIdea_Validation(t *testing.T) {
-	ideas, _ := setupIdeasWithMocks(t)
-	id, err := ideas.InsertIdea("", "body", "author", 1, 1)
-	assert.NoError(t, err)
+	ideas, _ := setupIdeas(t)
+	id, err := ideas.InsertIdea(context.Background(), "", "body", "author", 1, 1)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
 	assert.Equal(t, models.Idea{}, id)
 }
 
+func TestInsertIdea_UnknownCategory(t *testing.T) {
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	_, err := ideas.InsertIdea(context.Background(), "n", "t", u.UID, 1, 999)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
 func TestInsertIdea_Success(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	repo.On("InsertIdea", mock.AnythingOfType("models.Idea")).Return(nil)
-	idea, err := ideas.InsertIdea("n", "t", "a", 1, 1)
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea, err := ideas.InsertIdea(ctx, "n", "t", u.UID, 1, 1)
 	assert.NoError(t, err)
 	assert.Equal(t, "n", idea.Name)
-	assert.Equal(t, "a", idea.Author)
+	assert.Equal(t, u.UID, idea.Author)
+
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "t", stored.Text)
 }
 
 func TestInsertComment_Validate(t *testing.T) {
-	ideas, _ := setupIdeasWithMocks(t)
-	c, err := ideas.InsertComment("", "uid", "txt")
-	assert.NoError(t, err)
+	ideas, _ := setupIdeas(t)
+	c, err := ideas.InsertComment(context.Background(), "", "", "uid", "txt")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
 	assert.Equal(t, models.Comment{}, c)
 }
 
+func TestInsertComment_IdeaNotFound(t *testing.T) {
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	_, err := ideas.InsertComment(context.Background(), uuid.NewString(), "", u.UID, "t")
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestInsertComment_ParentNotFound(t *testing.T) {
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	_, err := ideas.InsertComment(context.Background(), idea.IdeaUID, uuid.NewString(), u.UID, "t")
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestInsertComment_ParentOfAnotherIdea(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	other := repotest.Idea(t, repo, u.UID, "other")
+	c, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "t")
+	require.NoError(t, err)
+
+	_, err = ideas.InsertComment(ctx, other.IdeaUID, c.CommentUID, u.UID, "t")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+func TestInsertComment_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	root, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "t")
+	require.NoError(t, err)
+
+	deepest := chain(t, ideas, root, u.UID, MaxCommentDepth)[MaxCommentDepth-1]
+	assert.Equal(t, MaxCommentDepth, deepest.Depth)
+
+	_, err = ideas.InsertComment(ctx, idea.IdeaUID, deepest.CommentUID, u.UID, "too deep")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
 func TestInsertComment_Success(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	repo.On("InsertIdeaComment", mock.AnythingOfType("models.Comment")).Return(nil)
-	c, err := ideas.InsertComment("i", "a", "t")
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	c, err := ideas.InsertComment(context.Background(), idea.IdeaUID, "", u.UID, "t")
 	assert.NoError(t, err)
-	assert.Equal(t, "i", c.IdeaUID)
+	assert.Equal(t, idea.IdeaUID, c.IdeaUID)
+	assert.Nil(t, c.ParentUID)
+	assert.Equal(t, 0, c.Depth)
 }
 
 func TestInsertReply_Validate(t *testing.T) {
-	ideas, _ := setupIdeasWithMocks(t)
-	r, err := ideas.InsertReply("", "author", "txt")
-	assert.NoError(t, err)
-	assert.Equal(t, models.Reply{}, r)
+	ideas, _ := setupIdeas(t)
+	r, err := ideas.InsertReply(context.Background(), "", "author", "txt")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	assert.Equal(t, models.Comment{}, r)
 }
 
 func TestInsertReply_Success(t *testing.T) {
-	ideas, repo := setupIdeasWithMocks(t)
-	repo.On("InsertCommentReply", mock.AnythingOfType("models.Reply")).Return(nil)
-	r, err := ideas.InsertReply("comm", "auth", "txt")
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	c, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "t")
+	require.NoError(t, err)
+	r, err := ideas.InsertReply(ctx, c.CommentUID, u.UID, "txt")
+	assert.NoError(t, err)
+	assert.Equal(t, u.UID, r.AuthorID)
+	assert.Equal(t, idea.IdeaUID, r.IdeaUID)
+	require.NotNil(t, r.ParentUID)
+	assert.Equal(t, c.CommentUID, *r.ParentUID)
+	assert.Equal(t, 1, r.Depth)
+}
+
+// discussion creates an idea by author with one comment and one reply
+func discussion(t *testing.T, ideas *Ideas, repo *memory.Repository, author models.User) (models.Comment, models.Comment) {
+	ctx := context.Background()
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+	c, err := ideas.InsertComment(ctx, idea.IdeaUID, "", author.UID, "original")
+	require.NoError(t, err)
+	r, err := ideas.InsertReply(ctx, c.CommentUID, author.UID, "original reply")
+	require.NoError(t, err)
+	return c, r
+}
+
+func TestEditComment(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	c, _ := discussion(t, ideas, repo, u)
+
+	edited, err := ideas.EditComment(ctx, c.CommentUID, u.UID, "fixed")
+	require.NoError(t, err)
+	assert.Equal(t, "fixed", edited.CommentText)
+	assert.NotNil(t, edited.EditedAt)
+
+	// same text again is not a new revision
+	_, err = ideas.EditComment(ctx, c.CommentUID, u.UID, "fixed")
+	require.NoError(t, err)
+
+	revisions, err := ideas.GetCommentRevisions(ctx, c.CommentUID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "original", revisions[0].Text)
+}
+
+func TestEditComment_Forbidden(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	author := repotest.User(t, repo, "a@example.com")
+	stranger := repotest.User(t, repo, "b@example.com")
+	c, r := discussion(t, ideas, repo, author)
+
+	_, err := ideas.EditComment(ctx, c.CommentUID, stranger.UID, "hacked")
+	assert.ErrorIs(t, err, apperr.ErrForbidden)
+	_, err = ideas.EditComment(ctx, r.CommentUID, stranger.UID, "hacked")
+	assert.ErrorIs(t, err, apperr.ErrForbidden)
+	assert.ErrorIs(t, ideas.DeleteComment(ctx, c.CommentUID, stranger.UID), apperr.ErrForbidden)
+
+	stored, err := repo.SelectCommentByUID(ctx, c.CommentUID)
+	require.NoError(t, err)
+	assert.Equal(t, "original", stored.CommentText)
+}
+
+func TestEditComment_Moderator(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	author := repotest.User(t, repo, "a@example.com")
+	moderator := models.User{UID: uuid.NewString(), Email: "m@example.com", PositionID: 1, IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, moderator))
+	c, r := discussion(t, ideas, repo, author)
+
+	_, err := ideas.EditComment(ctx, r.CommentUID, moderator.UID, "moderated")
+	assert.NoError(t, err)
+	assert.NoError(t, ideas.DeleteComment(ctx, c.CommentUID, moderator.UID))
+
+	revisions, err := ideas.GetCommentRevisions(ctx, r.CommentUID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, moderator.UID, revisions[0].EditorUID)
+}
+
+func TestDeleteComment(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	c, r := discussion(t, ideas, repo, u)
+	_, err := ideas.EditComment(ctx, c.CommentUID, u.UID, "second version")
+	require.NoError(t, err)
+
+	require.NoError(t, ideas.DeleteComment(ctx, c.CommentUID, u.UID))
+	// deleting twice is fine
+	require.NoError(t, ideas.DeleteComment(ctx, c.CommentUID, u.UID))
+
+	thread, err := ideas.GetIdeaByUID(ctx, c.IdeaUID, "")
+	require.NoError(t, err)
+	require.Len(t, thread.Comments, 1)
+	tomb := thread.Comments[0]
+	assert.Empty(t, tomb.CommentText)
+	assert.NotNil(t, tomb.DeletedAt)
+	require.Len(t, tomb.Children, 1)
+	assert.Equal(t, r.CommentUID, tomb.Children[0].CommentUID)
+
+	revisions, err := ideas.GetCommentRevisions(ctx, c.CommentUID)
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	_, err = ideas.EditComment(ctx, c.CommentUID, u.UID, "resurrect")
+	assert.ErrorIs(t, err, apperr.ErrConflict)
+}
+
+func TestDeleteComment_NotFound(t *testing.T) {
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	err := ideas.DeleteComment(context.Background(), uuid.NewString(), u.UID)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestVote_Like(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	ok, err := ideas.Vote(ctx, idea.IdeaUID, u.UID, models.VoteLike)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.LikeCount)
+	assert.Equal(t, 0, stored.DislikeCount)
+}
+
+func TestVote_AlreadyVoted(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	_, err := ideas.Vote(ctx, idea.IdeaUID, u.UID, models.VoteLike)
+	require.NoError(t, err)
+
+	ok, err := ideas.Vote(ctx, idea.IdeaUID, u.UID, models.VoteDislike)
 	assert.NoError(t, err)
-	assert.Equal(t, "auth", r.AuthorID)
+	assert.False(t, ok)
+
+	stored, err := repo.SelectIdeaByUID(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.DislikeCount)
+}
+
+func TestVote_IdeaNotFound(t *testing.T) {
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	_, err := ideas.Vote(context.Background(), uuid.NewString(), u.UID, models.VoteLike)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestChangeIdeaStatus(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	notifier := &recordingNotifier{}
+	ideas.SetNotifier(notifier)
+	author := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+	mod := models.User{UID: uuid.NewString(), Name: "Mod", Surname: "Erator", PositionID: 1,
+		Email: "mod@example.com", Password: "hash", IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, mod))
+
+	_, err := ideas.ChangeIdeaStatus(ctx, idea.IdeaUID, author.UID, 2)
+	assert.ErrorIs(t, err, apperr.ErrForbidden)
+	_, err = ideas.ChangeIdeaStatus(ctx, uuid.NewString(), mod.UID, 2)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = ideas.ChangeIdeaStatus(ctx, idea.IdeaUID, mod.UID, 999)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	changed, err := ideas.ChangeIdeaStatus(ctx, idea.IdeaUID, mod.UID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed.StatusID)
+	_, err = ideas.ChangeIdeaStatus(ctx, idea.IdeaUID, mod.UID, 2)
+	require.NoError(t, err)
+
+	require.Len(t, notifier.statuses, 1, "setting the same status again notifies no one")
+	assert.Equal(t, 2, notifier.statuses[0].StatusID)
+}
+
+func TestNotifier_CommentsAndVotes(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	notifier := &recordingNotifier{}
+	ideas.SetNotifier(notifier)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "comment")
+	require.NoError(t, err)
+	reply, err := ideas.InsertReply(ctx, comment.CommentUID, u.UID, "reply")
+	require.NoError(t, err)
+	require.Len(t, notifier.comments, 2)
+	assert.Equal(t, comment.CommentUID, notifier.comments[0].CommentUID)
+	assert.Equal(t, reply.CommentUID, notifier.comments[1].CommentUID)
+	assert.Equal(t, comment.CommentUID, *notifier.comments[1].ParentUID)
+
+	_, err = ideas.Vote(ctx, idea.IdeaUID, u.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = ideas.Vote(ctx, idea.IdeaUID, u.UID, models.VoteDislike)
+	require.NoError(t, err)
+	assert.Equal(t, []models.VoteType{models.VoteLike}, notifier.votes, "only counted votes")
 }
@@ -0,0 +1,220 @@
+package ideas
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// MaxMentions - references parsed from one text, the rest are left as plain text
+const MaxMentions = 50
+
+// mentionPattern matches @uid or @name.surname, names may contain letters of
+// any script, digits, '_' and '-'
+var mentionPattern = regexp.MustCompile(
+	`@(?:([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})|([\p{L}\p{M}\p{N}_-]+)\.([\p{L}\p{M}\p{N}_-]+))`,
+)
+
+// handle - unresolved reference found in a text, start and end are in runes
+type handle struct {
+	start, end int
+	uid        string
+	name       models.FullName
+}
+
+// parseHandles finds references in text. The @ must not follow a letter, a
+// digit or one of "_.@-", so e-mail addresses are not mentions.
+func parseHandles(text string) []handle {
+	var handles []handle
+	for _, loc := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		if len(handles) == MaxMentions {
+			break
+		}
+		if prev, _ := utf8.DecodeLastRuneInString(text[:loc[0]]); loc[0] > 0 && isHandleRune(prev) {
+			continue
+		}
+		// a uid glued to more name characters is something else
+		if next, _ := utf8.DecodeRuneInString(text[loc[1]:]); loc[2] >= 0 && loc[1] < len(text) && isNameRune(next) {
+			continue
+		}
+
+		h := handle{
+			start: utf8.RuneCountInString(text[:loc[0]]),
+			end:   utf8.RuneCountInString(text[:loc[1]]),
+		}
+		if loc[2] >= 0 {
+			h.uid = strings.ToLower(text[loc[2]:loc[3]])
+		} else {
+			h.name = models.FullName{Name: text[loc[4]:loc[5]], Surname: text[loc[6]:loc[7]]}
+		}
+		handles = append(handles, h)
+	}
+	return handles
+}
+
+func isHandleRune(r rune) bool {
+	return isNameRune(r) || r == '.' || r == '@'
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+// resolveMentions turns references in text into mentions of existing users.
+// A name shared by several users is ambiguous and stays plain text, @uid
+// works for them. Target fields of the result are left to the caller.
+func (i *Ideas) resolveMentions(ctx context.Context, text string) ([]models.Mention, error) {
+	handles := parseHandles(text)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	var uids []string
+	var names []models.FullName
+	for _, h := range handles {
+		if h.uid != "" {
+			uids = append(uids, h.uid)
+		} else {
+			names = append(names, h.name)
+		}
+	}
+
+	byUID := map[string]models.AuthorProfile{}
+	profiles, err := i.repo.SelectProfiles(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		byUID[strings.ToLower(p.UID)] = p
+	}
+
+	byName := map[models.FullName][]models.AuthorProfile{}
+	profiles, err = i.repo.SelectProfilesByName(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		key := fullNameKey(models.FullName{Name: p.Name, Surname: p.Surname})
+		byName[key] = append(byName[key], p)
+	}
+
+	var mentions []models.Mention
+	for _, h := range handles {
+		var user models.AuthorProfile
+		if h.uid != "" {
+			p, ok := byUID[h.uid]
+			if !ok {
+				continue
+			}
+			user = p
+		} else {
+			matches := byName[fullNameKey(h.name)]
+			if len(matches) != 1 {
+				continue
+			}
+			user = matches[0]
+		}
+		mentions = append(mentions, models.Mention{
+			UserUID: user.UID,
+			Start:   h.start,
+			End:     h.end,
+			User:    &user,
+		})
+	}
+	return mentions, nil
+}
+
+func fullNameKey(n models.FullName) models.FullName {
+	return models.FullName{Name: strings.ToLower(n.Name), Surname: strings.ToLower(n.Surname)}
+}
+
+// saveMentions stores mentions of a new or edited text and fills their target
+// fields. Returns users to notify: mentioned for the first time in this
+// text and not the author.
+func saveMentions(ctx context.Context, repo repository.Repository, mentions []models.Mention,
+	target models.TargetType, targetUID, ideaUID, authorUID string, previous []models.Mention) ([]models.Mention, error) {
+	for k := range mentions {
+		mentions[k].TargetType = target
+		mentions[k].TargetUID = targetUID
+		mentions[k].IdeaUID = ideaUID
+		mentions[k].AuthorUID = authorUID
+	}
+	if err := repo.InsertMentions(ctx, mentions); err != nil {
+		return nil, err
+	}
+
+	var notify []models.Mention
+	for _, m := range mentions {
+		seen := func(o models.Mention) bool { return o.UserUID == m.UserUID }
+		if m.UserUID == authorUID || slices.ContainsFunc(previous, seen) || slices.ContainsFunc(notify, seen) {
+			continue
+		}
+		notify = append(notify, m)
+	}
+	return notify, nil
+}
+
+// notifyMentioned hands mentions to the notifier, failures are only logged:
+// the text is already saved
+func (i *Ideas) notifyMentioned(ctx context.Context, mentions []models.Mention) {
+	for _, m := range mentions {
+		if err := i.notifier.Mentioned(ctx, m); err != nil {
+			i.log.ErrorContext(ctx, "failed to notify mentioned user",
+				slog.String("userUID", m.UserUID),
+				slog.String("targetUID", m.TargetUID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// withIdeaMentions fills Mentions of every idea with one query
+func (i *Ideas) withIdeaMentions(ctx context.Context, repo repository.Repository, ideas []models.Idea) error {
+	uids := make([]string, 0, len(ideas))
+	for _, idea := range ideas {
+		uids = append(uids, idea.IdeaUID)
+	}
+	byTarget, err := mentionsByTarget(ctx, repo, uids)
+	if err != nil {
+		return err
+	}
+	for k := range ideas {
+		ideas[k].Mentions = byTarget[ideas[k].IdeaUID]
+	}
+	return nil
+}
+
+// withCommentMentions fills Mentions of every comment with one query
+func (i *Ideas) withCommentMentions(ctx context.Context, repo repository.Repository, comments []models.Comment) error {
+	uids := make([]string, 0, len(comments))
+	for _, c := range comments {
+		uids = append(uids, c.CommentUID)
+	}
+	byTarget, err := mentionsByTarget(ctx, repo, uids)
+	if err != nil {
+		return err
+	}
+	for k := range comments {
+		comments[k].Mentions = byTarget[comments[k].CommentUID]
+	}
+	return nil
+}
+
+func mentionsByTarget(ctx context.Context, repo repository.Repository, targetUIDs []string) (map[string][]models.Mention, error) {
+	mentions, err := repo.SelectMentions(ctx, targetUIDs)
+	if err != nil {
+		return nil, err
+	}
+	byTarget := map[string][]models.Mention{}
+	for _, m := range mentions {
+		byTarget[m.TargetUID] = append(byTarget[m.TargetUID], m)
+	}
+	return byTarget, nil
+}
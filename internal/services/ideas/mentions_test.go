@@ -0,0 +1,165 @@
+package ideas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier keeps every event it gets
+type recordingNotifier struct {
+	mentions []models.Mention
+	comments []models.Comment
+	votes    []models.VoteType
+	statuses []models.Idea
+}
+
+func (n *recordingNotifier) Mentioned(ctx context.Context, m models.Mention) error {
+	n.mentions = append(n.mentions, m)
+	return nil
+}
+
+func (n *recordingNotifier) Commented(ctx context.Context, c models.Comment) error {
+	n.comments = append(n.comments, c)
+	return nil
+}
+
+func (n *recordingNotifier) Voted(ctx context.Context, ideaUID, voterUID string, vote models.VoteType) error {
+	n.votes = append(n.votes, vote)
+	return nil
+}
+
+func (n *recordingNotifier) StatusChanged(ctx context.Context, idea models.Idea, actorUID string) error {
+	n.statuses = append(n.statuses, idea)
+	return nil
+}
+
+func (n *recordingNotifier) mentioned() []string {
+	var uids []string
+	for _, m := range n.mentions {
+		uids = append(uids, m.UserUID)
+	}
+	return uids
+}
+
+func namedUser(t *testing.T, repo *memory.Repository, name, surname string) models.User {
+	u := models.User{UID: uuid.NewString(), Name: name, Surname: surname,
+		PositionID: repotest.Positions[0].ID, Email: uuid.NewString() + "@example.com", Password: "hash"}
+	require.NoError(t, repo.InsertUser(context.Background(), u))
+	return u
+}
+
+func TestParseHandles(t *testing.T) {
+	uid := uuid.NewString()
+	tests := []struct {
+		text string
+		want []handle
+	}{
+		{"@Иван.Петров, привет", []handle{{start: 0, end: 12, name: models.FullName{Name: "Иван", Surname: "Петров"}}}},
+		{"спасибо @anna.smith-jones.", []handle{{start: 8, end: 25, name: models.FullName{Name: "anna", Surname: "smith-jones"}}}},
+		{"cc @" + uid + ".", []handle{{start: 3, end: 40, uid: uid}}},
+		{"🎉 @a.b @c.d", []handle{
+			{start: 2, end: 6, name: models.FullName{Name: "a", Surname: "b"}},
+			{start: 7, end: 11, name: models.FullName{Name: "c", Surname: "d"}},
+		}},
+		{"mail ivan@petrov.ru", nil},
+		{"@ivan without surname", nil},
+		{"@" + uid + "abc", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseHandles(tt.text))
+		})
+	}
+}
+
+func TestInsertIdea_Mentions(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	notifier := &recordingNotifier{}
+	ideas.SetNotifier(notifier)
+	author := namedUser(t, repo, "Олег", "Смирнов")
+	anna := namedUser(t, repo, "Анна", "Иванова")
+	bob := namedUser(t, repo, "Bob", "Stone")
+
+	text := "@анна.иванова и @" + bob.UID + ", посмотрите. @Анна.Иванова @Олег.Смирнов @Кто.Это"
+	idea, err := ideas.InsertIdea(ctx, "idea", text, author.UID, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, idea.Mentions, 4)
+	assert.Equal(t, anna.UID, idea.Mentions[0].UserUID)
+	assert.Equal(t, 0, idea.Mentions[0].Start)
+	assert.Equal(t, 13, idea.Mentions[0].End)
+	assert.Equal(t, "Иванова", idea.Mentions[0].User.Surname)
+	assert.Equal(t, bob.UID, idea.Mentions[1].UserUID)
+
+	// once per user, never the author
+	assert.Equal(t, []string{anna.UID, bob.UID}, notifier.mentioned())
+	assert.Equal(t, models.TargetIdea, notifier.mentions[0].TargetType)
+	assert.Equal(t, idea.IdeaUID, notifier.mentions[0].IdeaUID)
+	assert.Equal(t, author.UID, notifier.mentions[0].AuthorUID)
+
+	ic, err := ideas.GetIdeaByUID(ctx, idea.IdeaUID, "")
+	require.NoError(t, err)
+	require.Len(t, ic.Idea.Mentions, 4)
+	assert.Equal(t, "Анна", ic.Idea.Mentions[0].User.Name)
+}
+
+func TestInsertComment_AmbiguousMention(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	notifier := &recordingNotifier{}
+	ideas.SetNotifier(notifier)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	first := namedUser(t, repo, "Анна", "Иванова")
+	namedUser(t, repo, "Анна", "Иванова")
+
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "@Анна.Иванова или @"+first.UID)
+	require.NoError(t, err)
+	require.Len(t, comment.Mentions, 1)
+	assert.Equal(t, first.UID, comment.Mentions[0].UserUID)
+	assert.Equal(t, []string{first.UID}, notifier.mentioned())
+}
+
+func TestEditComment_Mentions(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	notifier := &recordingNotifier{}
+	ideas.SetNotifier(notifier)
+	author := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+	namedUser(t, repo, "Анна", "Иванова")
+	bob := namedUser(t, repo, "Bob", "Stone")
+
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", author.UID, "@Анна.Иванова")
+	require.NoError(t, err)
+	reply, err := ideas.InsertReply(ctx, comment.CommentUID, author.UID, "@bob.stone")
+	require.NoError(t, err)
+	assert.Len(t, reply.Mentions, 1)
+	notifier.mentions = nil
+
+	edited, err := ideas.EditComment(ctx, comment.CommentUID, author.UID, "@bob.stone, @Анна.Иванова")
+	require.NoError(t, err)
+	require.Len(t, edited.Mentions, 2)
+	assert.Equal(t, bob.UID, edited.Mentions[0].UserUID)
+	assert.Equal(t, 12, edited.Mentions[1].Start)
+	assert.Equal(t, []string{bob.UID}, notifier.mentioned(), "anna was mentioned before the edit")
+
+	page, err := ideas.GetIdeaComments(ctx, idea.IdeaUID, "", "", 10)
+	require.NoError(t, err)
+	require.Len(t, page.Comments, 1)
+	assert.Len(t, page.Comments[0].Mentions, 2)
+	require.Len(t, page.Comments[0].Children, 1)
+	assert.Len(t, page.Comments[0].Children[0].Mentions, 1)
+
+	require.NoError(t, ideas.DeleteComment(ctx, comment.CommentUID, author.UID))
+	page, err = ideas.GetIdeaComments(ctx, idea.IdeaUID, "", "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, page.Comments[0].Mentions)
+}
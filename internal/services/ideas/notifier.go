@@ -0,0 +1,44 @@
+package ideas
+
+import (
+	"context"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// Notifier delivers events of the ideas service to the users concerned. It is
+// called after the change is committed, an error is logged and doesn't undo
+// the change. Who is told about an event is up to the notifier.
+type Notifier interface {
+	// Mentioned is called once per user newly mentioned in a text, authors
+	// mentioning themselves are skipped
+	Mentioned(ctx context.Context, mention models.Mention) error
+	// Commented is called for every new comment and reply
+	Commented(ctx context.Context, comment models.Comment) error
+	// Voted is called for every counted vote
+	Voted(ctx context.Context, ideaUID, voterUID string, vote models.VoteType) error
+	// StatusChanged is called with the idea after the change
+	StatusChanged(ctx context.Context, idea models.Idea, actorUID string) error
+}
+
+// SetNotifier replaces the notifier, by default events go nowhere
+func (i *Ideas) SetNotifier(n Notifier) {
+	i.notifier = n
+}
+
+// notifyFailed logs a notifier error, the change itself is already saved
+func (i *Ideas) notifyFailed(ctx context.Context, event string, err error) {
+	if err != nil {
+		i.log.ErrorContext(ctx, "failed to notify about "+event+": "+err.Error())
+	}
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) Mentioned(context.Context, models.Mention) error { return nil }
+
+func (nopNotifier) Commented(context.Context, models.Comment) error { return nil }
+
+func (nopNotifier) Voted(context.Context, string, string, models.VoteType) error { return nil }
+
+func (nopNotifier) StatusChanged(context.Context, models.Idea, string) error { return nil }
@@ -0,0 +1,122 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// Publisher pushes changes to connected clients, see models.Event. Like the
+// notifier it is called after the change is committed and an error is only
+// logged.
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// Outbox queues events that must not be lost, like webhook deliveries.
+// Queue is called within the transaction of the change with its
+// repository, so the event is committed or rolled back with the change and
+// an error fails the change. The same event then goes to the Publisher.
+type Outbox interface {
+	Queue(ctx context.Context, repo repository.Repository, event models.Event) error
+}
+
+// SetPublisher replaces the publisher, by default changes are not pushed
+func (i *Ideas) SetPublisher(p Publisher) {
+	i.publisher = p
+}
+
+// SetOutbox replaces the outbox, by default events are not queued
+func (i *Ideas) SetOutbox(o Outbox) {
+	i.outbox = o
+}
+
+// Publishers hands every event to each publisher in turn, one failing
+// doesn't keep it from the rest
+type Publishers []Publisher
+
+func (ps Publishers) Publish(ctx context.Context, event models.Event) error {
+	var errs []error
+	for _, p := range ps {
+		errs = append(errs, p.Publish(ctx, event))
+	}
+	return errors.Join(errs...)
+}
+
+// ideaTopics - an idea is shown in the feed and on its own page
+func ideaTopics(ideaUID string) []string {
+	return []string{models.TopicIdeas, models.IdeaTopic(ideaUID)}
+}
+
+// ideaEvent carries the idea as the API returns it, it is read back for the
+// fields set by the storage
+func (i *Ideas) ideaEvent(ctx context.Context, repo repository.Repository, typ models.EventType, ideaUID string) (models.Event, error) {
+	idea, err := repo.SelectIdeaByUID(ctx, ideaUID)
+	if err != nil {
+		return models.Event{}, err
+	}
+	ideas := []models.Idea{idea}
+	if err := i.withIdeaMentions(ctx, repo, ideas); err != nil {
+		return models.Event{}, err
+	}
+	return models.Event{Type: typ, Topics: ideaTopics(ideaUID), Data: ideas[0]}, nil
+}
+
+func (i *Ideas) votesEvent(ctx context.Context, repo repository.Repository, ideaUID string) (models.Event, error) {
+	idea, err := repo.SelectIdeaByUID(ctx, ideaUID)
+	if err != nil {
+		return models.Event{}, err
+	}
+	return models.Event{
+		Type:   models.EventVotes,
+		Topics: ideaTopics(ideaUID),
+		Data: models.VoteCounts{
+			IdeaUID:      ideaUID,
+			LikeCount:    idea.LikeCount,
+			DislikeCount: idea.DislikeCount,
+		},
+	}, nil
+}
+
+// commentEvent carries the new comment with its author and mentions, as it
+// appears in the discussion
+func (i *Ideas) commentEvent(ctx context.Context, repo repository.Repository, commentUID string) (models.Event, error) {
+	comment, err := repo.SelectCommentByUID(ctx, commentUID)
+	if err != nil {
+		return models.Event{}, err
+	}
+	profiles, err := repo.SelectProfiles(ctx, []string{comment.AuthorID})
+	if err != nil {
+		return models.Event{}, err
+	}
+	comments := []models.Comment{comment}
+	if err := i.withCommentMentions(ctx, repo, comments); err != nil {
+		return models.Event{}, err
+	}
+	comment = comments[0]
+	if len(profiles) == 1 {
+		comment.AuthorProfile = &profiles[0]
+	}
+	return models.Event{
+		Type:   models.EventCommentAdded,
+		Topics: []string{models.IdeaTopic(comment.IdeaUID)},
+		Data:   comment,
+	}, nil
+}
+
+// publish pushes a committed change, an error is only logged
+func (i *Ideas) publish(ctx context.Context, event models.Event) {
+	if err := i.publisher.Publish(ctx, event); err != nil {
+		i.log.ErrorContext(ctx, "failed to publish "+string(event.Type)+": "+err.Error())
+	}
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, models.Event) error { return nil }
+
+type nopOutbox struct{}
+
+func (nopOutbox) Queue(context.Context, repository.Repository, models.Event) error { return nil }
@@ -0,0 +1,119 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher keeps every event it gets
+type recordingPublisher struct {
+	events []models.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e models.Event) error {
+	p.events = append(p.events, e)
+	return nil
+}
+
+// failingOutbox counts the events queued with it and then fails the change
+type failingOutbox struct {
+	queued int
+}
+
+func (o *failingOutbox) Queue(ctx context.Context, repo repository.Repository, e models.Event) error {
+	o.queued++
+	return errors.New("outbox is full")
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	publisher := &recordingPublisher{}
+	ideas.SetPublisher(publisher)
+	author := namedUser(t, repo, "Анна", "Иванова")
+	voter := repotest.User(t, repo, "voter@example.com")
+	admin := models.User{UID: uuid.NewString(), Email: "admin@example.com", PositionID: 1, IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, admin))
+
+	idea, err := ideas.InsertIdea(ctx, "idea", "@анна.иванова", author.UID, 1, 1)
+	require.NoError(t, err)
+	feed := []string{models.TopicIdeas, models.IdeaTopic(idea.IdeaUID)}
+
+	require.Len(t, publisher.events, 1)
+	e := publisher.events[0]
+	assert.Equal(t, models.EventIdeaCreated, e.Type)
+	assert.Equal(t, feed, e.Topics)
+	published := e.Data.(models.Idea)
+	assert.False(t, published.CreationDate.IsZero(), "read back from the storage")
+	assert.Len(t, published.Mentions, 1)
+
+	_, err = ideas.Vote(ctx, idea.IdeaUID, voter.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = ideas.Vote(ctx, idea.IdeaUID, voter.UID, models.VoteLike)
+	require.NoError(t, err)
+	require.Len(t, publisher.events, 2, "a repeated vote changes nothing")
+	assert.Equal(t, models.Event{
+		Type:   models.EventVotes,
+		Topics: feed,
+		Data:   models.VoteCounts{IdeaUID: idea.IdeaUID, LikeCount: 1},
+	}, publisher.events[1])
+
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", voter.UID, "comment")
+	require.NoError(t, err)
+	require.Len(t, publisher.events, 3)
+	e = publisher.events[2]
+	assert.Equal(t, models.EventCommentAdded, e.Type)
+	assert.Equal(t, []string{models.IdeaTopic(idea.IdeaUID)}, e.Topics)
+	added := e.Data.(models.Comment)
+	assert.Equal(t, comment.CommentUID, added.CommentUID)
+	require.NotNil(t, added.AuthorProfile)
+	assert.Equal(t, voter.Name, added.AuthorProfile.Name)
+
+	_, err = ideas.ChangeIdeaStatus(ctx, idea.IdeaUID, admin.UID, 2)
+	require.NoError(t, err)
+	_, err = ideas.ChangeIdeaStatus(ctx, idea.IdeaUID, admin.UID, 2)
+	require.NoError(t, err)
+	require.Len(t, publisher.events, 4)
+	assert.Equal(t, models.EventIdeaUpdated, publisher.events[3].Type)
+	assert.Equal(t, 2, publisher.events[3].Data.(models.Idea).StatusID)
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	publisher := &recordingPublisher{}
+	ideas.SetPublisher(publisher)
+	author := repotest.User(t, repo, "author@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+
+	outbox := &failingOutbox{}
+	ideas.SetOutbox(outbox)
+	_, err := ideas.InsertIdea(ctx, "lost", "text", author.UID, 1, 1)
+	assert.Error(t, err)
+	_, err = ideas.Vote(ctx, idea.IdeaUID, author.UID, models.VoteLike)
+	assert.Error(t, err)
+	_, err = ideas.InsertComment(ctx, idea.IdeaUID, "", author.UID, "comment")
+	assert.Error(t, err)
+	assert.Equal(t, 3, outbox.queued)
+
+	// nothing is changed without its event, nor published
+	all, err := repo.SelectIdeas(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Zero(t, all[0].LikeCount)
+	ok, err := repo.CheckVote(ctx, idea.IdeaUID, author.UID)
+	require.NoError(t, err)
+	assert.True(t, ok, "the vote is rolled back too")
+	comments, err := repo.SelectIdeaComments(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+	assert.Empty(t, publisher.events)
+}
@@ -0,0 +1,205 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// DefaultReactions - emojis users can react with unless SetReactions is called
+var DefaultReactions = []string{"👍", "👎", "❤️", "🎉", "😄", "🤔"}
+
+// maxEmojiLength matches the emoji column
+const maxEmojiLength = 32
+
+// SetReactions replaces the emoji set, responses list counts in this order
+func (i *Ideas) SetReactions(emojis []string) error {
+	var set []string
+	for _, e := range emojis {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if utf8.RuneCountInString(e) > maxEmojiLength {
+			return fmt.Errorf("reaction %q is longer than %d characters", e, maxEmojiLength)
+		}
+		if slices.Contains(set, e) {
+			return fmt.Errorf("reaction %q is listed twice", e)
+		}
+		set = append(set, e)
+	}
+	if len(set) == 0 {
+		return errors.New("reaction set is empty")
+	}
+	i.reactions = set
+	return nil
+}
+
+// GetReactionSet returns allowed emojis in display order
+func (i *Ideas) GetReactionSet() []string {
+	return i.reactions
+}
+
+// React leaves the emoji on an idea or a comment. Reacting twice with the same
+// emoji is a no-op. Returns the target's counts after the change.
+func (i *Ideas) React(ctx context.Context, target models.TargetType, targetUID, userUID, emoji string) ([]models.ReactionCount, error) {
+	op := "IdeasReact"
+	log := i.log.With(slog.String("op", op),
+		slog.String("target", string(target)),
+		slog.String("targetUID", targetUID),
+		slog.String("emoji", emoji),
+	)
+	log.DebugContext(ctx, "adding reaction")
+
+	if !slices.Contains(i.reactions, emoji) {
+		return nil, apperr.Validation("unknown reaction")
+	}
+	if err := i.checkReactionTarget(ctx, target, targetUID, true); err != nil {
+		return nil, err
+	}
+
+	err := i.repo.InsertReaction(ctx, models.Reaction{TargetUID: targetUID, UserUID: userUID, Emoji: emoji})
+	if err != nil && !errors.Is(err, repository.ErrConflict) {
+		log.ErrorContext(ctx, "failed to insert reaction"+err.Error())
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "successfully added reaction")
+	return i.targetReactions(ctx, targetUID, userUID)
+}
+
+// Unreact removes the user's emoji from the target, removing a missing
+// reaction is a no-op. Emojis dropped from the set can still be removed.
+func (i *Ideas) Unreact(ctx context.Context, target models.TargetType, targetUID, userUID, emoji string) ([]models.ReactionCount, error) {
+	op := "IdeasUnreact"
+	log := i.log.With(slog.String("op", op),
+		slog.String("target", string(target)),
+		slog.String("targetUID", targetUID),
+		slog.String("emoji", emoji),
+	)
+	log.DebugContext(ctx, "removing reaction")
+
+	if emoji == "" {
+		return nil, apperr.Validation("reaction is required")
+	}
+	if err := i.checkReactionTarget(ctx, target, targetUID, false); err != nil {
+		return nil, err
+	}
+
+	err := i.repo.DeleteReaction(ctx, targetUID, userUID, emoji)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.ErrorContext(ctx, "failed to delete reaction"+err.Error())
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "successfully removed reaction")
+	return i.targetReactions(ctx, targetUID, userUID)
+}
+
+// checkReactionTarget makes sure the target exists, deleted comments take no
+// new reactions
+func (i *Ideas) checkReactionTarget(ctx context.Context, target models.TargetType, uid string, adding bool) error {
+	switch target {
+	case models.TargetIdea:
+		_, err := i.repo.SelectIdeaByUID(ctx, uid)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.NotFound("idea not found")
+		}
+		return err
+	case models.TargetComment:
+		comment, err := i.repo.SelectCommentByUID(ctx, uid)
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.NotFound("comment not found")
+		}
+		if err != nil {
+			return err
+		}
+		if adding && comment.DeletedAt != nil {
+			return apperr.Conflict("comment is deleted")
+		}
+		return nil
+	default:
+		return apperr.Validation("unknown reaction target")
+	}
+}
+
+func (i *Ideas) targetReactions(ctx context.Context, targetUID, viewerUID string) ([]models.ReactionCount, error) {
+	byTarget, err := i.reactionCounts(ctx, []string{targetUID}, viewerUID)
+	if err != nil {
+		return nil, err
+	}
+	if counts := byTarget[targetUID]; counts != nil {
+		return counts, nil
+	}
+	return []models.ReactionCount{}, nil
+}
+
+// reactionCounts groups counts by target, emojis of the set go first in its
+// order, ones dropped from the set follow
+func (i *Ideas) reactionCounts(ctx context.Context, targetUIDs []string, viewerUID string) (map[string][]models.ReactionCount, error) {
+	counts, err := i.repo.SelectReactionCounts(ctx, targetUIDs, viewerUID)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := func(emoji string) int {
+		if k := slices.Index(i.reactions, emoji); k >= 0 {
+			return k
+		}
+		return len(i.reactions)
+	}
+
+	byTarget := map[string][]models.ReactionCount{}
+	for _, c := range counts {
+		byTarget[c.TargetUID] = append(byTarget[c.TargetUID], c)
+	}
+	for _, list := range byTarget {
+		slices.SortFunc(list, func(a, b models.ReactionCount) int {
+			if ra, rb := rank(a.Emoji), rank(b.Emoji); ra != rb {
+				return ra - rb
+			}
+			return strings.Compare(a.Emoji, b.Emoji)
+		})
+	}
+	return byTarget, nil
+}
+
+// withIdeaReactions fills Reactions of every idea with one query
+func (i *Ideas) withIdeaReactions(ctx context.Context, ideas []models.Idea, viewerUID string) error {
+	uids := make([]string, 0, len(ideas))
+	for _, idea := range ideas {
+		uids = append(uids, idea.IdeaUID)
+	}
+	byTarget, err := i.reactionCounts(ctx, uids, viewerUID)
+	if err != nil {
+		return err
+	}
+	for k := range ideas {
+		ideas[k].Reactions = byTarget[ideas[k].IdeaUID]
+	}
+	return nil
+}
+
+// withCommentReactions fills Reactions of every comment with one query
+func (i *Ideas) withCommentReactions(ctx context.Context, comments []models.Comment, viewerUID string) error {
+	uids := make([]string, 0, len(comments))
+	for _, c := range comments {
+		uids = append(uids, c.CommentUID)
+	}
+	byTarget, err := i.reactionCounts(ctx, uids, viewerUID)
+	if err != nil {
+		return err
+	}
+	for k := range comments {
+		comments[k].Reactions = byTarget[comments[k].CommentUID]
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package ideas
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetReactions(t *testing.T) {
+	ideas, _ := setupIdeas(t)
+	assert.Equal(t, DefaultReactions, ideas.GetReactionSet())
+
+	require.NoError(t, ideas.SetReactions([]string{" 👍", "🔥 ", ""}))
+	assert.Equal(t, []string{"👍", "🔥"}, ideas.GetReactionSet())
+
+	assert.Error(t, ideas.SetReactions([]string{" ", ""}))
+	assert.Error(t, ideas.SetReactions([]string{"👍", "👍"}))
+	assert.Error(t, ideas.SetReactions([]string{"abcdefghijklmnopqrstuvwxyz0123456789"}))
+	assert.Equal(t, []string{"👍", "🔥"}, ideas.GetReactionSet())
+}
+
+func TestReact(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u1 := repotest.User(t, repo, "a@example.com")
+	u2 := repotest.User(t, repo, "b@example.com")
+	idea := repotest.Idea(t, repo, u1.UID, "idea")
+
+	_, err := ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u2.UID, "🎉")
+	require.NoError(t, err)
+	_, err = ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u1.UID, "👍")
+	require.NoError(t, err)
+	counts, err := ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u1.UID, "👍")
+	require.NoError(t, err, "reacting twice is a no-op")
+
+	// set order, not insertion order
+	assert.Equal(t, []models.ReactionCount{
+		{TargetUID: idea.IdeaUID, Emoji: "👍", Count: 1, Mine: true},
+		{TargetUID: idea.IdeaUID, Emoji: "🎉", Count: 1, Mine: false},
+	}, counts)
+
+	counts, err = ideas.Unreact(ctx, models.TargetIdea, idea.IdeaUID, u1.UID, "👍")
+	require.NoError(t, err)
+	assert.Len(t, counts, 1)
+	_, err = ideas.Unreact(ctx, models.TargetIdea, idea.IdeaUID, u1.UID, "👍")
+	require.NoError(t, err, "removing a missing reaction is a no-op")
+
+	counts, err = ideas.Unreact(ctx, models.TargetIdea, idea.IdeaUID, u2.UID, "🎉")
+	require.NoError(t, err)
+	assert.Equal(t, []models.ReactionCount{}, counts)
+}
+
+func TestReact_Validate(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+
+	_, err := ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u.UID, "🦄")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = ideas.React(ctx, models.TargetIdea, uuid.NewString(), u.UID, "👍")
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+	_, err = ideas.React(ctx, models.TargetComment, uuid.NewString(), u.UID, "👍")
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestReact_RemovedFromSet(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	_, err := ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u.UID, "🤔")
+	require.NoError(t, err)
+
+	require.NoError(t, ideas.SetReactions([]string{"👍"}))
+	_, err = ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u.UID, "🤔")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	counts, err := ideas.Unreact(ctx, models.TargetIdea, idea.IdeaUID, u.UID, "🤔")
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+}
+
+func TestReact_DeletedComment(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u := repotest.User(t, repo, "a@example.com")
+	idea := repotest.Idea(t, repo, u.UID, "idea")
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u.UID, "comment")
+	require.NoError(t, err)
+	_, err = ideas.React(ctx, models.TargetComment, comment.CommentUID, u.UID, "👍")
+	require.NoError(t, err)
+
+	require.NoError(t, ideas.DeleteComment(ctx, comment.CommentUID, u.UID))
+	_, err = ideas.React(ctx, models.TargetComment, comment.CommentUID, u.UID, "❤️")
+	assert.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = ideas.Unreact(ctx, models.TargetComment, comment.CommentUID, u.UID, "👍")
+	assert.NoError(t, err)
+}
+
+func TestReactions_InDiscussion(t *testing.T) {
+	ctx := context.Background()
+	ideas, repo := setupIdeas(t)
+	u1 := repotest.User(t, repo, "a@example.com")
+	u2 := repotest.User(t, repo, "b@example.com")
+	idea := repotest.Idea(t, repo, u1.UID, "idea")
+	comment, err := ideas.InsertComment(ctx, idea.IdeaUID, "", u1.UID, "comment")
+	require.NoError(t, err)
+	reply, err := ideas.InsertReply(ctx, comment.CommentUID, u2.UID, "reply")
+	require.NoError(t, err)
+
+	_, err = ideas.React(ctx, models.TargetIdea, idea.IdeaUID, u1.UID, "❤️")
+	require.NoError(t, err)
+	_, err = ideas.React(ctx, models.TargetComment, reply.CommentUID, u2.UID, "😄")
+	require.NoError(t, err)
+
+	ic, err := ideas.GetIdeaByUID(ctx, idea.IdeaUID, u2.UID)
+	require.NoError(t, err)
+	require.Len(t, ic.Idea.Reactions, 1)
+	assert.Equal(t, "❤️", ic.Idea.Reactions[0].Emoji)
+	assert.False(t, ic.Idea.Reactions[0].Mine)
+	require.Len(t, ic.Comments, 1)
+	assert.Empty(t, ic.Comments[0].Reactions)
+	require.Len(t, ic.Comments[0].Children, 1)
+	require.Len(t, ic.Comments[0].Children[0].Reactions, 1)
+	assert.True(t, ic.Comments[0].Children[0].Reactions[0].Mine)
+
+	all, err := ideas.GetAllIdeas(ctx, u1.UID)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	require.Len(t, all[0].Reactions, 1)
+	assert.True(t, all[0].Reactions[0].Mine)
+}
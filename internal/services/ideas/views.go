@@ -0,0 +1,88 @@
+package ideas
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// ViewWindow - visits of an idea by a user within it count as one view
+const ViewWindow = 24 * time.Hour
+
+// GetViewedIdeas returns up to limit ideas the user has visited, last
+// visited first
+func (i *Ideas) GetViewedIdeas(ctx context.Context, viewerUID string, limit int) ([]models.Idea, error) {
+	op := "IdeasGetViewed"
+	log := i.log.With(slog.String("op", op), slog.String("viewerUID", viewerUID))
+
+	ideas, err := i.repo.SelectViewedIdeas(ctx, viewerUID, limit)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch viewed ideas: "+err.Error())
+		return []models.Idea{}, err
+	}
+	if ideas == nil {
+		ideas = []models.Idea{}
+	}
+	if err := i.decorateIdeas(ctx, ideas, viewerUID); err != nil {
+		log.ErrorContext(ctx, "failed to fetch reactions and mentions: "+err.Error())
+		return []models.Idea{}, err
+	}
+	return ideas, nil
+}
+
+// recordView saves the viewer's visit and counts it as a view of the idea,
+// unless the viewer is the author or was counted within ViewWindow. It
+// returns whether the view was counted; a failure is only logged, the idea
+// is shown anyway.
+func (i *Ideas) recordView(ctx context.Context, log *slog.Logger, idea models.Idea, viewerUID string) bool {
+	if viewerUID == "" {
+		return false
+	}
+	now := i.now()
+	counted := false
+	err := i.repo.WithTx(ctx, func(repo repository.Repository) error {
+		var err error
+		visit := models.BrowseHistory{VisitorID: viewerUID, IdeaID: idea.IdeaUID, ViewedAt: now}
+		counted, err = repo.UpsertBrowseHistory(ctx, visit, now.Add(-ViewWindow))
+		if err != nil || !counted || viewerUID == idea.Author {
+			counted = false
+			return err
+		}
+		return repo.IncrementViewCount(ctx, idea.IdeaUID)
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to record view: "+err.Error())
+		return false
+	}
+	return counted
+}
+
+// withIdeaVisits fills LastViewedAt and UnreadComments of the ideas the
+// viewer has visited with one query
+func (i *Ideas) withIdeaVisits(ctx context.Context, ideas []models.Idea, viewerUID string) error {
+	if viewerUID == "" || len(ideas) == 0 {
+		return nil
+	}
+	uids := make([]string, 0, len(ideas))
+	for _, idea := range ideas {
+		uids = append(uids, idea.IdeaUID)
+	}
+	visits, err := i.repo.SelectIdeaVisits(ctx, viewerUID, uids)
+	if err != nil {
+		return err
+	}
+	byIdea := make(map[string]models.IdeaVisit, len(visits))
+	for _, v := range visits {
+		byIdea[v.IdeaUID] = v
+	}
+	for k := range ideas {
+		if v, ok := byIdea[ideas[k].IdeaUID]; ok {
+			ideas[k].LastViewedAt = &v.ViewedAt
+			ideas[k].UnreadComments = v.UnreadComments
+		}
+	}
+	return nil
+}
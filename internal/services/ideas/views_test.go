@@ -0,0 +1,98 @@
+package ideas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestViews(t *testing.T) {
+	ctx := context.Background()
+	i, repo := setupIdeas(t)
+	now := time.Now()
+	i.now = func() time.Time { return now }
+	author := repotest.User(t, repo, "author@example.com")
+	reader := repotest.User(t, repo, "reader@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "viewed")
+	other := repotest.Idea(t, repo, author.UID, "other")
+
+	page, err := i.GetIdeaByUID(ctx, idea.IdeaUID, author.UID)
+	require.NoError(t, err)
+	assert.Zero(t, page.Idea.ViewCount, "the author's views aren't counted")
+
+	page, err = i.GetIdeaByUID(ctx, idea.IdeaUID, reader.UID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Idea.ViewCount)
+	assert.Nil(t, page.Idea.LastViewedAt, "the first visit")
+
+	now = now.Add(time.Minute)
+	page, err = i.GetIdeaByUID(ctx, idea.IdeaUID, reader.UID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Idea.ViewCount, "counted once a day")
+	require.NotNil(t, page.Idea.LastViewedAt)
+	assert.Equal(t, now.Add(-time.Minute), *page.Idea.LastViewedAt)
+
+	now = now.Add(ViewWindow)
+	page, err = i.GetIdeaByUID(ctx, idea.IdeaUID, reader.UID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Idea.ViewCount)
+
+	now = now.Add(time.Minute)
+	_, err = i.GetIdeaByUID(ctx, other.IdeaUID, reader.UID)
+	require.NoError(t, err)
+	viewed, err := i.GetViewedIdeas(ctx, reader.UID, 10)
+	require.NoError(t, err)
+	require.Len(t, viewed, 2)
+	assert.Equal(t, "other", viewed[0].Name, "last visited first")
+	assert.Equal(t, "viewed", viewed[1].Name)
+	assert.Equal(t, 2, viewed[1].ViewCount)
+	viewed, err = i.GetViewedIdeas(ctx, author.UID, 10)
+	require.NoError(t, err)
+	assert.Len(t, viewed, 1)
+	nobody := repotest.User(t, repo, "nobody@example.com")
+	viewed, err = i.GetViewedIdeas(ctx, nobody.UID, 10)
+	require.NoError(t, err)
+	assert.NotNil(t, viewed)
+	assert.Empty(t, viewed)
+}
+
+func TestUnreadComments(t *testing.T) {
+	ctx := context.Background()
+	i, repo := setupIdeas(t)
+	author := repotest.User(t, repo, "author@example.com")
+	reader := repotest.User(t, repo, "reader@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "discussed")
+	visited := time.Now().Add(-time.Minute)
+	i.now = func() time.Time { return visited }
+
+	_, err := i.GetIdeaByUID(ctx, idea.IdeaUID, reader.UID)
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", author.UID, "first")
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", author.UID, "second")
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", reader.UID, "the reader's own")
+	require.NoError(t, err)
+
+	ideas, err := i.GetAllIdeas(ctx, reader.UID)
+	require.NoError(t, err)
+	require.Len(t, ideas, 1)
+	assert.Equal(t, 2, ideas[0].UnreadComments)
+	ideas, err = i.GetAllIdeas(ctx, author.UID)
+	require.NoError(t, err)
+	assert.Zero(t, ideas[0].UnreadComments, "never visited")
+	assert.Nil(t, ideas[0].LastViewedAt)
+
+	// the page shows what was unread, the visit marks it read
+	i.now = time.Now
+	page, err := i.GetIdeaByUID(ctx, idea.IdeaUID, reader.UID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, page.Idea.UnreadComments)
+	ideas, err = i.GetAllIdeas(ctx, reader.UID)
+	require.NoError(t, err)
+	assert.Zero(t, ideas[0].UnreadComments)
+}
@@ -1,32 +1,121 @@
 package interfaces
 
 import (
+	"context"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/services/realtime"
+	"io"
 	"mime/multipart"
+	"net/http"
 )
 
 type IdeaService interface {
 	GetIdeaCategories() []models.IdeaCategory
 	GetIdeaStatuses() []models.IdeaStatus
-	GetAllIdeas() ([]models.Idea, error)
-	GetIdeaByUID(uid string) (models.IdeaComment, error)
-	GetAuthorIdeas(uid string, limit int) ([]models.Idea, error)
-	InsertIdea(name string, text string, author string, status int, category int) (models.Idea, error)
-	InsertComment(ideaUID, authorUID, commentText string) (models.Comment, error)
-	InsertReply(commentUID, authorID, replyText string) (models.Reply, error)
-	IncrementDislikes(ideaUID string) error
-	IncrementLikes(ideaUID string) error
-	CheckVote(ideaUID string, userUID string) (bool, error)
+	GetAllIdeas(ctx context.Context, viewerUID string) ([]models.Idea, error)
+	GetRankedIdeas(ctx context.Context, sort models.IdeaSort, viewerUID string) ([]models.Idea, error)
+	GetViewedIdeas(ctx context.Context, viewerUID string, limit int) ([]models.Idea, error)
+	GetIdeaByUID(ctx context.Context, uid, viewerUID string) (models.IdeaComment, error)
+	GetAuthorIdeas(ctx context.Context, uid string, limit int) ([]models.Idea, error)
+	SearchIdeas(ctx context.Context, query string, limit int, viewerUID string) ([]models.Idea, error)
+	InsertIdea(ctx context.Context, name string, text string, author string, status int, category int) (models.Idea, error)
+	InsertCampaignIdea(ctx context.Context, campaignID int64, name, text, author string, status, category int) (models.Idea, error)
+	GetIdeaComments(ctx context.Context, ideaUID, viewerUID, cursor string, limit int) (models.CommentPage, error)
+	GetCommentChildren(ctx context.Context, uid, viewerUID, cursor string, limit int) (models.CommentPage, error)
+	InsertComment(ctx context.Context, ideaUID, parentUID, authorUID, commentText string) (models.Comment, error)
+	InsertReply(ctx context.Context, commentUID, authorID, replyText string) (models.Comment, error)
+	EditComment(ctx context.Context, uid, editorUID, text string) (models.Comment, error)
+	DeleteComment(ctx context.Context, uid, userUID string) error
+	GetCommentRevisions(ctx context.Context, uid string) ([]models.Revision, error)
+	Vote(ctx context.Context, ideaUID, userUID string, vote models.VoteType) (bool, error)
+	GetReactionSet() []string
+	React(ctx context.Context, target models.TargetType, targetUID, userUID, emoji string) ([]models.ReactionCount, error)
+	Unreact(ctx context.Context, target models.TargetType, targetUID, userUID, emoji string) ([]models.ReactionCount, error)
+	ChangeIdeaStatus(ctx context.Context, ideaUID, actorUID string, statusID int) (models.Idea, error)
 }
 
 type AuthService interface {
-	Register(u models.User) error
-	Login(email string, password string) (string, string, error)
+	Register(ctx context.Context, u models.User) error
+	Login(ctx context.Context, email string, password string) (string, string, error)
 	GetJWT() string
+	IssueStreamTicket(ctx context.Context, userUID string) (models.StreamTicket, error)
+	RedeemStreamTicket(ctx context.Context, ticket string) (string, error)
 }
 
 type UserService interface {
-	GetUserByUID(uid string) (models.User, error)
-	UploadPFP(file multipart.File, header *multipart.FileHeader, UID string) (string, error)
-	GetPositions() ([]models.UserPosition, error)
+	GetUserByUID(ctx context.Context, uid string) (models.User, error)
+	UploadPFP(ctx context.Context, file multipart.File, header *multipart.FileHeader, UID string) (string, error)
+	GetPositions(ctx context.Context) ([]models.UserPosition, error)
+}
+
+type NotificationService interface {
+	GetNotifications(ctx context.Context, userUID string, unreadOnly bool, cursor string, limit int) (models.NotificationPage, error)
+	MarkRead(ctx context.Context, userUID string, id int64) error
+	MarkAllRead(ctx context.Context, userUID string) error
+	GetPreferences(ctx context.Context, userUID string) ([]models.NotificationPreference, error)
+	SetPreferences(ctx context.Context, userUID string, preferences []models.NotificationPreference) ([]models.NotificationPreference, error)
+}
+
+type EmailService interface {
+	GetSettings(ctx context.Context, userUID string) (models.EmailSettings, error)
+	SetSettings(ctx context.Context, settings models.EmailSettings) (models.EmailSettings, error)
+}
+
+type WebhookService interface {
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	CreateWebhook(ctx context.Context, actorUID string, req models.WebhookRequest) (models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id int64, req models.WebhookRequest) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	TestWebhook(ctx context.Context, id int64) (models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookID int64, state models.DeliveryState, limit int) ([]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id int64) (models.WebhookDelivery, error)
+}
+
+type CampaignService interface {
+	GetCampaigns(ctx context.Context) ([]models.Campaign, error)
+	GetCampaign(ctx context.Context, id int64) (models.CampaignPage, error)
+	GetLeaderboard(ctx context.Context, id int64, limit int) ([]models.LeaderboardEntry, error)
+	CreateCampaign(ctx context.Context, actorUID string, req models.CampaignRequest) (models.Campaign, error)
+	UpdateCampaign(ctx context.Context, id int64, req models.CampaignRequest) (models.Campaign, error)
+	DeleteCampaign(ctx context.Context, id int64) error
+}
+
+type JobService interface {
+	GetJobs(ctx context.Context) ([]models.Job, error)
+	GetRuns(ctx context.Context, name string, limit int) ([]models.JobRun, error)
+	Trigger(ctx context.Context, name, actorUID string) (models.JobRun, error)
+}
+
+type AnalyticsService interface {
+	GetSubmissions(ctx context.Context, r models.AnalyticsRange, group models.AnalyticsGroup) (models.SubmissionReport, error)
+	GetDecisions(ctx context.Context, r models.AnalyticsRange) (models.DecisionReport, error)
+	GetContributors(ctx context.Context, r models.AnalyticsRange, limit int) (models.ContributorReport, error)
+	GetEngagement(ctx context.Context, r models.AnalyticsRange) (models.EngagementReport, error)
+}
+
+type ExportService interface {
+	ExportIdeas(ctx context.Context, filter models.IdeaFilter, format models.ExportFormat, w io.Writer) error
+	ExportDiscussions(ctx context.Context, filter models.IdeaFilter, format models.ExportFormat, w io.Writer) error
+}
+
+type ReportService interface {
+	IdeaReport(ctx context.Context, uid string) ([]byte, error)
+}
+
+type OnboardingService interface {
+	Import(ctx context.Context, r io.Reader, commit bool) (models.ImportReport, error)
+	Invite(ctx context.Context, uid string) error
+	AcceptInvitation(ctx context.Context, token string, password string) error
+}
+
+type BotService interface {
+	CreateLinkCode(ctx context.Context, userUID string) (models.BotLinkCode, error)
+	GetAccounts(ctx context.Context, userUID string) ([]models.BotAccount, error)
+	Unlink(ctx context.Context, userUID, platform, externalID string) error
+	Webhook(ctx context.Context, platform string, r *http.Request) error
+}
+
+type EventHub interface {
+	Subscribe(topics ...string) *realtime.Subscription
 }
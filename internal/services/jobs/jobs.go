@@ -0,0 +1,322 @@
+// Package jobs runs background work on cron schedules inside the server
+// process. Every replica runs the scheduler, a run takes a lock named after
+// the job first and the database records each scheduled moment once, so a
+// job runs on one replica at a time and a moment isn't run twice. Every run
+// is kept in the job history, admins can also start a job by hand.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/cron"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+const (
+	// RunTimeout bounds a single run
+	RunTimeout = 10 * time.Minute
+	// RunRetention - how long finished runs stay in the history
+	RunRetention = 30 * 24 * time.Hour
+	// DefaultRunPage - runs listed when no limit is given
+	DefaultRunPage = 20
+)
+
+// Func does the work of a job, ctx is done when the run times out or the
+// server stops
+type Func func(ctx context.Context) error
+
+// Count adapts work reporting how many items it handled, the number is
+// logged by the work itself
+func Count(fn func(ctx context.Context) (int, error)) Func {
+	return func(ctx context.Context) error {
+		_, err := fn(ctx)
+		return err
+	}
+}
+
+// Locker lets one process at a time hold a named lock, across replicas
+// when they share it
+type Locker interface {
+	// TryLock takes the lock unless it is held, ok is false then. The lock
+	// is released with unlock.
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	fn       Func
+}
+
+type Scheduler struct {
+	log    slog.Logger
+	repo   repository.Repository
+	locker Locker
+	node   string
+	now    func() time.Time
+	jobs   map[string]*job
+	// order - names in the order jobs were added
+	order []string
+	// running tracks runs started by Trigger
+	running sync.WaitGroup
+}
+
+// New returns a scheduler taking locks from locker, a LocalLocker when the
+// server runs alone
+func New(log slog.Logger, repo repository.Repository, locker Locker) *Scheduler {
+	node, err := os.Hostname()
+	if err != nil {
+		node = "unknown"
+	}
+	return &Scheduler{
+		log:    log,
+		repo:   repo,
+		locker: locker,
+		node:   node,
+		now:    time.Now,
+		jobs:   make(map[string]*job),
+	}
+}
+
+// Add registers a job running fn on the cron expression spec (see package
+// cron), jobs are added before Run
+func (s *Scheduler) Add(name, spec string, fn Func) error {
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s is already added", name)
+	}
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+	s.jobs[name] = &job{name: name, spec: spec, schedule: schedule, fn: fn}
+	s.order = append(s.order, name)
+	return nil
+}
+
+// Run runs every job on its schedule until ctx is done. A run taking longer
+// than the interval skips the moments it overlaps.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, name := range s.order {
+		j := s.jobs[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	from := s.now()
+	for {
+		slot := j.schedule.Next(from)
+		if slot.IsZero() {
+			s.log.WarnContext(ctx, "job "+j.name+" never runs", slog.String("schedule", j.spec))
+			return
+		}
+		timer := time.NewTimer(slot.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.runScheduled(ctx, j, slot)
+
+		// the wall clock may lag behind the timer
+		from = s.now()
+		if from.Before(slot) {
+			from = slot
+		}
+	}
+}
+
+// runScheduled runs the job for the moment slot unless another replica
+// runs it or already has
+func (s *Scheduler) runScheduled(ctx context.Context, j *job, slot time.Time) {
+	log := s.log.With(slog.String("op", "JobsRunScheduled"), slog.String("job", j.name))
+
+	unlock, ok, err := s.locker.TryLock(ctx, j.name)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to take job lock: "+err.Error())
+		return
+	}
+	if !ok {
+		log.DebugContext(ctx, "job is running elsewhere, skipped")
+		return
+	}
+	defer unlock()
+
+	run, err := s.repo.InsertJobRun(ctx, models.JobRun{
+		Job:         j.name,
+		Source:      models.JobScheduled,
+		ScheduledAt: &slot,
+		Node:        s.node,
+		Status:      models.JobRunning,
+		StartedAt:   s.now(),
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		log.DebugContext(ctx, "job has run elsewhere, skipped")
+		return
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to record job run: "+err.Error())
+		return
+	}
+	s.execute(ctx, j, run)
+}
+
+// Trigger starts the job right away on behalf of the admin and returns the
+// run, the job runs in the background
+func (s *Scheduler) Trigger(ctx context.Context, name, actorUID string) (models.JobRun, error) {
+	op := "JobsTrigger"
+	log := s.log.With(slog.String("op", op), slog.String("job", name), slog.String("actorUID", actorUID))
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return models.JobRun{}, apperr.NotFound("job not found")
+	}
+	unlock, ok, err := s.locker.TryLock(ctx, name)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to take job lock: "+err.Error())
+		return models.JobRun{}, err
+	}
+	if !ok {
+		return models.JobRun{}, apperr.Conflict("job is already running")
+	}
+
+	run, err := s.repo.InsertJobRun(ctx, models.JobRun{
+		Job:         name,
+		Source:      models.JobManual,
+		TriggeredBy: &actorUID,
+		Node:        s.node,
+		Status:      models.JobRunning,
+		StartedAt:   s.now(),
+	})
+	if err != nil {
+		unlock()
+		log.ErrorContext(ctx, "failed to record job run: "+err.Error())
+		return models.JobRun{}, err
+	}
+	log.InfoContext(ctx, "job triggered", slog.Int64("runID", run.ID))
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer unlock()
+		s.execute(context.WithoutCancel(ctx), j, run)
+	}()
+	return run, nil
+}
+
+// execute runs the job and records the outcome, a panic fails the run
+func (s *Scheduler) execute(ctx context.Context, j *job, run models.JobRun) {
+	log := s.log.With(slog.String("job", j.name), slog.Int64("runID", run.ID))
+
+	err := func() (err error) {
+		ctx, cancel := context.WithTimeout(ctx, RunTimeout)
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return j.fn(ctx)
+	}()
+
+	finished := s.now()
+	status, runErr := models.JobSucceeded, ""
+	if err != nil {
+		status, runErr = models.JobFailed, err.Error()
+		log.ErrorContext(ctx, "job failed: "+runErr)
+	} else {
+		log.InfoContext(ctx, "job finished", slog.Duration("took", finished.Sub(run.StartedAt)))
+	}
+	// the outcome is recorded even when the server is stopping
+	if err := s.repo.FinishJobRun(context.WithoutCancel(ctx), run.ID, status, runErr, finished); err != nil {
+		log.ErrorContext(ctx, "failed to record job outcome: "+err.Error())
+	}
+}
+
+// GetJobs returns the registered jobs with their last runs
+func (s *Scheduler) GetJobs(ctx context.Context) ([]models.Job, error) {
+	now := s.now()
+	jobs := make([]models.Job, 0, len(s.order))
+	for _, name := range s.order {
+		j := s.jobs[name]
+		runs, err := s.repo.SelectJobRuns(ctx, name, 1)
+		if err != nil {
+			s.log.ErrorContext(ctx, "failed to fetch job runs: "+err.Error(), slog.String("op", "JobsGetJobs"))
+			return nil, err
+		}
+		job := models.Job{Name: name, Schedule: j.spec, NextRunAt: j.schedule.Next(now)}
+		if len(runs) > 0 {
+			job.LastRun = &runs[0]
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// GetRuns returns up to limit latest runs of the job
+func (s *Scheduler) GetRuns(ctx context.Context, name string, limit int) ([]models.JobRun, error) {
+	if _, ok := s.jobs[name]; !ok {
+		return nil, apperr.NotFound("job not found")
+	}
+	runs, err := s.repo.SelectJobRuns(ctx, name, limit)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to fetch job runs: "+err.Error(), slog.String("op", "JobsGetRuns"))
+		return nil, err
+	}
+	if runs == nil {
+		runs = []models.JobRun{}
+	}
+	return runs, nil
+}
+
+// PurgeRuns deletes runs finished more than RunRetention ago and returns
+// how many were deleted
+func (s *Scheduler) PurgeRuns(ctx context.Context) (int, error) {
+	n, err := s.repo.DeleteJobRuns(ctx, s.now().Add(-RunRetention))
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to purge job runs: "+err.Error(), slog.String("op", "JobsPurgeRuns"))
+		return 0, err
+	}
+	return n, nil
+}
+
+// LocalLocker - locks of a single process, for a server running without
+// replicas or a database with no locks of its own
+type LocalLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{held: make(map[string]bool)}
+}
+
+func (l *LocalLocker) TryLock(_ context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true, nil
+}
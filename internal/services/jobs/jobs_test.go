@@ -0,0 +1,184 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+func setup(repo *memory.Repository) *Scheduler {
+	s := New(*slog.Default(), repo, NewLocalLocker())
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestAdd(t *testing.T) {
+	s := setup(memory.New())
+	noop := func(context.Context) error { return nil }
+
+	require.NoError(t, s.Add("noop", "*/5 * * * *", noop))
+	assert.Error(t, s.Add("noop", "@hourly", noop))
+	assert.Error(t, s.Add("broken", "* * *", noop))
+}
+
+func TestRunScheduled(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	var calls atomic.Int32
+	count := func(context.Context) error { calls.Add(1); return nil }
+
+	// replicas with locks of their own still run a moment once
+	first, second := setup(repo), setup(repo)
+	require.NoError(t, first.Add("count", "@hourly", count))
+	require.NoError(t, second.Add("count", "@hourly", count))
+	slot := now.Truncate(time.Hour)
+	first.runScheduled(ctx, first.jobs["count"], slot)
+	second.runScheduled(ctx, second.jobs["count"], slot)
+	assert.EqualValues(t, 1, calls.Load())
+
+	runs, err := repo.SelectJobRuns(ctx, "count", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.JobScheduled, runs[0].Source)
+	assert.Equal(t, models.JobSucceeded, runs[0].Status)
+	assert.True(t, slot.Equal(*runs[0].ScheduledAt))
+	require.NotNil(t, runs[0].FinishedAt)
+
+	// the next moment runs again
+	second.runScheduled(ctx, second.jobs["count"], slot.Add(time.Hour))
+	assert.EqualValues(t, 2, calls.Load())
+
+	// a replica holding the lock keeps the others off
+	unlock, ok, err := first.locker.TryLock(ctx, "count")
+	require.NoError(t, err)
+	require.True(t, ok)
+	first.runScheduled(ctx, first.jobs["count"], slot.Add(2*time.Hour))
+	unlock()
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestRunFailed(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	s := setup(repo)
+	require.NoError(t, s.Add("fail", "@daily", func(context.Context) error { return errors.New("smtp is down") }))
+	require.NoError(t, s.Add("panic", "@daily", func(context.Context) error { panic("nil map") }))
+
+	s.runScheduled(ctx, s.jobs["fail"], now)
+	s.runScheduled(ctx, s.jobs["panic"], now)
+
+	runs, err := repo.SelectJobRuns(ctx, "fail", 1)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.JobFailed, runs[0].Status)
+	assert.Equal(t, "smtp is down", runs[0].Error)
+
+	runs, err = repo.SelectJobRuns(ctx, "panic", 1)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.JobFailed, runs[0].Status)
+	assert.Equal(t, "panic: nil map", runs[0].Error)
+}
+
+func TestTrigger(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	admin := repotest.User(t, repo, "admin@example.com")
+	s := setup(repo)
+	release := make(chan struct{})
+	require.NoError(t, s.Add("slow", "@daily", func(context.Context) error { <-release; return nil }))
+
+	_, err := s.Trigger(ctx, "missing", admin.UID)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+
+	run, err := s.Trigger(ctx, "slow", admin.UID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobManual, run.Source)
+	assert.Equal(t, models.JobRunning, run.Status)
+	assert.Equal(t, admin.UID, *run.TriggeredBy)
+
+	// the job is running: neither a second trigger nor the schedule start it
+	_, err = s.Trigger(ctx, "slow", admin.UID)
+	assert.ErrorIs(t, err, apperr.ErrConflict)
+	s.runScheduled(ctx, s.jobs["slow"], now)
+
+	close(release)
+	s.running.Wait()
+	runs, err := s.GetRuns(ctx, "slow", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, run.ID, runs[0].ID)
+	assert.Equal(t, models.JobSucceeded, runs[0].Status)
+
+	_, err = s.GetRuns(ctx, "missing", 10)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestGetJobs(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	s := setup(repo)
+	noop := func(context.Context) error { return nil }
+	require.NoError(t, s.Add("hourly", "0 * * * *", noop))
+	require.NoError(t, s.Add("nightly", "30 3 * * *", noop))
+	s.runScheduled(ctx, s.jobs["hourly"], now)
+
+	jobs, err := s.GetJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "hourly", jobs[0].Name)
+	assert.Equal(t, now.Add(time.Hour), jobs[0].NextRunAt)
+	require.NotNil(t, jobs[0].LastRun)
+	assert.Equal(t, models.JobSucceeded, jobs[0].LastRun.Status)
+	assert.Equal(t, "nightly", jobs[1].Name)
+	assert.Equal(t, "30 3 * * *", jobs[1].Schedule)
+	assert.Equal(t, time.Date(2026, 7, 2, 3, 30, 0, 0, time.UTC), jobs[1].NextRunAt)
+	assert.Nil(t, jobs[1].LastRun)
+}
+
+func TestPurgeRuns(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	s := setup(repo)
+	require.NoError(t, s.Add("noop", "@daily", func(context.Context) error { return nil }))
+	s.runScheduled(ctx, s.jobs["noop"], now)
+
+	n, err := s.PurgeRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	s.now = func() time.Time { return now.Add(RunRetention + time.Hour) }
+	n, err = s.PurgeRuns(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestRun(t *testing.T) {
+	repo := memory.New()
+	s := New(*slog.Default(), repo, NewLocalLocker())
+	var calls atomic.Int32
+	require.NoError(t, s.Add("tick", "@every 1s", func(context.Context) error { calls.Add(1); return nil }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() > 0 }, 3*time.Second, 50*time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,313 @@
+// Package notifications keeps the in-app notification center: it turns events
+// of the ideas service into notifications for the users concerned and serves
+// them back with unread counters and per-type preferences.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+// DefaultPage - notifications per page when no limit is given
+const DefaultPage = 20
+
+type Notifications struct {
+	log       slog.Logger
+	repo      repository.Repository
+	publisher Publisher
+	forwarder Forwarder
+}
+
+// Publisher pushes new notifications to connected clients
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+func New(log slog.Logger, repo repository.Repository) *Notifications {
+	return &Notifications{
+		log:       log,
+		repo:      repo,
+		publisher: nopPublisher{},
+		forwarder: nopForwarder{},
+	}
+}
+
+// SetPublisher replaces the publisher, by default notifications are only
+// stored
+func (n *Notifications) SetPublisher(p Publisher) {
+	n.publisher = p
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, models.Event) error { return nil }
+
+// Forwarder passes notifications on to another channel, email or a
+// messenger, deciding itself which ones deserve it. It gets the ones the
+// user has switched off in the app too, unsaved and with no ID: email has
+// settings of its own, a channel following the app checks Enabled.
+type Forwarder interface {
+	Notified(ctx context.Context, notification models.Notification) error
+}
+
+// SetForwarder replaces the forwarder, by default notifications aren't sent
+// anywhere
+func (n *Notifications) SetForwarder(f Forwarder) {
+	n.forwarder = f
+}
+
+type nopForwarder struct{}
+
+func (nopForwarder) Notified(context.Context, models.Notification) error { return nil }
+
+// Forwarders hands every notification to each forwarder in turn, one failing
+// doesn't keep it from the rest
+type Forwarders []Forwarder
+
+func (fs Forwarders) Notified(ctx context.Context, notification models.Notification) error {
+	var errs []error
+	for _, f := range fs {
+		errs = append(errs, f.Notified(ctx, notification))
+	}
+	return errors.Join(errs...)
+}
+
+// Mentioned notifies the mentioned user
+func (n *Notifications) Mentioned(ctx context.Context, mention models.Mention) error {
+	notification := models.Notification{
+		UserUID:  mention.UserUID,
+		Type:     models.NotificationMention,
+		ActorUID: mention.AuthorUID,
+		IdeaUID:  mention.IdeaUID,
+	}
+	if mention.TargetType == models.TargetComment {
+		notification.CommentUID = &mention.TargetUID
+	}
+	return n.notify(ctx, notification)
+}
+
+// Commented notifies the author of the parent comment about a reply and the
+// author of the idea about any comment, a user gets at most one of the two
+func (n *Notifications) Commented(ctx context.Context, comment models.Comment) error {
+	base := models.Notification{
+		ActorUID:   comment.AuthorID,
+		IdeaUID:    comment.IdeaUID,
+		CommentUID: &comment.CommentUID,
+	}
+
+	replied := ""
+	if comment.ParentUID != nil {
+		parent, err := n.repo.SelectCommentByUID(ctx, *comment.ParentUID)
+		if err != nil {
+			return err
+		}
+		replied = parent.AuthorID
+		reply := base
+		reply.UserUID, reply.Type = parent.AuthorID, models.NotificationReply
+		if err := n.notify(ctx, reply); err != nil {
+			return err
+		}
+	}
+
+	idea, err := n.repo.SelectIdeaByUID(ctx, comment.IdeaUID)
+	if err != nil {
+		return err
+	}
+	if idea.Author == replied {
+		return nil
+	}
+	commented := base
+	commented.UserUID, commented.Type = idea.Author, models.NotificationComment
+	return n.notify(ctx, commented)
+}
+
+// Voted notifies the author of the idea
+func (n *Notifications) Voted(ctx context.Context, ideaUID, voterUID string, vote models.VoteType) error {
+	idea, err := n.repo.SelectIdeaByUID(ctx, ideaUID)
+	if err != nil {
+		return err
+	}
+	return n.notify(ctx, models.Notification{
+		UserUID:  idea.Author,
+		Type:     models.NotificationVote,
+		ActorUID: voterUID,
+		IdeaUID:  ideaUID,
+		Detail:   string(vote),
+	})
+}
+
+// StatusChanged notifies the author of the idea, Detail is the new status id
+func (n *Notifications) StatusChanged(ctx context.Context, idea models.Idea, actorUID string) error {
+	return n.notify(ctx, models.Notification{
+		UserUID:  idea.Author,
+		Type:     models.NotificationStatus,
+		ActorUID: actorUID,
+		IdeaUID:  idea.IdeaUID,
+		Detail:   strconv.Itoa(idea.StatusID),
+	})
+}
+
+// notify saves the notification unless the user caused the event or has
+// switched its type off and pushes it to the user's clients, then hands it
+// to the forwarder whatever the preferences. Failures of both are only
+// logged: the notification is in the list anyway.
+func (n *Notifications) notify(ctx context.Context, notification models.Notification) error {
+	if notification.UserUID == "" || notification.UserUID == notification.ActorUID {
+		return nil
+	}
+	enabled, err := Enabled(ctx, n.repo, notification.UserUID, notification.Type)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		notification, err = n.repo.InsertNotification(ctx, notification)
+		if err != nil {
+			return err
+		}
+		n.publish(ctx, notification)
+	}
+	if err := n.forwarder.Notified(ctx, notification); err != nil {
+		n.log.ErrorContext(ctx, "failed to forward notification: "+err.Error())
+	}
+	return nil
+}
+
+// publish pushes a saved notification with the unread counter
+func (n *Notifications) publish(ctx context.Context, notification models.Notification) {
+	unread, err := n.repo.CountUnreadNotifications(ctx, notification.UserUID)
+	if err == nil {
+		err = n.publisher.Publish(ctx, models.Event{
+			Type:   models.EventNotification,
+			Topics: []string{models.UserTopic(notification.UserUID)},
+			Data:   models.NotificationEvent{Notification: notification, Unread: unread},
+		})
+	}
+	if err != nil {
+		n.log.ErrorContext(ctx, "failed to publish notification: "+err.Error())
+	}
+}
+
+// Enabled reports whether the user gets notifications of the type in the
+// app, types are on until switched off
+func Enabled(ctx context.Context, repo repository.Repository, userUID string, typ models.NotificationType) (bool, error) {
+	preferences, err := repo.SelectNotificationPreferences(ctx, userUID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range preferences {
+		if p.Type == typ {
+			return p.Enabled, nil
+		}
+	}
+	return true, nil
+}
+
+// GetNotifications returns a page of the user's notifications, newest first.
+// cursor is NextCursor of the previous page, empty for the first one.
+func (n *Notifications) GetNotifications(ctx context.Context, userUID string, unreadOnly bool, cursor string, limit int) (models.NotificationPage, error) {
+	op := "NotificationsGet"
+	log := n.log.With(slog.String("op", op), slog.String("userUID", userUID))
+	log.DebugContext(ctx, "fetching notifications")
+
+	var beforeID int64
+	if cursor != "" {
+		var err error
+		beforeID, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return models.NotificationPage{}, apperr.Validation("invalid cursor")
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultPage
+	}
+
+	notifications, err := n.repo.SelectNotifications(ctx, userUID, unreadOnly, beforeID, limit+1)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch notifications"+err.Error())
+		return models.NotificationPage{}, err
+	}
+	unread, err := n.repo.CountUnreadNotifications(ctx, userUID)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to count unread notifications"+err.Error())
+		return models.NotificationPage{}, err
+	}
+
+	page := models.NotificationPage{Notifications: notifications, Unread: unread}
+	if len(notifications) > limit {
+		page.Notifications = notifications[:limit]
+		page.NextCursor = strconv.FormatInt(notifications[limit-1].ID, 10)
+	}
+	if page.Notifications == nil {
+		page.Notifications = []models.Notification{}
+	}
+	return page, nil
+}
+
+// MarkRead marks one of the user's notifications as read
+func (n *Notifications) MarkRead(ctx context.Context, userUID string, id int64) error {
+	err := n.repo.MarkNotificationRead(ctx, userUID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("notification not found")
+	}
+	return err
+}
+
+// MarkAllRead marks every notification of the user as read
+func (n *Notifications) MarkAllRead(ctx context.Context, userUID string) error {
+	return n.repo.MarkAllNotificationsRead(ctx, userUID)
+}
+
+// GetPreferences returns a preference for every notification type
+func (n *Notifications) GetPreferences(ctx context.Context, userUID string) ([]models.NotificationPreference, error) {
+	stored, err := n.repo.SelectNotificationPreferences(ctx, userUID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(models.NotificationTypes))
+	for _, typ := range models.NotificationTypes {
+		p := models.NotificationPreference{UserUID: userUID, Type: typ, Enabled: true}
+		if k := slices.IndexFunc(stored, func(s models.NotificationPreference) bool { return s.Type == typ }); k >= 0 {
+			p.Enabled = stored[k].Enabled
+		}
+		preferences = append(preferences, p)
+	}
+	return preferences, nil
+}
+
+// SetPreferences switches the given types on or off, other types keep their
+// setting. Returns every preference after the change.
+func (n *Notifications) SetPreferences(ctx context.Context, userUID string, preferences []models.NotificationPreference) ([]models.NotificationPreference, error) {
+	op := "NotificationsSetPreferences"
+	log := n.log.With(slog.String("op", op), slog.String("userUID", userUID))
+
+	for _, p := range preferences {
+		if !slices.Contains(models.NotificationTypes, p.Type) {
+			return nil, apperr.Validation("unknown notification type " + string(p.Type))
+		}
+	}
+
+	err := n.repo.WithTx(ctx, func(repo repository.Repository) error {
+		for _, p := range preferences {
+			p.UserUID = userUID
+			if err := repo.UpsertNotificationPreference(ctx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to save preferences"+err.Error())
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "successfully saved preferences")
+	return n.GetPreferences(ctx, userUID)
+}
@@ -0,0 +1,255 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setup wires the notification center into an ideas service, like main does
+func setup(t *testing.T) (*Notifications, *ideas.Ideas, *memory.Repository) {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	n := New(*slog.Default(), repo)
+	i, err := ideas.New(context.Background(), *slog.Default(), repo)
+	require.NoError(t, err)
+	i.SetNotifier(n)
+	return n, i, repo
+}
+
+func types(page models.NotificationPage) []models.NotificationType {
+	var res []models.NotificationType
+	for _, n := range page.Notifications {
+		res = append(res, n.Type)
+	}
+	return res
+}
+
+func TestCommentsAndReplies(t *testing.T) {
+	ctx := context.Background()
+	n, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	carol := repotest.User(t, repo, "carol@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+
+	comment, err := i.InsertComment(ctx, idea.IdeaUID, "", bob.UID, "comment")
+	require.NoError(t, err)
+	_, err = i.InsertReply(ctx, comment.CommentUID, carol.UID, "reply to bob")
+	require.NoError(t, err)
+	own, err := i.InsertComment(ctx, idea.IdeaUID, "", author.UID, "own comment")
+	require.NoError(t, err)
+	_, err = i.InsertReply(ctx, own.CommentUID, bob.UID, "reply to the author")
+	require.NoError(t, err)
+
+	page, err := n.GetNotifications(ctx, author.UID, false, "", 10)
+	require.NoError(t, err)
+	// the reply to the author's own comment is a reply, not a comment too
+	assert.Equal(t, []models.NotificationType{
+		models.NotificationReply, models.NotificationComment, models.NotificationComment,
+	}, types(page))
+	assert.Equal(t, 3, page.Unread)
+	assert.Equal(t, bob.UID, page.Notifications[0].ActorUID)
+	assert.Equal(t, idea.Name, page.Notifications[0].IdeaName)
+	require.NotNil(t, page.Notifications[0].Actor)
+	assert.Equal(t, bob.Name, page.Notifications[0].Actor.Name)
+
+	page, err = n.GetNotifications(ctx, bob.UID, false, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, []models.NotificationType{models.NotificationReply}, types(page))
+	assert.Equal(t, carol.UID, page.Notifications[0].ActorUID)
+
+	page, err = n.GetNotifications(ctx, carol.UID, false, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, page.Notifications)
+	assert.NotNil(t, page.Notifications)
+}
+
+func TestVotesStatusesMentions(t *testing.T) {
+	ctx := context.Background()
+	n, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	voter := repotest.User(t, repo, "voter@example.com")
+	mod := models.User{UID: "8f0f5b8e-2b1a-4f0e-9c39-5d2f3f6b7a10", Name: "Mod", Surname: "Erator",
+		PositionID: 1, Email: "mod@example.com", Password: "hash", IsAdmin: true}
+	require.NoError(t, repo.InsertUser(ctx, mod))
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+
+	_, err := i.Vote(ctx, idea.IdeaUID, voter.UID, models.VoteDislike)
+	require.NoError(t, err)
+	_, err = i.Vote(ctx, idea.IdeaUID, author.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = i.ChangeIdeaStatus(ctx, idea.IdeaUID, mod.UID, 2)
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", mod.UID, "@"+voter.UID+" what do you think?")
+	require.NoError(t, err)
+
+	page, err := n.GetNotifications(ctx, author.UID, false, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, []models.NotificationType{
+		models.NotificationComment, models.NotificationStatus, models.NotificationVote,
+	}, types(page), "voting for your own idea notifies no one")
+	assert.Equal(t, "2", page.Notifications[1].Detail)
+	assert.Equal(t, string(models.VoteDislike), page.Notifications[2].Detail)
+
+	page, err = n.GetNotifications(ctx, voter.UID, false, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, []models.NotificationType{models.NotificationMention}, types(page))
+	assert.NotNil(t, page.Notifications[0].CommentUID)
+}
+
+func TestPagesAndReading(t *testing.T) {
+	ctx := context.Background()
+	n, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+	for k := 0; k < 5; k++ {
+		_, err := i.InsertComment(ctx, idea.IdeaUID, "", bob.UID, "comment")
+		require.NoError(t, err)
+	}
+
+	first, err := n.GetNotifications(ctx, author.UID, false, "", 2)
+	require.NoError(t, err)
+	require.Len(t, first.Notifications, 2)
+	require.NotEmpty(t, first.NextCursor)
+	assert.Equal(t, 5, first.Unread)
+
+	second, err := n.GetNotifications(ctx, author.UID, false, first.NextCursor, 3)
+	require.NoError(t, err)
+	require.Len(t, second.Notifications, 3)
+	assert.Empty(t, second.NextCursor)
+	assert.Less(t, second.Notifications[0].ID, first.Notifications[1].ID)
+
+	_, err = n.GetNotifications(ctx, author.UID, false, "abc", 2)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	require.NoError(t, n.MarkRead(ctx, author.UID, first.Notifications[0].ID))
+	assert.ErrorIs(t, n.MarkRead(ctx, bob.UID, first.Notifications[1].ID), apperr.ErrNotFound)
+
+	unread, err := n.GetNotifications(ctx, author.UID, true, "", 10)
+	require.NoError(t, err)
+	assert.Len(t, unread.Notifications, 4)
+	assert.Equal(t, 4, unread.Unread)
+
+	require.NoError(t, n.MarkAllRead(ctx, author.UID))
+	unread, err = n.GetNotifications(ctx, author.UID, true, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, unread.Notifications)
+	assert.Zero(t, unread.Unread)
+}
+
+func TestPreferences(t *testing.T) {
+	ctx := context.Background()
+	n, i, repo := setup(t)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+
+	prefs, err := n.GetPreferences(ctx, author.UID)
+	require.NoError(t, err)
+	require.Len(t, prefs, len(models.NotificationTypes))
+	for _, p := range prefs {
+		assert.True(t, p.Enabled)
+	}
+
+	prefs, err = n.SetPreferences(ctx, author.UID, []models.NotificationPreference{
+		{Type: models.NotificationVote, Enabled: false},
+	})
+	require.NoError(t, err)
+	for _, p := range prefs {
+		assert.Equal(t, p.Type != models.NotificationVote, p.Enabled)
+	}
+	_, err = n.SetPreferences(ctx, author.UID, []models.NotificationPreference{{Type: "digest"}})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	_, err = i.Vote(ctx, idea.IdeaUID, bob.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", bob.UID, "comment")
+	require.NoError(t, err)
+
+	page, err := n.GetNotifications(ctx, author.UID, false, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.NotificationType{models.NotificationComment}, types(page))
+}
+
+// recordingForwarder keeps every notification it gets
+type recordingForwarder struct {
+	notifications []models.Notification
+}
+
+func (f *recordingForwarder) Notified(ctx context.Context, n models.Notification) error {
+	f.notifications = append(f.notifications, n)
+	return nil
+}
+
+func TestForwardsSwitchedOff(t *testing.T) {
+	ctx := context.Background()
+	n, i, repo := setup(t)
+	forwarder := &recordingForwarder{}
+	n.SetForwarder(forwarder)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+
+	_, err := n.SetPreferences(ctx, author.UID, []models.NotificationPreference{
+		{Type: models.NotificationVote, Enabled: false},
+	})
+	require.NoError(t, err)
+	_, err = i.Vote(ctx, idea.IdeaUID, bob.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = i.InsertComment(ctx, idea.IdeaUID, "", bob.UID, "comment")
+	require.NoError(t, err)
+
+	page, err := n.GetNotifications(ctx, author.UID, false, "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []models.NotificationType{models.NotificationComment}, types(page))
+
+	// the forwarders decide for themselves
+	require.Len(t, forwarder.notifications, 2)
+	assert.Equal(t, models.NotificationVote, forwarder.notifications[0].Type)
+	assert.Zero(t, forwarder.notifications[0].ID, "not saved")
+	assert.Equal(t, models.NotificationComment, forwarder.notifications[1].Type)
+	assert.NotZero(t, forwarder.notifications[1].ID)
+}
+
+type recordingPublisher struct {
+	events []models.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e models.Event) error {
+	p.events = append(p.events, e)
+	return nil
+}
+
+func TestPublishes(t *testing.T) {
+	ctx := context.Background()
+	n, i, repo := setup(t)
+	publisher := &recordingPublisher{}
+	n.SetPublisher(publisher)
+	author := repotest.User(t, repo, "author@example.com")
+	bob := repotest.User(t, repo, "bob@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "idea")
+
+	_, err := i.Vote(ctx, idea.IdeaUID, bob.UID, models.VoteLike)
+	require.NoError(t, err)
+	_, err = i.Vote(ctx, idea.IdeaUID, author.UID, models.VoteLike)
+	require.NoError(t, err)
+
+	require.Len(t, publisher.events, 1)
+	e := publisher.events[0]
+	assert.Equal(t, models.EventNotification, e.Type)
+	assert.Equal(t, []string{models.UserTopic(author.UID)}, e.Topics)
+	event := e.Data.(models.NotificationEvent)
+	assert.NotZero(t, event.Notification.ID)
+	assert.Equal(t, models.NotificationVote, event.Notification.Type)
+	assert.Equal(t, 1, event.Unread)
+}
@@ -0,0 +1,269 @@
+package onboarding
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+// columns of an imported file
+const (
+	colName     = "name"
+	colSurname  = "surname"
+	colEmail    = "email"
+	colPhone    = "phone"
+	colPosition = "position"
+	colHireDate = "hire_date"
+)
+
+var requiredColumns = []string{colName, colSurname, colEmail, colPosition}
+
+// headers - names HR systems give the columns, lowercased, by column
+var headers = map[string][]string{
+	colName:     {"name", "first name", "firstname", "имя"},
+	colSurname:  {"surname", "last name", "lastname", "фамилия"},
+	colEmail:    {"email", "e-mail", "mail", "почта", "эл. почта", "электронная почта"},
+	colPhone:    {"phone", "phone number", "телефон", "мобильный телефон"},
+	colPosition: {"position", "position name", "job title", "должность"},
+	colHireDate: {"hire date", "hired", "дата приема", "дата найма"},
+}
+
+// dateLayouts - hire dates are accepted in ISO and in the Russian format
+var dateLayouts = []string{time.DateOnly, "02.01.2006"}
+
+// limits follow the columns in sql schema
+const (
+	maxNameLength     = 20
+	maxEmailLength    = 35
+	maxPositionLength = 30
+)
+
+// row - fields of a row by column, line is where the row starts in the file
+type row struct {
+	line   int
+	fields map[string]string
+}
+
+// readRows reads the header and the rows of a CSV file saved by Excel or an
+// HR system: with or without the byte order mark, separated by commas,
+// semicolons or tabs, whichever the header has most of. Problems of the
+// whole file are validation errors, rows are checked by parseRow.
+func readRows(data []byte) ([]row, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		return nil, fileError(0, "", "the file must be in UTF-8")
+	}
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if len(bytes.TrimSpace(header)) == 0 {
+		return nil, fileError(1, "", "the file is empty")
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = separator(header)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.LazyQuotes = true
+
+	names, err := r.Read()
+	if err != nil {
+		return nil, fileError(1, "", "failed to read the header: "+err.Error())
+	}
+	index, err := columnIndex(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			line := 0
+			if errors.As(err, &parseErr) {
+				line = parseErr.Line
+			}
+			return nil, fileError(line, "", "failed to read the file: "+err.Error())
+		}
+		if blank(record) {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fileError(0, "", fmt.Sprintf("too many rows, at most %d are imported at once", MaxRows))
+		}
+		line, _ := r.FieldPos(0)
+		fields := make(map[string]string, len(index))
+		for col, n := range index {
+			if n < len(record) {
+				fields[col] = strings.TrimSpace(record[n])
+			}
+		}
+		rows = append(rows, row{line: line, fields: fields})
+	}
+	if len(rows) == 0 {
+		return nil, fileError(0, "", "the file has no rows besides the header")
+	}
+	return rows, nil
+}
+
+// fileError is a validation error with the problem in the report's shape
+func fileError(line int, field, message string) error {
+	return apperr.Validation("invalid file: " + message).
+		WithDetails([]models.ImportRowError{{Row: line, Field: field, Message: message}})
+}
+
+func separator(header []byte) rune {
+	best, count := ',', bytes.Count(header, []byte(","))
+	for _, sep := range []rune{';', '\t'} {
+		if n := bytes.Count(header, []byte(string(sep))); n > count {
+			best, count = sep, n
+		}
+	}
+	return best
+}
+
+// columnIndex finds the known columns in the header, others are skipped
+func columnIndex(names []string) (map[string]int, error) {
+	index := map[string]int{}
+	for n, name := range names {
+		col := column(name)
+		if col == "" {
+			continue
+		}
+		if _, ok := index[col]; ok {
+			return nil, fileError(1, col, "the column is given twice")
+		}
+		index[col] = n
+	}
+
+	var missing []models.ImportRowError
+	for _, col := range requiredColumns {
+		if _, ok := index[col]; !ok {
+			missing = append(missing, models.ImportRowError{Row: 1, Field: col, Message: "the column is missing"})
+		}
+	}
+	if len(missing) > 0 {
+		return nil, apperr.Validation("invalid file: required columns are missing").WithDetails(missing)
+	}
+	return index, nil
+}
+
+// column returns the column a header names, empty for an unknown one
+func column(header string) string {
+	header = strings.ToLower(normalizeSpace(strings.ReplaceAll(header, "_", " ")))
+	header = strings.ReplaceAll(header, "ё", "е")
+	for col, names := range headers {
+		for _, name := range names {
+			if header == name {
+				return col
+			}
+		}
+	}
+	return ""
+}
+
+func blank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRow checks the fields of the row and returns the user it describes,
+// errors are reported by field
+func parseRow(r row) (models.ImportedUser, []models.ImportRowError) {
+	var errs []models.ImportRowError
+	fail := func(field, message string) {
+		errs = append(errs, models.ImportRowError{Row: r.line, Field: field, Message: message})
+	}
+	u := models.ImportedUser{Row: r.line}
+
+	u.Name = normalizeSpace(r.fields[colName])
+	u.Surname = normalizeSpace(r.fields[colSurname])
+	for _, f := range []struct{ col, value string }{{colName, u.Name}, {colSurname, u.Surname}} {
+		switch {
+		case f.value == "":
+			fail(f.col, "is required")
+		case utf8.RuneCountInString(f.value) > maxNameLength:
+			fail(f.col, fmt.Sprintf("must be at most %d characters", maxNameLength))
+		}
+	}
+
+	u.Email = r.fields[colEmail]
+	switch addr, err := mail.ParseAddress(u.Email); {
+	case u.Email == "":
+		fail(colEmail, "is required")
+	case len(u.Email) > maxEmailLength:
+		fail(colEmail, fmt.Sprintf("must be at most %d characters", maxEmailLength))
+	case err != nil || addr.Address != u.Email:
+		fail(colEmail, "must be a valid email address")
+	}
+
+	if phone := r.fields[colPhone]; phone != "" {
+		var ok bool
+		if u.Phone, ok = normalizePhone(phone); !ok {
+			fail(colPhone, "must be a Russian phone number of 10 digits, +7 or 8 may go before them")
+		}
+	}
+
+	u.Position = normalizeSpace(r.fields[colPosition])
+	switch {
+	case u.Position == "":
+		fail(colPosition, "is required")
+	case utf8.RuneCountInString(u.Position) > maxPositionLength:
+		fail(colPosition, fmt.Sprintf("must be at most %d characters", maxPositionLength))
+	}
+
+	if date := r.fields[colHireDate]; date != "" {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, date); err == nil {
+				u.HireDate = &t
+				break
+			}
+		}
+		if u.HireDate == nil {
+			fail(colHireDate, "must be a date like 2024-09-01 or 01.09.2024")
+		}
+	}
+	return u, errs
+}
+
+// normalizePhone keeps the 10 digits stored in users.phone, dropping the
+// country code and anything between the digits
+func normalizePhone(phone string) (string, bool) {
+	digits := make([]byte, 0, len(phone))
+	for _, c := range []byte(phone) {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case strings.IndexByte(" +-().", c) < 0:
+			return "", false
+		}
+	}
+	if len(digits) == 11 && (digits[0] == '7' || digits[0] == '8') {
+		digits = digits[1:]
+	}
+	return string(digits), len(digits) == 10
+}
+
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// positionKey - positions differing in case or spacing are the same
+func positionKey(name string) string {
+	return strings.ToLower(normalizeSpace(name))
+}
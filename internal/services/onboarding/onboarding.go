@@ -0,0 +1,330 @@
+// Package onboarding brings employees in from HR exports. A CSV of users is
+// imported as a whole: a dry run reports what every row would become and
+// what is wrong with it, a commit creates the users and the positions they
+// need in one transaction. Imported users have no password, each gets an
+// email invitation to set one and can't sign in until then.
+package onboarding
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MaxRows - users imported at once
+	MaxRows = 5000
+	// MaxFileSize - size of an imported file
+	MaxFileSize = 5 << 20
+	// InvitationTTL - how long an invitation link works
+	InvitationTTL = 14 * 24 * time.Hour
+)
+
+// noPassword is the password of imported users until they accept the
+// invitation, it is no bcrypt hash so login never matches it
+const noPassword = "!"
+
+// Inviter delivers the invitation link with token to a user, see
+// email.Mailer
+type Inviter interface {
+	Invite(ctx context.Context, user models.User, token string, expiresAt time.Time) error
+}
+
+type Onboarding struct {
+	log     slog.Logger
+	repo    repository.Repository
+	inviter Inviter
+	now     func() time.Time
+}
+
+func New(log slog.Logger, repo repository.Repository, inviter Inviter) *Onboarding {
+	return &Onboarding{log: log, repo: repo, inviter: inviter, now: time.Now}
+}
+
+// errRejected rolls the import back when rows turn out wrong within the
+// transaction
+var errRejected = errors.New("import rejected")
+
+// Import checks a CSV of users, see readRows for the format, and reports
+// the users its rows describe, positions missing in the dictionary and
+// errors by row. With commit and no errors the users and positions are
+// created and the users invited, with any errors nothing is created.
+// Problems of the file as a whole, like a missing column, are validation
+// errors with []models.ImportRowError details.
+func (o *Onboarding) Import(ctx context.Context, r io.Reader, commit bool) (models.ImportReport, error) {
+	log := o.log.With(slog.String("op", "OnboardingImport"), slog.Bool("commit", commit))
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+	if err != nil {
+		return models.ImportReport{}, err
+	}
+	if len(data) > MaxFileSize {
+		return models.ImportReport{}, apperr.TooLarge(fmt.Sprintf("the file must be at most %d MiB", MaxFileSize>>20))
+	}
+	rows, err := readRows(data)
+	if err != nil {
+		return models.ImportReport{}, err
+	}
+
+	report := models.ImportReport{
+		DryRun:       !commit,
+		Rows:         len(rows),
+		Users:        []models.ImportedUser{},
+		NewPositions: []string{},
+		Errors:       []models.ImportRowError{},
+	}
+	users := make([]models.ImportedUser, 0, len(rows))
+	emails := map[string]int{} // rows by lowercased email
+	for _, row := range rows {
+		u, errs := parseRow(row)
+		if u.Email != "" {
+			email := strings.ToLower(u.Email)
+			if first, ok := emails[email]; ok {
+				errs = append(errs, models.ImportRowError{Row: u.Row, Field: colEmail,
+					Message: fmt.Sprintf("repeats the email of row %d", first)})
+			} else {
+				emails[email] = u.Row
+			}
+		}
+		if len(errs) > 0 {
+			report.Errors = append(report.Errors, errs...)
+			continue
+		}
+		users = append(users, u)
+	}
+
+	if !commit || len(report.Errors) > 0 {
+		if _, err := o.check(ctx, o.repo, &report, users); err != nil {
+			log.ErrorContext(ctx, "failed to check users: "+err.Error())
+			return models.ImportReport{}, err
+		}
+		log.InfoContext(ctx, "checked users", slog.Int("rows", report.Rows), slog.Int("errors", len(report.Errors)))
+		return report, nil
+	}
+
+	var checked models.ImportReport
+	var created []models.User
+	err = o.repo.WithTx(ctx, func(repo repository.Repository) error {
+		// a retried transaction runs this again, every attempt starts
+		// from the report of the file
+		attempt := report
+		attempt.Users, attempt.NewPositions = []models.ImportedUser{}, []string{}
+		attempt.Errors = slices.Clone(report.Errors)
+
+		// checked within the transaction, so that nobody registers in between
+		positions, err := o.check(ctx, repo, &attempt, users)
+		if err != nil {
+			return err
+		}
+		if len(attempt.Errors) > 0 {
+			checked = attempt
+			return errRejected
+		}
+		for _, name := range attempt.NewPositions {
+			p, err := repo.InsertPosition(ctx, name)
+			if err != nil {
+				return err
+			}
+			positions[positionKey(name)] = p
+		}
+
+		inserted := make([]models.User, 0, len(attempt.Users))
+		for n, u := range attempt.Users {
+			user := models.User{
+				UID:        uuid.NewString(),
+				Name:       u.Name,
+				Surname:    u.Surname,
+				PositionID: positions[positionKey(u.Position)].ID,
+				Email:      u.Email,
+				Password:   noPassword,
+				Phone:      u.Phone,
+				HireDate:   u.HireDate,
+			}
+			if err := repo.InsertUser(ctx, user); err != nil {
+				return err
+			}
+			attempt.Users[n].UID = user.UID
+			inserted = append(inserted, user)
+		}
+		checked, created = attempt, inserted
+		return nil
+	})
+	if errors.Is(err, errRejected) {
+		report = checked
+		log.InfoContext(ctx, "rejected users", slog.Int("rows", report.Rows), slog.Int("errors", len(report.Errors)))
+		return report, nil
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		return models.ImportReport{}, apperr.Conflict("positions have changed during the import, try again").WithCause(err)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to import users: "+err.Error())
+		return models.ImportReport{}, err
+	}
+	report = checked
+	report.Committed = true
+
+	// the users are there whatever happens to the emails, an admin invites
+	// again the ones left out
+	invited := 0
+	for n, user := range created {
+		if err := o.invite(ctx, user); err != nil {
+			log.ErrorContext(ctx, "failed to invite user: "+err.Error(), slog.String("uid", user.UID))
+			continue
+		}
+		report.Users[n].Invited = true
+		invited++
+	}
+
+	log.InfoContext(ctx, "imported users", slog.Int("users", len(created)),
+		slog.Int("positions", len(report.NewPositions)), slog.Int("invited", invited))
+	return report, nil
+}
+
+// check matches users against repo: rows whose email is taken, in any
+// case like the repeats within the file, are turned into errors, the others go to report.Users with the position spelled as
+// in the dictionary or to be added to it. Returns the dictionary by
+// positionKey.
+func (o *Onboarding) check(ctx context.Context, repo repository.Repository, report *models.ImportReport,
+	users []models.ImportedUser) (map[string]models.UserPosition, error) {
+	dictionary, err := repo.SelectPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]models.UserPosition, len(dictionary))
+	for _, p := range dictionary {
+		positions[positionKey(p.Name)] = p
+	}
+	added := map[string]string{} // new positions by key, as first spelled
+
+	for _, u := range users {
+		taken, err := repo.UserEmailTaken(ctx, u.Email)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			report.Errors = append(report.Errors, models.ImportRowError{Row: u.Row, Field: colEmail,
+				Message: "a user with this email already exists"})
+			continue
+		}
+
+		key := positionKey(u.Position)
+		if p, ok := positions[key]; ok {
+			u.Position = p.Name
+		} else if name, ok := added[key]; ok {
+			u.Position = name
+		} else {
+			added[key] = u.Position
+			report.NewPositions = append(report.NewPositions, u.Position)
+		}
+		report.Users = append(report.Users, u)
+	}
+
+	slices.SortStableFunc(report.Errors, func(a, b models.ImportRowError) int { return a.Row - b.Row })
+	return positions, nil
+}
+
+// Invite sends the user an invitation again, replacing the one sent before.
+// Only users who haven't set a password yet are invited.
+func (o *Onboarding) Invite(ctx context.Context, uid string) error {
+	log := o.log.With(slog.String("op", "OnboardingInvite"), slog.String("uid", uid))
+
+	user, err := o.repo.SelectUserByUID(ctx, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("user not found")
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to fetch user: "+err.Error())
+		return err
+	}
+	if user.Password != noPassword {
+		return apperr.Conflict("the user has already set a password")
+	}
+
+	if err := o.invite(ctx, user); err != nil {
+		log.ErrorContext(ctx, "failed to invite user: "+err.Error())
+		return err
+	}
+	log.InfoContext(ctx, "invited user")
+	return nil
+}
+
+// invite issues a new invitation to the user and hands its token to the
+// inviter, only a hash of the token is saved
+func (o *Onboarding) invite(ctx context.Context, user models.User) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := o.now()
+	invitation := models.Invitation{
+		TokenHash: hashToken(token),
+		UserUID:   user.UID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(InvitationTTL),
+	}
+	if err := o.repo.InsertInvitation(ctx, invitation); err != nil {
+		return err
+	}
+	return o.inviter.Invite(ctx, user, token, invitation.ExpiresAt)
+}
+
+// AcceptInvitation sets the password of the user invited with token, the
+// token is spent only when the password is set
+func (o *Onboarding) AcceptInvitation(ctx context.Context, token string, password string) error {
+	log := o.log.With(slog.String("op", "OnboardingAcceptInvitation"))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return apperr.Validation("password must be at most 72 bytes")
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to generate password hash: "+err.Error())
+		return err
+	}
+
+	var uid string
+	err = o.repo.WithTx(ctx, func(repo repository.Repository) error {
+		invitation, err := repo.ConsumeInvitation(ctx, hashToken(token))
+		if err != nil {
+			return err
+		}
+		if !o.now().Before(invitation.ExpiresAt) {
+			return repository.ErrNotFound
+		}
+		uid = invitation.UserUID
+		return repo.UpdateUserPassword(ctx, uid, string(hash))
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("invitation not found or expired")
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to accept invitation: "+err.Error())
+		return err
+	}
+
+	log.InfoContext(ctx, "user accepted invitation", slog.String("uid", uid))
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,236 @@
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviter keeps the tokens it is given by email, failing for fail
+type inviter struct {
+	tokens map[string]string
+	fail   string
+}
+
+func (i *inviter) Invite(ctx context.Context, user models.User, token string, expiresAt time.Time) error {
+	if user.Email == i.fail {
+		return errors.New("outbox is down")
+	}
+	i.tokens[user.Email] = token
+	return nil
+}
+
+var errSerialization = errors.New("could not serialize access")
+
+// retryingRepo fails the first attempt of a transaction after running it
+// and runs it again, as WithTx does after a serialization failure
+type retryingRepo struct {
+	*memory.Repository
+	retries int
+}
+
+func (r *retryingRepo) WithTx(ctx context.Context, fn func(repo repository.Repository) error) error {
+	err := r.Repository.WithTx(ctx, func(repo repository.Repository) error {
+		if err := fn(repo); err != nil {
+			return err
+		}
+		return errSerialization
+	})
+	if !errors.Is(err, errSerialization) {
+		return err
+	}
+	r.retries++
+	return r.Repository.WithTx(ctx, fn)
+}
+
+func setup(t *testing.T) (*Onboarding, *inviter, *memory.Repository) {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	inv := &inviter{tokens: map[string]string{}}
+	return New(*slog.Default(), repo, inv), inv, repo
+}
+
+const employees = "\ufeffИмя;Фамилия;E-mail;Телефон;Должность;Дата приёма;Табельный номер\n" +
+	"Анна;Смирнова;anna@example.com;+7 (999) 123-45-67;developer;01.09.2024;100\n" +
+	"Олег;Кузнецов;oleg@example.com;;Тестировщик;2024-10-15;101\n" +
+	";;;;;;\n" +
+	"Мария;Попова;maria@example.com;89991112233; тестировщик ;;102\n"
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	o, inv, repo := setup(t)
+
+	report, err := o.Import(ctx, strings.NewReader(employees), false)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.False(t, report.Committed)
+	assert.Equal(t, 3, report.Rows, "blank rows are skipped")
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []string{"Тестировщик"}, report.NewPositions)
+	require.Len(t, report.Users, 3)
+
+	anna := report.Users[0]
+	assert.Equal(t, 2, anna.Row)
+	assert.Equal(t, "9991234567", anna.Phone)
+	assert.Equal(t, "Developer", anna.Position, "spelled as in the dictionary")
+	require.NotNil(t, anna.HireDate)
+	assert.Equal(t, time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), *anna.HireDate)
+	assert.Empty(t, anna.UID)
+	maria := report.Users[2]
+	assert.Equal(t, 5, maria.Row)
+	assert.Equal(t, "9991112233", maria.Phone)
+	assert.Equal(t, "Тестировщик", maria.Position)
+	assert.Nil(t, maria.HireDate)
+
+	_, err = repo.SelectUserByEmail(ctx, "anna@example.com")
+	assert.Error(t, err, "a dry run creates nobody")
+	assert.Empty(t, inv.tokens)
+
+	inv.fail = "oleg@example.com"
+	report, err = o.Import(ctx, strings.NewReader(employees), true)
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assert.True(t, report.Committed)
+	require.Len(t, report.Users, 3)
+	assert.True(t, report.Users[0].Invited)
+	assert.False(t, report.Users[1].Invited, "the invitation failed")
+
+	user, err := repo.SelectUserByEmail(ctx, "maria@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, report.Users[2].UID, user.UID)
+	positions, err := repo.SelectPositions(ctx)
+	require.NoError(t, err)
+	require.Len(t, positions, len(repotest.Positions)+1)
+	assert.Equal(t, models.UserPosition{ID: user.PositionID, Name: "Тестировщик"}, positions[len(positions)-1])
+	assert.Error(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("")), "no password until invited")
+
+	// the same file again is a file of existing users
+	report, err = o.Import(ctx, strings.NewReader(employees), true)
+	require.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.Empty(t, report.Users)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, models.ImportRowError{Row: 2, Field: "email", Message: "a user with this email already exists"},
+		report.Errors[0])
+
+	// the failed invitation is sent again
+	oleg, err := repo.SelectUserByEmail(ctx, "oleg@example.com")
+	require.NoError(t, err)
+	inv.fail = ""
+	require.NoError(t, o.Invite(ctx, oleg.UID))
+	assert.NotEmpty(t, inv.tokens["oleg@example.com"])
+}
+
+func TestImportRetried(t *testing.T) {
+	ctx := context.Background()
+	_, inv, repo := setup(t)
+	retrying := &retryingRepo{Repository: repo}
+	o := New(*slog.Default(), retrying, inv)
+
+	report, err := o.Import(ctx, strings.NewReader(employees), true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retrying.retries)
+	assert.True(t, report.Committed)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, []string{"Тестировщик"}, report.NewPositions)
+	require.Len(t, report.Users, 3, "the first attempt leaves nothing in the report")
+
+	for _, u := range report.Users {
+		user, err := repo.SelectUserByEmail(ctx, u.Email)
+		require.NoError(t, err)
+		assert.Equal(t, u.UID, user.UID)
+		profiles, err := repo.SelectProfilesByName(ctx, []models.FullName{{Name: u.Name, Surname: u.Surname}})
+		require.NoError(t, err)
+		assert.Len(t, profiles, 1, "inserted once")
+	}
+	positions, err := repo.SelectPositions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, positions, len(repotest.Positions)+1)
+}
+
+func TestImportErrors(t *testing.T) {
+	ctx := context.Background()
+	o, _, repo := setup(t)
+	repotest.User(t, repo, "petr@example.com")
+
+	file := "name,surname,email,phone,position,hire_date\n" +
+		"Anna,Smirnova,anna@example.com,,Developer,\n" +
+		"Oleg,,not an email,12345,,31.02.2024\n" +
+		"\"Maria\nAnn\",Popova,ANNA@example.com,,Developer,\n" +
+		"Ivan,Ivanov,ivan@example.com,,A position name longer than thirty,\n" +
+		"Petr,Petrov,Petr@Example.com,,Developer,\n"
+	report, err := o.Import(ctx, strings.NewReader(file), true)
+	require.NoError(t, err)
+	assert.False(t, report.Committed)
+	assert.Equal(t, []models.ImportRowError{
+		{Row: 3, Field: "surname", Message: "is required"},
+		{Row: 3, Field: "email", Message: "must be a valid email address"},
+		{Row: 3, Field: "phone", Message: "must be a Russian phone number of 10 digits, +7 or 8 may go before them"},
+		{Row: 3, Field: "position", Message: "is required"},
+		{Row: 3, Field: "hire_date", Message: "must be a date like 2024-09-01 or 01.09.2024"},
+		{Row: 4, Field: "email", Message: "repeats the email of row 2"},
+		{Row: 6, Field: "position", Message: "must be at most 30 characters"},
+		{Row: 7, Field: "email", Message: "a user with this email already exists"},
+	}, report.Errors)
+	require.Len(t, report.Users, 1)
+	_, err = repo.SelectUserByEmail(ctx, "anna@example.com")
+	assert.Error(t, err, "nothing is imported while any row is wrong")
+
+	for name, file := range map[string]string{
+		"empty":           "",
+		"missing columns": "name;email\nAnna;anna@example.com\n",
+		"repeated column": "name,surname,email,position,Name\n",
+		"no rows":         "name,surname,email,position\n\n",
+		"not utf-8":       "name,surname,email,position\n\xcf\xe5\xf2\xf0,a,b,c\n",
+	} {
+		_, err := o.Import(ctx, strings.NewReader(file), false)
+		assert.ErrorIs(t, err, apperr.ErrValidation, name)
+	}
+
+	_, err = o.Import(ctx, strings.NewReader(strings.Repeat("x", MaxFileSize+1)), false)
+	assert.ErrorIs(t, err, apperr.ErrTooLarge)
+	many := "name,surname,email,position\n" + strings.Repeat("Anna,Smirnova,anna@example.com,Developer\n", MaxRows+1)
+	_, err = o.Import(ctx, strings.NewReader(many), false)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	ctx := context.Background()
+	o, inv, repo := setup(t)
+
+	_, err := o.Import(ctx, strings.NewReader("name,surname,email,position\nAnna,Smirnova,anna@example.com,Developer\n"), true)
+	require.NoError(t, err)
+	user, err := repo.SelectUserByEmail(ctx, "anna@example.com")
+	require.NoError(t, err)
+
+	// a new invitation replaces the first one
+	first := inv.tokens[user.Email]
+	require.NoError(t, o.Invite(ctx, user.UID))
+	assert.ErrorIs(t, o.AcceptInvitation(ctx, first, "password1"), apperr.ErrNotFound)
+
+	token := inv.tokens[user.Email]
+	o.now = func() time.Time { return time.Now().Add(InvitationTTL) }
+	assert.ErrorIs(t, o.AcceptInvitation(ctx, token, "password1"), apperr.ErrNotFound, "expired")
+	o.now = time.Now
+
+	require.NoError(t, o.AcceptInvitation(ctx, token, "password1"))
+	user, err = repo.SelectUserByUID(ctx, user.UID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("password1")))
+	assert.ErrorIs(t, o.AcceptInvitation(ctx, token, "password2"), apperr.ErrNotFound, "invitations are one-time")
+
+	assert.ErrorIs(t, o.Invite(ctx, user.UID), apperr.ErrConflict, "the password is set")
+	assert.ErrorIs(t, o.Invite(ctx, "8f0f5b8e-2b1a-4f0e-9c39-5d2f3f6b7a10"), apperr.ErrNotFound)
+}
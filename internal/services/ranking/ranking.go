@@ -0,0 +1,143 @@
+// Package ranking scores ideas for the hot, top, controversial and rising
+// feeds. Scores are stored next to the ideas: an idea is rescored when it is
+// created, voted for or commented on, and every idea is rescored periodically
+// since rising scores fade with time.
+//
+//   - hot: log10 of points (likes minus dislikes, comments and views weighted
+//     by CommentWeight and ViewWeight) plus the idea's age in HotPeriods since
+//     a fixed epoch, so a newer idea needs ten times fewer points per period
+//     to stay level, as on Reddit
+//   - top: likes minus dislikes
+//   - controversial: lower bound of the Wilson score interval for the share
+//     of the smaller side of votes, high for many votes split evenly
+//   - rising: votes and weighted comments of the last RisingWindow divided by
+//     the age in hours plus two raised to RisingGravity, as on Hacker News
+package ranking
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+const (
+	// CommentWeight and ViewWeight - votes a comment and a view are worth
+	CommentWeight = 0.5
+	ViewWeight    = 0.1
+	// HotPeriod - age that makes up for ten times the points in hot
+	HotPeriod = 12*time.Hour + 30*time.Minute
+	// RisingWindow - how far back rising counts votes and comments
+	RisingWindow = 24 * time.Hour
+	// RisingGravity - how fast rising ideas sink with age
+	RisingGravity = 1.5
+	// wilsonZ - z of the 95% confidence level
+	wilsonZ = 1.96
+)
+
+// epoch - hot counts age from it, any fixed moment would do
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type Ranker struct {
+	log  slog.Logger
+	repo repository.Repository
+	now  func() time.Time
+}
+
+func New(log slog.Logger, repo repository.Repository) *Ranker {
+	return &Ranker{log: log, repo: repo, now: time.Now}
+}
+
+// Publish rescores the idea an idea, vote or comment event is about, other
+// events are ignored. It makes the ranker an ideas.Publisher.
+func (r *Ranker) Publish(ctx context.Context, event models.Event) error {
+	var uid string
+	switch data := event.Data.(type) {
+	case models.Idea:
+		uid = data.IdeaUID
+	case models.VoteCounts:
+		uid = data.IdeaUID
+	case models.Comment:
+		uid = data.IdeaUID
+	default:
+		return nil
+	}
+	_, err := r.rescore(ctx, uid)
+	return err
+}
+
+// Recalculate rescores every idea and returns how many were scored
+func (r *Ranker) Recalculate(ctx context.Context) (int, error) {
+	op := "RankingRecalculate"
+	log := r.log.With(slog.String("op", op))
+
+	n, err := r.rescore(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to rescore ideas: "+err.Error())
+		return 0, err
+	}
+	log.InfoContext(ctx, "rescored ideas", slog.Int("ideas", n))
+	return n, nil
+}
+
+// rescore scores the given ideas, every idea when none are given
+func (r *Ranker) rescore(ctx context.Context, uids ...string) (int, error) {
+	now := r.now()
+	activity, err := r.repo.SelectIdeaActivity(ctx, now.Add(-RisingWindow), uids...)
+	if err != nil {
+		return 0, err
+	}
+	scores := make([]models.IdeaScore, 0, len(activity))
+	for _, a := range activity {
+		scores = append(scores, Score(a, now))
+	}
+	if err := r.repo.UpsertIdeaScores(ctx, scores); err != nil {
+		return 0, err
+	}
+	return len(scores), nil
+}
+
+// Score computes every score of an idea at the moment now
+func Score(a models.IdeaActivity, now time.Time) models.IdeaScore {
+	net := float64(a.LikeCount - a.DislikeCount)
+	points := net + CommentWeight*float64(a.Comments) + ViewWeight*float64(a.Views)
+	recent := float64(a.RecentVotes) + CommentWeight*float64(a.RecentComments)
+	age := max(now.Sub(a.CreationDate).Hours(), 0)
+
+	return models.IdeaScore{
+		IdeaUID:       a.IdeaUID,
+		Hot:           hot(points, a.CreationDate),
+		Top:           net,
+		Controversial: wilsonLower(min(a.LikeCount, a.DislikeCount), a.LikeCount+a.DislikeCount),
+		Rising:        recent / math.Pow(age+2, RisingGravity),
+		ComputedAt:    now,
+	}
+}
+
+func hot(points float64, created time.Time) float64 {
+	order := math.Log10(max(math.Abs(points), 1))
+	sign := 0.0
+	if points > 0 {
+		sign = 1
+	} else if points < 0 {
+		sign = -1
+	}
+	return sign*order + created.Sub(epoch).Seconds()/HotPeriod.Seconds()
+}
+
+// wilsonLower returns the lower bound of the Wilson score interval for
+// positive out of n, 0 without any
+func wilsonLower(positive, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	total := float64(n)
+	p := float64(positive) / total
+	z2 := wilsonZ * wilsonZ
+	center := p + z2/(2*total)
+	spread := wilsonZ * math.Sqrt((p*(1-p)+z2/(4*total))/total)
+	return (center - spread) / (1 + z2/total)
+}
@@ -0,0 +1,122 @@
+package ranking
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+func votes(likes, dislikes int) models.IdeaActivity {
+	return models.IdeaActivity{IdeaUID: "1", CreationDate: now, LikeCount: likes, DislikeCount: dislikes}
+}
+
+func TestTop(t *testing.T) {
+	assert.Equal(t, 3.0, Score(votes(5, 2), now).Top)
+	assert.Equal(t, -2.0, Score(votes(0, 2), now).Top)
+}
+
+func TestHot(t *testing.T) {
+	base := Score(votes(10, 0), now).Hot
+
+	// a period newer with ten times fewer points is level
+	newer := votes(1, 0)
+	newer.CreationDate = now.Add(HotPeriod)
+	assert.InDelta(t, base, Score(newer, now).Hot, 1e-9)
+
+	// comments and views count as points
+	discussed := votes(5, 0)
+	discussed.Comments, discussed.Views = 6, 20
+	assert.InDelta(t, base, Score(discussed, now).Hot, 1e-9)
+
+	assert.Less(t, Score(votes(0, 10), now).Hot, Score(votes(0, 0), now).Hot, "disliked ideas sink")
+	older := votes(10, 0)
+	older.CreationDate = now.Add(-time.Hour)
+	assert.Less(t, Score(older, now).Hot, base)
+	// hot doesn't change as the idea ages
+	assert.Equal(t, base, Score(votes(10, 0), now.Add(24*time.Hour)).Hot)
+}
+
+func TestControversial(t *testing.T) {
+	assert.Zero(t, Score(votes(0, 0), now).Controversial)
+	assert.Zero(t, Score(votes(20, 0), now).Controversial, "nobody disagrees")
+	assert.InDelta(t, 0.2366, Score(votes(5, 5), now).Controversial, 1e-4)
+	assert.Equal(t, Score(votes(8, 2), now).Controversial, Score(votes(2, 8), now).Controversial,
+		"either side may be smaller")
+	assert.InDelta(t, 0.4038, Score(votes(50, 50), now).Controversial, 1e-4)
+	assert.InDelta(t, 0.0179, Score(votes(9, 1), now).Controversial, 1e-4)
+}
+
+func TestRising(t *testing.T) {
+	quiet := votes(100, 0)
+	assert.Zero(t, Score(quiet, now).Rising, "only recent activity counts")
+
+	active := votes(3, 0)
+	active.RecentVotes, active.RecentComments = 3, 2
+	fresh := Score(active, now).Rising
+	assert.InDelta(t, 4/(2*1.4142135623730951), fresh, 1e-9)
+
+	assert.Less(t, Score(active, now.Add(6*time.Hour)).Rising, fresh, "sinks with age")
+	assert.Equal(t, fresh, Score(active, now.Add(-time.Hour)).Rising, "clock skew doesn't make the age negative")
+}
+
+func TestRanker(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	i, err := ideas.New(ctx, *slog.Default(), repo)
+	require.NoError(t, err)
+	r := New(*slog.Default(), repo)
+	i.SetPublisher(r)
+
+	author := repotest.User(t, repo, "author@example.com")
+	quiet, err := i.InsertIdea(ctx, "quiet", "text", author.UID, repotest.Statuses[0].ID, repotest.Categories[0].ID)
+	require.NoError(t, err)
+	split, err := i.InsertIdea(ctx, "split", "text", author.UID, repotest.Statuses[0].ID, repotest.Categories[0].ID)
+	require.NoError(t, err)
+	liked, err := i.InsertIdea(ctx, "liked", "text", author.UID, repotest.Statuses[0].ID, repotest.Categories[0].ID)
+	require.NoError(t, err)
+	for k, vote := range []models.VoteType{models.VoteLike, models.VoteLike, models.VoteDislike} {
+		voter := repotest.User(t, repo, "voter"+string(rune('a'+k))+"@example.com")
+		_, err := i.Vote(ctx, liked.IdeaUID, voter.UID, models.VoteLike)
+		require.NoError(t, err)
+		_, err = i.Vote(ctx, split.IdeaUID, voter.UID, vote)
+		require.NoError(t, err)
+	}
+	_, err = i.InsertComment(ctx, quiet.IdeaUID, "", author.UID, "anyone?")
+	require.NoError(t, err)
+
+	names := func(sort models.IdeaSort) []string {
+		ranked, err := i.GetRankedIdeas(ctx, sort, author.UID)
+		require.NoError(t, err)
+		var names []string
+		for _, idea := range ranked {
+			names = append(names, idea.Name)
+		}
+		return names
+	}
+	// ideas are scored as they are created, voted for and commented on
+	assert.Equal(t, []string{"liked", "split", "quiet"}, names(models.SortTop))
+	assert.Equal(t, []string{"split", "liked", "quiet"}, names(models.SortControversial))
+	assert.Equal(t, []string{"liked", "split", "quiet"}, names(models.SortRising))
+
+	_, err = i.GetRankedIdeas(ctx, "random", author.UID)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	// a day later nothing is recent, equal rising scores go newer first
+	r.now = func() time.Time { return time.Now().Add(RisingWindow + time.Hour) }
+	n, err := r.Recalculate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"liked", "split", "quiet"}, names(models.SortRising))
+}
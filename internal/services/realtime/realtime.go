@@ -0,0 +1,221 @@
+// Package realtime pushes models.Event to connected clients. The Hub keeps
+// subscriptions of this process; with a Relay (PostgreSQL LISTEN/NOTIFY)
+// events go through the database and reach subscribers of every replica.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+)
+
+const (
+	// Channel - NOTIFY channel events are relayed on
+	Channel = "agora_events"
+	// Buffer - messages a subscription holds, a subscriber falling further
+	// behind is dropped and has to reconnect
+	Buffer = 64
+	// MaxRelayPayload - larger events are relayed without Data (NOTIFY
+	// payloads are limited to 8000 bytes), clients reload the topic instead
+	MaxRelayPayload = 7900
+
+	minRetry = time.Second
+	maxRetry = 30 * time.Second
+)
+
+// Relay carries events between replicas: every replica listening on the
+// channel receives every payload, its own included
+type Relay interface {
+	Notify(ctx context.Context, channel, payload string) error
+	// Listen calls handle for every payload until ctx is done or the
+	// connection is lost
+	Listen(ctx context.Context, channel string, handle func(payload string)) error
+}
+
+// Message - event as sent to clients, Data is already encoded
+type Message struct {
+	Type models.EventType
+	Data json.RawMessage
+}
+
+// envelope - event in the form it is relayed in
+type envelope struct {
+	Type   models.EventType `json:"type"`
+	Topics []string         `json:"topics"`
+	Data   json.RawMessage  `json:"data,omitempty"`
+}
+
+type Hub struct {
+	log   slog.Logger
+	relay Relay
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewHub(log slog.Logger) *Hub {
+	return &Hub{
+		log:  log,
+		subs: map[*Subscription]struct{}{},
+	}
+}
+
+// SetRelay sends events through relay instead of delivering them directly,
+// Listen must be running for them to come back
+func (h *Hub) SetRelay(r Relay) {
+	h.relay = r
+}
+
+// Publish delivers the event to subscribers of any of its topics. When the
+// relay fails the event is still delivered in this process.
+func (h *Hub) Publish(ctx context.Context, event models.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	e := envelope{Type: event.Type, Topics: event.Topics, Data: data}
+	if h.relay == nil {
+		h.deliver(e)
+		return nil
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if len(payload) > MaxRelayPayload {
+		e.Data = nil
+		if payload, err = json.Marshal(e); err != nil {
+			return err
+		}
+	}
+	if err := h.relay.Notify(ctx, Channel, string(payload)); err != nil {
+		h.deliver(e)
+		return err
+	}
+	return nil
+}
+
+// Listen receives relayed events and delivers them here, reconnecting until
+// ctx is done. Without a relay it returns at once.
+func (h *Hub) Listen(ctx context.Context) {
+	if h.relay == nil {
+		return
+	}
+	retry := minRetry
+	for {
+		started := time.Now()
+		err := h.relay.Listen(ctx, Channel, h.received)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxRetry {
+			retry = minRetry
+		}
+		h.log.ErrorContext(ctx, "realtime relay lost, reconnecting",
+			slog.String("error", errString(err)),
+			slog.Duration("retry", retry),
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, maxRetry)
+	}
+}
+
+func (h *Hub) received(payload string) {
+	var e envelope
+	if err := json.Unmarshal([]byte(payload), &e); err != nil {
+		h.log.Error("malformed relayed event: " + err.Error())
+		return
+	}
+	h.deliver(e)
+}
+
+// deliver hands the event to matching subscriptions, subscriptions with a
+// full buffer are closed rather than blocking the publisher
+func (h *Hub) deliver(e envelope) {
+	msg := Message{Type: e.Type, Data: e.Data}
+	if len(msg.Data) == 0 {
+		msg.Data = json.RawMessage("null")
+	}
+
+	var slow []*Subscription
+	h.mu.RLock()
+	for s := range h.subs {
+		if !slices.ContainsFunc(e.Topics, s.wants) {
+			continue
+		}
+		select {
+		case s.ch <- msg:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		h.log.Warn("dropping slow realtime subscriber")
+		s.Close()
+	}
+}
+
+// Subscription - events of some topics, C is closed when the subscription
+// is closed or dropped for falling behind
+type Subscription struct {
+	C <-chan Message
+
+	hub    *Hub
+	ch     chan Message
+	topics map[string]bool
+}
+
+// Subscribe starts receiving events of topics, Close the subscription when
+// done
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	ch := make(chan Message, Buffer)
+	s := &Subscription{C: ch, hub: h, ch: ch, topics: map[string]bool{}}
+	for _, t := range topics {
+		s.topics[t] = true
+	}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+func (s *Subscription) wants(topic string) bool {
+	return s.topics[topic]
+}
+
+// Close stops the subscription, closing twice is fine
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subs[s]; ok {
+		delete(s.hub.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscribers - open subscriptions of this process
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "listener stopped"
+	}
+	return err.Error()
+}
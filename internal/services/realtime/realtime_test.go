@@ -0,0 +1,157 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, s *Subscription) Message {
+	t.Helper()
+	select {
+	case msg, ok := <-s.C:
+		require.True(t, ok, "subscription is closed")
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+		return Message{}
+	}
+}
+
+func assertEmpty(t *testing.T, s *Subscription) {
+	t.Helper()
+	select {
+	case msg := <-s.C:
+		t.Fatalf("unexpected message %s", msg.Type)
+	default:
+	}
+}
+
+func TestHub_Topics(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(*slog.Default())
+	feed := hub.Subscribe(models.TopicIdeas)
+	both := hub.Subscribe(models.TopicIdeas, models.IdeaTopic("a"))
+	other := hub.Subscribe(models.IdeaTopic("b"))
+	defer feed.Close()
+	defer both.Close()
+	defer other.Close()
+
+	require.NoError(t, hub.Publish(ctx, models.Event{
+		Type:   models.EventVotes,
+		Topics: []string{models.TopicIdeas, models.IdeaTopic("a")},
+		Data:   models.VoteCounts{IdeaUID: "a", LikeCount: 2},
+	}))
+	require.NoError(t, hub.Publish(ctx, models.Event{
+		Type:   models.EventCommentAdded,
+		Topics: []string{models.IdeaTopic("a")},
+		Data:   models.Comment{CommentUID: "c"},
+	}))
+
+	msg := receive(t, feed)
+	assert.Equal(t, models.EventVotes, msg.Type)
+	var counts models.VoteCounts
+	require.NoError(t, json.Unmarshal(msg.Data, &counts))
+	assert.Equal(t, 2, counts.LikeCount)
+	assertEmpty(t, feed)
+
+	// one copy, whichever topics matched
+	assert.Equal(t, models.EventVotes, receive(t, both).Type)
+	assert.Equal(t, models.EventCommentAdded, receive(t, both).Type)
+	assertEmpty(t, both)
+	assertEmpty(t, other)
+}
+
+func TestHub_DropsSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	hub := NewHub(*slog.Default())
+	slow := hub.Subscribe("t")
+	for k := 0; k <= Buffer; k++ {
+		require.NoError(t, hub.Publish(ctx, models.Event{Type: models.EventIdeaCreated, Topics: []string{"t"}}))
+	}
+	assert.Zero(t, hub.Subscribers())
+
+	n := 0
+	for range slow.C {
+		n++
+	}
+	assert.Equal(t, Buffer, n)
+	slow.Close()
+}
+
+// loopback - relay of a single process, what NOTIFY does for every replica
+type loopback struct {
+	mu       sync.Mutex
+	handlers []func(string)
+	sent     []string
+	fail     error
+	ready    chan struct{}
+}
+
+func (l *loopback) Notify(ctx context.Context, channel, payload string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fail != nil {
+		return l.fail
+	}
+	l.sent = append(l.sent, payload)
+	for _, h := range l.handlers {
+		h(payload)
+	}
+	return nil
+}
+
+func (l *loopback) Listen(ctx context.Context, channel string, handle func(string)) error {
+	l.mu.Lock()
+	l.handlers = append(l.handlers, handle)
+	l.mu.Unlock()
+	close(l.ready)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHub_Relay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	relay := &loopback{ready: make(chan struct{})}
+	hub := NewHub(*slog.Default())
+	hub.SetRelay(relay)
+	go hub.Listen(ctx)
+	<-relay.ready
+
+	sub := hub.Subscribe(models.TopicIdeas)
+	defer sub.Close()
+
+	require.NoError(t, hub.Publish(ctx, models.Event{
+		Type: models.EventIdeaCreated, Topics: []string{models.TopicIdeas}, Data: models.Idea{Name: "small"},
+	}))
+	msg := receive(t, sub)
+	var idea models.Idea
+	require.NoError(t, json.Unmarshal(msg.Data, &idea))
+	assert.Equal(t, "small", idea.Name)
+
+	require.NoError(t, hub.Publish(ctx, models.Event{
+		Type:   models.EventIdeaUpdated,
+		Topics: []string{models.TopicIdeas},
+		Data:   models.Idea{Name: strings.Repeat("я", MaxRelayPayload)},
+	}))
+	msg = receive(t, sub)
+	assert.Equal(t, models.EventIdeaUpdated, msg.Type)
+	assert.Equal(t, "null", string(msg.Data), "too large for NOTIFY")
+	assert.Len(t, relay.sent, 2)
+	assert.LessOrEqual(t, len(relay.sent[1]), MaxRelayPayload)
+
+	relay.fail = errors.New("connection lost")
+	err := hub.Publish(ctx, models.Event{Type: models.EventIdeaCreated, Topics: []string{models.TopicIdeas}})
+	assert.Error(t, err)
+	assert.Equal(t, models.EventIdeaCreated, receive(t, sub).Type, "delivered here anyway")
+}
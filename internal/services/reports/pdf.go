@@ -0,0 +1,247 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// page layout, in millimeters and points
+const (
+	margin      = 20.0
+	lineHeight  = 5.5
+	indentStep  = 6.0 // per level of replies
+	maxIndent   = 6   // deeper replies aren't indented further
+	voteBar     = 4.0 // height of the likes and dislikes bar
+	timeLayout  = "02.01.2006 15:04 UTC"
+	fontFamily  = "go"
+	titleSize   = 16
+	headingSize = 13
+	textSize    = 10.5
+	smallSize   = 8.5
+)
+
+// document draws on A4 pages, every write goes below the previous one and
+// pages are added as they fill up
+type document struct {
+	*fpdf.Fpdf
+	width float64 // between the margins
+}
+
+func newDocument(title string, created time.Time) *document {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(fontFamily, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", gobold.TTF)
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Voice Agora", true)
+	pdf.SetCreationDate(created)
+	pdf.SetModificationDate(created)
+	pdf.AliasNbPages("")
+
+	pageWidth, _ := pdf.GetPageSize()
+	d := &document{Fpdf: pdf, width: pageWidth - 2*margin}
+	pdf.SetFooterFunc(func() {
+		d.SetY(-margin + 5)
+		d.font("", smallSize, 120)
+		d.CellFormat(d.width/2, lineHeight, "Сформирован "+formatTime(created), "", 0, "L", false, 0, "")
+		d.CellFormat(d.width/2, lineHeight, fmt.Sprintf("Стр. %d из {nb}", d.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+	return d
+}
+
+// font sets the style, size and gray level of the text that follows
+func (d *document) font(style string, size float64, gray int) {
+	d.SetFont(fontFamily, style, size)
+	d.SetTextColor(gray, gray, gray)
+}
+
+func (d *document) heading(text string) {
+	d.Ln(lineHeight)
+	d.font("B", headingSize, 0)
+	d.MultiCell(d.width, lineHeight+1, text, "", "L", false)
+	y := d.GetY() + 1
+	d.SetDrawColor(180, 180, 180)
+	d.Line(margin, y, margin+d.width, y)
+	d.Ln(3)
+}
+
+func (d *document) paragraph(text string) {
+	d.font("", textSize, 0)
+	d.MultiCell(d.width, lineHeight, text, "", "L", false)
+}
+
+// note writes a gray remark, like an empty section
+func (d *document) note(text string) {
+	d.font("", textSize, 120)
+	d.MultiCell(d.width, lineHeight, text, "", "L", false)
+}
+
+// field writes a row of the metadata, the name in gray
+func (d *document) field(name, value string) {
+	const nameWidth = 40.0
+	d.font("", textSize, 110)
+	d.CellFormat(nameWidth, lineHeight, name, "", 0, "L", false, 0, "")
+	d.font("", textSize, 0)
+	d.MultiCell(d.width-nameWidth, lineHeight, value, "", "L", false)
+}
+
+func renderIdea(r ideaReport) ([]byte, error) {
+	idea := r.idea
+	d := newDocument(idea.Name, r.created)
+
+	d.font("", smallSize, 120)
+	d.MultiCell(d.width, lineHeight, "Отчет по идее "+idea.IdeaUID, "", "L", false)
+	d.font("B", titleSize, 0)
+	d.MultiCell(d.width, lineHeight+2, idea.Name, "", "L", false)
+	d.Ln(3)
+
+	author := fullName(r.profiles, idea.Author)
+	if idea.AuthorProfile != nil {
+		author = strings.TrimSpace(idea.AuthorProfile.Name + " " + idea.AuthorProfile.Surname)
+	}
+	d.field("Автор", orDash(author))
+	d.field("Категория", orDash(r.category))
+	d.field("Статус", orDash(r.status))
+	if idea.CampaignID != nil {
+		d.field("Кампания", orDash(r.campaign))
+	}
+	d.field("Подана", formatTime(idea.CreationDate))
+	d.field("Просмотры", fmt.Sprint(idea.ViewCount))
+
+	d.heading("Описание")
+	d.paragraph(idea.Text)
+
+	d.heading("Голоса")
+	renderVotes(d, idea.LikeCount, idea.DislikeCount)
+
+	d.heading("История статусов")
+	renderHistory(d, r)
+
+	d.heading(fmt.Sprintf("Обсуждение (%d)", countLive(r.comments)))
+	renderComments(d, r)
+
+	var buf bytes.Buffer
+	if err := d.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderVotes(d *document, likes, dislikes int) {
+	total := likes + dislikes
+	d.field("За", share(likes, total))
+	d.field("Против", share(dislikes, total))
+	d.field("Всего", fmt.Sprint(total))
+	if total == 0 {
+		return
+	}
+
+	d.Ln(2)
+	x, y := margin, d.GetY()
+	forWidth := d.width * float64(likes) / float64(total)
+	d.SetFillColor(76, 175, 80)
+	d.Rect(x, y, forWidth, voteBar, "F")
+	d.SetFillColor(229, 57, 53)
+	d.Rect(x+forWidth, y, d.width-forWidth, voteBar, "F")
+	d.Ln(voteBar)
+}
+
+func renderHistory(d *document, r ideaReport) {
+	if len(r.history) == 0 {
+		d.note("Смена статусов не записана: идея не рассматривалась или рассмотрена до того, как история начала вестись.")
+		return
+	}
+
+	widths := []float64{45, 55, d.width - 100}
+	d.font("B", textSize, 0)
+	d.SetFillColor(238, 238, 238)
+	for k, name := range []string{"Дата", "Статус", "Изменил"} {
+		d.CellFormat(widths[k], lineHeight+1, name, "B", 0, "L", true, 0, "")
+	}
+	d.Ln(-1)
+	d.font("", textSize, 0)
+	for _, c := range r.history {
+		by := "—"
+		if c.ChangedBy != nil {
+			by = orDash(fullName(r.profiles, *c.ChangedBy))
+		}
+		d.CellFormat(widths[0], lineHeight+1, formatTime(c.ChangedAt), "", 0, "L", false, 0, "")
+		d.CellFormat(widths[1], lineHeight+1, orDash(c.status), "", 0, "L", false, 0, "")
+		d.CellFormat(widths[2], lineHeight+1, by, "", 1, "L", false, 0, "")
+	}
+}
+
+func renderComments(d *document, r ideaReport) {
+	if len(r.comments) == 0 {
+		d.note("Комментариев нет.")
+		return
+	}
+
+	for _, c := range r.comments {
+		indent := float64(min(c.Depth, maxIndent)) * indentStep
+		d.SetX(margin + indent)
+
+		caption := orDash(fullName(r.profiles, c.AuthorID)) + " · " + formatTime(c.Timestamp)
+		if c.EditedAt != nil && c.DeletedAt == nil {
+			caption += " · изменен"
+		}
+		d.font("B", smallSize+0.5, 60)
+		d.MultiCell(d.width-indent, lineHeight, caption, "", "L", false)
+
+		d.SetX(margin + indent)
+		if c.DeletedAt != nil {
+			d.font("", textSize, 140)
+			d.MultiCell(d.width-indent, lineHeight, "Комментарий удален", "", "L", false)
+		} else {
+			d.font("", textSize, 0)
+			d.MultiCell(d.width-indent, lineHeight, c.CommentText, "", "L", false)
+		}
+		d.Ln(2)
+	}
+}
+
+// countLive counts comments that aren't deleted
+func countLive(comments []models.Comment) int {
+	n := 0
+	for _, c := range comments {
+		if c.DeletedAt == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func fullName(profiles map[string]models.AuthorProfile, uid string) string {
+	p, ok := profiles[uid]
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(p.Name + " " + p.Surname)
+}
+
+func share(n, total int) string {
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%.0f%%)", n, 100*float64(n)/float64(total))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
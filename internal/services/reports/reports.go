@@ -0,0 +1,137 @@
+// Package reports renders printable documents. The idea report goes with an
+// approved idea to the committee: the idea and its metadata, status
+// history, votes and the whole discussion as a paginated PDF. It is drawn
+// with the Go fonts, which cover Cyrillic, so no font is read from disk.
+package reports
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+)
+
+type Reports struct {
+	log  slog.Logger
+	repo repository.Repository
+	now  func() time.Time
+}
+
+func New(log slog.Logger, repo repository.Repository) *Reports {
+	return &Reports{log: log, repo: repo, now: time.Now}
+}
+
+// ideaReport - everything the idea report shows
+type ideaReport struct {
+	idea     models.Idea
+	category string
+	status   string
+	campaign string
+	history  []statusChange
+	comments []models.Comment // in thread order
+	profiles map[string]models.AuthorProfile
+	created  time.Time
+}
+
+type statusChange struct {
+	models.IdeaStatusChange
+	status string
+}
+
+// IdeaReport returns the PDF report of an idea. Opening it doesn't count as
+// a view.
+func (r *Reports) IdeaReport(ctx context.Context, uid string) ([]byte, error) {
+	log := r.log.With(slog.String("op", "IdeaReport"), slog.String("uid", uid))
+
+	report, err := r.collect(ctx, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperr.NotFound("idea not found")
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to collect the report: "+err.Error())
+		return nil, err
+	}
+
+	pdf, err := renderIdea(report)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to render the report: "+err.Error())
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "rendered idea report", slog.Int("comments", len(report.comments)),
+		slog.Int("bytes", len(pdf)))
+	return pdf, nil
+}
+
+func (r *Reports) collect(ctx context.Context, uid string) (ideaReport, error) {
+	report := ideaReport{created: r.now()}
+
+	var err error
+	report.idea, err = r.repo.SelectIdeaWithAuthor(ctx, uid)
+	if err != nil {
+		return report, err
+	}
+
+	categories, err := r.repo.SelectIdeaCategories(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, c := range categories {
+		if c.ID == report.idea.CategoryID {
+			report.category = c.Name
+		}
+	}
+	statuses, err := r.repo.SelectIdeaStatuses(ctx)
+	if err != nil {
+		return report, err
+	}
+	statusNames := map[int]string{}
+	for _, s := range statuses {
+		statusNames[s.ID] = s.Name
+	}
+	report.status = statusNames[report.idea.StatusID]
+
+	if report.idea.CampaignID != nil {
+		campaign, err := r.repo.SelectCampaign(ctx, *report.idea.CampaignID)
+		// the campaign may be gone, the idea stays
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return report, err
+		}
+		report.campaign = campaign.Title
+	}
+
+	changes, err := r.repo.SelectIdeaStatusChanges(ctx, uid)
+	if err != nil {
+		return report, err
+	}
+	var uids []string
+	for _, c := range changes {
+		report.history = append(report.history, statusChange{IdeaStatusChange: c, status: statusNames[c.StatusID]})
+		if c.ChangedBy != nil {
+			uids = append(uids, *c.ChangedBy)
+		}
+	}
+
+	comments, err := r.repo.SelectIdeaComments(ctx, uid)
+	if err != nil {
+		return report, err
+	}
+	report.comments = models.ThreadOrder(comments)
+	for _, c := range comments {
+		uids = append(uids, c.AuthorID)
+	}
+
+	profiles, err := r.repo.SelectProfiles(ctx, uids)
+	if err != nil {
+		return report, err
+	}
+	report.profiles = map[string]models.AuthorProfile{}
+	for _, p := range profiles {
+		report.profiles[p.UID] = p
+	}
+	return report, nil
+}
@@ -0,0 +1,65 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pages reads the page count of the document catalog
+func pages(t *testing.T, pdf []byte) int {
+	t.Helper()
+	m := regexp.MustCompile(`/Type /Pages\s*/Kids \[[^\]]*\]\s*/Count (\d+)`).FindSubmatch(pdf)
+	require.NotNil(t, m, "no page tree")
+	n, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	return n
+}
+
+func TestIdeaReport(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	r := New(*slog.Default(), repo)
+
+	author := repotest.User(t, repo, "author@example.com")
+	idea := repotest.Idea(t, repo, author.UID, "Кофемашина на третий этаж")
+	pdf, err := r.IdeaReport(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+	assert.Equal(t, 1, pages(t, pdf))
+	assert.Contains(t, string(pdf), "/FontFile2", "the font is embedded")
+
+	// a long discussion goes on over pages
+	require.NoError(t, repo.InsertIdeaStatusChange(ctx, models.IdeaStatusChange{IdeaUID: idea.IdeaUID,
+		StatusID: repotest.Statuses[1].ID, ChangedBy: &author.UID, ChangedAt: time.Now()}))
+	var parent *string
+	for n := range 40 {
+		c := models.Comment{CommentUID: uuid.NewString(), IdeaUID: idea.IdeaUID, AuthorID: author.UID,
+			CommentText: strings.Repeat("Поддерживаю, давно пора. ", 10)}
+		if n%4 != 0 {
+			c.ParentUID = parent
+		}
+		require.NoError(t, repo.InsertIdeaComment(ctx, c))
+		parent = &c.CommentUID
+	}
+	pdf, err = r.IdeaReport(ctx, idea.IdeaUID)
+	require.NoError(t, err)
+	assert.Greater(t, pages(t, pdf), 2)
+
+	_, err = r.IdeaReport(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
@@ -1,8 +1,10 @@
 package users
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
 	"github.com/TP2-Voice-Agora/backend/internal/models"
 	"github.com/TP2-Voice-Agora/backend/internal/repository"
 	"io"
@@ -25,7 +27,7 @@ func New(log slog.Logger, repo repository.Repository) *Users {
 	}
 }
 
-func (u *Users) GetUserByUID(UID string) (models.User, error) {
+func (u *Users) GetUserByUID(ctx context.Context, UID string) (models.User, error) {
 	op := "GetUserByUID"
 	log := u.log.With(
 		slog.String("op", op),
@@ -33,23 +35,28 @@ func (u *Users) GetUserByUID(UID string) (models.User, error) {
 	)
 
 	if UID == "" {
-		log.Error("uid is empty")
-		return models.User{}, errors.New("uid is empty")
+		log.ErrorContext(ctx, "uid is empty")
+		return models.User{}, apperr.Validation("user uid is required")
 	}
 
-	log.Debug("fetching user by uid")
+	log.DebugContext(ctx, "fetching user by uid")
 
-	user, err := u.repo.SelectUserByUID(UID)
+	user, err := u.repo.SelectUserByUID(ctx, UID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.DebugContext(ctx, "user not found")
+		return models.User{}, apperr.NotFound("user not found")
+	}
 	if err != nil {
-		log.Error("failed to fetch user by uid" + err.Error())
+		log.ErrorContext(ctx, "failed to fetch user by uid"+err.Error())
 		return models.User{}, err
 	}
-	log.Info("successfully fetched user")
+	log.InfoContext(ctx, "successfully fetched user")
 
 	return user, nil
 }
 
 func (u *Users) UploadPFP(
+	ctx context.Context,
 	file multipart.File,
 	header *multipart.FileHeader,
 	UID string) (string, error) {
@@ -60,7 +67,7 @@ func (u *Users) UploadPFP(
 	}
 
 	if !isValid(header) {
-		return "", errors.New("invalid file type, only jpeg and png allowed")
+		return "", apperr.Validation("invalid file type, only jpeg and png allowed")
 	}
 
 	ext := filepath.Ext(header.Filename)
@@ -69,33 +76,34 @@ func (u *Users) UploadPFP(
 
 	dst, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
-		return "", errors.New("could not create or overwrite file")
+		return "", fmt.Errorf("could not create or overwrite file: %w", err)
 	}
 	defer dst.Close()
 
 	if _, err := io.Copy(dst, file); err != nil {
-		return "", errors.New("failed to write file")
+		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 
-	err = u.repo.UpdateUserPfpURL(UID, filePath)
+	err = u.repo.UpdateUserPfpURL(ctx, UID, filePath)
 	if err != nil {
-		u.log.Error("failed to update user pfp url", slog.String("error", err.Error()))
+		u.log.ErrorContext(ctx, "failed to update user pfp url", slog.String("error", err.Error()))
 		return "", err
 	}
 
 	return filePath, nil
 }
 
-func (u *Users) GetPositions() ([]models.UserPosition, error) {
+func (u *Users) GetPositions(ctx context.Context) ([]models.UserPosition, error) {
 	op := "GetPositions"
 	log := u.log.With(
 		slog.String("op", op),
 	)
 
-	userCategories, err := u.repo.SelectPositions()
+	userCategories, err := u.repo.SelectPositions(ctx)
 	if err != nil {
-		log.Error("failed to fetch positions", slog.String("error", err.Error()))
+		log.ErrorContext(ctx, "failed to fetch positions", slog.String("error", err.Error()))
+		return nil, err
 	}
-	log.Debug("successfully fetched positions")
+	log.DebugContext(ctx, "successfully fetched positions")
 	return userCategories, nil
 }
@@ -0,0 +1,481 @@
+// Package webhooks delivers idea events to URLs configured by admins. An
+// event is queued in the database for every subscribed webhook, in the
+// transaction of the change it is about, and posted from there, signed with
+// the webhook's secret; failed deliveries are retried with exponential
+// backoff and dead-lettered when attempts run out, until an admin
+// redelivers them.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Delivery settings
+const (
+	// Batch - deliveries claimed at once
+	Batch = 20
+	// RequestTimeout bounds a single request to a webhook
+	RequestTimeout = 10 * time.Second
+	// Lease - time a claimed delivery is hidden from other workers, it is
+	// retried afterwards if the worker died while sending it
+	Lease = time.Minute
+	// MaxAttempts - attempts before a delivery is dead
+	MaxAttempts = 8
+	// RetryBase - wait after the first failed attempt, doubled after every
+	// next one: 30s, 1m, 2m... about an hour in total
+	RetryBase = 30 * time.Second
+	// PollInterval - how often Run looks for due deliveries, new events are
+	// sent right away
+	PollInterval = 5 * time.Second
+	// DefaultDeliveryPage - deliveries listed when no limit is given
+	DefaultDeliveryPage = 50
+)
+
+// Request headers, HeaderDelivery is the event id and stays the same when
+// a delivery is retried
+const (
+	HeaderEvent     = "X-Agora-Event"
+	HeaderDelivery  = "X-Agora-Delivery"
+	HeaderSignature = "X-Agora-Signature"
+)
+
+// Payload - body of every webhook request
+type Payload struct {
+	ID        string           `json:"id"`
+	Type      models.EventType `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      any              `json:"data"`
+}
+
+type Webhooks struct {
+	log    slog.Logger
+	repo   repository.Repository
+	client *http.Client
+	now    func() time.Time
+	wake   chan struct{}
+}
+
+func New(log slog.Logger, repo repository.Repository) *Webhooks {
+	return &Webhooks{
+		log:  log,
+		repo: repo,
+		client: &http.Client{
+			Timeout: RequestTimeout,
+			// a redirect is an answer of its own, it isn't followed with the payload
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		now:  time.Now,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Sign returns the HeaderSignature value for body: "sha256=" and the hex
+// HMAC-SHA256 of the body with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature made by Sign in constant time
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Queue saves a delivery of the event for every active webhook subscribed
+// to it, with repo of the transaction that makes the change, so deliveries
+// are committed or rolled back with it. Other than models.WebhookEvents are
+// ignored. It makes Webhooks an ideas.Outbox.
+func (w *Webhooks) Queue(ctx context.Context, repo repository.Repository, event models.Event) error {
+	if !slices.Contains(models.WebhookEvents, event.Type) {
+		return nil
+	}
+	webhooks, err := repo.SelectWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	webhooks = slices.DeleteFunc(webhooks, func(h models.Webhook) bool {
+		return !h.Active || !slices.Contains(h.Events, event.Type)
+	})
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	payload, err := w.payload(event.Type, event.Data)
+	if err != nil {
+		return err
+	}
+	for _, h := range webhooks {
+		payload.WebhookID = h.ID
+		if _, err := repo.InsertWebhookDelivery(ctx, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish wakes Run up once the change queued by Queue is committed, so that
+// its deliveries are sent without waiting for PollInterval. It makes
+// Webhooks an ideas.Publisher.
+func (w *Webhooks) Publish(ctx context.Context, event models.Event) error {
+	if slices.Contains(models.WebhookEvents, event.Type) {
+		w.nudge()
+	}
+	return nil
+}
+
+// payload returns a pending delivery of a new event, due now
+func (w *Webhooks) payload(typ models.EventType, data any) (models.WebhookDelivery, error) {
+	now := w.now()
+	id := uuid.NewString()
+	body, err := json.Marshal(Payload{ID: id, Type: typ, CreatedAt: now.UTC(), Data: data})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return models.WebhookDelivery{
+		EventID:       id,
+		Event:         typ,
+		Payload:       string(body),
+		State:         models.DeliveryPending,
+		NextAttemptAt: now,
+	}, nil
+}
+
+// nudge wakes Run up without waiting for PollInterval
+func (w *Webhooks) nudge() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SendPending posts due deliveries and returns how many were delivered
+func (w *Webhooks) SendPending(ctx context.Context) (int, error) {
+	op := "WebhooksSendPending"
+	log := w.log.With(slog.String("op", op))
+
+	delivered := 0
+	for {
+		now := w.now()
+		deliveries, err := w.repo.ClaimWebhookDeliveries(ctx, now, now.Add(Lease), Batch)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to claim deliveries: "+err.Error())
+			return delivered, err
+		}
+		for _, d := range deliveries {
+			if w.send(ctx, log, d) {
+				delivered++
+			}
+		}
+		if len(deliveries) < Batch {
+			return delivered, nil
+		}
+	}
+}
+
+// send makes an attempt of a claimed delivery and records it. Deliveries of
+// disabled webhooks are dead right away, they can be redelivered once the
+// webhook is back.
+func (w *Webhooks) send(ctx context.Context, log *slog.Logger, d models.WebhookDelivery) bool {
+	log = log.With(slog.Int64("deliveryID", d.ID), slog.Int64("webhookID", d.WebhookID))
+
+	hook, err := w.repo.SelectWebhook(ctx, d.WebhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// deleted together with its deliveries meanwhile
+		return false
+	}
+	if err != nil {
+		// the lease runs out and the delivery is claimed again
+		log.ErrorContext(ctx, "failed to fetch webhook: "+err.Error())
+		return false
+	}
+
+	var attempt models.WebhookAttempt
+	if hook.Active {
+		attempt = w.attempt(ctx, hook, d)
+	} else {
+		attempt = models.WebhookAttempt{DeliveryID: d.ID, Error: "webhook is disabled"}
+	}
+
+	state, retryAt := models.DeliveryDelivered, time.Time{}
+	switch {
+	case attempt.Error == "":
+	case hook.Active && d.Attempts < MaxAttempts:
+		state, retryAt = models.DeliveryPending, w.now().Add(RetryBase<<(d.Attempts-1))
+		log.WarnContext(ctx, "failed to deliver webhook, will retry: "+attempt.Error)
+	default:
+		state = models.DeliveryDead
+		log.ErrorContext(ctx, "failed to deliver webhook, giving up: "+attempt.Error)
+	}
+	if err := w.record(ctx, attempt, state, retryAt); err != nil {
+		log.ErrorContext(ctx, "failed to record webhook attempt: "+err.Error())
+	}
+	return state == models.DeliveryDelivered
+}
+
+// attempt posts the payload, any answer but 2xx is an error
+func (w *Webhooks) attempt(ctx context.Context, hook models.Webhook, d models.WebhookDelivery) models.WebhookAttempt {
+	attempt := models.WebhookAttempt{DeliveryID: d.ID}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(d.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Agora-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, string(d.Event))
+	req.Header.Set(HeaderDelivery, d.EventID)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, []byte(d.Payload)))
+
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	attempt.DurationMS = int(time.Since(start).Milliseconds())
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	// drained for the connection to be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.ResponseCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = "unexpected status " + resp.Status
+	}
+	return attempt
+}
+
+func (w *Webhooks) record(ctx context.Context, attempt models.WebhookAttempt, state models.DeliveryState, retryAt time.Time) error {
+	return w.repo.WithTx(ctx, func(repo repository.Repository) error {
+		return repo.RecordWebhookAttempt(ctx, attempt, state, retryAt)
+	})
+}
+
+// Run posts due deliveries until ctx is done
+func (w *Webhooks) Run(ctx context.Context) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// errors are logged by SendPending
+		_, _ = w.SendPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// GetWebhooks returns every webhook, without secrets
+func (w *Webhooks) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := w.repo.SelectWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for k := range webhooks {
+		webhooks[k].Secret = ""
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook saves a webhook of actorUID and returns it with the secret,
+// one is generated unless given
+func (w *Webhooks) CreateWebhook(ctx context.Context, actorUID string, req models.WebhookRequest) (models.Webhook, error) {
+	op := "WebhooksCreate"
+	log := w.log.With(slog.String("op", op), slog.String("actorUID", actorUID))
+
+	if err := checkEvents(req.Events); err != nil {
+		return models.Webhook{}, err
+	}
+	hook := models.Webhook{
+		URL:       req.URL,
+		Secret:    req.Secret,
+		Events:    models.EventTypes(req.Events),
+		Active:    req.Active == nil || *req.Active,
+		CreatedBy: &actorUID,
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return models.Webhook{}, err
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+
+	hook, err := w.repo.InsertWebhook(ctx, hook)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create webhook: "+err.Error())
+		return models.Webhook{}, err
+	}
+	log.InfoContext(ctx, "successfully created webhook", slog.Int64("webhookID", hook.ID))
+	return hook, nil
+}
+
+// UpdateWebhook changes the webhook, an empty secret keeps the old one
+func (w *Webhooks) UpdateWebhook(ctx context.Context, id int64, req models.WebhookRequest) (models.Webhook, error) {
+	op := "WebhooksUpdate"
+	log := w.log.With(slog.String("op", op), slog.Int64("webhookID", id))
+
+	if err := checkEvents(req.Events); err != nil {
+		return models.Webhook{}, err
+	}
+	hook, err := w.webhook(ctx, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	hook.URL = req.URL
+	hook.Events = models.EventTypes(req.Events)
+	if req.Secret != "" {
+		hook.Secret = req.Secret
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+
+	if err := w.repo.UpdateWebhook(ctx, hook); err != nil {
+		log.ErrorContext(ctx, "failed to update webhook: "+err.Error())
+		return models.Webhook{}, err
+	}
+	log.InfoContext(ctx, "successfully updated webhook")
+	hook.Secret = ""
+	return hook, nil
+}
+
+// DeleteWebhook deletes the webhook with its delivery log
+func (w *Webhooks) DeleteWebhook(ctx context.Context, id int64) error {
+	err := w.repo.DeleteWebhook(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperr.NotFound("webhook not found")
+	}
+	return err
+}
+
+func (w *Webhooks) webhook(ctx context.Context, id int64) (models.Webhook, error) {
+	hook, err := w.repo.SelectWebhook(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Webhook{}, apperr.NotFound("webhook not found")
+	}
+	return hook, err
+}
+
+func checkEvents(events []models.EventType) error {
+	if len(events) == 0 {
+		return apperr.Validation("no events")
+	}
+	for _, e := range events {
+		if !slices.Contains(models.WebhookEvents, e) {
+			return apperr.Validation("unknown event " + string(e))
+		}
+	}
+	return nil
+}
+
+// TestWebhook sends a models.EventPing to the webhook right away, even a
+// disabled one, and returns the delivery with its log. A failed test event
+// isn't retried.
+func (w *Webhooks) TestWebhook(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	hook, err := w.webhook(ctx, id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	ping, err := w.payload(models.EventPing, map[string]int64{"webhookId": hook.ID})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	// queued as claimed, workers leave it alone
+	ping.WebhookID = hook.ID
+	ping.Attempts = 1
+	ping.NextAttemptAt = ping.NextAttemptAt.Add(Lease)
+	ping, err = w.repo.InsertWebhookDelivery(ctx, ping)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	attempt := w.attempt(ctx, hook, ping)
+	state := models.DeliveryDelivered
+	if attempt.Error != "" {
+		state = models.DeliveryDead
+	}
+	if err := w.record(ctx, attempt, state, time.Time{}); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return w.GetDelivery(ctx, ping.ID)
+}
+
+// GetDeliveries returns the latest deliveries of the webhook in state, in
+// any state when it is empty
+func (w *Webhooks) GetDeliveries(ctx context.Context, webhookID int64, state models.DeliveryState, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := w.webhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultDeliveryPage
+	}
+	deliveries, err := w.repo.SelectWebhookDeliveries(ctx, webhookID, state, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// GetDelivery returns the delivery with its log
+func (w *Webhooks) GetDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	d, err := w.repo.SelectWebhookDelivery(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.WebhookDelivery{}, apperr.NotFound("delivery not found")
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	d.Log, err = w.repo.SelectWebhookAttempts(ctx, id)
+	return d, err
+}
+
+// Redeliver queues a delivered or dead delivery again with a fresh set of
+// attempts
+func (w *Webhooks) Redeliver(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	op := "WebhooksRedeliver"
+	log := w.log.With(slog.String("op", op), slog.Int64("deliveryID", id))
+
+	d, err := w.GetDelivery(ctx, id)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if d.State == models.DeliveryPending {
+		return models.WebhookDelivery{}, apperr.Conflict("delivery is still pending")
+	}
+	if err := w.repo.RequeueWebhookDelivery(ctx, id, w.now()); err != nil {
+		log.ErrorContext(ctx, "failed to requeue delivery: "+err.Error())
+		return models.WebhookDelivery{}, err
+	}
+	w.nudge()
+	log.InfoContext(ctx, "successfully requeued delivery")
+	return w.GetDelivery(ctx, id)
+}
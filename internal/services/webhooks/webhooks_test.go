@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TP2-Voice-Agora/backend/internal/lib/apperr"
+	"github.com/TP2-Voice-Agora/backend/internal/models"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/memory"
+	"github.com/TP2-Voice-Agora/backend/internal/repository/repotest"
+	"github.com/TP2-Voice-Agora/backend/internal/services/ideas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver - webhook endpoint answering with status, which tests change
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	rc := &receiver{status: http.StatusOK}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.requests = append(rc.requests, r)
+		rc.bodies = append(rc.bodies, body)
+		w.WriteHeader(rc.status)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) answer(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+func (rc *receiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func setup(t *testing.T) (*Webhooks, *ideas.Ideas, *memory.Repository, models.User) {
+	repo := memory.New()
+	repo.SeedDictionaries(repotest.Positions, repotest.Categories, repotest.Statuses)
+	w := New(*slog.Default(), repo)
+	i, err := ideas.New(context.Background(), *slog.Default(), repo)
+	require.NoError(t, err)
+	i.SetOutbox(w)
+	i.SetPublisher(w)
+	admin := repotest.User(t, repo, "admin@example.com")
+	return w, i, repo, admin
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", body)
+	// echo -n '{"id":"1"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=6146142a2ce0159e84c0767881e4ec80bc397da62526e7d19f70795eb79460c0", signature)
+	assert.True(t, Verify("secret", body, signature))
+	assert.False(t, Verify("other", body, signature))
+	assert.False(t, Verify("secret", []byte(`{"id":"2"}`), signature))
+}
+
+func TestDelivery(t *testing.T) {
+	ctx := context.Background()
+	w, i, _, admin := setup(t)
+	rc := newReceiver(t)
+	hook, err := w.CreateWebhook(ctx, admin.UID, models.WebhookRequest{
+		URL: rc.URL, Events: []models.EventType{models.EventIdeaCreated, models.EventIdeaUpdated},
+	})
+	require.NoError(t, err)
+	assert.Len(t, hook.Secret, 64, "generated")
+	assert.True(t, hook.Active)
+	disabled := false
+	_, err = w.CreateWebhook(ctx, admin.UID, models.WebhookRequest{
+		URL: rc.URL, Events: []models.EventType{models.EventIdeaCreated}, Active: &disabled,
+	})
+	require.NoError(t, err)
+
+	idea, err := i.InsertIdea(ctx, "idea", "text", admin.UID, 1, 1)
+	require.NoError(t, err)
+	_, err = i.Vote(ctx, idea.IdeaUID, admin.UID, models.VoteLike)
+	require.NoError(t, err)
+	assert.Zero(t, rc.received(), "queued, not sent in the request")
+
+	sent, err := w.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent, "votes aren't subscribed to, the other webhook is disabled")
+	require.Equal(t, 1, rc.received())
+
+	req, body := rc.requests[0], rc.bodies[0]
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, string(models.EventIdeaCreated), req.Header.Get(HeaderEvent))
+	assert.True(t, Verify(hook.Secret, body, req.Header.Get(HeaderSignature)))
+	var payload struct {
+		Payload
+		Data models.Idea `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, req.Header.Get(HeaderDelivery), payload.ID)
+	assert.Equal(t, models.EventIdeaCreated, payload.Type)
+	assert.Equal(t, idea.IdeaUID, payload.Data.IdeaUID)
+
+	deliveries, err := w.GetDeliveries(ctx, hook.ID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.DeliveryDelivered, deliveries[0].State)
+	d, err := w.GetDelivery(ctx, deliveries[0].ID)
+	require.NoError(t, err)
+	require.Len(t, d.Log, 1)
+	assert.Equal(t, http.StatusOK, *d.Log[0].ResponseCode)
+
+	webhooks, err := w.GetWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	assert.Empty(t, webhooks[0].Secret, "shown only on creation")
+}
+
+func TestRetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	w, i, _, admin := setup(t)
+	rc := newReceiver(t)
+	rc.answer(http.StatusServiceUnavailable)
+	now := time.Now()
+	w.now = func() time.Time { return now }
+	hook, err := w.CreateWebhook(ctx, admin.UID, models.WebhookRequest{
+		URL: rc.URL, Secret: "0123456789abcdef", Events: []models.EventType{models.EventIdeaCreated},
+	})
+	require.NoError(t, err)
+	_, err = i.InsertIdea(ctx, "idea", "text", admin.UID, 1, 1)
+	require.NoError(t, err)
+
+	var id int64
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		sent, err := w.SendPending(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		require.Equal(t, attempt, rc.received())
+
+		deliveries, err := w.GetDeliveries(ctx, hook.ID, "", 1)
+		require.NoError(t, err)
+		d := deliveries[0]
+		id = d.ID
+		assert.Equal(t, attempt, d.Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, *d.ResponseCode)
+		if attempt < MaxAttempts {
+			require.Equal(t, models.DeliveryPending, d.State)
+			assert.WithinDuration(t, now.Add(RetryBase<<(attempt-1)), d.NextAttemptAt, time.Millisecond)
+			_, err = w.SendPending(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, attempt, rc.received(), "not due yet")
+			now = d.NextAttemptAt
+		}
+	}
+
+	dead, err := w.GetDeliveries(ctx, hook.ID, models.DeliveryDead, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	d, err := w.GetDelivery(ctx, id)
+	require.NoError(t, err)
+	assert.Len(t, d.Log, MaxAttempts)
+	assert.Equal(t, "unexpected status 503 Service Unavailable", d.LastError)
+
+	// the receiver is fixed, the admin redelivers
+	rc.answer(http.StatusNoContent)
+	d, err = w.Redeliver(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, d.State)
+	_, err = w.Redeliver(ctx, id)
+	assert.ErrorIs(t, err, apperr.ErrConflict)
+	sent, err := w.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, rc.requests[0].Header.Get(HeaderDelivery), rc.requests[MaxAttempts].Header.Get(HeaderDelivery),
+		"the same event")
+}
+
+func TestDisabledWebhook(t *testing.T) {
+	ctx := context.Background()
+	w, i, _, admin := setup(t)
+	rc := newReceiver(t)
+	hook, err := w.CreateWebhook(ctx, admin.UID, models.WebhookRequest{
+		URL: rc.URL, Events: []models.EventType{models.EventIdeaCreated},
+	})
+	require.NoError(t, err)
+	_, err = i.InsertIdea(ctx, "idea", "text", admin.UID, 1, 1)
+	require.NoError(t, err)
+
+	disabled := false
+	hook, err = w.UpdateWebhook(ctx, hook.ID, models.WebhookRequest{
+		URL: rc.URL, Events: []models.EventType{models.EventIdeaCreated}, Active: &disabled,
+	})
+	require.NoError(t, err)
+	assert.False(t, hook.Active)
+	assert.Empty(t, hook.Secret)
+
+	_, err = w.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, rc.received())
+	dead, err := w.GetDeliveries(ctx, hook.ID, models.DeliveryDead, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "webhook is disabled", dead[0].LastError)
+}
+
+func TestTestWebhook(t *testing.T) {
+	ctx := context.Background()
+	w, _, _, admin := setup(t)
+	rc := newReceiver(t)
+	disabled := false
+	hook, err := w.CreateWebhook(ctx, admin.UID, models.WebhookRequest{
+		URL: rc.URL, Events: []models.EventType{models.EventIdeaCreated}, Active: &disabled,
+	})
+	require.NoError(t, err)
+
+	d, err := w.TestWebhook(ctx, hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDelivered, d.State)
+	assert.Equal(t, models.EventPing, d.Event)
+	require.Len(t, d.Log, 1)
+	require.Equal(t, 1, rc.received())
+	assert.Equal(t, "ping", rc.requests[0].Header.Get(HeaderEvent))
+	assert.True(t, Verify(hook.Secret, rc.bodies[0], rc.requests[0].Header.Get(HeaderSignature)))
+
+	rc.answer(http.StatusNotFound)
+	d, err = w.TestWebhook(ctx, hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryDead, d.State, "test events aren't retried")
+	assert.Equal(t, http.StatusNotFound, *d.ResponseCode)
+
+	sent, err := w.SendPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Equal(t, 2, rc.received())
+
+	_, err = w.TestWebhook(ctx, hook.ID+100)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+func TestValidation(t *testing.T) {
+	ctx := context.Background()
+	w, _, _, admin := setup(t)
+
+	_, err := w.CreateWebhook(ctx, admin.UID, models.WebhookRequest{URL: "https://example.com", Events: []models.EventType{models.EventPing}})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = w.UpdateWebhook(ctx, 100, models.WebhookRequest{URL: "https://example.com", Events: []models.EventType{models.EventVotes}})
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+	assert.ErrorIs(t, w.DeleteWebhook(ctx, 100), apperr.ErrNotFound)
+	_, err = w.GetDeliveries(ctx, 100, "", 10)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}